@@ -0,0 +1,244 @@
+// Package age implements file encryption according to the age-encryption.org/v1
+// specification. See https://age-encryption.org/v1.
+//
+// Recipients and identities are pluggable, new types can be added by
+// implementing Recipient and Identity and registering them with
+// AddRecipientParser and AddIdentityParser.
+package age
+
+import (
+    "io"
+    "fmt"
+    "bytes"
+    "errors"
+    "crypto/rand"
+    "crypto/hmac"
+    "crypto/sha256"
+
+    "golang.org/x/crypto/chacha20poly1305"
+
+    "github.com/deatil/go-cryptobin/kdf/hkdf"
+)
+
+const fileKeySize = 16
+const streamNonceSize = 16
+
+// Recipient 为加密接收者, 使用接收者的公钥包装文件密钥
+// An Recipient is passed to Encrypt to wrap an opaque file key to one or more
+// recipient stanzas.
+type Recipient interface {
+    Wrap(fileKey []byte) ([]*Stanza, error)
+}
+
+// Identity 为解密身份, 从接收者 stanza 中解出文件密钥
+// An Identity is passed to Decrypt to unwrap an opaque file key from a
+// recipient stanza. It must return ErrIncorrectIdentity for recipient
+// stanzas that don't match the identity.
+type Identity interface {
+    Unwrap(stanzas []*Stanza) (fileKey []byte, err error)
+}
+
+// ErrIncorrectIdentity is returned by Identity.Unwrap when the identity
+// does not match any of the stanzas.
+var ErrIncorrectIdentity = errors.New("go-cryptobin/age: incorrect identity for recipient block")
+
+// NoIdentityMatchError is returned by Decrypt when none of the supplied
+// identities match the encrypted file.
+type NoIdentityMatchError struct {
+    // Errors 为每个身份返回的错误
+    Errors []error
+}
+
+func (e *NoIdentityMatchError) Error() string {
+    if len(e.Errors) == 1 {
+        return "go-cryptobin/age: no identity matched any of the recipients: " + e.Errors[0].Error()
+    }
+
+    return "go-cryptobin/age: no identity matched any of the recipients"
+}
+
+// Encrypt encrypts a file to one or more recipients.
+//
+// Writes to the returned WriteCloser are encrypted and written to dst as an
+// age file. Every recipient will be able to decrypt the file.
+//
+// The caller must call Close on the WriteCloser when done for the last chunk
+// to be encrypted and flushed to dst.
+func Encrypt(dst io.Writer, recipients ...Recipient) (io.WriteCloser, error) {
+    if len(recipients) == 0 {
+        return nil, errors.New("go-cryptobin/age: no recipients specified")
+    }
+
+    fileKey := make([]byte, fileKeySize)
+    if _, err := rand.Read(fileKey); err != nil {
+        return nil, err
+    }
+
+    hdr := &header{}
+    for i, r := range recipients {
+        stanzas, err := r.Wrap(fileKey)
+        if err != nil {
+            return nil, fmt.Errorf("go-cryptobin/age: failed to wrap key for recipient #%d: %w", i, err)
+        }
+
+        hdr.recipients = append(hdr.recipients, stanzas...)
+    }
+
+    // scrypt 必须是唯一的接收者
+    for _, s := range hdr.recipients {
+        if s.Type == scryptLabel && len(hdr.recipients) != 1 {
+            return nil, errors.New("go-cryptobin/age: an scrypt recipient must be the only one")
+        }
+    }
+
+    mac, err := headerMAC(fileKey, hdr)
+    if err != nil {
+        return nil, err
+    }
+    hdr.mac = mac
+
+    if err := hdr.marshal(dst); err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to write header: %w", err)
+    }
+
+    nonce := make([]byte, streamNonceSize)
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+    if _, err := dst.Write(nonce); err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to write nonce: %w", err)
+    }
+
+    return newStreamWriter(streamKey(fileKey, nonce), dst)
+}
+
+// Decrypt decrypts a file encrypted to one or more identities.
+//
+// It returns a Reader reading the decrypted plaintext of the age file read
+// from src. All identities will be tried until one successfully decrypts
+// the file.
+func Decrypt(src io.Reader, identities ...Identity) (io.Reader, error) {
+    if len(identities) == 0 {
+        return nil, errors.New("go-cryptobin/age: no identities specified")
+    }
+
+    hdr, payload, err := parseHeader(src)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to read header: %w", err)
+    }
+
+    fileKey, err := unwrapFileKey(hdr, identities)
+    if err != nil {
+        return nil, err
+    }
+
+    mac, err := headerMAC(fileKey, hdr)
+    if err != nil {
+        return nil, err
+    }
+    if !hmac.Equal(mac, hdr.mac) {
+        return nil, errors.New("go-cryptobin/age: bad header MAC")
+    }
+
+    nonce := make([]byte, streamNonceSize)
+    if _, err := io.ReadFull(payload, nonce); err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to read nonce: %w", err)
+    }
+
+    return newStreamReader(streamKey(fileKey, nonce), payload)
+}
+
+func unwrapFileKey(hdr *header, identities []Identity) ([]byte, error) {
+    // scrypt 必须是唯一的 stanza
+    for _, s := range hdr.recipients {
+        if s.Type == scryptLabel && len(hdr.recipients) != 1 {
+            return nil, errors.New("go-cryptobin/age: an scrypt recipient must be the only one")
+        }
+    }
+
+    errs := make([]error, 0, len(identities))
+    for _, id := range identities {
+        fileKey, err := id.Unwrap(hdr.recipients)
+        if errors.Is(err, ErrIncorrectIdentity) {
+            errs = append(errs, err)
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+
+        if len(fileKey) != fileKeySize {
+            return nil, errors.New("go-cryptobin/age: invalid file key size")
+        }
+
+        return fileKey, nil
+    }
+
+    return nil, &NoIdentityMatchError{Errors: errs}
+}
+
+// multiUnwrap 依次使用 unwrap 解析每个 stanza
+func multiUnwrap(unwrap func(*Stanza) ([]byte, error), stanzas []*Stanza) ([]byte, error) {
+    for _, s := range stanzas {
+        fileKey, err := unwrap(s)
+        if errors.Is(err, ErrIncorrectIdentity) {
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+
+        return fileKey, nil
+    }
+
+    return nil, ErrIncorrectIdentity
+}
+
+// headerMAC 计算头部的 HMAC
+func headerMAC(fileKey []byte, hdr *header) ([]byte, error) {
+    key, err := hkdf.Key(sha256.New, fileKey, nil, []byte("header"), 32)
+    if err != nil {
+        return nil, err
+    }
+
+    var buf bytes.Buffer
+    if err := hdr.marshalWithoutMAC(&buf); err != nil {
+        return nil, err
+    }
+
+    h := hmac.New(sha256.New, key)
+    h.Write(buf.Bytes())
+    return h.Sum(nil), nil
+}
+
+// streamKey 派生数据加密密钥
+func streamKey(fileKey, nonce []byte) []byte {
+    key, _ := hkdf.Key(sha256.New, fileKey, nonce, []byte("payload"), chacha20poly1305.KeySize)
+    return key
+}
+
+// aeadEncrypt 使用全零 nonce 加密文件密钥
+func aeadEncrypt(key, plaintext []byte) ([]byte, error) {
+    aead, err := chacha20poly1305.New(key)
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := make([]byte, chacha20poly1305.NonceSize)
+    return aead.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// aeadDecrypt 解密文件密钥, 密文长度必须与 size 匹配
+func aeadDecrypt(key []byte, size int, ciphertext []byte) ([]byte, error) {
+    aead, err := chacha20poly1305.New(key)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(ciphertext) != size+aead.Overhead() {
+        return nil, errors.New("go-cryptobin/age: encrypted value has unexpected length")
+    }
+
+    nonce := make([]byte, chacha20poly1305.NonceSize)
+    return aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,241 @@
+package age
+
+import (
+    "io"
+    "bufio"
+    "bytes"
+    "errors"
+    "encoding/base64"
+)
+
+const (
+    armorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+    armorFooter = "-----END AGE ENCRYPTED FILE-----"
+)
+
+// armorWriter 为 PEM 格式的 ASCII armor 编码
+type armorWriter struct {
+    dst     io.Writer
+    lines   *armorLineWriter
+    encoder io.WriteCloser
+    started bool
+}
+
+// NewArmorWriter returns a Writer which ASCII armors the data written to it.
+// The caller must call Close to write the footer. It does not close dst.
+func NewArmorWriter(dst io.Writer) io.WriteCloser {
+    lines := &armorLineWriter{dst: dst}
+
+    return &armorWriter{
+        dst:     dst,
+        lines:   lines,
+        encoder: base64.NewEncoder(base64.StdEncoding, lines),
+    }
+}
+
+func (a *armorWriter) writeHeader() error {
+    if a.started {
+        return nil
+    }
+
+    a.started = true
+    _, err := io.WriteString(a.dst, armorHeader+"\n")
+    return err
+}
+
+func (a *armorWriter) Write(p []byte) (int, error) {
+    if err := a.writeHeader(); err != nil {
+        return 0, err
+    }
+
+    return a.encoder.Write(p)
+}
+
+func (a *armorWriter) Close() error {
+    if err := a.writeHeader(); err != nil {
+        return err
+    }
+
+    if err := a.encoder.Close(); err != nil {
+        return err
+    }
+
+    footer := armorFooter + "\n"
+    if a.lines.column != 0 {
+        footer = "\n" + footer
+    }
+
+    _, err := io.WriteString(a.dst, footer)
+    return err
+}
+
+// armorLineWriter 按 64 列换行
+type armorLineWriter struct {
+    dst    io.Writer
+    column int
+}
+
+func (l *armorLineWriter) Write(p []byte) (n int, err error) {
+    for len(p) > 0 {
+        m := columnsPerLine - l.column
+        if m > len(p) {
+            m = len(p)
+        }
+
+        if _, err = l.dst.Write(p[:m]); err != nil {
+            return
+        }
+
+        n += m
+        l.column += m
+        p = p[m:]
+
+        if l.column == columnsPerLine {
+            if _, err = l.dst.Write([]byte{'\n'}); err != nil {
+                return
+            }
+            l.column = 0
+        }
+    }
+
+    return
+}
+
+// armorReader 解析 ASCII armor
+type armorReader struct {
+    r       *bufio.Reader
+    started bool
+    unread  []byte
+    err     error
+}
+
+// NewArmorReader returns a Reader which decodes the ASCII armored data read
+// from src. Leading and trailing whitespace is ignored.
+func NewArmorReader(src io.Reader) io.Reader {
+    return &armorReader{
+        r: bufio.NewReader(src),
+    }
+}
+
+func (r *armorReader) Read(p []byte) (int, error) {
+    if len(r.unread) > 0 {
+        n := copy(p, r.unread)
+        r.unread = r.unread[n:]
+        return n, nil
+    }
+    if r.err != nil {
+        return 0, r.err
+    }
+
+    if err := r.readLine(); err != nil {
+        r.err = err
+        return 0, err
+    }
+
+    n := copy(p, r.unread)
+    r.unread = r.unread[n:]
+    return n, nil
+}
+
+// readLine 读取并解码一行
+func (r *armorReader) readLine() error {
+    if !r.started {
+        // 跳过开头的空白
+        for {
+            c, err := r.r.ReadByte()
+            if err != nil {
+                return armorError(err)
+            }
+            if !isWhitespace(c) {
+                r.r.UnreadByte()
+                break
+            }
+        }
+
+        line, err := r.getLine()
+        if err != nil {
+            return err
+        }
+        if line != armorHeader {
+            return errors.New("go-cryptobin/age: invalid armor header")
+        }
+
+        r.started = true
+    }
+
+    line, err := r.getLine()
+    if err != nil {
+        return err
+    }
+
+    if line == armorFooter {
+        return r.checkTrailing()
+    }
+
+    if len(line) > columnsPerLine {
+        return errors.New("go-cryptobin/age: armor line too long")
+    }
+
+    data, err := base64.StdEncoding.Strict().DecodeString(line)
+    if err != nil {
+        return errors.New("go-cryptobin/age: invalid armor body: " + err.Error())
+    }
+
+    // 短行必须为最后一行
+    if len(line) < columnsPerLine {
+        next, err := r.getLine()
+        if err != nil {
+            return err
+        }
+        if next != armorFooter {
+            return errors.New("go-cryptobin/age: invalid armor closing line")
+        }
+        if err = r.checkTrailing(); err != io.EOF {
+            return err
+        }
+
+        r.err = io.EOF
+    }
+
+    r.unread = data
+    return nil
+}
+
+func (r *armorReader) getLine() (string, error) {
+    line, err := r.r.ReadBytes('\n')
+    if err != nil && !(err == io.EOF && len(line) > 0) {
+        return "", armorError(err)
+    }
+
+    line = bytes.TrimSuffix(line, []byte("\n"))
+    line = bytes.TrimSuffix(line, []byte("\r"))
+    return string(line), nil
+}
+
+// checkTrailing 检测结尾只有空白
+func (r *armorReader) checkTrailing() error {
+    rest, err := io.ReadAll(io.LimitReader(r.r, 1024))
+    if err != nil {
+        return err
+    }
+
+    for _, c := range rest {
+        if !isWhitespace(c) {
+            return errors.New("go-cryptobin/age: trailing data after armored file")
+        }
+    }
+
+    return io.EOF
+}
+
+func isWhitespace(c byte) bool {
+    return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func armorError(err error) error {
+    if err == io.EOF {
+        err = io.ErrUnexpectedEOF
+    }
+
+    return errors.New("go-cryptobin/age: failed to read armor: " + err.Error())
+}
@@ -0,0 +1,174 @@
+package age
+
+import (
+    "fmt"
+    "errors"
+    "strings"
+)
+
+// bech32 编码, 参考 BIP 173. age 不限制字符串长度.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+    chk := uint32(1)
+    for _, v := range values {
+        top := chk >> 25
+        chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+        for i := 0; i < 5; i++ {
+            if (top>>uint(i))&1 == 1 {
+                chk ^= bech32Generator[i]
+            }
+        }
+    }
+
+    return chk
+}
+
+func bech32HrpExpand(hrp string) []byte {
+    h := []byte(strings.ToLower(hrp))
+
+    var ret []byte
+    for _, c := range h {
+        ret = append(ret, c>>5)
+    }
+    ret = append(ret, 0)
+    for _, c := range h {
+        ret = append(ret, c&31)
+    }
+
+    return ret
+}
+
+func bech32VerifyChecksum(hrp string, data []byte) bool {
+    return bech32Polymod(append(bech32HrpExpand(hrp), data...)) == 1
+}
+
+func bech32CreateChecksum(hrp string, data []byte) []byte {
+    values := append(bech32HrpExpand(hrp), data...)
+    values = append(values, []byte{0, 0, 0, 0, 0, 0}...)
+    mod := bech32Polymod(values) ^ 1
+
+    var ret []byte
+    for p := 0; p < 6; p++ {
+        ret = append(ret, byte((mod>>uint(5*(5-p)))&31))
+    }
+
+    return ret
+}
+
+// convertBits 转换位宽
+func convertBits(data []byte, frombits, tobits byte, pad bool) ([]byte, error) {
+    var ret []byte
+    acc := uint32(0)
+    bits := byte(0)
+    maxv := byte(1<<tobits - 1)
+
+    for idx, value := range data {
+        if value>>frombits != 0 {
+            return nil, fmt.Errorf("invalid data range: data[%d]=%d (frombits=%d)", idx, value, frombits)
+        }
+
+        acc = acc<<frombits | uint32(value)
+        bits += frombits
+        for bits >= tobits {
+            bits -= tobits
+            ret = append(ret, byte(acc>>bits)&maxv)
+        }
+    }
+
+    if pad {
+        if bits > 0 {
+            ret = append(ret, byte(acc<<(tobits-bits))&maxv)
+        }
+    } else if bits >= frombits {
+        return nil, errors.New("illegal zero padding")
+    } else if byte(acc<<(tobits-bits))&maxv != 0 {
+        return nil, errors.New("non-zero padding")
+    }
+
+    return ret, nil
+}
+
+// bech32Encode 编码数据, 大写 hrp 会返回大写字符串
+func bech32Encode(hrp string, data []byte) (string, error) {
+    values, err := convertBits(data, 8, 5, true)
+    if err != nil {
+        return "", err
+    }
+    if len(hrp) < 1 {
+        return "", fmt.Errorf("invalid HRP: %q", hrp)
+    }
+
+    for p, c := range hrp {
+        if c < 33 || c > 126 {
+            return "", fmt.Errorf("invalid HRP character: hrp[%d]=%d", p, c)
+        }
+    }
+
+    if strings.ToUpper(hrp) != hrp && strings.ToLower(hrp) != hrp {
+        return "", fmt.Errorf("mixed case HRP: %q", hrp)
+    }
+
+    lower := strings.ToLower(hrp) == hrp
+    hrp = strings.ToLower(hrp)
+
+    var ret strings.Builder
+    ret.WriteString(hrp)
+    ret.WriteString("1")
+    for _, p := range values {
+        ret.WriteByte(bech32Charset[p])
+    }
+    for _, p := range bech32CreateChecksum(hrp, values) {
+        ret.WriteByte(bech32Charset[p])
+    }
+
+    if lower {
+        return ret.String(), nil
+    }
+
+    return strings.ToUpper(ret.String()), nil
+}
+
+// bech32Decode 解码数据, 返回小写的 hrp
+func bech32Decode(s string) (string, []byte, error) {
+    if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+        return "", nil, errors.New("mixed case")
+    }
+
+    pos := strings.LastIndex(s, "1")
+    if pos < 1 || pos+7 > len(s) {
+        return "", nil, errors.New("separator '1' at invalid position")
+    }
+
+    hrp := s[:pos]
+    for p, c := range hrp {
+        if c < 33 || c > 126 {
+            return "", nil, fmt.Errorf("invalid character human-readable part: s[%d]=%d", p, c)
+        }
+    }
+
+    s = strings.ToLower(s)
+
+    var data []byte
+    for p, c := range s[pos+1:] {
+        d := strings.IndexRune(bech32Charset, c)
+        if d == -1 {
+            return "", nil, fmt.Errorf("invalid character data part: s[%d]=%v", p, c)
+        }
+
+        data = append(data, byte(d))
+    }
+
+    if !bech32VerifyChecksum(hrp, data) {
+        return "", nil, errors.New("invalid checksum")
+    }
+
+    out, err := convertBits(data[:len(data)-6], 5, 8, false)
+    if err != nil {
+        return "", nil, err
+    }
+
+    return strings.ToLower(hrp), out, nil
+}
@@ -0,0 +1,213 @@
+package age
+
+import (
+    "io"
+    "fmt"
+    "bufio"
+    "errors"
+    "strings"
+    "encoding/base64"
+)
+
+const (
+    intro      = "age-encryption.org/v1\n"
+    stanzaHead = "->"
+    footerHead = "---"
+
+    // 每行 base64 的列数
+    columnsPerLine = 64
+    bytesPerLine   = columnsPerLine / 4 * 3
+)
+
+// b64 为不带填充的标准 base64 编码
+var b64 = base64.RawStdEncoding.Strict()
+
+// Stanza 为 age 头部的接收者块
+// A Stanza is a section of the age header that encapsulates the file key as
+// encrypted to a specific recipient.
+type Stanza struct {
+    Type string
+    Args []string
+    Body []byte
+}
+
+// header 为 age 头部
+type header struct {
+    recipients []*Stanza
+    mac        []byte
+}
+
+// Marshal 编码 stanza
+func (s *Stanza) Marshal(w io.Writer) error {
+    if _, err := io.WriteString(w, stanzaHead); err != nil {
+        return err
+    }
+
+    for _, a := range append([]string{s.Type}, s.Args...) {
+        if !isValidString(a) {
+            return errors.New("go-cryptobin/age: invalid stanza argument")
+        }
+
+        if _, err := io.WriteString(w, " "+a); err != nil {
+            return err
+        }
+    }
+
+    if _, err := io.WriteString(w, "\n"); err != nil {
+        return err
+    }
+
+    // 最后一行必须短于 64 列, 可以为空行
+    body := b64.EncodeToString(s.Body)
+    for {
+        n := len(body)
+        if n > columnsPerLine {
+            n = columnsPerLine
+        }
+
+        if _, err := io.WriteString(w, body[:n]+"\n"); err != nil {
+            return err
+        }
+
+        body = body[n:]
+        if n < columnsPerLine {
+            return nil
+        }
+    }
+}
+
+func (h *header) marshalWithoutMAC(w io.Writer) error {
+    if _, err := io.WriteString(w, intro); err != nil {
+        return err
+    }
+
+    for _, r := range h.recipients {
+        if err := r.Marshal(w); err != nil {
+            return err
+        }
+    }
+
+    _, err := io.WriteString(w, footerHead)
+    return err
+}
+
+func (h *header) marshal(w io.Writer) error {
+    if err := h.marshalWithoutMAC(w); err != nil {
+        return err
+    }
+
+    _, err := io.WriteString(w, " "+b64.EncodeToString(h.mac)+"\n")
+    return err
+}
+
+// parseHeader 解析头部, 返回头部和剩余的数据
+func parseHeader(input io.Reader) (*header, io.Reader, error) {
+    rr := bufio.NewReader(input)
+
+    line, err := rr.ReadString('\n')
+    if err != nil {
+        return nil, nil, errorf("failed to read intro: %v", err)
+    }
+    if line != intro {
+        return nil, nil, errorf("unexpected intro: %q", line)
+    }
+
+    h := &header{}
+    for {
+        line, err := readLine(rr)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        if strings.HasPrefix(line, footerHead) {
+            prefix, args := splitArgs(line)
+            if prefix != footerHead || len(args) != 1 {
+                return nil, nil, errorf("malformed closing line: %q", line)
+            }
+
+            h.mac, err = b64.DecodeString(args[0])
+            if err != nil || len(h.mac) != 32 {
+                return nil, nil, errorf("malformed closing line %q: %v", line, err)
+            }
+
+            break
+        }
+
+        prefix, args := splitArgs(line)
+        if prefix != stanzaHead || len(args) < 1 {
+            return nil, nil, errorf("malformed recipient stanza: %q", line)
+        }
+        for _, a := range args {
+            if !isValidString(a) {
+                return nil, nil, errorf("malformed recipient stanza: %q", line)
+            }
+        }
+
+        s := &Stanza{
+            Type: args[0],
+            Args: args[1:],
+        }
+
+        for {
+            line, err := readLine(rr)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            b, err := b64.DecodeString(line)
+            if err != nil {
+                return nil, nil, errorf("malformed body line %q: %v", line, err)
+            }
+            if len(b) > bytesPerLine {
+                return nil, nil, errorf("malformed body line %q: too long", line)
+            }
+
+            s.Body = append(s.Body, b...)
+            if len(b) < bytesPerLine {
+                break
+            }
+        }
+
+        h.recipients = append(h.recipients, s)
+    }
+
+    // 剩余的数据为加密内容
+    return h, rr, nil
+}
+
+// readLine 读取一行, 不包括换行符
+func readLine(rr *bufio.Reader) (string, error) {
+    line, err := rr.ReadString('\n')
+    if err != nil {
+        if err == io.EOF {
+            err = io.ErrUnexpectedEOF
+        }
+        return "", errorf("failed to read header: %v", err)
+    }
+
+    return strings.TrimSuffix(line, "\n"), nil
+}
+
+func splitArgs(line string) (string, []string) {
+    parts := strings.Split(line, " ")
+    return parts[0], parts[1:]
+}
+
+// isValidString 检测参数是否为非空的可见 ASCII 字符
+func isValidString(s string) bool {
+    if len(s) == 0 {
+        return false
+    }
+
+    for _, c := range []byte(s) {
+        if c < 33 || c > 126 {
+            return false
+        }
+    }
+
+    return true
+}
+
+func errorf(format string, a ...any) error {
+    return fmt.Errorf("parsing age header: "+format, a...)
+}
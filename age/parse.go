@@ -0,0 +1,154 @@
+package age
+
+import (
+    "io"
+    "fmt"
+    "bufio"
+    "strings"
+)
+
+// 解析方式
+// Recipient Parser
+type RecipientParser = func(string) (Recipient, error)
+
+// 解析方式
+// Identity Parser
+type IdentityParser = func(string) (Identity, error)
+
+var recipientParsers = make(map[string]RecipientParser)
+var identityParsers = make(map[string]IdentityParser)
+
+// 添加接收者解析方式, prefix 为字符串前缀, 比如 age1
+// Add Recipient Parser
+func AddRecipientParser(prefix string, parser RecipientParser) {
+    recipientParsers[prefix] = parser
+}
+
+// 添加身份解析方式, prefix 为字符串前缀, 比如 AGE-SECRET-KEY-1
+// Add Identity Parser
+func AddIdentityParser(prefix string, parser IdentityParser) {
+    identityParsers[prefix] = parser
+}
+
+func init() {
+    AddRecipientParser("age1", func(s string) (Recipient, error) {
+        return ParseX25519Recipient(s)
+    })
+    AddRecipientParser("ssh-rsa ", ParseSSHRecipient)
+    AddRecipientParser("ssh-ed25519 ", ParseSSHRecipient)
+
+    AddIdentityParser("AGE-SECRET-KEY-1", func(s string) (Identity, error) {
+        return ParseX25519Identity(s)
+    })
+}
+
+// 使用最长匹配的前缀
+func findParser[T any](parsers map[string]T, s string) (parser T, ok bool) {
+    matched := -1
+    for prefix, p := range parsers {
+        if len(prefix) > matched && strings.HasPrefix(s, prefix) {
+            parser, ok = p, true
+            matched = len(prefix)
+        }
+    }
+
+    return
+}
+
+// ParseRecipient parses a recipient string with the registered parsers.
+func ParseRecipient(s string) (Recipient, error) {
+    parser, ok := findParser(recipientParsers, s)
+    if !ok {
+        return nil, fmt.Errorf("go-cryptobin/age: unknown recipient type: %q", s)
+    }
+
+    return parser(s)
+}
+
+// ParseIdentity parses an identity string with the registered parsers.
+func ParseIdentity(s string) (Identity, error) {
+    parser, ok := findParser(identityParsers, s)
+    if !ok {
+        return nil, fmt.Errorf("go-cryptobin/age: unknown identity type")
+    }
+
+    return parser(s)
+}
+
+// ParseRecipients parses a file with one or more recipients per line.
+// Empty lines and lines starting with "#" are ignored.
+func ParseRecipients(f io.Reader) ([]Recipient, error) {
+    var recs []Recipient
+
+    err := parseLines(f, func(n int, line string) error {
+        r, err := ParseRecipient(line)
+        if err != nil {
+            return fmt.Errorf("go-cryptobin/age: error at line %d: %v", n, err)
+        }
+
+        recs = append(recs, r)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    if len(recs) == 0 {
+        return nil, fmt.Errorf("go-cryptobin/age: no recipients found")
+    }
+
+    return recs, nil
+}
+
+// ParseIdentities parses a file with one or more identities per line.
+// Empty lines and lines starting with "#" are ignored.
+func ParseIdentities(f io.Reader) ([]Identity, error) {
+    var ids []Identity
+
+    err := parseLines(f, func(n int, line string) error {
+        i, err := ParseIdentity(line)
+        if err != nil {
+            // 不输出密钥内容
+            return fmt.Errorf("go-cryptobin/age: error at line %d: %v", n, err)
+        }
+
+        ids = append(ids, i)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    if len(ids) == 0 {
+        return nil, fmt.Errorf("go-cryptobin/age: no secret keys found")
+    }
+
+    return ids, nil
+}
+
+func parseLines(f io.Reader, fn func(n int, line string) error) error {
+    const maxSize = 16 << 20
+
+    scanner := bufio.NewScanner(io.LimitReader(f, maxSize))
+    scanner.Buffer(make([]byte, 0, 4096), maxSize)
+
+    var n int
+    for scanner.Scan() {
+        n++
+
+        line := strings.TrimSpace(scanner.Text())
+        if strings.HasPrefix(line, "#") || line == "" {
+            continue
+        }
+
+        if err := fn(n, line); err != nil {
+            return err
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return fmt.Errorf("go-cryptobin/age: failed to read file: %v", err)
+    }
+
+    return nil
+}
@@ -0,0 +1,179 @@
+package age
+
+import (
+    "fmt"
+    "errors"
+    "regexp"
+    "strconv"
+    "crypto/rand"
+
+    "golang.org/x/crypto/scrypt"
+    "golang.org/x/crypto/chacha20poly1305"
+)
+
+const scryptLabel = "scrypt"
+
+const scryptSaltLabel = "age-encryption.org/v1/scrypt"
+const scryptSaltSize = 16
+
+// 默认工作因子
+const (
+    defaultScryptWorkFactor    = 18
+    defaultScryptMaxWorkFactor = 22
+)
+
+// 工作因子必须为不以 0 开头的十进制数
+var scryptWorkFactorRe = regexp.MustCompile(`^[1-9][0-9]*$`)
+
+// ScryptRecipient is a password-based recipient. Anyone with the password can
+// decrypt the message.
+//
+// If a ScryptRecipient is used, it must be the only recipient for the file:
+// it can't be mixed with other recipient types and can't be used multiple
+// times for the same file.
+type ScryptRecipient struct {
+    password   []byte
+    workFactor int
+}
+
+var _ Recipient = &ScryptRecipient{}
+
+// NewScryptRecipient returns a new ScryptRecipient with the provided password.
+func NewScryptRecipient(password string) (*ScryptRecipient, error) {
+    if len(password) == 0 {
+        return nil, errors.New("go-cryptobin/age: empty scrypt password")
+    }
+
+    return &ScryptRecipient{
+        password:   []byte(password),
+        workFactor: defaultScryptWorkFactor,
+    }, nil
+}
+
+// SetWorkFactor sets the scrypt work factor to 2^logN.
+// It must be called before Wrap.
+//
+// If SetWorkFactor is not called, a reasonable default is used.
+func (r *ScryptRecipient) SetWorkFactor(logN int) {
+    if logN > 30 || logN < 1 {
+        panic("go-cryptobin/age: SetWorkFactor called with illegal value")
+    }
+
+    r.workFactor = logN
+}
+
+func (r *ScryptRecipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+    salt := make([]byte, scryptSaltSize)
+    if _, err := rand.Read(salt); err != nil {
+        return nil, err
+    }
+
+    logN := r.workFactor
+    l := &Stanza{
+        Type: scryptLabel,
+        Args: []string{b64.EncodeToString(salt), strconv.Itoa(logN)},
+    }
+
+    salt = append([]byte(scryptSaltLabel), salt...)
+    k, err := scrypt.Key(r.password, salt, 1<<logN, 8, 1, chacha20poly1305.KeySize)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to generate scrypt hash: %v", err)
+    }
+
+    l.Body, err = aeadEncrypt(k, fileKey)
+    if err != nil {
+        return nil, err
+    }
+
+    return []*Stanza{l}, nil
+}
+
+// ScryptIdentity is a password-based identity.
+type ScryptIdentity struct {
+    password      []byte
+    maxWorkFactor int
+}
+
+var _ Identity = &ScryptIdentity{}
+
+// NewScryptIdentity returns a new ScryptIdentity with the provided password.
+func NewScryptIdentity(password string) (*ScryptIdentity, error) {
+    if len(password) == 0 {
+        return nil, errors.New("go-cryptobin/age: empty scrypt password")
+    }
+
+    return &ScryptIdentity{
+        password:      []byte(password),
+        maxWorkFactor: defaultScryptMaxWorkFactor,
+    }, nil
+}
+
+// SetMaxWorkFactor sets the maximum accepted scrypt work factor to 2^logN.
+// It must be called before Unwrap.
+//
+// This caps the amount of work that Decrypt might have to do to process
+// received files. If SetMaxWorkFactor is not called, a fairly high default
+// is used, which might not be suitable for systems processing untrusted files.
+func (i *ScryptIdentity) SetMaxWorkFactor(logN int) {
+    if logN > 30 || logN < 1 {
+        panic("go-cryptobin/age: SetMaxWorkFactor called with illegal value")
+    }
+
+    i.maxWorkFactor = logN
+}
+
+func (i *ScryptIdentity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+    for _, s := range stanzas {
+        if s.Type == scryptLabel && len(stanzas) != 1 {
+            return nil, errors.New("go-cryptobin/age: an scrypt recipient must be the only one")
+        }
+    }
+
+    return multiUnwrap(i.unwrap, stanzas)
+}
+
+func (i *ScryptIdentity) unwrap(block *Stanza) ([]byte, error) {
+    if block.Type != scryptLabel {
+        return nil, ErrIncorrectIdentity
+    }
+    if len(block.Args) != 2 {
+        return nil, errors.New("go-cryptobin/age: invalid scrypt recipient block")
+    }
+
+    salt, err := b64.DecodeString(block.Args[0])
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to parse scrypt salt: %v", err)
+    }
+    if len(salt) != scryptSaltSize {
+        return nil, errors.New("go-cryptobin/age: invalid scrypt recipient block")
+    }
+
+    if w := block.Args[1]; !scryptWorkFactorRe.MatchString(w) {
+        return nil, fmt.Errorf("go-cryptobin/age: invalid scrypt work factor: %q", w)
+    }
+
+    logN, err := strconv.Atoi(block.Args[1])
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to parse scrypt work factor: %v", err)
+    }
+    if logN > i.maxWorkFactor {
+        return nil, fmt.Errorf("go-cryptobin/age: scrypt work factor too large: %v", logN)
+    }
+    if logN <= 0 {
+        return nil, fmt.Errorf("go-cryptobin/age: invalid scrypt work factor: %v", logN)
+    }
+
+    salt = append([]byte(scryptSaltLabel), salt...)
+    k, err := scrypt.Key(i.password, salt, 1<<logN, 8, 1, chacha20poly1305.KeySize)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to generate scrypt hash: %v", err)
+    }
+
+    // 密码错误时返回 ErrIncorrectIdentity
+    fileKey, err := aeadDecrypt(k, fileKeySize, block.Body)
+    if err != nil {
+        return nil, ErrIncorrectIdentity
+    }
+
+    return fileKey, nil
+}
@@ -0,0 +1,433 @@
+package age
+
+import (
+    "fmt"
+    "errors"
+    "math/big"
+    "crypto/rsa"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/sha512"
+    "crypto/ed25519"
+
+    "golang.org/x/crypto/ssh"
+    "golang.org/x/crypto/chacha20poly1305"
+
+    "github.com/deatil/go-cryptobin/kdf/hkdf"
+    "github.com/deatil/go-cryptobin/pubkey/x25519"
+    cryptobin_ssh "github.com/deatil/go-cryptobin/ssh"
+)
+
+const (
+    sshRSALabel     = "age-encryption.org/v1/ssh-rsa"
+    sshEd25519Label = "age-encryption.org/v1/ssh-ed25519"
+)
+
+// sshFingerprint 为 SSH 公钥的 SHA-256 的前 4 字节
+func sshFingerprint(pk ssh.PublicKey) string {
+    h := sha256.Sum256(pk.Marshal())
+    return b64.EncodeToString(h[:4])
+}
+
+// SSHRSARecipient is an age recipient which encrypts to an ssh-rsa public key.
+type SSHRSARecipient struct {
+    sshKey ssh.PublicKey
+    pubKey *rsa.PublicKey
+}
+
+var _ Recipient = &SSHRSARecipient{}
+
+// NewSSHRSARecipient returns a new SSHRSARecipient from an ssh-rsa public key.
+func NewSSHRSARecipient(pk ssh.PublicKey) (*SSHRSARecipient, error) {
+    if pk.Type() != ssh.KeyAlgoRSA {
+        return nil, errors.New("go-cryptobin/age: SSH public key is not an RSA key")
+    }
+
+    r := &SSHRSARecipient{
+        sshKey: pk,
+    }
+
+    if pk, ok := pk.(ssh.CryptoPublicKey); ok {
+        if pk, ok := pk.CryptoPublicKey().(*rsa.PublicKey); ok {
+            r.pubKey = pk
+        } else {
+            return nil, errors.New("go-cryptobin/age: unexpected public key type")
+        }
+    } else {
+        return nil, errors.New("go-cryptobin/age: pk does not implement ssh.CryptoPublicKey")
+    }
+
+    if r.pubKey.Size() < 2048/8 {
+        return nil, errors.New("go-cryptobin/age: RSA key size is too small")
+    }
+
+    return r, nil
+}
+
+func (r *SSHRSARecipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+    l := &Stanza{
+        Type: "ssh-rsa",
+        Args: []string{sshFingerprint(r.sshKey)},
+    }
+
+    wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, r.pubKey, fileKey, []byte(sshRSALabel))
+    if err != nil {
+        return nil, err
+    }
+    l.Body = wrappedKey
+
+    return []*Stanza{l}, nil
+}
+
+// SSHRSAIdentity is an age identity which decrypts with an RSA private key.
+type SSHRSAIdentity struct {
+    k      *rsa.PrivateKey
+    sshKey ssh.PublicKey
+}
+
+var _ Identity = &SSHRSAIdentity{}
+
+// NewSSHRSAIdentity returns a new SSHRSAIdentity from an RSA private key.
+func NewSSHRSAIdentity(key *rsa.PrivateKey) (*SSHRSAIdentity, error) {
+    s, err := ssh.NewSignerFromKey(key)
+    if err != nil {
+        return nil, err
+    }
+
+    i := &SSHRSAIdentity{
+        k:      key,
+        sshKey: s.PublicKey(),
+    }
+
+    return i, nil
+}
+
+// Recipient returns the SSHRSARecipient of i.
+func (i *SSHRSAIdentity) Recipient() (*SSHRSARecipient, error) {
+    return NewSSHRSARecipient(i.sshKey)
+}
+
+func (i *SSHRSAIdentity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+    return multiUnwrap(i.unwrap, stanzas)
+}
+
+func (i *SSHRSAIdentity) unwrap(block *Stanza) ([]byte, error) {
+    if block.Type != "ssh-rsa" {
+        return nil, ErrIncorrectIdentity
+    }
+    if len(block.Args) != 1 {
+        return nil, errors.New("go-cryptobin/age: invalid ssh-rsa recipient block")
+    }
+
+    if block.Args[0] != sshFingerprint(i.sshKey) {
+        return nil, ErrIncorrectIdentity
+    }
+
+    fileKey, err := rsa.DecryptOAEP(sha256.New(), nil, i.k, block.Body, []byte(sshRSALabel))
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to decrypt file key: %v", err)
+    }
+
+    return fileKey, nil
+}
+
+// SSHEd25519Recipient is an age recipient which encrypts to an ssh-ed25519
+// public key.
+type SSHEd25519Recipient struct {
+    sshKey         ssh.PublicKey
+    theirPublicKey []byte
+}
+
+var _ Recipient = &SSHEd25519Recipient{}
+
+// NewSSHEd25519Recipient returns a new SSHEd25519Recipient from an
+// ssh-ed25519 public key.
+func NewSSHEd25519Recipient(pk ssh.PublicKey) (*SSHEd25519Recipient, error) {
+    if pk.Type() != ssh.KeyAlgoED25519 {
+        return nil, errors.New("go-cryptobin/age: SSH public key is not an Ed25519 key")
+    }
+
+    cpk, ok := pk.(ssh.CryptoPublicKey)
+    if !ok {
+        return nil, errors.New("go-cryptobin/age: pk does not implement ssh.CryptoPublicKey")
+    }
+
+    epk, ok := cpk.CryptoPublicKey().(ed25519.PublicKey)
+    if !ok {
+        return nil, errors.New("go-cryptobin/age: unexpected public key type")
+    }
+
+    mpk, err := ed25519PublicKeyToCurve25519(epk)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: invalid Ed25519 public key: %v", err)
+    }
+
+    return &SSHEd25519Recipient{
+        sshKey:         pk,
+        theirPublicKey: mpk,
+    }, nil
+}
+
+// ed25519Tweak 为由 SSH 公钥派生的标量
+func ed25519Tweak(sshKey ssh.PublicKey) []byte {
+    tweak, _ := hkdf.Key(sha256.New, nil, sshKey.Marshal(), []byte(sshEd25519Label), x25519.SeedSize)
+    return tweak
+}
+
+func (r *SSHEd25519Recipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+    _, ephemeral, err := x25519.GenerateKey(rand.Reader)
+    if err != nil {
+        return nil, err
+    }
+
+    ourPublicKey := ephemeral.Public().(x25519.PublicKey)
+    sharedSecret, err := x25519.X25519(ephemeral.Seed(), r.theirPublicKey)
+    if err != nil {
+        return nil, err
+    }
+
+    sharedSecret, err = x25519.X25519(ed25519Tweak(r.sshKey), sharedSecret)
+    if err != nil {
+        return nil, err
+    }
+
+    l := &Stanza{
+        Type: "ssh-ed25519",
+        Args: []string{sshFingerprint(r.sshKey), b64.EncodeToString(ourPublicKey)},
+    }
+
+    salt := make([]byte, 0, len(ourPublicKey)+len(r.theirPublicKey))
+    salt = append(salt, ourPublicKey...)
+    salt = append(salt, r.theirPublicKey...)
+
+    wrappingKey, err := hkdf.Key(sha256.New, sharedSecret, salt, []byte(sshEd25519Label), chacha20poly1305.KeySize)
+    if err != nil {
+        return nil, err
+    }
+
+    l.Body, err = aeadEncrypt(wrappingKey, fileKey)
+    if err != nil {
+        return nil, err
+    }
+
+    return []*Stanza{l}, nil
+}
+
+// SSHEd25519Identity is an age identity which decrypts with an Ed25519
+// private key.
+type SSHEd25519Identity struct {
+    secretKey    []byte
+    ourPublicKey []byte
+    sshKey       ssh.PublicKey
+}
+
+var _ Identity = &SSHEd25519Identity{}
+
+// NewSSHEd25519Identity returns a new SSHEd25519Identity from an Ed25519
+// private key.
+func NewSSHEd25519Identity(key ed25519.PrivateKey) (*SSHEd25519Identity, error) {
+    s, err := ssh.NewSignerFromKey(key)
+    if err != nil {
+        return nil, err
+    }
+
+    mpk, err := ed25519PublicKeyToCurve25519(key.Public().(ed25519.PublicKey))
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: invalid Ed25519 public key: %v", err)
+    }
+
+    // X25519 标量为种子的 SHA-512 的前半部分
+    h := sha512.Sum512(key.Seed())
+
+    return &SSHEd25519Identity{
+        secretKey:    h[:x25519.SeedSize],
+        ourPublicKey: mpk,
+        sshKey:       s.PublicKey(),
+    }, nil
+}
+
+// Recipient returns the SSHEd25519Recipient of i.
+func (i *SSHEd25519Identity) Recipient() (*SSHEd25519Recipient, error) {
+    return NewSSHEd25519Recipient(i.sshKey)
+}
+
+func (i *SSHEd25519Identity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+    return multiUnwrap(i.unwrap, stanzas)
+}
+
+func (i *SSHEd25519Identity) unwrap(block *Stanza) ([]byte, error) {
+    if block.Type != "ssh-ed25519" {
+        return nil, ErrIncorrectIdentity
+    }
+    if len(block.Args) != 2 {
+        return nil, errors.New("go-cryptobin/age: invalid ssh-ed25519 recipient block")
+    }
+
+    publicKey, err := b64.DecodeString(block.Args[1])
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to parse ssh-ed25519 recipient: %v", err)
+    }
+    if len(publicKey) != x25519.PublicKeySize {
+        return nil, errors.New("go-cryptobin/age: invalid ssh-ed25519 recipient block")
+    }
+
+    if block.Args[0] != sshFingerprint(i.sshKey) {
+        return nil, ErrIncorrectIdentity
+    }
+
+    sharedSecret, err := x25519.X25519(i.secretKey, publicKey)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: invalid X25519 recipient: %v", err)
+    }
+
+    sharedSecret, err = x25519.X25519(ed25519Tweak(i.sshKey), sharedSecret)
+    if err != nil {
+        return nil, err
+    }
+
+    salt := make([]byte, 0, len(publicKey)+len(i.ourPublicKey))
+    salt = append(salt, publicKey...)
+    salt = append(salt, i.ourPublicKey...)
+
+    wrappingKey, err := hkdf.Key(sha256.New, sharedSecret, salt, []byte(sshEd25519Label), chacha20poly1305.KeySize)
+    if err != nil {
+        return nil, err
+    }
+
+    fileKey, err := aeadDecrypt(wrappingKey, fileKeySize, block.Body)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to decrypt file key: %v", err)
+    }
+
+    return fileKey, nil
+}
+
+// ParseSSHRecipient parses a public key as found in an authorized_keys file,
+// it returns an SSHRSARecipient or an SSHEd25519Recipient.
+func ParseSSHRecipient(s string) (Recipient, error) {
+    pubKey, _, _, _, err := cryptobin_ssh.ParseAuthorizedKey([]byte(s))
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: malformed SSH recipient: %q: %v", s, err)
+    }
+
+    switch t := pubKey.Type(); t {
+        case ssh.KeyAlgoRSA:
+            return NewSSHRSARecipient(pubKey)
+        case ssh.KeyAlgoED25519:
+            return NewSSHEd25519Recipient(pubKey)
+        default:
+            return nil, fmt.Errorf("go-cryptobin/age: unknown SSH recipient type: %q", t)
+    }
+}
+
+// ParseSSHIdentity parses an unencrypted ssh-rsa or ssh-ed25519 private key,
+// in OpenSSH or PEM format.
+func ParseSSHIdentity(pemBytes []byte) (Identity, error) {
+    k, err := cryptobin_ssh.ParseRawPrivateKey(pemBytes)
+    if err != nil {
+        return nil, err
+    }
+
+    return newSSHIdentity(k)
+}
+
+// ParseSSHIdentityWithPassphrase parses a passphrase protected ssh-rsa or
+// ssh-ed25519 private key.
+func ParseSSHIdentityWithPassphrase(pemBytes, passphrase []byte) (Identity, error) {
+    k, err := cryptobin_ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, passphrase)
+    if err != nil {
+        return nil, err
+    }
+
+    return newSSHIdentity(k)
+}
+
+func newSSHIdentity(k any) (Identity, error) {
+    switch k := k.(type) {
+        case *ed25519.PrivateKey:
+            return NewSSHEd25519Identity(*k)
+        case ed25519.PrivateKey:
+            return NewSSHEd25519Identity(k)
+        case *rsa.PrivateKey:
+            return NewSSHRSAIdentity(k)
+    }
+
+    return nil, fmt.Errorf("go-cryptobin/age: unsupported SSH identity type: %T", k)
+}
+
+var (
+    // p = 2^255 - 19
+    curve25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+    // d = -121665 / 121666 mod p
+    edwards25519D = new(big.Int).Mod(
+        new(big.Int).Mul(
+            big.NewInt(-121665),
+            new(big.Int).ModInverse(big.NewInt(121666), curve25519P),
+        ),
+        curve25519P,
+    )
+)
+
+// ed25519PublicKeyToCurve25519 转换 Edwards 点为 Montgomery u 坐标, u = (1 + y) / (1 - y)
+func ed25519PublicKeyToCurve25519(pk ed25519.PublicKey) ([]byte, error) {
+    if len(pk) != ed25519.PublicKeySize {
+        return nil, errors.New("invalid public key size")
+    }
+
+    // 小端序, 最高位为 x 的符号
+    le := make([]byte, ed25519.PublicKeySize)
+    copy(le, pk)
+    sign := le[31] >> 7
+    le[31] &= 0x7f
+
+    y := new(big.Int).SetBytes(reverseBytes(le))
+    if y.Cmp(curve25519P) >= 0 {
+        return nil, errors.New("invalid point encoding")
+    }
+
+    // 检测点是否在曲线上, x^2 = (y^2 - 1) / (d y^2 + 1)
+    y2 := new(big.Int).Mul(y, y)
+    y2.Mod(y2, curve25519P)
+
+    num := new(big.Int).Sub(y2, big.NewInt(1))
+    num.Mod(num, curve25519P)
+
+    den := new(big.Int).Mul(edwards25519D, y2)
+    den.Add(den, big.NewInt(1))
+    den.Mod(den, curve25519P)
+
+    x2 := new(big.Int).Mul(num, new(big.Int).ModInverse(den, curve25519P))
+    x2.Mod(x2, curve25519P)
+
+    if x2.Sign() == 0 {
+        if sign == 1 {
+            return nil, errors.New("invalid point encoding")
+        }
+    } else if new(big.Int).ModSqrt(x2, curve25519P) == nil {
+        return nil, errors.New("point is not on the curve")
+    }
+
+    oneMinusY := new(big.Int).Sub(big.NewInt(1), y)
+    oneMinusY.Mod(oneMinusY, curve25519P)
+    if oneMinusY.Sign() == 0 {
+        return nil, errors.New("invalid point")
+    }
+
+    u := new(big.Int).Add(big.NewInt(1), y)
+    u.Mul(u, new(big.Int).ModInverse(oneMinusY, curve25519P))
+    u.Mod(u, curve25519P)
+
+    out := make([]byte, x25519.PublicKeySize)
+    u.FillBytes(out)
+
+    return reverseBytes(out), nil
+}
+
+func reverseBytes(b []byte) []byte {
+    out := make([]byte, len(b))
+    for i := range b {
+        out[len(b)-1-i] = b[i]
+    }
+
+    return out
+}
@@ -0,0 +1,231 @@
+package age
+
+import (
+    "io"
+    "errors"
+    "crypto/cipher"
+
+    "golang.org/x/crypto/chacha20poly1305"
+)
+
+// 每个数据块明文大小为 64 KiB
+const chunkSize = 64 * 1024
+
+const encChunkSize = chunkSize + chacha20poly1305.Overhead
+
+const lastChunkFlag = 0x01
+
+// streamNonce 为 11 字节大端计数器和 1 字节最后块标记
+type streamNonce [chacha20poly1305.NonceSize]byte
+
+func (n *streamNonce) setLastChunk() {
+    n[len(n)-1] = lastChunkFlag
+}
+
+func (n *streamNonce) isLastChunk() bool {
+    return n[len(n)-1] == lastChunkFlag
+}
+
+func (n *streamNonce) isFirstChunk() bool {
+    for _, b := range n[:len(n)-1] {
+        if b != 0 {
+            return false
+        }
+    }
+    return true
+}
+
+func (n *streamNonce) increment() error {
+    for i := len(n) - 2; i >= 0; i-- {
+        n[i]++
+        if n[i] != 0 {
+            return nil
+        }
+    }
+
+    return errors.New("go-cryptobin/age: stream counter overflow")
+}
+
+// streamReader 解密 STREAM 数据
+type streamReader struct {
+    aead  cipher.AEAD
+    src   io.Reader
+    nonce streamNonce
+
+    // buf 多读取一个字节用于判断是否为最后一块
+    buf    [encChunkSize + 1]byte
+    held   int
+    plain  []byte
+    unread []byte
+    err    error
+}
+
+func newStreamReader(key []byte, src io.Reader) (*streamReader, error) {
+    aead, err := chacha20poly1305.New(key)
+    if err != nil {
+        return nil, err
+    }
+
+    return &streamReader{
+        aead:  aead,
+        src:   src,
+        plain: make([]byte, 0, chunkSize),
+    }, nil
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+    if len(r.unread) > 0 {
+        n := copy(p, r.unread)
+        r.unread = r.unread[n:]
+        return n, nil
+    }
+    if r.err != nil {
+        return 0, r.err
+    }
+    if len(p) == 0 {
+        return 0, nil
+    }
+
+    last, err := r.readChunk()
+    if err != nil {
+        r.err = err
+        return 0, err
+    }
+
+    n := copy(p, r.unread)
+    r.unread = r.unread[n:]
+
+    if last {
+        r.err = io.EOF
+    }
+
+    return n, nil
+}
+
+// readChunk 读取并解密下一个数据块
+func (r *streamReader) readChunk() (last bool, err error) {
+    n, err := io.ReadFull(r.src, r.buf[r.held:])
+    total := r.held + n
+
+    switch {
+        case err == io.EOF || err == io.ErrUnexpectedEOF:
+            last = true
+        case err != nil:
+            return false, err
+    }
+
+    chunk := r.buf[:encChunkSize]
+    if last {
+        chunk = r.buf[:total]
+        if total < chacha20poly1305.Overhead {
+            return false, errors.New("go-cryptobin/age: truncated payload")
+        }
+
+        r.nonce.setLastChunk()
+    }
+
+    r.unread, err = r.aead.Open(r.plain[:0], r.nonce[:], chunk, nil)
+    if err != nil {
+        return false, errors.New("go-cryptobin/age: failed to decrypt and authenticate payload chunk")
+    }
+
+    if last {
+        // 只有空文件的最后一块可以为空
+        if len(r.unread) == 0 && !r.nonce.isFirstChunk() {
+            return false, errors.New("go-cryptobin/age: last chunk is empty")
+        }
+
+        return true, nil
+    }
+
+    // 多读取的字节为下一块的开头
+    r.buf[0] = r.buf[encChunkSize]
+    r.held = 1
+
+    return false, r.nonce.increment()
+}
+
+// streamWriter 使用 STREAM 加密数据
+type streamWriter struct {
+    aead  cipher.AEAD
+    dst   io.Writer
+    nonce streamNonce
+    buf   []byte
+    err   error
+}
+
+func newStreamWriter(key []byte, dst io.Writer) (*streamWriter, error) {
+    aead, err := chacha20poly1305.New(key)
+    if err != nil {
+        return nil, err
+    }
+
+    return &streamWriter{
+        aead: aead,
+        dst:  dst,
+        buf:  make([]byte, 0, encChunkSize),
+    }, nil
+}
+
+func (w *streamWriter) Write(p []byte) (n int, err error) {
+    if w.err != nil {
+        return 0, w.err
+    }
+    if w.nonce.isLastChunk() {
+        return 0, errors.New("go-cryptobin/age: write on closed writer")
+    }
+
+    total := len(p)
+    for len(p) > 0 {
+        // 缓冲区满且还有数据时才写出, 最后一块在 Close 中写出
+        if len(w.buf) == chunkSize {
+            if err := w.flushChunk(false); err != nil {
+                w.err = err
+                return 0, err
+            }
+        }
+
+        n := chunkSize - len(w.buf)
+        if n > len(p) {
+            n = len(p)
+        }
+
+        w.buf = append(w.buf, p[:n]...)
+        p = p[n:]
+    }
+
+    return total, nil
+}
+
+// Close flushes the last chunk. It does not close the underlying Writer.
+func (w *streamWriter) Close() error {
+    if w.err != nil {
+        return w.err
+    }
+
+    w.err = w.flushChunk(true)
+    if w.err != nil {
+        return w.err
+    }
+
+    w.err = errors.New("go-cryptobin/age: stream already closed")
+    return nil
+}
+
+func (w *streamWriter) flushChunk(last bool) error {
+    if last {
+        w.nonce.setLastChunk()
+    }
+
+    w.buf = w.aead.Seal(w.buf[:0], w.nonce[:], w.buf, nil)
+    if _, err := w.dst.Write(w.buf); err != nil {
+        return err
+    }
+
+    w.buf = w.buf[:0]
+    if last {
+        return nil
+    }
+
+    return w.nonce.increment()
+}
@@ -0,0 +1,215 @@
+package age
+
+import (
+    "fmt"
+    "errors"
+    "strings"
+    "crypto/rand"
+    "crypto/sha256"
+
+    "golang.org/x/crypto/chacha20poly1305"
+
+    "github.com/deatil/go-cryptobin/kdf/hkdf"
+    "github.com/deatil/go-cryptobin/pubkey/x25519"
+)
+
+const x25519Label = "age-encryption.org/v1/X25519"
+
+// X25519Recipient is the standard age public key. Messages encrypted to this
+// recipient can be decrypted with the corresponding X25519Identity.
+//
+// This recipient is anonymous, in the sense that an attacker can't tell from
+// the message alone if it is encrypted to a certain recipient.
+type X25519Recipient struct {
+    theirPublicKey x25519.PublicKey
+}
+
+var _ Recipient = &X25519Recipient{}
+
+// NewX25519Recipient returns a new X25519Recipient from a public key.
+func NewX25519Recipient(pub x25519.PublicKey) (*X25519Recipient, error) {
+    if len(pub) != x25519.PublicKeySize {
+        return nil, errors.New("go-cryptobin/age: invalid X25519 public key")
+    }
+
+    r := &X25519Recipient{
+        theirPublicKey: make(x25519.PublicKey, x25519.PublicKeySize),
+    }
+    copy(r.theirPublicKey, pub)
+
+    return r, nil
+}
+
+// ParseX25519Recipient returns a new X25519Recipient from a Bech32 public key
+// encoding with the "age1" prefix.
+func ParseX25519Recipient(s string) (*X25519Recipient, error) {
+    t, k, err := bech32Decode(s)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: malformed recipient %q: %v", s, err)
+    }
+    if t != "age" {
+        return nil, fmt.Errorf("go-cryptobin/age: malformed recipient %q: invalid type %q", s, t)
+    }
+
+    r, err := NewX25519Recipient(k)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: malformed recipient %q: %v", s, err)
+    }
+
+    return r, nil
+}
+
+func (r *X25519Recipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+    _, ephemeral, err := x25519.GenerateKey(rand.Reader)
+    if err != nil {
+        return nil, err
+    }
+
+    ourPublicKey := ephemeral.Public().(x25519.PublicKey)
+    sharedSecret, err := x25519.X25519(ephemeral.Seed(), r.theirPublicKey)
+    if err != nil {
+        return nil, err
+    }
+
+    l := &Stanza{
+        Type: "X25519",
+        Args: []string{b64.EncodeToString(ourPublicKey)},
+    }
+
+    salt := make([]byte, 0, len(ourPublicKey)+len(r.theirPublicKey))
+    salt = append(salt, ourPublicKey...)
+    salt = append(salt, r.theirPublicKey...)
+
+    wrappingKey, err := hkdf.Key(sha256.New, sharedSecret, salt, []byte(x25519Label), chacha20poly1305.KeySize)
+    if err != nil {
+        return nil, err
+    }
+
+    l.Body, err = aeadEncrypt(wrappingKey, fileKey)
+    if err != nil {
+        return nil, err
+    }
+
+    return []*Stanza{l}, nil
+}
+
+// PublicKey 返回公钥
+func (r *X25519Recipient) PublicKey() x25519.PublicKey {
+    return r.theirPublicKey
+}
+
+// String returns the Bech32 public key encoding of r.
+func (r *X25519Recipient) String() string {
+    s, _ := bech32Encode("age", r.theirPublicKey)
+    return s
+}
+
+// X25519Identity is the standard age private key, which can decrypt messages
+// encrypted to the corresponding X25519Recipient.
+type X25519Identity struct {
+    secretKey    x25519.PrivateKey
+    ourPublicKey x25519.PublicKey
+}
+
+var _ Identity = &X25519Identity{}
+
+// NewX25519Identity returns a new X25519Identity from a private key.
+func NewX25519Identity(priv x25519.PrivateKey) (*X25519Identity, error) {
+    if len(priv) != x25519.PrivateKeySize && len(priv) != x25519.SeedSize {
+        return nil, errors.New("go-cryptobin/age: invalid X25519 private key")
+    }
+
+    secretKey := x25519.NewKeyFromSeed(priv[:x25519.SeedSize])
+
+    return &X25519Identity{
+        secretKey:    secretKey,
+        ourPublicKey: secretKey.Public().(x25519.PublicKey),
+    }, nil
+}
+
+// GenerateX25519Identity randomly generates a new X25519Identity.
+func GenerateX25519Identity() (*X25519Identity, error) {
+    _, priv, err := x25519.GenerateKey(rand.Reader)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: internal error: %v", err)
+    }
+
+    return NewX25519Identity(priv)
+}
+
+// ParseX25519Identity returns a new X25519Identity from a Bech32 private key
+// encoding with the "AGE-SECRET-KEY-1" prefix.
+func ParseX25519Identity(s string) (*X25519Identity, error) {
+    t, k, err := bech32Decode(s)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: malformed secret key: %v", err)
+    }
+    if t != "age-secret-key-" {
+        return nil, fmt.Errorf("go-cryptobin/age: malformed secret key: unknown type %q", t)
+    }
+    if len(k) != x25519.SeedSize {
+        return nil, errors.New("go-cryptobin/age: malformed secret key: invalid length")
+    }
+
+    return NewX25519Identity(k)
+}
+
+func (i *X25519Identity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+    return multiUnwrap(i.unwrap, stanzas)
+}
+
+func (i *X25519Identity) unwrap(block *Stanza) ([]byte, error) {
+    if block.Type != "X25519" {
+        return nil, ErrIncorrectIdentity
+    }
+    if len(block.Args) != 1 {
+        return nil, errors.New("go-cryptobin/age: invalid X25519 recipient block")
+    }
+
+    publicKey, err := b64.DecodeString(block.Args[0])
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: failed to parse X25519 recipient: %v", err)
+    }
+    if len(publicKey) != x25519.PublicKeySize {
+        return nil, errors.New("go-cryptobin/age: invalid X25519 recipient block")
+    }
+
+    sharedSecret, err := x25519.X25519(i.secretKey.Seed(), publicKey)
+    if err != nil {
+        return nil, fmt.Errorf("go-cryptobin/age: invalid X25519 recipient: %v", err)
+    }
+
+    salt := make([]byte, 0, len(publicKey)+len(i.ourPublicKey))
+    salt = append(salt, publicKey...)
+    salt = append(salt, i.ourPublicKey...)
+
+    wrappingKey, err := hkdf.Key(sha256.New, sharedSecret, salt, []byte(x25519Label), chacha20poly1305.KeySize)
+    if err != nil {
+        return nil, err
+    }
+
+    fileKey, err := aeadDecrypt(wrappingKey, fileKeySize, block.Body)
+    if err != nil {
+        return nil, ErrIncorrectIdentity
+    }
+
+    return fileKey, nil
+}
+
+// PrivateKey 返回私钥
+func (i *X25519Identity) PrivateKey() x25519.PrivateKey {
+    return i.secretKey
+}
+
+// Recipient returns the public X25519Recipient value corresponding to i.
+func (i *X25519Identity) Recipient() *X25519Recipient {
+    return &X25519Recipient{
+        theirPublicKey: i.ourPublicKey,
+    }
+}
+
+// String returns the Bech32 private key encoding of i.
+func (i *X25519Identity) String() string {
+    s, _ := bech32Encode("AGE-SECRET-KEY-", i.secretKey.Seed())
+    return strings.ToUpper(s)
+}
@@ -0,0 +1,198 @@
+package bencode
+
+import (
+    "bytes"
+    "errors"
+)
+
+var (
+    errCanonicalLeadingZero = errors.New("leading zero in integer")
+    errCanonicalNegZero     = errors.New("negative zero integer")
+    errCanonicalEmptyInt    = errors.New("empty integer")
+    errCanonicalInvalidInt  = errors.New("invalid integer")
+    errCanonicalStrLen      = errors.New("invalid string length")
+    errCanonicalShortStr    = errors.New("string is longer than the data")
+    errCanonicalKeyType     = errors.New("non-string key in a dict")
+    errCanonicalDupKey      = errors.New("duplicate dict key")
+    errCanonicalUnsorted    = errors.New("dict keys unsorted")
+    errCanonicalMissing     = errors.New("dict elem missing value")
+    errCanonicalUnexpectedE = errors.New("unexpected 'e'")
+    errCanonicalUnknown     = errors.New("unknown value type")
+    errCanonicalTrailing    = errors.New("unused trailing bytes")
+    errCanonicalEOF         = errors.New("unexpected end of data")
+)
+
+// 规范检测时打开的容器
+type canonicalState struct {
+    dict      bool
+    expectKey bool
+    hasKey    bool
+    lastKey   []byte
+}
+
+// ValidateCanonical 检测 data 为一个规范编码的 bencode 值:
+// 整数没有前导零和 -0, 字符串长度没有前导零, 字典的键严格升序并且不重复,
+// 没有多余的数据. 错误为带有字节偏移的 *SyntaxError
+func ValidateCanonical(data []byte) error {
+    var stack []canonicalState
+
+    fail := func(offset int, what error) error {
+        return &SyntaxError{
+            Offset: int64(offset),
+            What:   what,
+        }
+    }
+
+    pos := 0
+    for {
+        if pos >= len(data) {
+            return fail(pos, errCanonicalEOF)
+        }
+
+        start := pos
+        b := data[pos]
+
+        var top *canonicalState
+        if len(stack) > 0 {
+            top = &stack[len(stack)-1]
+        }
+
+        if top != nil && top.dict && top.expectKey && b != 'e' && (b < '0' || b > '9') {
+            return fail(start, errCanonicalKeyType)
+        }
+
+        // 读取到一个完整的值
+        done := false
+
+        switch {
+            case b == 'd' || b == 'l':
+                stack = append(stack, canonicalState{
+                    dict:      b == 'd',
+                    expectKey: b == 'd',
+                })
+                pos++
+            case b == 'e':
+                if top == nil {
+                    return fail(start, errCanonicalUnexpectedE)
+                }
+
+                if top.dict && !top.expectKey {
+                    return fail(start, errCanonicalMissing)
+                }
+
+                stack = stack[:len(stack)-1]
+                pos++
+                done = true
+            case b == 'i':
+                end := bytes.IndexByte(data[pos+1:], 'e')
+                if end < 0 {
+                    return fail(len(data), errCanonicalEOF)
+                }
+
+                if err := checkCanonicalInt(data[pos+1:pos+1+end], true); err != nil {
+                    return fail(start, err)
+                }
+
+                pos += end + 2
+                done = true
+            case b >= '0' && b <= '9':
+                colon := bytes.IndexByte(data[pos:], ':')
+                if colon < 0 {
+                    return fail(len(data), errCanonicalEOF)
+                }
+
+                lenStr := data[pos:pos+colon]
+                if err := checkCanonicalInt(lenStr, false); err != nil {
+                    return fail(start, errCanonicalStrLen)
+                }
+
+                length := 0
+                for _, c := range lenStr {
+                    length = length * 10 + int(c - '0')
+                    if length > len(data) {
+                        return fail(start, errCanonicalShortStr)
+                    }
+                }
+
+                pos += colon + 1
+                if len(data) - pos < length {
+                    return fail(start, errCanonicalShortStr)
+                }
+
+                str := data[pos:pos+length]
+                pos += length
+
+                if top != nil && top.dict && top.expectKey {
+                    if top.hasKey {
+                        switch c := bytes.Compare(str, top.lastKey); {
+                            case c == 0:
+                                return fail(start, errCanonicalDupKey)
+                            case c < 0:
+                                return fail(start, errCanonicalUnsorted)
+                        }
+                    }
+
+                    top.lastKey = str
+                    top.hasKey = true
+                    top.expectKey = false
+
+                    continue
+                }
+
+                done = true
+            default:
+                return fail(start, errCanonicalUnknown)
+        }
+
+        if !done {
+            continue
+        }
+
+        if len(stack) == 0 {
+            if pos != len(data) {
+                return fail(pos, errCanonicalTrailing)
+            }
+
+            return nil
+        }
+
+        if top := &stack[len(stack)-1]; top.dict {
+            top.expectKey = true
+        }
+    }
+}
+
+// 检测整数或者字符串长度的规范格式
+func checkCanonicalInt(b []byte, signed bool) error {
+    if len(b) == 0 {
+        return errCanonicalEmptyInt
+    }
+
+    neg := false
+    if signed && b[0] == '-' {
+        neg = true
+        b = b[1:]
+
+        if len(b) == 0 {
+            return errCanonicalInvalidInt
+        }
+    }
+
+    for _, c := range b {
+        if c < '0' || c > '9' {
+            return errCanonicalInvalidInt
+        }
+    }
+
+    if b[0] == '0' {
+        if neg {
+            return errCanonicalNegZero
+        }
+
+        if len(b) > 1 {
+            return errCanonicalLeadingZero
+        }
+    }
+
+    return nil
+}
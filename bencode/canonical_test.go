@@ -0,0 +1,75 @@
+package bencode
+
+import (
+    "errors"
+    "testing"
+)
+
+func Test_ValidateCanonical(t *testing.T) {
+    valid := []string{
+        "i0e",
+        "i-12e",
+        "0:",
+        "4:spam",
+        "le",
+        "de",
+        "d1:ai1e1:bl1:xi0eee",
+        "d1:a0:2:aad1:x0:ee",
+    }
+
+    for _, s := range valid {
+        if err := ValidateCanonical([]byte(s)); err != nil {
+            t.Errorf("%q: %v", s, err)
+        }
+    }
+
+    invalid := []struct {
+        data   string
+        offset int64
+        what   error
+    }{
+        {"i03e", 0, errCanonicalLeadingZero},
+        {"i-0e", 0, errCanonicalNegZero},
+        {"ie", 0, errCanonicalEmptyInt},
+        {"i1xe", 0, errCanonicalInvalidInt},
+        {"li1e03:abce", 4, errCanonicalStrLen},
+        {"5:abc", 0, errCanonicalShortStr},
+        {"d1:bi1e1:ai2ee", 7, errCanonicalUnsorted},
+        {"d1:ai1e1:ai2ee", 7, errCanonicalDupKey},
+        {"di1ei2ee", 1, errCanonicalKeyType},
+        {"d1:ae", 4, errCanonicalMissing},
+        {"e", 0, errCanonicalUnexpectedE},
+        {"x", 0, errCanonicalUnknown},
+        {"i1ei2e", 3, errCanonicalTrailing},
+        {"l1:a", 4, errCanonicalEOF},
+        {"", 0, errCanonicalEOF},
+    }
+
+    for _, tt := range invalid {
+        err := ValidateCanonical([]byte(tt.data))
+
+        var se *SyntaxError
+        if !errors.As(err, &se) {
+            t.Errorf("%q: want SyntaxError, got %v", tt.data, err)
+            continue
+        }
+
+        if se.Offset != tt.offset || se.What != tt.what {
+            t.Errorf("%q: got %d %v, want %d %v", tt.data, se.Offset, se.What, tt.offset, tt.what)
+        }
+    }
+}
+
+func Test_ValidateCanonical_Marshal(t *testing.T) {
+    data, err := Marshal(map[string]any{
+        "z": 1,
+        "a": []any{"x", -5, map[string]int{"b": 0, "a": 1}},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if err := ValidateCanonical(data); err != nil {
+        t.Errorf("Marshal output not canonical: %v", err)
+    }
+}
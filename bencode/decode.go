@@ -29,6 +29,9 @@ type Decoder struct {
     // Sum of bytes used to Decode values.
     Offset int64
     buf    bytes.Buffer
+
+    // containers opened by Token
+    tokenStack []tokenState
 }
 
 func (d *Decoder) Decode(v any) (err error) {
@@ -72,6 +75,8 @@ func (d *Decoder) Decode(v any) (err error) {
         d.throwSyntaxError(d.Offset-1, errors.New("unexpected 'e'"))
     }
 
+    d.tokenValue()
+
     return
 }
 
@@ -0,0 +1,132 @@
+package bencode
+
+import (
+    "errors"
+    "strings"
+    "net/url"
+    "encoding/hex"
+    "encoding/base32"
+)
+
+// btmh 的 multihash 前缀, sha2-256 和 32 字节长度
+const multihashSHA256 = "1220"
+
+var ErrInvalidMagnet = errors.New("bencode: invalid magnet link")
+
+// 磁力链接, InfoHash 为 v1 的 btih, InfoHashV2 为 v2 的 btmh
+type Magnet struct {
+    InfoHash   []byte
+    InfoHashV2 []byte
+
+    // 显示名称 dn
+    Name     string
+    // tracker 列表 tr
+    Trackers []string
+    // web seed 列表 ws
+    WebSeeds []string
+    // peer 地址 x.pe
+    Peers    []string
+}
+
+// 生成磁力链接
+func (this Magnet) String() string {
+    var params []string
+
+    if len(this.InfoHash) > 0 {
+        params = append(params, "xt=urn:btih:" + hex.EncodeToString(this.InfoHash))
+    }
+
+    if len(this.InfoHashV2) > 0 {
+        params = append(params, "xt=urn:btmh:" + multihashSHA256 + hex.EncodeToString(this.InfoHashV2))
+    }
+
+    if this.Name != "" {
+        params = append(params, "dn=" + url.QueryEscape(this.Name))
+    }
+
+    for _, tr := range this.Trackers {
+        params = append(params, "tr=" + url.QueryEscape(tr))
+    }
+
+    for _, ws := range this.WebSeeds {
+        params = append(params, "ws=" + url.QueryEscape(ws))
+    }
+
+    for _, pe := range this.Peers {
+        params = append(params, "x.pe=" + url.QueryEscape(pe))
+    }
+
+    return "magnet:?" + strings.Join(params, "&")
+}
+
+// 解析磁力链接, 支持 hex 和 base32 格式的 btih
+func ParseMagnet(uri string) (Magnet, error) {
+    if !strings.HasPrefix(uri, "magnet:?") {
+        return Magnet{}, ErrInvalidMagnet
+    }
+
+    query, err := url.ParseQuery(uri[len("magnet:?"):])
+    if err != nil {
+        return Magnet{}, err
+    }
+
+    m := Magnet{
+        Name:     query.Get("dn"),
+        Trackers: query["tr"],
+        WebSeeds: query["ws"],
+        Peers:    query["x.pe"],
+    }
+
+    for _, xt := range query["xt"] {
+        switch {
+            case strings.HasPrefix(xt, "urn:btih:"):
+                m.InfoHash, err = parseBtih(xt[len("urn:btih:"):])
+            case strings.HasPrefix(xt, "urn:btmh:"):
+                m.InfoHashV2, err = parseBtmh(xt[len("urn:btmh:"):])
+        }
+
+        if err != nil {
+            return Magnet{}, err
+        }
+    }
+
+    if m.InfoHash == nil && m.InfoHashV2 == nil {
+        return Magnet{}, ErrInvalidMagnet
+    }
+
+    return m, nil
+}
+
+func parseBtih(s string) ([]byte, error) {
+    switch len(s) {
+        case 40:
+            h, err := hex.DecodeString(s)
+            if err != nil {
+                return nil, ErrInvalidMagnet
+            }
+
+            return h, nil
+        case 32:
+            h, err := base32.StdEncoding.DecodeString(strings.ToUpper(s))
+            if err != nil {
+                return nil, ErrInvalidMagnet
+            }
+
+            return h, nil
+    }
+
+    return nil, ErrInvalidMagnet
+}
+
+func parseBtmh(s string) ([]byte, error) {
+    if len(s) != 68 || !strings.HasPrefix(s, multihashSHA256) {
+        return nil, ErrInvalidMagnet
+    }
+
+    h, err := hex.DecodeString(s[len(multihashSHA256):])
+    if err != nil {
+        return nil, ErrInvalidMagnet
+    }
+
+    return h, nil
+}
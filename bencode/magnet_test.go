@@ -0,0 +1,56 @@
+package bencode
+
+import (
+    "testing"
+    "encoding/hex"
+)
+
+func Test_ParseMagnet(t *testing.T) {
+    uri := "magnet:?xt=urn:btih:631a31dd0a46257d5078c0dee4e66e26f73e42ac" +
+        "&xt=urn:btmh:1220d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb" +
+        "&dn=bittorrent-v1-v2-hybrid-test"
+
+    m, err := ParseMagnet(uri)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if hex.EncodeToString(m.InfoHash) != "631a31dd0a46257d5078c0dee4e66e26f73e42ac" {
+        t.Errorf("btih got %x", m.InfoHash)
+    }
+
+    if hex.EncodeToString(m.InfoHashV2) != "d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb" {
+        t.Errorf("btmh got %x", m.InfoHashV2)
+    }
+
+    if m.Name != "bittorrent-v1-v2-hybrid-test" {
+        t.Errorf("dn got %s", m.Name)
+    }
+
+    if m.String() != uri {
+        t.Errorf("String got %s", m.String())
+    }
+
+    // base32 btih
+    m, err = ParseMagnet("magnet:?xt=urn:btih:MMNDDXIKIYSX2UDYYDPOJZTOE33T4QVM")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if hex.EncodeToString(m.InfoHash) != "631a31dd0a46257d5078c0dee4e66e26f73e42ac" {
+        t.Errorf("base32 btih got %x", m.InfoHash)
+    }
+
+    bad := []string{
+        "http://example.com",
+        "magnet:?dn=name",
+        "magnet:?xt=urn:btih:1234",
+        "magnet:?xt=urn:btmh:1114d8dd32ac93357c368556af3ac1d95c9d76bd0dff",
+    }
+
+    for _, s := range bad {
+        if _, err := ParseMagnet(s); err == nil {
+            t.Errorf("ParseMagnet(%q) want error", s)
+        }
+    }
+}
@@ -29,6 +29,35 @@ type MultipleTorrent struct {
     Encoding     string          `bencode:"encoding,omitempty"`
     // 备注的utf-8编码
     CommentUtf8  string          `bencode:"comment.utf-8,omitempty"`
+
+    // 解析时 info 的原始数据, 有数据时用于计算 info hash
+    // 修改 Info 后需要清空, 或者使用 SetInfo 设置
+    InfoBytes    RawMessage `bencode:"-"`
+}
+
+// 解析并保留 info 的原始数据
+func (this *MultipleTorrent) UnmarshalBencode(b []byte) error {
+    type torrent MultipleTorrent
+
+    var t torrent
+    raw, err := unmarshalWithInfo(b, &t)
+    if err != nil {
+        return err
+    }
+
+    *this = MultipleTorrent(t)
+    this.InfoBytes = raw
+
+    return nil
+}
+
+// info 的编码数据
+func (this MultipleTorrent) infoData() ([]byte, error) {
+    if len(this.InfoBytes) > 0 {
+        return this.InfoBytes, nil
+    }
+
+    return Marshal(this.Info)
 }
 
 // 获取备用节点
@@ -61,9 +90,18 @@ func (this MultipleTorrent) SetCreationDateTime(t time.Time) MultipleTorrent {
     return this
 }
 
+// 设置详情, 同时清空解析时 info 的原始数据
+func (this MultipleTorrent) SetInfo(info MultipleInfo) MultipleTorrent {
+    this.Info = info
+    this.InfoBytes = nil
+
+    return this
+}
+
 // 生成 info hash
+// 有 InfoBytes 时使用原始数据计算, 修改 Info 后需先调用 SetInfo 或清空 InfoBytes
 func (this MultipleTorrent) GetInfoHash() ([20]byte, error) {
-    data, err := Marshal(this.Info)
+    data, err := this.infoData()
     if err != nil {
         return [20]byte{}, err
     }
@@ -74,6 +112,7 @@ func (this MultipleTorrent) GetInfoHash() ([20]byte, error) {
 }
 
 // 生成 info hash 字符
+// 有 InfoBytes 时使用原始数据计算, 修改 Info 后需先调用 SetInfo 或清空 InfoBytes
 func (this MultipleTorrent) GetInfoHashString() string {
     sum, err := this.GetInfoHash()
     if err != nil {
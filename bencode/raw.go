@@ -0,0 +1,50 @@
+package bencode
+
+import (
+    "errors"
+)
+
+// 原始的 bencode 编码数据, 解析时保留原始字节,
+// 可用于保持 info 的原始数据来计算 info hash
+type RawMessage []byte
+
+var (
+    _ Unmarshaler = (*RawMessage)(nil)
+    _ Marshaler   = RawMessage{}
+)
+
+// Marshal RawMessage
+func (m RawMessage) MarshalBencode() ([]byte, error) {
+    if len(m) == 0 {
+        return nil, errors.New("bencode: marshalled RawMessage should not be zero-length")
+    }
+
+    return m, nil
+}
+
+// Unmarshal RawMessage
+func (m *RawMessage) UnmarshalBencode(b []byte) error {
+    if m == nil {
+        return errors.New("bencode: UnmarshalBencode on nil pointer")
+    }
+
+    *m = append((*m)[0:0], b...)
+    return nil
+}
+
+// 解析种子数据, 同时返回 info 的原始数据
+func unmarshalWithInfo(b []byte, v any) (RawMessage, error) {
+    if err := Unmarshal(b, v); err != nil {
+        return nil, err
+    }
+
+    var raw struct {
+        Info RawMessage `bencode:"info"`
+    }
+
+    if err := Unmarshal(b, &raw); err != nil {
+        return nil, err
+    }
+
+    return raw.Info, nil
+}
@@ -0,0 +1,139 @@
+package bencode
+
+import (
+    "bytes"
+    "testing"
+    "crypto/sha1"
+)
+
+func Test_RawMessage(t *testing.T) {
+    var v struct {
+        A RawMessage `bencode:"a"`
+        B int        `bencode:"b"`
+    }
+
+    data := []byte("d1:ad1:xi1e1:yl1:zee1:bi2ee")
+    if err := Unmarshal(data, &v); err != nil {
+        t.Fatal(err)
+    }
+
+    if string(v.A) != "d1:xi1e1:yl1:zee" || v.B != 2 {
+        t.Fatalf("got %q, %d", v.A, v.B)
+    }
+
+    out, err := Marshal(v)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(out, data) {
+        t.Errorf("got %s, want %s", out, data)
+    }
+
+    if _, err := Marshal(RawMessage{}); err == nil {
+        t.Error("empty RawMessage want error")
+    }
+}
+
+func Test_Torrent_InfoBytes(t *testing.T) {
+    // info 中有未知的键, 重新编码后 hash 会不同
+    info := "d6:lengthi10e4:name5:a.txt12:piece lengthi16384e6:pieces20:01234567890123456789" +
+        "7:unknownl1:x1:yee"
+    data := []byte("d8:announce3:url4:info" + info + "e")
+    want := sha1.Sum([]byte(info))
+
+    var st SingleTorrent
+    if err := Unmarshal(data, &st); err != nil {
+        t.Fatal(err)
+    }
+
+    if st.Info.Name != "a.txt" || st.Announce != "url" {
+        t.Fatalf("unexpected torrent %+v", st)
+    }
+
+    if h, _ := st.GetInfoHash(); h != want {
+        t.Errorf("SingleTorrent info hash got %x, want %x", h, want)
+    }
+
+    var tt Torrent
+    if err := Unmarshal(data, &tt); err != nil {
+        t.Fatal(err)
+    }
+
+    if h, _ := tt.GetInfoHash(); h != want {
+        t.Errorf("Torrent info hash got %x, want %x", h, want)
+    }
+
+    // 没有原始数据时重新编码
+    tt.InfoBytes = nil
+    if h, _ := tt.GetInfoHash(); h == want {
+        t.Error("info hash want re-encoded info")
+    }
+}
+
+func Test_Torrent_SetInfo(t *testing.T) {
+    info := "d6:lengthi10e4:name5:a.txt12:piece lengthi16384e6:pieces20:01234567890123456789e"
+    data := []byte("d8:announce3:url4:info" + info + "e")
+    old := sha1.Sum([]byte(info))
+
+    check := func(name string, h [20]byte, v any) {
+        enc, err := Marshal(v)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if want := sha1.Sum(enc); h != want {
+            t.Errorf("%s info hash got %x, want %x", name, h, want)
+        }
+        if h == old {
+            t.Errorf("%s info hash not changed", name)
+        }
+    }
+
+    var st SingleTorrent
+    if err := Unmarshal(data, &st); err != nil {
+        t.Fatal(err)
+    }
+
+    // 直接修改 Info 时仍然使用原始数据
+    st.Info.Name = "b.txt"
+    if h, _ := st.GetInfoHash(); h != old {
+        t.Errorf("SingleTorrent info hash got %x, want %x", h, old)
+    }
+
+    st = st.SetInfo(st.Info)
+    h, _ := st.GetInfoHash()
+    check("SingleTorrent", h, st.Info)
+
+    var mt MultipleTorrent
+    if err := Unmarshal([]byte("d8:announce3:url4:infod5:filesld6:lengthi10e4:pathl5:a.txteee" +
+        "4:name1:d12:piece lengthi16384e6:pieces20:01234567890123456789ee"), &mt); err != nil {
+        t.Fatal(err)
+    }
+
+    mi := mt.Info
+    mi.Name = "e"
+    mt = mt.SetInfo(mi)
+    h, _ = mt.GetInfoHash()
+    check("MultipleTorrent", h, mt.Info)
+
+    var tt Torrent
+    if err := Unmarshal(data, &tt); err != nil {
+        t.Fatal(err)
+    }
+
+    ti := tt.Info
+    ti.Name = "b.txt"
+    tt = tt.SetInfo(ti)
+    h, _ = tt.GetInfoHash()
+    check("Torrent", h, tt.Info)
+
+    m, err := tt.GetMagnet()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(m.InfoHash, h[:]) {
+        t.Errorf("magnet info hash got %x, want %x", m.InfoHash, h)
+    }
+}
@@ -0,0 +1,149 @@
+package bencode
+
+import (
+    "io"
+    "errors"
+    "runtime"
+)
+
+// Token 为 Delim, int64, *big.Int 或者 string
+type Token any
+
+// 容器的开始和结束, 'd', 'l' 和 'e'
+type Delim byte
+
+func (d Delim) String() string {
+    return string(d)
+}
+
+// 正在读取的容器
+type tokenState struct {
+    dict      bool
+    expectKey bool
+}
+
+// Token 返回输入中的下一个 token, 输入结束时返回 io.EOF.
+// 容器返回 Delim('d') 或者 Delim('l') 开始, Delim('e') 结束,
+// 字典中的键和值依次返回. 可以和 Decode 混用, Decode 读取下一个完整的值.
+func (d *Decoder) Token() (tok Token, err error) {
+    // 读取整数和字符串时的错误为 panic
+    defer func() {
+        r := recover()
+        if r == nil {
+            return
+        }
+
+        if _, ok := r.(runtime.Error); ok {
+            panic(r)
+        }
+
+        e, ok := r.(error)
+        if !ok {
+            panic(r)
+        }
+
+        tok, err = nil, d.tokenEOF(e)
+    }()
+
+    b, err := d.r.ReadByte()
+    if err != nil {
+        return nil, d.tokenEOF(err)
+    }
+
+    start := d.Offset
+    d.Offset++
+
+    top := d.tokenTop()
+    if top != nil && top.dict && top.expectKey && b != 'e' && (b < '0' || b > '9') {
+        return nil, &SyntaxError{
+            Offset: start,
+            What:   errors.New("non-string key in a dict"),
+        }
+    }
+
+    switch b {
+        case 'd', 'l':
+            d.tokenStack = append(d.tokenStack, tokenState{
+                dict:      b == 'd',
+                expectKey: b == 'd',
+            })
+
+            return Delim(b), nil
+        case 'e':
+            if top == nil {
+                return nil, &SyntaxError{
+                    Offset: start,
+                    What:   errors.New("unexpected 'e'"),
+                }
+            }
+
+            if top.dict && !top.expectKey {
+                return nil, &SyntaxError{
+                    Offset: start,
+                    What:   errors.New("dict elem missing value"),
+                }
+            }
+
+            d.tokenStack = d.tokenStack[:len(d.tokenStack)-1]
+            d.tokenValue()
+
+            return Delim('e'), nil
+        case 'i':
+            d.buf.Reset()
+            tok = d.parseIntInterface()
+            d.tokenValue()
+
+            return tok, nil
+        default:
+            if b >= '0' && b <= '9' {
+                d.buf.Reset()
+                d.buf.WriteByte(b)
+
+                tok = d.parseStringInterface()
+                d.tokenValue()
+
+                return tok, nil
+            }
+
+            return nil, &SyntaxError{
+                Offset: start,
+                What:   errors.New("unknown value type " + string([]byte{b})),
+            }
+    }
+}
+
+// More 返回当前容器中是否还有值
+func (d *Decoder) More() bool {
+    b, err := d.r.ReadByte()
+    if err != nil {
+        return false
+    }
+
+    d.r.UnreadByte()
+
+    return b != 'e'
+}
+
+// 容器中的 EOF 为 io.ErrUnexpectedEOF
+func (d *Decoder) tokenEOF(err error) error {
+    if err == io.EOF && len(d.tokenStack) > 0 {
+        return io.ErrUnexpectedEOF
+    }
+
+    return err
+}
+
+func (d *Decoder) tokenTop() *tokenState {
+    if len(d.tokenStack) == 0 {
+        return nil
+    }
+
+    return &d.tokenStack[len(d.tokenStack)-1]
+}
+
+// 读取一个完整的值后, 字典中切换键和值
+func (d *Decoder) tokenValue() {
+    if top := d.tokenTop(); top != nil && top.dict {
+        top.expectKey = !top.expectKey
+    }
+}
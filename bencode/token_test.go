@@ -0,0 +1,133 @@
+package bencode
+
+import (
+    "io"
+    "bytes"
+    "errors"
+    "testing"
+    "reflect"
+)
+
+func Test_Decoder_Token(t *testing.T) {
+    d := NewDecoder(bytes.NewReader([]byte("d1:ai-3e1:bl3:abci7ee1:cdee")))
+
+    var toks []Token
+    for {
+        tok, err := d.Token()
+        if err == io.EOF {
+            break
+        }
+
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        toks = append(toks, tok)
+    }
+
+    want := []Token{
+        Delim('d'),
+        "a", int64(-3),
+        "b", Delim('l'), "abc", int64(7), Delim('e'),
+        "c", Delim('d'), Delim('e'),
+        Delim('e'),
+    }
+
+    if !reflect.DeepEqual(toks, want) {
+        t.Errorf("got %v, want %v", toks, want)
+    }
+
+    if d.Offset != 27 {
+        t.Errorf("Offset got %d", d.Offset)
+    }
+}
+
+func Test_Decoder_TokenDecode(t *testing.T) {
+    d := NewDecoder(bytes.NewReader([]byte("ld1:xi1eed1:xi2eee")))
+
+    tok, err := d.Token()
+    if err != nil || tok != Delim('l') {
+        t.Fatalf("got %v, %v", tok, err)
+    }
+
+    var xs []int
+    for d.More() {
+        var v struct {
+            X int `bencode:"x"`
+        }
+
+        if err := d.Decode(&v); err != nil {
+            t.Fatal(err)
+        }
+
+        xs = append(xs, v.X)
+    }
+
+    if tok, err := d.Token(); err != nil || tok != Delim('e') {
+        t.Fatalf("got %v, %v", tok, err)
+    }
+
+    if !reflect.DeepEqual(xs, []int{1, 2}) {
+        t.Errorf("got %v", xs)
+    }
+
+    // 字典的值用 Decode 读取
+    d = NewDecoder(bytes.NewReader([]byte("d1:ali1ei2ee1:bi3ee")))
+    d.Token()
+
+    var key string
+    var list []int
+    if err := d.Decode(&key); err != nil || key != "a" {
+        t.Fatalf("got %q, %v", key, err)
+    }
+
+    if err := d.Decode(&list); err != nil || len(list) != 2 {
+        t.Fatalf("got %v, %v", list, err)
+    }
+
+    if tok, _ := d.Token(); tok != "b" {
+        t.Errorf("got %v", tok)
+    }
+}
+
+func Test_Decoder_TokenErrors(t *testing.T) {
+    tests := []struct {
+        data   string
+        offset int64
+    }{
+        {"di1ei2ee", 1},
+        {"d1:ae", 4},
+        {"e", 0},
+        {"l1:ax", 4},
+    }
+
+    for _, tt := range tests {
+        d := NewDecoder(bytes.NewReader([]byte(tt.data)))
+
+        var err error
+        for err == nil {
+            _, err = d.Token()
+        }
+
+        var se *SyntaxError
+        if !errors.As(err, &se) {
+            t.Errorf("%q: want SyntaxError, got %v", tt.data, err)
+            continue
+        }
+
+        if se.Offset != tt.offset {
+            t.Errorf("%q: offset got %d, want %d", tt.data, se.Offset, tt.offset)
+        }
+    }
+
+    d := NewDecoder(bytes.NewReader([]byte("l1:a")))
+
+    var err error
+    for err == nil {
+        _, err = d.Token()
+    }
+
+    if err != io.ErrUnexpectedEOF {
+        t.Errorf("want io.ErrUnexpectedEOF, got %v", err)
+    }
+}
@@ -0,0 +1,510 @@
+package bencode
+
+import (
+    "sort"
+    "time"
+    "errors"
+    "crypto/sha1"
+    "crypto/sha256"
+    "encoding/hex"
+)
+
+// 种子版本
+type TorrentVersion int
+
+const (
+    // BEP 3 种子
+    TorrentV1 TorrentVersion = 1 + iota
+    // BEP 52 种子
+    TorrentV2
+    // 同时包含 v1 和 v2 信息的混合种子
+    TorrentHybrid
+)
+
+func (v TorrentVersion) String() string {
+    switch v {
+        case TorrentV1:
+            return "v1"
+        case TorrentV2:
+            return "v2"
+        case TorrentHybrid:
+            return "hybrid"
+        default:
+            return "unknown"
+    }
+}
+
+var (
+    ErrTorrentNotV1      = errors.New("bencode: torrent has no v1 info")
+    ErrTorrentNotV2      = errors.New("bencode: torrent has no v2 info")
+    ErrPieceLayerMissing = errors.New("bencode: piece layer is missing")
+    ErrPieceLayerInvalid = errors.New("bencode: piece layer does not match pieces root")
+)
+
+// 通用种子, 支持 v1, v2 (BEP 52) 和混合种子
+type Torrent struct {
+    // tracker服务器的URL 字符串
+    Announce     string            `bencode:"announce,omitempty"`
+    // 备用tracker服务器列表 列表
+    AnnounceList [][]string        `bencode:"announce-list,omitempty"`
+    // 种子的创建时间 整数
+    CreatDate    int64             `bencode:"creation date,omitempty"`
+    // 备注 字符串
+    Comment      string            `bencode:"comment,omitempty"`
+    // 创建者 字符串
+    CreatedBy    string            `bencode:"created by,omitempty"`
+    // 详情
+    Info         TorrentInfo       `bencode:"info"`
+    // v2 文件的分片层, pieces root 映射该文件分片的 SHA-256 hash
+    PieceLayers  map[string]string `bencode:"piece layers,omitempty"`
+    // web seed 列表
+    URLList      []string          `bencode:"url-list,omitempty"`
+
+    // 解析时 info 的原始数据, 有数据时用于计算 info hash
+    // 修改 Info 后需要清空, 或者使用 SetInfo 设置
+    InfoBytes    RawMessage        `bencode:"-"`
+}
+
+// 解析并保留 info 的原始数据
+func (this *Torrent) UnmarshalBencode(b []byte) error {
+    type torrent Torrent
+
+    var t torrent
+    raw, err := unmarshalWithInfo(b, &t)
+    if err != nil {
+        return err
+    }
+
+    *this = Torrent(t)
+    this.InfoBytes = raw
+
+    return nil
+}
+
+// info 的编码数据
+func (this Torrent) infoData() ([]byte, error) {
+    if len(this.InfoBytes) > 0 {
+        return this.InfoBytes, nil
+    }
+
+    return Marshal(this.Info)
+}
+
+// 获取备用节点
+func (this Torrent) GetAnnounceList() []string {
+    announceList := []string{}
+
+    for _, v := range this.AnnounceList {
+        announceList = append(announceList, v...)
+    }
+
+    return announceList
+}
+
+// 获取格式化后的创建时间
+func (this Torrent) GetCreationDateTime(tz ...string) time.Time {
+    timezone := "Local"
+    if len(tz) > 0 {
+        timezone = tz[0]
+    }
+
+    loc, _ := time.LoadLocation(timezone)
+
+    return time.Unix(this.CreatDate, 0).In(loc)
+}
+
+// 设置创建时间
+func (this Torrent) SetCreationDateTime(t time.Time) Torrent {
+    this.CreatDate = t.Unix()
+
+    return this
+}
+
+// 设置详情, 同时清空解析时 info 的原始数据
+func (this Torrent) SetInfo(info TorrentInfo) Torrent {
+    this.Info = info
+    this.InfoBytes = nil
+
+    return this
+}
+
+// 种子版本
+func (this Torrent) GetVersion() TorrentVersion {
+    return this.Info.GetVersion()
+}
+
+// 生成 v1 info hash, 为 info 的 SHA-1
+// 有 InfoBytes 时使用原始数据计算, 修改 Info 后需先调用 SetInfo 或清空 InfoBytes
+func (this Torrent) GetInfoHash() ([20]byte, error) {
+    if !this.Info.HasV1() {
+        return [20]byte{}, ErrTorrentNotV1
+    }
+
+    data, err := this.infoData()
+    if err != nil {
+        return [20]byte{}, err
+    }
+
+    return sha1.Sum(data), nil
+}
+
+// 生成 v1 info hash 字符
+// 有 InfoBytes 时使用原始数据计算, 修改 Info 后需先调用 SetInfo 或清空 InfoBytes
+func (this Torrent) GetInfoHashString() string {
+    sum, err := this.GetInfoHash()
+    if err != nil {
+        return ""
+    }
+
+    return hex.EncodeToString(sum[:])
+}
+
+// 生成 v2 info hash, 为 info 的 SHA-256
+// 和 tracker 及 DHT 通信时使用前 20 字节
+// 有 InfoBytes 时使用原始数据计算, 修改 Info 后需先调用 SetInfo 或清空 InfoBytes
+func (this Torrent) GetInfoHashV2() ([32]byte, error) {
+    if !this.Info.HasV2() {
+        return [32]byte{}, ErrTorrentNotV2
+    }
+
+    data, err := this.infoData()
+    if err != nil {
+        return [32]byte{}, err
+    }
+
+    return sha256.Sum256(data), nil
+}
+
+// 生成 v2 info hash 字符
+// 有 InfoBytes 时使用原始数据计算, 修改 Info 后需先调用 SetInfo 或清空 InfoBytes
+func (this Torrent) GetInfoHashV2String() string {
+    sum, err := this.GetInfoHashV2()
+    if err != nil {
+        return ""
+    }
+
+    return hex.EncodeToString(sum[:])
+}
+
+// 获取文件的分片层
+func (this Torrent) GetPieceLayer(piecesRoot string) ([][32]byte, error) {
+    layer, ok := this.PieceLayers[piecesRoot]
+    if !ok {
+        return nil, ErrPieceLayerMissing
+    }
+
+    if len(layer) % sha256.Size != 0 {
+        return nil, ErrPieceLayerInvalid
+    }
+
+    hashes := make([][32]byte, len(layer) / sha256.Size)
+    for i := range hashes {
+        copy(hashes[i][:], layer[i*sha256.Size:])
+    }
+
+    return hashes, nil
+}
+
+// 检测大于一个分片的文件都有分片层, 并且分片层的 merkle 根为 pieces root
+func (this Torrent) CheckPieceLayers() error {
+    if !this.Info.HasV2() {
+        return ErrTorrentNotV2
+    }
+
+    pieceLength := int64(this.Info.PieceLength)
+    if !validPieceLengthV2(this.Info.PieceLength) {
+        return ErrInvalidPieceLength
+    }
+
+    pad := padHashV2(this.Info.PieceLength)
+
+    for _, f := range this.Info.GetFileTreeFiles() {
+        if f.Length <= pieceLength {
+            continue
+        }
+
+        layer, err := this.GetPieceLayer(f.PiecesRoot)
+        if err != nil {
+            return err
+        }
+
+        if int64(len(layer)) != (f.Length + pieceLength - 1) / pieceLength {
+            return ErrPieceLayerInvalid
+        }
+
+        root := merkleRootV2(layer, nextPowerOfTwo(len(layer)), pad)
+        if string(root[:]) != f.PiecesRoot {
+            return ErrPieceLayerInvalid
+        }
+    }
+
+    return nil
+}
+
+// 生成磁力链接
+// 有 InfoBytes 时使用原始数据计算, 修改 Info 后需先调用 SetInfo 或清空 InfoBytes
+func (this Torrent) GetMagnet() (Magnet, error) {
+    m := Magnet{
+        Name:     this.Info.Name,
+        WebSeeds: this.URLList,
+    }
+
+    if this.Announce != "" {
+        m.Trackers = append(m.Trackers, this.Announce)
+    }
+
+    for _, tr := range this.GetAnnounceList() {
+        if tr != this.Announce {
+            m.Trackers = append(m.Trackers, tr)
+        }
+    }
+
+    if this.Info.HasV1() {
+        h, err := this.GetInfoHash()
+        if err != nil {
+            return Magnet{}, err
+        }
+
+        m.InfoHash = h[:]
+    }
+
+    if this.Info.HasV2() {
+        h, err := this.GetInfoHashV2()
+        if err != nil {
+            return Magnet{}, err
+        }
+
+        m.InfoHashV2 = h[:]
+    }
+
+    return m, nil
+}
+
+// v1 文件信息, attr 为 "p" 时为混合种子的填充文件
+type TorrentFile struct {
+    Attr   string   `bencode:"attr,omitempty"`
+    Length int64    `bencode:"length"`
+    Path   []string `bencode:"path"`
+}
+
+// 是否为填充文件
+func (this TorrentFile) IsPadding() bool {
+    for _, c := range this.Attr {
+        if c == 'p' {
+            return true
+        }
+    }
+
+    return false
+}
+
+// 通用种子信息
+type TorrentInfo struct {
+    // 名称 字符串
+    Name        string        `bencode:"name"`
+    // 每个块的大小，单位字节 整数
+    PieceLength int           `bencode:"piece length"`
+
+    // v2 版本, 为 2
+    MetaVersion int           `bencode:"meta version,omitempty"`
+    // v2 文件树
+    FileTree    FileTree      `bencode:"file tree,omitempty"`
+
+    // v1 每个块的20个字节的SHA1 Hash的值(二进制格式)
+    Pieces      string        `bencode:"pieces,omitempty"`
+    // v1 单文件长度
+    Length      int64         `bencode:"length,omitempty"`
+    // v1 多文件信息
+    Files       []TorrentFile `bencode:"files,omitempty"`
+
+    Private     bool          `bencode:"private,omitempty"`
+}
+
+// 是否有 v1 信息
+func (this TorrentInfo) HasV1() bool {
+    return this.Pieces != "" || this.Files != nil || this.Length > 0
+}
+
+// 是否有 v2 信息
+func (this TorrentInfo) HasV2() bool {
+    return this.MetaVersion == 2
+}
+
+// 种子版本
+func (this TorrentInfo) GetVersion() TorrentVersion {
+    switch {
+        case this.HasV2() && this.HasV1():
+            return TorrentHybrid
+        case this.HasV2():
+            return TorrentV2
+        default:
+            return TorrentV1
+    }
+}
+
+// 每个分片的 SHA-1 hash 长度是20 把他们从Pieces中切出来
+func (this TorrentInfo) GetPieceHashes() ([][20]byte, error) {
+    return splitPieceHashes(this.Pieces)
+}
+
+// 返回 v1 文件数据列表, 不包含填充文件
+func (this TorrentInfo) GetFileList() []FileInfo {
+    var fileInfo []FileInfo
+
+    if this.Files == nil {
+        return []FileInfo{
+            {
+                Path:   "/" + this.Name,
+                Length: int(this.Length),
+            },
+        }
+    }
+
+    for _, v := range this.Files {
+        if v.IsPadding() {
+            continue
+        }
+
+        path := ""
+        for _, p := range v.Path {
+            path += "/" + p
+        }
+
+        fileInfo = append(fileInfo, FileInfo{
+            Path:   path,
+            Length: int(v.Length),
+        })
+    }
+
+    return fileInfo
+}
+
+// 按文件树的顺序返回 v2 文件列表
+func (this TorrentInfo) GetFileTreeFiles() []FileTreeEntry {
+    var files []FileTreeEntry
+    this.FileTree.walk(nil, func(path []string, f *FileTreeFile) {
+        files = append(files, FileTreeEntry{
+            Path:       path,
+            Length:     f.Length,
+            PiecesRoot: f.PiecesRoot,
+        })
+    })
+
+    return files
+}
+
+// v2 文件树中的文件
+type FileTreeEntry struct {
+    Path       []string
+    Length     int64
+    PiecesRoot string
+}
+
+// v2 文件树的文件信息, 空文件没有 pieces root
+type FileTreeFile struct {
+    Length     int64  `bencode:"length"`
+    PiecesRoot string `bencode:"pieces root,omitempty"`
+}
+
+// v2 文件树, 名称映射子节点
+type FileTree map[string]FileTreeNode
+
+// 文件树节点, 文件时 File 不为空, 否则为目录
+type FileTreeNode struct {
+    File *FileTreeFile
+    Dir  FileTree
+}
+
+var (
+    _ Unmarshaler = (*FileTreeNode)(nil)
+    _ Marshaler   = FileTreeNode{}
+)
+
+// 文件编码为 {"": {...}}
+func (this FileTreeNode) MarshalBencode() ([]byte, error) {
+    if this.File != nil {
+        return Marshal(map[string]*FileTreeFile{
+            "": this.File,
+        })
+    }
+
+    if this.Dir == nil {
+        return []byte("de"), nil
+    }
+
+    return Marshal(this.Dir)
+}
+
+func (this *FileTreeNode) UnmarshalBencode(b []byte) error {
+    var m map[string]Bytes
+    if err := Unmarshal(b, &m); err != nil {
+        return err
+    }
+
+    if raw, ok := m[""]; ok {
+        f := &FileTreeFile{}
+        if err := Unmarshal(raw, f); err != nil {
+            return err
+        }
+
+        this.File = f
+        this.Dir = nil
+
+        return nil
+    }
+
+    this.File = nil
+    this.Dir = make(FileTree, len(m))
+
+    for name, raw := range m {
+        var node FileTreeNode
+        if err := Unmarshal(raw, &node); err != nil {
+            return err
+        }
+
+        this.Dir[name] = node
+    }
+
+    return nil
+}
+
+// 按名称顺序遍历文件
+func (this FileTree) walk(path []string, fn func(path []string, f *FileTreeFile)) {
+    names := make([]string, 0, len(this))
+    for name := range this {
+        names = append(names, name)
+    }
+
+    sort.Strings(names)
+
+    for _, name := range names {
+        node := this[name]
+
+        p := append(append([]string(nil), path...), name)
+        if node.File != nil {
+            fn(p, node.File)
+        } else {
+            node.Dir.walk(p, fn)
+        }
+    }
+}
+
+// 添加文件
+func (this FileTree) add(path []string, f *FileTreeFile) {
+    if len(path) == 1 {
+        this[path[0]] = FileTreeNode{
+            File: f,
+        }
+        return
+    }
+
+    node, ok := this[path[0]]
+    if !ok || node.Dir == nil {
+        node = FileTreeNode{
+            Dir: make(FileTree),
+        }
+        this[path[0]] = node
+    }
+
+    node.Dir.add(path[1:], f)
+}
@@ -0,0 +1,438 @@
+package bencode
+
+import (
+    "io"
+    "os"
+    "path"
+    "sync"
+    "time"
+    "errors"
+    "runtime"
+    "strconv"
+    "io/fs"
+    "crypto/sha1"
+    "path/filepath"
+)
+
+var (
+    ErrInvalidPieceLength = errors.New("bencode: piece length must be a power of two and at least 16KiB")
+    ErrNoTorrentFiles     = errors.New("bencode: no files to build torrent")
+    ErrInvalidVersion     = errors.New("bencode: invalid torrent version")
+)
+
+// 自动分片大小的范围和分片数
+const (
+    minAutoPieceLength = 16 << 10
+    maxAutoPieceLength = 16 << 20
+    autoPieceCount     = 1500
+)
+
+// 种子生成器, 遍历文件并发计算分片 hash, 生成 v1, v2 或者混合种子
+type TorrentBuilder struct {
+    // 名称, 为空时使用文件或者目录名
+    Name         string
+    // 分片大小, 为 0 时按文件大小选择
+    PieceLength  int
+    // 种子版本, 为 0 时生成混合种子
+    Version      TorrentVersion
+
+    Announce     string
+    AnnounceList [][]string
+    URLList      []string
+    Comment      string
+    CreatedBy    string
+    CreationDate time.Time
+    Private      bool
+
+    // 计算 hash 的协程数, 为 0 时为 GOMAXPROCS
+    Workers      int
+}
+
+// 从本地文件或者目录生成种子
+func (this TorrentBuilder) Build(name string) (*Torrent, error) {
+    stat, err := os.Stat(name)
+    if err != nil {
+        return nil, err
+    }
+
+    name = filepath.Clean(name)
+    if this.Name == "" {
+        this.Name = filepath.Base(name)
+    }
+
+    if stat.IsDir() {
+        return this.BuildFS(os.DirFS(name), ".")
+    }
+
+    return this.BuildFS(os.DirFS(filepath.Dir(name)), filepath.Base(name))
+}
+
+type builderFile struct {
+    name   string
+    path   []string
+    length int64
+}
+
+// 从 fsys 中的 root 文件或者目录生成种子
+func (this TorrentBuilder) BuildFS(fsys fs.FS, root string) (*Torrent, error) {
+    version := this.Version
+    if version == 0 {
+        version = TorrentHybrid
+    }
+
+    if version != TorrentV1 && version != TorrentV2 && version != TorrentHybrid {
+        return nil, ErrInvalidVersion
+    }
+
+    name := this.Name
+    if name == "" {
+        name = path.Base(root)
+    }
+
+    files, single, err := this.walkFiles(fsys, root, name)
+    if err != nil {
+        return nil, err
+    }
+
+    var total int64
+    for _, f := range files {
+        total += f.length
+    }
+
+    pieceLength := this.PieceLength
+    if pieceLength == 0 {
+        pieceLength = autoPieceLength(total)
+    }
+
+    if !validPieceLengthV2(pieceLength) {
+        return nil, ErrInvalidPieceLength
+    }
+
+    h := newTorrentHasher(this.Workers, pieceLength, version)
+
+    for i, f := range files {
+        last := i == len(files) - 1
+
+        if err := h.addFile(fsys, f, last); err != nil {
+            h.wait()
+            return nil, err
+        }
+    }
+
+    h.finish()
+
+    t := &Torrent{
+        Announce:     this.Announce,
+        AnnounceList: this.AnnounceList,
+        Comment:      this.Comment,
+        CreatedBy:    this.CreatedBy,
+        URLList:      this.URLList,
+        Info:         TorrentInfo{
+            Name:        name,
+            PieceLength: pieceLength,
+            Private:     this.Private,
+        },
+    }
+
+    if !this.CreationDate.IsZero() {
+        t.CreatDate = this.CreationDate.Unix()
+    }
+
+    if version != TorrentV2 {
+        pieces := make([]byte, 0, len(h.pieces) * sha1.Size)
+        for _, p := range h.pieces {
+            pieces = append(pieces, p[:]...)
+        }
+
+        t.Info.Pieces = string(pieces)
+
+        if single {
+            t.Info.Length = files[0].length
+        } else {
+            t.Info.Files = h.v1Files
+        }
+    }
+
+    if version != TorrentV1 {
+        t.Info.MetaVersion = 2
+        t.Info.FileTree = make(FileTree)
+        t.PieceLayers = make(map[string]string)
+
+        for i, f := range files {
+            file := &FileTreeFile{
+                Length: f.length,
+            }
+
+            if f.length > 0 {
+                root := h.fileRoot(i)
+                file.PiecesRoot = string(root[:])
+
+                if f.length > int64(pieceLength) {
+                    layer := make([]byte, 0, len(h.layers[i]) * 32)
+                    for _, p := range h.layers[i] {
+                        layer = append(layer, p[:]...)
+                    }
+
+                    t.PieceLayers[file.PiecesRoot] = string(layer)
+                }
+            }
+
+            t.Info.FileTree.add(f.path, file)
+        }
+    }
+
+    return t, nil
+}
+
+// 遍历普通文件, 按文件树顺序返回
+func (this TorrentBuilder) walkFiles(fsys fs.FS, root string, name string) ([]builderFile, bool, error) {
+    stat, err := fs.Stat(fsys, root)
+    if err != nil {
+        return nil, false, err
+    }
+
+    if stat.Mode().IsRegular() {
+        return []builderFile{
+            {
+                name:   root,
+                path:   []string{name},
+                length: stat.Size(),
+            },
+        }, true, nil
+    }
+
+    var files []builderFile
+    err = fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+
+        if !d.Type().IsRegular() {
+            return nil
+        }
+
+        info, err := d.Info()
+        if err != nil {
+            return err
+        }
+
+        rel := p
+        if root != "." {
+            rel = p[len(root)+1:]
+        }
+
+        files = append(files, builderFile{
+            name:   p,
+            path:   splitPath(rel),
+            length: info.Size(),
+        })
+
+        return nil
+    })
+    if err != nil {
+        return nil, false, err
+    }
+
+    if len(files) == 0 {
+        return nil, false, ErrNoTorrentFiles
+    }
+
+    return files, false, nil
+}
+
+func splitPath(p string) []string {
+    var parts []string
+    for {
+        dir, file := path.Split(p)
+        parts = append([]string{file}, parts...)
+
+        if dir == "" {
+            return parts
+        }
+
+        p = dir[:len(dir)-1]
+    }
+}
+
+// 按总大小选择分片大小
+func autoPieceLength(total int64) int {
+    pieceLength := minAutoPieceLength
+    for pieceLength < maxAutoPieceLength && total / int64(pieceLength) > autoPieceCount {
+        pieceLength <<= 1
+    }
+
+    return pieceLength
+}
+
+// 并发计算 v1 分片和 v2 文件分片层
+type torrentHasher struct {
+    sem chan struct{}
+    wg  sync.WaitGroup
+
+    pieceLength int
+    v1, v2      bool
+    hybrid      bool
+
+    // v1 分片跨文件, 数据先写入 buf
+    buf     []byte
+    pieces  [][20]byte
+    v1Files []TorrentFile
+
+    // v2 每个文件的分片 hash, 单分片文件为文件的 merkle 根
+    layers [][][32]byte
+    mu     sync.Mutex
+}
+
+func newTorrentHasher(workers int, pieceLength int, version TorrentVersion) *torrentHasher {
+    if workers <= 0 {
+        workers = runtime.GOMAXPROCS(0)
+    }
+
+    return &torrentHasher{
+        sem:         make(chan struct{}, workers),
+        pieceLength: pieceLength,
+        v1:          version != TorrentV2,
+        v2:          version != TorrentV1,
+        hybrid:      version == TorrentHybrid,
+    }
+}
+
+// 在协程中执行, 同时运行的任务不超过 workers 个
+func (this *torrentHasher) run(fn func()) {
+    this.sem <- struct{}{}
+    this.wg.Add(1)
+
+    go func() {
+        defer func() {
+            <-this.sem
+            this.wg.Done()
+        }()
+
+        fn()
+    }()
+}
+
+func (this *torrentHasher) wait() {
+    this.wg.Wait()
+}
+
+// 添加 v1 分片数据
+func (this *torrentHasher) writeV1(data []byte) {
+    for len(data) > 0 {
+        if this.buf == nil {
+            this.buf = make([]byte, 0, this.pieceLength)
+        }
+
+        n := copy(this.buf[len(this.buf):this.pieceLength], data)
+        this.buf = this.buf[:len(this.buf)+n]
+        data = data[n:]
+
+        if len(this.buf) == this.pieceLength {
+            this.flushV1()
+        }
+    }
+}
+
+func (this *torrentHasher) flushV1() {
+    if len(this.buf) == 0 {
+        return
+    }
+
+    piece := this.buf
+    this.buf = nil
+
+    this.mu.Lock()
+    index := len(this.pieces)
+    this.pieces = append(this.pieces, [20]byte{})
+    this.mu.Unlock()
+
+    this.run(func() {
+        sum := sha1.Sum(piece)
+
+        this.mu.Lock()
+        this.pieces[index] = sum
+        this.mu.Unlock()
+    })
+}
+
+func (this *torrentHasher) addFile(fsys fs.FS, f builderFile, last bool) error {
+    file, err := fsys.Open(f.name)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    pieceLength := int64(this.pieceLength)
+    numPieces := int((f.length + pieceLength - 1) / pieceLength)
+
+    layer := make([][32]byte, numPieces)
+    this.layers = append(this.layers, layer)
+
+    for i := 0; i < numPieces; i++ {
+        size := pieceLength
+        if rest := f.length - int64(i) * pieceLength; rest < size {
+            size = rest
+        }
+
+        data := make([]byte, size)
+        if _, err := io.ReadFull(file, data); err != nil {
+            return err
+        }
+
+        if this.v1 {
+            this.writeV1(data)
+        }
+
+        if this.v2 {
+            index := i
+            width := this.pieceLength / BlockSizeV2
+            if numPieces == 1 {
+                // 小文件的树只填充到 2 的幂个叶子
+                width = nextPowerOfTwo((int(size) + BlockSizeV2 - 1) / BlockSizeV2)
+            }
+
+            this.run(func() {
+                layer[index] = merkleRootV2(blockHashesV2(data), width, [32]byte{})
+            })
+        }
+    }
+
+    if this.v1 {
+        this.v1Files = append(this.v1Files, TorrentFile{
+            Length: f.length,
+            Path:   f.path,
+        })
+
+        // 混合种子的文件需要对齐到分片
+        if rest := f.length % pieceLength; this.hybrid && !last && rest != 0 {
+            pad := pieceLength - rest
+
+            this.writeV1(make([]byte, pad))
+            this.v1Files = append(this.v1Files, TorrentFile{
+                Attr:   "p",
+                Length: pad,
+                Path:   []string{".pad", strconv.FormatInt(pad, 10)},
+            })
+        }
+    }
+
+    return nil
+}
+
+func (this *torrentHasher) finish() {
+    if this.v1 {
+        this.flushV1()
+    }
+
+    this.wait()
+}
+
+// 文件的 pieces root
+func (this *torrentHasher) fileRoot(i int) [32]byte {
+    layer := this.layers[i]
+    if len(layer) == 1 {
+        return layer[0]
+    }
+
+    return merkleRootV2(layer, nextPowerOfTwo(len(layer)), padHashV2(this.pieceLength))
+}
@@ -0,0 +1,75 @@
+package bencode
+
+import (
+    "crypto/sha256"
+)
+
+// v2 merkle 树的叶子块大小
+const BlockSizeV2 = 16 << 10
+
+// v2 分片大小需为 2 的幂并且不小于 16KiB
+func validPieceLengthV2(pieceLength int) bool {
+    return pieceLength >= BlockSizeV2 && pieceLength & (pieceLength - 1) == 0
+}
+
+func nextPowerOfTwo(n int) int {
+    p := 1
+    for p < n {
+        p <<= 1
+    }
+
+    return p
+}
+
+// 计算 merkle 根, 叶子不足 width 时用 pad 填充, width 为 2 的幂
+func merkleRootV2(leaves [][32]byte, width int, pad [32]byte) [32]byte {
+    layer := make([][32]byte, width)
+    n := copy(layer, leaves)
+    for i := n; i < width; i++ {
+        layer[i] = pad
+    }
+
+    var buf [64]byte
+    for len(layer) > 1 {
+        for i := 0; i < len(layer) / 2; i++ {
+            copy(buf[:32], layer[2*i][:])
+            copy(buf[32:], layer[2*i+1][:])
+            layer[i] = sha256.Sum256(buf[:])
+        }
+
+        layer = layer[:len(layer) / 2]
+    }
+
+    return layer[0]
+}
+
+// 分片层的填充 hash, 为全零叶子的分片子树的根
+func padHashV2(pieceLength int) [32]byte {
+    var h [32]byte
+    var buf [64]byte
+
+    for n := pieceLength / BlockSizeV2; n > 1; n >>= 1 {
+        copy(buf[:32], h[:])
+        copy(buf[32:], h[:])
+        h = sha256.Sum256(buf[:])
+    }
+
+    return h
+}
+
+// 计算数据块的叶子 hash, 最后一块不填充
+func blockHashesV2(data []byte) [][32]byte {
+    leaves := make([][32]byte, 0, (len(data) + BlockSizeV2 - 1) / BlockSizeV2)
+
+    for len(data) > 0 {
+        n := BlockSizeV2
+        if n > len(data) {
+            n = len(data)
+        }
+
+        leaves = append(leaves, sha256.Sum256(data[:n]))
+        data = data[n:]
+    }
+
+    return leaves
+}
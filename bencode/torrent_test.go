@@ -0,0 +1,447 @@
+package bencode
+
+import (
+    "os"
+    "bytes"
+    "testing"
+    "math/rand"
+    "crypto/sha1"
+    "crypto/sha256"
+    "path/filepath"
+    "testing/fstest"
+)
+
+func testTorrentData(seed int64, n int) []byte {
+    data := make([]byte, n)
+    rand.New(rand.NewSource(seed)).Read(data)
+
+    return data
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+    return sha256.Sum256(append(a[:], b[:]...))
+}
+
+func testTorrentFS() fstest.MapFS {
+    return fstest.MapFS{
+        "dir/b.txt":     {Data: testTorrentData(1, 80 << 10)},
+        "dir/a.txt":     {Data: testTorrentData(2, 3000)},
+        "dir/sub/c.bin": {Data: testTorrentData(3, 40 << 10)},
+        "dir/empty":     {Data: []byte{}},
+    }
+}
+
+func Test_TorrentBuilder_V2Merkle(t *testing.T) {
+    fsys := testTorrentFS()
+
+    b := TorrentBuilder{
+        PieceLength: 32 << 10,
+        Version:     TorrentV2,
+    }
+
+    tt, err := b.BuildFS(fsys, "dir")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if tt.Info.Name != "dir" || tt.GetVersion() != TorrentV2 || tt.Info.Pieces != "" {
+        t.Fatalf("unexpected info %+v", tt.Info)
+    }
+
+    files := tt.Info.GetFileTreeFiles()
+    if len(files) != 4 {
+        t.Fatalf("got %d files", len(files))
+    }
+
+    wantOrder := []string{"a.txt", "b.txt", "empty", "sub/c.bin"}
+    for i, f := range files {
+        p := filepath.ToSlash(filepath.Join(f.Path...))
+        if p != wantOrder[i] {
+            t.Errorf("file %d got %s, want %s", i, p, wantOrder[i])
+        }
+    }
+
+    // a.txt is one block
+    a := fsys["dir/a.txt"].Data
+    if root := sha256.Sum256(a); files[0].PiecesRoot != string(root[:]) {
+        t.Errorf("a.txt root got %x, want %x", files[0].PiecesRoot, root)
+    }
+
+    // b.txt is 5 blocks in 3 pieces
+    bd := fsys["dir/b.txt"].Data
+    var leaves [5][32]byte
+    for i := range leaves {
+        leaves[i] = sha256.Sum256(bd[i*BlockSizeV2:(i+1)*BlockSizeV2])
+    }
+
+    var zero [32]byte
+    pieces := [][32]byte{
+        hashPair(leaves[0], leaves[1]),
+        hashPair(leaves[2], leaves[3]),
+        hashPair(leaves[4], zero),
+    }
+    root := hashPair(hashPair(pieces[0], pieces[1]), hashPair(pieces[2], hashPair(zero, zero)))
+
+    if files[1].PiecesRoot != string(root[:]) {
+        t.Errorf("b.txt root got %x, want %x", files[1].PiecesRoot, root)
+    }
+
+    layer, err := tt.GetPieceLayer(files[1].PiecesRoot)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for i := range pieces {
+        if layer[i] != pieces[i] {
+            t.Errorf("b.txt piece %d got %x, want %x", i, layer[i], pieces[i])
+        }
+    }
+
+    // empty files have no root, files up to a piece have no layer
+    if files[2].PiecesRoot != "" || files[2].Length != 0 {
+        t.Errorf("empty file got %+v", files[2])
+    }
+
+    if len(tt.PieceLayers) != 2 {
+        t.Errorf("got %d piece layers, want 2", len(tt.PieceLayers))
+    }
+
+    if err := tt.CheckPieceLayers(); err != nil {
+        t.Error(err)
+    }
+
+    tt.PieceLayers[files[1].PiecesRoot] = string(make([]byte, 96))
+    if err := tt.CheckPieceLayers(); err != ErrPieceLayerInvalid {
+        t.Errorf("want ErrPieceLayerInvalid, got %v", err)
+    }
+}
+
+func Test_TorrentBuilder_Hybrid(t *testing.T) {
+    fsys := testTorrentFS()
+
+    b := TorrentBuilder{
+        PieceLength: 32 << 10,
+        Announce:    "http://tracker.example.com/announce",
+        Comment:     "hybrid",
+        Workers:     3,
+    }
+
+    tt, err := b.BuildFS(fsys, "dir")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if tt.GetVersion() != TorrentHybrid {
+        t.Fatalf("got version %s", tt.GetVersion())
+    }
+
+    // files are aligned to pieces with padding files
+    var stream []byte
+    var names []string
+    for _, f := range tt.Info.Files {
+        if f.IsPadding() {
+            stream = append(stream, make([]byte, f.Length)...)
+            continue
+        }
+
+        p := "dir/" + filepath.ToSlash(filepath.Join(f.Path...))
+        names = append(names, p)
+        stream = append(stream, fsys[p].Data...)
+    }
+
+    if len(names) != 4 || len(tt.Info.Files) != 6 {
+        t.Fatalf("unexpected files %+v", tt.Info.Files)
+    }
+
+    hashes, err := tt.Info.GetPieceHashes()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    pieceLength := tt.Info.PieceLength
+    if len(hashes) != (len(stream) + pieceLength - 1) / pieceLength {
+        t.Fatalf("got %d pieces", len(hashes))
+    }
+
+    for i := range hashes {
+        end := (i + 1) * pieceLength
+        if end > len(stream) {
+            end = len(stream)
+        }
+
+        if sha1.Sum(stream[i*pieceLength:end]) != hashes[i] {
+            t.Errorf("piece %d is wrong", i)
+        }
+    }
+
+    if len(tt.Info.GetFileList()) != 4 {
+        t.Errorf("GetFileList got %+v", tt.Info.GetFileList())
+    }
+
+    // the same result with one worker
+    b.Workers = 1
+    tt2, err := b.BuildFS(fsys, "dir")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if tt.GetInfoHashV2String() != tt2.GetInfoHashV2String() {
+        t.Error("info hash depends on workers")
+    }
+
+    // round trip
+    data, err := Marshal(tt)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var parsed Torrent
+    if err := Unmarshal(data, &parsed); err != nil {
+        t.Fatal(err)
+    }
+
+    if parsed.GetInfoHashString() != tt.GetInfoHashString() ||
+        parsed.GetInfoHashV2String() != tt.GetInfoHashV2String() {
+        t.Error("info hash changed after round trip")
+    }
+
+    if err := parsed.CheckPieceLayers(); err != nil {
+        t.Error(err)
+    }
+
+    data2, _ := Marshal(parsed)
+    if !bytes.Equal(data, data2) {
+        t.Error("Marshal after Unmarshal got different data")
+    }
+
+    // info hashes are the hashes of the info dict
+    info, _ := Marshal(tt.Info)
+    h1, _ := tt.GetInfoHash()
+    h2, _ := tt.GetInfoHashV2()
+    if h1 != sha1.Sum(info) || h2 != sha256.Sum256(info) {
+        t.Error("wrong info hash")
+    }
+}
+
+func Test_TorrentBuilder_V1(t *testing.T) {
+    fsys := testTorrentFS()
+
+    b := TorrentBuilder{
+        PieceLength: 16 << 10,
+        Version:     TorrentV1,
+    }
+
+    tt, err := b.BuildFS(fsys, "dir")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if tt.GetVersion() != TorrentV1 || tt.Info.FileTree != nil || tt.PieceLayers != nil {
+        t.Fatalf("unexpected torrent %+v", tt.Info)
+    }
+
+    if _, err := tt.GetInfoHashV2(); err != ErrTorrentNotV2 {
+        t.Errorf("want ErrTorrentNotV2, got %v", err)
+    }
+
+    // v1 pieces span files without padding
+    var stream []byte
+    for _, p := range []string{"dir/a.txt", "dir/b.txt", "dir/empty", "dir/sub/c.bin"} {
+        stream = append(stream, fsys[p].Data...)
+    }
+
+    hashes, _ := tt.Info.GetPieceHashes()
+    last := len(hashes) - 1
+    if sha1.Sum(stream[last*16<<10:]) != hashes[last] {
+        t.Error("last piece is wrong")
+    }
+
+    // decodes as MultipleTorrent too
+    data, _ := Marshal(tt)
+
+    var mt MultipleTorrent
+    if err := Unmarshal(data, &mt); err != nil {
+        t.Fatal(err)
+    }
+
+    if mt.GetInfoHashString() != tt.GetInfoHashString() {
+        t.Error("info hash differs from MultipleTorrent")
+    }
+}
+
+func Test_TorrentBuilder_Single(t *testing.T) {
+    dir := t.TempDir()
+    name := filepath.Join(dir, "single.bin")
+    data := testTorrentData(4, 100 << 10)
+
+    if err := os.WriteFile(name, data, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    tt, err := TorrentBuilder{PieceLength: 64 << 10}.Build(name)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if tt.Info.Name != "single.bin" || tt.Info.Length != int64(len(data)) || tt.Info.Files != nil {
+        t.Fatalf("unexpected info %+v", tt.Info)
+    }
+
+    files := tt.Info.GetFileTreeFiles()
+    if len(files) != 1 || files[0].Path[0] != "single.bin" {
+        t.Fatalf("unexpected file tree %+v", files)
+    }
+
+    if err := tt.CheckPieceLayers(); err != nil {
+        t.Error(err)
+    }
+
+    // a directory builds the same file tree under the dir name
+    td, err := TorrentBuilder{PieceLength: 64 << 10}.Build(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dfiles := td.Info.GetFileTreeFiles()
+    if len(dfiles) != 1 || dfiles[0].PiecesRoot != files[0].PiecesRoot {
+        t.Errorf("unexpected file tree %+v", dfiles)
+    }
+}
+
+func Test_TorrentBuilder_Errors(t *testing.T) {
+    fsys := testTorrentFS()
+
+    if _, err := (TorrentBuilder{PieceLength: 20000}).BuildFS(fsys, "dir"); err != ErrInvalidPieceLength {
+        t.Errorf("want ErrInvalidPieceLength, got %v", err)
+    }
+
+    if _, err := (TorrentBuilder{Version: 7}).BuildFS(fsys, "dir"); err != ErrInvalidVersion {
+        t.Errorf("want ErrInvalidVersion, got %v", err)
+    }
+
+    fsys["none/.keep"] = &fstest.MapFile{Mode: os.ModeSymlink}
+    if _, err := (TorrentBuilder{}).BuildFS(fsys, "none"); err != ErrNoTorrentFiles {
+        t.Errorf("want ErrNoTorrentFiles, got %v", err)
+    }
+
+    if got := autoPieceLength(1 << 30); got != 1 << 20 {
+        t.Errorf("autoPieceLength got %d", got)
+    }
+}
+
+func Test_Torrent_Magnet(t *testing.T) {
+    b := TorrentBuilder{
+        Name:         "test torrent",
+        PieceLength:  16 << 10,
+        Announce:     "http://tracker.example.com/announce",
+        AnnounceList: [][]string{{"http://tracker.example.com/announce"}, {"udp://tracker.example.org:6969"}},
+        URLList:      []string{"https://example.com/files/"},
+    }
+
+    tt, err := b.BuildFS(testTorrentFS(), "dir")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m, err := tt.GetMagnet()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    uri := m.String()
+
+    want := "magnet:?xt=urn:btih:" + tt.GetInfoHashString() +
+        "&xt=urn:btmh:1220" + tt.GetInfoHashV2String() +
+        "&dn=test+torrent" +
+        "&tr=http%3A%2F%2Ftracker.example.com%2Fannounce" +
+        "&tr=udp%3A%2F%2Ftracker.example.org%3A6969" +
+        "&ws=https%3A%2F%2Fexample.com%2Ffiles%2F"
+    if uri != want {
+        t.Errorf("got %s, want %s", uri, want)
+    }
+
+    parsed, err := ParseMagnet(uri)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(parsed.InfoHash, m.InfoHash) ||
+        !bytes.Equal(parsed.InfoHashV2, m.InfoHashV2) ||
+        parsed.Name != m.Name ||
+        len(parsed.Trackers) != 2 ||
+        len(parsed.WebSeeds) != 1 {
+        t.Errorf("unexpected magnet %+v", parsed)
+    }
+}
+
+// 种子来自 libtorrent 的 test/test_torrents
+func Test_Torrent_LibtorrentFixtures(t *testing.T) {
+    tests := []struct {
+        file    string
+        version TorrentVersion
+        hash    string
+        hashV2  string
+        magnet  string
+    }{
+        {
+            "bittorrent-v2-test.torrent",
+            TorrentV2,
+            "",
+            "caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105232b28ad099f3a302e",
+            "magnet:?xt=urn:btmh:1220caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105232b28ad099f3a302e" +
+                "&dn=bittorrent-v2-test",
+        },
+        {
+            "bittorrent-v2-hybrid-test.torrent",
+            TorrentHybrid,
+            "631a31dd0a46257d5078c0dee4e66e26f73e42ac",
+            "d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb",
+            "magnet:?xt=urn:btih:631a31dd0a46257d5078c0dee4e66e26f73e42ac" +
+                "&xt=urn:btmh:1220d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb" +
+                "&dn=bittorrent-v1-v2-hybrid-test",
+        },
+    }
+
+    for _, td := range tests {
+        t.Run(td.file, func(t *testing.T) {
+            data, err := os.ReadFile(filepath.Join("testdata", td.file))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            var tt Torrent
+            if err := Unmarshal(data, &tt); err != nil {
+                t.Fatal(err)
+            }
+
+            if v := tt.GetVersion(); v != td.version {
+                t.Errorf("version got %s, want %s", v, td.version)
+            }
+
+            if td.hash == "" {
+                if _, err := tt.GetInfoHash(); err != ErrTorrentNotV1 {
+                    t.Errorf("GetInfoHash want ErrTorrentNotV1, got %v", err)
+                }
+            } else if got := tt.GetInfoHashString(); got != td.hash {
+                t.Errorf("GetInfoHash got %s, want %s", got, td.hash)
+            }
+
+            if got := tt.GetInfoHashV2String(); got != td.hashV2 {
+                t.Errorf("GetInfoHashV2 got %s, want %s", got, td.hashV2)
+            }
+
+            if err := tt.CheckPieceLayers(); err != nil {
+                t.Error(err)
+            }
+
+            m, err := tt.GetMagnet()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if got := m.String(); got != td.magnet {
+                t.Errorf("magnet got %s, want %s", got, td.magnet)
+            }
+        })
+    }
+}
@@ -0,0 +1,462 @@
+// Package aegis implements the AEGIS family of AEAD ciphers as specified
+// in draft-irtf-cfrg-aegis-aead: AEGIS-128L, AEGIS-256 and the multi-lane
+// AEGIS-128X and AEGIS-256X.
+package aegis
+
+import (
+    "errors"
+    "runtime"
+    "strconv"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+    "github.com/deatil/go-cryptobin/tool/aesround"
+)
+
+const (
+    // KeySize128L is the size in bytes of AEGIS-128L and AEGIS-128X keys and nonces.
+    KeySize128L = 16
+
+    // KeySize256 is the size in bytes of AEGIS-256 and AEGIS-256X keys and nonces.
+    KeySize256 = 32
+
+    // TagSize is the default tag size. 32 bytes tags are also supported.
+    TagSize = 16
+
+    blockSize = 16
+
+    // 最大状态: 8 个分组, 4 路
+    maxLanes     = 4
+    maxStateSize = 8 * maxLanes * blockSize
+)
+
+var c0 = [blockSize]byte{
+    0x00, 0x01, 0x01, 0x02, 0x03, 0x05, 0x08, 0x0d,
+    0x15, 0x22, 0x37, 0x59, 0x90, 0xe9, 0x79, 0x62,
+}
+
+var c1 = [blockSize]byte{
+    0xdb, 0x3d, 0x18, 0x55, 0x6d, 0xc2, 0x2f, 0xf1,
+    0x20, 0x11, 0x31, 0x42, 0x73, 0xb5, 0x28, 0xdd,
+}
+
+var (
+    errOpen = errors.New("go-cryptobin/aegis: message authentication failed")
+    errKeySize = errors.New("go-cryptobin/aegis: invalid key size")
+    errTagSize = errors.New("go-cryptobin/aegis: tag size must be 16 or 32")
+    errDegree = errors.New("go-cryptobin/aegis: degree must be 1, 2 or 4")
+)
+
+type aegis struct {
+    key   []byte
+    is256 bool
+    lanes int
+
+    tagSize int
+}
+
+// New128L returns AEGIS-128L with the 16 bytes key.
+func New128L(key []byte, tagSize int) (cipher.AEAD, error) {
+    return New128X(key, 1, tagSize)
+}
+
+// New256 returns AEGIS-256 with the 32 bytes key.
+func New256(key []byte, tagSize int) (cipher.AEAD, error) {
+    return New256X(key, 1, tagSize)
+}
+
+// New128X returns AEGIS-128X with the degree of parallelism 2 or 4.
+// The degree 1 is AEGIS-128L.
+func New128X(key []byte, degree, tagSize int) (cipher.AEAD, error) {
+    if len(key) != KeySize128L {
+        return nil, errKeySize
+    }
+
+    return newAEGIS(key, false, degree, tagSize)
+}
+
+// New256X returns AEGIS-256X with the degree of parallelism 2 or 4.
+// The degree 1 is AEGIS-256.
+func New256X(key []byte, degree, tagSize int) (cipher.AEAD, error) {
+    if len(key) != KeySize256 {
+        return nil, errKeySize
+    }
+
+    return newAEGIS(key, true, degree, tagSize)
+}
+
+func newAEGIS(key []byte, is256 bool, degree, tagSize int) (cipher.AEAD, error) {
+    if degree != 1 && degree != 2 && degree != 4 {
+        return nil, errDegree
+    }
+
+    if tagSize != 16 && tagSize != 32 {
+        return nil, errTagSize
+    }
+
+    a := &aegis{
+        key:     append([]byte(nil), key...),
+        is256:   is256,
+        lanes:   degree,
+        tagSize: tagSize,
+    }
+
+    return a, nil
+}
+
+func (a *aegis) NonceSize() int {
+    return len(a.key)
+}
+
+func (a *aegis) Overhead() int {
+    return a.tagSize
+}
+
+func (a *aegis) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+    if len(nonce) != a.NonceSize() {
+        panic("go-cryptobin/aegis: incorrect nonce length: " + strconv.Itoa(len(nonce)))
+    }
+
+    ret, out := alias.SliceForAppend(dst, len(plaintext) + a.tagSize)
+    if alias.InexactOverlap(out, plaintext) {
+        panic("go-cryptobin/aegis: invalid buffer overlap")
+    }
+
+    s := a.newState(nonce)
+    s.absorb(additionalData)
+    s.encrypt(out[:len(plaintext)], plaintext)
+    s.finalize(out[len(plaintext):], len(additionalData), len(plaintext))
+
+    return ret
+}
+
+func (a *aegis) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+    if len(nonce) != a.NonceSize() {
+        panic("go-cryptobin/aegis: incorrect nonce length: " + strconv.Itoa(len(nonce)))
+    }
+
+    if len(ciphertext) < a.tagSize {
+        return nil, errOpen
+    }
+
+    tag := ciphertext[len(ciphertext)-a.tagSize:]
+    ciphertext = ciphertext[:len(ciphertext)-a.tagSize]
+
+    ret, out := alias.SliceForAppend(dst, len(ciphertext))
+    if alias.InexactOverlap(out, ciphertext) {
+        panic("go-cryptobin/aegis: invalid buffer overlap")
+    }
+
+    s := a.newState(nonce)
+    s.absorb(additionalData)
+    s.decrypt(out, ciphertext)
+
+    expectedTag := make([]byte, a.tagSize)
+    s.finalize(expectedTag, len(additionalData), len(ciphertext))
+
+    if subtle.ConstantTimeCompare(expectedTag, tag) != 1 {
+        for i := range out {
+            out[i] = 0
+        }
+
+        runtime.KeepAlive(out)
+        return nil, errOpen
+    }
+
+    return ret, nil
+}
+
+func (a *aegis) newState(nonce []byte) *state {
+    s := &state{
+        lanes: a.lanes,
+    }
+
+    if a.is256 {
+        s.blocks = 6
+        s.rate = 1
+        s.init256(a.key, nonce)
+    } else {
+        s.blocks = 8
+        s.rate = 2
+        s.init128(a.key, nonce)
+    }
+
+    return s
+}
+
+type state struct {
+    s   [maxStateSize]byte
+    tmp [maxStateSize]byte
+
+    // 分组数, 每个分组宽度为 lanes * 16 字节
+    blocks int
+    lanes  int
+
+    // 每次处理的分组数, 128L 为 2, 256 为 1
+    rate int
+}
+
+func (s *state) width() int {
+    return s.lanes * blockSize
+}
+
+func (s *state) block(i int) []byte {
+    w := s.width()
+    return s.s[i*w:(i+1)*w]
+}
+
+// 每路重复 16 字节
+func (s *state) repeat(dst, b []byte) {
+    for i := 0; i < s.lanes; i++ {
+        copy(dst[i*blockSize:], b)
+    }
+}
+
+// 路标识 ctx_i = i || D-1
+func (s *state) xorCtx(dst []byte) {
+    if s.lanes == 1 {
+        return
+    }
+
+    for i := 0; i < s.lanes; i++ {
+        dst[i*blockSize] ^= byte(i)
+        dst[i*blockSize+1] ^= byte(s.lanes - 1)
+    }
+}
+
+// S'_i = AESRound(S_{i-1}, S_i), 消息异或到 S_0 和 S_4 (128L) 或 S_0 (256)
+func (s *state) update(m []byte) {
+    w := s.width()
+    size := s.blocks * w
+
+    copy(s.tmp[:w], s.s[size-w:size])
+    copy(s.tmp[w:size], s.s[:size-w])
+
+    subtle.XORBytes(s.s[:w], s.s[:w], m[:w])
+    if s.rate == 2 {
+        subtle.XORBytes(s.s[4*w:5*w], s.s[4*w:5*w], m[w:2*w])
+    }
+
+    aesround.Rounds(s.s[:size], s.tmp[:size], s.s[:size])
+}
+
+func (s *state) init128(key, nonce []byte) {
+    var kn, kc0, kc1 [blockSize]byte
+    subtle.XORBytes(kn[:], key, nonce)
+    subtle.XORBytes(kc0[:], key, c0[:])
+    subtle.XORBytes(kc1[:], key, c1[:])
+
+    s.repeat(s.block(0), kn[:])
+    s.repeat(s.block(1), c1[:])
+    s.repeat(s.block(2), c0[:])
+    s.repeat(s.block(3), c1[:])
+    s.repeat(s.block(4), kn[:])
+    s.repeat(s.block(5), kc0[:])
+    s.repeat(s.block(6), kc1[:])
+    s.repeat(s.block(7), kc0[:])
+
+    w := s.width()
+
+    var m [2 * maxLanes * blockSize]byte
+    s.repeat(m[:w], nonce)
+    s.repeat(m[w:], key)
+
+    for i := 0; i < 10; i++ {
+        s.xorCtx(s.block(3))
+        s.xorCtx(s.block(7))
+        s.update(m[:2*w])
+    }
+}
+
+func (s *state) init256(key, nonce []byte) {
+    k0, k1 := key[:16], key[16:]
+    n0, n1 := nonce[:16], nonce[16:]
+
+    var kn0, kn1, kc0, kc1 [blockSize]byte
+    subtle.XORBytes(kn0[:], k0, n0)
+    subtle.XORBytes(kn1[:], k1, n1)
+    subtle.XORBytes(kc0[:], k0, c0[:])
+    subtle.XORBytes(kc1[:], k1, c1[:])
+
+    s.repeat(s.block(0), kn0[:])
+    s.repeat(s.block(1), kn1[:])
+    s.repeat(s.block(2), c1[:])
+    s.repeat(s.block(3), c0[:])
+    s.repeat(s.block(4), kc0[:])
+    s.repeat(s.block(5), kc1[:])
+
+    w := s.width()
+
+    var m [4][maxLanes * blockSize]byte
+    s.repeat(m[0][:w], k0)
+    s.repeat(m[1][:w], k1)
+    s.repeat(m[2][:w], kn0[:])
+    s.repeat(m[3][:w], kn1[:])
+
+    for i := 0; i < 4; i++ {
+        for j := 0; j < 4; j++ {
+            s.xorCtx(s.block(3))
+            s.xorCtx(s.block(5))
+            s.update(m[j][:w])
+        }
+    }
+}
+
+// 密钥流
+func (s *state) keystream(z []byte) {
+    w := s.width()
+
+    if s.rate == 2 {
+        // z0 = S6 ^ S1 ^ (S2 & S3), z1 = S2 ^ S5 ^ (S6 & S7)
+        s1, s2, s3 := s.block(1), s.block(2), s.block(3)
+        s5, s6, s7 := s.block(5), s.block(6), s.block(7)
+
+        for i := 0; i < w; i += 8 {
+            x1 := binary.LittleEndian.Uint64(s1[i:])
+            x2 := binary.LittleEndian.Uint64(s2[i:])
+            x3 := binary.LittleEndian.Uint64(s3[i:])
+            x5 := binary.LittleEndian.Uint64(s5[i:])
+            x6 := binary.LittleEndian.Uint64(s6[i:])
+            x7 := binary.LittleEndian.Uint64(s7[i:])
+
+            binary.LittleEndian.PutUint64(z[i:], x6 ^ x1 ^ (x2 & x3))
+            binary.LittleEndian.PutUint64(z[w+i:], x2 ^ x5 ^ (x6 & x7))
+        }
+    } else {
+        // z = S1 ^ S4 ^ S5 ^ (S2 & S3)
+        s1, s2, s3 := s.block(1), s.block(2), s.block(3)
+        s4, s5 := s.block(4), s.block(5)
+
+        for i := 0; i < w; i += 8 {
+            x1 := binary.LittleEndian.Uint64(s1[i:])
+            x2 := binary.LittleEndian.Uint64(s2[i:])
+            x3 := binary.LittleEndian.Uint64(s3[i:])
+            x4 := binary.LittleEndian.Uint64(s4[i:])
+            x5 := binary.LittleEndian.Uint64(s5[i:])
+
+            binary.LittleEndian.PutUint64(z[i:], x1 ^ x4 ^ x5 ^ (x2 & x3))
+        }
+    }
+}
+
+func (s *state) rateSize() int {
+    return s.rate * s.width()
+}
+
+func (s *state) absorb(ad []byte) {
+    r := s.rateSize()
+
+    for len(ad) >= r {
+        s.update(ad[:r])
+        ad = ad[r:]
+    }
+
+    if len(ad) > 0 {
+        var buf [2 * maxLanes * blockSize]byte
+        copy(buf[:], ad)
+        s.update(buf[:r])
+    }
+}
+
+func (s *state) encrypt(dst, src []byte) {
+    r := s.rateSize()
+
+    var z, buf [2 * maxLanes * blockSize]byte
+
+    for len(src) >= r {
+        s.keystream(z[:r])
+
+        // 先保存明文, dst 可能与 src 相同
+        copy(buf[:r], src[:r])
+        subtle.XORBytes(dst[:r], src[:r], z[:r])
+        s.update(buf[:r])
+
+        src, dst = src[r:], dst[r:]
+    }
+
+    if len(src) > 0 {
+        s.keystream(z[:r])
+
+        buf = [len(buf)]byte{}
+        copy(buf[:], src)
+        subtle.XORBytes(dst, src, z[:len(src)])
+        s.update(buf[:r])
+    }
+}
+
+func (s *state) decrypt(dst, src []byte) {
+    r := s.rateSize()
+
+    var z, buf [2 * maxLanes * blockSize]byte
+
+    for len(src) >= r {
+        s.keystream(z[:r])
+        subtle.XORBytes(dst[:r], src[:r], z[:r])
+        s.update(dst[:r])
+
+        src, dst = src[r:], dst[r:]
+    }
+
+    if len(src) > 0 {
+        s.keystream(z[:r])
+
+        buf = [len(buf)]byte{}
+        subtle.XORBytes(buf[:len(src)], src, z[:len(src)])
+        copy(dst, buf[:len(src)])
+        s.update(buf[:r])
+    }
+}
+
+func (s *state) finalize(tag []byte, adLen, msgLen int) {
+    w := s.width()
+
+    var u [blockSize]byte
+    binary.LittleEndian.PutUint64(u[0:], uint64(adLen) * 8)
+    binary.LittleEndian.PutUint64(u[8:], uint64(msgLen) * 8)
+
+    // 128L 为 S2, 256 为 S3
+    idx := 2
+    if s.rate == 1 {
+        idx = 3
+    }
+
+    var t [2 * maxLanes * blockSize]byte
+    s.repeat(t[:w], u[:])
+    subtle.XORBytes(t[:w], t[:w], s.block(idx))
+    copy(t[w:2*w], t[:w])
+
+    for i := 0; i < 7; i++ {
+        s.update(t[:s.rateSize()])
+    }
+
+    for i := range tag {
+        tag[i] = 0
+    }
+
+    if len(tag) == 16 {
+        // 128L 为 S0 到 S6, 256 为 S0 到 S5
+        n := s.blocks
+        if s.rate == 2 {
+            n = 7
+        }
+
+        for i := 0; i < n; i++ {
+            s.xorLanes(tag, s.block(i))
+        }
+    } else {
+        half := s.blocks / 2
+        for i := 0; i < half; i++ {
+            s.xorLanes(tag[:16], s.block(i))
+            s.xorLanes(tag[16:], s.block(half + i))
+        }
+    }
+}
+
+// 各路异或到 16 字节
+func (s *state) xorLanes(dst, b []byte) {
+    for i := 0; i < s.lanes; i++ {
+        subtle.XORBytes(dst[:blockSize], dst[:blockSize], b[i*blockSize:(i+1)*blockSize])
+    }
+}
@@ -0,0 +1,235 @@
+package aegis
+
+import (
+    "bytes"
+    "testing"
+    "crypto/rand"
+    "crypto/cipher"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+type testVector struct {
+    name    string
+    newFn   func(key []byte, tagSize int) (cipher.AEAD, error)
+    key     string
+    nonce   string
+    ad      string
+    msg     string
+    ct      string
+    tag128  string
+    tag256  string
+}
+
+func new128X2(key []byte, tagSize int) (cipher.AEAD, error) {
+    return New128X(key, 2, tagSize)
+}
+
+func new128X4(key []byte, tagSize int) (cipher.AEAD, error) {
+    return New128X(key, 4, tagSize)
+}
+
+func new256X2(key []byte, tagSize int) (cipher.AEAD, error) {
+    return New256X(key, 2, tagSize)
+}
+
+func new256X4(key []byte, tagSize int) (cipher.AEAD, error) {
+    return New256X(key, 4, tagSize)
+}
+
+// draft-irtf-cfrg-aegis-aead 测试向量
+var testVectors = []testVector{
+    {
+        name:   "128L-1",
+        newFn:  New128L,
+        key:    "10010000000000000000000000000000",
+        nonce:  "10000200000000000000000000000000",
+        msg:    "00000000000000000000000000000000",
+        ct:     "c1c0e58bd913006feba00f4b3cc3594e",
+        tag128: "abe0ece80c24868a226a35d16bdae37a",
+        tag256: "25835bfbb21632176cf03840687cb968cace4617af1bd0f7d064c639a5c79ee4",
+    },
+    {
+        name:   "128L-2",
+        newFn:  New128L,
+        key:    "10010000000000000000000000000000",
+        nonce:  "10000200000000000000000000000000",
+        tag128: "c2b879a67def9d74e6c14f708bbcc9b4",
+        tag256: "1360dc9db8ae42455f6e5b6a9d488ea4f2184c4e12120249335c4ee84bafe25d",
+    },
+    {
+        name:   "128L-3",
+        newFn:  New128L,
+        key:    "10010000000000000000000000000000",
+        nonce:  "10000200000000000000000000000000",
+        ad:     "0001020304050607",
+        msg:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+        ct:     "79d94593d8c2119d7e8fd9b8fc77845c5c077a05b2528b6ac54b563aed8efe84",
+        tag128: "cc6f3372f6aa1bb82388d695c3962d9a",
+        tag256: "022cb796fe7e0ae1197525ff67e309484cfbab6528ddef89f17d74ef8ecd82b3",
+    },
+    {
+        name:   "256-1",
+        newFn:  New256,
+        key:    "1001000000000000000000000000000000000000000000000000000000000000",
+        nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+        msg:    "00000000000000000000000000000000",
+        ct:     "754fc3d8c973246dcc6d741412a4b236",
+        tag128: "3fe91994768b332ed7f570a19ec5896e",
+        tag256: "1181a1d18091082bf0266f66297d167d2e68b845f61a3b0527d31fc7b7b89f13",
+    },
+    {
+        name:   "128X2-1",
+        newFn:  new128X2,
+        key:    "000102030405060708090a0b0c0d0e0f",
+        nonce:  "101112131415161718191a1b1c1d1e1f",
+        tag128: "63117dc57756e402819a82e13eca8379",
+        tag256: "b92c71fdbd358b8a4de70b27631ace90cffd9b9cfba82028412bac41b4f53759",
+    },
+    {
+        name:   "128X4-1",
+        newFn:  new128X4,
+        key:    "000102030405060708090a0b0c0d0e0f",
+        nonce:  "101112131415161718191a1b1c1d1e1f",
+        tag128: "5bef762d0947c00455b97bb3af30dfa3",
+        tag256: "a4b25437f4be93cfa856a2f27e4416b42cac79fd4698f2cdbe6af25673e10a68",
+    },
+    {
+        name:   "256X2-1",
+        newFn:  new256X2,
+        key:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+        nonce:  "101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f",
+        tag128: "62cdbab084c83dacdb945bb446f049c8",
+        tag256: "25d7e799b49a80354c3f881ac2f1027f471a5d293052bd9997abd3ae84014bb7",
+    },
+    {
+        name:   "256X4-1",
+        newFn:  new256X4,
+        key:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+        nonce:  "101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f",
+        tag128: "3b7fee6cee7bf17888ad11ed2397beb4",
+        tag256: "6093a1a8aab20ec635dc1ca71745b01b5bec4fc444c9ffbebd710d4a34d20eaf",
+    },
+}
+
+func Test_Vectors(t *testing.T) {
+    for _, v := range testVectors {
+        for _, tag := range []string{v.tag128, v.tag256} {
+            tag := fromHex(tag)
+
+            aead, err := v.newFn(fromHex(v.key), len(tag))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            nonce := fromHex(v.nonce)
+            ad := fromHex(v.ad)
+            msg := fromHex(v.msg)
+            want := append(fromHex(v.ct), tag...)
+
+            got := aead.Seal(nil, nonce, msg, ad)
+            if !bytes.Equal(got, want) {
+                t.Errorf("%s: Seal got %x, want %x", v.name, got, want)
+            }
+
+            pt, err := aead.Open(nil, nonce, want, ad)
+            if err != nil {
+                t.Fatalf("%s: %v", v.name, err)
+            }
+
+            if !bytes.Equal(pt, msg) {
+                t.Errorf("%s: Open got %x, want %x", v.name, pt, msg)
+            }
+        }
+    }
+}
+
+func Test_RoundTrip(t *testing.T) {
+    fns := []struct {
+        name    string
+        newFn   func(key []byte, tagSize int) (cipher.AEAD, error)
+        keySize int
+    }{
+        {"128L", New128L, KeySize128L},
+        {"256", New256, KeySize256},
+        {"128X2", new128X2, KeySize128L},
+        {"128X4", new128X4, KeySize128L},
+        {"256X2", new256X2, KeySize256},
+        {"256X4", new256X4, KeySize256},
+    }
+
+    for _, f := range fns {
+        key := make([]byte, f.keySize)
+        rand.Read(key)
+
+        aead, err := f.newFn(key, TagSize)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        nonce := make([]byte, aead.NonceSize())
+        rand.Read(nonce)
+
+        for _, n := range []int{0, 1, 15, 16, 31, 32, 33, 100, 257} {
+            msg := make([]byte, n)
+            ad := make([]byte, n / 3)
+            rand.Read(msg)
+            rand.Read(ad)
+
+            ct := aead.Seal(nil, nonce, msg, ad)
+
+            pt, err := aead.Open(nil, nonce, ct, ad)
+            if err != nil {
+                t.Fatalf("%s-%d: %v", f.name, n, err)
+            }
+
+            if !bytes.Equal(pt, msg) {
+                t.Errorf("%s-%d: got %x, want %x", f.name, n, pt, msg)
+            }
+
+            ct[len(ct)-1] ^= 1
+            if _, err := aead.Open(nil, nonce, ct, ad); err != errOpen {
+                t.Errorf("%s-%d: tampered tag should fail", f.name, n)
+            }
+        }
+    }
+}
+
+func Test_Errors(t *testing.T) {
+    if _, err := New128L(make([]byte, 32), 16); err == nil {
+        t.Error("should fail with bad key size")
+    }
+
+    if _, err := New256(make([]byte, 32), 8); err == nil {
+        t.Error("should fail with bad tag size")
+    }
+
+    if _, err := New128X(make([]byte, 16), 3, 16); err == nil {
+        t.Error("should fail with bad degree")
+    }
+}
+
+func Benchmark_Seal128L(b *testing.B) {
+    aead, _ := New128L(make([]byte, KeySize128L), TagSize)
+    benchmarkSeal(b, aead)
+}
+
+func Benchmark_Seal128X4(b *testing.B) {
+    aead, _ := New128X(make([]byte, KeySize128L), 4, TagSize)
+    benchmarkSeal(b, aead)
+}
+
+func benchmarkSeal(b *testing.B, aead cipher.AEAD) {
+    buf := make([]byte, 8*1024)
+    nonce := make([]byte, aead.NonceSize())
+    b.SetBytes(int64(len(buf)))
+
+    var out []byte
+    for i := 0; i < b.N; i++ {
+        out = aead.Seal(out[:0], nonce, buf, nil)
+    }
+}
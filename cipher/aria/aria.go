@@ -21,6 +21,7 @@ type ariaCipher struct {
     k   int // Key size in bytes.
     enc []uint32
     dec []uint32
+    ct  bool // 使用常量时间 S 盒
 }
 
 // NewCipher creates and returns a new cipher.Block.
@@ -91,13 +92,13 @@ func (c *ariaCipher) cryptBlock(dst, src []byte, xk []uint32) {
 
     for i := 1; i <= n-1; i++ {
         if i&1 == 1 {
-            p = roundOdd(p, toBytes(xk[(i-1)*4:i*4]))
+            p = c.roundOdd(p, toBytes(xk[(i-1)*4:i*4]))
         } else {
-            p = roundEven(p, toBytes(xk[(i-1)*4:i*4]))
+            p = c.roundEven(p, toBytes(xk[(i-1)*4:i*4]))
         }
     }
 
-    p = xor(substitute2(xor(p, toBytes(xk[(n-1)*4:n*4]))), toBytes(xk[n*4:(n+1)*4]))
+    p = xor(c.substitute2(xor(p, toBytes(xk[(n-1)*4:n*4]))), toBytes(xk[n*4:(n+1)*4]))
 
     copy(dst[:BlockSize], p[:])
 }
@@ -146,9 +147,9 @@ func (c *ariaCipher) keyRound(key []byte) {
     var w0, w1, w2, w3 [16]byte
 
     w0 = kl
-    w1 = xor(roundOdd(w0, ck1), kr)
-    w2 = xor(roundEven(w1, ck2), w0)
-    w3 = xor(roundOdd(w2, ck3), w1)
+    w1 = xor(c.roundOdd(w0, ck1), kr)
+    w2 = xor(c.roundEven(w1, ck2), w0)
+    w3 = xor(c.roundOdd(w2, ck3), w1)
 
     copyBytes(c.enc, xor(w0, rrot(w1, 19)))
     copyBytes(c.enc[4:], xor(w1, rrot(w2, 19)))
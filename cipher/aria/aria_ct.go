@@ -0,0 +1,59 @@
+package aria
+
+import (
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/tool/ct"
+)
+
+var (
+    sb1CT = ct.NewSbox(&sb1)
+    sb2CT = ct.NewSbox(&sb2)
+    sb3CT = ct.NewSbox(&sb3)
+    sb4CT = ct.NewSbox(&sb4)
+)
+
+// NewCipherCT creates and returns a new ARIA cipher.Block whose
+// substitution layers SL1 and SL2 do not index sb1..sb4 with secret
+// bytes, each S-box is read through a masked truth table (see tool/ct).
+// The diffusion layer has no tables and is shared with NewCipher.
+// It is about 6 times slower than NewCipher.
+func NewCipherCT(key []byte) (cipher.Block, error) {
+    k := len(key)
+    switch k {
+        case 16, 24, 32:
+            break
+        default:
+            return nil, KeySizeError(k)
+    }
+
+    c := new(ariaCipher)
+    c.ct = true
+    c.expandKey(key)
+
+    return c, nil
+}
+
+// Substitution Layer SL1, constant time
+func substitute1CT(x [16]byte) (y [16]byte) {
+    for i := 0; i < 16; i += 4 {
+        y[i] = sb1CT.Lookup(x[i])
+        y[i+1] = sb2CT.Lookup(x[i+1])
+        y[i+2] = sb3CT.Lookup(x[i+2])
+        y[i+3] = sb4CT.Lookup(x[i+3])
+    }
+
+    return
+}
+
+// Substitution Layer SL2, constant time
+func substitute2CT(x [16]byte) (y [16]byte) {
+    for i := 0; i < 16; i += 4 {
+        y[i] = sb3CT.Lookup(x[i])
+        y[i+1] = sb4CT.Lookup(x[i+1])
+        y[i+2] = sb1CT.Lookup(x[i+2])
+        y[i+3] = sb2CT.Lookup(x[i+3])
+    }
+
+    return
+}
@@ -4,8 +4,11 @@ import (
     "fmt"
     "bytes"
     "testing"
+    "math/rand"
     "crypto/aes"
     "encoding/hex"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
 )
 
 func fromHex(s string) []byte {
@@ -180,6 +183,75 @@ func Test_Check(t *testing.T) {
     }
 }
 
+// RFC 5794, Appendix A.1 - A.3
+func Test_CheckCT(t *testing.T) {
+    tests := []testData{
+        {
+           16,
+           fromHex("00112233445566778899aabbccddeeff"),
+           fromHex("d718fbd6ab644c739da95f3be6451778"),
+           fromHex("000102030405060708090a0b0c0d0e0f"),
+        },
+        {
+           24,
+           fromHex("00112233445566778899aabbccddeeff"),
+           fromHex("26449c1805dbe7aa25a468ce263a9e79"),
+           fromHex("000102030405060708090a0b0c0d0e0f1011121314151617"),
+        },
+        {
+           32,
+           fromHex("00112233445566778899aabbccddeeff"),
+           fromHex("f92bd7c79fb72e2f2b8f80c1972d24fc"),
+           fromHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"),
+        },
+    }
+
+    for i, test := range tests {
+        c, err := NewCipherCT(test.key)
+        if err != nil {
+            t.Fatal(err.Error())
+        }
+
+        tmp := make([]byte, BlockSize)
+        c.Encrypt(tmp, test.pt)
+
+        if !bytes.Equal(tmp, test.ct) {
+            t.Errorf("[%d] CheckCT error: got %x, want %x", i, tmp, test.ct)
+        }
+
+        tmp2 := make([]byte, BlockSize)
+        c.Decrypt(tmp2, test.ct)
+
+        if !bytes.Equal(tmp2, test.pt) {
+            t.Errorf("[%d] CheckCT Decrypt error: got %x, want %x", i, tmp2, test.pt)
+        }
+    }
+
+    // 与查表实现的结果一致
+    for _, k := range []int{16, 24, 32} {
+        key := make([]byte, k)
+        rand.Read(key)
+
+        c1, _ := NewCipher(key)
+        c2, _ := NewCipherCT(key)
+
+        src := make([]byte, BlockSize)
+        dst1 := make([]byte, BlockSize)
+        dst2 := make([]byte, BlockSize)
+
+        for i := 0; i < 100; i++ {
+            rand.Read(src)
+
+            c1.Encrypt(dst1, src)
+            c2.Encrypt(dst2, src)
+
+            if !bytes.Equal(dst1, dst2) {
+                t.Fatalf("key size %d: got %x, want %x", k, dst2, dst1)
+            }
+        }
+    }
+}
+
 func BenchmarkAES128(b *testing.B) { benchmarkAES(b, 16) }
 func BenchmarkAES192(b *testing.B) { benchmarkAES(b, 24) }
 func BenchmarkAES256(b *testing.B) { benchmarkAES(b, 32) }
@@ -217,3 +289,21 @@ func benchmarkARIA(b *testing.B, k int) {
         block.Encrypt(cipher, input)
     }
 }
+
+// DUDECT=1 go test -run Test_CipherCTTiming
+func Test_CipherCTTiming(t *testing.T) {
+    key := make([]byte, 16)
+    rand.Read(key)
+
+    c, err := NewCipherCT(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dst := make([]byte, 16)
+    cryptobin_test.AssertConstantTime(t, cryptobin_test.DudectConfig{
+        Measurements: 200000,
+    }, make([]byte, 16), func(in []byte) {
+        c.Encrypt(dst, in)
+    })
+}
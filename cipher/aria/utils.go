@@ -53,13 +53,29 @@ func toBytes(u []uint32) (r [16]byte) {
 }
 
 // Round Function Fo
-func roundOdd(d, rk [16]byte) [16]byte {
-    return diffuse(substitute1(xor(d, rk)))
+func (c *ariaCipher) roundOdd(d, rk [16]byte) [16]byte {
+    return diffuse(c.substitute1(xor(d, rk)))
 }
 
 // Round Function Fe
-func roundEven(d, rk [16]byte) [16]byte {
-    return diffuse(substitute2(xor(d, rk)))
+func (c *ariaCipher) roundEven(d, rk [16]byte) [16]byte {
+    return diffuse(c.substitute2(xor(d, rk)))
+}
+
+func (c *ariaCipher) substitute1(x [16]byte) [16]byte {
+    if c.ct {
+        return substitute1CT(x)
+    }
+
+    return substitute1(x)
+}
+
+func (c *ariaCipher) substitute2(x [16]byte) [16]byte {
+    if c.ct {
+        return substitute2CT(x)
+    }
+
+    return substitute2(x)
 }
 
 // Substitution Layer SL1
@@ -0,0 +1,195 @@
+package ascon
+
+import (
+    "errors"
+    "runtime"
+    "strconv"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+// NIST SP 800-232 Ascon-AEAD128
+const ivAEAD128 uint64 = 0x00001000808c0001
+
+// 分组大小
+const BlockSizeAEAD128 = 16
+
+type asconAEAD128 struct {
+    k0, k1 uint64
+}
+
+// NewAEAD128 creates the Ascon-AEAD128 AEAD of NIST SP 800-232.
+// It uses little-endian byte ordering and differs from Ascon-128a.
+func NewAEAD128(key []byte) (cipher.AEAD, error) {
+    if len(key) != KeySize {
+        return nil, errors.New("go-cryptobin/ascon: bad key length")
+    }
+
+    return &asconAEAD128{
+        k0: binary.LittleEndian.Uint64(key[0:]),
+        k1: binary.LittleEndian.Uint64(key[8:]),
+    }, nil
+}
+
+func (a *asconAEAD128) NonceSize() int {
+    return NonceSize
+}
+
+func (a *asconAEAD128) Overhead() int {
+    return TagSize
+}
+
+func (a *asconAEAD128) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+    if len(nonce) != NonceSize {
+        panic("go-cryptobin/ascon: incorrect nonce length: " + strconv.Itoa(len(nonce)))
+    }
+
+    ret, out := alias.SliceForAppend(dst, len(plaintext)+TagSize)
+    if alias.InexactOverlap(out, plaintext) {
+        panic("go-cryptobin/ascon: invalid buffer overlap")
+    }
+
+    var s state
+    a.init(&s, nonce)
+    s.additionalDataLE(additionalData)
+    s.encryptLE(out[:len(plaintext)], plaintext)
+    a.finalize(&s, out[len(plaintext):])
+
+    return ret
+}
+
+func (a *asconAEAD128) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+    if len(nonce) != NonceSize {
+        panic("go-cryptobin/ascon: incorrect nonce length: " + strconv.Itoa(len(nonce)))
+    }
+
+    if len(ciphertext) < TagSize {
+        return nil, errOpen
+    }
+
+    tag := ciphertext[len(ciphertext)-TagSize:]
+    ciphertext = ciphertext[:len(ciphertext)-TagSize]
+
+    ret, out := alias.SliceForAppend(dst, len(ciphertext))
+    if alias.InexactOverlap(out, ciphertext) {
+        panic("go-cryptobin/ascon: invalid buffer overlap")
+    }
+
+    var s state
+    a.init(&s, nonce)
+    s.additionalDataLE(additionalData)
+    s.decryptLE(out, ciphertext)
+
+    expectedTag := make([]byte, TagSize)
+    a.finalize(&s, expectedTag)
+
+    if subtle.ConstantTimeCompare(expectedTag, tag) != 1 {
+        for i := range out {
+            out[i] = 0
+        }
+
+        runtime.KeepAlive(out)
+        return nil, errOpen
+    }
+
+    return ret, nil
+}
+
+func (a *asconAEAD128) init(s *state, nonce []byte) {
+    s.x0 = ivAEAD128
+    s.x1 = a.k0
+    s.x2 = a.k1
+    s.x3 = binary.LittleEndian.Uint64(nonce[0:])
+    s.x4 = binary.LittleEndian.Uint64(nonce[8:])
+    p12(s)
+    s.x3 ^= a.k0
+    s.x4 ^= a.k1
+}
+
+func (a *asconAEAD128) finalize(s *state, tag []byte) {
+    s.x2 ^= a.k0
+    s.x3 ^= a.k1
+    p12(s)
+    binary.LittleEndian.PutUint64(tag[0:], s.x3 ^ a.k0)
+    binary.LittleEndian.PutUint64(tag[8:], s.x4 ^ a.k1)
+}
+
+func (s *state) additionalDataLE(ad []byte) {
+    if len(ad) > 0 {
+        for len(ad) >= BlockSizeAEAD128 {
+            s.x0 ^= binary.LittleEndian.Uint64(ad[0:])
+            s.x1 ^= binary.LittleEndian.Uint64(ad[8:])
+            p8(s)
+            ad = ad[BlockSizeAEAD128:]
+        }
+
+        if len(ad) >= 8 {
+            s.x0 ^= binary.LittleEndian.Uint64(ad[0:])
+            s.x1 ^= le64n(ad[8:])
+            s.x1 ^= padLE(len(ad) - 8)
+        } else {
+            s.x0 ^= le64n(ad)
+            s.x0 ^= padLE(len(ad))
+        }
+        p8(s)
+    }
+
+    // 域分隔
+    s.x4 ^= 1 << 63
+}
+
+func (s *state) encryptLE(dst, src []byte) {
+    for len(src) >= BlockSizeAEAD128 {
+        s.x0 ^= binary.LittleEndian.Uint64(src[0:])
+        s.x1 ^= binary.LittleEndian.Uint64(src[8:])
+        binary.LittleEndian.PutUint64(dst[0:], s.x0)
+        binary.LittleEndian.PutUint64(dst[8:], s.x1)
+        p8(s)
+        src = src[BlockSizeAEAD128:]
+        dst = dst[BlockSizeAEAD128:]
+    }
+
+    if len(src) >= 8 {
+        s.x0 ^= binary.LittleEndian.Uint64(src[0:])
+        s.x1 ^= le64n(src[8:])
+        binary.LittleEndian.PutUint64(dst[0:], s.x0)
+        putLE64n(dst[8:], s.x1)
+        s.x1 ^= padLE(len(src) - 8)
+    } else {
+        s.x0 ^= le64n(src)
+        putLE64n(dst, s.x0)
+        s.x0 ^= padLE(len(src))
+    }
+}
+
+func (s *state) decryptLE(dst, src []byte) {
+    for len(src) >= BlockSizeAEAD128 {
+        c0 := binary.LittleEndian.Uint64(src[0:])
+        c1 := binary.LittleEndian.Uint64(src[8:])
+        binary.LittleEndian.PutUint64(dst[0:], s.x0 ^ c0)
+        binary.LittleEndian.PutUint64(dst[8:], s.x1 ^ c1)
+        s.x0 = c0
+        s.x1 = c1
+        p8(s)
+        src = src[BlockSizeAEAD128:]
+        dst = dst[BlockSizeAEAD128:]
+    }
+
+    if len(src) >= 8 {
+        c0 := binary.LittleEndian.Uint64(src[0:])
+        c1 := le64n(src[8:])
+        binary.LittleEndian.PutUint64(dst[0:], s.x0 ^ c0)
+        putLE64n(dst[8:], s.x1 ^ c1)
+        s.x0 = c0
+        s.x1 = maskLE(s.x1, len(src) - 8) | c1
+        s.x1 ^= padLE(len(src) - 8)
+    } else {
+        c0 := le64n(src)
+        putLE64n(dst, s.x0 ^ c0)
+        s.x0 = maskLE(s.x0, len(src)) | c0
+        s.x0 ^= padLE(len(src))
+    }
+}
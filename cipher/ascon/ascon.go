@@ -1,4 +1,8 @@
 // Package ascon implements the ASCON AEAD cipher.
+//
+// NewCipher and NewCiphera are Ascon-128 and Ascon-128a of the competition
+// spec v1.2. The NIST SP 800-232 suite is Ascon-AEAD128, Ascon-Hash256,
+// Ascon-XOF128 and Ascon-CXOF128.
 package ascon
 
 import (
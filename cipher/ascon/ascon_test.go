@@ -240,3 +240,163 @@ func readVecs(path string) ([]vector, error) {
     }
     return vecs, nil
 }
+
+// NIST SP 800-232, ascon-c LWC_AEAD_KAT_128_128 Count 1.
+// TODO: 导入 AD 和 PT 非空且末尾分组不完整的 KAT 行
+func Test_AEAD128(t *testing.T) {
+    key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+    nonce, _ := hex.DecodeString("101112131415161718191a1b1c1d1e1f")
+    want, _ := hex.DecodeString("4f9c278211bec9316bf68f46ee8b2ec6")
+
+    aead, err := NewAEAD128(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    got := aead.Seal(nil, nonce, nil, nil)
+    if !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+
+    for n := 0; n < 40; n++ {
+        pt := make([]byte, n)
+        ad := make([]byte, n / 2)
+        for i := range pt {
+            pt[i] = byte(i)
+        }
+
+        ct := aead.Seal(nil, nonce, pt, ad)
+
+        got, err := aead.Open(nil, nonce, ct, ad)
+        if err != nil {
+            t.Fatalf("#%d: %v", n, err)
+        }
+        if !bytes.Equal(got, pt) {
+            t.Fatalf("#%d: expected %x, got %x", n, pt, got)
+        }
+
+        ct[0] ^= 1
+        if _, err := aead.Open(nil, nonce, ct, ad); err == nil {
+            t.Fatalf("#%d: tampered ciphertext should fail", n)
+        }
+    }
+}
+
+func Test_Hash256(t *testing.T) {
+    // ascon-c Ascon-Hash256 KAT, Count = 1 和 2
+    for _, td := range []struct{
+        msg  string
+        want string
+    }{
+        {"", "0b3be5850f2f6b98caf29f8fdea89b64a1fa70aa249b8f839bd53baa304d92b2"},
+        {"00", "0728621035af3ed2bca03bf6fde900f9456f5330e4b5ee23e7f6a1e70291bc80"},
+    } {
+        msg, _ := hex.DecodeString(td.msg)
+
+        sum := Sum256(msg)
+        if hex.EncodeToString(sum[:]) != td.want {
+            t.Errorf("Msg = %s: got %x, want %s", td.msg, sum, td.want)
+        }
+    }
+
+    data := make([]byte, 100)
+    for i := range data {
+        data[i] = byte(i)
+    }
+
+    h := NewHash256()
+    h.Write(data[:3])
+    h.Write(data[3:50])
+    h.Write(data[50:])
+
+    sum := Sum256(data)
+    if !bytes.Equal(h.Sum(nil), sum[:]) {
+        t.Error("streaming hash mismatch")
+    }
+}
+
+func Test_XOF128(t *testing.T) {
+    // ascon-c Ascon-XOF128 KAT, Count = 1 和 2 输出的前 32 字节
+    for _, td := range []struct{
+        msg  string
+        want string
+    }{
+        {"", "473d5e6164f58b39dfd84aacdb8ae42ec2d91fed33388ee0d960d9b3993295c6"},
+        {"00", "51430e0438ecdf642b393630d977625f5f337656ba58ab1e960784ac32a16e0d"},
+    } {
+        msg, _ := hex.DecodeString(td.msg)
+
+        got := SumXOF128(msg, 32)
+        if hex.EncodeToString(got) != td.want {
+            t.Errorf("Msg = %s: got %x, want %s", td.msg, got, td.want)
+        }
+    }
+
+    data := []byte("test-passtest-passtest-pass")
+    full := SumXOF128(data, 100)
+
+    // 分段读取
+    x := NewXOF128()
+    x.Write(data[:5])
+    x.Write(data[5:])
+
+    out := make([]byte, 100)
+    x.Read(out[:3])
+    x.Read(out[3:20])
+    x.Read(out[20:])
+
+    if !bytes.Equal(out, full) {
+        t.Errorf("got %x, want %x", out, full)
+    }
+}
+
+func Test_CXOF128(t *testing.T) {
+    // ascon-c Ascon-CXOF128 KAT, Count = 1
+    want := "4f50159ef70bb3dad8807e034eaebd44c4fa2cbbc8cf1f05511ab66cdcc52990" +
+        "5ca12083fc186ad899b270b1473dc5f7ec88d1052082dcdfe69fb75d269e7b74"
+
+    x, err := NewCXOF128(nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    got := make([]byte, 64)
+    x.Read(got)
+    if hex.EncodeToString(got) != want {
+        t.Errorf("got %x, want %s", got, want)
+    }
+
+    data := []byte("test-pass")
+
+    x1, err := NewCXOF128(nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    x2, _ := NewCXOF128([]byte("custom"))
+
+    x1.Write(data)
+    x2.Write(data)
+
+    out1 := make([]byte, 32)
+    out2 := make([]byte, 32)
+    x1.Read(out1)
+    x2.Read(out2)
+
+    if bytes.Equal(out1, out2) || bytes.Equal(out1, SumXOF128(data, 32)) {
+        t.Error("customization should change the output")
+    }
+
+    x2.Reset()
+    x2.Write(data)
+
+    out := make([]byte, 32)
+    x2.Read(out)
+    if !bytes.Equal(out, out2) {
+        t.Error("Reset should keep the customization")
+    }
+
+    if _, err := NewCXOF128(make([]byte, MaxCustomizationSize+1)); err == nil {
+        t.Error("long customization should fail")
+    }
+}
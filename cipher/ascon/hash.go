@@ -0,0 +1,220 @@
+package ascon
+
+import (
+    "hash"
+    "errors"
+    "encoding/binary"
+)
+
+// NIST SP 800-232 hash 和 XOF
+const (
+    ivHash256 uint64 = 0x0000080100cc0002
+    ivXOF128  uint64 = 0x0000080000cc0003
+    ivCXOF128 uint64 = 0x0000080000cc0004
+)
+
+const (
+    // Size256 is the size in bytes of an Ascon-Hash256 checksum.
+    Size256 = 32
+
+    // HashBlockSize is the rate in bytes of Ascon-Hash256 and the XOFs.
+    HashBlockSize = 8
+
+    // MaxCustomizationSize is the max size in bytes of the
+    // Ascon-CXOF128 customization string.
+    MaxCustomizationSize = 256
+)
+
+var errCustomization = errors.New("go-cryptobin/ascon: customization string too long")
+
+// XOF is a streaming Ascon-XOF128 or Ascon-CXOF128. Data is written
+// with Write and output is read with Read. Write panics after Read.
+type XOF struct {
+    s    state
+    init state
+
+    buf [HashBlockSize]byte
+    n   int
+
+    squeezing bool
+}
+
+// NewXOF128 returns a new Ascon-XOF128.
+func NewXOF128() *XOF {
+    x := &XOF{}
+    x.init = initState(ivXOF128)
+    x.Reset()
+
+    return x
+}
+
+// NewCXOF128 returns a new Ascon-CXOF128 with the customization string.
+// The customization string is at most 256 bytes.
+func NewCXOF128(customization []byte) (*XOF, error) {
+    if len(customization) > MaxCustomizationSize {
+        return nil, errCustomization
+    }
+
+    s := initState(ivCXOF128)
+
+    // 先吸收定制串的位长度, 再吸收补位后的定制串
+    s.x0 ^= uint64(len(customization)) * 8
+    p12(&s)
+    absorbLE(&s, customization)
+
+    x := &XOF{}
+    x.init = s
+    x.Reset()
+
+    return x, nil
+}
+
+// SumXOF128 returns n bytes of Ascon-XOF128 output of the data.
+func SumXOF128(data []byte, n int) []byte {
+    x := NewXOF128()
+    x.Write(data)
+
+    out := make([]byte, n)
+    x.Read(out)
+
+    return out
+}
+
+// Write absorbs more data into the XOF state.
+func (x *XOF) Write(p []byte) (int, error) {
+    if x.squeezing {
+        panic("go-cryptobin/ascon: write after read")
+    }
+
+    nn := len(p)
+
+    if x.n > 0 {
+        n := copy(x.buf[x.n:], p)
+        x.n += n
+        p = p[n:]
+
+        if x.n < HashBlockSize {
+            return nn, nil
+        }
+
+        x.s.x0 ^= binary.LittleEndian.Uint64(x.buf[:])
+        p12(&x.s)
+        x.n = 0
+    }
+
+    for len(p) >= HashBlockSize {
+        x.s.x0 ^= binary.LittleEndian.Uint64(p)
+        p12(&x.s)
+        p = p[HashBlockSize:]
+    }
+
+    x.n = copy(x.buf[:], p)
+
+    return nn, nil
+}
+
+// Read squeezes output from the XOF. It never returns an error.
+func (x *XOF) Read(out []byte) (int, error) {
+    if !x.squeezing {
+        x.s.x0 ^= le64n(x.buf[:x.n])
+        x.s.x0 ^= padLE(x.n)
+        p12(&x.s)
+
+        binary.LittleEndian.PutUint64(x.buf[:], x.s.x0)
+        x.n = 0
+        x.squeezing = true
+    }
+
+    nn := len(out)
+
+    for len(out) > 0 {
+        if x.n == HashBlockSize {
+            p12(&x.s)
+            binary.LittleEndian.PutUint64(x.buf[:], x.s.x0)
+            x.n = 0
+        }
+
+        n := copy(out, x.buf[x.n:])
+        x.n += n
+        out = out[n:]
+    }
+
+    return nn, nil
+}
+
+// Reset resets the XOF to its initial state, keeping the customization.
+func (x *XOF) Reset() {
+    x.s = x.init
+    x.n = 0
+    x.squeezing = false
+}
+
+// Clone returns a copy of the XOF in its current state.
+func (x *XOF) Clone() *XOF {
+    c := *x
+    return &c
+}
+
+type digest256 struct {
+    x *XOF
+}
+
+// NewHash256 returns a new hash.Hash computing the Ascon-Hash256 checksum.
+func NewHash256() hash.Hash {
+    x := &XOF{}
+    x.init = initState(ivHash256)
+    x.Reset()
+
+    return &digest256{x}
+}
+
+// Sum256 returns the Ascon-Hash256 checksum of the data.
+func Sum256(data []byte) (sum [Size256]byte) {
+    h := NewHash256()
+    h.Write(data)
+    h.Sum(sum[:0])
+    return
+}
+
+func (d *digest256) Size() int {
+    return Size256
+}
+
+func (d *digest256) BlockSize() int {
+    return HashBlockSize
+}
+
+func (d *digest256) Reset() {
+    d.x.Reset()
+}
+
+func (d *digest256) Write(p []byte) (int, error) {
+    return d.x.Write(p)
+}
+
+func (d *digest256) Sum(in []byte) []byte {
+    var out [Size256]byte
+    d.x.Clone().Read(out[:])
+
+    return append(in, out[:]...)
+}
+
+func initState(iv uint64) state {
+    s := state{x0: iv}
+    p12(&s)
+
+    return s
+}
+
+// 按 8 字节分组吸收并补位
+func absorbLE(s *state, data []byte) {
+    for len(data) >= HashBlockSize {
+        s.x0 ^= binary.LittleEndian.Uint64(data)
+        p12(s)
+        data = data[HashBlockSize:]
+    }
+
+    s.x0 ^= le64n(data)
+    s.x0 ^= padLE(len(data))
+    p12(s)
+}
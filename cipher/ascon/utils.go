@@ -182,3 +182,29 @@ func p8(s *state) {
 func p6(s *state) {
     p6Generic(s)
 }
+
+func padLE(n int) uint64 {
+    return 0x01 << (8*n)
+}
+
+func le64n(b []byte) uint64 {
+    var x uint64
+    for i := len(b) - 1; i >= 0; i-- {
+        x |= uint64(b[i]) << (8*i)
+    }
+
+    return x
+}
+
+func putLE64n(b []byte, x uint64) {
+    for i := len(b) - 1; i >= 0; i-- {
+        b[i] = byte(x >> (8*i))
+    }
+}
+
+func maskLE(x uint64, n int) uint64 {
+    for i := 0; i < n; i++ {
+        x &^= 255 << (8*i)
+    }
+    return x
+}
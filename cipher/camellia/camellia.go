@@ -26,6 +26,7 @@ type camelliaCipher struct {
     k    [25]uint64
     ke   [7]uint64
     klen int
+    ct   bool // 使用常量时间 S 盒
 }
 
 // New creates and returns a new cipher.Block.
@@ -88,32 +89,32 @@ func (this *camelliaCipher) encrypt(dst, src []byte) {
     d1 ^= this.kw[1]
     d2 ^= this.kw[2]
 
-    d2 = d2 ^ f(d1, this.k[1])
-    d1 = d1 ^ f(d2, this.k[2])
-    d2 = d2 ^ f(d1, this.k[3])
-    d1 = d1 ^ f(d2, this.k[4])
-    d2 = d2 ^ f(d1, this.k[5])
-    d1 = d1 ^ f(d2, this.k[6])
+    d2 = d2 ^ this.f(d1, this.k[1])
+    d1 = d1 ^ this.f(d2, this.k[2])
+    d2 = d2 ^ this.f(d1, this.k[3])
+    d1 = d1 ^ this.f(d2, this.k[4])
+    d2 = d2 ^ this.f(d1, this.k[5])
+    d1 = d1 ^ this.f(d2, this.k[6])
 
     d1 = fl(d1, this.ke[1])
     d2 = flinv(d2, this.ke[2])
 
-    d2 = d2 ^ f(d1, this.k[7])
-    d1 = d1 ^ f(d2, this.k[8])
-    d2 = d2 ^ f(d1, this.k[9])
-    d1 = d1 ^ f(d2, this.k[10])
-    d2 = d2 ^ f(d1, this.k[11])
-    d1 = d1 ^ f(d2, this.k[12])
+    d2 = d2 ^ this.f(d1, this.k[7])
+    d1 = d1 ^ this.f(d2, this.k[8])
+    d2 = d2 ^ this.f(d1, this.k[9])
+    d1 = d1 ^ this.f(d2, this.k[10])
+    d2 = d2 ^ this.f(d1, this.k[11])
+    d1 = d1 ^ this.f(d2, this.k[12])
 
     d1 = fl(d1, this.ke[3])
     d2 = flinv(d2, this.ke[4])
 
-    d2 = d2 ^ f(d1, this.k[13])
-    d1 = d1 ^ f(d2, this.k[14])
-    d2 = d2 ^ f(d1, this.k[15])
-    d1 = d1 ^ f(d2, this.k[16])
-    d2 = d2 ^ f(d1, this.k[17])
-    d1 = d1 ^ f(d2, this.k[18])
+    d2 = d2 ^ this.f(d1, this.k[13])
+    d1 = d1 ^ this.f(d2, this.k[14])
+    d2 = d2 ^ this.f(d1, this.k[15])
+    d1 = d1 ^ this.f(d2, this.k[16])
+    d2 = d2 ^ this.f(d1, this.k[17])
+    d1 = d1 ^ this.f(d2, this.k[18])
 
     if this.klen > 16 {
         // 24 or 32
@@ -121,12 +122,12 @@ func (this *camelliaCipher) encrypt(dst, src []byte) {
         d1 = fl(d1, this.ke[5])
         d2 = flinv(d2, this.ke[6])
 
-        d2 = d2 ^ f(d1, this.k[19])
-        d1 = d1 ^ f(d2, this.k[20])
-        d2 = d2 ^ f(d1, this.k[21])
-        d1 = d1 ^ f(d2, this.k[22])
-        d2 = d2 ^ f(d1, this.k[23])
-        d1 = d1 ^ f(d2, this.k[24])
+        d2 = d2 ^ this.f(d1, this.k[19])
+        d1 = d1 ^ this.f(d2, this.k[20])
+        d2 = d2 ^ this.f(d1, this.k[21])
+        d1 = d1 ^ this.f(d2, this.k[22])
+        d2 = d2 ^ this.f(d1, this.k[23])
+        d1 = d1 ^ this.f(d2, this.k[24])
     }
 
     d2 = d2 ^ this.kw[3]
@@ -146,43 +147,43 @@ func (this *camelliaCipher) decrypt(dst, src []byte) {
     if this.klen > 16 {
         // 24 or 32
 
-        d1 = d1 ^ f(d2, this.k[24])
-        d2 = d2 ^ f(d1, this.k[23])
-        d1 = d1 ^ f(d2, this.k[22])
-        d2 = d2 ^ f(d1, this.k[21])
-        d1 = d1 ^ f(d2, this.k[20])
-        d2 = d2 ^ f(d1, this.k[19])
+        d1 = d1 ^ this.f(d2, this.k[24])
+        d2 = d2 ^ this.f(d1, this.k[23])
+        d1 = d1 ^ this.f(d2, this.k[22])
+        d2 = d2 ^ this.f(d1, this.k[21])
+        d1 = d1 ^ this.f(d2, this.k[20])
+        d2 = d2 ^ this.f(d1, this.k[19])
 
         d2 = fl(d2, this.ke[6])
         d1 = flinv(d1, this.ke[5])
     }
 
-    d1 = d1 ^ f(d2, this.k[18])
-    d2 = d2 ^ f(d1, this.k[17])
-    d1 = d1 ^ f(d2, this.k[16])
-    d2 = d2 ^ f(d1, this.k[15])
-    d1 = d1 ^ f(d2, this.k[14])
-    d2 = d2 ^ f(d1, this.k[13])
+    d1 = d1 ^ this.f(d2, this.k[18])
+    d2 = d2 ^ this.f(d1, this.k[17])
+    d1 = d1 ^ this.f(d2, this.k[16])
+    d2 = d2 ^ this.f(d1, this.k[15])
+    d1 = d1 ^ this.f(d2, this.k[14])
+    d2 = d2 ^ this.f(d1, this.k[13])
 
     d2 = fl(d2, this.ke[4])
     d1 = flinv(d1, this.ke[3])
 
-    d1 = d1 ^ f(d2, this.k[12])
-    d2 = d2 ^ f(d1, this.k[11])
-    d1 = d1 ^ f(d2, this.k[10])
-    d2 = d2 ^ f(d1, this.k[9])
-    d1 = d1 ^ f(d2, this.k[8])
-    d2 = d2 ^ f(d1, this.k[7])
+    d1 = d1 ^ this.f(d2, this.k[12])
+    d2 = d2 ^ this.f(d1, this.k[11])
+    d1 = d1 ^ this.f(d2, this.k[10])
+    d2 = d2 ^ this.f(d1, this.k[9])
+    d1 = d1 ^ this.f(d2, this.k[8])
+    d2 = d2 ^ this.f(d1, this.k[7])
 
     d2 = fl(d2, this.ke[2])
     d1 = flinv(d1, this.ke[1])
 
-    d1 = d1 ^ f(d2, this.k[6])
-    d2 = d2 ^ f(d1, this.k[5])
-    d1 = d1 ^ f(d2, this.k[4])
-    d2 = d2 ^ f(d1, this.k[3])
-    d1 = d1 ^ f(d2, this.k[2])
-    d2 = d2 ^ f(d1, this.k[1])
+    d1 = d1 ^ this.f(d2, this.k[6])
+    d2 = d2 ^ this.f(d1, this.k[5])
+    d1 = d1 ^ this.f(d2, this.k[4])
+    d2 = d2 ^ this.f(d1, this.k[3])
+    d1 = d1 ^ this.f(d2, this.k[2])
+    d2 = d2 ^ this.f(d1, this.k[1])
 
     d2 ^= this.kw[2]
     d1 ^= this.kw[1]
@@ -216,19 +217,19 @@ func (this *camelliaCipher) expandKey(key []byte) {
     d1 = (kl[0] ^ kr[0])
     d2 = (kl[1] ^ kr[1])
 
-    d2 = d2 ^ f(d1, sigma1)
-    d1 = d1 ^ f(d2, sigma2)
+    d2 = d2 ^ this.f(d1, sigma1)
+    d1 = d1 ^ this.f(d2, sigma2)
 
     d1 = d1 ^ (kl[0])
     d2 = d2 ^ (kl[1])
-    d2 = d2 ^ f(d1, sigma3)
-    d1 = d1 ^ f(d2, sigma4)
+    d2 = d2 ^ this.f(d1, sigma3)
+    d1 = d1 ^ this.f(d2, sigma4)
     ka[0] = d1
     ka[1] = d2
     d1 = (ka[0] ^ kr[0])
     d2 = (ka[1] ^ kr[1])
-    d2 = d2 ^ f(d1, sigma5)
-    d1 = d1 ^ f(d2, sigma6)
+    d2 = d2 ^ this.f(d1, sigma5)
+    d1 = d1 ^ this.f(d2, sigma6)
     kb[0] = d1
     kb[1] = d2
 
@@ -0,0 +1,56 @@
+package camellia
+
+import (
+    "math/bits"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/tool/ct"
+)
+
+var sbox1CT = ct.NewSbox(&sbox1)
+
+// NewCipherCT creates and returns a new Camellia cipher.Block whose
+// F-function does not index sbox1..sbox4 with secret bytes. Only sbox1
+// is kept as a masked truth table (see tool/ct), the other three are
+// rotations of it. FL, FL^-1 and the key schedule are shared with
+// NewCipher. It is about 15 times slower than NewCipher.
+func NewCipherCT(key []byte) (cipher.Block, error) {
+    klen := len(key)
+    switch klen {
+        default:
+            return nil, KeySizeError(klen)
+        case 16, 24, 32:
+            break
+    }
+
+    c := new(camelliaCipher)
+    c.ct = true
+    c.expandKey(key)
+
+    return c, nil
+}
+
+func (this *camelliaCipher) f(fin, ke uint64) uint64 {
+    if this.ct {
+        return fCT(fin, ke)
+    }
+
+    return f(fin, ke)
+}
+
+// F-function, constant time.
+// sbox2, sbox3 和 sbox4 由 sbox1 循环移位得到.
+func fCT(fin, ke uint64) uint64 {
+    x := fin ^ ke
+
+    t1 := sbox1CT.Lookup(uint8(x>>56))
+    t2 := bits.RotateLeft8(sbox1CT.Lookup(uint8(x>>48)), 1)
+    t3 := bits.RotateLeft8(sbox1CT.Lookup(uint8(x>>40)), 7)
+    t4 := sbox1CT.Lookup(bits.RotateLeft8(uint8(x>>32), 1))
+    t5 := bits.RotateLeft8(sbox1CT.Lookup(uint8(x>>24)), 1)
+    t6 := bits.RotateLeft8(sbox1CT.Lookup(uint8(x>>16)), 7)
+    t7 := sbox1CT.Lookup(bits.RotateLeft8(uint8(x>>8), 1))
+    t8 := sbox1CT.Lookup(uint8(x))
+
+    return p(t1, t2, t3, t4, t5, t6, t7, t8)
+}
@@ -3,6 +3,9 @@ package camellia
 import (
     "bytes"
     "testing"
+    "math/rand"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
 )
 
 // Test vectors from http://tools.ietf.org/html/rfc3713
@@ -48,3 +51,37 @@ func TestCamellia(t *testing.T) {
         }
     }
 }
+
+func TestCamelliaCT(t *testing.T) {
+    for _, tt := range camelliaTests {
+        c, _ := NewCipherCT(tt.key)
+        var b [16]byte
+        c.Encrypt(b[:], tt.plain)
+        if !bytes.Equal(b[:], tt.cipher) {
+            t.Errorf("encrypt failed: got %x, want %x", b, tt.cipher)
+        }
+
+        c.Decrypt(b[:], tt.cipher)
+        if !bytes.Equal(b[:], tt.plain) {
+            t.Errorf("decrypt failed: got %x, want %x", b, tt.plain)
+        }
+    }
+}
+
+// DUDECT=1 go test -run Test_CipherCTTiming
+func Test_CipherCTTiming(t *testing.T) {
+    key := make([]byte, 16)
+    rand.Read(key)
+
+    c, err := NewCipherCT(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dst := make([]byte, 16)
+    cryptobin_test.AssertConstantTime(t, cryptobin_test.DudectConfig{
+        Measurements: 200000,
+    }, make([]byte, 16), func(in []byte) {
+        c.Encrypt(dst, in)
+    })
+}
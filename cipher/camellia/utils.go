@@ -37,6 +37,12 @@ func f(fin, ke uint64) uint64 {
     t6 := sbox3[uint8(x>>16)]
     t7 := sbox4[uint8(x>>8)]
     t8 := sbox1[uint8(x)]
+
+    return p(t1, t2, t3, t4, t5, t6, t7, t8)
+}
+
+// P-function
+func p(t1, t2, t3, t4, t5, t6, t7, t8 byte) uint64 {
     y1 := t1 ^ t3 ^ t4 ^ t6 ^ t7 ^ t8
     y2 := t1 ^ t2 ^ t4 ^ t5 ^ t7 ^ t8
     y3 := t1 ^ t2 ^ t3 ^ t5 ^ t6 ^ t8
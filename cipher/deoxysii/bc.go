@@ -0,0 +1,118 @@
+package deoxysii
+
+import (
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/aesround"
+)
+
+const (
+    blockSize = 16
+
+    // Deoxys-BC-384 轮数
+    rounds = 16
+
+    stkCount = rounds + 1
+
+    // 每次批量处理的分组数
+    batchBlocks = 16
+)
+
+var rcon = [stkCount]byte{
+    0x2f, 0x5e, 0xbc, 0x63, 0xc6, 0x97, 0x35, 0x6a,
+    0xd4, 0xb3, 0x7d, 0xfa, 0xef, 0xc5, 0x91, 0x39,
+    0x72,
+}
+
+// h 置换: out[i] = in[h[i]]
+var hPerm = [blockSize]int{
+    1, 6, 11, 12, 5, 10, 15, 0,
+    9, 14, 3, 4, 13, 2, 7, 8,
+}
+
+// 只与密钥有关的子密钥部分: TK2_i ^ TK3_i ^ RC_i
+func deriveKeySTKs(stks *[stkCount][blockSize]byte, tk2, tk3 []byte) {
+    var t2, t3 [blockSize]byte
+    copy(t2[:], tk2)
+    copy(t3[:], tk3)
+
+    for i := 0; i < stkCount; i++ {
+        for j := 0; j < blockSize; j++ {
+            stks[i][j] = t2[j] ^ t3[j]
+        }
+
+        rc := [blockSize]byte{1, 2, 4, 8, rcon[i], rcon[i], rcon[i], rcon[i]}
+        for j := range rc {
+            stks[i][j] ^= rc[j]
+        }
+
+        // LFSR2, LFSR3 后 h 置换
+        var n2, n3 [blockSize]byte
+        for j := 0; j < blockSize; j++ {
+            b2 := t2[hPerm[j]]
+            b3 := t3[hPerm[j]]
+
+            n2[j] = b2<<1 | (b2>>7 ^ b2>>5) & 1
+            n3[j] = b3>>1 | (b3<<7 ^ b3<<1) & 0x80
+        }
+
+        t2, t3 = n2, n3
+    }
+}
+
+// TK1 的 h 置换, lo/hi 为小端序的字节 0-7 和 8-15.
+// 输出 lo 的字节 0-7 依次取自 1,6,11,12,5,10,15,0,
+// 输出 hi 的字节 0-7 依次取自 9,14,3,4,13,2,7,8
+func permuteH(lo, hi uint64) (uint64, uint64) {
+    nlo := (lo >> 8) & 0x000000ff000000ff |
+        (lo >> 40) & 0x000000000000ff00 |
+        lo << 56 |
+        (hi >> 8) & 0x00ff0000ffff0000 |
+        (hi << 24) & 0x0000ff0000000000
+
+    nhi := (hi >> 8) & 0x000000ff000000ff |
+        (hi >> 40) & 0x000000000000ff00 |
+        hi << 56 |
+        (lo >> 8) & 0x00ff0000ffff0000 |
+        (lo << 24) & 0x0000ff0000000000
+
+    return nlo, nhi
+}
+
+// 用各自的 tweak 加密 n 个分组, n 不超过 batchBlocks
+func encryptBatch(stks *[stkCount][blockSize]byte, dst, src, tweaks []byte) {
+    n := len(src) / blockSize
+    size := n * blockSize
+
+    var tk1 [batchBlocks][2]uint64
+    var rk [batchBlocks * blockSize]byte
+    var state [batchBlocks * blockSize]byte
+
+    for b := 0; b < n; b++ {
+        tk1[b][0] = binary.LittleEndian.Uint64(tweaks[b*blockSize:])
+        tk1[b][1] = binary.LittleEndian.Uint64(tweaks[b*blockSize+8:])
+    }
+
+    subtle.XORBytes(state[:size], src[:size], tweaks[:size])
+    for b := 0; b < size; b += blockSize {
+        subtle.XORBytes(state[b:b+blockSize], state[b:b+blockSize], stks[0][:])
+    }
+
+    for r := 1; r <= rounds; r++ {
+        s0 := binary.LittleEndian.Uint64(stks[r][0:])
+        s1 := binary.LittleEndian.Uint64(stks[r][8:])
+
+        for b := 0; b < n; b++ {
+            lo, hi := permuteH(tk1[b][0], tk1[b][1])
+            tk1[b][0], tk1[b][1] = lo, hi
+
+            binary.LittleEndian.PutUint64(rk[b*blockSize:], lo ^ s0)
+            binary.LittleEndian.PutUint64(rk[b*blockSize+8:], hi ^ s1)
+        }
+
+        aesround.Rounds(state[:size], state[:size], rk[:size])
+    }
+
+    copy(dst, state[:size])
+}
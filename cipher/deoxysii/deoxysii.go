@@ -0,0 +1,218 @@
+// Package deoxysii implements the Deoxys-II-256-128 AEAD of the CAESAR
+// final portfolio (Deoxys v1.43), a nonce misuse-resistant mode of the
+// Deoxys-BC-384 tweakable block cipher.
+package deoxysii
+
+import (
+    "errors"
+    "runtime"
+    "strconv"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+const (
+    // KeySize is the size of a key in bytes.
+    KeySize = 32
+
+    // NonceSize is the size of a nonce in bytes.
+    NonceSize = 15
+
+    // TagSize is the size of an authentication tag in bytes.
+    TagSize = 16
+)
+
+// tweak 前缀
+const (
+    prefixADBlock  = 0x2
+    prefixADFinal  = 0x6
+    prefixMsgBlock = 0x0
+    prefixMsgFinal = 0x4
+    prefixTag      = 0x1
+)
+
+var (
+    errOpen = errors.New("go-cryptobin/deoxysii: message authentication failed")
+    errKeySize = errors.New("go-cryptobin/deoxysii: invalid key size")
+)
+
+type deoxysII struct {
+    stks [stkCount][blockSize]byte
+}
+
+// New returns Deoxys-II-256-128 with the 32 bytes key.
+func New(key []byte) (cipher.AEAD, error) {
+    if len(key) != KeySize {
+        return nil, errKeySize
+    }
+
+    d := &deoxysII{}
+    deriveKeySTKs(&d.stks, key[16:32], key[0:16])
+
+    return d, nil
+}
+
+func (d *deoxysII) NonceSize() int {
+    return NonceSize
+}
+
+func (d *deoxysII) Overhead() int {
+    return TagSize
+}
+
+func (d *deoxysII) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+    if len(nonce) != NonceSize {
+        panic("go-cryptobin/deoxysii: incorrect nonce length: " + strconv.Itoa(len(nonce)))
+    }
+
+    ret, out := alias.SliceForAppend(dst, len(plaintext) + TagSize)
+    if alias.InexactOverlap(out, plaintext) {
+        panic("go-cryptobin/deoxysii: invalid buffer overlap")
+    }
+
+    var tag [TagSize]byte
+    d.auth(&tag, nonce, plaintext, additionalData)
+
+    d.ctr(out[:len(plaintext)], plaintext, nonce, &tag)
+    copy(out[len(plaintext):], tag[:])
+
+    return ret
+}
+
+func (d *deoxysII) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+    if len(nonce) != NonceSize {
+        panic("go-cryptobin/deoxysii: incorrect nonce length: " + strconv.Itoa(len(nonce)))
+    }
+
+    if len(ciphertext) < TagSize {
+        return nil, errOpen
+    }
+
+    var tag [TagSize]byte
+    copy(tag[:], ciphertext[len(ciphertext)-TagSize:])
+    ciphertext = ciphertext[:len(ciphertext)-TagSize]
+
+    ret, out := alias.SliceForAppend(dst, len(ciphertext))
+    if alias.InexactOverlap(out, ciphertext) {
+        panic("go-cryptobin/deoxysii: invalid buffer overlap")
+    }
+
+    d.ctr(out, ciphertext, nonce, &tag)
+
+    var expectedTag [TagSize]byte
+    d.auth(&expectedTag, nonce, out, additionalData)
+
+    if subtle.ConstantTimeCompare(expectedTag[:], tag[:]) != 1 {
+        for i := range out {
+            out[i] = 0
+        }
+
+        runtime.KeepAlive(out)
+        return nil, errOpen
+    }
+
+    return ret, nil
+}
+
+// tweak = prefix (4 位) || 分组序号 (124 位大端)
+func putTweak(dst []byte, prefix byte, index uint64) {
+    for i := range dst[:blockSize] {
+        dst[i] = 0
+    }
+
+    binary.BigEndian.PutUint64(dst[8:], index)
+    dst[0] = prefix << 4
+}
+
+// 附加数据和消息的认证值, 再用 nonce 加密得到标签
+func (d *deoxysII) auth(tag *[TagSize]byte, nonce, msg, ad []byte) {
+    var sum [blockSize]byte
+
+    d.hashBlocks(&sum, ad, prefixADBlock, prefixADFinal)
+    d.hashBlocks(&sum, msg, prefixMsgBlock, prefixMsgFinal)
+
+    var tweak [blockSize]byte
+    tweak[0] = prefixTag << 4
+    copy(tweak[1:], nonce)
+
+    encryptBatch(&d.stks, tag[:], sum[:], tweak[:])
+}
+
+// sum ^= E(tweak_i, block_i), 最后不完整分组补 10*
+func (d *deoxysII) hashBlocks(sum *[blockSize]byte, data []byte, prefix, finalPrefix byte) {
+    var src, out, tweaks [batchBlocks * blockSize]byte
+
+    var index uint64
+    for len(data) >= blockSize {
+        n := len(data) / blockSize
+        if n > batchBlocks {
+            n = batchBlocks
+        }
+
+        for i := 0; i < n; i++ {
+            putTweak(tweaks[i*blockSize:], prefix, index)
+            index++
+        }
+
+        size := n * blockSize
+        encryptBatch(&d.stks, out[:size], data[:size], tweaks[:size])
+
+        for i := 0; i < size; i += blockSize {
+            subtle.XORBytes(sum[:], sum[:], out[i:i+blockSize])
+        }
+
+        data = data[size:]
+    }
+
+    if len(data) > 0 {
+        for i := range src[:blockSize] {
+            src[i] = 0
+        }
+
+        copy(src[:], data)
+        src[len(data)] = 0x80
+
+        putTweak(tweaks[:], finalPrefix, index)
+        encryptBatch(&d.stks, out[:blockSize], src[:blockSize], tweaks[:blockSize])
+
+        subtle.XORBytes(sum[:], sum[:], out[:blockSize])
+    }
+}
+
+// C_j = M_j ^ E((1 || tag) ^ j, 0^8 || N)
+func (d *deoxysII) ctr(dst, src, nonce []byte, tag *[TagSize]byte) {
+    var in, ks, tweaks [batchBlocks * blockSize]byte
+
+    for i := 0; i < batchBlocks; i++ {
+        copy(in[i*blockSize+1:], nonce)
+    }
+
+    base := *tag
+    base[0] |= 0x80
+
+    var j uint64
+    for len(src) > 0 {
+        n := (len(src) + blockSize - 1) / blockSize
+        if n > batchBlocks {
+            n = batchBlocks
+        }
+
+        for i := 0; i < n; i++ {
+            t := tweaks[i*blockSize:(i+1)*blockSize]
+            copy(t, base[:])
+
+            c := binary.BigEndian.Uint64(t[8:]) ^ j
+            binary.BigEndian.PutUint64(t[8:], c)
+            j++
+        }
+
+        size := n * blockSize
+        encryptBatch(&d.stks, ks[:size], in[:size], tweaks[:size])
+
+        k := subtle.XORBytes(dst, src, ks[:size])
+        dst, src = dst[k:], src[k:]
+    }
+}
@@ -0,0 +1,147 @@
+package deoxysii
+
+import (
+    "bytes"
+    "testing"
+    "crypto/rand"
+    "crypto/cipher"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+func seqBytes(n int) []byte {
+    b := make([]byte, n)
+    for i := range b {
+        b[i] = byte(i)
+    }
+
+    return b
+}
+
+var testKey = "101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f"
+var testNonce = "202122232425262728292a2b2c2d2e"
+
+// 前三个为 Deoxys v1.43 KAT 的 Test vector 1, 2 和 4, 其余为本实现的回归值
+var testVectors = []struct {
+    adLen  int
+    msgLen int
+    out    string
+}{
+    {0, 0, "2b97bd77712f0cde975309959dfe1d7c"},
+    {32, 0, "54708ae5565a71f147bdb94d7ba3aed7"},
+    {0, 32, "9da20db1c2781f6669257d87e2a4d9be1970f7581bef2c995e1149331e5e8cc192ce3aec3a4b72ff9eab71c2a93492fa"},
+    {33, 33, "7885cda8e93272cc3b91f553d90085fc1c3a13eb3115675e1008fbe84c2aab00e8cf097ff3e24d421f1dd3a7804c7665be"},
+    {16, 160, "fe9486f1bb66e4b2912f8765b714494ed8a035e165b2936faa04b406b92cfd7a6ea96c0ad8635a553979ff2ef9fcf91229e15f37cadca9294d843ddb2a074d66c4ed04e2b9efaed0e4aa27216c8dc29b7c336811419314833374a4fa8c4fe73ecac366808483c74a03331cf08237577021de4108aaf2ef6da60fcf9fc24957abb13b58b8bd457adab3053fce3722a98fd6a0a95e65e5d5fa66380251b1a66e6c0b1d8ca1a6bb360e40d5b1be5caa45ee"},
+}
+
+func Test_Vectors(t *testing.T) {
+    aead, err := New(fromHex(testKey))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    nonce := fromHex(testNonce)
+
+    for i, v := range testVectors {
+        ad := seqBytes(v.adLen)
+        msg := seqBytes(v.msgLen)
+
+        out := aead.Seal(nil, nonce, msg, ad)
+        if hex.EncodeToString(out) != v.out {
+            t.Errorf("[%d] Seal got %x, want %s", i, out, v.out)
+        }
+
+        pt, err := aead.Open(nil, nonce, out, ad)
+        if err != nil {
+            t.Fatalf("[%d] Open: %v", i, err)
+        }
+
+        if !bytes.Equal(pt, msg) {
+            t.Errorf("[%d] Open got %x, want %x", i, pt, msg)
+        }
+    }
+}
+
+func Test_EncryptBatch(t *testing.T) {
+    d := &deoxysII{}
+    deriveKeySTKs(&d.stks, seqBytes(16), seqBytes(32)[16:])
+
+    src := make([]byte, batchBlocks*blockSize)
+    tweaks := make([]byte, batchBlocks*blockSize)
+    rand.Read(src)
+    rand.Read(tweaks)
+
+    batch := make([]byte, len(src))
+    encryptBatch(&d.stks, batch, src, tweaks)
+
+    for i := 0; i < len(src); i += blockSize {
+        var one [blockSize]byte
+        encryptBatch(&d.stks, one[:], src[i:i+blockSize], tweaks[i:i+blockSize])
+
+        if !bytes.Equal(one[:], batch[i:i+blockSize]) {
+            t.Errorf("block %d: got %x, want %x", i/blockSize, batch[i:i+blockSize], one)
+        }
+    }
+}
+
+func Test_RoundTrip(t *testing.T) {
+    key := make([]byte, KeySize)
+    rand.Read(key)
+
+    aead, err := New(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    nonce := make([]byte, aead.NonceSize())
+    rand.Read(nonce)
+
+    for _, n := range []int{0, 1, 15, 16, 17, 127, 128, 129, 257} {
+        msg := make([]byte, n)
+        ad := make([]byte, n / 3)
+        rand.Read(msg)
+        rand.Read(ad)
+
+        ct := aead.Seal(nil, nonce, msg, ad)
+
+        pt, err := aead.Open(nil, nonce, ct, ad)
+        if err != nil {
+            t.Fatalf("%d: %v", n, err)
+        }
+
+        if !bytes.Equal(pt, msg) {
+            t.Errorf("%d: got %x, want %x", n, pt, msg)
+        }
+
+        ct[0] ^= 1
+        if _, err := aead.Open(nil, nonce, ct, ad); err != errOpen {
+            t.Errorf("%d: tampered ciphertext should fail", n)
+        }
+    }
+}
+
+func Test_Errors(t *testing.T) {
+    if _, err := New(make([]byte, 16)); err == nil {
+        t.Error("should fail with bad key size")
+    }
+}
+
+func Benchmark_Seal(b *testing.B) {
+    aead, _ := New(make([]byte, KeySize))
+    benchmarkSeal(b, aead)
+}
+
+func benchmarkSeal(b *testing.B, aead cipher.AEAD) {
+    buf := make([]byte, 8*1024)
+    nonce := make([]byte, aead.NonceSize())
+    b.SetBytes(int64(len(buf)))
+
+    var out []byte
+    for i := 0; i < b.N; i++ {
+        out = aead.Seal(out[:0], nonce, buf, nil)
+    }
+}
@@ -40,6 +40,9 @@ type cbcMode interface {
     SetIV([]byte)
 }
 
+// CipherFunc creates the underlying block cipher, which must have a 16 bytes block size
+type CipherFunc = func(key []byte) (cipher.Block, error)
+
 // A Cipher is an instance of the FF1 mode of format preserving encryption
 // using a particular key, radix, and tweak
 type Cipher struct {
@@ -65,6 +68,12 @@ func NewCipher(radix int, maxTLen int, key []byte, tweak []byte) (*Cipher, error
             return nil, errors.New("go-cryptobin/fpe: key length must be 128, 192, or 256 bits")
     }
 
+    return NewCipherWithFunc(aes.NewCipher, radix, maxTLen, key, tweak)
+}
+
+// NewCipherWithFunc initializes a new FF1 Cipher with the block cipher
+// from cipherFunc, such as sm4.NewCipher.
+func NewCipherWithFunc(cipherFunc CipherFunc, radix int, maxTLen int, key []byte, tweak []byte) (*Cipher, error) {
     // While FF1 allows radices in [2, 2^16],
     // realistically there's a practical limit based on the alphabet that can be passed in
     if (radix < 2) || (radix > big.MaxBase) {
@@ -85,13 +94,16 @@ func NewCipher(radix int, maxTLen int, key []byte, tweak []byte) (*Cipher, error
         return nil, errors.New("go-cryptobin/fpe: minLen invalid, adjust your radix")
     }
 
-    // aes.NewCipher automatically returns the correct block based on the length of the key passed in
-    aesBlock, err := aes.NewCipher(key)
+    block, err := cipherFunc(key)
     if err != nil {
-        return nil, errors.New("go-cryptobin/fpe: failed to create AES block")
+        return nil, errors.New("go-cryptobin/fpe: failed to create cipher block")
+    }
+
+    if block.BlockSize() != blockSize {
+        return nil, errors.New("go-cryptobin/fpe: block size must be 16 bytes")
     }
 
-    cbcEncryptor := cipher.NewCBCEncrypter(aesBlock, ivZero)
+    cbcEncryptor := cipher.NewCBCEncrypter(block, ivZero)
 
     newCipher := &Cipher{}
     newCipher.tweak = tweak
@@ -4,6 +4,8 @@ import (
     "fmt"
     "testing"
     "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/cipher/sm4"
 )
 
 // Test vectors taken from here: http://csrc.nist.gov/groups/ST/toolkit/documents/Examples/FF1samples.pdf
@@ -410,3 +412,33 @@ func BenchmarkEncryptLong(b *testing.B) {
         ff1.Encrypt("xs8a0azh2avyalyzuwdxs8a0azh2avyalyzuwdxs8a0azh2avyalyzuwdxs8a0azh2avyalyzuwdxs8a0azh2avyalyzuwdxs8a0azh2avyalyzuwdxs8a0azh2avyalyzuwd")
     }
 }
+
+func TestNewCipherWithFunc(t *testing.T) {
+    key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+    tweak, _ := hex.DecodeString("3737373770717273")
+
+    c, err := NewCipherWithFunc(sm4.NewCipher, 10, 8, key, tweak)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    plaintext := "890121234567890000"
+
+    ciphertext, err := c.Encrypt(plaintext)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(ciphertext) != len(plaintext) || ciphertext == plaintext {
+        t.Fatalf("Encrypt got %s", ciphertext)
+    }
+
+    got, err := c.Decrypt(ciphertext)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if got != plaintext {
+        t.Errorf("Decrypt got %s, want %s", got, plaintext)
+    }
+}
@@ -26,6 +26,9 @@ var (
     ErrTweakLengthInvalid = errors.New("go-cryptobin/fpe: tweak must be 8 bytes, or 64 bits")
 )
 
+// CipherFunc creates the underlying block cipher, which must have a 16 bytes block size
+type CipherFunc = func(key []byte) (cipher.Block, error)
+
 // A Cipher is an instance of the FF3 mode of format preserving encryption
 // using a particular key, radix, and tweak
 type Cipher struct {
@@ -50,6 +53,12 @@ func NewCipher(radix int, key []byte, tweak []byte) (*Cipher, error) {
             return nil, errors.New("go-cryptobin/fpe: key length must be 128, 192, or 256 bits")
     }
 
+    return NewCipherWithFunc(aes.NewCipher, radix, key, tweak)
+}
+
+// NewCipherWithFunc initializes a new FF3 Cipher with the block cipher
+// from cipherFunc, such as sm4.NewCipher.
+func NewCipherWithFunc(cipherFunc CipherFunc, radix int, key []byte, tweak []byte) (*Cipher, error) {
     // While FF3 allows radices in [2, 2^16], there is a practical limit to 36 (alphanumeric) because the Go math/big library only supports up to base 36.
     if (radix < 2) || (radix > big.MaxBase) {
         return nil, errors.New("go-cryptobin/fpe: radix must be between 2 and 36, inclusive")
@@ -69,11 +78,14 @@ func NewCipher(radix int, key []byte, tweak []byte) (*Cipher, error) {
         return nil, errors.New("go-cryptobin/fpe: minLen or maxLen invalid, adjust your radix")
     }
 
-    // aes.NewCipher automatically returns the correct block based on the length of the key passed in
     // Always use the reversed key since Encrypt and Decrypt call ciph expecting that
-    aesBlock, err := aes.NewCipher(revB(key))
+    aesBlock, err := cipherFunc(revB(append([]byte(nil), key...)))
     if err != nil {
-        return nil, errors.New("go-cryptobin/fpe: failed to create AES block")
+        return nil, errors.New("go-cryptobin/fpe: failed to create cipher block")
+    }
+
+    if aesBlock.BlockSize() != blockSize {
+        return nil, errors.New("go-cryptobin/fpe: block size must be 16 bytes")
     }
 
     newCipher := &Cipher{}
@@ -0,0 +1,252 @@
+package ff3_1
+
+import (
+    "math"
+    "math/big"
+    "errors"
+    "crypto/aes"
+    "crypto/cipher"
+)
+
+// FF3-1 follows NIST SP 800-38G Revision 1, which requires radix^minLength >= 1,000,000
+// and shortens the tweak to 56 bits.
+const (
+    feistelMin = 1000000
+    numRounds  = 8
+    blockSize  = 16
+    tweakLen   = 7
+)
+
+var (
+    // ErrStringNotInRadix is returned if input or intermediate strings cannot be parsed in the given radix
+    ErrStringNotInRadix = errors.New("go-cryptobin/fpe: string is not within base/radix")
+
+    // ErrTweakLengthInvalid is returned if the tweak length is not 7 bytes
+    ErrTweakLengthInvalid = errors.New("go-cryptobin/fpe: tweak must be 7 bytes, or 56 bits")
+)
+
+// CipherFunc creates the underlying block cipher, which must have a 16 bytes block size
+type CipherFunc = func(key []byte) (cipher.Block, error)
+
+// A Cipher is an instance of the FF3-1 mode of format preserving encryption
+// using a particular key, radix, and tweak
+type Cipher struct {
+    tweak  []byte
+    radix  int
+    minLen uint32
+    maxLen uint32
+
+    block cipher.Block
+}
+
+// NewCipher initializes a new FF3-1 Cipher with AES
+// based on the radix, key and tweak parameters.
+func NewCipher(radix int, key []byte, tweak []byte) (*Cipher, error) {
+    // Check if the key is 128, 192, or 256 bits = 16, 24, or 32 bytes
+    switch len(key) {
+        case 16, 24, 32:
+            break
+        default:
+            return nil, errors.New("go-cryptobin/fpe: key length must be 128, 192, or 256 bits")
+    }
+
+    return NewCipherWithFunc(aes.NewCipher, radix, key, tweak)
+}
+
+// NewCipherWithFunc initializes a new FF3-1 Cipher with the block cipher
+// from cipherFunc, such as sm4.NewCipher.
+func NewCipherWithFunc(cipherFunc CipherFunc, radix int, key []byte, tweak []byte) (*Cipher, error) {
+    // math/big only supports up to base 36
+    if (radix < 2) || (radix > big.MaxBase) {
+        return nil, errors.New("go-cryptobin/fpe: radix must be between 2 and 36, inclusive")
+    }
+
+    if len(tweak) != tweakLen {
+        return nil, ErrTweakLengthInvalid
+    }
+
+    // radix^minLen >= 1,000,000 and maxLen = 2 * floor(log_radix(2^96))
+    minLen := uint32(math.Ceil(math.Log(feistelMin) / math.Log(float64(radix))))
+    maxLen := 2 * uint32(math.Floor(96 / math.Log2(float64(radix))))
+
+    if (minLen < 2) || (maxLen < minLen) {
+        return nil, errors.New("go-cryptobin/fpe: minLen or maxLen invalid, adjust your radix")
+    }
+
+    // FF3-1 uses the reversed key
+    revKey := revB(append([]byte(nil), key...))
+
+    block, err := cipherFunc(revKey)
+    if err != nil {
+        return nil, err
+    }
+
+    if block.BlockSize() != blockSize {
+        return nil, errors.New("go-cryptobin/fpe: block size must be 16 bytes")
+    }
+
+    newCipher := &Cipher{}
+    newCipher.tweak = tweak
+    newCipher.radix = radix
+    newCipher.minLen = minLen
+    newCipher.maxLen = maxLen
+    newCipher.block = block
+
+    return newCipher, nil
+}
+
+// Encrypt encrypts the string X over the current FF3-1 parameters
+// and returns the ciphertext of the same length and format
+func (c *Cipher) Encrypt(X string) (string, error) {
+    return c.EncryptWithTweak(X, c.tweak)
+}
+
+// EncryptWithTweak is the same as Encrypt except it uses the
+// tweak from the parameter rather than the current Cipher's tweak
+func (c *Cipher) EncryptWithTweak(X string, tweak []byte) (string, error) {
+    return c.crypt(X, tweak, true)
+}
+
+// Decrypt decrypts the string X over the current FF3-1 parameters
+// and returns the plaintext of the same length and format
+func (c *Cipher) Decrypt(X string) (string, error) {
+    return c.DecryptWithTweak(X, c.tweak)
+}
+
+// DecryptWithTweak is the same as Decrypt except it uses the
+// tweak from the parameter rather than the current Cipher's tweak
+func (c *Cipher) DecryptWithTweak(X string, tweak []byte) (string, error) {
+    return c.crypt(X, tweak, false)
+}
+
+func (c *Cipher) crypt(X string, tweak []byte, encrypt bool) (string, error) {
+    n := uint32(len(X))
+
+    // Check if message length is within minLength and maxLength bounds
+    if (n < c.minLen) || (n > c.maxLen) {
+        return "", errors.New("go-cryptobin/fpe: message length is not within min and max bounds")
+    }
+
+    if len(tweak) != tweakLen {
+        return "", ErrTweakLengthInvalid
+    }
+
+    radix := c.radix
+
+    // Check if the message is in the current radix
+    if _, ok := new(big.Int).SetString(X, radix); !ok {
+        return "", ErrStringNotInRadix
+    }
+
+    // Calculate split point
+    u := (n + 1) / 2
+    v := n - u
+
+    A := X[:u]
+    B := X[u:]
+
+    // Tl = T[0..27] || 0^4, Tr = T[32..55] || T[28..31] || 0^4
+    Tl := []byte{tweak[0], tweak[1], tweak[2], tweak[3] & 0xF0}
+    Tr := []byte{tweak[4], tweak[5], tweak[6], tweak[3] << 4}
+
+    numRadix := big.NewInt(int64(radix))
+    numModU := new(big.Int).Exp(numRadix, big.NewInt(int64(u)), nil)
+    numModV := new(big.Int).Exp(numRadix, big.NewInt(int64(v)), nil)
+
+    P := make([]byte, blockSize)
+
+    for r := 0; r < numRounds; r++ {
+        i := r
+        if !encrypt {
+            i = numRounds - 1 - r
+        }
+
+        var (
+            m    uint32
+            W    []byte
+            mod  *big.Int
+        )
+
+        if i%2 == 0 {
+            m, W, mod = u, Tr, numModU
+        } else {
+            m, W, mod = v, Tl, numModV
+        }
+
+        // the round input is B when encrypting and A when decrypting
+        in, out := B, A
+        if !encrypt {
+            in, out = A, B
+        }
+
+        // P = W xor [i]^4 || [NUM_radix(REV(in))]^12
+        copy(P, W)
+        P[3] ^= byte(i)
+
+        numIn, ok := new(big.Int).SetString(rev(in), radix)
+        if !ok {
+            return "", ErrStringNotInRadix
+        }
+
+        numInBytes := numIn.Bytes()
+        if len(numInBytes) > blockSize-4 {
+            return "", ErrStringNotInRadix
+        }
+
+        for x := 4; x < blockSize-len(numInBytes); x++ {
+            P[x] = 0x00
+        }
+        copy(P[blockSize-len(numInBytes):], numInBytes)
+
+        // S = REVB(CIPH_REVB(K)(REVB(P)))
+        S := revB(append([]byte(nil), P...))
+        c.block.Encrypt(S, S)
+        revB(S)
+
+        numY := new(big.Int).SetBytes(S)
+
+        numC, ok := new(big.Int).SetString(rev(out), radix)
+        if !ok {
+            return "", ErrStringNotInRadix
+        }
+
+        if encrypt {
+            numC.Add(numC, numY)
+        } else {
+            numC.Sub(numC, numY)
+        }
+
+        numC.Mod(numC, mod)
+
+        C := numC.Text(radix)
+
+        // Need to pad the text with leading 0s first to make sure it's the correct length
+        for len(C) < int(m) {
+            C = "0" + C
+        }
+        C = rev(C)
+
+        if encrypt {
+            A, B = B, C
+        } else {
+            B, A = A, C
+        }
+    }
+
+    return A + B, nil
+}
+
+// rev reverses a string
+func rev(s string) string {
+    return string(revB([]byte(s)))
+}
+
+// revB reverses a byte slice in place
+func revB(a []byte) []byte {
+    for i := len(a)/2 - 1; i >= 0; i-- {
+        opp := len(a) - 1 - i
+        a[i], a[opp] = a[opp], a[i]
+    }
+
+    return a
+}
@@ -0,0 +1,154 @@
+package ff3_1
+
+import (
+    "fmt"
+    "testing"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+)
+
+// Test vectors from the NIST ACVP FF3-1 samples
+
+type testVector struct {
+    radix      int
+    key        string
+    tweak      string
+    plaintext  string
+    ciphertext string
+}
+
+var testVectors = []testVector{
+    {
+        10,
+        "2DE79D232DF5585D68CE47882AE256D6",
+        "CBD09280979564",
+        "3992520240",
+        "8901801106",
+    },
+    {
+        10,
+        "01C63017111438F7FC8E24EB16C71AB5",
+        "C4E822DCD09F27",
+        "60761757463116869318437658042297305934914824457484538562",
+        "35637144092473838892796702739628394376915177448290847293",
+    },
+}
+
+func TestEncrypt(t *testing.T) {
+    for idx, testVector := range testVectors {
+        sampleNumber := idx + 1
+        t.Run(fmt.Sprintf("Sample%d", sampleNumber), func(t *testing.T) {
+            key, _ := hex.DecodeString(testVector.key)
+            tweak, _ := hex.DecodeString(testVector.tweak)
+
+            ff3_1, err := NewCipher(testVector.radix, key, tweak)
+            if err != nil {
+                t.Fatalf("Unable to create cipher: %v", err)
+            }
+
+            ciphertext, err := ff3_1.Encrypt(testVector.plaintext)
+            if err != nil {
+                t.Fatalf("%v", err)
+            }
+
+            if ciphertext != testVector.ciphertext {
+                t.Fatalf("\nSample%d\nRadix:\t\t%d\nKey:\t\t%s\nTweak:\t\t%s\nPlaintext:\t%s\nCiphertext:\t%s\nExpected:\t%s", sampleNumber, testVector.radix, testVector.key, testVector.tweak, testVector.plaintext, ciphertext, testVector.ciphertext)
+            }
+        })
+    }
+}
+
+func TestDecrypt(t *testing.T) {
+    for idx, testVector := range testVectors {
+        sampleNumber := idx + 1
+        t.Run(fmt.Sprintf("Sample%d", sampleNumber), func(t *testing.T) {
+            key, _ := hex.DecodeString(testVector.key)
+            tweak, _ := hex.DecodeString(testVector.tweak)
+
+            ff3_1, err := NewCipher(testVector.radix, key, tweak)
+            if err != nil {
+                t.Fatalf("Unable to create cipher: %v", err)
+            }
+
+            plaintext, err := ff3_1.Decrypt(testVector.ciphertext)
+            if err != nil {
+                t.Fatalf("%v", err)
+            }
+
+            if plaintext != testVector.plaintext {
+                t.Fatalf("\nSample%d\nRadix:\t\t%d\nKey:\t\t%s\nTweak:\t\t%s\nCiphertext:\t%s\nPlaintext:\t%s\nExpected:\t%s", sampleNumber, testVector.radix, testVector.key, testVector.tweak, testVector.ciphertext, plaintext, testVector.plaintext)
+            }
+        })
+    }
+}
+
+func TestSM4(t *testing.T) {
+    key, _ := hex.DecodeString("0123456789ABCDEFFEDCBA9876543210")
+    tweak, _ := hex.DecodeString("CBD09280979564")
+
+    c, err := NewCipherWithFunc(sm4.NewCipher, 36, key, tweak)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    plaintext := "0123456789abcdefghijklmnopqrstuvwxyz"
+
+    ciphertext, err := c.Encrypt(plaintext)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(ciphertext) != len(plaintext) || ciphertext == plaintext {
+        t.Fatalf("Encrypt got %s", ciphertext)
+    }
+
+    got, err := c.Decrypt(ciphertext)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if got != plaintext {
+        t.Errorf("Decrypt got %s, want %s", got, plaintext)
+    }
+
+    // the key is not changed
+    if hex.EncodeToString(key) != "0123456789abcdeffedcba9876543210" {
+        t.Error("key is changed")
+    }
+}
+
+func TestBounds(t *testing.T) {
+    key, _ := hex.DecodeString("2DE79D232DF5585D68CE47882AE256D6")
+    tweak, _ := hex.DecodeString("CBD09280979564")
+
+    c, err := NewCipher(10, key, tweak)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // minLen is 6 and maxLen is 56 for radix 10
+    if _, err := c.Encrypt("123456"); err != nil {
+        t.Error(err)
+    }
+
+    if _, err := c.Encrypt(testVectors[1].plaintext + "0"); err == nil {
+        t.Error("long message want error")
+    }
+
+    if _, err := c.Encrypt("12345"); err == nil {
+        t.Error("short message want error")
+    }
+
+    if _, err := c.Encrypt("12345a"); err != ErrStringNotInRadix {
+        t.Errorf("want ErrStringNotInRadix, got %v", err)
+    }
+
+    if _, err := c.EncryptWithTweak("123456", tweak[:6]); err != ErrTweakLengthInvalid {
+        t.Errorf("want ErrTweakLengthInvalid, got %v", err)
+    }
+
+    if _, err := NewCipher(10, key, make([]byte, 8)); err != ErrTweakLengthInvalid {
+        t.Errorf("want ErrTweakLengthInvalid, got %v", err)
+    }
+}
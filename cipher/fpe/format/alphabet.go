@@ -0,0 +1,141 @@
+package format
+
+import (
+    "errors"
+    "math/big"
+)
+
+// numerals of the engines
+const numerals = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var (
+    Digits     = MustAlphabet("0123456789")
+    Lower      = MustAlphabet("abcdefghijklmnopqrstuvwxyz")
+    Upper      = MustAlphabet("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+    Hex        = MustAlphabet("0123456789abcdef")
+    LowerAlnum = MustAlphabet("0123456789abcdefghijklmnopqrstuvwxyz")
+    UpperAlnum = MustAlphabet("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+)
+
+// Alphabet is the characters of a radix, from 2 to 36 characters.
+// As a Format, the characters in the alphabet are encrypted
+// and the others are kept.
+type Alphabet struct {
+    chars []rune
+    index map[rune]int
+}
+
+// NewAlphabet returns an Alphabet of the different characters
+func NewAlphabet(chars string) (*Alphabet, error) {
+    runes := []rune(chars)
+    if len(runes) < 2 || len(runes) > big.MaxBase {
+        return nil, errors.New("go-cryptobin/fpe: alphabet must have between 2 and 36 characters")
+    }
+
+    index := make(map[rune]int, len(runes))
+    for i, r := range runes {
+        if _, ok := index[r]; ok {
+            return nil, errors.New("go-cryptobin/fpe: alphabet has duplicate characters")
+        }
+
+        index[r] = i
+    }
+
+    return &Alphabet{
+        chars: runes,
+        index: index,
+    }, nil
+}
+
+// MustAlphabet is like NewAlphabet but panics if chars is invalid
+func MustAlphabet(chars string) *Alphabet {
+    a, err := NewAlphabet(chars)
+    if err != nil {
+        panic(err)
+    }
+
+    return a
+}
+
+// Radix returns the number of characters
+func (a *Alphabet) Radix() int {
+    return len(a.chars)
+}
+
+// Contains reports whether r is in the alphabet
+func (a *Alphabet) Contains(r rune) bool {
+    _, ok := a.index[r]
+    return ok
+}
+
+// String returns the characters
+func (a *Alphabet) String() string {
+    return string(a.chars)
+}
+
+// Encrypt encrypts the characters of s in the alphabet
+func (a *Alphabet) Encrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return a.crypt(c, s, tweak, true)
+}
+
+// Decrypt decrypts the characters of s in the alphabet
+func (a *Alphabet) Decrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return a.crypt(c, s, tweak, false)
+}
+
+func (a *Alphabet) crypt(c *Cipher, s string, tweak []byte, encrypt bool) (string, error) {
+    runes := []rune(s)
+
+    var pos []int
+    var text []rune
+    for i, r := range runes {
+        if a.Contains(r) {
+            pos = append(pos, i)
+            text = append(text, r)
+        }
+    }
+
+    out, err := c.cryptText(a, string(text), tweak, encrypt)
+    if err != nil {
+        return "", err
+    }
+
+    for i, r := range []rune(out) {
+        runes[pos[i]] = r
+    }
+
+    return string(runes), nil
+}
+
+// 转换为引擎使用的数字字符串
+func (a *Alphabet) toNumerals(s []rune) (string, error) {
+    x := make([]byte, len(s))
+    for i, r := range s {
+        n, ok := a.index[r]
+        if !ok {
+            return "", ErrNotInAlphabet
+        }
+
+        x[i] = numerals[n]
+    }
+
+    return string(x), nil
+}
+
+func (a *Alphabet) fromNumerals(x string) []rune {
+    s := make([]rune, len(x))
+    for i := 0; i < len(x); i++ {
+        c := x[i]
+
+        var n int
+        if c <= '9' {
+            n = int(c - '0')
+        } else {
+            n = int(c - 'a') + 10
+        }
+
+        s[i] = a.chars[n]
+    }
+
+    return s
+}
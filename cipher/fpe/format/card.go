@@ -0,0 +1,112 @@
+package format
+
+import (
+    "errors"
+)
+
+var (
+    ErrInvalidCardNumber = errors.New("go-cryptobin/fpe: invalid card number")
+)
+
+// DefaultCreditCard keeps the 6 digits BIN and the last 4 digits
+var DefaultCreditCard = CreditCard{
+    KeepPrefix: 6,
+    KeepSuffix: 4,
+}
+
+// CreditCard encrypts the digits of a card number between the kept prefix
+// and suffix. The Luhn check of the number is preserved by cycle walking,
+// so a valid number is encrypted to a valid number.
+// Spaces and '-' are kept.
+type CreditCard struct {
+    KeepPrefix int
+    KeepSuffix int
+}
+
+// Encrypt encrypts the card number
+func (cc CreditCard) Encrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return cc.crypt(c, s, tweak, true)
+}
+
+// Decrypt decrypts the card number
+func (cc CreditCard) Decrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return cc.crypt(c, s, tweak, false)
+}
+
+func (cc CreditCard) crypt(c *Cipher, s string, tweak []byte, encrypt bool) (string, error) {
+    runes := []rune(s)
+
+    var pos []int
+    var digits []byte
+    for i, r := range runes {
+        switch {
+            case r >= '0' && r <= '9':
+                pos = append(pos, i)
+                digits = append(digits, byte(r))
+            case r == ' ' || r == '-':
+            default:
+                return "", ErrInvalidCardNumber
+        }
+    }
+
+    start, end := cc.KeepPrefix, len(digits) - cc.KeepSuffix
+    if start < 0 || end <= start {
+        return "", ErrInvalidCardNumber
+    }
+
+    residue := luhnSum(digits)
+
+    // 循环加密直到 Luhn 校验值不变
+    for {
+        out, err := c.cryptNumerals(10, string(digits[start:end]), tweak, encrypt)
+        if err != nil {
+            return "", err
+        }
+
+        copy(digits[start:end], out)
+
+        if luhnSum(digits) == residue {
+            break
+        }
+    }
+
+    for i, p := range pos {
+        runes[p] = rune(digits[i])
+    }
+
+    return string(runes), nil
+}
+
+// Luhn 校验和, 有效的号码为 0
+func luhnSum(digits []byte) int {
+    sum := 0
+    for i := 0; i < len(digits); i++ {
+        d := int(digits[len(digits)-1-i] - '0')
+        if i % 2 == 1 {
+            d *= 2
+            if d > 9 {
+                d -= 9
+            }
+        }
+
+        sum += d
+    }
+
+    return sum % 10
+}
+
+// LuhnValid reports whether the digits of s pass the Luhn check
+func LuhnValid(s string) bool {
+    var digits []byte
+    for _, r := range s {
+        switch {
+            case r >= '0' && r <= '9':
+                digits = append(digits, byte(r))
+            case r == ' ' || r == '-':
+            default:
+                return false
+        }
+    }
+
+    return len(digits) > 1 && luhnSum(digits) == 0
+}
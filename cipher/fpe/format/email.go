@@ -0,0 +1,85 @@
+package format
+
+import (
+    "errors"
+    "strings"
+)
+
+var (
+    ErrInvalidEmail = errors.New("go-cryptobin/fpe: invalid email address")
+)
+
+// Email encrypts the letters and digits of the local part of an email
+// address, the other characters and the domain are kept. Upper case
+// letters keep their positions by cycle walking, so that the case of
+// the address is kept too.
+type Email struct{}
+
+// Encrypt encrypts the email address
+func (Email) Encrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return cryptEmail(c, s, tweak, true)
+}
+
+// Decrypt decrypts the email address
+func (Email) Decrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return cryptEmail(c, s, tweak, false)
+}
+
+func cryptEmail(c *Cipher, s string, tweak []byte, encrypt bool) (string, error) {
+    at := strings.LastIndexByte(s, '@')
+    if at <= 0 || at == len(s) - 1 {
+        return "", ErrInvalidEmail
+    }
+
+    local := []rune(s[:at])
+
+    var pos []int
+    var upper []bool
+    var text []rune
+    for i, r := range local {
+        isUpper := r >= 'A' && r <= 'Z'
+        if isUpper {
+            r += 'a' - 'A'
+        }
+
+        if LowerAlnum.Contains(r) {
+            pos = append(pos, i)
+            upper = append(upper, isUpper)
+            text = append(text, r)
+        }
+    }
+
+    // 循环加密直到大写字母的位置都为字母
+    out := string(text)
+    for {
+        var err error
+        out, err = c.cryptText(LowerAlnum, out, tweak, encrypt)
+        if err != nil {
+            return "", err
+        }
+
+        if lettersAt([]rune(out), upper) {
+            break
+        }
+    }
+
+    for i, r := range []rune(out) {
+        if upper[i] {
+            r -= 'a' - 'A'
+        }
+
+        local[pos[i]] = r
+    }
+
+    return string(local) + s[at:], nil
+}
+
+func lettersAt(s []rune, upper []bool) bool {
+    for i, r := range s {
+        if upper[i] && (r < 'a' || r > 'z') {
+            return false
+        }
+    }
+
+    return true
+}
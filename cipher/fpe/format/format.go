@@ -0,0 +1,126 @@
+package format
+
+import (
+    "sync"
+    "errors"
+
+    "github.com/deatil/go-cryptobin/cipher/fpe/ff1"
+    "github.com/deatil/go-cryptobin/cipher/fpe/ff3_1"
+)
+
+var (
+    ErrNotInAlphabet = errors.New("go-cryptobin/fpe: character is not in the alphabet")
+)
+
+// Engine is a FPE algorithm over numeral strings in a radix,
+// such as *ff1.Cipher, *ff3.Cipher and *ff3_1.Cipher
+type Engine interface {
+    EncryptWithTweak(X string, tweak []byte) (string, error)
+    DecryptWithTweak(X string, tweak []byte) (string, error)
+}
+
+// EngineFunc creates the Engine for the radix
+type EngineFunc = func(radix int) (Engine, error)
+
+// FF1 returns the EngineFunc of FF1 with the block cipher from cipherFunc
+func FF1(cipherFunc ff1.CipherFunc, key []byte, maxTLen int) EngineFunc {
+    return func(radix int) (Engine, error) {
+        return ff1.NewCipherWithFunc(cipherFunc, radix, maxTLen, key, nil)
+    }
+}
+
+// FF3_1 returns the EngineFunc of FF3-1 with the block cipher from cipherFunc.
+// The tweak of FF3-1 must be 7 bytes.
+func FF3_1(cipherFunc ff3_1.CipherFunc, key []byte) EngineFunc {
+    return func(radix int) (Engine, error) {
+        return ff3_1.NewCipherWithFunc(cipherFunc, radix, key, make([]byte, 7))
+    }
+}
+
+// Format encrypts a kind of string and keeps its format
+type Format interface {
+    Encrypt(c *Cipher, s string, tweak []byte) (string, error)
+    Decrypt(c *Cipher, s string, tweak []byte) (string, error)
+}
+
+// Cipher encrypts strings over alphabets with the engines of each radix
+type Cipher struct {
+    newEngine EngineFunc
+
+    mu      sync.Mutex
+    engines map[int]Engine
+}
+
+// New returns a Cipher with the EngineFunc
+func New(fn EngineFunc) *Cipher {
+    return &Cipher{
+        newEngine: fn,
+        engines:   make(map[int]Engine),
+    }
+}
+
+// Encrypt encrypts s with the format
+func (c *Cipher) Encrypt(f Format, s string, tweak []byte) (string, error) {
+    return f.Encrypt(c, s, tweak)
+}
+
+// Decrypt decrypts s with the format
+func (c *Cipher) Decrypt(f Format, s string, tweak []byte) (string, error) {
+    return f.Decrypt(c, s, tweak)
+}
+
+// EncryptText encrypts s, all characters of which must be in the alphabet
+func (c *Cipher) EncryptText(alphabet *Alphabet, s string, tweak []byte) (string, error) {
+    return c.cryptText(alphabet, s, tweak, true)
+}
+
+// DecryptText decrypts s, all characters of which must be in the alphabet
+func (c *Cipher) DecryptText(alphabet *Alphabet, s string, tweak []byte) (string, error) {
+    return c.cryptText(alphabet, s, tweak, false)
+}
+
+func (c *Cipher) cryptText(alphabet *Alphabet, s string, tweak []byte, encrypt bool) (string, error) {
+    x, err := alphabet.toNumerals([]rune(s))
+    if err != nil {
+        return "", err
+    }
+
+    y, err := c.cryptNumerals(alphabet.Radix(), x, tweak, encrypt)
+    if err != nil {
+        return "", err
+    }
+
+    return string(alphabet.fromNumerals(y)), nil
+}
+
+// 加密基数为 radix 的数字字符串
+func (c *Cipher) cryptNumerals(radix int, x string, tweak []byte, encrypt bool) (string, error) {
+    engine, err := c.engine(radix)
+    if err != nil {
+        return "", err
+    }
+
+    if encrypt {
+        return engine.EncryptWithTweak(x, tweak)
+    }
+
+    return engine.DecryptWithTweak(x, tweak)
+}
+
+func (c *Cipher) engine(radix int) (Engine, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if engine, ok := c.engines[radix]; ok {
+        return engine, nil
+    }
+
+    engine, err := c.newEngine(radix)
+    if err != nil {
+        return nil, err
+    }
+
+    c.engines[radix] = engine
+
+    return engine, nil
+}
@@ -0,0 +1,206 @@
+package format
+
+import (
+    "strings"
+    "testing"
+    "crypto/aes"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+)
+
+func fromHex(s string) []byte {
+    b, _ := hex.DecodeString(s)
+    return b
+}
+
+func testCiphers() map[string]*Cipher {
+    key := fromHex("2DE79D232DF5585D68CE47882AE256D6")
+
+    return map[string]*Cipher{
+        "FF1-AES":   New(FF1(aes.NewCipher, key, 16)),
+        "FF1-SM4":   New(FF1(sm4.NewCipher, key, 16)),
+        "FF3_1-AES": New(FF3_1(aes.NewCipher, key)),
+        "FF3_1-SM4": New(FF3_1(sm4.NewCipher, key)),
+    }
+}
+
+func Test_EncryptText_Vectors(t *testing.T) {
+    c := New(FF3_1(aes.NewCipher, fromHex("2DE79D232DF5585D68CE47882AE256D6")))
+
+    out, err := c.EncryptText(Digits, "3992520240", fromHex("CBD09280979564"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if out != "8901801106" {
+        t.Errorf("FF3-1 got %s", out)
+    }
+
+    c = New(FF1(aes.NewCipher, fromHex("2B7E151628AED2A6ABF7158809CF4F3C"), 16))
+
+    out, err = c.EncryptText(Digits, "0123456789", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if out != "2433477484" {
+        t.Errorf("FF1 got %s", out)
+    }
+
+    // 字母表中的字符映射为数字
+    abc := MustAlphabet("ABCDEFGHIJ")
+    out, err = c.EncryptText(abc, "ABCDEFGHIJ", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if out != "CEDDEHHEIE" {
+        t.Errorf("FF1 alphabet got %s", out)
+    }
+
+    if _, err := c.EncryptText(abc, "ABCDEFGHIK", nil); err != ErrNotInAlphabet {
+        t.Errorf("want ErrNotInAlphabet, got %v", err)
+    }
+}
+
+func Test_Formats(t *testing.T) {
+    tweak := fromHex("C4E822DCD09F27")
+
+    tests := []struct {
+        name   string
+        format Format
+        input  string
+        check  func(t *testing.T, in, out string)
+    }{
+        {
+            "CreditCard", DefaultCreditCard, "4111 1111 1111 1111",
+            func(t *testing.T, in, out string) {
+                if !LuhnValid(out) || out[:7] != in[:7] || out[15:] != in[15:] {
+                    t.Errorf("got %s", out)
+                }
+            },
+        },
+        {
+            "CreditCardAll", CreditCard{}, "5555-5555-5555-4444",
+            func(t *testing.T, in, out string) {
+                if !LuhnValid(out) || strings.Count(out, "-") != 3 {
+                    t.Errorf("got %s", out)
+                }
+            },
+        },
+        {
+            "ChineseID", ChineseID{}, "11010519491231002X",
+            func(t *testing.T, in, out string) {
+                if !ChineseIDValid(out) || out[:6] != "110105" {
+                    t.Errorf("got %s", out)
+                }
+            },
+        },
+        {
+            "Email", Email{}, "John.Smith+news@example.com",
+            func(t *testing.T, in, out string) {
+                if !strings.HasSuffix(out, "@example.com") ||
+                    out[4] != '.' || out[10] != '+' {
+                    t.Errorf("got %s", out)
+                }
+
+                for i := range in {
+                    if (in[i] >= 'A' && in[i] <= 'Z') != (out[i] >= 'A' && out[i] <= 'Z') {
+                        t.Errorf("case changed: %s", out)
+                    }
+                }
+            },
+        },
+        {
+            "Mask", NewMask("###-##-####", Digits), "123-45-6789",
+            func(t *testing.T, in, out string) {
+                if out[3] != '-' || out[6] != '-' {
+                    t.Errorf("got %s", out)
+                }
+            },
+        },
+        {
+            "Alphabet", Hex, "de:ad:be:ef:01:23",
+            func(t *testing.T, in, out string) {
+                if strings.Count(out, ":") != 5 || out[2] != ':' {
+                    t.Errorf("got %s", out)
+                }
+            },
+        },
+    }
+
+    for name, c := range testCiphers() {
+        for _, tt := range tests {
+            t.Run(name + "/" + tt.name, func(t *testing.T) {
+                out, err := c.Encrypt(tt.format, tt.input, tweak)
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                if out == tt.input || len(out) != len(tt.input) {
+                    t.Fatalf("Encrypt got %s", out)
+                }
+
+                tt.check(t, tt.input, out)
+
+                got, err := c.Decrypt(tt.format, out, tweak)
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                if got != tt.input {
+                    t.Errorf("Decrypt got %s, want %s", got, tt.input)
+                }
+            })
+        }
+    }
+}
+
+func Test_Format_Errors(t *testing.T) {
+    c := testCiphers()["FF3_1-SM4"]
+    tweak := fromHex("C4E822DCD09F27")
+
+    if _, err := c.Encrypt(DefaultCreditCard, "4111-1111-1111-111a", tweak); err != ErrInvalidCardNumber {
+        t.Errorf("want ErrInvalidCardNumber, got %v", err)
+    }
+
+    if _, err := c.Encrypt(ChineseID{}, "110105194912310021", tweak); err != ErrInvalidIDNumber {
+        t.Errorf("want ErrInvalidIDNumber, got %v", err)
+    }
+
+    if _, err := c.Encrypt(Email{}, "example.com", tweak); err != ErrInvalidEmail {
+        t.Errorf("want ErrInvalidEmail, got %v", err)
+    }
+
+    if _, err := c.Encrypt(NewMask("###-##", Digits), "123456", tweak); err != ErrMaskMismatch {
+        t.Errorf("want ErrMaskMismatch, got %v", err)
+    }
+
+    // FF3-1 的 tweak 为 7 字节
+    if _, err := c.EncryptText(Digits, "1234567890", tweak[:4]); err == nil {
+        t.Error("short tweak want error")
+    }
+
+    if _, err := NewAlphabet("aab"); err == nil {
+        t.Error("duplicate alphabet want error")
+    }
+
+    if _, err := NewAlphabet("a"); err == nil {
+        t.Error("short alphabet want error")
+    }
+}
+
+func Test_LuhnValid(t *testing.T) {
+    for _, s := range []string{"4111111111111111", "5555 5555 5555 4444", "79927398713"} {
+        if !LuhnValid(s) {
+            t.Errorf("%s want valid", s)
+        }
+    }
+
+    for _, s := range []string{"4111111111111112", "79927398710", "1"} {
+        if LuhnValid(s) {
+            t.Errorf("%s want invalid", s)
+        }
+    }
+}
@@ -0,0 +1,72 @@
+package format
+
+import (
+    "errors"
+)
+
+var (
+    ErrInvalidIDNumber = errors.New("go-cryptobin/fpe: invalid ID number")
+)
+
+// ChineseID encrypts the 18 characters resident identity number of
+// GB 11643. The 6 digits region code is kept, the birth date and
+// sequence code are encrypted and the ISO 7064 MOD 11-2 check
+// character is recomputed.
+type ChineseID struct{}
+
+// Encrypt encrypts the ID number
+func (ChineseID) Encrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return cryptChineseID(c, s, tweak, true)
+}
+
+// Decrypt decrypts the ID number
+func (ChineseID) Decrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return cryptChineseID(c, s, tweak, false)
+}
+
+func cryptChineseID(c *Cipher, s string, tweak []byte, encrypt bool) (string, error) {
+    if !ChineseIDValid(s) {
+        return "", ErrInvalidIDNumber
+    }
+
+    out, err := c.cryptNumerals(10, s[6:17], tweak, encrypt)
+    if err != nil {
+        return "", err
+    }
+
+    body := s[:6] + out
+
+    return body + string(chineseIDCheck(body)), nil
+}
+
+// ChineseIDValid reports whether s is 18 characters with a valid check character
+func ChineseIDValid(s string) bool {
+    if len(s) != 18 {
+        return false
+    }
+
+    for i := 0; i < 17; i++ {
+        if s[i] < '0' || s[i] > '9' {
+            return false
+        }
+    }
+
+    check := s[17]
+    if check == 'x' {
+        check = 'X'
+    }
+
+    return check == chineseIDCheck(s[:17])
+}
+
+// ISO 7064 MOD 11-2 校验字符
+func chineseIDCheck(body string) byte {
+    sum := 0
+    weight := 1
+    for i := len(body) - 1; i >= 0; i-- {
+        weight = weight * 2 % 11
+        sum += int(body[i] - '0') * weight
+    }
+
+    return "10X98765432"[sum % 11]
+}
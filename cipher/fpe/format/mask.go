@@ -0,0 +1,71 @@
+package format
+
+import (
+    "errors"
+)
+
+var (
+    ErrMaskMismatch = errors.New("go-cryptobin/fpe: string does not match the mask")
+)
+
+// Mask encrypts the positions of '#' in the pattern over the alphabet,
+// keeps any character at the positions of '*',
+// and the other characters of the pattern must match.
+// For example "###-##-####" for US SSN and "******######****" for card numbers.
+type Mask struct {
+    Pattern  string
+    Alphabet *Alphabet
+}
+
+// NewMask returns a Mask
+func NewMask(pattern string, alphabet *Alphabet) Mask {
+    return Mask{
+        Pattern:  pattern,
+        Alphabet: alphabet,
+    }
+}
+
+// Encrypt encrypts s with the mask
+func (m Mask) Encrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return m.crypt(c, s, tweak, true)
+}
+
+// Decrypt decrypts s with the mask
+func (m Mask) Decrypt(c *Cipher, s string, tweak []byte) (string, error) {
+    return m.crypt(c, s, tweak, false)
+}
+
+func (m Mask) crypt(c *Cipher, s string, tweak []byte, encrypt bool) (string, error) {
+    runes := []rune(s)
+    pattern := []rune(m.Pattern)
+
+    if len(runes) != len(pattern) {
+        return "", ErrMaskMismatch
+    }
+
+    var pos []int
+    var text []rune
+    for i, p := range pattern {
+        switch p {
+            case '#':
+                pos = append(pos, i)
+                text = append(text, runes[i])
+            case '*':
+            default:
+                if runes[i] != p {
+                    return "", ErrMaskMismatch
+                }
+        }
+    }
+
+    out, err := c.cryptText(m.Alphabet, string(text), tweak, encrypt)
+    if err != nil {
+        return "", err
+    }
+
+    for i, r := range []rune(out) {
+        runes[pos[i]] = r
+    }
+
+    return string(runes), nil
+}
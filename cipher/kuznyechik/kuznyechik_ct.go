@@ -0,0 +1,170 @@
+package kuznyechik
+
+import (
+    "fmt"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/tool/ct"
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+var (
+    sCT  = ct.NewSbox(&s)
+    isCT = ct.NewSbox(&is)
+
+    // lCols[j][b] 为只有第 j 字节的第 b 位为 1 时 L 变换的结果,
+    // L 为线性变换, 按输入的位选取并异或即可, 无需查表
+    lCols  [16][8][2]uint64
+    ilCols [16][8][2]uint64
+)
+
+func init() {
+    for j := 0; j < 16; j++ {
+        for b := 0; b < 8; b++ {
+            var x, e [2]uint64
+
+            // S(is[0]) = 0
+            for k := 0; k < 16; k++ {
+                x[k/8] |= uint64(is[0]) << (8 * (k % 8))
+            }
+
+            x[j/8] &^= 0xff << (8 * (j % 8))
+            x[j/8] |= uint64(is[1<<b]) << (8 * (j % 8))
+
+            e[j/8] = 1 << (8 * (j % 8) + b)
+
+            lCols[j][b][0], lCols[j][b][1] = ls(x[0], x[1])
+            ilCols[j][b][0], ilCols[j][b][1] = ilss(e[0], e[1])
+        }
+    }
+}
+
+type kuznyechikCipherCT struct {
+    rk [10][2]uint64
+}
+
+// NewCipherCT creates and returns a new Kuznyechik cipher.Block that does
+// not use the 64 KiB LS tables t and it. The S-box is read through a
+// masked truth table (see tool/ct) and L is computed from its columns
+// selected by bit masks. It is the slowest of the constant time ciphers,
+// about 40 times slower than NewCipher.
+func NewCipherCT(key []byte) (cipher.Block, error) {
+    if len(key) != 32 {
+        return nil, fmt.Errorf("go-cryptobin/kuznyechik: invalid key size %d", len(key))
+    }
+
+    k := new(kuznyechikCipherCT)
+    k.expandKey(key)
+
+    return k, nil
+}
+
+func (k *kuznyechikCipherCT) BlockSize() int {
+    return BlockSize
+}
+
+func (k *kuznyechikCipherCT) Encrypt(dst, src []byte) {
+    if len(src) < BlockSize {
+        panic("go-cryptobin/kuznyechik: input not full block")
+    }
+
+    if len(dst) < BlockSize {
+        panic("go-cryptobin/kuznyechik: output not full block")
+    }
+
+    if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
+        panic("go-cryptobin/kuznyechik: invalid buffer overlap")
+    }
+
+    x1 := getu64(src[0:]) ^ k.rk[0][0]
+    x2 := getu64(src[8:]) ^ k.rk[0][1]
+
+    for i := 1; i < 10; i++ {
+        x1, x2 = linearCT(&lCols, substituteCT(sCT, x1), substituteCT(sCT, x2))
+        x1 ^= k.rk[i][0]
+        x2 ^= k.rk[i][1]
+    }
+
+    putu64(dst[0:], x1)
+    putu64(dst[8:], x2)
+}
+
+func (k *kuznyechikCipherCT) Decrypt(dst, src []byte) {
+    if len(src) < BlockSize {
+        panic("go-cryptobin/kuznyechik: input not full block")
+    }
+
+    if len(dst) < BlockSize {
+        panic("go-cryptobin/kuznyechik: output not full block")
+    }
+
+    if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
+        panic("go-cryptobin/kuznyechik: invalid buffer overlap")
+    }
+
+    x1 := getu64(src[0:]) ^ k.rk[9][0]
+    x2 := getu64(src[8:]) ^ k.rk[9][1]
+
+    for i := 8; i >= 0; i-- {
+        x1, x2 = linearCT(&ilCols, x1, x2)
+        x1 = substituteCT(isCT, x1) ^ k.rk[i][0]
+        x2 = substituteCT(isCT, x2) ^ k.rk[i][1]
+    }
+
+    putu64(dst[0:], x1)
+    putu64(dst[8:], x2)
+}
+
+func (k *kuznyechikCipherCT) expandKey(key []byte) {
+    k00 := getu64(key[0:])
+    k01 := getu64(key[8:])
+    k10 := getu64(key[16:])
+    k11 := getu64(key[24:])
+
+    k.rk[0] = [2]uint64{k00, k01}
+    k.rk[1] = [2]uint64{k10, k11}
+
+    for i := 2; i < 10; i += 2 {
+        for j := 0; j < 8; j++ {
+            n := 4*i - 8 + j
+
+            t1, t2 := linearCT(&lCols,
+                substituteCT(sCT, k00 ^ c[n][0]),
+                substituteCT(sCT, k01 ^ c[n][1]),
+            )
+
+            k00, k01, k10, k11 = t1 ^ k10, t2 ^ k11, k00, k01
+        }
+
+        k.rk[i] = [2]uint64{k00, k01}
+        k.rk[i+1] = [2]uint64{k10, k11}
+    }
+}
+
+func substituteCT(sb *ct.Sbox, x uint64) (y uint64) {
+    for i := 0; i < 64; i += 8 {
+        y |= uint64(sb.Lookup(uint8(x >> i))) << i
+    }
+
+    return
+}
+
+func linearCT(cols *[16][8][2]uint64, x1, x2 uint64) (t1, t2 uint64) {
+    for j := 0; j < 16; j++ {
+        v := x1
+        if j >= 8 {
+            v = x2
+        }
+
+        v >>= 8 * (j % 8)
+
+        for b := 0; b < 8; b++ {
+            m := ct.Bit(v, uint(b))
+
+            t1 ^= cols[j][b][0] & m
+            t2 ^= cols[j][b][1] & m
+        }
+    }
+
+    return
+}
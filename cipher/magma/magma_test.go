@@ -3,6 +3,9 @@ package magma
 import (
     "bytes"
     "testing"
+    "math/rand"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
 )
 
 func Test_Check(t *testing.T) {
@@ -28,4 +31,34 @@ func Test_Check(t *testing.T) {
     if !bytes.Equal(dst, pt[:]) {
         t.Errorf("fail, got %x, want %x", dst, pt)
     }
+
+    c, _ = NewCipherCT(key)
+
+    c.Encrypt(dst, pt[:])
+    if !bytes.Equal(dst, ct[:]) {
+        t.Errorf("CT fail, got %x, want %x", dst, ct)
+    }
+
+    c.Decrypt(dst, ct[:])
+    if !bytes.Equal(dst, pt[:]) {
+        t.Errorf("CT fail, got %x, want %x", dst, pt)
+    }
+}
+
+// DUDECT=1 go test -run Test_CipherCTTiming
+func Test_CipherCTTiming(t *testing.T) {
+    key := make([]byte, 32)
+    rand.Read(key)
+
+    c, err := NewCipherCT(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dst := make([]byte, 8)
+    cryptobin_test.AssertConstantTime(t, cryptobin_test.DudectConfig{
+        Measurements: 200000,
+    }, make([]byte, 8), func(in []byte) {
+        c.Encrypt(dst, in)
+    })
 }
@@ -0,0 +1,410 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// SM4 的 S 盒与 AES 的 S 盒仿射等价:
+// S(x) = post(AES_SubBytes(pre(x))), pre 和 post 为仿射变换,
+// 使用 PSHUFB 按半字节查表实现. GFNI 时直接计算
+// S(x) = A2 * Inv(A1 * x + c1) + c2.
+
+// 半字节掩码
+DATA nibble_mask<>+0x00(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA nibble_mask<>+0x08(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA nibble_mask<>+0x10(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA nibble_mask<>+0x18(SB)/8, $0x0f0f0f0f0f0f0f0f
+GLOBL nibble_mask<>(SB), (NOPTR+RODATA), $32
+
+// S 盒前置仿射变换, 低半字节
+DATA pre_lo<>+0x00(SB)/8, $0x078b37bb820eb23e
+DATA pre_lo<>+0x08(SB)/8, $0x9814a8241d912da1
+DATA pre_lo<>+0x10(SB)/8, $0x078b37bb820eb23e
+DATA pre_lo<>+0x18(SB)/8, $0x9814a8241d912da1
+GLOBL pre_lo<>(SB), (NOPTR+RODATA), $32
+
+// S 盒前置仿射变换, 高半字节
+DATA pre_hi<>+0x00(SB)/8, $0x37eb19c5f22edc00
+DATA pre_hi<>+0x08(SB)/8, $0x3fe311cdfa26d408
+DATA pre_hi<>+0x10(SB)/8, $0x37eb19c5f22edc00
+DATA pre_hi<>+0x18(SB)/8, $0x3fe311cdfa26d408
+GLOBL pre_hi<>(SB), (NOPTR+RODATA), $32
+
+// S 盒后置仿射变换, 低半字节
+DATA post_lo<>+0x00(SB)/8, $0x2098ea521ea6d46c
+DATA post_lo<>+0x08(SB)/8, $0x47ff8d3579c1b30b
+DATA post_lo<>+0x10(SB)/8, $0x2098ea521ea6d46c
+DATA post_lo<>+0x18(SB)/8, $0x47ff8d3579c1b30b
+GLOBL post_lo<>(SB), (NOPTR+RODATA), $32
+
+// S 盒后置仿射变换, 高半字节
+DATA post_hi<>+0x00(SB)/8, $0x2dcd7d9db050e000
+DATA post_hi<>+0x08(SB)/8, $0xed0dbd5d709020c0
+DATA post_hi<>+0x10(SB)/8, $0x2dcd7d9db050e000
+DATA post_hi<>+0x18(SB)/8, $0xed0dbd5d709020c0
+GLOBL post_hi<>(SB), (NOPTR+RODATA), $32
+
+// 抵消 AESENCLAST 中的 ShiftRows
+DATA inv_shift_rows<>+0x00(SB)/8, $0x0b0e0104070a0d00
+DATA inv_shift_rows<>+0x08(SB)/8, $0x0306090c0f020508
+DATA inv_shift_rows<>+0x10(SB)/8, $0x0b0e0104070a0d00
+DATA inv_shift_rows<>+0x18(SB)/8, $0x0306090c0f020508
+GLOBL inv_shift_rows<>(SB), (NOPTR+RODATA), $32
+
+// 32 位字节序转换
+DATA bswap_mask<>+0x00(SB)/8, $0x0405060700010203
+DATA bswap_mask<>+0x08(SB)/8, $0x0c0d0e0f08090a0b
+DATA bswap_mask<>+0x10(SB)/8, $0x0405060700010203
+DATA bswap_mask<>+0x18(SB)/8, $0x0c0d0e0f08090a0b
+GLOBL bswap_mask<>(SB), (NOPTR+RODATA), $32
+
+// 32 位循环左移 8 位
+DATA r08_mask<>+0x00(SB)/8, $0x0605040702010003
+DATA r08_mask<>+0x08(SB)/8, $0x0e0d0c0f0a09080b
+DATA r08_mask<>+0x10(SB)/8, $0x0605040702010003
+DATA r08_mask<>+0x18(SB)/8, $0x0e0d0c0f0a09080b
+GLOBL r08_mask<>(SB), (NOPTR+RODATA), $32
+
+// 32 位循环左移 16 位
+DATA r16_mask<>+0x00(SB)/8, $0x0504070601000302
+DATA r16_mask<>+0x08(SB)/8, $0x0d0c0f0e09080b0a
+DATA r16_mask<>+0x10(SB)/8, $0x0504070601000302
+DATA r16_mask<>+0x18(SB)/8, $0x0d0c0f0e09080b0a
+GLOBL r16_mask<>(SB), (NOPTR+RODATA), $32
+
+// 32 位循环左移 24 位
+DATA r24_mask<>+0x00(SB)/8, $0x0407060500030201
+DATA r24_mask<>+0x08(SB)/8, $0x0c0f0e0d080b0a09
+DATA r24_mask<>+0x10(SB)/8, $0x0407060500030201
+DATA r24_mask<>+0x18(SB)/8, $0x0c0f0e0d080b0a09
+GLOBL r24_mask<>(SB), (NOPTR+RODATA), $32
+
+// GFNI 前置仿射矩阵, 常量为 0x3e
+DATA gfni_pre<>+0x00(SB)/8, $0x4c287db91a22505d
+DATA gfni_pre<>+0x08(SB)/8, $0x4c287db91a22505d
+DATA gfni_pre<>+0x10(SB)/8, $0x4c287db91a22505d
+DATA gfni_pre<>+0x18(SB)/8, $0x4c287db91a22505d
+GLOBL gfni_pre<>(SB), (NOPTR+RODATA), $32
+
+// GFNI 后置仿射矩阵, 常量为 0xd3
+DATA gfni_post<>+0x00(SB)/8, $0xf3ab34a974a6b589
+DATA gfni_post<>+0x08(SB)/8, $0xf3ab34a974a6b589
+DATA gfni_post<>+0x10(SB)/8, $0xf3ab34a974a6b589
+DATA gfni_post<>+0x18(SB)/8, $0xf3ab34a974a6b589
+GLOBL gfni_post<>(SB), (NOPTR+RODATA), $32
+
+// 4x4 的 32 位矩阵转置
+#define TRANSPOSE_SSE(r0, r1, r2, r3, t0, t1) \
+	MOVOU r0, t0;       \
+	PUNPCKHLQ r1, t0;   \
+	PUNPCKLLQ r1, r0;   \
+	MOVOU r2, t1;       \
+	PUNPCKHLQ r3, t1;   \
+	PUNPCKLLQ r3, r2;   \
+	MOVOU r0, r1;       \
+	PUNPCKHQDQ r2, r1;  \
+	PUNPCKLQDQ r2, r0;  \
+	MOVOU t0, r3;       \
+	PUNPCKHQDQ t1, r3;  \
+	MOVOU t0, r2;       \
+	PUNPCKLQDQ t1, r2
+
+// x = L(S(x)), 使用 X5, X6 作为临时寄存器
+// X8: 半字节掩码, X9/X10: 前置变换, X11/X12: 后置变换,
+// X13: 逆 ShiftRows, X14: 循环左移 8 位, X15: 零
+#define SM4_TAO_L1_SSE(x) \
+	MOVOU x, X5;                   \
+	PAND X8, X5;                   \
+	PSRLQ $4, x;                   \
+	PAND X8, x;                    \
+	MOVOU X9, X6;                  \
+	PSHUFB X5, X6;                 \
+	MOVOU X10, X5;                 \
+	PSHUFB x, X5;                  \
+	PXOR X6, X5;                   \
+	PSHUFB X13, X5;                \
+	AESENCLAST X15, X5;            \
+	MOVOU X5, x;                   \
+	PAND X8, x;                    \
+	PSRLQ $4, X5;                  \
+	PAND X8, X5;                   \
+	MOVOU X11, X6;                 \
+	PSHUFB x, X6;                  \
+	MOVOU X12, x;                  \
+	PSHUFB X5, x;                  \
+	PXOR X6, x;                    \
+	MOVOU x, X5;                   \
+	PSHUFB X14, X5;                \
+	PXOR x, X5;                    \
+	MOVOU x, X6;                   \
+	PSHUFB r16_mask<>(SB), X6;     \
+	PXOR X6, X5;                   \
+	MOVOU X5, X6;                  \
+	PSLLL $2, X6;                  \
+	PSRLL $30, X5;                 \
+	PXOR X6, X5;                   \
+	MOVOU x, X6;                   \
+	PSHUFB r24_mask<>(SB), X6;     \
+	PXOR X6, x;                    \
+	PXOR X5, x
+
+// x0 ^= T(x1 ^ x2 ^ x3 ^ rk), rk 在 X7 中, imm 选择轮密钥
+#define SM4_ROUND_SSE(imm, x0, x1, x2, x3) \
+	PSHUFD $imm, X7, X4;  \
+	PXOR x1, X4;          \
+	PXOR x2, X4;          \
+	PXOR x3, X4;          \
+	SM4_TAO_L1_SSE(X4);   \
+	PXOR X4, x0
+
+// func encryptBlocksSSE(rk *uint32, dst, src []byte)
+TEXT ·encryptBlocksSSE(SB), NOSPLIT, $0-56
+	MOVQ rk+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+
+	MOVOU nibble_mask<>(SB), X8
+	MOVOU pre_lo<>(SB), X9
+	MOVOU pre_hi<>(SB), X10
+	MOVOU post_lo<>(SB), X11
+	MOVOU post_hi<>(SB), X12
+	MOVOU inv_shift_rows<>(SB), X13
+	MOVOU r08_mask<>(SB), X14
+	PXOR X15, X15
+
+sse_loop:
+	CMPQ CX, $64
+	JB sse_done
+
+	MOVOU 0(SI), X0
+	MOVOU 16(SI), X1
+	MOVOU 32(SI), X2
+	MOVOU 48(SI), X3
+	PSHUFB bswap_mask<>(SB), X0
+	PSHUFB bswap_mask<>(SB), X1
+	PSHUFB bswap_mask<>(SB), X2
+	PSHUFB bswap_mask<>(SB), X3
+	TRANSPOSE_SSE(X0, X1, X2, X3, X4, X5)
+
+	MOVQ AX, BX
+	MOVQ $8, DX
+
+sse_rounds:
+	MOVOU (BX), X7
+	SM4_ROUND_SSE(0x00, X0, X1, X2, X3)
+	SM4_ROUND_SSE(0x55, X1, X2, X3, X0)
+	SM4_ROUND_SSE(0xaa, X2, X3, X0, X1)
+	SM4_ROUND_SSE(0xff, X3, X0, X1, X2)
+	ADDQ $16, BX
+	DECQ DX
+	JNZ sse_rounds
+
+	// 输出顺序为 X3, X2, X1, X0
+	TRANSPOSE_SSE(X3, X2, X1, X0, X4, X5)
+	PSHUFB bswap_mask<>(SB), X3
+	PSHUFB bswap_mask<>(SB), X2
+	PSHUFB bswap_mask<>(SB), X1
+	PSHUFB bswap_mask<>(SB), X0
+	MOVOU X3, 0(DI)
+	MOVOU X2, 16(DI)
+	MOVOU X1, 32(DI)
+	MOVOU X0, 48(DI)
+
+	ADDQ $64, SI
+	ADDQ $64, DI
+	SUBQ $64, CX
+	JMP sse_loop
+
+sse_done:
+	RET
+
+// 128 位通道内的 4x4 的 32 位矩阵转置
+#define TRANSPOSE_AVX2(r0, r1, r2, r3, t0, t1, t2, t3) \
+	VPUNPCKLDQ r1, r0, t0;   \
+	VPUNPCKHDQ r1, r0, t1;   \
+	VPUNPCKLDQ r3, r2, t2;   \
+	VPUNPCKHDQ r3, r2, t3;   \
+	VPUNPCKLQDQ t2, t0, r0;  \
+	VPUNPCKHQDQ t2, t0, r1;  \
+	VPUNPCKLQDQ t3, t1, r2;  \
+	VPUNPCKHQDQ t3, t1, r3
+
+// 读取 8 个分组, 通道 0 为分组 0-3, 通道 1 为分组 4-7
+#define LOAD_AVX2 \
+	VMOVDQU 0(SI), X0;                      \
+	VINSERTI128 $1, 64(SI), Y0, Y0;         \
+	VMOVDQU 16(SI), X1;                     \
+	VINSERTI128 $1, 80(SI), Y1, Y1;         \
+	VMOVDQU 32(SI), X2;                     \
+	VINSERTI128 $1, 96(SI), Y2, Y2;         \
+	VMOVDQU 48(SI), X3;                     \
+	VINSERTI128 $1, 112(SI), Y3, Y3;        \
+	VPSHUFB bswap_mask<>(SB), Y0, Y0;       \
+	VPSHUFB bswap_mask<>(SB), Y1, Y1;       \
+	VPSHUFB bswap_mask<>(SB), Y2, Y2;       \
+	VPSHUFB bswap_mask<>(SB), Y3, Y3;       \
+	TRANSPOSE_AVX2(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7)
+
+// 输出顺序为 Y3, Y2, Y1, Y0
+#define STORE_AVX2 \
+	TRANSPOSE_AVX2(Y3, Y2, Y1, Y0, Y4, Y5, Y6, Y7); \
+	VPSHUFB bswap_mask<>(SB), Y3, Y3;       \
+	VPSHUFB bswap_mask<>(SB), Y2, Y2;       \
+	VPSHUFB bswap_mask<>(SB), Y1, Y1;       \
+	VPSHUFB bswap_mask<>(SB), Y0, Y0;       \
+	VMOVDQU X3, 0(DI);                      \
+	VEXTRACTI128 $1, Y3, 64(DI);            \
+	VMOVDQU X2, 16(DI);                     \
+	VEXTRACTI128 $1, Y2, 80(DI);            \
+	VMOVDQU X1, 32(DI);                     \
+	VEXTRACTI128 $1, Y1, 96(DI);            \
+	VMOVDQU X0, 48(DI);                     \
+	VEXTRACTI128 $1, Y0, 112(DI)
+
+// x = L(x), 使用 Y5, Y6 作为临时寄存器
+// Y14: 循环左移 8 位, Y15: 循环左移 16 位
+#define SM4_L_AVX2(x) \
+	VPSHUFB Y14, x, Y5;               \
+	VPXOR x, Y5, Y5;                  \
+	VPSHUFB Y15, x, Y6;               \
+	VPXOR Y6, Y5, Y5;                 \
+	VPSLLD $2, Y5, Y6;                \
+	VPSRLD $30, Y5, Y5;               \
+	VPXOR Y6, Y5, Y5;                 \
+	VPSHUFB r24_mask<>(SB), x, Y6;    \
+	VPXOR Y6, x, x;                   \
+	VPXOR Y5, x, x
+
+// x = S(x), 使用 AES-NI, 使用 Y5 作为临时寄存器
+// Y8: 半字节掩码, Y9/Y10: 前置变换, Y11/Y12: 后置变换, Y13: 逆 ShiftRows
+#define SM4_SBOX_AVX2(x, xx) \
+	VPAND Y8, x, Y5;                          \
+	VPSRLQ $4, x, x;                          \
+	VPAND Y8, x, x;                           \
+	VPSHUFB Y5, Y9, Y5;                       \
+	VPSHUFB x, Y10, x;                        \
+	VPXOR Y5, x, x;                           \
+	VPSHUFB Y13, x, x;                        \
+	VEXTRACTI128 $1, x, X5;                   \
+	VAESENCLAST zero<>(SB), xx, xx;           \
+	VAESENCLAST zero<>(SB), X5, X5;           \
+	VINSERTI128 $1, X5, x, x;                 \
+	VPAND Y8, x, Y5;                          \
+	VPSRLQ $4, x, x;                          \
+	VPAND Y8, x, x;                           \
+	VPSHUFB Y5, Y11, Y5;                      \
+	VPSHUFB x, Y12, x;                        \
+	VPXOR Y5, x, x
+
+// x = S(x), 使用 GFNI
+#define SM4_SBOX_GFNI(x) \
+	VGF2P8AFFINEQB $0x3e, Y9, x, x;      \
+	VGF2P8AFFINEINVQB $0xd3, Y10, x, x
+
+// x0 ^= T(x1 ^ x2 ^ x3 ^ rk)
+#define SM4_ROUND_AVX2(off, x0, x1, x2, x3) \
+	VPBROADCASTD off(BX), Y4;   \
+	VPXOR x1, Y4, Y4;           \
+	VPXOR x2, Y4, Y4;           \
+	VPXOR x3, Y4, Y4;           \
+	SM4_SBOX_AVX2(Y4, X4);      \
+	SM4_L_AVX2(Y4);             \
+	VPXOR Y4, x0, x0
+
+#define SM4_ROUND_GFNI(off, x0, x1, x2, x3) \
+	VPBROADCASTD off(BX), Y4;   \
+	VPXOR x1, Y4, Y4;           \
+	VPXOR x2, Y4, Y4;           \
+	VPXOR x3, Y4, Y4;           \
+	SM4_SBOX_GFNI(Y4);          \
+	SM4_L_AVX2(Y4);             \
+	VPXOR Y4, x0, x0
+
+DATA zero<>+0x00(SB)/8, $0
+DATA zero<>+0x08(SB)/8, $0
+GLOBL zero<>(SB), (NOPTR+RODATA), $16
+
+// func encryptBlocksAVX2(rk *uint32, dst, src []byte)
+TEXT ·encryptBlocksAVX2(SB), NOSPLIT, $0-56
+	MOVQ rk+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+
+	VMOVDQU nibble_mask<>(SB), Y8
+	VMOVDQU pre_lo<>(SB), Y9
+	VMOVDQU pre_hi<>(SB), Y10
+	VMOVDQU post_lo<>(SB), Y11
+	VMOVDQU post_hi<>(SB), Y12
+	VMOVDQU inv_shift_rows<>(SB), Y13
+	VMOVDQU r08_mask<>(SB), Y14
+	VMOVDQU r16_mask<>(SB), Y15
+
+avx2_loop:
+	CMPQ CX, $128
+	JB avx2_done
+
+	LOAD_AVX2
+
+	MOVQ AX, BX
+	MOVQ $8, DX
+
+avx2_rounds:
+	SM4_ROUND_AVX2(0, Y0, Y1, Y2, Y3)
+	SM4_ROUND_AVX2(4, Y1, Y2, Y3, Y0)
+	SM4_ROUND_AVX2(8, Y2, Y3, Y0, Y1)
+	SM4_ROUND_AVX2(12, Y3, Y0, Y1, Y2)
+	ADDQ $16, BX
+	DECQ DX
+	JNZ avx2_rounds
+
+	STORE_AVX2
+
+	ADDQ $128, SI
+	ADDQ $128, DI
+	SUBQ $128, CX
+	JMP avx2_loop
+
+avx2_done:
+	VZEROUPPER
+	RET
+
+// func encryptBlocksGFNI(rk *uint32, dst, src []byte)
+TEXT ·encryptBlocksGFNI(SB), NOSPLIT, $0-56
+	MOVQ rk+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+
+	VMOVDQU gfni_pre<>(SB), Y9
+	VMOVDQU gfni_post<>(SB), Y10
+	VMOVDQU r08_mask<>(SB), Y14
+	VMOVDQU r16_mask<>(SB), Y15
+
+gfni_loop:
+	CMPQ CX, $128
+	JB gfni_done
+
+	LOAD_AVX2
+
+	MOVQ AX, BX
+	MOVQ $8, DX
+
+gfni_rounds:
+	SM4_ROUND_GFNI(0, Y0, Y1, Y2, Y3)
+	SM4_ROUND_GFNI(4, Y1, Y2, Y3, Y0)
+	SM4_ROUND_GFNI(8, Y2, Y3, Y0, Y1)
+	SM4_ROUND_GFNI(12, Y3, Y0, Y1, Y2)
+	ADDQ $16, BX
+	DECQ DX
+	JNZ gfni_rounds
+
+	STORE_AVX2
+
+	ADDQ $128, SI
+	ADDQ $128, DI
+	SUBQ $128, CX
+	JMP gfni_loop
+
+gfni_done:
+	VZEROUPPER
+	RET
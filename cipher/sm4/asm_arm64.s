@@ -0,0 +1,40 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// SM4E Vd.4S, Vn.4S
+#define SM4E_V0(n) WORD $(0xcec08400 | (n << 5))
+
+// func encryptBlocksSM4E(rk *uint32, dst, src []byte)
+TEXT ·encryptBlocksSM4E(SB), NOSPLIT, $0-56
+	MOVD rk+0(FP), R0
+	MOVD dst_base+8(FP), R1
+	MOVD src_base+32(FP), R2
+	MOVD src_len+40(FP), R3
+
+	VLD1.P 64(R0), [V16.S4, V17.S4, V18.S4, V19.S4]
+	VLD1 (R0), [V20.S4, V21.S4, V22.S4, V23.S4]
+
+loop:
+	CBZ R3, done
+
+	VLD1.P 16(R2), [V0.B16]
+	VREV32 V0.B16, V0.B16
+	SM4E_V0(16)
+	SM4E_V0(17)
+	SM4E_V0(18)
+	SM4E_V0(19)
+	SM4E_V0(20)
+	SM4E_V0(21)
+	SM4E_V0(22)
+	SM4E_V0(23)
+	VREV64 V0.S4, V0.S4
+	VEXT $8, V0.B16, V0.B16, V0.B16
+	VREV32 V0.B16, V0.B16
+	VST1.P [V0.B16], 16(R1)
+
+	SUB $16, R3
+	B loop
+
+done:
+	RET
@@ -0,0 +1,79 @@
+//go:build (amd64 || arm64) && !purego
+
+package sm4
+
+import (
+    "crypto/subtle"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+type cbcDecrypter struct {
+    b   *sm4CipherAsm
+    iv  [BlockSize]byte
+    buf [streamBufferSize]byte
+}
+
+// NewCBCDecrypter returns a CBC decrypter which decrypts blocks
+// in batches. It is used by cipher.NewCBCDecrypter.
+func (this *sm4CipherAsm) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+    if len(iv) != BlockSize {
+        panic("go-cryptobin/sm4: IV length must equal block size")
+    }
+
+    c := &cbcDecrypter{
+        b: this,
+    }
+    copy(c.iv[:], iv)
+
+    return c
+}
+
+func (x *cbcDecrypter) BlockSize() int {
+    return BlockSize
+}
+
+func (x *cbcDecrypter) CryptBlocks(dst, src []byte) {
+    if len(src) % BlockSize != 0 {
+        panic("go-cryptobin/sm4: input not full blocks")
+    }
+
+    if len(dst) < len(src) {
+        panic("go-cryptobin/sm4: output smaller than input")
+    }
+
+    if alias.InexactOverlap(dst[:len(src)], src) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    for len(src) > 0 {
+        n := len(src)
+        if n > len(x.buf) {
+            n = len(x.buf)
+        }
+
+        x.b.cryptBlocksPadded(&x.b.dec[0], x.buf[:n], src[:n])
+
+        var last [BlockSize]byte
+        copy(last[:], src[n-BlockSize:n])
+
+        // 从后往前异或, 使 dst 和 src 相同时密文不被覆盖
+        for i := n - BlockSize; i > 0; i -= BlockSize {
+            subtle.XORBytes(dst[i:i+BlockSize], x.buf[i:i+BlockSize], src[i-BlockSize:i])
+        }
+        subtle.XORBytes(dst[:BlockSize], x.buf[:BlockSize], x.iv[:])
+
+        x.iv = last
+        dst, src = dst[n:], src[n:]
+    }
+}
+
+// SetIV sets the IV, it is used by crypto/cipher.
+func (x *cbcDecrypter) SetIV(iv []byte) {
+    if len(iv) != BlockSize {
+        panic("go-cryptobin/sm4: incorrect length IV")
+    }
+
+    copy(x.iv[:], iv)
+}
@@ -0,0 +1,73 @@
+//go:build (amd64 || arm64) && !purego
+
+package sm4
+
+import (
+    "crypto/subtle"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+// 密钥流缓存大小, 为批量大小的倍数
+const streamBufferSize = 32 * BlockSize
+
+type ctr struct {
+    b       *sm4CipherAsm
+    ctr     [BlockSize]byte
+    out     [streamBufferSize]byte
+    outUsed int
+}
+
+// NewCTR returns a CTR stream which generates the key stream
+// in batches. It is used by cipher.NewCTR.
+func (this *sm4CipherAsm) NewCTR(iv []byte) cipher.Stream {
+    if len(iv) != BlockSize {
+        panic("go-cryptobin/sm4: IV length must equal block size")
+    }
+
+    s := &ctr{
+        b:       this,
+        outUsed: streamBufferSize,
+    }
+    copy(s.ctr[:], iv)
+
+    return s
+}
+
+func (x *ctr) refill() {
+    for i := 0; i < streamBufferSize; i += BlockSize {
+        copy(x.out[i:], x.ctr[:])
+
+        // 计数器按 128 位大端整数递增
+        for j := BlockSize - 1; j >= 0; j-- {
+            x.ctr[j]++
+            if x.ctr[j] != 0 {
+                break
+            }
+        }
+    }
+
+    cryptBlocks(&x.b.rk[0], x.out[:], x.out[:])
+    x.outUsed = 0
+}
+
+func (x *ctr) XORKeyStream(dst, src []byte) {
+    if len(dst) < len(src) {
+        panic("go-cryptobin/sm4: output smaller than input")
+    }
+
+    if alias.InexactOverlap(dst[:len(src)], src) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    for len(src) > 0 {
+        if x.outUsed >= len(x.out) {
+            x.refill()
+        }
+
+        n := subtle.XORBytes(dst, src, x.out[x.outUsed:])
+        dst, src = dst[n:], src[n:]
+        x.outUsed += n
+    }
+}
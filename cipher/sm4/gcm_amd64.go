@@ -0,0 +1,14 @@
+//go:build !purego
+
+package sm4
+
+import (
+    "golang.org/x/sys/cpu"
+)
+
+// GHASH 使用 PCLMULQDQ, 没有时使用 4 位查表
+var useCLMUL = cpu.X86.HasPCLMULQDQ && cpu.X86.HasSSSE3
+
+// y = (y ^ b1) * H ..., len(blocks) 为 16 的倍数
+//go:noescape
+func ghashBlocksCLMUL(h, y *[16]byte, blocks []byte)
@@ -0,0 +1,302 @@
+//go:build (amd64 || arm64) && !purego
+
+package sm4
+
+import (
+    "errors"
+    "crypto/subtle"
+    "crypto/cipher"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+const (
+    gcmBlockSize         = 16
+    gcmTagSize           = 16
+    gcmMinimumTagSize    = 12
+    gcmStandardNonceSize = 12
+)
+
+var errOpen = errors.New("go-cryptobin/sm4: message authentication failed")
+
+// GHASH 域元素, 比特按 GCM 的顺序存放
+type gcmFieldElement struct {
+    low, high uint64
+}
+
+// gcm 使用批量 CTR 加密. GHASH 在 amd64 上使用 PCLMULQDQ,
+// 其他情况使用 4 位查表
+type gcm struct {
+    cipher    *sm4CipherAsm
+    nonceSize int
+    tagSize   int
+
+    hkey         [gcmBlockSize]byte
+    productTable [16]gcmFieldElement
+}
+
+// NewGCM returns the SM4 cipher wrapped in Galois Counter Mode.
+// It is used by cipher.NewGCM and its variants.
+func (this *sm4CipherAsm) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
+    if tagSize < gcmMinimumTagSize || tagSize > gcmBlockSize {
+        return nil, errors.New("go-cryptobin/sm4: incorrect tag size given to GCM")
+    }
+
+    if nonceSize <= 0 {
+        return nil, errors.New("go-cryptobin/sm4: the nonce can't have zero length")
+    }
+
+    var key [gcmBlockSize]byte
+    this.Encrypt(key[:], key[:])
+
+    g := &gcm{
+        cipher:    this,
+        nonceSize: nonceSize,
+        tagSize:   tagSize,
+        hkey:      key,
+    }
+
+    x := gcmFieldElement{
+        binary.BigEndian.Uint64(key[:8]),
+        binary.BigEndian.Uint64(key[8:]),
+    }
+    g.productTable[reverseBits(1)] = x
+
+    for i := 2; i < 16; i += 2 {
+        g.productTable[reverseBits(i)] = gcmDouble(&g.productTable[reverseBits(i/2)])
+        g.productTable[reverseBits(i+1)] = gcmAdd(&g.productTable[reverseBits(i)], &x)
+    }
+
+    return g, nil
+}
+
+func (g *gcm) NonceSize() int {
+    return g.nonceSize
+}
+
+func (g *gcm) Overhead() int {
+    return g.tagSize
+}
+
+func (g *gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+    if len(nonce) != g.nonceSize {
+        panic("go-cryptobin/sm4: incorrect nonce length given to GCM")
+    }
+
+    if uint64(len(plaintext)) > ((1<<32)-2)*uint64(gcmBlockSize) {
+        panic("go-cryptobin/sm4: message too large for GCM")
+    }
+
+    ret, out := alias.SliceForAppend(dst, len(plaintext)+g.tagSize)
+    if alias.InexactOverlap(out, plaintext) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    var counter, tagMask [gcmBlockSize]byte
+    g.deriveCounter(&counter, nonce)
+
+    g.cipher.Encrypt(tagMask[:], counter[:])
+    gcmInc32(&counter)
+
+    g.counterCrypt(out, plaintext, &counter)
+
+    var tag [gcmTagSize]byte
+    g.auth(tag[:], out[:len(plaintext)], additionalData, &tagMask)
+    copy(out[len(plaintext):], tag[:])
+
+    return ret
+}
+
+func (g *gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+    if len(nonce) != g.nonceSize {
+        panic("go-cryptobin/sm4: incorrect nonce length given to GCM")
+    }
+
+    if len(ciphertext) < g.tagSize {
+        return nil, errOpen
+    }
+
+    if uint64(len(ciphertext)) > ((1<<32)-2)*uint64(gcmBlockSize)+uint64(g.tagSize) {
+        return nil, errOpen
+    }
+
+    tag := ciphertext[len(ciphertext)-g.tagSize:]
+    ciphertext = ciphertext[:len(ciphertext)-g.tagSize]
+
+    var counter, tagMask [gcmBlockSize]byte
+    g.deriveCounter(&counter, nonce)
+
+    g.cipher.Encrypt(tagMask[:], counter[:])
+    gcmInc32(&counter)
+
+    var expectedTag [gcmTagSize]byte
+    g.auth(expectedTag[:], ciphertext, additionalData, &tagMask)
+
+    ret, out := alias.SliceForAppend(dst, len(ciphertext))
+    if alias.InexactOverlap(out, ciphertext) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    if subtle.ConstantTimeCompare(expectedTag[:g.tagSize], tag) != 1 {
+        for i := range out {
+            out[i] = 0
+        }
+
+        return nil, errOpen
+    }
+
+    g.counterCrypt(out, ciphertext, &counter)
+
+    return ret, nil
+}
+
+// 批量生成密钥流并异或
+func (g *gcm) counterCrypt(out, in []byte, counter *[gcmBlockSize]byte) {
+    var mask [streamBufferSize]byte
+
+    for len(in) > 0 {
+        n := len(in)
+        if n > len(mask) {
+            n = len(mask)
+        }
+
+        m := (n + gcmBlockSize - 1) / gcmBlockSize * gcmBlockSize
+        for i := 0; i < m; i += gcmBlockSize {
+            copy(mask[i:], counter[:])
+            gcmInc32(counter)
+        }
+
+        g.cipher.cryptBlocksPadded(&g.cipher.rk[0], mask[:m], mask[:m])
+
+        subtle.XORBytes(out, in[:n], mask[:n])
+        out, in = out[n:], in[n:]
+    }
+}
+
+func (g *gcm) deriveCounter(counter *[gcmBlockSize]byte, nonce []byte) {
+    if len(nonce) == gcmStandardNonceSize {
+        copy(counter[:], nonce)
+        counter[gcmBlockSize-1] = 1
+    } else {
+        var y gcmFieldElement
+        g.update(&y, nonce)
+        y.high ^= uint64(len(nonce)) * 8
+        g.mul(&y)
+
+        binary.BigEndian.PutUint64(counter[:8], y.low)
+        binary.BigEndian.PutUint64(counter[8:], y.high)
+    }
+}
+
+func (g *gcm) auth(out, ciphertext, additionalData []byte, tagMask *[gcmTagSize]byte) {
+    var y gcmFieldElement
+    g.update(&y, additionalData)
+    g.update(&y, ciphertext)
+
+    y.low ^= uint64(len(additionalData)) * 8
+    y.high ^= uint64(len(ciphertext)) * 8
+
+    g.mul(&y)
+
+    binary.BigEndian.PutUint64(out, y.low)
+    binary.BigEndian.PutUint64(out[8:], y.high)
+
+    subtle.XORBytes(out, out, tagMask[:])
+}
+
+var gcmReductionTable = []uint16{
+    0x0000, 0x1c20, 0x3840, 0x2460, 0x7080, 0x6ca0, 0x48c0, 0x54e0,
+    0xe100, 0xfd20, 0xd940, 0xc560, 0x9180, 0x8da0, 0xa9c0, 0xb5e0,
+}
+
+// y = y * H
+func (g *gcm) mul(y *gcmFieldElement) {
+    var z gcmFieldElement
+
+    for i := 0; i < 2; i++ {
+        word := y.high
+        if i == 1 {
+            word = y.low
+        }
+
+        for j := 0; j < 64; j += 4 {
+            msw := z.high & 0xf
+            z.high >>= 4
+            z.high |= z.low << 60
+            z.low >>= 4
+            z.low ^= uint64(gcmReductionTable[msw]) << 48
+
+            t := &g.productTable[word&0xf]
+
+            z.low ^= t.low
+            z.high ^= t.high
+            word >>= 4
+        }
+    }
+
+    *y = z
+}
+
+func (g *gcm) updateBlocks(y *gcmFieldElement, blocks []byte) {
+    if useCLMUL {
+        var b [gcmBlockSize]byte
+        binary.BigEndian.PutUint64(b[:8], y.low)
+        binary.BigEndian.PutUint64(b[8:], y.high)
+
+        ghashBlocksCLMUL(&g.hkey, &b, blocks)
+
+        y.low = binary.BigEndian.Uint64(b[:8])
+        y.high = binary.BigEndian.Uint64(b[8:])
+        return
+    }
+
+    for len(blocks) > 0 {
+        y.low ^= binary.BigEndian.Uint64(blocks)
+        y.high ^= binary.BigEndian.Uint64(blocks[8:])
+        g.mul(y)
+
+        blocks = blocks[gcmBlockSize:]
+    }
+}
+
+func (g *gcm) update(y *gcmFieldElement, data []byte) {
+    fullBlocks := (len(data) >> 4) << 4
+    g.updateBlocks(y, data[:fullBlocks])
+
+    if len(data) != fullBlocks {
+        var partialBlock [gcmBlockSize]byte
+        copy(partialBlock[:], data[fullBlocks:])
+        g.updateBlocks(y, partialBlock[:])
+    }
+}
+
+func reverseBits(i int) int {
+    i = ((i << 2) & 0xc) | ((i >> 2) & 0x3)
+    i = ((i << 1) & 0xa) | ((i >> 1) & 0x5)
+    return i
+}
+
+func gcmAdd(x, y *gcmFieldElement) gcmFieldElement {
+    return gcmFieldElement{x.low ^ y.low, x.high ^ y.high}
+}
+
+func gcmDouble(x *gcmFieldElement) (double gcmFieldElement) {
+    msbSet := x.high&1 == 1
+
+    double.high = x.high >> 1
+    double.high |= x.low << 63
+    double.low = x.low >> 1
+
+    if msbSet {
+        double.low ^= 0xe100000000000000
+    }
+
+    return
+}
+
+// 计数器低 32 位递增
+func gcmInc32(counterBlock *[gcmBlockSize]byte) {
+    ctr := counterBlock[len(counterBlock)-4:]
+    binary.BigEndian.PutUint32(ctr, binary.BigEndian.Uint32(ctr)+1)
+}
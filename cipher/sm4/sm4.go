@@ -36,10 +36,14 @@ func NewCipher(key []byte) (cipher.Block, error) {
             return nil, KeySizeError(len(key))
     }
 
+    return newCipher(key), nil
+}
+
+func newCipherGeneric(key []byte) *sm4Cipher {
     c := new(sm4Cipher)
     c.expandKey(key)
 
-    return c, nil
+    return c
 }
 
 func (this *sm4Cipher) BlockSize() int {
@@ -0,0 +1,43 @@
+//go:build !purego
+
+package sm4
+
+import (
+    "golang.org/x/sys/cpu"
+)
+
+var useAESNI = cpu.X86.HasAES && cpu.X86.HasSSSE3
+var useAVX2 = cpu.X86.HasAVX2 && useAESNI
+var useGFNI = cpu.X86.HasAVX2 && cpu.X86.HasAVX512GFNI
+
+var supportsAsm = useAESNI || useGFNI
+
+// 每次汇编调用处理的分组数
+var batchBlocks = 4
+
+func init() {
+    if useAVX2 || useGFNI {
+        batchBlocks = 8
+    }
+}
+
+//go:noescape
+func encryptBlocksSSE(rk *uint32, dst, src []byte)
+
+//go:noescape
+func encryptBlocksAVX2(rk *uint32, dst, src []byte)
+
+//go:noescape
+func encryptBlocksGFNI(rk *uint32, dst, src []byte)
+
+// 加密多个分组, len(src) 为 batchBlocks * BlockSize 的倍数
+func cryptBlocks(rk *uint32, dst, src []byte) {
+    switch {
+        case useGFNI:
+            encryptBlocksGFNI(rk, dst, src)
+        case useAVX2:
+            encryptBlocksAVX2(rk, dst, src)
+        default:
+            encryptBlocksSSE(rk, dst, src)
+    }
+}
@@ -0,0 +1,59 @@
+//go:build !purego
+
+package sm4
+
+import (
+    "testing"
+    "math/rand"
+    "encoding/binary"
+)
+
+func Test_Kernels(t *testing.T) {
+    if useAESNI {
+        testKernel(t, "SSE", 4, encryptBlocksSSE)
+    }
+
+    if useAVX2 {
+        testKernel(t, "AVX2", 8, encryptBlocksAVX2)
+    }
+
+    if useGFNI {
+        testKernel(t, "GFNI", 8, encryptBlocksGFNI)
+    }
+}
+
+func Test_GHASHCLMUL(t *testing.T) {
+    if !useCLMUL || !useAESNI {
+        t.Skip("no PCLMULQDQ")
+    }
+
+    random := rand.New(rand.NewSource(2))
+
+    key := make([]byte, 16)
+    random.Read(key)
+
+    c, _ := NewCipher(key)
+    aead, _ := c.(*sm4CipherAsm).NewGCM(gcmStandardNonceSize, gcmTagSize)
+    g := aead.(*gcm)
+
+    for _, n := range []int{0, 1, 2, 7, 64} {
+        blocks := make([]byte, n*gcmBlockSize)
+        random.Read(blocks)
+
+        var y gcmFieldElement
+        y.low, y.high = random.Uint64(), random.Uint64()
+        want := y
+
+        for i := 0; i < len(blocks); i += gcmBlockSize {
+            want.low ^= binary.BigEndian.Uint64(blocks[i:])
+            want.high ^= binary.BigEndian.Uint64(blocks[i+8:])
+            g.mul(&want)
+        }
+
+        g.updateBlocks(&y, blocks)
+
+        if y != want {
+            t.Fatalf("%d blocks: got %x, want %x", n, y, want)
+        }
+    }
+}
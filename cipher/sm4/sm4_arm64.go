@@ -0,0 +1,25 @@
+//go:build !purego
+
+package sm4
+
+import (
+    "os"
+    "golang.org/x/sys/cpu"
+)
+
+// arm64 只使用 SM4 扩展指令, 没有时使用通用实现.
+// 没有 NEON 实现, GHASH 也使用查表
+var useSM4E = cpu.ARM64.HasSM4 && os.Getenv("DISABLE_SM4NI") != "1"
+
+var supportsAsm = useSM4E
+
+// 每次汇编调用处理的分组数, SM4E 逐个分组处理
+var batchBlocks = 1
+
+//go:noescape
+func encryptBlocksSM4E(rk *uint32, dst, src []byte)
+
+// 加密多个分组, len(src) 为 BlockSize 的倍数
+func cryptBlocks(rk *uint32, dst, src []byte) {
+    encryptBlocksSM4E(rk, dst, src)
+}
@@ -0,0 +1,15 @@
+//go:build !purego
+
+package sm4
+
+import (
+    "testing"
+)
+
+func Test_Kernels(t *testing.T) {
+    if !useSM4E {
+        t.Skip("no SM4 instructions")
+    }
+
+    testKernel(t, "SM4E", 1, encryptBlocksSM4E)
+}
@@ -0,0 +1,100 @@
+//go:build (amd64 || arm64) && !purego
+
+package sm4
+
+import (
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+// 最大批量分组数
+const maxBatchBlocks = 8
+
+// sm4CipherAsm 使用汇编实现, 同时实现了标准库的
+// ctrAble, cbcDecAble 和 gcmAble 接口
+type sm4CipherAsm struct {
+    sm4Cipher
+    dec [KeySchedule]uint32
+}
+
+func newCipher(key []byte) cipher.Block {
+    if !supportsAsm {
+        return newCipherGeneric(key)
+    }
+
+    c := new(sm4CipherAsm)
+    c.expandKey(key)
+
+    for i := 0; i < KeySchedule; i++ {
+        c.dec[i] = c.rk[KeySchedule-1-i]
+    }
+
+    return c
+}
+
+func (this *sm4CipherAsm) BlockSize() int {
+    return BlockSize
+}
+
+func (this *sm4CipherAsm) Encrypt(dst, src []byte) {
+    if len(src) < BlockSize {
+        panic("go-cryptobin/sm4: input not full block")
+    }
+
+    if len(dst) < BlockSize {
+        panic("go-cryptobin/sm4: output not full block")
+    }
+
+    if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    // 需要补齐为一批时, 单个分组使用通用实现更快
+    if batchBlocks > 1 {
+        this.sm4Cipher.encrypt(dst, src)
+        return
+    }
+
+    cryptBlocks(&this.rk[0], dst[:BlockSize], src[:BlockSize])
+}
+
+func (this *sm4CipherAsm) Decrypt(dst, src []byte) {
+    if len(src) < BlockSize {
+        panic("go-cryptobin/sm4: input not full block")
+    }
+
+    if len(dst) < BlockSize {
+        panic("go-cryptobin/sm4: output not full block")
+    }
+
+    if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    // 需要补齐为一批时, 单个分组使用通用实现更快
+    if batchBlocks > 1 {
+        this.sm4Cipher.decrypt(dst, src)
+        return
+    }
+
+    cryptBlocks(&this.dec[0], dst[:BlockSize], src[:BlockSize])
+}
+
+// 批量处理, len(src) 为 BlockSize 的倍数, 不足一批的部分补齐处理
+func (this *sm4CipherAsm) cryptBlocksPadded(rk *uint32, dst, src []byte) {
+    batch := batchBlocks * BlockSize
+
+    n := len(src) - len(src) % batch
+    if n > 0 {
+        cryptBlocks(rk, dst[:n], src[:n])
+    }
+
+    if n < len(src) {
+        var buf [maxBatchBlocks * BlockSize]byte
+
+        copy(buf[:], src[n:])
+        cryptBlocks(rk, buf[:batch], buf[:batch])
+        copy(dst[n:], buf[:len(src)-n])
+    }
+}
@@ -0,0 +1,207 @@
+//go:build (amd64 || arm64) && !purego
+
+package sm4
+
+import (
+    "bytes"
+    "testing"
+    "math/rand"
+    "crypto/cipher"
+)
+
+// 隐藏扩展接口, 使标准库使用通用实现
+type wrapBlock struct {
+    b cipher.Block
+}
+
+func (w wrapBlock) BlockSize() int {
+    return w.b.BlockSize()
+}
+
+func (w wrapBlock) Encrypt(dst, src []byte) {
+    w.b.Encrypt(dst, src)
+}
+
+func (w wrapBlock) Decrypt(dst, src []byte) {
+    w.b.Decrypt(dst, src)
+}
+
+func testKernel(t *testing.T, name string, batch int, fn func(rk *uint32, dst, src []byte)) {
+    random := rand.New(rand.NewSource(int64(batch)))
+
+    for i := 0; i < 50; i++ {
+        key := make([]byte, 16)
+        random.Read(key)
+
+        src := make([]byte, batch*BlockSize*(i%3+1))
+        random.Read(src)
+
+        c := newCipherGeneric(key)
+
+        want := make([]byte, len(src))
+        for j := 0; j < len(src); j += BlockSize {
+            c.encrypt(want[j:], src[j:])
+        }
+
+        got := make([]byte, len(src))
+        fn(&c.rk[0], got, src)
+
+        if !bytes.Equal(got, want) {
+            t.Fatalf("%s encrypt got %x, want %x", name, got, want)
+        }
+
+        var dec [KeySchedule]uint32
+        for j := range dec {
+            dec[j] = c.rk[KeySchedule-1-j]
+        }
+
+        // 原地解密
+        fn(&dec[0], got, got)
+
+        if !bytes.Equal(got, src) {
+            t.Fatalf("%s decrypt got %x, want %x", name, got, src)
+        }
+    }
+}
+
+func Test_AsmModes(t *testing.T) {
+    if !supportsAsm {
+        t.Skip("no asm support")
+    }
+
+    random := rand.New(rand.NewSource(1))
+
+    key := make([]byte, 16)
+    random.Read(key)
+
+    c, _ := NewCipher(key)
+    if _, ok := c.(*sm4CipherAsm); !ok {
+        t.Fatal("NewCipher should return asm cipher")
+    }
+
+    generic := wrapBlock{newCipherGeneric(key)}
+
+    for _, size := range []int{0, 1, 15, 16, 17, 63, 64, 127, 128, 129, 500, 512, 1000, 4097} {
+        iv := make([]byte, BlockSize)
+        random.Read(iv)
+
+        src := make([]byte, size)
+        random.Read(src)
+
+        // CTR, 计数器跨越 64 位边界
+        iv[8] = 0xff
+        for i := 9; i < 16; i++ {
+            iv[i] = 0xff
+        }
+
+        got := make([]byte, size)
+        want := make([]byte, size)
+
+        s := cipher.NewCTR(c, iv)
+        for i := 0; i < size; {
+            n := random.Intn(100)
+            if i+n > size {
+                n = size - i
+            }
+
+            s.XORKeyStream(got[i:i+n], src[i:i+n])
+            i += n
+        }
+
+        cipher.NewCTR(generic, iv).XORKeyStream(want, src)
+        if !bytes.Equal(got, want) {
+            t.Fatalf("CTR size %d: got %x, want %x", size, got, want)
+        }
+
+        // CBC 原地解密
+        n := size - size%BlockSize
+
+        ct := make([]byte, n)
+        cipher.NewCBCEncrypter(generic, iv).CryptBlocks(ct, src[:n])
+
+        got = append([]byte{}, ct...)
+        cipher.NewCBCDecrypter(c, iv).CryptBlocks(got, got)
+        if !bytes.Equal(got, src[:n]) {
+            t.Fatalf("CBC size %d: got %x, want %x", n, got, src[:n])
+        }
+
+        // GCM
+        ad := src[:size/3]
+        for _, sizes := range [][2]int{{12, 16}, {8, 16}, {16, 16}, {12, 12}} {
+            nonce := make([]byte, sizes[0])
+            random.Read(nonce)
+
+            a1, err := newGCM(c, sizes[0], sizes[1])
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            a2, _ := newGCM(generic, sizes[0], sizes[1])
+
+            sealed := a1.Seal(nil, nonce, src, ad)
+            expected := a2.Seal(nil, nonce, src, ad)
+            if !bytes.Equal(sealed, expected) {
+                t.Fatalf("GCM size %d %v: got %x, want %x", size, sizes, sealed, expected)
+            }
+
+            opened, err := a1.Open(nil, nonce, sealed, ad)
+            if err != nil || !bytes.Equal(opened, src) {
+                t.Fatalf("GCM open size %d failed", size)
+            }
+
+            sealed[0] ^= 1
+            if _, err := a1.Open(nil, nonce, sealed, ad); err == nil {
+                t.Fatal("GCM open should fail")
+            }
+        }
+    }
+}
+
+func newGCM(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+    if tagSize != 16 {
+        return cipher.NewGCMWithTagSize(b, tagSize)
+    }
+
+    return cipher.NewGCMWithNonceSize(b, nonceSize)
+}
+
+func benchmarkMode(b *testing.B, size int, fn func(c cipher.Block, buf []byte)) {
+    c, _ := NewCipher(make([]byte, 16))
+    buf := make([]byte, size)
+
+    b.SetBytes(int64(size))
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        fn(c, buf)
+    }
+}
+
+func Benchmark_Encrypt(b *testing.B) {
+    benchmarkMode(b, BlockSize, func(c cipher.Block, buf []byte) {
+        c.Encrypt(buf, buf)
+    })
+}
+
+func Benchmark_CTR(b *testing.B) {
+    iv := make([]byte, BlockSize)
+    benchmarkMode(b, 8192, func(c cipher.Block, buf []byte) {
+        cipher.NewCTR(c, iv).XORKeyStream(buf, buf)
+    })
+}
+
+func Benchmark_CBCDecrypt(b *testing.B) {
+    iv := make([]byte, BlockSize)
+    benchmarkMode(b, 8192, func(c cipher.Block, buf []byte) {
+        cipher.NewCBCDecrypter(c, iv).CryptBlocks(buf, buf)
+    })
+}
+
+func Benchmark_GCMSeal(b *testing.B) {
+    nonce := make([]byte, 12)
+    out := make([]byte, 0, 8192+16)
+    benchmarkMode(b, 8192, func(c cipher.Block, buf []byte) {
+        a, _ := cipher.NewGCM(c)
+        a.Seal(out, nonce, buf, nil)
+    })
+}
@@ -0,0 +1,108 @@
+package sm4
+
+import (
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/tool/ct"
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+var sboxCT = ct.NewSbox(&sbox)
+
+type sm4CipherCT struct {
+    rk [KeySchedule]uint32
+}
+
+// NewCipherCT creates and returns a new SM4 cipher.Block that does not
+// read sbox_t0..sbox_t3 or sbox with secret indexes. Each S-box byte is
+// taken from a masked truth table (see tool/ct), for the rounds and the
+// key schedule. It does not use the assembly code and is about 15 times
+// slower than NewCipher on amd64.
+func NewCipherCT(key []byte) (cipher.Block, error) {
+    if len(key) != 16 {
+        return nil, KeySizeError(len(key))
+    }
+
+    c := new(sm4CipherCT)
+    c.expandKey(key)
+
+    return c, nil
+}
+
+func (this *sm4CipherCT) BlockSize() int {
+    return BlockSize
+}
+
+func (this *sm4CipherCT) Encrypt(dst, src []byte) {
+    if len(src) < BlockSize {
+        panic("go-cryptobin/sm4: input not full block")
+    }
+
+    if len(dst) < BlockSize {
+        panic("go-cryptobin/sm4: output not full block")
+    }
+
+    if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    this.crypt(dst, src, false)
+}
+
+func (this *sm4CipherCT) Decrypt(dst, src []byte) {
+    if len(src) < BlockSize {
+        panic("go-cryptobin/sm4: input not full block")
+    }
+
+    if len(dst) < BlockSize {
+        panic("go-cryptobin/sm4: output not full block")
+    }
+
+    if alias.InexactOverlap(dst[:BlockSize], src[:BlockSize]) {
+        panic("go-cryptobin/sm4: invalid buffer overlap")
+    }
+
+    this.crypt(dst, src, true)
+}
+
+func (this *sm4CipherCT) crypt(dst, src []byte, decrypt bool) {
+    x0 := getu32(src[0:])
+    x1 := getu32(src[4:])
+    x2 := getu32(src[8:])
+    x3 := getu32(src[12:])
+
+    for i := 0; i < KeySchedule; i += 4 {
+        var k0, k1, k2, k3 uint32
+        if decrypt {
+            k0, k1, k2, k3 = this.rk[31-i], this.rk[30-i], this.rk[29-i], this.rk[28-i]
+        } else {
+            k0, k1, k2, k3 = this.rk[i], this.rk[i+1], this.rk[i+2], this.rk[i+3]
+        }
+
+        x0 ^= l(sboxCT.Lookup32(x1 ^ x2 ^ x3 ^ k0))
+        x1 ^= l(sboxCT.Lookup32(x2 ^ x3 ^ x0 ^ k1))
+        x2 ^= l(sboxCT.Lookup32(x3 ^ x0 ^ x1 ^ k2))
+        x3 ^= l(sboxCT.Lookup32(x0 ^ x1 ^ x2 ^ k3))
+    }
+
+    putu32(dst[0:], x3)
+    putu32(dst[4:], x2)
+    putu32(dst[8:], x1)
+    putu32(dst[12:], x0)
+}
+
+func (this *sm4CipherCT) expandKey(key []byte) {
+    var k [4]uint32
+    for i := 0; i < 4; i++ {
+        k[i] = getu32(key[4*i:]) ^ fk[i]
+    }
+
+    for i := 0; i < KeySchedule; i += 4 {
+        k[0] ^= lAp(sboxCT.Lookup32(k[1] ^ k[2] ^ k[3] ^ ck[i]))
+        k[1] ^= lAp(sboxCT.Lookup32(k[2] ^ k[3] ^ k[0] ^ ck[i+1]))
+        k[2] ^= lAp(sboxCT.Lookup32(k[3] ^ k[0] ^ k[1] ^ ck[i+2]))
+        k[3] ^= lAp(sboxCT.Lookup32(k[0] ^ k[1] ^ k[2] ^ ck[i+3]))
+
+        copy(this.rk[i:], k[:])
+    }
+}
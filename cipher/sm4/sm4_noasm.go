@@ -0,0 +1,11 @@
+//go:build purego || !(amd64 || arm64)
+
+package sm4
+
+import (
+    "crypto/cipher"
+)
+
+func newCipher(key []byte) cipher.Block {
+    return newCipherGeneric(key)
+}
@@ -5,6 +5,8 @@ import (
     "reflect"
     "testing"
     "math/rand"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
 )
 
 func Test_Cipher(t *testing.T) {
@@ -87,3 +89,68 @@ func Test_Check2(t *testing.T) {
         t.Errorf("expected=%x, result=%x\n", expected, dst)
     }
 }
+
+func Test_CipherCT(t *testing.T) {
+    src := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10}
+    expected := []byte{0x68, 0x1e, 0xdf, 0x34, 0xd2, 0x06, 0x96, 0x5e, 0x86, 0xb3, 0xe9, 0x4f, 0x53, 0x6e, 0x42, 0x46}
+
+    c, err := NewCipherCT(src)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dst := make([]byte, 16)
+    c.Encrypt(dst, src)
+    if !bytes.Equal(dst, expected) {
+        t.Errorf("expected=%x, result=%x\n", expected, dst)
+    }
+
+    c.Decrypt(dst, expected)
+    if !bytes.Equal(dst, src) {
+        t.Errorf("expected=%x, result=%x\n", src, dst)
+    }
+
+    random := rand.New(rand.NewSource(99))
+
+    for i := 0; i < 100; i++ {
+        key := make([]byte, 16)
+        random.Read(key)
+        value := make([]byte, 16)
+        random.Read(value)
+
+        c1, _ := NewCipher(key)
+        c2, _ := NewCipherCT(key)
+
+        want := make([]byte, 16)
+        got := make([]byte, 16)
+
+        c1.Encrypt(want, value)
+        c2.Encrypt(got, value)
+        if !bytes.Equal(got, want) {
+            t.Fatalf("Encrypt got %x, want %x", got, want)
+        }
+
+        c2.Decrypt(got, got)
+        if !bytes.Equal(got, value) {
+            t.Fatalf("Decrypt got %x, want %x", got, value)
+        }
+    }
+}
+
+// DUDECT=1 go test -run Test_CipherCTTiming
+func Test_CipherCTTiming(t *testing.T) {
+    key := make([]byte, 16)
+    rand.Read(key)
+
+    c, err := NewCipherCT(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dst := make([]byte, 16)
+    cryptobin_test.AssertConstantTime(t, cryptobin_test.DudectConfig{
+        Measurements: 200000,
+    }, make([]byte, 16), func(in []byte) {
+        c.Encrypt(dst, in)
+    })
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+    "fmt"
+    "bytes"
+    "strings"
+    "encoding/hex"
+    "encoding/base64"
+)
+
+// 数据编码
+// raw | hex | base64
+func encodeData(data []byte, encoding string) ([]byte, error) {
+    switch strings.ToLower(encoding) {
+        case "", "raw":
+            return data, nil
+        case "hex":
+            return []byte(hex.EncodeToString(data) + "\n"), nil
+        case "base64":
+            return []byte(base64.StdEncoding.EncodeToString(data) + "\n"), nil
+    }
+
+    return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// 数据解码
+// raw | hex | base64
+func decodeData(data []byte, encoding string) ([]byte, error) {
+    switch strings.ToLower(encoding) {
+        case "", "raw":
+            return data, nil
+        case "hex":
+            return hex.DecodeString(string(bytes.TrimSpace(data)))
+        case "base64":
+            return base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+    }
+
+    return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// 解析 hex 参数
+func decodeHexFlag(name, value string) ([]byte, error) {
+    if value == "" {
+        return nil, nil
+    }
+
+    data, err := hex.DecodeString(value)
+    if err != nil {
+        return nil, fmt.Errorf("-%s: %w", name, err)
+    }
+
+    return data, nil
+}
+
+// 多次设置的参数
+type listFlag []string
+
+func (this *listFlag) String() string {
+    return strings.Join(*this, ",")
+}
+
+func (this *listFlag) Set(value string) error {
+    *this = append(*this, value)
+    return nil
+}
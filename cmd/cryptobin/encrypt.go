@@ -0,0 +1,331 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "strconv"
+
+    "github.com/deatil/go-cryptobin/cryptobin/crypto"
+)
+
+// 对称加密参数
+type cipherOptions struct {
+    cipher   string
+    mode     string
+    padding  string
+    key      string
+    iv       string
+    aad      string
+    params   listFlag
+    in       string
+    out      string
+    encoding string
+}
+
+func runEncrypt(c *cli, args []string) error {
+    return runCipher(c, "encrypt", args)
+}
+
+func runDecrypt(c *cli, args []string) error {
+    return runCipher(c, "decrypt", args)
+}
+
+func runCipher(c *cli, name string, args []string) error {
+    fs := c.flags(name)
+
+    var o cipherOptions
+    fs.StringVar(&o.cipher, "cipher", "Aes", "cipher: " + strings.Join(typeNames(crypto.Multiple(1)), " | "))
+    fs.StringVar(&o.mode, "mode", "CBC", "mode: " + strings.Join(typeNames(crypto.Mode(1)), " | "))
+    fs.StringVar(&o.padding, "padding", "PKCS7Padding", "padding: " + strings.Join(typeNames(crypto.Padding(1)), " | "))
+    fs.StringVar(&o.key, "key", "", "key in hex")
+    fs.StringVar(&o.iv, "iv", "", "iv or nonce in hex")
+    fs.StringVar(&o.aad, "aad", "", "additional data in hex for GCM, CCM and Chacha20poly1305")
+    fs.Var(&o.params, "param", "cipher or mode parameter name=value, may be repeated\n" +
+        "(salt, rounds, word_size, cipher, sector_num, sbox, type, block_size, counter,\n" +
+        "nonce_size, tag_size, resync, tweak, hkey)")
+    fs.StringVar(&o.in, "in", "", "input file (default stdin)")
+    fs.StringVar(&o.out, "out", "", "output file (default stdout)")
+    fs.StringVar(&o.encoding, "encoding", "raw", "ciphertext encoding: raw | hex | base64")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    obj, err := o.cryptobin()
+    if err != nil {
+        return err
+    }
+
+    data, err := c.readInput(o.in)
+    if err != nil {
+        return err
+    }
+
+    if name == "encrypt" {
+        obj = obj.FromBytes(data).Encrypt()
+        if err := obj.Error(); err != nil {
+            return err
+        }
+
+        data, err = encodeData(obj.ToBytes(), o.encoding)
+        if err != nil {
+            return err
+        }
+
+        return c.writeOutput(o.out, data)
+    }
+
+    data, err = decodeData(data, o.encoding)
+    if err != nil {
+        return err
+    }
+
+    obj = obj.FromBytes(data).Decrypt()
+    if err := obj.Error(); err != nil {
+        return err
+    }
+
+    return c.writeOutput(o.out, obj.ToBytes())
+}
+
+// 生成加密对象
+func (this *cipherOptions) cryptobin() (crypto.Cryptobin, error) {
+    obj := crypto.New()
+
+    params, err := parseParams(this.params)
+    if err != nil {
+        return obj, err
+    }
+
+    multiple, ok := findType(crypto.Multiple(1), this.cipher)
+    if !ok {
+        return obj, fmt.Errorf("unknown cipher %q", this.cipher)
+    }
+
+    mode, ok := findType(crypto.Mode(1), this.mode)
+    if !ok {
+        return obj, fmt.Errorf("unknown mode %q", this.mode)
+    }
+
+    padding, ok := findType(crypto.Padding(1), this.padding)
+    if !ok {
+        return obj, fmt.Errorf("unknown padding %q", this.padding)
+    }
+
+    key, err := decodeHexFlag("key", this.key)
+    if err != nil {
+        return obj, err
+    }
+
+    iv, err := decodeHexFlag("iv", this.iv)
+    if err != nil {
+        return obj, err
+    }
+
+    aad, err := decodeHexFlag("aad", this.aad)
+    if err != nil {
+        return obj, err
+    }
+
+    obj, err = useMultiple(obj, multiple, params)
+    if err != nil {
+        return obj, err
+    }
+
+    modeCfg, err := modeConfig(mode, params)
+    if err != nil {
+        return obj, err
+    }
+
+    if aad != nil {
+        modeCfg["additional"] = aad
+    }
+
+    obj = obj.
+        ModeBy(mode, modeCfg).
+        PaddingBy(padding).
+        WithKey(key).
+        WithIv(iv)
+
+    return obj, nil
+}
+
+// 设置加密类型和参数
+func useMultiple(obj crypto.Cryptobin, multiple crypto.Multiple, p params) (crypto.Cryptobin, error) {
+    var err error
+
+    switch multiple {
+        case crypto.Blowfish:
+            if salt, ok := p["salt"]; ok {
+                return obj.Blowfish(salt), nil
+            }
+
+            return obj.Blowfish(), nil
+        case crypto.Tea:
+            if _, ok := p["rounds"]; ok {
+                rounds, err := p.int("rounds", 0)
+                return obj.Tea(rounds), err
+            }
+
+            return obj.Tea(), nil
+        case crypto.RC5:
+            var wordSize, rounds uint64
+            if wordSize, err = p.uint("word_size", 32, 64); err != nil {
+                return obj, err
+            }
+            if rounds, err = p.uint("rounds", 12, 64); err != nil {
+                return obj, err
+            }
+
+            return obj.RC5(uint(wordSize), uint(rounds)), nil
+        case crypto.Xts:
+            sectorNum, err := p.uint("sector_num", 0, 64)
+            return obj.Xts(p.string("cipher", "Aes"), sectorNum), err
+        case crypto.Gost:
+            return obj.Gost(p.string("sbox", "SboxDESDerivedParamSet")), nil
+        case crypto.Safer:
+            rounds, err := p.int("rounds", 0)
+            return obj.Safer(p.string("type", "K"), int32(rounds)), err
+        case crypto.Multi2:
+            rounds, err := p.int("rounds", 128)
+            return obj.Multi2(int32(rounds)), err
+        case crypto.Rijndael:
+            blockSize, err := p.int("block_size", 16)
+            return obj.Rijndael(blockSize), err
+        case crypto.Chacha20:
+            if _, ok := p["counter"]; ok {
+                counter, err := p.uint("counter", 0, 32)
+                return obj.Chacha20(uint32(counter)), err
+            }
+
+            return obj.Chacha20(), nil
+    }
+
+    return obj.MultipleBy(multiple), nil
+}
+
+// 模式参数
+func modeConfig(mode crypto.Mode, p params) (map[string]any, error) {
+    cfg := make(map[string]any)
+
+    switch mode {
+        case crypto.GCM, crypto.CCM:
+            nonceSize, err := p.int("nonce_size", 0)
+            if err != nil {
+                return nil, err
+            }
+
+            tagSize, err := p.int("tag_size", 0)
+            if err != nil {
+                return nil, err
+            }
+
+            cfg["nonce_size"] = nonceSize
+            cfg["tag_size"] = tagSize
+        case crypto.OCFB:
+            resync, err := strconv.ParseBool(p.string("resync", "false"))
+            if err != nil {
+                return nil, fmt.Errorf("-param resync: %w", err)
+            }
+
+            cfg["resync"] = resync
+        case crypto.HCTR:
+            tweak, err := decodeHexFlag("param tweak", p.string("tweak", ""))
+            if err != nil {
+                return nil, err
+            }
+
+            hkey, err := decodeHexFlag("param hkey", p.string("hkey", ""))
+            if err != nil {
+                return nil, err
+            }
+
+            cfg["tweak"] = tweak
+            cfg["hkey"] = hkey
+    }
+
+    return cfg, nil
+}
+
+// ==========
+
+type typeName interface {
+    ~uint
+    String() string
+}
+
+// 类型名称列表, 从 1 开始直到未知类型
+func typeNames[T typeName](first T) []string {
+    var names []string
+    for t := first; !strings.HasPrefix(t.String(), "unknown "); t++ {
+        names = append(names, t.String())
+    }
+
+    return names
+}
+
+// 根据名称查找类型, 不区分大小写
+func findType[T typeName](first T, name string) (T, bool) {
+    for t := first; !strings.HasPrefix(t.String(), "unknown "); t++ {
+        if strings.EqualFold(t.String(), name) {
+            return t, true
+        }
+    }
+
+    return 0, false
+}
+
+// ==========
+
+// name=value 参数
+type params map[string]string
+
+func parseParams(list []string) (params, error) {
+    p := make(params)
+    for _, item := range list {
+        name, value, ok := strings.Cut(item, "=")
+        if !ok {
+            return nil, fmt.Errorf("-param %q: want name=value", item)
+        }
+
+        p[name] = value
+    }
+
+    return p, nil
+}
+
+func (this params) string(name, def string) string {
+    if v, ok := this[name]; ok {
+        return v
+    }
+
+    return def
+}
+
+func (this params) int(name string, def int) (int, error) {
+    v, ok := this[name]
+    if !ok {
+        return def, nil
+    }
+
+    n, err := strconv.Atoi(v)
+    if err != nil {
+        return 0, fmt.Errorf("-param %s: %w", name, err)
+    }
+
+    return n, nil
+}
+
+func (this params) uint(name string, def uint64, bitSize int) (uint64, error) {
+    v, ok := this[name]
+    if !ok {
+        return def, nil
+    }
+
+    n, err := strconv.ParseUint(v, 10, bitSize)
+    if err != nil {
+        return 0, fmt.Errorf("-param %s: %w", name, err)
+    }
+
+    return n, nil
+}
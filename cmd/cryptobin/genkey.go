@@ -0,0 +1,50 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+)
+
+func runGenkey(c *cli, args []string) error {
+    fs := c.flags("genkey")
+
+    typ := fs.String("type", "", "key type: " + strings.Join(keyTypeNames(nil), " | "))
+    curve := fs.String("curve", "", "curve, DSA parameter sizes or DH group, see -list")
+    bits := fs.Int("bits", 0, "RSA and ElGamal key size")
+    pkcs1 := fs.Bool("pkcs1", false, "write the private key as PKCS#1 instead of PKCS#8")
+    password := fs.String("password", "", "encrypt the private key with this password")
+    cipher := fs.String("cipher", "", "private key cipher, e.g. AES256CBC")
+    out := fs.String("out", "", "private key file (default stdout)")
+    pubout := fs.String("pubout", "", "public key file (default: appended to the private key output)")
+    list := fs.Bool("list", false, "list key types and their parameters")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    if *list {
+        for _, name := range keyTypeNames(nil) {
+            fmt.Fprintf(c.stdout, "%-10s %s\n", name, keyTypes[name].params)
+        }
+
+        return nil
+    }
+
+    kt, err := getKeyType(*typ)
+    if err != nil {
+        return err
+    }
+
+    priv, pub, err := kt.generate(genOptions{
+        curve:    *curve,
+        bits:     *bits,
+        pkcs1:    *pkcs1,
+        password: *password,
+        cipher:   *cipher,
+    })
+    if err != nil {
+        return err
+    }
+
+    return c.writeKeyPair(*out, *pubout, priv, pub)
+}
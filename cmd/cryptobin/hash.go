@@ -0,0 +1,55 @@
+package main
+
+import (
+    "io"
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/deatil/go-cryptobin/tool/hash"
+)
+
+func runHash(c *cli, args []string) error {
+    fs := c.flags("hash")
+
+    alg := fs.String("alg", "SHA256", "hash algorithm, see -list")
+    list := fs.Bool("list", false, "list hash algorithms")
+    in := fs.String("in", "", "input file (default stdin)")
+    out := fs.String("out", "", "output file (default stdout)")
+    encoding := fs.String("encoding", "hex", "digest encoding: raw | hex | base64")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    if *list {
+        names := hash.HashNames()
+        sort.Strings(names)
+
+        fmt.Fprintln(c.stdout, strings.Join(names, "\n"))
+        return nil
+    }
+
+    fn, err := hash.GetHash(*alg)
+    if err != nil {
+        return fmt.Errorf("unknown hash %q, use -list", *alg)
+    }
+
+    r, err := c.openInput(*in)
+    if err != nil {
+        return err
+    }
+    defer r.Close()
+
+    h := fn()
+    if _, err := io.Copy(h, r); err != nil {
+        return err
+    }
+
+    data, err := encodeData(h.Sum(nil), *encoding)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, data)
+}
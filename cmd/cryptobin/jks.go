@@ -0,0 +1,117 @@
+package main
+
+import (
+    "time"
+    "strings"
+
+    "github.com/deatil/go-cryptobin/pkcs12"
+)
+
+func runJKS(c *cli, args []string) error {
+    return c.runCommands("cryptobin jks", []command{
+        {"create", "create a keystore from a private key and certificates", runJKSCreate},
+        {"list", "list the entries of a keystore", func(c *cli, args []string) error {
+            return runStoreList(c, "jks list", "auto", args)
+        }},
+        {"convert", "convert a keystore to another format", func(c *cli, args []string) error {
+            return runStoreConvert(c, "jks convert", "auto", args)
+        }},
+    }, args)
+}
+
+func runJKSCreate(c *cli, args []string) error {
+    fs := c.flags("jks create")
+
+    format := fs.String("format", "jks", "keystore format: " + strings.Join(sortedKeys(storeFormats), " | "))
+    alias := fs.String("alias", "mykey", "alias of the private key entry")
+    keyFile := fs.String("key", "", "private key file")
+    keyPassword := fs.String("key-password", "", "private key file password")
+    certFile := fs.String("cert", "", "certificate chain file for the private key")
+    var trusted listFlag
+    fs.Var(&trusted, "trusted", "trusted certificates file, may be repeated")
+    password := fs.String("password", "", "keystore password")
+    entryPassword := fs.String("entry-password", "", "private key entry password (default -password)")
+    out := fs.String("out", "", "keystore file (default stdout)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    f, err := getStoreFormat(*format)
+    if err != nil {
+        return err
+    }
+
+    if *entryPassword == "" {
+        *entryPassword = *password
+    }
+
+    ks := f.create()
+    now := time.Now()
+
+    if *keyFile != "" {
+        keyData, err := c.readInput(*keyFile)
+        if err != nil {
+            return err
+        }
+
+        key, err := parsePrivateKey(keyData, *keyPassword)
+        if err != nil {
+            return err
+        }
+
+        certData, err := c.readRequired("cert", *certFile)
+        if err != nil {
+            return err
+        }
+
+        certs, err := parseCerts(certData)
+        if err != nil {
+            return err
+        }
+
+        chain, err := toStdCerts(certs)
+        if err != nil {
+            return err
+        }
+
+        if err := ks.SetPrivateKeyEntry(*alias, key, chain, *entryPassword, now); err != nil {
+            return err
+        }
+    }
+
+    for _, file := range trusted {
+        certData, err := c.readInput(file)
+        if err != nil {
+            return err
+        }
+
+        certs, err := parseCerts(certData)
+        if err != nil {
+            return err
+        }
+
+        stdCerts, err := toStdCerts(certs)
+        if err != nil {
+            return err
+        }
+
+        for _, cert := range stdCerts {
+            name := strings.ToLower(cert.Subject.CommonName)
+            if name == "" {
+                name = cert.SerialNumber.String()
+            }
+
+            if err := ks.SetTrustedCertEntry(name, cert, now); err != nil {
+                return err
+            }
+        }
+    }
+
+    data, err := f.marshal(ks, *password, pkcs12.DefaultOpts)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, data)
+}
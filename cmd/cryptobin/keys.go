@@ -0,0 +1,395 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/deatil/go-cryptobin/cryptobin/rsa"
+    "github.com/deatil/go-cryptobin/cryptobin/dsa"
+    "github.com/deatil/go-cryptobin/cryptobin/sm2"
+    "github.com/deatil/go-cryptobin/cryptobin/bign"
+    "github.com/deatil/go-cryptobin/cryptobin/ecdh"
+    "github.com/deatil/go-cryptobin/cryptobin/gost"
+    "github.com/deatil/go-cryptobin/cryptobin/ecdsa"
+    "github.com/deatil/go-cryptobin/cryptobin/eddsa"
+    "github.com/deatil/go-cryptobin/cryptobin/ed448"
+    "github.com/deatil/go-cryptobin/cryptobin/ed521"
+    "github.com/deatil/go-cryptobin/cryptobin/ecgdsa"
+    "github.com/deatil/go-cryptobin/cryptobin/ecsdsa"
+    "github.com/deatil/go-cryptobin/cryptobin/elgamal"
+    "github.com/deatil/go-cryptobin/cryptobin/bip0340"
+    "github.com/deatil/go-cryptobin/cryptobin/dh/dh"
+    "github.com/deatil/go-cryptobin/cryptobin/dh/curve25519"
+    dh_ecdh "github.com/deatil/go-cryptobin/cryptobin/dh/ecdh"
+    pubkey_bign "github.com/deatil/go-cryptobin/pubkey/bign"
+)
+
+// 生成密钥配置
+type genOptions struct {
+    // 曲线, DSA 参数或者 DH 分组
+    curve string
+
+    // RSA, ElGamal 位数
+    bits int
+
+    // 使用 PKCS1 格式私钥
+    pkcs1 bool
+
+    // 私钥密码
+    password string
+
+    // 私钥加密方式
+    cipher string
+}
+
+func (this genOptions) curveOr(def string) string {
+    if this.curve != "" {
+        return this.curve
+    }
+
+    return def
+}
+
+func (this genOptions) bitsOr(def int) int {
+    if this.bits > 0 {
+        return this.bits
+    }
+
+    return def
+}
+
+type (
+    generateFunc = func(o genOptions) (priv, pub []byte, err error)
+    signFunc     = func(key []byte, password, hash string, data []byte) ([]byte, error)
+    verifyFunc   = func(pub []byte, hash string, data, sig []byte) error
+)
+
+// 密钥类型
+type keyType struct {
+    name     string
+    params   string
+    generate generateFunc
+    sign     signFunc
+    verify   verifyFunc
+}
+
+var keyTypes = map[string]keyType{
+    "rsa": {
+        params:   "-bits (default 2048)",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(rsa.GenerateKey(o.bitsOr(2048)), o)
+        },
+        sign:   newSignFunc(rsa.New),
+        verify: newVerifyFunc(rsa.New),
+    },
+    "dsa": {
+        params:   "-curve L1024N160 | L2048N224 | L2048N256 (default) | L3072N256",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(dsa.GenerateKey(o.curveOr("L2048N256")), o)
+        },
+        sign:   newSignFunc(dsa.New),
+        verify: newVerifyFunc(dsa.New),
+    },
+    "ecdsa": {
+        params:   "-curve P224 | P256 (default) | P384 | P521",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(ecdsa.GenerateKey(o.curveOr("P256")), o)
+        },
+        sign:   newSignFunc(ecdsa.New),
+        verify: newVerifyFunc(ecdsa.New),
+    },
+    "eddsa": {
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(eddsa.GenerateKey(), o)
+        },
+        sign:   newSignFunc(eddsa.New),
+        verify: newVerifyFunc(eddsa.New),
+    },
+    "ed448": {
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(ed448.GenerateKey(), o)
+        },
+        sign:   newSignFunc(ed448.New),
+        verify: newVerifyFunc(ed448.New),
+    },
+    "ed521": {
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(ed521.GenerateKey(), o)
+        },
+        sign:   newSignFunc(ed521.New),
+        verify: newVerifyFunc(ed521.New),
+    },
+    "sm2": {
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(sm2.GenerateKey(), o)
+        },
+        sign:   newSignFunc(sm2.New),
+        verify: newVerifyFunc(sm2.New),
+    },
+    "gost": {
+        params:   "-curve Idtc26gost34102012256paramSetA (default) | IdGostR34102001CryptoProAParamSet | ...",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(gost.GenerateKey(o.curveOr("Idtc26gost34102012256paramSetA")), o)
+        },
+        sign:   newSignFunc(gost.New),
+        verify: newVerifyFunc(gost.New),
+    },
+    "elgamal": {
+        params:   "-bits (default 1024)",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(elgamal.GenerateKey(o.bitsOr(1024), 64), o)
+        },
+        sign:   newSignFunc(elgamal.New),
+        verify: newVerifyFunc(elgamal.New),
+    },
+    "ecgdsa": {
+        params:   "-curve P224 | P256 (default) | P384 | P521 | BrainpoolP256r1 | ...",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(ecgdsa.GenerateKey(o.curveOr("P256")), o)
+        },
+        sign:   newSignFunc(ecgdsa.New),
+        verify: newVerifyFunc(ecgdsa.New),
+    },
+    "ecsdsa": {
+        params:   "-curve P224 | P256 (default) | P384 | P521 | BrainpoolP256r1 | ...",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(ecsdsa.GenerateKey(o.curveOr("P256")), o)
+        },
+        sign:   newSignFunc(ecsdsa.New),
+        verify: newVerifyFunc(ecsdsa.New),
+    },
+    "bign": {
+        params:   "-curve Bign256v1 (default) | Bign384v1 | Bign512v1",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(bign.GenerateKey(o.curveOr("Bign256v1")), o)
+        },
+        sign:   newSignFunc(newBign),
+        verify: newVerifyFunc(newBign),
+    },
+    "bip0340": {
+        params:   "-curve S256 (default) | P224 | P256 | P384 | P521",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(bip0340.GenerateKey(o.curveOr("S256")), o)
+        },
+        sign:   newSignFunc(bip0340.New),
+        verify: newVerifyFunc(bip0340.New),
+    },
+    "ecdh": {
+        params:   "-curve P256 (default) | P384 | P521 | X25519",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(ecdh.GenerateKey(o.curveOr("P256")), o)
+        },
+    },
+    "dh": {
+        params:   "-curve P1001 | P1002 | P1536 | P2048 (default) | P3072 | P4096 | P6144 | P8192",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(dh.GenerateKey(o.curveOr("P2048")), o)
+        },
+    },
+    "dh-ecdh": {
+        params:   "-curve P224 | P256 (default) | P384 | P521",
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(dh_ecdh.GenerateKey(o.curveOr("P256")), o)
+        },
+    },
+    "curve25519": {
+        generate: func(o genOptions) ([]byte, []byte, error) {
+            return createKeys(curve25519.GenerateKey(), o)
+        },
+    },
+}
+
+// 获取密钥类型
+func getKeyType(name string) (keyType, error) {
+    typ, ok := keyTypes[strings.ToLower(name)]
+    if !ok {
+        return keyType{}, fmt.Errorf("unknown key type %q, available: %s", name, strings.Join(keyTypeNames(nil), ", "))
+    }
+
+    typ.name = strings.ToLower(name)
+
+    return typ, nil
+}
+
+// 密钥类型列表
+func keyTypeNames(filter func(keyType) bool) []string {
+    names := make([]string, 0, len(keyTypes))
+    for name, typ := range keyTypes {
+        if filter == nil || filter(typ) {
+            names = append(names, name)
+        }
+    }
+
+    sort.Strings(names)
+
+    return names
+}
+
+// ==========
+
+type keyCreator[T any] interface {
+    CreatePrivateKey() T
+    CreatePublicKey() T
+    ToKeyBytes() []byte
+    Error() error
+}
+
+type pkcs1Creator[T any] interface {
+    CreatePKCS1PrivateKey() T
+    CreatePKCS1PrivateKeyWithPassword(password string, opts ...string) T
+}
+
+type pkcs8Creator[T any] interface {
+    CreatePKCS8PrivateKey() T
+    CreatePKCS8PrivateKeyWithPassword(password string, opts ...any) T
+}
+
+type passwordCreator[T any] interface {
+    CreatePrivateKeyWithPassword(password string, opts ...any) T
+}
+
+// 生成私钥和公钥 PEM 数据
+// 默认为 PKCS8 格式私钥
+func createKeys[T keyCreator[T]](k T, o genOptions) (priv, pub []byte, err error) {
+    if err = k.Error(); err != nil {
+        return
+    }
+
+    var privKey T
+
+    switch {
+        case o.pkcs1:
+            c, ok := any(k).(pkcs1Creator[T])
+            if !ok {
+                return nil, nil, fmt.Errorf("PKCS#1 private keys are not supported by this key type")
+            }
+
+            if o.password != "" {
+                opts := []string{}
+                if o.cipher != "" {
+                    opts = append(opts, o.cipher)
+                }
+
+                privKey = c.CreatePKCS1PrivateKeyWithPassword(o.password, opts...)
+            } else {
+                privKey = c.CreatePKCS1PrivateKey()
+            }
+
+        case o.password != "":
+            opts := []any{}
+            if o.cipher != "" {
+                opts = append(opts, o.cipher)
+            }
+
+            if c, ok := any(k).(pkcs8Creator[T]); ok {
+                privKey = c.CreatePKCS8PrivateKeyWithPassword(o.password, opts...)
+            } else if c, ok := any(k).(passwordCreator[T]); ok {
+                privKey = c.CreatePrivateKeyWithPassword(o.password, opts...)
+            } else {
+                return nil, nil, fmt.Errorf("encrypted private keys are not supported by this key type")
+            }
+
+        default:
+            if c, ok := any(k).(pkcs8Creator[T]); ok {
+                privKey = c.CreatePKCS8PrivateKey()
+            } else {
+                privKey = k.CreatePrivateKey()
+            }
+    }
+
+    if err = privKey.Error(); err != nil {
+        return
+    }
+
+    pubKey := k.CreatePublicKey()
+    if err = pubKey.Error(); err != nil {
+        return
+    }
+
+    return privKey.ToKeyBytes(), pubKey.ToKeyBytes(), nil
+}
+
+// ==========
+
+type keySigner[T any] interface {
+    FromPrivateKey(key []byte) T
+    FromPrivateKeyWithPassword(key []byte, password string) T
+    FromPublicKey(key []byte) T
+    FromBytes(data []byte) T
+    Sign() T
+    Verify(data []byte) T
+    ToBytes() []byte
+    ToVerify() bool
+    Error() error
+}
+
+type signHashSetter[T any] interface {
+    SetSignHash(name string) T
+}
+
+// 设置签名摘要
+func withSignHash[T keySigner[T]](k T, hash string) (T, error) {
+    if hash == "" {
+        return k, nil
+    }
+
+    s, ok := any(k).(signHashSetter[T])
+    if !ok {
+        return k, fmt.Errorf("-hash is not supported by this key type")
+    }
+
+    return s.SetSignHash(hash), nil
+}
+
+// bign 签名使用 belt-hash OID 作为默认 adata
+var bignAdata = pubkey_bign.MakeAdata([]byte{
+    0x06, 0x09, 0x2A, 0x70, 0x00, 0x02, 0x00, 0x22, 0x65, 0x1F, 0x51,
+}, nil)
+
+func newBign() bign.Bign {
+    return bign.New().WithAdata(bignAdata)
+}
+
+func newSignFunc[T keySigner[T]](newKey func() T) signFunc {
+    return func(key []byte, password, hash string, data []byte) ([]byte, error) {
+        k := newKey()
+        if password != "" {
+            k = k.FromPrivateKeyWithPassword(key, password)
+        } else {
+            k = k.FromPrivateKey(key)
+        }
+
+        k, err := withSignHash(k, hash)
+        if err != nil {
+            return nil, err
+        }
+
+        k = k.FromBytes(data).Sign()
+        if err := k.Error(); err != nil {
+            return nil, err
+        }
+
+        return k.ToBytes(), nil
+    }
+}
+
+func newVerifyFunc[T keySigner[T]](newKey func() T) verifyFunc {
+    return func(pub []byte, hash string, data, sig []byte) error {
+        k, err := withSignHash(newKey().FromPublicKey(pub), hash)
+        if err != nil {
+            return err
+        }
+
+        k = k.FromBytes(sig).Verify(data)
+        if !k.ToVerify() {
+            if err := k.Error(); err != nil {
+                return fmt.Errorf("verification failed: %w", err)
+            }
+
+            return fmt.Errorf("verification failed")
+        }
+
+        return nil
+    }
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "time"
+    "bytes"
+    "strings"
+    "crypto/rand"
+
+    "github.com/deatil/go-cryptobin/jceks"
+    "github.com/deatil/go-cryptobin/pkcs12"
+    "github.com/deatil/go-cryptobin/keystore"
+)
+
+// 证书库格式
+type storeFormat struct {
+    load    func(data []byte, password string) (keystore.KeyStore, error)
+    create  func() keystore.KeyStore
+    marshal func(ks keystore.KeyStore, password string, opts pkcs12.Opts) ([]byte, error)
+}
+
+var storeFormats = map[string]storeFormat{
+    "jks": {
+        load: func(data []byte, password string) (keystore.KeyStore, error) {
+            return jceks.LoadJksFromBytes(data, password)
+        },
+        create: func() keystore.KeyStore {
+            return jceks.NewJKS()
+        },
+        marshal: func(ks keystore.KeyStore, password string, _ pkcs12.Opts) ([]byte, error) {
+            return ks.(*jceks.JKS).Marshal(password)
+        },
+    },
+    "jceks": {
+        load: func(data []byte, password string) (keystore.KeyStore, error) {
+            return jceks.LoadJceksFromBytes(data, password)
+        },
+        create: func() keystore.KeyStore {
+            return jceks.NewJCEKS()
+        },
+        marshal: func(ks keystore.KeyStore, password string, _ pkcs12.Opts) ([]byte, error) {
+            return ks.(*jceks.JCEKS).Marshal(password)
+        },
+    },
+    "bks": {
+        load: func(data []byte, password string) (keystore.KeyStore, error) {
+            return jceks.LoadBksFromBytes(data, password)
+        },
+        create: func() keystore.KeyStore {
+            return jceks.NewBKS()
+        },
+        marshal: func(ks keystore.KeyStore, password string, _ pkcs12.Opts) ([]byte, error) {
+            return ks.(*jceks.BKS).Marshal(password)
+        },
+    },
+    "uber": {
+        load: func(data []byte, password string) (keystore.KeyStore, error) {
+            return jceks.LoadUberFromBytes(data, password)
+        },
+        create: func() keystore.KeyStore {
+            return jceks.NewUBER()
+        },
+        marshal: func(ks keystore.KeyStore, password string, _ pkcs12.Opts) ([]byte, error) {
+            return ks.(*jceks.UBER).Marshal(password)
+        },
+    },
+    "pkcs12": {
+        load: func(data []byte, password string) (keystore.KeyStore, error) {
+            return pkcs12.LoadFromBytes(data, password)
+        },
+        create: func() keystore.KeyStore {
+            return pkcs12.New()
+        },
+        marshal: func(ks keystore.KeyStore, password string, opts pkcs12.Opts) ([]byte, error) {
+            return ks.(*pkcs12.PKCS12).Marshal(rand.Reader, password, opts)
+        },
+    },
+}
+
+// PKCS12 加密配置
+var pkcs12Opts = map[string]pkcs12.Opts{
+    "default":      pkcs12.DefaultOpts,
+    "legacy-rc2":   pkcs12.LegacyRC2Opts,
+    "legacy-des":   pkcs12.LegacyDESOpts,
+    "modern":       pkcs12.Modern2023Opts,
+    "gost":         pkcs12.LegacyGostOpts,
+    "gmsm":         pkcs12.LegacyGmsmOpts,
+    "pbmac1":       pkcs12.LegacyPBMAC1Opts,
+    "passwordless": pkcs12.PasswordlessOpts,
+}
+
+func getPKCS12Opts(name string) (pkcs12.Opts, error) {
+    opts, ok := pkcs12Opts[strings.ToLower(name)]
+    if !ok {
+        return pkcs12.Opts{}, fmt.Errorf("unknown PKCS#12 options %q, available: %s", name, strings.Join(sortedKeys(pkcs12Opts), ", "))
+    }
+
+    return opts, nil
+}
+
+func getStoreFormat(name string) (storeFormat, error) {
+    f, ok := storeFormats[strings.ToLower(name)]
+    if !ok {
+        return f, fmt.Errorf("unknown keystore format %q, available: %s", name, strings.Join(sortedKeys(storeFormats), ", "))
+    }
+
+    return f, nil
+}
+
+// 根据文件头识别格式, BKS 和 UBER 需要手动指定
+func detectStoreFormat(data []byte) (string, error) {
+    switch {
+        case bytes.HasPrefix(data, []byte{0xfe, 0xed, 0xfe, 0xed}):
+            return "jks", nil
+        case bytes.HasPrefix(data, []byte{0xce, 0xce, 0xce, 0xce}):
+            return "jceks", nil
+        case len(data) > 0 && data[0] == 0x30:
+            return "pkcs12", nil
+    }
+
+    return "", fmt.Errorf("can not detect keystore format, use -format bks or -format uber")
+}
+
+// 读取证书库
+func (c *cli) loadStore(path, format, password string) (keystore.KeyStore, error) {
+    data, err := c.readInput(path)
+    if err != nil {
+        return nil, err
+    }
+
+    if format == "" || format == "auto" {
+        if format, err = detectStoreFormat(data); err != nil {
+            return nil, err
+        }
+    }
+
+    f, err := getStoreFormat(format)
+    if err != nil {
+        return nil, err
+    }
+
+    return f.load(data, password)
+}
+
+// 列出证书库条目
+func runStoreList(c *cli, name, defaultFormat string, args []string) error {
+    fs := c.flags(name)
+
+    in := fs.String("in", "", "keystore file (default stdin)")
+    format := fs.String("format", defaultFormat, "keystore format: auto | " + strings.Join(sortedKeys(storeFormats), " | "))
+    password := fs.String("password", "", "keystore password")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    ks, err := c.loadStore(*in, *format, *password)
+    if err != nil {
+        return err
+    }
+
+    for _, alias := range ks.Aliases() {
+        typ, err := ks.EntryType(alias)
+        if err != nil {
+            return err
+        }
+
+        line := fmt.Sprintf("%s\t%s", alias, typ)
+
+        if date, err := ks.CreationDate(alias); err == nil && !date.IsZero() {
+            line += "\t" + date.Format(time.RFC3339)
+        }
+
+        if typ == keystore.TrustedCertEntry {
+            if cert, err := ks.TrustedCertEntry(alias); err == nil {
+                line += "\t" + cert.Subject.String()
+            }
+        }
+
+        fmt.Fprintln(c.stdout, line)
+    }
+
+    return nil
+}
+
+// 转换证书库格式
+func runStoreConvert(c *cli, name, defaultFormat string, args []string) error {
+    fs := c.flags(name)
+
+    in := fs.String("in", "", "source keystore file (default stdin)")
+    from := fs.String("from", defaultFormat, "source format: auto | " + strings.Join(sortedKeys(storeFormats), " | "))
+    password := fs.String("password", "", "source keystore password")
+    entryPassword := fs.String("entry-password", "", "source entry password (default -password)")
+    to := fs.String("to", "", "target format: " + strings.Join(sortedKeys(storeFormats), " | "))
+    out := fs.String("out", "", "target keystore file (default stdout)")
+    newPassword := fs.String("new-password", "", "target keystore password (default -password)")
+    newEntryPassword := fs.String("new-entry-password", "", "target entry password (default target keystore password)")
+    p12Opts := fs.String("pkcs12-opts", "default", "PKCS#12 options: " + strings.Join(sortedKeys(pkcs12Opts), " | "))
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    src, err := c.loadStore(*in, *from, *password)
+    if err != nil {
+        return err
+    }
+
+    target, err := getStoreFormat(*to)
+    if err != nil {
+        return err
+    }
+
+    opts, err := getPKCS12Opts(*p12Opts)
+    if err != nil {
+        return err
+    }
+
+    if *entryPassword == "" {
+        *entryPassword = *password
+    }
+    if *newPassword == "" {
+        *newPassword = *password
+    }
+    if *newEntryPassword == "" {
+        *newEntryPassword = *newPassword
+    }
+
+    dst := target.create()
+
+    res, err := keystore.Convert(src, dst, keystore.Passwords{
+        Source: *entryPassword,
+        Target: *newEntryPassword,
+    })
+    if err != nil {
+        return err
+    }
+
+    for _, skipped := range res.Skipped {
+        fmt.Fprintf(c.stderr, "skipped %s (%s): %v\n", skipped.Alias, skipped.Type, skipped.Err)
+    }
+
+    data, err := target.marshal(dst, *newPassword, opts)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, data)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+
+    sort.Strings(keys)
+
+    return keys
+}
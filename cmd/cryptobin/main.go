@@ -0,0 +1,160 @@
+// Command cryptobin exposes the go-cryptobin packages on the command line.
+//
+// Every command reads its main input from -in (default stdin) and writes
+// to -out (default stdout), so commands can be chained with pipes.
+//
+//    cryptobin genkey -type sm2 -out sm2.key -pubout sm2.pub
+//    cryptobin sign -type sm2 -key sm2.key -in data.txt -out data.sig
+//    cryptobin verify -type sm2 -key sm2.pub -sig data.sig -in data.txt
+package main
+
+import (
+    "io"
+    "os"
+    "fmt"
+    "flag"
+    "errors"
+    "strings"
+)
+
+// 子命令
+type command struct {
+    name  string
+    usage string
+    run   func(c *cli, args []string) error
+}
+
+// 命令行环境
+type cli struct {
+    stdin  io.Reader
+    stdout io.Writer
+    stderr io.Writer
+}
+
+func commands() []command {
+    return []command{
+        {"genkey", "generate a key pair for any cryptobin key type", runGenkey},
+        {"sign", "sign data with a private key", runSign},
+        {"verify", "verify a signature with a public key", runVerify},
+        {"encrypt", "encrypt data with a symmetric cipher", runEncrypt},
+        {"decrypt", "decrypt data with a symmetric cipher", runDecrypt},
+        {"pkcs12", "create, export, list or convert PKCS#12 files", runPKCS12},
+        {"jks", "create, list or convert JKS, JCEKS, BKS and UBER keystores", runJKS},
+        {"x509", "create requests, sign and show certificates", runX509},
+        {"pkcs7", "sign, verify, encrypt and decrypt PKCS#7 messages", runPKCS7},
+        {"ssh", "generate OpenSSH keys", runSSH},
+        {"hash", "hash data", runHash},
+    }
+}
+
+func main() {
+    c := &cli{
+        stdin:  os.Stdin,
+        stdout: os.Stdout,
+        stderr: os.Stderr,
+    }
+
+    if err := c.run(os.Args[1:]); err != nil {
+        fmt.Fprintln(os.Stderr, "cryptobin:", err)
+        os.Exit(1)
+    }
+}
+
+func (c *cli) run(args []string) error {
+    return c.runCommands("cryptobin", commands(), args)
+}
+
+// 执行子命令
+func (c *cli) runCommands(name string, cmds []command, args []string) error {
+    if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+        c.usage(name, cmds)
+        return nil
+    }
+
+    for _, cmd := range cmds {
+        if cmd.name == args[0] {
+            err := cmd.run(c, args[1:])
+            if errors.Is(err, flag.ErrHelp) {
+                return nil
+            }
+
+            return err
+        }
+    }
+
+    c.usage(name, cmds)
+
+    return fmt.Errorf("unknown command %q", strings.Join(append([]string{name}, args[0]), " "))
+}
+
+func (c *cli) usage(name string, cmds []command) {
+    fmt.Fprintf(c.stderr, "Usage: %s <command> [flags]\n\nCommands:\n", name)
+
+    for _, cmd := range cmds {
+        fmt.Fprintf(c.stderr, "    %-10s %s\n", cmd.name, cmd.usage)
+    }
+
+    fmt.Fprintf(c.stderr, "\nRun '%s <command> -h' for the flags of a command.\n", name)
+}
+
+// 创建 flag 集合
+func (c *cli) flags(name string) *flag.FlagSet {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.SetOutput(c.stderr)
+
+    return fs
+}
+
+// 读取文件, 为空或者 "-" 时读取 stdin
+func (c *cli) readInput(path string) ([]byte, error) {
+    if path == "" || path == "-" {
+        return io.ReadAll(c.stdin)
+    }
+
+    return os.ReadFile(path)
+}
+
+// 打开文件, 为空或者 "-" 时使用 stdin
+func (c *cli) openInput(path string) (io.ReadCloser, error) {
+    if path == "" || path == "-" {
+        return io.NopCloser(c.stdin), nil
+    }
+
+    return os.Open(path)
+}
+
+// 写入文件, 为空或者 "-" 时写入 stdout
+func (c *cli) writeOutput(path string, data []byte) error {
+    if path == "" || path == "-" {
+        _, err := c.stdout.Write(data)
+        return err
+    }
+
+    return os.WriteFile(path, data, 0600)
+}
+
+// 写入密钥对, 未指定公钥文件时私钥输出到 stdout 则一起输出, 否则公钥输出到 stdout
+func (c *cli) writeKeyPair(out, pubout string, priv, pub []byte) error {
+    if pubout == "" {
+        if out == "" || out == "-" {
+            return c.writeOutput(out, append(priv, pub...))
+        }
+
+        pubout = "-"
+    }
+
+    if err := c.writeOutput(out, priv); err != nil {
+        return err
+    }
+
+    return c.writeOutput(pubout, pub)
+}
+
+// 读取必须的文件
+func (c *cli) readRequired(flagName, path string) ([]byte, error) {
+    if path == "" {
+        return nil, fmt.Errorf("missing -%s", flagName)
+    }
+
+    return c.readInput(path)
+}
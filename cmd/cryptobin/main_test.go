@@ -0,0 +1,197 @@
+package main
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "path/filepath"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
+)
+
+// 执行命令并返回 stdout
+func runCLI(t *testing.T, stdin []byte, args ...string) string {
+    t.Helper()
+
+    var stdout, stderr bytes.Buffer
+    c := &cli{
+        stdin:  bytes.NewReader(stdin),
+        stdout: &stdout,
+        stderr: &stderr,
+    }
+
+    if err := c.run(args); err != nil {
+        t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+    }
+
+    return stdout.String()
+}
+
+func Test_GenkeySignVerify(t *testing.T) {
+    dir := t.TempDir()
+    key := filepath.Join(dir, "key.pem")
+    pub := filepath.Join(dir, "pub.pem")
+    sig := filepath.Join(dir, "data.sig")
+    data := []byte("cryptobin sign data")
+
+    for _, typ := range []string{"sm2", "ecdsa", "eddsa", "rsa", "gost", "bign"} {
+        t.Run(typ, func(t *testing.T) {
+            runCLI(t, nil, "genkey", "-type", typ, "-bits", "1024", "-out", key, "-pubout", pub)
+            runCLI(t, data, "sign", "-type", typ, "-key", key, "-encoding", "base64", "-out", sig)
+            runCLI(t, data, "verify", "-type", typ, "-key", pub, "-sig", sig, "-encoding", "base64")
+
+            var stdout, stderr bytes.Buffer
+            c := &cli{stdin: strings.NewReader("other data"), stdout: &stdout, stderr: &stderr}
+            if err := c.run([]string{"verify", "-type", typ, "-key", pub, "-sig", sig, "-encoding", "base64"}); err == nil {
+                t.Error("verify should fail for other data")
+            }
+        })
+    }
+}
+
+func Test_EncryptDecrypt(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+
+    plain := []byte("cryptobin command line test data")
+    key := "000102030405060708090a0b0c0d0e0f"
+    iv := "0f0e0d0c0b0a09080706050403020100"
+
+    cases := [][]string{
+        {"-cipher", "Aes", "-mode", "CBC", "-padding", "PKCS7Padding", "-iv", iv},
+        {"-cipher", "SM4", "-mode", "CTR", "-padding", "NoPadding", "-iv", iv},
+        {"-cipher", "Aes", "-mode", "GCM", "-iv", "000102030405060708090a0b", "-aad", "0102"},
+    }
+
+    for _, args := range cases {
+        enc := runCLI(t, plain, append([]string{"encrypt", "-key", key, "-encoding", "hex"}, args...)...)
+        dec := runCLI(t, []byte(enc), append([]string{"decrypt", "-key", key, "-encoding", "hex"}, args...)...)
+
+        assertEqual(dec, string(plain), "EncryptDecrypt " + strings.Join(args, " "))
+    }
+}
+
+func Test_Hash(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+
+    out := runCLI(t, []byte("abc"), "hash", "-alg", "SM3")
+    assertEqual(out, "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0\n", "Hash")
+
+    if !strings.Contains(runCLI(t, nil, "hash", "-list"), "SHA256") {
+        t.Error("hash -list missing SHA256")
+    }
+}
+
+// 生成自签名证书
+func testCert(t *testing.T, dir, typ string) (string, string) {
+    key := filepath.Join(dir, typ + ".key")
+    csr := filepath.Join(dir, typ + ".csr")
+    cert := filepath.Join(dir, typ + ".crt")
+
+    runCLI(t, nil, "genkey", "-type", typ, "-out", key, "-pubout", filepath.Join(dir, typ + ".pub"))
+    runCLI(t, nil, "x509", "req", "-key", key, "-cn", "cryptobin " + typ, "-org", "test", "-dns", "example.com", "-out", csr)
+    runCLI(t, nil, "x509", "sign", "-csr", csr, "-cakey", key, "-is-ca", "-ip", "127.0.0.1", "-out", cert)
+
+    return key, cert
+}
+
+func Test_X509(t *testing.T) {
+    dir := t.TempDir()
+
+    for _, typ := range []string{"rsa", "ecdsa", "sm2"} {
+        _, cert := testCert(t, dir, typ)
+
+        out := runCLI(t, nil, "x509", "show", "-in", cert)
+        for _, want := range []string{"CN=cryptobin " + typ, "DNS: example.com", "IP: 127.0.0.1", "CA: true"} {
+            if !strings.Contains(out, want) {
+                t.Errorf("%s: x509 show missing %q in\n%s", typ, want, out)
+            }
+        }
+
+        out = runCLI(t, nil, "x509", "show", "-in", filepath.Join(dir, typ + ".csr"))
+        if !strings.Contains(out, "Signature: OK") {
+            t.Errorf("%s: x509 show csr got\n%s", typ, out)
+        }
+    }
+}
+
+func Test_PKCS12AndJKS(t *testing.T) {
+    dir := t.TempDir()
+    key, cert := testCert(t, dir, "rsa")
+
+    p12 := filepath.Join(dir, "store.p12")
+    jks := filepath.Join(dir, "store.jks")
+
+    runCLI(t, nil, "pkcs12", "create", "-key", key, "-cert", cert, "-password", "pass", "-opts", "modern", "-out", p12)
+
+    out := runCLI(t, nil, "pkcs12", "list", "-in", p12, "-password", "pass")
+    if !strings.Contains(out, "PrivateKeyEntry") {
+        t.Errorf("pkcs12 list got %q", out)
+    }
+
+    pemOut := runCLI(t, nil, "pkcs12", "export", "-in", p12, "-password", "pass")
+    if !strings.Contains(pemOut, "CERTIFICATE") || !strings.Contains(pemOut, "PRIVATE KEY") {
+        t.Errorf("pkcs12 export got %q", pemOut)
+    }
+
+    runCLI(t, nil, "pkcs12", "convert", "-in", p12, "-password", "pass", "-to", "jks", "-new-password", "changeit", "-out", jks)
+
+    out = runCLI(t, nil, "jks", "list", "-in", jks, "-password", "changeit")
+    if !strings.Contains(out, "PrivateKeyEntry") {
+        t.Errorf("jks list got %q", out)
+    }
+
+    bks := filepath.Join(dir, "store.bks")
+    runCLI(t, nil, "jks", "create", "-format", "bks", "-key", key, "-cert", cert, "-trusted", cert, "-password", "pass", "-out", bks)
+
+    out = runCLI(t, nil, "jks", "list", "-in", bks, "-format", "bks", "-password", "pass")
+    if !strings.Contains(out, "mykey") || !strings.Contains(out, "TrustedCertEntry") {
+        t.Errorf("bks list got %q", out)
+    }
+}
+
+func Test_PKCS7(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+
+    dir := t.TempDir()
+    content := []byte("pkcs7 content")
+
+    for _, typ := range []string{"rsa", "sm2"} {
+        key, cert := testCert(t, dir, typ)
+        signed := filepath.Join(dir, typ + ".p7s")
+        detached := filepath.Join(dir, typ + ".detached.p7s")
+
+        runCLI(t, content, "pkcs7", "sign", "-cert", cert, "-key", key, "-pem", "-out", signed)
+        out := runCLI(t, nil, "pkcs7", "verify", "-in", signed, "-ca", cert, "-out", "-")
+        assertEqual(out, string(content), typ + " pkcs7 verify")
+
+        runCLI(t, content, "pkcs7", "sign", "-cert", cert, "-key", key, "-detach", "-out", detached)
+        runCLI(t, content, "pkcs7", "verify", "-in", detached, "-content", "-")
+
+        enc := runCLI(t, content, "pkcs7", "encrypt", "-recipient", cert, "-pem")
+        dec := runCLI(t, []byte(enc), "pkcs7", "decrypt", "-cert", cert, "-key", key)
+        assertEqual(dec, string(content), typ + " pkcs7 decrypt")
+    }
+}
+
+func Test_SSHKeygen(t *testing.T) {
+    for _, typ := range []string{"rsa", "ecdsa", "eddsa", "sm2"} {
+        out := runCLI(t, nil, "ssh", "keygen", "-type", typ, "-bits", "1024", "-comment", "test", "-password", "pass")
+
+        if !strings.Contains(out, "BEGIN OPENSSH PRIVATE KEY") || !strings.Contains(out, " test") {
+            t.Errorf("%s: ssh keygen got %q", typ, out)
+        }
+    }
+}
+
+func Test_UnknownCommand(t *testing.T) {
+    var stdout, stderr bytes.Buffer
+    c := &cli{stdin: strings.NewReader(""), stdout: &stdout, stderr: &stderr}
+
+    if err := c.run([]string{"nope"}); err == nil {
+        t.Error("unknown command should fail")
+    }
+
+    if err := c.run([]string{"genkey", "-type", "nope"}); err == nil {
+        t.Error("unknown key type should fail")
+    }
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+    "errors"
+    "crypto"
+    "encoding/pem"
+    std_x509 "crypto/x509"
+
+    "github.com/deatil/go-cryptobin/x509"
+    "github.com/deatil/go-cryptobin/cryptobin/ca"
+    "github.com/deatil/go-cryptobin/cryptobin/rsa"
+    "github.com/deatil/go-cryptobin/cryptobin/dsa"
+    "github.com/deatil/go-cryptobin/cryptobin/sm2"
+    "github.com/deatil/go-cryptobin/cryptobin/ecdsa"
+)
+
+// 解析 PEM 私钥, 支持 PKCS8 和 RSA, DSA, ECDSA, SM2 的 PKCS1 格式
+func parsePrivateKey(data []byte, password string) (crypto.PrivateKey, error) {
+    var obj ca.CA
+    if password != "" {
+        obj = ca.FromPrivateKeyWithPassword(data, []byte(password))
+    } else {
+        obj = ca.FromPrivateKey(data)
+    }
+
+    if obj.Error() == nil {
+        return obj.GetPrivateKey(), nil
+    }
+
+    if password != "" {
+        if k := rsa.FromPKCS1PrivateKeyWithPassword(data, password); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+        if k := ecdsa.FromPKCS1PrivateKeyWithPassword(data, password); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+        if k := sm2.FromPKCS1PrivateKeyWithPassword(data, password); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+        if k := dsa.FromPKCS1PrivateKeyWithPassword(data, password); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+    } else {
+        if k := rsa.FromPKCS1PrivateKey(data); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+        if k := ecdsa.FromPKCS1PrivateKey(data); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+        if k := sm2.FromPKCS1PrivateKey(data); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+        if k := dsa.FromPKCS1PrivateKey(data); k.Error() == nil {
+            return k.GetPrivateKey(), nil
+        }
+    }
+
+    return nil, obj.Error()
+}
+
+// 解析证书, 支持多个 PEM 证书或者 DER 证书
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+    var certs []*x509.Certificate
+
+    rest := data
+    for {
+        var block *pem.Block
+        block, rest = pem.Decode(rest)
+        if block == nil {
+            break
+        }
+
+        if block.Type != "CERTIFICATE" {
+            continue
+        }
+
+        cert, err := x509.ParseCertificate(block.Bytes)
+        if err != nil {
+            return nil, err
+        }
+
+        certs = append(certs, cert)
+    }
+
+    if len(certs) > 0 {
+        return certs, nil
+    }
+
+    certs, err := x509.ParseCertificates(data)
+    if err != nil {
+        return nil, errors.New("no certificate found")
+    }
+
+    return certs, nil
+}
+
+// 解析单个证书
+func parseCert(data []byte) (*x509.Certificate, error) {
+    certs, err := parseCerts(data)
+    if err != nil {
+        return nil, err
+    }
+
+    return certs[0], nil
+}
+
+// 转换为标准库证书
+func toStdCerts(certs []*x509.Certificate) ([]*std_x509.Certificate, error) {
+    stdCerts := make([]*std_x509.Certificate, 0, len(certs))
+    for _, cert := range certs {
+        stdCert, err := std_x509.ParseCertificate(cert.Raw)
+        if err != nil {
+            return nil, err
+        }
+
+        stdCerts = append(stdCerts, stdCert)
+    }
+
+    return stdCerts, nil
+}
+
+// 证书 PEM 编码
+func encodeCertPEM(der []byte) []byte {
+    return pem.EncodeToMemory(&pem.Block{
+        Type:  "CERTIFICATE",
+        Bytes: der,
+    })
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+    "strings"
+    "crypto/rand"
+    "encoding/pem"
+
+    "github.com/deatil/go-cryptobin/pkcs12"
+)
+
+func runPKCS12(c *cli, args []string) error {
+    return c.runCommands("cryptobin pkcs12", []command{
+        {"create", "create a PKCS#12 file from a private key and certificates", runPKCS12Create},
+        {"export", "export the contents of a PKCS#12 file as PEM", runPKCS12Export},
+        {"list", "list the entries of a PKCS#12 file", func(c *cli, args []string) error {
+            return runStoreList(c, "pkcs12 list", "pkcs12", args)
+        }},
+        {"convert", "convert a PKCS#12 file to another keystore format", func(c *cli, args []string) error {
+            return runStoreConvert(c, "pkcs12 convert", "pkcs12", args)
+        }},
+    }, args)
+}
+
+func runPKCS12Create(c *cli, args []string) error {
+    fs := c.flags("pkcs12 create")
+
+    keyFile := fs.String("key", "", "private key file")
+    keyPassword := fs.String("key-password", "", "private key password")
+    certFile := fs.String("cert", "", "certificate file")
+    caFile := fs.String("ca", "", "CA certificates file")
+    password := fs.String("password", "", "PKCS#12 password")
+    opts := fs.String("opts", "default", "encryption options: " + strings.Join(sortedKeys(pkcs12Opts), " | "))
+    out := fs.String("out", "", "PKCS#12 file (default stdout)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    p12Opts, err := getPKCS12Opts(*opts)
+    if err != nil {
+        return err
+    }
+
+    p12 := pkcs12.New()
+
+    if *keyFile != "" {
+        keyData, err := c.readInput(*keyFile)
+        if err != nil {
+            return err
+        }
+
+        key, err := parsePrivateKey(keyData, *keyPassword)
+        if err != nil {
+            return err
+        }
+
+        if err := p12.AddPrivateKey(key); err != nil {
+            return err
+        }
+    }
+
+    if *certFile != "" {
+        certData, err := c.readInput(*certFile)
+        if err != nil {
+            return err
+        }
+
+        certs, err := parseCerts(certData)
+        if err != nil {
+            return err
+        }
+
+        p12.AddCertBytes(certs[0].Raw)
+        for _, cert := range certs[1:] {
+            p12.AddCaCertBytes(cert.Raw)
+        }
+    }
+
+    if *caFile != "" {
+        caData, err := c.readInput(*caFile)
+        if err != nil {
+            return err
+        }
+
+        caCerts, err := parseCerts(caData)
+        if err != nil {
+            return err
+        }
+
+        for _, cert := range caCerts {
+            p12.AddCaCertBytes(cert.Raw)
+        }
+    }
+
+    pfxData, err := p12.Marshal(rand.Reader, *password, p12Opts)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, pfxData)
+}
+
+func runPKCS12Export(c *cli, args []string) error {
+    fs := c.flags("pkcs12 export")
+
+    in := fs.String("in", "", "PKCS#12 file (default stdin)")
+    password := fs.String("password", "", "PKCS#12 password")
+    out := fs.String("out", "", "PEM file (default stdout)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    pfxData, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    blocks, err := pkcs12.ToPEM(pfxData, *password)
+    if err != nil {
+        return err
+    }
+
+    var data []byte
+    for _, block := range blocks {
+        data = append(data, pem.EncodeToMemory(block)...)
+    }
+
+    return c.writeOutput(*out, data)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+    "fmt"
+    "errors"
+    "crypto"
+    "crypto/dsa"
+    "crypto/rsa"
+    "crypto/ecdsa"
+    "crypto/rand"
+    "encoding/pem"
+
+    "github.com/deatil/go-cryptobin/x509"
+    "github.com/deatil/go-cryptobin/pkcs7"
+    "github.com/deatil/go-cryptobin/gm/sm2"
+)
+
+func runPKCS7(c *cli, args []string) error {
+    return c.runCommands("cryptobin pkcs7", []command{
+        {"sign", "create a PKCS#7 signed data", runPKCS7Sign},
+        {"verify", "verify a PKCS#7 signed data", runPKCS7Verify},
+        {"encrypt", "create a PKCS#7 enveloped data", runPKCS7Encrypt},
+        {"decrypt", "decrypt a PKCS#7 enveloped data", runPKCS7Decrypt},
+    }, args)
+}
+
+func runPKCS7Sign(c *cli, args []string) error {
+    fs := c.flags("pkcs7 sign")
+
+    certFile := fs.String("cert", "", "signer certificate file, extra certificates are added to the chain")
+    keyFile := fs.String("key", "", "signer private key file")
+    password := fs.String("password", "", "signer private key password")
+    detach := fs.Bool("detach", false, "do not include the content")
+    in := fs.String("in", "", "content file (default stdin)")
+    out := fs.String("out", "", "signed data file (default stdout)")
+    pemOut := fs.Bool("pem", false, "PEM encode the output")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    certData, err := c.readRequired("cert", *certFile)
+    if err != nil {
+        return err
+    }
+
+    certs, err := parseCerts(certData)
+    if err != nil {
+        return err
+    }
+
+    keyData, err := c.readRequired("key", *keyFile)
+    if err != nil {
+        return err
+    }
+
+    key, err := parsePrivateKey(keyData, *password)
+    if err != nil {
+        return err
+    }
+
+    content, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    sd, err := newSignedData(content, key)
+    if err != nil {
+        return err
+    }
+
+    if err := sd.AddSignerChain(certs[0], key, certs[1:], pkcs7.SignerInfoConfig{}); err != nil {
+        return err
+    }
+
+    if *detach {
+        sd.Detach()
+    }
+
+    data, err := sd.Finish()
+    if err != nil {
+        return err
+    }
+
+    if *pemOut {
+        data = pkcs7.EncodePkcs7ToPem(data, "PKCS7")
+    }
+
+    return c.writeOutput(*out, data)
+}
+
+// 根据私钥类型选择签名算法
+func newSignedData(content []byte, key crypto.PrivateKey) (*pkcs7.SignedData, error) {
+    if _, ok := key.(*sm2.PrivateKey); ok {
+        return pkcs7.NewSMSignedData(content)
+    }
+
+    sd, err := pkcs7.NewSignedData(content)
+    if err != nil {
+        return nil, err
+    }
+
+    sd.SetDigestAlgorithm(pkcs7.OidDigestAlgorithmSHA256)
+
+    switch key.(type) {
+        case *rsa.PrivateKey:
+            sd.SetEncryptionAlgorithm(pkcs7.OidEncryptionAlgorithmRSASHA256)
+        case *ecdsa.PrivateKey:
+            sd.SetEncryptionAlgorithm(pkcs7.OidEncryptionAlgorithmECDSASHA256)
+        case *dsa.PrivateKey:
+            sd.SetEncryptionAlgorithm(pkcs7.OidEncryptionAlgorithmDSASHA256)
+        default:
+            return nil, fmt.Errorf("unsupported PKCS#7 signer key %T", key)
+    }
+
+    return sd, nil
+}
+
+func runPKCS7Verify(c *cli, args []string) error {
+    fs := c.flags("pkcs7 verify")
+
+    in := fs.String("in", "", "signed data file (default stdin)")
+    caFile := fs.String("ca", "", "trusted certificates file, verifies the signer chain when set")
+    contentFile := fs.String("content", "", "content file for detached signatures")
+    out := fs.String("out", "", "write the signed content to file, - for stdout")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    data, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    p7, err := pkcs7.Parse(pemOrDER(data))
+    if err != nil {
+        return err
+    }
+
+    if *contentFile != "" {
+        if p7.Content, err = c.readInput(*contentFile); err != nil {
+            return err
+        }
+    }
+
+    if *caFile != "" {
+        caData, err := c.readInput(*caFile)
+        if err != nil {
+            return err
+        }
+
+        caCerts, err := parseCerts(caData)
+        if err != nil {
+            return err
+        }
+
+        pool := x509.NewCertPool()
+        for _, cert := range caCerts {
+            pool.AddCert(cert)
+        }
+
+        err = p7.VerifyWithChain(pool)
+    } else {
+        err = p7.Verify()
+    }
+
+    if err != nil {
+        return fmt.Errorf("verification failed: %w", err)
+    }
+
+    if *out != "" {
+        return c.writeOutput(*out, p7.Content)
+    }
+
+    fmt.Fprintln(c.stderr, "Verified OK")
+
+    return nil
+}
+
+func runPKCS7Encrypt(c *cli, args []string) error {
+    fs := c.flags("pkcs7 encrypt")
+
+    var recipients listFlag
+    fs.Var(&recipients, "recipient", "recipient certificates file, may be repeated")
+    cipherName := fs.String("cipher", "", "content cipher, e.g. AES256CBC, AES256GCM, SM4CBC (default by recipient key)")
+    in := fs.String("in", "", "content file (default stdin)")
+    out := fs.String("out", "", "enveloped data file (default stdout)")
+    pemOut := fs.Bool("pem", false, "PEM encode the output")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    if len(recipients) == 0 {
+        return errors.New("missing -recipient")
+    }
+
+    var certs []*x509.Certificate
+    for _, file := range recipients {
+        certData, err := c.readInput(file)
+        if err != nil {
+            return err
+        }
+
+        list, err := parseCerts(certData)
+        if err != nil {
+            return err
+        }
+
+        certs = append(certs, list...)
+    }
+
+    opts := pkcs7.DefaultOpts
+    if _, ok := certs[0].PublicKey.(*sm2.PublicKey); ok {
+        opts = pkcs7.SM2Opts
+    }
+
+    if *cipherName != "" {
+        if !pkcs7.CheckCipherFromName(*cipherName) {
+            return fmt.Errorf("unknown cipher %q", *cipherName)
+        }
+
+        opts.Cipher = pkcs7.GetCipherFromName(*cipherName)
+    }
+
+    content, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    data, err := pkcs7.Encrypt(rand.Reader, content, certs, opts)
+    if err != nil {
+        return err
+    }
+
+    if *pemOut {
+        data = pkcs7.EncodePkcs7ToPem(data, "PKCS7")
+    }
+
+    return c.writeOutput(*out, data)
+}
+
+func runPKCS7Decrypt(c *cli, args []string) error {
+    fs := c.flags("pkcs7 decrypt")
+
+    certFile := fs.String("cert", "", "recipient certificate file")
+    keyFile := fs.String("key", "", "recipient private key file")
+    password := fs.String("password", "", "recipient private key password")
+    in := fs.String("in", "", "enveloped data file (default stdin)")
+    out := fs.String("out", "", "content file (default stdout)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    certData, err := c.readRequired("cert", *certFile)
+    if err != nil {
+        return err
+    }
+
+    cert, err := parseCert(certData)
+    if err != nil {
+        return err
+    }
+
+    keyData, err := c.readRequired("key", *keyFile)
+    if err != nil {
+        return err
+    }
+
+    key, err := parsePrivateKey(keyData, *password)
+    if err != nil {
+        return err
+    }
+
+    data, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    content, err := pkcs7.Decrypt(pemOrDER(data), cert, key)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, content)
+}
+
+// PEM 数据取出 DER, 否则原样返回
+func pemOrDER(data []byte) []byte {
+    if block, _ := pem.Decode(data); block != nil {
+        return block.Bytes
+    }
+
+    return data
+}
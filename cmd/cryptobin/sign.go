@@ -0,0 +1,114 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+)
+
+func runSign(c *cli, args []string) error {
+    fs := c.flags("sign")
+
+    typ := fs.String("type", "", "key type: " + strings.Join(keyTypeNames(canSign), " | "))
+    keyFile := fs.String("key", "", "private key file")
+    password := fs.String("password", "", "private key password")
+    hash := fs.String("hash", "", "signature digest, e.g. SHA256 or SM3")
+    in := fs.String("in", "", "data file (default stdin)")
+    out := fs.String("out", "", "signature file (default stdout)")
+    encoding := fs.String("encoding", "raw", "signature encoding: raw | hex | base64")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    kt, err := getSignKeyType(*typ)
+    if err != nil {
+        return err
+    }
+
+    key, err := c.readRequired("key", *keyFile)
+    if err != nil {
+        return err
+    }
+
+    data, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    sig, err := kt.sign(key, *password, *hash, data)
+    if err != nil {
+        return err
+    }
+
+    sig, err = encodeData(sig, *encoding)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, sig)
+}
+
+func runVerify(c *cli, args []string) error {
+    fs := c.flags("verify")
+
+    typ := fs.String("type", "", "key type: " + strings.Join(keyTypeNames(canSign), " | "))
+    keyFile := fs.String("key", "", "public key file")
+    sigFile := fs.String("sig", "", "signature file")
+    hash := fs.String("hash", "", "signature digest, e.g. SHA256 or SM3")
+    in := fs.String("in", "", "data file (default stdin)")
+    encoding := fs.String("encoding", "raw", "signature encoding: raw | hex | base64")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    kt, err := getSignKeyType(*typ)
+    if err != nil {
+        return err
+    }
+
+    key, err := c.readRequired("key", *keyFile)
+    if err != nil {
+        return err
+    }
+
+    sig, err := c.readRequired("sig", *sigFile)
+    if err != nil {
+        return err
+    }
+
+    sig, err = decodeData(sig, *encoding)
+    if err != nil {
+        return err
+    }
+
+    data, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    if err := kt.verify(key, *hash, data, sig); err != nil {
+        return err
+    }
+
+    fmt.Fprintln(c.stdout, "Verified OK")
+
+    return nil
+}
+
+func canSign(typ keyType) bool {
+    return typ.sign != nil
+}
+
+func getSignKeyType(name string) (keyType, error) {
+    kt, err := getKeyType(name)
+    if err != nil {
+        return kt, err
+    }
+
+    if !canSign(kt) {
+        return kt, fmt.Errorf("key type %q can not sign, available: %s", name, strings.Join(keyTypeNames(canSign), ", "))
+    }
+
+    return kt, nil
+}
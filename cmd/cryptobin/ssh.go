@@ -0,0 +1,88 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/deatil/go-cryptobin/cryptobin/ssh"
+)
+
+// OpenSSH 密钥类型
+var sshKeyTypes = map[string]string{
+    "rsa":   "RSA",
+    "dsa":   "DSA",
+    "ecdsa": "ECDSA",
+    "eddsa": "EdDSA",
+    "sm2":   "SM2",
+}
+
+func runSSH(c *cli, args []string) error {
+    return c.runCommands("cryptobin ssh", []command{
+        {"keygen", "generate an OpenSSH key pair", runSSHKeygen},
+    }, args)
+}
+
+func runSSHKeygen(c *cli, args []string) error {
+    fs := c.flags("ssh keygen")
+
+    typ := fs.String("type", "rsa", "key type: " + strings.Join(sortedKeys(sshKeyTypes), " | "))
+    bits := fs.Int("bits", 2048, "RSA key size")
+    curve := fs.String("curve", "P256", "ECDSA curve: P256 | P384 | P521")
+    sizes := fs.String("sizes", "L1024N160", "DSA parameter sizes: L1024N160 | L2048N224 | L2048N256 | L3072N256")
+    comment := fs.String("comment", "", "key comment")
+    password := fs.String("password", "", "encrypt the private key with a password")
+    cipher := fs.String("cipher", "", "private key cipher, e.g. aes256-ctr (default aes256-ctr)")
+    out := fs.String("out", "", "private key file (default stdout)")
+    pubout := fs.String("pubout", "", "public key file (default stdout)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    keyType, ok := sshKeyTypes[strings.ToLower(*typ)]
+    if !ok {
+        return fmt.Errorf("unknown ssh key type %q, available: %s", *typ, strings.Join(sortedKeys(sshKeyTypes), ", "))
+    }
+
+    switch *curve {
+        case "P256", "P384", "P521":
+        default:
+            return fmt.Errorf("unknown curve %q", *curve)
+    }
+
+    switch *sizes {
+        case "L1024N160", "L2048N224", "L2048N256", "L3072N256":
+        default:
+            return fmt.Errorf("unknown DSA parameter sizes %q", *sizes)
+    }
+
+    key := ssh.New().
+        SetPublicKeyType(keyType).
+        SetCurve(*curve).
+        SetParameterSizes(*sizes).
+        WithBits(*bits).
+        WithComment(*comment).
+        WithCipherName(*cipher).
+        GenerateKey()
+    if err := key.Error(); err != nil {
+        return err
+    }
+
+    var priv ssh.SSH
+    if *password != "" {
+        priv = key.CreateOpenSSHPrivateKeyWithPassword([]byte(*password))
+    } else {
+        priv = key.CreateOpenSSHPrivateKey()
+    }
+
+    if err := priv.Error(); err != nil {
+        return err
+    }
+
+    pub := key.CreateOpenSSHPublicKey()
+    if err := pub.Error(); err != nil {
+        return err
+    }
+
+    return c.writeKeyPair(*out, *pubout, priv.ToKeyBytes(), pub.ToKeyBytes())
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+    "fmt"
+    "net"
+    "time"
+    "errors"
+    "crypto"
+    "strings"
+    "math/big"
+    "crypto/dsa"
+    "crypto/rsa"
+    "crypto/rand"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/pem"
+    "crypto/x509/pkix"
+
+    "github.com/deatil/go-cryptobin/x509"
+    "github.com/deatil/go-cryptobin/gm/sm2"
+)
+
+func runX509(c *cli, args []string) error {
+    return c.runCommands("cryptobin x509", []command{
+        {"req", "create a certificate request", runX509Req},
+        {"sign", "sign a certificate request", runX509Sign},
+        {"show", "show a certificate or certificate request", runX509Show},
+    }, args)
+}
+
+func runX509Req(c *cli, args []string) error {
+    fs := c.flags("x509 req")
+
+    keyFile := fs.String("key", "", "private key file")
+    password := fs.String("password", "", "private key password")
+    cn := fs.String("cn", "", "subject common name")
+    var orgs, dns, ips listFlag
+    fs.Var(&orgs, "org", "subject organization, may be repeated")
+    fs.Var(&dns, "dns", "DNS name, may be repeated")
+    fs.Var(&ips, "ip", "IP address, may be repeated")
+    alg := fs.String("alg", "", "signature algorithm, e.g. SHA256WithRSA, SM2WithSM3 (default by key type)")
+    out := fs.String("out", "", "certificate request file (default stdout)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    keyData, err := c.readRequired("key", *keyFile)
+    if err != nil {
+        return err
+    }
+
+    key, err := parsePrivateKey(keyData, *password)
+    if err != nil {
+        return err
+    }
+
+    sigAlg, err := signatureAlgorithm(*alg, key)
+    if err != nil {
+        return err
+    }
+
+    ipAddrs, err := parseIPs(ips)
+    if err != nil {
+        return err
+    }
+
+    template := &x509.CertificateRequest{
+        Subject: pkix.Name{
+            CommonName:   *cn,
+            Organization: orgs,
+        },
+        DNSNames:           dns,
+        IPAddresses:        ipAddrs,
+        SignatureAlgorithm: sigAlg,
+    }
+
+    csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, pem.EncodeToMemory(&pem.Block{
+        Type:  "CERTIFICATE REQUEST",
+        Bytes: csr,
+    }))
+}
+
+func runX509Sign(c *cli, args []string) error {
+    fs := c.flags("x509 sign")
+
+    csrFile := fs.String("csr", "", "certificate request file (default stdin)")
+    caFile := fs.String("ca", "", "issuer certificate file (self-signed when empty)")
+    caKeyFile := fs.String("cakey", "", "issuer private key file")
+    caPassword := fs.String("cakey-password", "", "issuer private key password")
+    days := fs.Int("days", 365, "validity in days")
+    alg := fs.String("alg", "", "signature algorithm, e.g. SHA256WithRSA, SM2WithSM3 (default by issuer key type)")
+    isCA := fs.Bool("is-ca", false, "issue a CA certificate")
+    var dns, ips listFlag
+    fs.Var(&dns, "dns", "additional DNS name, may be repeated")
+    fs.Var(&ips, "ip", "additional IP address, may be repeated")
+    out := fs.String("out", "", "certificate file (default stdout)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    csrData, err := c.readInput(*csrFile)
+    if err != nil {
+        return err
+    }
+
+    csr, err := parseCSR(csrData)
+    if err != nil {
+        return err
+    }
+
+    if err := csr.CheckSignature(); err != nil {
+        return fmt.Errorf("certificate request signature: %w", err)
+    }
+
+    caKeyData, err := c.readRequired("cakey", *caKeyFile)
+    if err != nil {
+        return err
+    }
+
+    caKey, err := parsePrivateKey(caKeyData, *caPassword)
+    if err != nil {
+        return err
+    }
+
+    sigAlg, err := signatureAlgorithm(*alg, caKey)
+    if err != nil {
+        return err
+    }
+
+    ipAddrs, err := parseIPs(ips)
+    if err != nil {
+        return err
+    }
+
+    serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+    if err != nil {
+        return err
+    }
+
+    now := time.Now()
+
+    template := &x509.Certificate{
+        SerialNumber: serial,
+        Subject:      csr.Subject,
+
+        DNSNames:       append(csr.DNSNames, dns...),
+        EmailAddresses: csr.EmailAddresses,
+        IPAddresses:    append(csr.IPAddresses, ipAddrs...),
+        URIs:           csr.URIs,
+
+        NotBefore: now,
+        NotAfter:  now.AddDate(0, 0, *days),
+
+        KeyUsage: x509.KeyUsageDigitalSignature,
+        ExtKeyUsage: []x509.ExtKeyUsage{
+            x509.ExtKeyUsageClientAuth,
+            x509.ExtKeyUsageServerAuth,
+        },
+
+        BasicConstraintsValid: true,
+        IsCA:                  *isCA,
+
+        SignatureAlgorithm: sigAlg,
+    }
+
+    if *isCA {
+        template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+    }
+
+    parent := template
+    if *caFile != "" {
+        caData, err := c.readInput(*caFile)
+        if err != nil {
+            return err
+        }
+
+        if parent, err = parseCert(caData); err != nil {
+            return err
+        }
+    }
+
+    der, err := x509.CreateCertificate(rand.Reader, template, parent, csr.PublicKey, caKey)
+    if err != nil {
+        return err
+    }
+
+    return c.writeOutput(*out, encodeCertPEM(der))
+}
+
+func runX509Show(c *cli, args []string) error {
+    fs := c.flags("x509 show")
+
+    in := fs.String("in", "", "certificate or certificate request file (default stdin)")
+
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    data, err := c.readInput(*in)
+    if err != nil {
+        return err
+    }
+
+    if certs, err := parseCerts(data); err == nil {
+        for i, cert := range certs {
+            if i > 0 {
+                fmt.Fprintln(c.stdout)
+            }
+
+            showCert(c, cert)
+        }
+
+        return nil
+    }
+
+    csr, err := parseCSR(data)
+    if err != nil {
+        return errors.New("no certificate or certificate request found")
+    }
+
+    fmt.Fprintf(c.stdout, "Subject: %s\n", csr.Subject)
+    fmt.Fprintf(c.stdout, "Signature Algorithm: %s\n", csr.SignatureAlgorithm)
+    fmt.Fprintf(c.stdout, "Public Key: %T\n", csr.PublicKey)
+    showNames(c, csr.DNSNames, csr.IPAddresses)
+
+    if err := csr.CheckSignature(); err != nil {
+        fmt.Fprintf(c.stdout, "Signature: invalid (%v)\n", err)
+    } else {
+        fmt.Fprintln(c.stdout, "Signature: OK")
+    }
+
+    return nil
+}
+
+func showCert(c *cli, cert *x509.Certificate) {
+    fingerprint := sha256.Sum256(cert.Raw)
+
+    fmt.Fprintf(c.stdout, "Subject: %s\n", cert.Subject)
+    fmt.Fprintf(c.stdout, "Issuer: %s\n", cert.Issuer)
+    fmt.Fprintf(c.stdout, "Serial: %s\n", cert.SerialNumber.Text(16))
+    fmt.Fprintf(c.stdout, "Not Before: %s\n", cert.NotBefore.UTC().Format(time.RFC3339))
+    fmt.Fprintf(c.stdout, "Not After: %s\n", cert.NotAfter.UTC().Format(time.RFC3339))
+    fmt.Fprintf(c.stdout, "Signature Algorithm: %s\n", cert.SignatureAlgorithm)
+    fmt.Fprintf(c.stdout, "Public Key: %T\n", cert.PublicKey)
+    showNames(c, cert.DNSNames, cert.IPAddresses)
+    fmt.Fprintf(c.stdout, "CA: %t\n", cert.IsCA)
+    fmt.Fprintf(c.stdout, "SHA256 Fingerprint: %s\n", strings.ToUpper(hex.EncodeToString(fingerprint[:])))
+}
+
+func showNames(c *cli, dns []string, ips []net.IP) {
+    if len(dns) > 0 {
+        fmt.Fprintf(c.stdout, "DNS: %s\n", strings.Join(dns, ", "))
+    }
+
+    if len(ips) > 0 {
+        list := make([]string, 0, len(ips))
+        for _, ip := range ips {
+            list = append(list, ip.String())
+        }
+
+        fmt.Fprintf(c.stdout, "IP: %s\n", strings.Join(list, ", "))
+    }
+}
+
+// 解析证书请求, 支持 PEM 和 DER
+func parseCSR(data []byte) (*x509.CertificateRequest, error) {
+    rest := data
+    for {
+        var block *pem.Block
+        block, rest = pem.Decode(rest)
+        if block == nil {
+            break
+        }
+
+        if block.Type == "CERTIFICATE REQUEST" || block.Type == "NEW CERTIFICATE REQUEST" {
+            return x509.ParseCertificateRequest(block.Bytes)
+        }
+    }
+
+    return x509.ParseCertificateRequest(data)
+}
+
+func parseIPs(list []string) ([]net.IP, error) {
+    var ips []net.IP
+    for _, s := range list {
+        ip := net.ParseIP(s)
+        if ip == nil {
+            return nil, fmt.Errorf("invalid IP address %q", s)
+        }
+
+        ips = append(ips, ip)
+    }
+
+    return ips, nil
+}
+
+// 签名算法, 未指定时根据私钥类型选择
+func signatureAlgorithm(name string, key crypto.PrivateKey) (x509.SignatureAlgorithm, error) {
+    if name != "" {
+        return findSignatureAlgorithm(name)
+    }
+
+    switch key.(type) {
+        case *rsa.PrivateKey:
+            return x509.SHA256WithRSA, nil
+        case *dsa.PrivateKey:
+            return x509.DSAWithSHA256, nil
+        case *ecdsa.PrivateKey:
+            return x509.ECDSAWithSHA256, nil
+        case ed25519.PrivateKey:
+            return x509.PureEd25519, nil
+        case *sm2.PrivateKey:
+            return x509.SM2WithSM3, nil
+    }
+
+    return 0, fmt.Errorf("unsupported key %T, set -alg", key)
+}
+
+// 签名算法名称, 支持 SHA256WithRSA 和 SHA256-RSA 两种写法
+func findSignatureAlgorithm(name string) (x509.SignatureAlgorithm, error) {
+    normalize := func(s string) string {
+        s = strings.ToLower(s)
+        s = strings.TrimPrefix(s, "pure")
+        s = strings.ReplaceAll(s, "with", "")
+        return strings.ReplaceAll(s, "-", "")
+    }
+
+    var names []string
+    for alg := x509.SignatureAlgorithm(1); ; alg++ {
+        algName := alg.String()
+        if algName == fmt.Sprint(int(alg)) {
+            break
+        }
+
+        if normalize(algName) == normalize(name) {
+            return alg, nil
+        }
+
+        names = append(names, algName)
+    }
+
+    return 0, fmt.Errorf("unknown signature algorithm %q, available: %s", name, strings.Join(names, ", "))
+}
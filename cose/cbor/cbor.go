@@ -0,0 +1,345 @@
+// Package cbor implements a small deterministic CBOR (RFC 8949) encoder
+// and decoder, as needed by COSE.
+//
+// Values are encoded with the core deterministic encoding requirements of
+// RFC 8949 section 4.2.1: integers and lengths use the shortest form,
+// indefinite lengths are never used and map keys are sorted by their
+// encoded bytes.
+//
+// Decoded values use the following Go types:
+//
+//   unsigned and negative integers  int64, or uint64 if it does not fit
+//   byte strings                    []byte
+//   text strings                    string
+//   arrays                          []any
+//   maps                            map[any]any
+//   tags                            Tag
+//   false, true                     bool
+//   null                            nil
+//   undefined                       Undefined
+//   floats                          float64
+package cbor
+
+import (
+    "math"
+    "sort"
+    "bytes"
+    "errors"
+    "reflect"
+    "encoding/binary"
+)
+
+// 主类型
+const (
+    majorUnsigned byte = 0
+    majorNegative byte = 1
+    majorBytes    byte = 2
+    majorText     byte = 3
+    majorArray    byte = 4
+    majorMap      byte = 5
+    majorTag      byte = 6
+    majorSimple   byte = 7
+)
+
+// 简单值
+const (
+    simpleFalse     byte = 20
+    simpleTrue      byte = 21
+    simpleNull      byte = 22
+    simpleUndefined byte = 23
+)
+
+// Tag represents a tagged data item.
+type Tag struct {
+    Number  uint64
+    Content any
+}
+
+// Undefined represents the CBOR undefined simple value.
+type Undefined struct{}
+
+// RawMessage is a pre-encoded CBOR data item, it is written as is.
+type RawMessage []byte
+
+// Marshal returns the deterministic CBOR encoding of v.
+func Marshal(v any) ([]byte, error) {
+    e := &encoder{}
+    if err := e.encode(v); err != nil {
+        return nil, err
+    }
+
+    return e.buf.Bytes(), nil
+}
+
+type encoder struct {
+    buf bytes.Buffer
+}
+
+// writeHead 写入主类型和参数, 使用最短编码
+func (e *encoder) writeHead(major byte, n uint64) {
+    m := major << 5
+
+    switch {
+        case n < 24:
+            e.buf.WriteByte(m | byte(n))
+        case n <= math.MaxUint8:
+            e.buf.Write([]byte{m | 24, byte(n)})
+        case n <= math.MaxUint16:
+            e.buf.WriteByte(m | 25)
+            e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+        case n <= math.MaxUint32:
+            e.buf.WriteByte(m | 26)
+            e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+        default:
+            e.buf.WriteByte(m | 27)
+            e.buf.Write(binary.BigEndian.AppendUint64(nil, n))
+    }
+}
+
+func (e *encoder) writeInt(n int64) {
+    if n >= 0 {
+        e.writeHead(majorUnsigned, uint64(n))
+    } else {
+        e.writeHead(majorNegative, uint64(-(n + 1)))
+    }
+}
+
+func (e *encoder) encode(v any) error {
+    switch v := v.(type) {
+        case nil:
+            e.buf.WriteByte(majorSimple<<5 | simpleNull)
+        case Undefined:
+            e.buf.WriteByte(majorSimple<<5 | simpleUndefined)
+        case bool:
+            if v {
+                e.buf.WriteByte(majorSimple<<5 | simpleTrue)
+            } else {
+                e.buf.WriteByte(majorSimple<<5 | simpleFalse)
+            }
+        case RawMessage:
+            if _, err := Unmarshal(v); err != nil {
+                return err
+            }
+            e.buf.Write(v)
+        case []byte:
+            e.writeHead(majorBytes, uint64(len(v)))
+            e.buf.Write(v)
+        case string:
+            e.writeHead(majorText, uint64(len(v)))
+            e.buf.WriteString(v)
+        case int:
+            e.writeInt(int64(v))
+        case int8:
+            e.writeInt(int64(v))
+        case int16:
+            e.writeInt(int64(v))
+        case int32:
+            e.writeInt(int64(v))
+        case int64:
+            e.writeInt(v)
+        case uint:
+            e.writeHead(majorUnsigned, uint64(v))
+        case uint8:
+            e.writeHead(majorUnsigned, uint64(v))
+        case uint16:
+            e.writeHead(majorUnsigned, uint64(v))
+        case uint32:
+            e.writeHead(majorUnsigned, uint64(v))
+        case uint64:
+            e.writeHead(majorUnsigned, v)
+        case float32:
+            e.writeFloat(float64(v))
+        case float64:
+            e.writeFloat(v)
+        case Tag:
+            e.writeHead(majorTag, v.Number)
+            return e.encode(v.Content)
+        case []any:
+            e.writeHead(majorArray, uint64(len(v)))
+            for _, item := range v {
+                if err := e.encode(item); err != nil {
+                    return err
+                }
+            }
+        case map[any]any:
+            return e.encodeMap(len(v), func(f func(k, v any) error) error {
+                for key, val := range v {
+                    if err := f(key, val); err != nil {
+                        return err
+                    }
+                }
+                return nil
+            })
+        default:
+            return e.encodeReflect(reflect.ValueOf(v))
+    }
+
+    return nil
+}
+
+// encodeReflect 编码其他类型的切片和 map
+func (e *encoder) encodeReflect(rv reflect.Value) error {
+    switch rv.Kind() {
+        case reflect.Slice, reflect.Array:
+            e.writeHead(majorArray, uint64(rv.Len()))
+            for i := 0; i < rv.Len(); i++ {
+                if err := e.encode(rv.Index(i).Interface()); err != nil {
+                    return err
+                }
+            }
+            return nil
+        case reflect.Map:
+            return e.encodeMap(rv.Len(), func(f func(k, v any) error) error {
+                iter := rv.MapRange()
+                for iter.Next() {
+                    if err := f(iter.Key().Interface(), iter.Value().Interface()); err != nil {
+                        return err
+                    }
+                }
+                return nil
+            })
+        case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+            e.writeInt(rv.Int())
+            return nil
+        case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+            e.writeHead(majorUnsigned, rv.Uint())
+            return nil
+        case reflect.String:
+            return e.encode(rv.String())
+    }
+
+    return errors.New("go-cryptobin/cbor: unsupported type " + rv.Type().String())
+}
+
+// encodeMap 编码 map, 键按照编码后的字节排序
+func (e *encoder) encodeMap(n int, each func(func(k, v any) error) error) error {
+    type entry struct {
+        key []byte
+        val []byte
+    }
+
+    entries := make([]entry, 0, n)
+    err := each(func(k, v any) error {
+        key, err := Marshal(k)
+        if err != nil {
+            return err
+        }
+
+        val, err := Marshal(v)
+        if err != nil {
+            return err
+        }
+
+        entries = append(entries, entry{key, val})
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    sort.Slice(entries, func(i, j int) bool {
+        return bytes.Compare(entries[i].key, entries[j].key) < 0
+    })
+
+    e.writeHead(majorMap, uint64(len(entries)))
+    for i, ent := range entries {
+        if i > 0 && bytes.Equal(entries[i-1].key, ent.key) {
+            return errors.New("go-cryptobin/cbor: duplicate map key")
+        }
+
+        e.buf.Write(ent.key)
+        e.buf.Write(ent.val)
+    }
+
+    return nil
+}
+
+// writeFloat 使用能无损表示的最短浮点格式
+func (e *encoder) writeFloat(f float64) {
+    if math.IsNaN(f) {
+        e.buf.Write([]byte{0xf9, 0x7e, 0x00})
+        return
+    }
+
+    if h, ok := float64ToHalf(f); ok {
+        e.buf.WriteByte(majorSimple<<5 | 25)
+        e.buf.Write(binary.BigEndian.AppendUint16(nil, h))
+        return
+    }
+
+    if f32 := float32(f); float64(f32) == f {
+        e.buf.WriteByte(majorSimple<<5 | 26)
+        e.buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(f32)))
+        return
+    }
+
+    e.buf.WriteByte(majorSimple<<5 | 27)
+    e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+// float64ToHalf 转换为半精度浮点数, 不能无损转换时返回 false
+func float64ToHalf(f float64) (uint16, bool) {
+    f32 := float32(f)
+    if float64(f32) != f {
+        return 0, false
+    }
+
+    bits := math.Float32bits(f32)
+    sign := uint16(bits>>16) & 0x8000
+    exp := int(bits>>23) & 0xff
+    mant := bits & 0x7fffff
+
+    switch {
+        case exp == 0xff:
+            // 无穷大
+            if mant != 0 {
+                return 0, false
+            }
+            return sign | 0x7c00, true
+        case exp == 0 && mant == 0:
+            return sign, true
+    }
+
+    e := exp - 127 + 15
+    switch {
+        case e >= 31:
+            return 0, false
+        case e >= 1:
+            if mant&0x1fff != 0 {
+                return 0, false
+            }
+            return sign | uint16(e)<<10 | uint16(mant>>13), true
+        case e >= -9:
+            // 非规格化数
+            m := mant | 0x800000
+            shift := uint(14 - e)
+            if m&(1<<shift-1) != 0 {
+                return 0, false
+            }
+            return sign | uint16(m>>shift), true
+    }
+
+    return 0, false
+}
+
+func halfToFloat64(h uint16) float64 {
+    sign := 1.0
+    if h&0x8000 != 0 {
+        sign = -1.0
+    }
+
+    exp := int(h>>10) & 0x1f
+    mant := float64(h & 0x3ff)
+
+    switch exp {
+        case 0:
+            return sign * math.Ldexp(mant, -24)
+        case 31:
+            if mant == 0 {
+                return math.Inf(int(sign))
+            }
+            return math.NaN()
+    }
+
+    return sign * math.Ldexp(mant+1024, exp-25)
+}
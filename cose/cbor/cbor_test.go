@@ -0,0 +1,227 @@
+package cbor
+
+import (
+    "math"
+    "bytes"
+    "testing"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// RFC 8949 Appendix A
+func Test_MarshalVectors(t *testing.T) {
+    cases := []struct {
+        v    any
+        want string
+    }{
+        {0, "00"},
+        {1, "01"},
+        {10, "0a"},
+        {23, "17"},
+        {24, "1818"},
+        {25, "1819"},
+        {100, "1864"},
+        {1000, "1903e8"},
+        {1000000, "1a000f4240"},
+        {uint64(1000000000000), "1b000000e8d4a51000"},
+        {uint64(18446744073709551615), "1bffffffffffffffff"},
+        {-1, "20"},
+        {-10, "29"},
+        {-100, "3863"},
+        {-1000, "3903e7"},
+        {0.0, "f90000"},
+        {math.Copysign(0, -1), "f98000"},
+        {1.0, "f93c00"},
+        {1.1, "fb3ff199999999999a"},
+        {1.5, "f93e00"},
+        {65504.0, "f97bff"},
+        {100000.0, "fa47c35000"},
+        {3.4028234663852886e+38, "fa7f7fffff"},
+        {1.0e+300, "fb7e37e43c8800759c"},
+        {5.960464477539063e-8, "f90001"},
+        {0.00006103515625, "f90400"},
+        {-4.0, "f9c400"},
+        {-4.1, "fbc010666666666666"},
+        {math.Inf(1), "f97c00"},
+        {math.NaN(), "f97e00"},
+        {math.Inf(-1), "f9fc00"},
+        {false, "f4"},
+        {true, "f5"},
+        {nil, "f6"},
+        {Undefined{}, "f7"},
+        {Tag{1, 1363896240}, "c11a514b67b0"},
+        {Tag{23, []byte{1, 2, 3, 4}}, "d74401020304"},
+        {[]byte{}, "40"},
+        {[]byte{1, 2, 3, 4}, "4401020304"},
+        {"", "60"},
+        {"a", "6161"},
+        {"IETF", "6449455446"},
+        {"\"\\", "62225c"},
+        {"ü", "62c3bc"},
+        {"水", "63e6b0b4"},
+        {[]any{}, "80"},
+        {[]any{1, 2, 3}, "83010203"},
+        {[]any{1, []any{2, 3}, []any{4, 5}}, "8301820203820405"},
+        {map[any]any{}, "a0"},
+        {map[any]any{1: 2, 3: 4}, "a201020304"},
+        {map[any]any{"a": 1, "b": []any{2, 3}}, "a26161016162820203"},
+        {[]any{"a", map[any]any{"b": "c"}}, "826161a161626163"},
+        {[]int{1, 2, 3}, "83010203"},
+    }
+
+    for _, c := range cases {
+        got, err := Marshal(c.v)
+        if err != nil {
+            t.Fatalf("Marshal(%v): %v", c.v, err)
+        }
+
+        if hex.EncodeToString(got) != c.want {
+            t.Errorf("Marshal(%v) = %x, want %s", c.v, got, c.want)
+        }
+    }
+}
+
+func Test_MapKeyOrder(t *testing.T) {
+    // 按编码后的字节排序
+    m := map[any]any{
+        "z":   1,
+        10:    2,
+        -1:    3,
+        100:   4,
+        "aa":  5,
+        false: 6,
+    }
+
+    got, err := Marshal(m)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := "a6" + "0a02" + "186404" + "2003" + "617a01" + "62616105" + "f406"
+    if hex.EncodeToString(got) != want {
+        t.Errorf("got %x, want %s", got, want)
+    }
+}
+
+func Test_Unmarshal(t *testing.T) {
+    cases := []struct {
+        data string
+        want any
+    }{
+        {"00", int64(0)},
+        {"1bffffffffffffffff", uint64(18446744073709551615)},
+        {"3903e7", int64(-1000)},
+        {"f93e00", 1.5},
+        {"fa47c35000", 100000.0},
+        {"f5", true},
+        {"f6", nil},
+        {"f7", Undefined{}},
+        {"6449455446", "IETF"},
+    }
+
+    for _, c := range cases {
+        got, err := Unmarshal(fromHex(c.data))
+        if err != nil {
+            t.Fatalf("Unmarshal(%s): %v", c.data, err)
+        }
+
+        if got != c.want {
+            t.Errorf("Unmarshal(%s) = %#v, want %#v", c.data, got, c.want)
+        }
+    }
+
+    v, err := Unmarshal(fromHex("a26161016162820203"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m := v.(map[any]any)
+    if m["a"] != int64(1) {
+        t.Errorf("got %v", m["a"])
+    }
+
+    arr := m["b"].([]any)
+    if len(arr) != 2 || arr[0] != int64(2) || arr[1] != int64(3) {
+        t.Errorf("got %v", arr)
+    }
+
+    v, err = Unmarshal(fromHex("d74401020304"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tag := v.(Tag)
+    if tag.Number != 23 || !bytes.Equal(tag.Content.([]byte), []byte{1, 2, 3, 4}) {
+        t.Errorf("got %v", tag)
+    }
+}
+
+func Test_RoundTrip(t *testing.T) {
+    data := fromHex("d28443a10127a10442313154546869732069732074686520636f6e74656e742e5840aca3f1af4808da97969ecdeb29a5cbf52ba4ec74123e5d1c8e1a8a67a77b27d1e417942a737c9dbbb771af28dc9a206f0fd07d8c85673ef017bf961fa9108000")
+
+    v, err := Unmarshal(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    got, err := Marshal(v)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(got, data) {
+        t.Errorf("got %x, want %x", got, data)
+    }
+}
+
+func Test_UnmarshalErrors(t *testing.T) {
+    cases := []struct {
+        data string
+        err  error
+    }{
+        {"", ErrUnexpectedEnd},
+        {"19", ErrUnexpectedEnd},
+        {"4401", ErrUnexpectedEnd},
+        {"0000", ErrTrailingData},
+        {"5f", ErrIndefinite},
+        {"9f01ff", ErrIndefinite},
+        {"1c", ErrInvalidEncoding},
+        {"62c328", ErrInvalidUTF8},
+        {"a201020103", ErrDuplicateKey},
+        {"a1800102", ErrInvalidMapKey},
+        {"3bffffffffffffffff", ErrIntOverflow},
+        {"98ff", ErrUnexpectedEnd},
+    }
+
+    for _, c := range cases {
+        _, err := Unmarshal(fromHex(c.data))
+        if err != c.err {
+            t.Errorf("Unmarshal(%s) error = %v, want %v", c.data, err, c.err)
+        }
+    }
+
+    deep := bytes.Repeat([]byte{0x81}, maxDepth+2)
+    deep = append(deep, 0x00)
+    if _, err := Unmarshal(deep); err != ErrMaxDepth {
+        t.Errorf("got %v, want %v", err, ErrMaxDepth)
+    }
+}
+
+func Test_RawMessage(t *testing.T) {
+    got, err := Marshal([]any{RawMessage{0xa1, 0x01, 0x02}, 3})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if hex.EncodeToString(got) != "82a1010203" {
+        t.Errorf("got %x", got)
+    }
+
+    if _, err = Marshal(RawMessage{0x19}); err == nil {
+        t.Error("invalid RawMessage should fail")
+    }
+}
@@ -0,0 +1,230 @@
+package cbor
+
+import (
+    "math"
+    "errors"
+    "unicode/utf8"
+    "encoding/binary"
+)
+
+// 最大嵌套深度
+const maxDepth = 64
+
+var (
+    ErrUnexpectedEnd   = errors.New("go-cryptobin/cbor: unexpected end of data")
+    ErrTrailingData    = errors.New("go-cryptobin/cbor: trailing data")
+    ErrIndefinite      = errors.New("go-cryptobin/cbor: indefinite length is not supported")
+    ErrMaxDepth        = errors.New("go-cryptobin/cbor: exceeded max nesting depth")
+    ErrInvalidUTF8     = errors.New("go-cryptobin/cbor: invalid UTF-8 text string")
+    ErrDuplicateKey    = errors.New("go-cryptobin/cbor: duplicate map key")
+    ErrInvalidMapKey   = errors.New("go-cryptobin/cbor: invalid map key type")
+    ErrIntOverflow     = errors.New("go-cryptobin/cbor: integer overflow")
+    ErrInvalidEncoding = errors.New("go-cryptobin/cbor: invalid encoding")
+)
+
+// Unmarshal decodes a single CBOR data item, data must not have
+// trailing bytes.
+func Unmarshal(data []byte) (any, error) {
+    v, rest, err := UnmarshalFirst(data)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(rest) > 0 {
+        return nil, ErrTrailingData
+    }
+
+    return v, nil
+}
+
+// UnmarshalFirst decodes the first CBOR data item and returns the
+// remaining bytes.
+func UnmarshalFirst(data []byte) (v any, rest []byte, err error) {
+    d := &decoder{data: data}
+
+    v, err = d.decode(0)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return v, d.data[d.off:], nil
+}
+
+type decoder struct {
+    data []byte
+    off  int
+}
+
+func (d *decoder) readByte() (byte, error) {
+    if d.off >= len(d.data) {
+        return 0, ErrUnexpectedEnd
+    }
+
+    b := d.data[d.off]
+    d.off++
+    return b, nil
+}
+
+func (d *decoder) readN(n uint64) ([]byte, error) {
+    if n > uint64(len(d.data)-d.off) {
+        return nil, ErrUnexpectedEnd
+    }
+
+    b := d.data[d.off : d.off+int(n)]
+    d.off += int(n)
+    return b, nil
+}
+
+// readHead 读取主类型和参数
+func (d *decoder) readHead() (major byte, info byte, n uint64, err error) {
+    b, err := d.readByte()
+    if err != nil {
+        return
+    }
+
+    major, info = b>>5, b&0x1f
+
+    switch {
+        case info < 24:
+            n = uint64(info)
+        case info == 24:
+            var p []byte
+            if p, err = d.readN(1); err == nil {
+                n = uint64(p[0])
+            }
+        case info == 25:
+            var p []byte
+            if p, err = d.readN(2); err == nil {
+                n = uint64(binary.BigEndian.Uint16(p))
+            }
+        case info == 26:
+            var p []byte
+            if p, err = d.readN(4); err == nil {
+                n = uint64(binary.BigEndian.Uint32(p))
+            }
+        case info == 27:
+            var p []byte
+            if p, err = d.readN(8); err == nil {
+                n = binary.BigEndian.Uint64(p)
+            }
+        case info == 31:
+            err = ErrIndefinite
+        default:
+            err = ErrInvalidEncoding
+    }
+
+    return
+}
+
+func (d *decoder) decode(depth int) (any, error) {
+    if depth > maxDepth {
+        return nil, ErrMaxDepth
+    }
+
+    major, info, n, err := d.readHead()
+    if err != nil {
+        return nil, err
+    }
+
+    switch major {
+        case majorUnsigned:
+            if n > math.MaxInt64 {
+                return n, nil
+            }
+            return int64(n), nil
+        case majorNegative:
+            if n > math.MaxInt64 {
+                return nil, ErrIntOverflow
+            }
+            return -1 - int64(n), nil
+        case majorBytes:
+            b, err := d.readN(n)
+            if err != nil {
+                return nil, err
+            }
+            return append([]byte{}, b...), nil
+        case majorText:
+            b, err := d.readN(n)
+            if err != nil {
+                return nil, err
+            }
+            if !utf8.Valid(b) {
+                return nil, ErrInvalidUTF8
+            }
+            return string(b), nil
+        case majorArray:
+            // 每个元素至少一个字节
+            if n > uint64(len(d.data)-d.off) {
+                return nil, ErrUnexpectedEnd
+            }
+
+            arr := make([]any, 0, n)
+            for i := uint64(0); i < n; i++ {
+                item, err := d.decode(depth + 1)
+                if err != nil {
+                    return nil, err
+                }
+                arr = append(arr, item)
+            }
+            return arr, nil
+        case majorMap:
+            if n > uint64(len(d.data)-d.off)/2 {
+                return nil, ErrUnexpectedEnd
+            }
+
+            m := make(map[any]any, n)
+            for i := uint64(0); i < n; i++ {
+                key, err := d.decode(depth + 1)
+                if err != nil {
+                    return nil, err
+                }
+
+                switch key.(type) {
+                    case int64, uint64, string, bool:
+                    default:
+                        return nil, ErrInvalidMapKey
+                }
+
+                if _, ok := m[key]; ok {
+                    return nil, ErrDuplicateKey
+                }
+
+                val, err := d.decode(depth + 1)
+                if err != nil {
+                    return nil, err
+                }
+
+                m[key] = val
+            }
+            return m, nil
+        case majorTag:
+            content, err := d.decode(depth + 1)
+            if err != nil {
+                return nil, err
+            }
+            return Tag{Number: n, Content: content}, nil
+        default:
+            return d.decodeSimple(info, n)
+    }
+}
+
+func (d *decoder) decodeSimple(info byte, n uint64) (any, error) {
+    switch info {
+        case simpleFalse:
+            return false, nil
+        case simpleTrue:
+            return true, nil
+        case simpleNull:
+            return nil, nil
+        case simpleUndefined:
+            return Undefined{}, nil
+        case 25:
+            return halfToFloat64(uint16(n)), nil
+        case 26:
+            return float64(math.Float32frombits(uint32(n))), nil
+        case 27:
+            return math.Float64frombits(n), nil
+    }
+
+    return nil, ErrInvalidEncoding
+}
@@ -0,0 +1,132 @@
+// Package cose implements CBOR Object Signing and Encryption (COSE) messages
+// as specified in RFC 9052 and RFC 9053.
+//
+// Supported structures are COSE_Sign1, COSE_Sign, COSE_Mac0, COSE_Mac,
+// COSE_Encrypt0, COSE_Encrypt and COSE_Key.
+package cose
+
+import (
+    "errors"
+    "strconv"
+)
+
+// CBOR 标签. See RFC 9052 section 2.
+const (
+    TagSign1    uint64 = 18
+    TagSign     uint64 = 98
+    TagMac0     uint64 = 17
+    TagMac      uint64 = 97
+    TagEncrypt0 uint64 = 16
+    TagEncrypt  uint64 = 96
+)
+
+// Algorithm represents a COSE algorithm identifier.
+type Algorithm int64
+
+// 签名算法
+const (
+    AlgorithmES256 Algorithm = -7
+    AlgorithmES384 Algorithm = -35
+    AlgorithmES512 Algorithm = -36
+    AlgorithmEdDSA Algorithm = -8
+    AlgorithmPS256 Algorithm = -37
+    AlgorithmPS384 Algorithm = -38
+    AlgorithmPS512 Algorithm = -39
+)
+
+// MAC 算法
+const (
+    AlgorithmHMAC256_64  Algorithm = 4
+    AlgorithmHMAC256_256 Algorithm = 5
+    AlgorithmHMAC384_384 Algorithm = 6
+    AlgorithmHMAC512_512 Algorithm = 7
+)
+
+// 内容加密算法
+const (
+    AlgorithmA128GCM Algorithm = 1
+    AlgorithmA192GCM Algorithm = 2
+    AlgorithmA256GCM Algorithm = 3
+
+    AlgorithmAESCCM_16_64_128  Algorithm = 10
+    AlgorithmAESCCM_16_64_256  Algorithm = 11
+    AlgorithmAESCCM_64_64_128  Algorithm = 12
+    AlgorithmAESCCM_64_64_256  Algorithm = 13
+    AlgorithmAESCCM_16_128_128 Algorithm = 30
+    AlgorithmAESCCM_16_128_256 Algorithm = 31
+    AlgorithmAESCCM_64_128_128 Algorithm = 32
+    AlgorithmAESCCM_64_128_256 Algorithm = 33
+)
+
+// 密钥分发算法
+const (
+    AlgorithmDirect Algorithm = -6
+
+    AlgorithmA128KW Algorithm = -3
+    AlgorithmA192KW Algorithm = -4
+    AlgorithmA256KW Algorithm = -5
+
+    AlgorithmECDH_ES_HKDF_256 Algorithm = -25
+    AlgorithmECDH_ES_HKDF_512 Algorithm = -26
+    AlgorithmECDH_ES_A128KW   Algorithm = -29
+    AlgorithmECDH_ES_A192KW   Algorithm = -30
+    AlgorithmECDH_ES_A256KW   Algorithm = -31
+)
+
+var algorithmNames = map[Algorithm]string{
+    AlgorithmES256: "ES256",
+    AlgorithmES384: "ES384",
+    AlgorithmES512: "ES512",
+    AlgorithmEdDSA: "EdDSA",
+    AlgorithmPS256: "PS256",
+    AlgorithmPS384: "PS384",
+    AlgorithmPS512: "PS512",
+
+    AlgorithmHMAC256_64:  "HMAC 256/64",
+    AlgorithmHMAC256_256: "HMAC 256/256",
+    AlgorithmHMAC384_384: "HMAC 384/384",
+    AlgorithmHMAC512_512: "HMAC 512/512",
+
+    AlgorithmA128GCM: "A128GCM",
+    AlgorithmA192GCM: "A192GCM",
+    AlgorithmA256GCM: "A256GCM",
+
+    AlgorithmAESCCM_16_64_128:  "AES-CCM-16-64-128",
+    AlgorithmAESCCM_16_64_256:  "AES-CCM-16-64-256",
+    AlgorithmAESCCM_64_64_128:  "AES-CCM-64-64-128",
+    AlgorithmAESCCM_64_64_256:  "AES-CCM-64-64-256",
+    AlgorithmAESCCM_16_128_128: "AES-CCM-16-128-128",
+    AlgorithmAESCCM_16_128_256: "AES-CCM-16-128-256",
+    AlgorithmAESCCM_64_128_128: "AES-CCM-64-128-128",
+    AlgorithmAESCCM_64_128_256: "AES-CCM-64-128-256",
+
+    AlgorithmDirect: "direct",
+    AlgorithmA128KW: "A128KW",
+    AlgorithmA192KW: "A192KW",
+    AlgorithmA256KW: "A256KW",
+
+    AlgorithmECDH_ES_HKDF_256: "ECDH-ES + HKDF-256",
+    AlgorithmECDH_ES_HKDF_512: "ECDH-ES + HKDF-512",
+    AlgorithmECDH_ES_A128KW:   "ECDH-ES + A128KW",
+    AlgorithmECDH_ES_A192KW:   "ECDH-ES + A192KW",
+    AlgorithmECDH_ES_A256KW:   "ECDH-ES + A256KW",
+}
+
+// String returns the name of the algorithm.
+func (alg Algorithm) String() string {
+    if name, ok := algorithmNames[alg]; ok {
+        return name
+    }
+
+    return "unknown algorithm value " + strconv.Itoa(int(alg))
+}
+
+var (
+    ErrAlgorithmNotSupported = errors.New("go-cryptobin/cose: algorithm not supported")
+    ErrAlgorithmMismatch     = errors.New("go-cryptobin/cose: algorithm mismatch")
+    ErrInvalidMessage        = errors.New("go-cryptobin/cose: invalid message")
+    ErrInvalidKey            = errors.New("go-cryptobin/cose: invalid key")
+    ErrVerification          = errors.New("go-cryptobin/cose: verification error")
+    ErrDecryption            = errors.New("go-cryptobin/cose: decryption error")
+    ErrNoRecipient           = errors.New("go-cryptobin/cose: no matching recipient")
+)
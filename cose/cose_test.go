@@ -0,0 +1,553 @@
+package cose
+
+import (
+    "bytes"
+    "testing"
+    "crypto"
+    "crypto/rsa"
+    "crypto/rand"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "crypto/elliptic"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/pubkey/x448"
+    "github.com/deatil/go-cryptobin/pubkey/ed448"
+    "github.com/deatil/go-cryptobin/pubkey/x25519"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// 以下测试数据由 go-cose v1.3.0 生成
+var (
+    testEd25519Seed = fromHex("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+
+    // kid 为 "11", 附加数据为 "external"
+    testSign1 = fromHex("d28443a10127a10442313154546869732069732074686520636f6e74656e742e5840aca3f1af4808da97969ecdeb29a5cbf52ba4ec74123e5d1c8e1a8a67a77b27d1e417942a737c9dbbb771af28dc9a206f0fd07d8c85673ef017bf961fa9108000")
+
+    testSign = fromHex("d8628440a054546869732069732074686520636f6e74656e742e818343a10127a104423131584081d92439ecaf31f11f611054346d50b5fbd4e5cfe00c1c237cf673fa3948678b378eacd5eecf6f680980f818a8ecc57a8b4c733ec2fd8d03ae3ba04a02ea4a06")
+
+    testEd25519Key = fromHex("a5010103272006215820d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a2358209d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+)
+
+func Test_Sign1Vector(t *testing.T) {
+    priv := ed25519.NewKeyFromSeed(testEd25519Seed)
+
+    m, err := ParseSign1Message(testSign1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if string(m.Payload) != "This is the content." {
+        t.Errorf("got payload %q", m.Payload)
+    }
+    if string(m.Unprotected.KeyID()) != "11" {
+        t.Errorf("got kid %q", m.Unprotected.KeyID())
+    }
+
+    verifier, err := NewVerifier(AlgorithmEdDSA, priv.Public())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if err = m.Verify([]byte("external"), verifier); err != nil {
+        t.Fatal(err)
+    }
+
+    if err = m.Verify(nil, verifier); err != ErrVerification {
+        t.Errorf("got %v, want %v", err, ErrVerification)
+    }
+
+    // EdDSA 签名是确定的, 编码结果应该相同
+    signer, err := NewSigner(AlgorithmEdDSA, priv)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m2 := NewSign1Message([]byte("This is the content."))
+    m2.Unprotected[HeaderLabelKeyID] = []byte("11")
+    if err = m2.Sign(rand.Reader, []byte("external"), signer); err != nil {
+        t.Fatal(err)
+    }
+
+    got, err := m2.Marshal()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(got, testSign1) {
+        t.Errorf("got %x, want %x", got, testSign1)
+    }
+}
+
+func Test_SignVector(t *testing.T) {
+    priv := ed25519.NewKeyFromSeed(testEd25519Seed)
+
+    m, err := ParseSignMessage(testSign)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    verifier, _ := NewVerifier(AlgorithmEdDSA, priv.Public())
+    if err = m.Verify(nil, verifier); err != nil {
+        t.Fatal(err)
+    }
+
+    got, err := m.Marshal()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(got, testSign) {
+        t.Errorf("got %x, want %x", got, testSign)
+    }
+}
+
+func Test_KeyVector(t *testing.T) {
+    k, err := ParseKey(testEd25519Key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if k.Type != KeyTypeOKP || k.Curve != CurveEd25519 || k.Algorithm != AlgorithmEdDSA {
+        t.Fatalf("got %+v", k)
+    }
+
+    priv, err := k.PrivateKey()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !priv.(ed25519.PrivateKey).Equal(ed25519.NewKeyFromSeed(testEd25519Seed)) {
+        t.Error("private key mismatch")
+    }
+
+    got, err := k.Marshal()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(got, testEd25519Key) {
+        t.Errorf("got %x, want %x", got, testEd25519Key)
+    }
+}
+
+func Test_SignRoundTrip(t *testing.T) {
+    p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+    p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+    _, ed, _ := ed25519.GenerateKey(rand.Reader)
+    _, ed4, _ := ed448.GenerateKey(rand.Reader)
+    rk, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+    cases := []struct {
+        alg Algorithm
+        key crypto.Signer
+    }{
+        {AlgorithmES256, p256},
+        {AlgorithmES384, p384},
+        {AlgorithmES512, p521},
+        {AlgorithmEdDSA, ed},
+        {AlgorithmEdDSA, ed4},
+        {AlgorithmPS256, rk},
+        {AlgorithmPS384, rk},
+        {AlgorithmPS512, rk},
+    }
+
+    for _, c := range cases {
+        t.Run(c.alg.String(), func(t *testing.T) {
+            signer, err := NewSigner(c.alg, c.key)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            verifier, err := NewVerifier(c.alg, c.key.Public())
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            m := NewSign1Message([]byte("payload"))
+            if err = m.Sign(rand.Reader, []byte("aad"), signer); err != nil {
+                t.Fatal(err)
+            }
+
+            data, err := m.Marshal()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            m2, err := ParseSign1Message(data)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if err = m2.Verify([]byte("aad"), verifier); err != nil {
+                t.Fatal(err)
+            }
+
+            m2.Payload = []byte("payloaD")
+            if err = m2.Verify([]byte("aad"), verifier); err != ErrVerification {
+                t.Errorf("got %v, want %v", err, ErrVerification)
+            }
+
+            sm := NewSignMessage([]byte("payload"))
+            if err = sm.Sign(rand.Reader, nil, signer); err != nil {
+                t.Fatal(err)
+            }
+
+            data, err = sm.Marshal()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            sm2, err := ParseSignMessage(data)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if err = sm2.Verify(nil, verifier); err != nil {
+                t.Fatal(err)
+            }
+        })
+    }
+}
+
+func Test_SignerErrors(t *testing.T) {
+    p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+    if _, err := NewSigner(AlgorithmES384, p256); err != ErrAlgorithmMismatch {
+        t.Errorf("got %v, want %v", err, ErrAlgorithmMismatch)
+    }
+    if _, err := NewSigner(AlgorithmPS256, p256); err != ErrInvalidKey {
+        t.Errorf("got %v, want %v", err, ErrInvalidKey)
+    }
+    if _, err := NewSigner(AlgorithmA128GCM, p256); err != ErrAlgorithmNotSupported {
+        t.Errorf("got %v, want %v", err, ErrAlgorithmNotSupported)
+    }
+
+    signer, _ := NewSigner(AlgorithmES256, p256)
+
+    m := NewSign1Message([]byte("payload"))
+    m.Protected[HeaderLabelAlgorithm] = AlgorithmEdDSA
+    if err := m.Sign(rand.Reader, nil, signer); err != ErrAlgorithmMismatch {
+        t.Errorf("got %v, want %v", err, ErrAlgorithmMismatch)
+    }
+}
+
+func Test_Mac0(t *testing.T) {
+    algs := []Algorithm{
+        AlgorithmHMAC256_64,
+        AlgorithmHMAC256_256,
+        AlgorithmHMAC384_384,
+        AlgorithmHMAC512_512,
+    }
+
+    for _, alg := range algs {
+        t.Run(alg.String(), func(t *testing.T) {
+            _, keySize, tagSize, _ := macParams(alg)
+
+            key := make([]byte, keySize)
+            rand.Read(key)
+
+            m := NewMac0Message(alg, []byte("payload"))
+            if err := m.Compute([]byte("aad"), key); err != nil {
+                t.Fatal(err)
+            }
+
+            if len(m.Tag) != tagSize {
+                t.Errorf("got tag size %d, want %d", len(m.Tag), tagSize)
+            }
+
+            data, err := m.Marshal()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            m2, err := ParseMac0Message(data)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if err = m2.Verify([]byte("aad"), key); err != nil {
+                t.Fatal(err)
+            }
+
+            if err = m2.Verify(nil, key); err != ErrVerification {
+                t.Errorf("got %v, want %v", err, ErrVerification)
+            }
+        })
+    }
+}
+
+func Test_Encrypt0(t *testing.T) {
+    algs := []Algorithm{
+        AlgorithmA128GCM,
+        AlgorithmA192GCM,
+        AlgorithmA256GCM,
+        AlgorithmAESCCM_16_64_128,
+        AlgorithmAESCCM_16_64_256,
+        AlgorithmAESCCM_64_64_128,
+        AlgorithmAESCCM_64_64_256,
+        AlgorithmAESCCM_16_128_128,
+        AlgorithmAESCCM_16_128_256,
+        AlgorithmAESCCM_64_128_128,
+        AlgorithmAESCCM_64_128_256,
+    }
+
+    for _, alg := range algs {
+        t.Run(alg.String(), func(t *testing.T) {
+            keySize, nonceSize, _, _ := aeadParams(alg)
+
+            key := make([]byte, keySize)
+            rand.Read(key)
+
+            m := NewEncrypt0Message(alg)
+            if err := m.Encrypt(rand.Reader, []byte("aad"), key, []byte("plaintext")); err != nil {
+                t.Fatal(err)
+            }
+
+            if len(m.Unprotected.IV()) != nonceSize {
+                t.Errorf("got IV size %d, want %d", len(m.Unprotected.IV()), nonceSize)
+            }
+
+            data, err := m.Marshal()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            m2, err := ParseEncrypt0Message(data)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            pt, err := m2.Decrypt([]byte("aad"), key)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if string(pt) != "plaintext" {
+                t.Errorf("got %q", pt)
+            }
+
+            if _, err = m2.Decrypt(nil, key); err != ErrDecryption {
+                t.Errorf("got %v, want %v", err, ErrDecryption)
+            }
+        })
+    }
+}
+
+func Test_EncryptRecipients(t *testing.T) {
+    p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+    _, x25519Key, _ := x25519.GenerateKey(rand.Reader)
+    _, x448Key, _ := x448.GenerateKey(rand.Reader)
+
+    kek := make([]byte, 24)
+    rand.Read(kek)
+
+    direct := make([]byte, 16)
+    rand.Read(direct)
+
+    newSender := func(s Sender, err error) Sender {
+        if err != nil {
+            t.Fatal(err)
+        }
+        return s
+    }
+
+    cases := []struct {
+        name   string
+        sender Sender
+        key    any
+    }{
+        {"direct", NewDirectSender(direct, []byte("kid")), direct},
+        {"A192KW", newSender(NewKeyWrapSender(AlgorithmA192KW, kek, nil)), NewSymmetricKey(kek)},
+        {"ECDH-ES P-256", newSender(NewECDHSender(AlgorithmECDH_ES_HKDF_256, &p256.PublicKey, nil)), p256},
+        {"ECDH-ES P-521", newSender(NewECDHSender(AlgorithmECDH_ES_HKDF_512, &p521.PublicKey, nil)), p521},
+        {"ECDH-ES X25519", newSender(NewECDHSender(AlgorithmECDH_ES_HKDF_256, x25519Key.Public(), nil)), x25519Key},
+        {"ECDH-ES+A128KW X448", newSender(NewECDHSender(AlgorithmECDH_ES_A128KW, x448Key.Public(), nil)), x448Key},
+        {"ECDH-ES+A256KW P-256", newSender(NewECDHSender(AlgorithmECDH_ES_A256KW, &p256.PublicKey, nil)), p256},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            m := NewEncryptMessage(AlgorithmA128GCM)
+            if err := m.Encrypt(rand.Reader, nil, []byte("plaintext"), c.sender); err != nil {
+                t.Fatal(err)
+            }
+
+            data, err := m.Marshal()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            m2, err := ParseEncryptMessage(data)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            pt, err := m2.Decrypt(nil, c.key)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if string(pt) != "plaintext" {
+                t.Errorf("got %q", pt)
+            }
+
+            mm := NewMacMessage(AlgorithmHMAC256_64, []byte("payload"))
+            if err = mm.Compute(rand.Reader, nil, c.sender); err != nil {
+                // 直接使用共享密钥时长度不匹配
+                if c.name == "direct" && err == ErrInvalidKey {
+                    return
+                }
+                t.Fatal(err)
+            }
+
+            data, err = mm.Marshal()
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            mm2, err := ParseMacMessage(data)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if err = mm2.Verify(nil, c.key); err != nil {
+                t.Fatal(err)
+            }
+        })
+    }
+}
+
+func Test_MultipleRecipients(t *testing.T) {
+    p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+    kek := make([]byte, 16)
+    rand.Read(kek)
+
+    kw, _ := NewKeyWrapSender(AlgorithmA128KW, kek, []byte("a"))
+    ecdh, _ := NewECDHSender(AlgorithmECDH_ES_A128KW, &p384.PublicKey, []byte("b"))
+
+    m := NewEncryptMessage(AlgorithmAESCCM_16_128_256)
+    if err := m.Encrypt(rand.Reader, []byte("aad"), []byte("plaintext"), kw, ecdh); err != nil {
+        t.Fatal(err)
+    }
+
+    data, err := m.Marshal()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m2, err := ParseEncryptMessage(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(m2.Recipients) != 2 {
+        t.Fatalf("got %d recipients", len(m2.Recipients))
+    }
+
+    for _, key := range []any{kek, p384} {
+        pt, err := m2.Decrypt([]byte("aad"), key)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if string(pt) != "plaintext" {
+            t.Errorf("got %q", pt)
+        }
+    }
+
+    wrong := make([]byte, 16)
+    if _, err = m2.Decrypt([]byte("aad"), wrong); err != ErrNoRecipient {
+        t.Errorf("got %v, want %v", err, ErrNoRecipient)
+    }
+
+    // 直接方式只能有一个接收者
+    direct := NewDirectSender(make([]byte, 32), nil)
+    if err = m.Encrypt(rand.Reader, nil, nil, kw, direct); err != errDirectMultiple {
+        t.Errorf("got %v, want %v", err, errDirectMultiple)
+    }
+}
+
+func Test_KeyRoundTrip(t *testing.T) {
+    p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+    _, ed, _ := ed25519.GenerateKey(rand.Reader)
+    _, ed4, _ := ed448.GenerateKey(rand.Reader)
+    _, x, _ := x25519.GenerateKey(rand.Reader)
+    _, x4, _ := x448.GenerateKey(rand.Reader)
+    rk, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+    type privateKey interface {
+        Public() crypto.PublicKey
+        Equal(crypto.PrivateKey) bool
+    }
+
+    for _, priv := range []privateKey{p256, p521, ed, ed4, x, x4, rk} {
+        k, err := NewKeyFromPrivate(priv)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        k.ID = []byte("kid")
+
+        data, err := k.Marshal()
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        k2, err := ParseKey(data)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if string(k2.ID) != "kid" {
+            t.Errorf("got kid %q", k2.ID)
+        }
+
+        got, err := k2.PrivateKey()
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if !priv.Equal(got) {
+            t.Errorf("%T private key mismatch", priv)
+        }
+
+        pk, err := NewKeyFromPublic(priv.Public())
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        pub, err := pk.PublicKey()
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(priv.Public()) {
+            t.Errorf("%T public key mismatch", priv)
+        }
+    }
+
+    sk := NewSymmetricKey([]byte("secret"))
+
+    data, err := sk.Marshal()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    sk2, err := ParseKey(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if string(sk2.K) != "secret" {
+        t.Errorf("got %q", sk2.K)
+    }
+}
@@ -0,0 +1,406 @@
+package cose
+
+import (
+    "io"
+    "crypto/aes"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/cose/cbor"
+    "github.com/deatil/go-cryptobin/mode/ccm"
+)
+
+// aeadParams 返回内容加密算法的密钥长度, nonce 长度和标签长度
+func aeadParams(alg Algorithm) (keySize, nonceSize, tagSize int, err error) {
+    switch alg {
+        case AlgorithmA128GCM:
+            return 16, 12, 16, nil
+        case AlgorithmA192GCM:
+            return 24, 12, 16, nil
+        case AlgorithmA256GCM:
+            return 32, 12, 16, nil
+        case AlgorithmAESCCM_16_64_128:
+            return 16, 13, 8, nil
+        case AlgorithmAESCCM_16_64_256:
+            return 32, 13, 8, nil
+        case AlgorithmAESCCM_64_64_128:
+            return 16, 7, 8, nil
+        case AlgorithmAESCCM_64_64_256:
+            return 32, 7, 8, nil
+        case AlgorithmAESCCM_16_128_128:
+            return 16, 13, 16, nil
+        case AlgorithmAESCCM_16_128_256:
+            return 32, 13, 16, nil
+        case AlgorithmAESCCM_64_128_128:
+            return 16, 7, 16, nil
+        case AlgorithmAESCCM_64_128_256:
+            return 32, 7, 16, nil
+    }
+
+    return 0, 0, 0, ErrAlgorithmNotSupported
+}
+
+func newAEAD(alg Algorithm, key []byte) (cipher.AEAD, error) {
+    keySize, nonceSize, tagSize, err := aeadParams(alg)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(key) != keySize {
+        return nil, ErrInvalidKey
+    }
+
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+
+    switch alg {
+        case AlgorithmA128GCM, AlgorithmA192GCM, AlgorithmA256GCM:
+            return cipher.NewGCM(block)
+    }
+
+    return ccm.NewCCMWithNonceAndTagSize(block, nonceSize, tagSize)
+}
+
+// encStructure 生成附加数据. See RFC 9052 section 5.3.
+func encStructure(context string, protected, external []byte) ([]byte, error) {
+    if external == nil {
+        external = []byte{}
+    }
+
+    return cbor.Marshal([]any{context, protected, external})
+}
+
+// 加密数据, IV 不存在时生成并放入非保护头
+func encryptContent(rand io.Reader, alg Algorithm, key []byte, h *Headers, aad, plaintext []byte) ([]byte, error) {
+    aead, err := newAEAD(alg, key)
+    if err != nil {
+        return nil, err
+    }
+
+    if *h == nil {
+        *h = Headers{}
+    }
+
+    iv := h.IV()
+    if iv == nil {
+        iv = make([]byte, aead.NonceSize())
+        if _, err := io.ReadFull(rand, iv); err != nil {
+            return nil, err
+        }
+
+        (*h)[HeaderLabelIV] = iv
+    }
+
+    if len(iv) != aead.NonceSize() {
+        return nil, ErrInvalidMessage
+    }
+
+    return aead.Seal(nil, iv, plaintext, aad), nil
+}
+
+func decryptContent(alg Algorithm, key, iv, aad, ciphertext []byte) ([]byte, error) {
+    aead, err := newAEAD(alg, key)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(iv) != aead.NonceSize() {
+        return nil, ErrInvalidMessage
+    }
+
+    plaintext, err := aead.Open(nil, iv, ciphertext, aad)
+    if err != nil {
+        return nil, ErrDecryption
+    }
+
+    return plaintext, nil
+}
+
+// Encrypt0Message represents a COSE_Encrypt0 message.
+// The IV is stored in the unprotected headers.
+type Encrypt0Message struct {
+    Protected   Headers
+    Unprotected Headers
+    Ciphertext  []byte
+
+    rawProtected []byte
+}
+
+// NewEncrypt0Message returns a new COSE_Encrypt0 message with the content
+// encryption algorithm.
+func NewEncrypt0Message(alg Algorithm) *Encrypt0Message {
+    return &Encrypt0Message{
+        Protected: Headers{
+            HeaderLabelAlgorithm: int64(alg),
+        },
+        Unprotected: Headers{},
+    }
+}
+
+// Encrypt encrypts the plaintext with the shared key. A random IV is
+// generated if the unprotected headers have none.
+func (m *Encrypt0Message) Encrypt(rand io.Reader, external, key, plaintext []byte) error {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return err
+    }
+
+    protected, err := encodeProtected(m.Protected)
+    if err != nil {
+        return err
+    }
+
+    aad, err := encStructure("Encrypt0", protected, external)
+    if err != nil {
+        return err
+    }
+
+    m.Ciphertext, err = encryptContent(rand, alg, key, &m.Unprotected, aad, plaintext)
+    if err != nil {
+        return err
+    }
+
+    m.rawProtected = protected
+
+    return nil
+}
+
+// Decrypt decrypts the message with the shared key.
+func (m *Encrypt0Message) Decrypt(external, key []byte) ([]byte, error) {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return nil, err
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    aad, err := encStructure("Encrypt0", protected, external)
+    if err != nil {
+        return nil, err
+    }
+
+    return decryptContent(alg, key, m.Unprotected.IV(), aad, m.Ciphertext)
+}
+
+func (m *Encrypt0Message) protected() ([]byte, error) {
+    if m.rawProtected != nil {
+        return m.rawProtected, nil
+    }
+
+    return encodeProtected(m.Protected)
+}
+
+// Marshal returns the tagged CBOR encoding of the message.
+func (m *Encrypt0Message) Marshal() ([]byte, error) {
+    if m.Ciphertext == nil {
+        return nil, ErrInvalidMessage
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    return cbor.Marshal(cbor.Tag{
+        Number: TagEncrypt0,
+        Content: []any{
+            protected,
+            unprotectedMap(m.Unprotected),
+            m.Ciphertext,
+        },
+    })
+}
+
+// ParseEncrypt0Message parses a tagged or untagged COSE_Encrypt0 message.
+func ParseEncrypt0Message(data []byte) (*Encrypt0Message, error) {
+    arr, err := decodeMessage(data, TagEncrypt0, 3)
+    if err != nil {
+        return nil, err
+    }
+
+    m := &Encrypt0Message{}
+
+    m.Protected, m.rawProtected, err = decodeProtected(arr[0])
+    if err != nil {
+        return nil, err
+    }
+
+    if m.Unprotected, err = decodeUnprotected(arr[1]); err != nil {
+        return nil, err
+    }
+
+    var ok bool
+    if m.Ciphertext, ok = arr[2].([]byte); !ok {
+        return nil, ErrInvalidMessage
+    }
+
+    return m, nil
+}
+
+// EncryptMessage represents a COSE_Encrypt message with recipients.
+type EncryptMessage struct {
+    Protected   Headers
+    Unprotected Headers
+    Ciphertext  []byte
+    Recipients  []*Recipient
+
+    rawProtected []byte
+}
+
+// NewEncryptMessage returns a new COSE_Encrypt message with the content
+// encryption algorithm.
+func NewEncryptMessage(alg Algorithm) *EncryptMessage {
+    return &EncryptMessage{
+        Protected: Headers{
+            HeaderLabelAlgorithm: int64(alg),
+        },
+        Unprotected: Headers{},
+    }
+}
+
+// Encrypt encrypts the plaintext with a new content key which is
+// distributed to each of the senders.
+func (m *EncryptMessage) Encrypt(rand io.Reader, external, plaintext []byte, senders ...Sender) error {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return err
+    }
+
+    keySize, _, _, err := aeadParams(alg)
+    if err != nil {
+        return err
+    }
+
+    recipients, cek, err := makeRecipients(rand, alg, keySize, senders)
+    if err != nil {
+        return err
+    }
+
+    protected, err := encodeProtected(m.Protected)
+    if err != nil {
+        return err
+    }
+
+    aad, err := encStructure("Encrypt", protected, external)
+    if err != nil {
+        return err
+    }
+
+    m.Ciphertext, err = encryptContent(rand, alg, cek, &m.Unprotected, aad, plaintext)
+    if err != nil {
+        return err
+    }
+
+    m.Recipients = recipients
+    m.rawProtected = protected
+
+    return nil
+}
+
+// Decrypt decrypts the message. The key is used to recover the content key
+// from one of the recipients, it is a []byte or symmetric *Key for direct
+// and AES key wrap recipients, or a private key for ECDH-ES recipients.
+func (m *EncryptMessage) Decrypt(external []byte, key any) ([]byte, error) {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return nil, err
+    }
+
+    keySize, _, _, err := aeadParams(alg)
+    if err != nil {
+        return nil, err
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    aad, err := encStructure("Encrypt", protected, external)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, r := range m.Recipients {
+        cek, err := r.decryptKey(alg, keySize, key)
+        if err != nil {
+            continue
+        }
+
+        plaintext, err := decryptContent(alg, cek, m.Unprotected.IV(), aad, m.Ciphertext)
+        if err == nil {
+            return plaintext, nil
+        }
+    }
+
+    return nil, ErrNoRecipient
+}
+
+func (m *EncryptMessage) protected() ([]byte, error) {
+    if m.rawProtected != nil {
+        return m.rawProtected, nil
+    }
+
+    return encodeProtected(m.Protected)
+}
+
+// Marshal returns the tagged CBOR encoding of the message.
+func (m *EncryptMessage) Marshal() ([]byte, error) {
+    if m.Ciphertext == nil || len(m.Recipients) == 0 {
+        return nil, ErrInvalidMessage
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    recipients, err := encodeRecipients(m.Recipients)
+    if err != nil {
+        return nil, err
+    }
+
+    return cbor.Marshal(cbor.Tag{
+        Number: TagEncrypt,
+        Content: []any{
+            protected,
+            unprotectedMap(m.Unprotected),
+            m.Ciphertext,
+            recipients,
+        },
+    })
+}
+
+// ParseEncryptMessage parses a tagged or untagged COSE_Encrypt message.
+func ParseEncryptMessage(data []byte) (*EncryptMessage, error) {
+    arr, err := decodeMessage(data, TagEncrypt, 4)
+    if err != nil {
+        return nil, err
+    }
+
+    m := &EncryptMessage{}
+
+    m.Protected, m.rawProtected, err = decodeProtected(arr[0])
+    if err != nil {
+        return nil, err
+    }
+
+    if m.Unprotected, err = decodeUnprotected(arr[1]); err != nil {
+        return nil, err
+    }
+
+    var ok bool
+    if m.Ciphertext, ok = arr[2].([]byte); !ok {
+        return nil, ErrInvalidMessage
+    }
+
+    if m.Recipients, err = decodeRecipients(arr[3]); err != nil {
+        return nil, err
+    }
+
+    return m, nil
+}
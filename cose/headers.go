@@ -0,0 +1,168 @@
+package cose
+
+import (
+    "github.com/deatil/go-cryptobin/cose/cbor"
+)
+
+// 通用头信息标签. See RFC 9052 section 3.1.
+const (
+    HeaderLabelAlgorithm    int64 = 1
+    HeaderLabelCritical     int64 = 2
+    HeaderLabelContentType  int64 = 3
+    HeaderLabelKeyID        int64 = 4
+    HeaderLabelIV           int64 = 5
+    HeaderLabelPartialIV    int64 = 6
+    HeaderLabelEphemeralKey int64 = -1
+)
+
+// Headers represents a COSE header map. Labels are int64 or string values.
+type Headers map[any]any
+
+// get 获取标签值, 兼容 int 类型的键
+func (h Headers) get(label int64) (any, bool) {
+    if v, ok := h[label]; ok {
+        return v, true
+    }
+
+    if v, ok := h[int(label)]; ok {
+        return v, true
+    }
+
+    return nil, false
+}
+
+// Algorithm returns the value of the algorithm header.
+func (h Headers) Algorithm() (Algorithm, bool) {
+    v, ok := h.get(HeaderLabelAlgorithm)
+    if !ok {
+        return 0, false
+    }
+
+    switch alg := v.(type) {
+        case Algorithm:
+            return alg, true
+        case int64:
+            return Algorithm(alg), true
+        case int:
+            return Algorithm(alg), true
+    }
+
+    return 0, false
+}
+
+// KeyID returns the value of the kid header.
+func (h Headers) KeyID() []byte {
+    return h.bytes(HeaderLabelKeyID)
+}
+
+// IV returns the value of the IV header.
+func (h Headers) IV() []byte {
+    return h.bytes(HeaderLabelIV)
+}
+
+func (h Headers) bytes(label int64) []byte {
+    v, ok := h.get(label)
+    if !ok {
+        return nil
+    }
+
+    b, _ := v.([]byte)
+    return b
+}
+
+// 查找算法, 先查找保护头
+func findAlgorithm(protected, unprotected Headers) (Algorithm, error) {
+    if alg, ok := protected.Algorithm(); ok {
+        return alg, nil
+    }
+
+    if alg, ok := unprotected.Algorithm(); ok {
+        return alg, nil
+    }
+
+    return 0, ErrAlgorithmNotSupported
+}
+
+// encodeProtected 编码保护头, 空头信息编码为空字节
+func encodeProtected(h Headers) ([]byte, error) {
+    if len(h) == 0 {
+        return []byte{}, nil
+    }
+
+    return cbor.Marshal(map[any]any(h))
+}
+
+func decodeProtected(v any) (Headers, []byte, error) {
+    raw, ok := v.([]byte)
+    if !ok {
+        return nil, nil, ErrInvalidMessage
+    }
+
+    if len(raw) == 0 {
+        return Headers{}, raw, nil
+    }
+
+    m, err := cbor.Unmarshal(raw)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    h, ok := m.(map[any]any)
+    if !ok {
+        return nil, nil, ErrInvalidMessage
+    }
+
+    return Headers(h), raw, nil
+}
+
+func decodeUnprotected(v any) (Headers, error) {
+    h, ok := v.(map[any]any)
+    if !ok {
+        return nil, ErrInvalidMessage
+    }
+
+    return Headers(h), nil
+}
+
+// 解析消息数组, 可以带有或者不带有标签
+func decodeMessage(data []byte, tag uint64, n int) ([]any, error) {
+    v, err := cbor.Unmarshal(data)
+    if err != nil {
+        return nil, err
+    }
+
+    if t, ok := v.(cbor.Tag); ok {
+        if t.Number != tag {
+            return nil, ErrInvalidMessage
+        }
+
+        v = t.Content
+    }
+
+    arr, ok := v.([]any)
+    if !ok || len(arr) != n {
+        return nil, ErrInvalidMessage
+    }
+
+    return arr, nil
+}
+
+// 内容为 nil 时编码为 null
+func encodePayload(payload []byte) any {
+    if payload == nil {
+        return nil
+    }
+
+    return payload
+}
+
+func decodePayload(v any) ([]byte, error) {
+    switch p := v.(type) {
+        case nil:
+            return nil, nil
+        case []byte:
+            return p, nil
+    }
+
+    return nil, ErrInvalidMessage
+}
@@ -0,0 +1,493 @@
+package cose
+
+import (
+    "errors"
+    "math/big"
+    "crypto"
+    "crypto/rsa"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "crypto/elliptic"
+
+    "github.com/deatil/go-cryptobin/cose/cbor"
+    "github.com/deatil/go-cryptobin/pubkey/x448"
+    "github.com/deatil/go-cryptobin/pubkey/ed448"
+    "github.com/deatil/go-cryptobin/pubkey/x25519"
+)
+
+// KeyType represents a COSE key type.
+type KeyType int64
+
+// 密钥类型. See RFC 9053 section 7.
+const (
+    KeyTypeOKP       KeyType = 1
+    KeyTypeEC2       KeyType = 2
+    KeyTypeRSA       KeyType = 3
+    KeyTypeSymmetric KeyType = 4
+)
+
+// Curve represents a COSE elliptic curve.
+type Curve int64
+
+// 曲线
+const (
+    CurveP256    Curve = 1
+    CurveP384    Curve = 2
+    CurveP521    Curve = 3
+    CurveX25519  Curve = 4
+    CurveX448    Curve = 5
+    CurveEd25519 Curve = 6
+    CurveEd448   Curve = 7
+)
+
+// 密钥参数标签
+const (
+    keyLabelKeyType   int64 = 1
+    keyLabelKeyID     int64 = 2
+    keyLabelAlgorithm int64 = 3
+
+    keyLabelCurve int64 = -1
+    keyLabelX     int64 = -2
+    keyLabelY     int64 = -3
+    keyLabelD     int64 = -4
+
+    keyLabelSymmetricK int64 = -1
+
+    keyLabelRSAN    int64 = -1
+    keyLabelRSAE    int64 = -2
+    keyLabelRSAD    int64 = -3
+    keyLabelRSAP    int64 = -4
+    keyLabelRSAQ    int64 = -5
+    keyLabelRSADP   int64 = -6
+    keyLabelRSADQ   int64 = -7
+    keyLabelRSAQInv int64 = -8
+)
+
+// Key represents a COSE_Key structure.
+type Key struct {
+    Type      KeyType
+    ID        []byte
+    Algorithm Algorithm
+
+    // EC2 和 OKP 密钥参数
+    Curve Curve
+    X     []byte
+    Y     []byte
+    D     []byte
+
+    // 对称密钥
+    K []byte
+
+    // RSA 密钥参数
+    N    []byte
+    E    []byte
+    RSAD []byte
+    P    []byte
+    Q    []byte
+    DP   []byte
+    DQ   []byte
+    QInv []byte
+}
+
+// NewSymmetricKey returns a symmetric COSE_Key.
+func NewSymmetricKey(k []byte) *Key {
+    return &Key{
+        Type: KeyTypeSymmetric,
+        K:    k,
+    }
+}
+
+// NewKeyFromPublic returns a COSE_Key from a public key.
+// Supported keys are *ecdsa.PublicKey, ed25519.PublicKey, ed448.PublicKey,
+// x25519.PublicKey, x448.PublicKey and *rsa.PublicKey.
+func NewKeyFromPublic(pub crypto.PublicKey) (*Key, error) {
+    switch k := pub.(type) {
+        case *ecdsa.PublicKey:
+            crv, size, err := curveFromElliptic(k.Curve)
+            if err != nil {
+                return nil, err
+            }
+
+            return &Key{
+                Type:  KeyTypeEC2,
+                Curve: crv,
+                X:     k.X.FillBytes(make([]byte, size)),
+                Y:     k.Y.FillBytes(make([]byte, size)),
+            }, nil
+        case ed25519.PublicKey:
+            return newOKPKey(CurveEd25519, k, nil), nil
+        case ed448.PublicKey:
+            return newOKPKey(CurveEd448, k, nil), nil
+        case x25519.PublicKey:
+            return newOKPKey(CurveX25519, k, nil), nil
+        case x448.PublicKey:
+            return newOKPKey(CurveX448, k, nil), nil
+        case *rsa.PublicKey:
+            return &Key{
+                Type: KeyTypeRSA,
+                N:    k.N.Bytes(),
+                E:    big.NewInt(int64(k.E)).Bytes(),
+            }, nil
+    }
+
+    return nil, ErrInvalidKey
+}
+
+// NewKeyFromPrivate returns a COSE_Key from a private key.
+// Supported keys are *ecdsa.PrivateKey, ed25519.PrivateKey, ed448.PrivateKey,
+// x25519.PrivateKey, x448.PrivateKey and *rsa.PrivateKey.
+func NewKeyFromPrivate(priv crypto.PrivateKey) (*Key, error) {
+    switch k := priv.(type) {
+        case *ecdsa.PrivateKey:
+            key, err := NewKeyFromPublic(&k.PublicKey)
+            if err != nil {
+                return nil, err
+            }
+
+            key.D = k.D.FillBytes(make([]byte, len(key.X)))
+            return key, nil
+        case ed25519.PrivateKey:
+            return newOKPKey(CurveEd25519, k.Public().(ed25519.PublicKey), k.Seed()), nil
+        case ed448.PrivateKey:
+            return newOKPKey(CurveEd448, k.Public().(ed448.PublicKey), k.Seed()), nil
+        case x25519.PrivateKey:
+            return newOKPKey(CurveX25519, k.Public().(x25519.PublicKey), k.Seed()), nil
+        case x448.PrivateKey:
+            return newOKPKey(CurveX448, k.Public().(x448.PublicKey), k.Seed()), nil
+        case *rsa.PrivateKey:
+            if len(k.Primes) != 2 {
+                return nil, errors.New("go-cryptobin/cose: multi-prime RSA keys are not supported")
+            }
+
+            k.Precompute()
+
+            return &Key{
+                Type: KeyTypeRSA,
+                N:    k.N.Bytes(),
+                E:    big.NewInt(int64(k.E)).Bytes(),
+                RSAD: k.D.Bytes(),
+                P:    k.Primes[0].Bytes(),
+                Q:    k.Primes[1].Bytes(),
+                DP:   k.Precomputed.Dp.Bytes(),
+                DQ:   k.Precomputed.Dq.Bytes(),
+                QInv: k.Precomputed.Qinv.Bytes(),
+            }, nil
+    }
+
+    return nil, ErrInvalidKey
+}
+
+func newOKPKey(crv Curve, x, d []byte) *Key {
+    return &Key{
+        Type:  KeyTypeOKP,
+        Curve: crv,
+        X:     append([]byte{}, x...),
+        D:     d,
+    }
+}
+
+// PublicKey returns the public key of the COSE_Key.
+func (k *Key) PublicKey() (crypto.PublicKey, error) {
+    switch k.Type {
+        case KeyTypeEC2:
+            curve, size, err := k.ellipticCurve()
+            if err != nil {
+                return nil, err
+            }
+
+            if len(k.X) != size || len(k.Y) != size {
+                return nil, ErrInvalidKey
+            }
+
+            x := new(big.Int).SetBytes(k.X)
+            y := new(big.Int).SetBytes(k.Y)
+            if !curve.IsOnCurve(x, y) {
+                return nil, ErrInvalidKey
+            }
+
+            return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+        case KeyTypeOKP:
+            switch k.Curve {
+                case CurveEd25519:
+                    if len(k.X) == ed25519.PublicKeySize {
+                        return ed25519.PublicKey(k.X), nil
+                    }
+                case CurveEd448:
+                    if len(k.X) == ed448.PublicKeySize {
+                        return ed448.PublicKey(k.X), nil
+                    }
+                case CurveX25519:
+                    if len(k.X) == x25519.PublicKeySize {
+                        return x25519.PublicKey(k.X), nil
+                    }
+                case CurveX448:
+                    if len(k.X) == x448.PublicKeySize {
+                        return x448.PublicKey(k.X), nil
+                    }
+            }
+        case KeyTypeRSA:
+            if len(k.N) == 0 || len(k.E) == 0 || len(k.E) > 4 {
+                return nil, ErrInvalidKey
+            }
+
+            return &rsa.PublicKey{
+                N: new(big.Int).SetBytes(k.N),
+                E: int(new(big.Int).SetBytes(k.E).Int64()),
+            }, nil
+    }
+
+    return nil, ErrInvalidKey
+}
+
+// PrivateKey returns the private key of the COSE_Key.
+func (k *Key) PrivateKey() (crypto.PrivateKey, error) {
+    switch k.Type {
+        case KeyTypeEC2:
+            curve, size, err := k.ellipticCurve()
+            if err != nil {
+                return nil, err
+            }
+
+            if len(k.D) != size {
+                return nil, ErrInvalidKey
+            }
+
+            priv := new(ecdsa.PrivateKey)
+            priv.Curve = curve
+            priv.D = new(big.Int).SetBytes(k.D)
+            priv.X, priv.Y = curve.ScalarBaseMult(k.D)
+
+            // 公钥存在时检测是否匹配
+            if len(k.X) > 0 && new(big.Int).SetBytes(k.X).Cmp(priv.X) != 0 {
+                return nil, ErrInvalidKey
+            }
+
+            return priv, nil
+        case KeyTypeOKP:
+            var priv interface{
+                Public() crypto.PublicKey
+            }
+
+            switch k.Curve {
+                case CurveEd25519:
+                    if len(k.D) == ed25519.SeedSize {
+                        priv = ed25519.NewKeyFromSeed(k.D)
+                    }
+                case CurveEd448:
+                    if len(k.D) == ed448.SeedSize {
+                        priv = ed448.NewKeyFromSeed(k.D)
+                    }
+                case CurveX25519:
+                    if len(k.D) == x25519.SeedSize {
+                        priv = x25519.NewKeyFromSeed(k.D)
+                    }
+                case CurveX448:
+                    if len(k.D) == x448.SeedSize {
+                        priv = x448.NewKeyFromSeed(k.D)
+                    }
+            }
+
+            if priv == nil {
+                return nil, ErrInvalidKey
+            }
+
+            if len(k.X) > 0 {
+                pub, err := k.PublicKey()
+                if err != nil {
+                    return nil, err
+                }
+
+                if !priv.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(pub) {
+                    return nil, ErrInvalidKey
+                }
+            }
+
+            return priv, nil
+        case KeyTypeRSA:
+            pub, err := k.PublicKey()
+            if err != nil {
+                return nil, err
+            }
+
+            if len(k.RSAD) == 0 || len(k.P) == 0 || len(k.Q) == 0 {
+                return nil, ErrInvalidKey
+            }
+
+            priv := &rsa.PrivateKey{
+                PublicKey: *pub.(*rsa.PublicKey),
+                D:         new(big.Int).SetBytes(k.RSAD),
+                Primes: []*big.Int{
+                    new(big.Int).SetBytes(k.P),
+                    new(big.Int).SetBytes(k.Q),
+                },
+            }
+
+            if err := priv.Validate(); err != nil {
+                return nil, err
+            }
+
+            priv.Precompute()
+
+            return priv, nil
+    }
+
+    return nil, ErrInvalidKey
+}
+
+func (k *Key) ellipticCurve() (elliptic.Curve, int, error) {
+    switch k.Curve {
+        case CurveP256:
+            return elliptic.P256(), 32, nil
+        case CurveP384:
+            return elliptic.P384(), 48, nil
+        case CurveP521:
+            return elliptic.P521(), 66, nil
+    }
+
+    return nil, 0, ErrInvalidKey
+}
+
+func curveFromElliptic(curve elliptic.Curve) (Curve, int, error) {
+    switch curve {
+        case elliptic.P256():
+            return CurveP256, 32, nil
+        case elliptic.P384():
+            return CurveP384, 48, nil
+        case elliptic.P521():
+            return CurveP521, 66, nil
+    }
+
+    return 0, 0, ErrInvalidKey
+}
+
+// Marshal returns the CBOR encoding of the COSE_Key.
+func (k *Key) Marshal() ([]byte, error) {
+    m, err := k.toMap()
+    if err != nil {
+        return nil, err
+    }
+
+    return cbor.Marshal(m)
+}
+
+func (k *Key) toMap() (map[any]any, error) {
+    m := map[any]any{
+        keyLabelKeyType: int64(k.Type),
+    }
+
+    if len(k.ID) > 0 {
+        m[keyLabelKeyID] = k.ID
+    }
+    if k.Algorithm != 0 {
+        m[keyLabelAlgorithm] = int64(k.Algorithm)
+    }
+
+    set := func(label int64, v []byte) {
+        if len(v) > 0 {
+            m[label] = v
+        }
+    }
+
+    switch k.Type {
+        case KeyTypeEC2, KeyTypeOKP:
+            m[keyLabelCurve] = int64(k.Curve)
+            set(keyLabelX, k.X)
+            set(keyLabelD, k.D)
+            if k.Type == KeyTypeEC2 {
+                set(keyLabelY, k.Y)
+            }
+        case KeyTypeSymmetric:
+            m[keyLabelSymmetricK] = k.K
+        case KeyTypeRSA:
+            set(keyLabelRSAN, k.N)
+            set(keyLabelRSAE, k.E)
+            set(keyLabelRSAD, k.RSAD)
+            set(keyLabelRSAP, k.P)
+            set(keyLabelRSAQ, k.Q)
+            set(keyLabelRSADP, k.DP)
+            set(keyLabelRSADQ, k.DQ)
+            set(keyLabelRSAQInv, k.QInv)
+        default:
+            return nil, ErrInvalidKey
+    }
+
+    return m, nil
+}
+
+// ParseKey parses a CBOR encoded COSE_Key.
+func ParseKey(data []byte) (*Key, error) {
+    v, err := cbor.Unmarshal(data)
+    if err != nil {
+        return nil, err
+    }
+
+    return keyFromCBOR(v)
+}
+
+func keyFromCBOR(v any) (*Key, error) {
+    m, ok := v.(map[any]any)
+    if !ok {
+        return nil, ErrInvalidKey
+    }
+
+    getInt := func(label int64) (int64, bool) {
+        n, ok := m[label].(int64)
+        return n, ok
+    }
+    getBytes := func(label int64) []byte {
+        b, _ := m[label].([]byte)
+        return b
+    }
+
+    kty, ok := getInt(keyLabelKeyType)
+    if !ok {
+        return nil, ErrInvalidKey
+    }
+
+    k := &Key{
+        Type: KeyType(kty),
+        ID:   getBytes(keyLabelKeyID),
+    }
+
+    if alg, ok := getInt(keyLabelAlgorithm); ok {
+        k.Algorithm = Algorithm(alg)
+    }
+
+    switch k.Type {
+        case KeyTypeEC2, KeyTypeOKP:
+            crv, ok := getInt(keyLabelCurve)
+            if !ok {
+                return nil, ErrInvalidKey
+            }
+
+            k.Curve = Curve(crv)
+            k.X = getBytes(keyLabelX)
+            k.D = getBytes(keyLabelD)
+            if k.Type == KeyTypeEC2 {
+                k.Y = getBytes(keyLabelY)
+
+                // 压缩点
+                if _, isBool := m[keyLabelY].(bool); isBool {
+                    return nil, errors.New("go-cryptobin/cose: compressed EC2 keys are not supported")
+                }
+            }
+        case KeyTypeSymmetric:
+            k.K = getBytes(keyLabelSymmetricK)
+            if len(k.K) == 0 {
+                return nil, ErrInvalidKey
+            }
+        case KeyTypeRSA:
+            k.N = getBytes(keyLabelRSAN)
+            k.E = getBytes(keyLabelRSAE)
+            k.RSAD = getBytes(keyLabelRSAD)
+            k.P = getBytes(keyLabelRSAP)
+            k.Q = getBytes(keyLabelRSAQ)
+            k.DP = getBytes(keyLabelRSADP)
+            k.DQ = getBytes(keyLabelRSADQ)
+            k.QInv = getBytes(keyLabelRSAQInv)
+        default:
+            return nil, ErrInvalidKey
+    }
+
+    return k, nil
+}
@@ -0,0 +1,359 @@
+package cose
+
+import (
+    "io"
+    "hash"
+    "crypto/hmac"
+    "crypto/sha256"
+    "crypto/sha512"
+
+    "github.com/deatil/go-cryptobin/cose/cbor"
+)
+
+// macParams 返回 MAC 算法的摘要方式, 密钥长度和标签长度
+func macParams(alg Algorithm) (h func() hash.Hash, keySize, tagSize int, err error) {
+    switch alg {
+        case AlgorithmHMAC256_64:
+            return sha256.New, 32, 8, nil
+        case AlgorithmHMAC256_256:
+            return sha256.New, 32, 32, nil
+        case AlgorithmHMAC384_384:
+            return sha512.New384, 48, 48, nil
+        case AlgorithmHMAC512_512:
+            return sha512.New, 64, 64, nil
+    }
+
+    return nil, 0, 0, ErrAlgorithmNotSupported
+}
+
+func computeTag(alg Algorithm, key, toBeMaced []byte) ([]byte, error) {
+    h, keySize, tagSize, err := macParams(alg)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(key) != keySize {
+        return nil, ErrInvalidKey
+    }
+
+    mac := hmac.New(h, key)
+    mac.Write(toBeMaced)
+
+    return mac.Sum(nil)[:tagSize], nil
+}
+
+// macStructure 生成待计算数据. See RFC 9052 section 6.3.
+func macStructure(context string, protected, external, payload []byte) ([]byte, error) {
+    if external == nil {
+        external = []byte{}
+    }
+    if payload == nil {
+        payload = []byte{}
+    }
+
+    return cbor.Marshal([]any{context, protected, external, payload})
+}
+
+// Mac0Message represents a COSE_Mac0 message.
+type Mac0Message struct {
+    Protected   Headers
+    Unprotected Headers
+    Payload     []byte
+    Tag         []byte
+
+    rawProtected []byte
+}
+
+// NewMac0Message returns a new COSE_Mac0 message with the MAC algorithm.
+func NewMac0Message(alg Algorithm, payload []byte) *Mac0Message {
+    return &Mac0Message{
+        Protected: Headers{
+            HeaderLabelAlgorithm: int64(alg),
+        },
+        Unprotected: Headers{},
+        Payload:     payload,
+    }
+}
+
+// Compute computes the authentication tag with the shared key.
+func (m *Mac0Message) Compute(external, key []byte) error {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return err
+    }
+
+    protected, err := encodeProtected(m.Protected)
+    if err != nil {
+        return err
+    }
+
+    toBeMaced, err := macStructure("MAC0", protected, external, m.Payload)
+    if err != nil {
+        return err
+    }
+
+    if m.Tag, err = computeTag(alg, key, toBeMaced); err != nil {
+        return err
+    }
+
+    m.rawProtected = protected
+
+    return nil
+}
+
+// Verify verifies the authentication tag with the shared key.
+func (m *Mac0Message) Verify(external, key []byte) error {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return err
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return err
+    }
+
+    toBeMaced, err := macStructure("MAC0", protected, external, m.Payload)
+    if err != nil {
+        return err
+    }
+
+    tag, err := computeTag(alg, key, toBeMaced)
+    if err != nil {
+        return err
+    }
+
+    if !hmac.Equal(tag, m.Tag) {
+        return ErrVerification
+    }
+
+    return nil
+}
+
+func (m *Mac0Message) protected() ([]byte, error) {
+    if m.rawProtected != nil {
+        return m.rawProtected, nil
+    }
+
+    return encodeProtected(m.Protected)
+}
+
+// Marshal returns the tagged CBOR encoding of the message.
+func (m *Mac0Message) Marshal() ([]byte, error) {
+    if m.Tag == nil {
+        return nil, ErrInvalidMessage
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    return cbor.Marshal(cbor.Tag{
+        Number: TagMac0,
+        Content: []any{
+            protected,
+            unprotectedMap(m.Unprotected),
+            encodePayload(m.Payload),
+            m.Tag,
+        },
+    })
+}
+
+// ParseMac0Message parses a tagged or untagged COSE_Mac0 message.
+func ParseMac0Message(data []byte) (*Mac0Message, error) {
+    arr, err := decodeMessage(data, TagMac0, 4)
+    if err != nil {
+        return nil, err
+    }
+
+    m := &Mac0Message{}
+
+    m.Protected, m.rawProtected, err = decodeProtected(arr[0])
+    if err != nil {
+        return nil, err
+    }
+
+    if m.Unprotected, err = decodeUnprotected(arr[1]); err != nil {
+        return nil, err
+    }
+
+    if m.Payload, err = decodePayload(arr[2]); err != nil {
+        return nil, err
+    }
+
+    var ok bool
+    if m.Tag, ok = arr[3].([]byte); !ok {
+        return nil, ErrInvalidMessage
+    }
+
+    return m, nil
+}
+
+// MacMessage represents a COSE_Mac message with recipients.
+type MacMessage struct {
+    Protected   Headers
+    Unprotected Headers
+    Payload     []byte
+    Tag         []byte
+    Recipients  []*Recipient
+
+    rawProtected []byte
+}
+
+// NewMacMessage returns a new COSE_Mac message with the MAC algorithm.
+func NewMacMessage(alg Algorithm, payload []byte) *MacMessage {
+    return &MacMessage{
+        Protected: Headers{
+            HeaderLabelAlgorithm: int64(alg),
+        },
+        Unprotected: Headers{},
+        Payload:     payload,
+    }
+}
+
+// Compute computes the authentication tag with a new key which is
+// distributed to each of the senders.
+func (m *MacMessage) Compute(rand io.Reader, external []byte, senders ...Sender) error {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return err
+    }
+
+    _, keySize, _, err := macParams(alg)
+    if err != nil {
+        return err
+    }
+
+    recipients, key, err := makeRecipients(rand, alg, keySize, senders)
+    if err != nil {
+        return err
+    }
+
+    protected, err := encodeProtected(m.Protected)
+    if err != nil {
+        return err
+    }
+
+    toBeMaced, err := macStructure("MAC", protected, external, m.Payload)
+    if err != nil {
+        return err
+    }
+
+    if m.Tag, err = computeTag(alg, key, toBeMaced); err != nil {
+        return err
+    }
+
+    m.Recipients = recipients
+    m.rawProtected = protected
+
+    return nil
+}
+
+// Verify verifies the authentication tag. The key is used to recover the
+// MAC key from one of the recipients, see EncryptMessage.Decrypt.
+func (m *MacMessage) Verify(external []byte, key any) error {
+    alg, err := findAlgorithm(m.Protected, m.Unprotected)
+    if err != nil {
+        return err
+    }
+
+    _, keySize, _, err := macParams(alg)
+    if err != nil {
+        return err
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return err
+    }
+
+    toBeMaced, err := macStructure("MAC", protected, external, m.Payload)
+    if err != nil {
+        return err
+    }
+
+    for _, r := range m.Recipients {
+        cek, err := r.decryptKey(alg, keySize, key)
+        if err != nil {
+            continue
+        }
+
+        tag, err := computeTag(alg, cek, toBeMaced)
+        if err == nil && hmac.Equal(tag, m.Tag) {
+            return nil
+        }
+    }
+
+    return ErrVerification
+}
+
+func (m *MacMessage) protected() ([]byte, error) {
+    if m.rawProtected != nil {
+        return m.rawProtected, nil
+    }
+
+    return encodeProtected(m.Protected)
+}
+
+// Marshal returns the tagged CBOR encoding of the message.
+func (m *MacMessage) Marshal() ([]byte, error) {
+    if m.Tag == nil || len(m.Recipients) == 0 {
+        return nil, ErrInvalidMessage
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    recipients, err := encodeRecipients(m.Recipients)
+    if err != nil {
+        return nil, err
+    }
+
+    return cbor.Marshal(cbor.Tag{
+        Number: TagMac,
+        Content: []any{
+            protected,
+            unprotectedMap(m.Unprotected),
+            encodePayload(m.Payload),
+            m.Tag,
+            recipients,
+        },
+    })
+}
+
+// ParseMacMessage parses a tagged or untagged COSE_Mac message.
+func ParseMacMessage(data []byte) (*MacMessage, error) {
+    arr, err := decodeMessage(data, TagMac, 5)
+    if err != nil {
+        return nil, err
+    }
+
+    m := &MacMessage{}
+
+    m.Protected, m.rawProtected, err = decodeProtected(arr[0])
+    if err != nil {
+        return nil, err
+    }
+
+    if m.Unprotected, err = decodeUnprotected(arr[1]); err != nil {
+        return nil, err
+    }
+
+    if m.Payload, err = decodePayload(arr[2]); err != nil {
+        return nil, err
+    }
+
+    var ok bool
+    if m.Tag, ok = arr[3].([]byte); !ok {
+        return nil, ErrInvalidMessage
+    }
+
+    if m.Recipients, err = decodeRecipients(arr[4]); err != nil {
+        return nil, err
+    }
+
+    return m, nil
+}
@@ -0,0 +1,594 @@
+package cose
+
+import (
+    "io"
+    "hash"
+    "errors"
+    "crypto"
+    "crypto/aes"
+    "crypto/ecdsa"
+    "crypto/sha256"
+    "crypto/sha512"
+    "crypto/subtle"
+
+    "github.com/deatil/go-cryptobin/mode"
+    "github.com/deatil/go-cryptobin/cose/cbor"
+    "github.com/deatil/go-cryptobin/kdf/hkdf"
+    "github.com/deatil/go-cryptobin/pubkey/x448"
+    "github.com/deatil/go-cryptobin/pubkey/x25519"
+)
+
+// Recipient represents a COSE_recipient structure.
+type Recipient struct {
+    Protected   Headers
+    Unprotected Headers
+    Ciphertext  []byte
+
+    rawProtected []byte
+}
+
+// Sender distributes the content key of a COSE_Encrypt or COSE_Mac message
+// to a recipient.
+type Sender interface {
+    // 生成接收者结构和内容密钥.
+    // cek 为 nil 时由接收者生成或者派生内容密钥
+    Recipient(rand io.Reader, alg Algorithm, keySize int, cek []byte) (*Recipient, []byte, error)
+}
+
+// 多个接收者时使用随机的内容密钥
+func makeRecipients(rand io.Reader, alg Algorithm, keySize int, senders []Sender) ([]*Recipient, []byte, error) {
+    if len(senders) == 0 {
+        return nil, nil, ErrNoRecipient
+    }
+
+    var cek []byte
+    if len(senders) > 1 {
+        cek = make([]byte, keySize)
+        if _, err := io.ReadFull(rand, cek); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    recipients := make([]*Recipient, 0, len(senders))
+    for _, s := range senders {
+        r, key, err := s.Recipient(rand, alg, keySize, cek)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        cek = key
+        recipients = append(recipients, r)
+    }
+
+    return recipients, cek, nil
+}
+
+var errDirectMultiple = errors.New("go-cryptobin/cose: direct key agreement needs a single recipient")
+
+// 直接使用共享密钥
+type directSender struct {
+    key []byte
+    kid []byte
+}
+
+// NewDirectSender returns a Sender which uses the shared key as the content
+// key directly. It can only be used with a single recipient.
+func NewDirectSender(key, kid []byte) Sender {
+    return &directSender{key, kid}
+}
+
+func (s *directSender) Recipient(rand io.Reader, alg Algorithm, keySize int, cek []byte) (*Recipient, []byte, error) {
+    if cek != nil {
+        return nil, nil, errDirectMultiple
+    }
+
+    if len(s.key) != keySize {
+        return nil, nil, ErrInvalidKey
+    }
+
+    r := &Recipient{
+        Protected:   Headers{},
+        Unprotected: recipientHeaders(AlgorithmDirect, s.kid),
+        Ciphertext:  []byte{},
+    }
+
+    return r, s.key, nil
+}
+
+// AES 密钥包装
+type keyWrapSender struct {
+    alg Algorithm
+    kek []byte
+    kid []byte
+}
+
+// NewKeyWrapSender returns a Sender which wraps the content key with the
+// AES key wrap algorithm A128KW, A192KW or A256KW.
+func NewKeyWrapSender(alg Algorithm, kek, kid []byte) (Sender, error) {
+    size, err := keyWrapSize(alg)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(kek) != size {
+        return nil, ErrInvalidKey
+    }
+
+    return &keyWrapSender{alg, kek, kid}, nil
+}
+
+func (s *keyWrapSender) Recipient(rand io.Reader, alg Algorithm, keySize int, cek []byte) (*Recipient, []byte, error) {
+    cek, err := randomKey(rand, keySize, cek)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    wrapped, err := wrapKey(s.kek, cek)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    r := &Recipient{
+        Protected:   Headers{},
+        Unprotected: recipientHeaders(s.alg, s.kid),
+        Ciphertext:  wrapped,
+    }
+
+    return r, cek, nil
+}
+
+// ECDH-ES 密钥协商
+type ecdhSender struct {
+    alg Algorithm
+    pub crypto.PublicKey
+    kid []byte
+}
+
+// NewECDHSender returns a Sender which uses ECDH-ES with an ephemeral key.
+// The public key is a *ecdsa.PublicKey, x25519.PublicKey, x448.PublicKey or
+// a *Key. ECDH-ES + HKDF algorithms can only be used with a single recipient.
+func NewECDHSender(alg Algorithm, pub crypto.PublicKey, kid []byte) (Sender, error) {
+    if _, _, err := ecdhParams(alg); err != nil {
+        return nil, err
+    }
+
+    if k, ok := pub.(*Key); ok {
+        var err error
+        if pub, err = k.PublicKey(); err != nil {
+            return nil, err
+        }
+    }
+
+    switch pub.(type) {
+        case *ecdsa.PublicKey, x25519.PublicKey, x448.PublicKey:
+        default:
+            return nil, ErrInvalidKey
+    }
+
+    return &ecdhSender{alg, pub, kid}, nil
+}
+
+func (s *ecdhSender) Recipient(rand io.Reader, alg Algorithm, keySize int, cek []byte) (*Recipient, []byte, error) {
+    kwAlg, h, _ := ecdhParams(s.alg)
+    if kwAlg == 0 && cek != nil {
+        return nil, nil, errDirectMultiple
+    }
+
+    eph, shared, err := ecdhEphemeral(rand, s.pub)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    ephMap, err := eph.toMap()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    r := &Recipient{
+        Protected: Headers{
+            HeaderLabelAlgorithm: int64(s.alg),
+        },
+        Unprotected: recipientHeaders(0, s.kid),
+    }
+    r.Unprotected[HeaderLabelEphemeralKey] = ephMap
+
+    if r.rawProtected, err = encodeProtected(r.Protected); err != nil {
+        return nil, nil, err
+    }
+
+    // 直接派生内容密钥
+    if kwAlg == 0 {
+        key, err := deriveKey(h, shared, alg, keySize, r.rawProtected)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        r.Ciphertext = []byte{}
+        return r, key, nil
+    }
+
+    kekSize, _ := keyWrapSize(kwAlg)
+
+    kek, err := deriveKey(h, shared, kwAlg, kekSize, r.rawProtected)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if cek, err = randomKey(rand, keySize, cek); err != nil {
+        return nil, nil, err
+    }
+
+    if r.Ciphertext, err = wrapKey(kek, cek); err != nil {
+        return nil, nil, err
+    }
+
+    return r, cek, nil
+}
+
+// decryptKey 使用密钥恢复内容密钥
+func (r *Recipient) decryptKey(alg Algorithm, keySize int, key any) ([]byte, error) {
+    ralg, err := findAlgorithm(r.Protected, r.Unprotected)
+    if err != nil {
+        return nil, err
+    }
+
+    switch ralg {
+        case AlgorithmDirect:
+            k := symmetricKey(key)
+            if len(k) != keySize {
+                return nil, ErrInvalidKey
+            }
+
+            return k, nil
+        case AlgorithmA128KW, AlgorithmA192KW, AlgorithmA256KW:
+            size, _ := keyWrapSize(ralg)
+
+            k := symmetricKey(key)
+            if len(k) != size {
+                return nil, ErrInvalidKey
+            }
+
+            return unwrapKey(k, r.Ciphertext, keySize)
+    }
+
+    kwAlg, h, err := ecdhParams(ralg)
+    if err != nil {
+        return nil, err
+    }
+
+    v, ok := r.Unprotected.get(HeaderLabelEphemeralKey)
+    if !ok {
+        return nil, ErrInvalidMessage
+    }
+
+    eph, err := keyFromCBOR(v)
+    if err != nil {
+        return nil, err
+    }
+
+    shared, err := ecdhShared(key, eph)
+    if err != nil {
+        return nil, err
+    }
+
+    protected, err := r.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    if kwAlg == 0 {
+        return deriveKey(h, shared, alg, keySize, protected)
+    }
+
+    kekSize, _ := keyWrapSize(kwAlg)
+
+    kek, err := deriveKey(h, shared, kwAlg, kekSize, protected)
+    if err != nil {
+        return nil, err
+    }
+
+    return unwrapKey(kek, r.Ciphertext, keySize)
+}
+
+func (r *Recipient) protected() ([]byte, error) {
+    if r.rawProtected != nil {
+        return r.rawProtected, nil
+    }
+
+    return encodeProtected(r.Protected)
+}
+
+func encodeRecipients(recipients []*Recipient) ([]any, error) {
+    out := make([]any, 0, len(recipients))
+
+    for _, r := range recipients {
+        protected, err := r.protected()
+        if err != nil {
+            return nil, err
+        }
+
+        ciphertext := r.Ciphertext
+        if ciphertext == nil {
+            ciphertext = []byte{}
+        }
+
+        out = append(out, []any{
+            protected,
+            unprotectedMap(r.Unprotected),
+            ciphertext,
+        })
+    }
+
+    return out, nil
+}
+
+func decodeRecipients(v any) ([]*Recipient, error) {
+    arr, ok := v.([]any)
+    if !ok || len(arr) == 0 {
+        return nil, ErrInvalidMessage
+    }
+
+    recipients := make([]*Recipient, 0, len(arr))
+    for _, item := range arr {
+        ra, ok := item.([]any)
+        if !ok || len(ra) != 3 {
+            return nil, ErrInvalidMessage
+        }
+
+        r := &Recipient{}
+
+        var err error
+        r.Protected, r.rawProtected, err = decodeProtected(ra[0])
+        if err != nil {
+            return nil, err
+        }
+
+        if r.Unprotected, err = decodeUnprotected(ra[1]); err != nil {
+            return nil, err
+        }
+
+        if r.Ciphertext, err = decodePayload(ra[2]); err != nil {
+            return nil, err
+        }
+
+        recipients = append(recipients, r)
+    }
+
+    return recipients, nil
+}
+
+func recipientHeaders(alg Algorithm, kid []byte) Headers {
+    h := Headers{}
+    if alg != 0 {
+        h[HeaderLabelAlgorithm] = int64(alg)
+    }
+    if len(kid) > 0 {
+        h[HeaderLabelKeyID] = kid
+    }
+
+    return h
+}
+
+func symmetricKey(key any) []byte {
+    switch k := key.(type) {
+        case []byte:
+            return k
+        case *Key:
+            if k.Type == KeyTypeSymmetric {
+                return k.K
+            }
+    }
+
+    return nil
+}
+
+func randomKey(rand io.Reader, size int, key []byte) ([]byte, error) {
+    if key != nil {
+        return key, nil
+    }
+
+    key = make([]byte, size)
+    if _, err := io.ReadFull(rand, key); err != nil {
+        return nil, err
+    }
+
+    return key, nil
+}
+
+func keyWrapSize(alg Algorithm) (int, error) {
+    switch alg {
+        case AlgorithmA128KW:
+            return 16, nil
+        case AlgorithmA192KW:
+            return 24, nil
+        case AlgorithmA256KW:
+            return 32, nil
+    }
+
+    return 0, ErrAlgorithmNotSupported
+}
+
+// ecdhParams 返回包装算法和 HKDF 摘要方式, 直接派生时包装算法为 0
+func ecdhParams(alg Algorithm) (Algorithm, func() hash.Hash, error) {
+    switch alg {
+        case AlgorithmECDH_ES_HKDF_256:
+            return 0, sha256.New, nil
+        case AlgorithmECDH_ES_HKDF_512:
+            return 0, sha512.New, nil
+        case AlgorithmECDH_ES_A128KW:
+            return AlgorithmA128KW, sha256.New, nil
+        case AlgorithmECDH_ES_A192KW:
+            return AlgorithmA192KW, sha256.New, nil
+        case AlgorithmECDH_ES_A256KW:
+            return AlgorithmA256KW, sha256.New, nil
+    }
+
+    return 0, nil, ErrAlgorithmNotSupported
+}
+
+// AES 密钥包装. See RFC 3394.
+func wrapKey(kek, cek []byte) ([]byte, error) {
+    if len(cek) < 16 || len(cek)%8 != 0 {
+        return nil, ErrInvalidKey
+    }
+
+    block, err := aes.NewCipher(kek)
+    if err != nil {
+        return nil, err
+    }
+
+    out := make([]byte, len(cek)+8)
+    mode.NewWrapEncrypter(block, nil).CryptBlocks(out, cek)
+
+    return out, nil
+}
+
+func unwrapKey(kek, wrapped []byte, keySize int) ([]byte, error) {
+    if len(wrapped) != keySize+8 || len(wrapped)%8 != 0 || len(wrapped) < 24 {
+        return nil, ErrDecryption
+    }
+
+    block, err := aes.NewCipher(kek)
+    if err != nil {
+        return nil, err
+    }
+
+    out := make([]byte, keySize)
+    mode.NewWrapDecrypter(block, nil).CryptBlocks(out, wrapped)
+
+    // 校验失败时输出为全零
+    if subtle.ConstantTimeCompare(out, make([]byte, keySize)) == 1 {
+        return nil, ErrDecryption
+    }
+
+    return out, nil
+}
+
+// deriveKey 使用 HKDF 派生密钥. See RFC 9053 section 5.
+func deriveKey(h func() hash.Hash, shared []byte, alg Algorithm, size int, protected []byte) ([]byte, error) {
+    context, err := cbor.Marshal([]any{
+        int64(alg),
+        []any{nil, nil, nil},
+        []any{nil, nil, nil},
+        []any{int64(size * 8), protected},
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return hkdf.Key(h, shared, nil, context, size)
+}
+
+// ecdhEphemeral 生成临时密钥并计算共享密钥
+func ecdhEphemeral(rand io.Reader, pub crypto.PublicKey) (*Key, []byte, error) {
+    switch pub := pub.(type) {
+        case *ecdsa.PublicKey:
+            crv, size, err := curveFromElliptic(pub.Curve)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            peer, err := pub.ECDH()
+            if err != nil {
+                return nil, nil, err
+            }
+
+            priv, err := peer.Curve().GenerateKey(rand)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            shared, err := priv.ECDH(peer)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            point := priv.PublicKey().Bytes()
+
+            eph := &Key{
+                Type:  KeyTypeEC2,
+                Curve: crv,
+                X:     point[1 : 1+size],
+                Y:     point[1+size:],
+            }
+
+            return eph, shared, nil
+        case x25519.PublicKey:
+            ephPub, priv, err := x25519.GenerateKey(rand)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            shared, err := x25519.X25519(priv.Seed(), pub)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            return newOKPKey(CurveX25519, ephPub, nil), shared, nil
+        case x448.PublicKey:
+            ephPub, priv, err := x448.GenerateKey(rand)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            shared, err := x448.X448(priv.Seed(), pub)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            return newOKPKey(CurveX448, ephPub, nil), shared, nil
+    }
+
+    return nil, nil, ErrInvalidKey
+}
+
+// ecdhShared 使用私钥和临时公钥计算共享密钥
+func ecdhShared(key any, eph *Key) ([]byte, error) {
+    if k, ok := key.(*Key); ok {
+        var err error
+        if key, err = k.PrivateKey(); err != nil {
+            return nil, err
+        }
+    }
+
+    pub, err := eph.PublicKey()
+    if err != nil {
+        return nil, err
+    }
+
+    switch priv := key.(type) {
+        case *ecdsa.PrivateKey:
+            peer, ok := pub.(*ecdsa.PublicKey)
+            if !ok || peer.Curve != priv.Curve {
+                return nil, ErrInvalidKey
+            }
+
+            p, err := priv.ECDH()
+            if err != nil {
+                return nil, err
+            }
+
+            pp, err := peer.ECDH()
+            if err != nil {
+                return nil, err
+            }
+
+            return p.ECDH(pp)
+        case x25519.PrivateKey:
+            peer, ok := pub.(x25519.PublicKey)
+            if !ok {
+                return nil, ErrInvalidKey
+            }
+
+            return x25519.X25519(priv.Seed(), peer)
+        case x448.PrivateKey:
+            peer, ok := pub.(x448.PublicKey)
+            if !ok {
+                return nil, ErrInvalidKey
+            }
+
+            return x448.X448(priv.Seed(), peer)
+    }
+
+    return nil, ErrInvalidKey
+}
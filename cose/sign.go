@@ -0,0 +1,395 @@
+package cose
+
+import (
+    "io"
+
+    "github.com/deatil/go-cryptobin/cose/cbor"
+)
+
+// Sign1Message represents a COSE_Sign1 message.
+// A nil Payload is encoded as null, which is used for detached content.
+type Sign1Message struct {
+    Protected   Headers
+    Unprotected Headers
+    Payload     []byte
+    Signature   []byte
+
+    rawProtected []byte
+}
+
+// NewSign1Message returns a new COSE_Sign1 message.
+func NewSign1Message(payload []byte) *Sign1Message {
+    return &Sign1Message{
+        Protected:   Headers{},
+        Unprotected: Headers{},
+        Payload:     payload,
+    }
+}
+
+// Sign signs the message, external is the externally supplied data.
+func (m *Sign1Message) Sign(rand io.Reader, external []byte, signer Signer) error {
+    protected, err := prepareProtected(&m.Protected, signer.Algorithm())
+    if err != nil {
+        return err
+    }
+
+    toBeSigned, err := sigStructure("Signature1", protected, nil, external, m.Payload)
+    if err != nil {
+        return err
+    }
+
+    sig, err := signer.Sign(rand, toBeSigned)
+    if err != nil {
+        return err
+    }
+
+    m.Signature = sig
+    m.rawProtected = protected
+
+    return nil
+}
+
+// Verify verifies the signature of the message.
+func (m *Sign1Message) Verify(external []byte, verifier Verifier) error {
+    if err := checkAlgorithm(m.Protected, m.Unprotected, verifier.Algorithm()); err != nil {
+        return err
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return err
+    }
+
+    toBeSigned, err := sigStructure("Signature1", protected, nil, external, m.Payload)
+    if err != nil {
+        return err
+    }
+
+    return verifier.Verify(toBeSigned, m.Signature)
+}
+
+func (m *Sign1Message) protected() ([]byte, error) {
+    if m.rawProtected != nil {
+        return m.rawProtected, nil
+    }
+
+    return encodeProtected(m.Protected)
+}
+
+// Marshal returns the tagged CBOR encoding of the message.
+func (m *Sign1Message) Marshal() ([]byte, error) {
+    if m.Signature == nil {
+        return nil, ErrInvalidMessage
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    return cbor.Marshal(cbor.Tag{
+        Number: TagSign1,
+        Content: []any{
+            protected,
+            unprotectedMap(m.Unprotected),
+            encodePayload(m.Payload),
+            m.Signature,
+        },
+    })
+}
+
+// ParseSign1Message parses a tagged or untagged COSE_Sign1 message.
+func ParseSign1Message(data []byte) (*Sign1Message, error) {
+    arr, err := decodeMessage(data, TagSign1, 4)
+    if err != nil {
+        return nil, err
+    }
+
+    m := &Sign1Message{}
+
+    m.Protected, m.rawProtected, err = decodeProtected(arr[0])
+    if err != nil {
+        return nil, err
+    }
+
+    if m.Unprotected, err = decodeUnprotected(arr[1]); err != nil {
+        return nil, err
+    }
+
+    if m.Payload, err = decodePayload(arr[2]); err != nil {
+        return nil, err
+    }
+
+    var ok bool
+    if m.Signature, ok = arr[3].([]byte); !ok {
+        return nil, ErrInvalidMessage
+    }
+
+    return m, nil
+}
+
+// Signature represents a COSE_Signature structure of COSE_Sign.
+type Signature struct {
+    Protected   Headers
+    Unprotected Headers
+    Signature   []byte
+
+    rawProtected []byte
+}
+
+func (s *Signature) protected() ([]byte, error) {
+    if s.rawProtected != nil {
+        return s.rawProtected, nil
+    }
+
+    return encodeProtected(s.Protected)
+}
+
+// SignMessage represents a COSE_Sign message with one or more signatures.
+type SignMessage struct {
+    Protected   Headers
+    Unprotected Headers
+    Payload     []byte
+    Signatures  []*Signature
+
+    rawProtected []byte
+}
+
+// NewSignMessage returns a new COSE_Sign message.
+func NewSignMessage(payload []byte) *SignMessage {
+    return &SignMessage{
+        Protected:   Headers{},
+        Unprotected: Headers{},
+        Payload:     payload,
+    }
+}
+
+// Sign adds a signature for each signer. The unprotected headers of the
+// new signatures can be set after signing.
+func (m *SignMessage) Sign(rand io.Reader, external []byte, signers ...Signer) error {
+    if len(signers) == 0 {
+        return ErrInvalidMessage
+    }
+
+    body, err := m.protected()
+    if err != nil {
+        return err
+    }
+
+    for _, signer := range signers {
+        s := &Signature{
+            Protected:   Headers{},
+            Unprotected: Headers{},
+        }
+
+        sign, err := prepareProtected(&s.Protected, signer.Algorithm())
+        if err != nil {
+            return err
+        }
+
+        toBeSigned, err := sigStructure("Signature", body, sign, external, m.Payload)
+        if err != nil {
+            return err
+        }
+
+        if s.Signature, err = signer.Sign(rand, toBeSigned); err != nil {
+            return err
+        }
+
+        s.rawProtected = sign
+        m.Signatures = append(m.Signatures, s)
+    }
+
+    m.rawProtected = body
+
+    return nil
+}
+
+// Verify checks that one of the signatures with the algorithm of the
+// verifier is valid.
+func (m *SignMessage) Verify(external []byte, verifier Verifier) error {
+    body, err := m.protected()
+    if err != nil {
+        return err
+    }
+
+    for _, s := range m.Signatures {
+        alg, err := findAlgorithm(s.Protected, s.Unprotected)
+        if err != nil || alg != verifier.Algorithm() {
+            continue
+        }
+
+        sign, err := s.protected()
+        if err != nil {
+            return err
+        }
+
+        toBeSigned, err := sigStructure("Signature", body, sign, external, m.Payload)
+        if err != nil {
+            return err
+        }
+
+        if verifier.Verify(toBeSigned, s.Signature) == nil {
+            return nil
+        }
+    }
+
+    return ErrVerification
+}
+
+func (m *SignMessage) protected() ([]byte, error) {
+    if m.rawProtected != nil {
+        return m.rawProtected, nil
+    }
+
+    return encodeProtected(m.Protected)
+}
+
+// Marshal returns the tagged CBOR encoding of the message.
+func (m *SignMessage) Marshal() ([]byte, error) {
+    if len(m.Signatures) == 0 {
+        return nil, ErrInvalidMessage
+    }
+
+    protected, err := m.protected()
+    if err != nil {
+        return nil, err
+    }
+
+    sigs := make([]any, 0, len(m.Signatures))
+    for _, s := range m.Signatures {
+        sp, err := s.protected()
+        if err != nil {
+            return nil, err
+        }
+
+        sigs = append(sigs, []any{
+            sp,
+            unprotectedMap(s.Unprotected),
+            s.Signature,
+        })
+    }
+
+    return cbor.Marshal(cbor.Tag{
+        Number: TagSign,
+        Content: []any{
+            protected,
+            unprotectedMap(m.Unprotected),
+            encodePayload(m.Payload),
+            sigs,
+        },
+    })
+}
+
+// ParseSignMessage parses a tagged or untagged COSE_Sign message.
+func ParseSignMessage(data []byte) (*SignMessage, error) {
+    arr, err := decodeMessage(data, TagSign, 4)
+    if err != nil {
+        return nil, err
+    }
+
+    m := &SignMessage{}
+
+    m.Protected, m.rawProtected, err = decodeProtected(arr[0])
+    if err != nil {
+        return nil, err
+    }
+
+    if m.Unprotected, err = decodeUnprotected(arr[1]); err != nil {
+        return nil, err
+    }
+
+    if m.Payload, err = decodePayload(arr[2]); err != nil {
+        return nil, err
+    }
+
+    sigs, ok := arr[3].([]any)
+    if !ok || len(sigs) == 0 {
+        return nil, ErrInvalidMessage
+    }
+
+    for _, v := range sigs {
+        sa, ok := v.([]any)
+        if !ok || len(sa) != 3 {
+            return nil, ErrInvalidMessage
+        }
+
+        s := &Signature{}
+
+        s.Protected, s.rawProtected, err = decodeProtected(sa[0])
+        if err != nil {
+            return nil, err
+        }
+
+        if s.Unprotected, err = decodeUnprotected(sa[1]); err != nil {
+            return nil, err
+        }
+
+        if s.Signature, ok = sa[2].([]byte); !ok {
+            return nil, ErrInvalidMessage
+        }
+
+        m.Signatures = append(m.Signatures, s)
+    }
+
+    return m, nil
+}
+
+// sigStructure 生成待签名数据. See RFC 9052 section 4.4.
+func sigStructure(context string, body, sign, external, payload []byte) ([]byte, error) {
+    if external == nil {
+        external = []byte{}
+    }
+    if payload == nil {
+        payload = []byte{}
+    }
+
+    s := []any{context, body}
+    if context == "Signature" {
+        s = append(s, sign)
+    }
+
+    s = append(s, external, payload)
+
+    return cbor.Marshal(s)
+}
+
+// prepareProtected 设置算法到保护头并编码
+func prepareProtected(h *Headers, alg Algorithm) ([]byte, error) {
+    if *h == nil {
+        *h = Headers{}
+    }
+
+    if got, ok := h.Algorithm(); ok {
+        if got != alg {
+            return nil, ErrAlgorithmMismatch
+        }
+    } else {
+        (*h)[HeaderLabelAlgorithm] = int64(alg)
+    }
+
+    return encodeProtected(*h)
+}
+
+// checkAlgorithm 检测消息的算法
+func checkAlgorithm(protected, unprotected Headers, alg Algorithm) error {
+    got, err := findAlgorithm(protected, unprotected)
+    if err != nil {
+        return err
+    }
+
+    if got != alg {
+        return ErrAlgorithmMismatch
+    }
+
+    return nil
+}
+
+// 空的非保护头编码为空 map
+func unprotectedMap(h Headers) map[any]any {
+    if h == nil {
+        return map[any]any{}
+    }
+
+    return map[any]any(h)
+}
@@ -0,0 +1,276 @@
+package cose
+
+import (
+    "io"
+    "errors"
+    "math/big"
+    "crypto"
+    "crypto/rsa"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "encoding/asn1"
+
+    "github.com/deatil/go-cryptobin/pubkey/ed448"
+)
+
+// Signer signs the Sig_structure of COSE messages.
+type Signer interface {
+    // 签名算法
+    Algorithm() Algorithm
+
+    // 签名数据
+    Sign(rand io.Reader, content []byte) ([]byte, error)
+}
+
+// Verifier verifies the signatures of COSE messages.
+type Verifier interface {
+    // 签名算法
+    Algorithm() Algorithm
+
+    // 验证签名
+    Verify(content, signature []byte) error
+}
+
+// 签名算法的摘要方式
+func algorithmHash(alg Algorithm) crypto.Hash {
+    switch alg {
+        case AlgorithmES256, AlgorithmPS256:
+            return crypto.SHA256
+        case AlgorithmES384, AlgorithmPS384:
+            return crypto.SHA384
+        case AlgorithmES512, AlgorithmPS512:
+            return crypto.SHA512
+    }
+
+    return 0
+}
+
+func digest(hash crypto.Hash, content []byte) []byte {
+    h := hash.New()
+    h.Write(content)
+    return h.Sum(nil)
+}
+
+// NewSigner returns a Signer for the algorithm and the private key.
+// ES256/ES384/ES512 need an ECDSA key, PS256/PS384/PS512 need a RSA key and
+// EdDSA needs an ed25519.PrivateKey or ed448.PrivateKey.
+func NewSigner(alg Algorithm, key crypto.Signer) (Signer, error) {
+    switch alg {
+        case AlgorithmES256, AlgorithmES384, AlgorithmES512:
+            pub, ok := key.Public().(*ecdsa.PublicKey)
+            if !ok {
+                return nil, ErrInvalidKey
+            }
+
+            if err := checkCurve(alg, pub); err != nil {
+                return nil, err
+            }
+
+            return &ecdsaSigner{alg, key, (pub.Curve.Params().BitSize + 7) / 8}, nil
+        case AlgorithmPS256, AlgorithmPS384, AlgorithmPS512:
+            if _, ok := key.Public().(*rsa.PublicKey); !ok {
+                return nil, ErrInvalidKey
+            }
+
+            return &rsaSigner{alg, key}, nil
+        case AlgorithmEdDSA:
+            switch key.Public().(type) {
+                case ed25519.PublicKey, ed448.PublicKey:
+                    return &edSigner{key}, nil
+            }
+
+            return nil, ErrInvalidKey
+    }
+
+    return nil, ErrAlgorithmNotSupported
+}
+
+// NewVerifier returns a Verifier for the algorithm and the public key.
+func NewVerifier(alg Algorithm, key crypto.PublicKey) (Verifier, error) {
+    switch alg {
+        case AlgorithmES256, AlgorithmES384, AlgorithmES512:
+            pub, ok := key.(*ecdsa.PublicKey)
+            if !ok {
+                return nil, ErrInvalidKey
+            }
+
+            if err := checkCurve(alg, pub); err != nil {
+                return nil, err
+            }
+
+            return &ecdsaVerifier{alg, pub}, nil
+        case AlgorithmPS256, AlgorithmPS384, AlgorithmPS512:
+            pub, ok := key.(*rsa.PublicKey)
+            if !ok {
+                return nil, ErrInvalidKey
+            }
+
+            return &rsaVerifier{alg, pub}, nil
+        case AlgorithmEdDSA:
+            switch key.(type) {
+                case ed25519.PublicKey, ed448.PublicKey:
+                    return &edVerifier{key}, nil
+            }
+
+            return nil, ErrInvalidKey
+    }
+
+    return nil, ErrAlgorithmNotSupported
+}
+
+// 检测曲线和算法是否匹配
+func checkCurve(alg Algorithm, pub *ecdsa.PublicKey) error {
+    crv, _, err := curveFromElliptic(pub.Curve)
+    if err != nil {
+        return err
+    }
+
+    switch {
+        case alg == AlgorithmES256 && crv == CurveP256,
+            alg == AlgorithmES384 && crv == CurveP384,
+            alg == AlgorithmES512 && crv == CurveP521:
+            return nil
+    }
+
+    return ErrAlgorithmMismatch
+}
+
+type ecdsaSigner struct {
+    alg  Algorithm
+    key  crypto.Signer
+    size int
+}
+
+func (s *ecdsaSigner) Algorithm() Algorithm {
+    return s.alg
+}
+
+// Sign 签名结果为 r || s
+func (s *ecdsaSigner) Sign(rand io.Reader, content []byte) ([]byte, error) {
+    hash := algorithmHash(s.alg)
+
+    der, err := s.key.Sign(rand, digest(hash, content), hash)
+    if err != nil {
+        return nil, err
+    }
+
+    var sig struct {
+        R, S *big.Int
+    }
+
+    rest, err := asn1.Unmarshal(der, &sig)
+    if err != nil {
+        return nil, err
+    }
+    if len(rest) > 0 {
+        return nil, errors.New("go-cryptobin/cose: trailing data after ECDSA signature")
+    }
+
+    out := make([]byte, 2*s.size)
+    sig.R.FillBytes(out[:s.size])
+    sig.S.FillBytes(out[s.size:])
+
+    return out, nil
+}
+
+type ecdsaVerifier struct {
+    alg Algorithm
+    key *ecdsa.PublicKey
+}
+
+func (v *ecdsaVerifier) Algorithm() Algorithm {
+    return v.alg
+}
+
+func (v *ecdsaVerifier) Verify(content, signature []byte) error {
+    size := (v.key.Curve.Params().BitSize + 7) / 8
+    if len(signature) != 2*size {
+        return ErrVerification
+    }
+
+    r := new(big.Int).SetBytes(signature[:size])
+    s := new(big.Int).SetBytes(signature[size:])
+
+    if !ecdsa.Verify(v.key, digest(algorithmHash(v.alg), content), r, s) {
+        return ErrVerification
+    }
+
+    return nil
+}
+
+type rsaSigner struct {
+    alg Algorithm
+    key crypto.Signer
+}
+
+func (s *rsaSigner) Algorithm() Algorithm {
+    return s.alg
+}
+
+func (s *rsaSigner) Sign(rand io.Reader, content []byte) ([]byte, error) {
+    hash := algorithmHash(s.alg)
+
+    return s.key.Sign(rand, digest(hash, content), &rsa.PSSOptions{
+        SaltLength: rsa.PSSSaltLengthEqualsHash,
+        Hash:       hash,
+    })
+}
+
+type rsaVerifier struct {
+    alg Algorithm
+    key *rsa.PublicKey
+}
+
+func (v *rsaVerifier) Algorithm() Algorithm {
+    return v.alg
+}
+
+func (v *rsaVerifier) Verify(content, signature []byte) error {
+    hash := algorithmHash(v.alg)
+
+    err := rsa.VerifyPSS(v.key, hash, digest(hash, content), signature, &rsa.PSSOptions{
+        SaltLength: rsa.PSSSaltLengthEqualsHash,
+    })
+    if err != nil {
+        return ErrVerification
+    }
+
+    return nil
+}
+
+type edSigner struct {
+    key crypto.Signer
+}
+
+func (s *edSigner) Algorithm() Algorithm {
+    return AlgorithmEdDSA
+}
+
+func (s *edSigner) Sign(rand io.Reader, content []byte) ([]byte, error) {
+    return s.key.Sign(rand, content, crypto.Hash(0))
+}
+
+type edVerifier struct {
+    key crypto.PublicKey
+}
+
+func (v *edVerifier) Algorithm() Algorithm {
+    return AlgorithmEdDSA
+}
+
+func (v *edVerifier) Verify(content, signature []byte) error {
+    var ok bool
+
+    switch key := v.key.(type) {
+        case ed25519.PublicKey:
+            ok = ed25519.Verify(key, content, signature)
+        case ed448.PublicKey:
+            ok = ed448.Verify(key, content, signature)
+    }
+
+    if !ok {
+        return ErrVerification
+    }
+
+    return nil
+}
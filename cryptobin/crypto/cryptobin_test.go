@@ -7,6 +7,8 @@ import (
     "encoding/hex"
     "encoding/base64"
 
+    "github.com/deatil/go-cryptobin/mode/gcmsiv"
+    "github.com/deatil/go-cryptobin/cipher/serpent"
     cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
 )
 
@@ -169,6 +171,34 @@ func Test_XtsPKCS5Padding(t *testing.T) {
     assert(data, cyptdeStr, "XtsPKCS5Padding")
 }
 
+func Test_XtsGB_NoPadding(t *testing.T) {
+    assert := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    // 非分组整数倍时使用密文窃取
+    data := "test-passtest-passtest-pass"
+    cypt := FromString(data).
+        SetKey("1234567890abcdef1234567890abcdef").
+        XtsGB("SM4", 0x3333333333).
+        NoPadding().
+        Encrypt()
+    cyptStr := cypt.ToHexString()
+
+    assertNoError(cypt.Error(), "XtsGB_NoPadding-Encode")
+    assert(len(data), len(cypt.ToBytes()), "XtsGB_NoPadding-Encode")
+
+    cyptde := FromHexString(cyptStr).
+        SetKey("1234567890abcdef1234567890abcdef").
+        NoPadding().
+        XtsGB("SM4", 0x3333333333).
+        Decrypt()
+    cyptdeStr := cyptde.ToString()
+
+    assertNoError(cyptde.Error(), "XtsGB_NoPadding-Decode")
+
+    assert(data, cyptdeStr, "XtsGB_NoPadding")
+}
+
 func Test_AesCFB1PKCS7Padding(t *testing.T) {
     assert := cryptobin_test.AssertEqualT(t)
     assertNoError := cryptobin_test.AssertNoErrorT(t)
@@ -1791,6 +1821,114 @@ func Test_Chacha20poly1305X(t *testing.T) {
     assert(data, cyptdeStr, "Test_Chacha20poly1305X")
 }
 
+func Test_Aegis128L(t *testing.T) {
+    assert := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    data := "test-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-pass"
+    cypt := FromString(data).
+        SetKey("dfertf12dfdfertf").
+        SetIv("jifu87ujasefjifu").
+        Aegis128L([]byte("test123")).
+        Encrypt()
+    cyptStr := cypt.ToBase64String()
+
+    assertNoError(cypt.Error(), "Test_Aegis128L-Encode")
+
+    cyptde := FromBase64String(cyptStr).
+        SetKey("dfertf12dfdfertf").
+        SetIv("jifu87ujasefjifu").
+        Aegis128L([]byte("test123")).
+        Decrypt()
+    cyptdeStr := cyptde.ToString()
+
+    assertNoError(cyptde.Error(), "Test_Aegis128L-Decode")
+
+    assert(data, cyptdeStr, "Test_Aegis128L")
+
+    cyptde2 := FromBase64String(cyptStr).
+        SetKey("dfertf12dfdfertf").
+        SetIv("jifu87ujasefjifu").
+        Aegis128L([]byte("test1234")).
+        Decrypt()
+
+    if cyptde2.Error() == nil {
+        t.Error("Test_Aegis128L: Decrypt with wrong additional should fail")
+    }
+}
+
+func Test_Aegis256(t *testing.T) {
+    assert := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    data := "test-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-pass"
+    cypt := FromString(data).
+        SetKey("dfertf12dfdfertf12dfdfertf12df12").
+        SetIv("jifu87ujasefjifu87ujasefjifu87uj").
+        Aegis256([]byte("test123")).
+        Encrypt()
+    cyptStr := cypt.ToBase64String()
+
+    assertNoError(cypt.Error(), "Test_Aegis256-Encode")
+
+    cyptde := FromBase64String(cyptStr).
+        SetKey("dfertf12dfdfertf12dfdfertf12df12").
+        SetIv("jifu87ujasefjifu87ujasefjifu87uj").
+        Aegis256([]byte("test123")).
+        Decrypt()
+    cyptdeStr := cyptde.ToString()
+
+    assertNoError(cyptde.Error(), "Test_Aegis256-Decode")
+
+    assert(data, cyptdeStr, "Test_Aegis256")
+
+    cyptde2 := FromBase64String(cyptStr).
+        SetKey("dfertf12dfdfertf12dfdfertf12df12").
+        SetIv("jifu87ujasefjifu87ujasefjifu87uj").
+        Aegis256([]byte("test1234")).
+        Decrypt()
+
+    if cyptde2.Error() == nil {
+        t.Error("Test_Aegis256: Decrypt with wrong additional should fail")
+    }
+}
+
+func Test_DeoxysII(t *testing.T) {
+    assert := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    data := "test-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-passtest-pass"
+    cypt := FromString(data).
+        SetKey("dfertf12dfdfertf12dfdfertf12df12").
+        SetIv("jifu87ujasefjif").
+        DeoxysII([]byte("test123")).
+        Encrypt()
+    cyptStr := cypt.ToBase64String()
+
+    assertNoError(cypt.Error(), "Test_DeoxysII-Encode")
+
+    cyptde := FromBase64String(cyptStr).
+        SetKey("dfertf12dfdfertf12dfdfertf12df12").
+        SetIv("jifu87ujasefjif").
+        DeoxysII([]byte("test123")).
+        Decrypt()
+    cyptdeStr := cyptde.ToString()
+
+    assertNoError(cyptde.Error(), "Test_DeoxysII-Decode")
+
+    assert(data, cyptdeStr, "Test_DeoxysII")
+
+    cyptde2 := FromBase64String(cyptStr).
+        SetKey("dfertf12dfdfertf12dfdfertf12df12").
+        SetIv("jifu87ujasefjif").
+        DeoxysII([]byte("test1234")).
+        Decrypt()
+
+    if cyptde2.Error() == nil {
+        t.Error("Test_DeoxysII: Decrypt with wrong additional should fail")
+    }
+}
+
 func Test_Aes_CCM_PKCS7Padding(t *testing.T) {
     assert := cryptobin_test.AssertEqualT(t)
     assertNoError := cryptobin_test.AssertNoErrorT(t)
@@ -2000,3 +2138,80 @@ func Test_Aes_GCMWithTagSize_NoPadding(t *testing.T) {
 
     assert(data, cyptdeStr, "Test_Aes_GCMWithTagSize_NoPadding")
 }
+
+func Test_GCMSIV(t *testing.T) {
+    assert := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    // RFC 8452 Appendix C.1
+    key, _ := hex.DecodeString("01000000000000000000000000000000")
+    iv, _ := hex.DecodeString("030000000000000000000000")
+    plain, _ := hex.DecodeString("0100000000000000")
+
+    cypt := New().FromBytes(plain).
+        WithKey(key).
+        WithIv(iv).
+        Aes().
+        GCMSIV().
+        NoPadding().
+        Encrypt()
+
+    assertNoError(cypt.Error(), "Test_GCMSIV-Encode")
+    assert("b5d839330ac7b786578782fff6013b815b287c22493a364c", cypt.ToHexString(), "Test_GCMSIV-Encode")
+
+    data := "test-passtest-passtest-pass"
+    additional := []byte("test-additional")
+
+    for _, c := range []Cryptobin{New().SM4(), New().Aria(), New().Camellia(), New().Twofish(), New().Serpent()} {
+        cypt := c.FromString(data).
+            SetKey("dfertf12dfertf12").
+            SetIv("dfertf12dfe1").
+            GCMSIV(additional).
+            NoPadding().
+            Encrypt()
+        assertNoError(cypt.Error(), "Test_GCMSIV-Encode")
+
+        cyptde := c.FromBytes(cypt.ToBytes()).
+            SetKey("dfertf12dfertf12").
+            SetIv("dfertf12dfe1").
+            GCMSIV(additional).
+            NoPadding().
+            Decrypt()
+        assertNoError(cyptde.Error(), "Test_GCMSIV-Decode")
+
+        assert(data, cyptde.ToString(), "Test_GCMSIV")
+    }
+
+    cypt = New().FromString(data).
+        SetKey("dfertf12dfertf12").
+        SetIv("dfertf12dfe1").
+        Des().
+        GCMSIV().
+        Encrypt()
+    if cypt.Error() == nil {
+        t.Error("Test_GCMSIV should fail with Des")
+    }
+}
+
+func Test_GCMSIVWithBlock(t *testing.T) {
+    assert := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    key := []byte("dfertf12dfertf12")
+    iv := []byte("dfertf12dfe1")
+    data := []byte("test-passtest-passtest-pass")
+    additional := []byte("test-additional")
+
+    aead, err := gcmsiv.NewGCMSIV(serpent.NewCipher, key)
+    assertNoError(err, "Test_GCMSIVWithBlock")
+
+    cypt := New().FromBytes(data).
+        WithKey(key).
+        WithIv(iv).
+        Serpent().
+        GCMSIV(additional).
+        NoPadding().
+        Encrypt()
+    assertNoError(cypt.Error(), "Test_GCMSIVWithBlock-Encode")
+    assert(aead.Seal(nil, iv, data, additional), cypt.ToBytes(), "Test_GCMSIVWithBlock-Encode")
+}
@@ -6,6 +6,7 @@ import (
 
     "github.com/deatil/go-cryptobin/mode/ccm"
     "github.com/deatil/go-cryptobin/mode/hctr"
+    "github.com/deatil/go-cryptobin/mode/gcmsiv"
     "github.com/deatil/go-cryptobin/tool/utils"
     cryptobin_mode "github.com/deatil/go-cryptobin/mode"
 )
@@ -524,3 +525,101 @@ func init() {
         return ModeHCTR{}
     })
 }
+
+// ===================
+
+type ModeGCMSIV struct {}
+
+// 加密 / Encrypt
+func (this ModeGCMSIV) Encrypt(plain []byte, block cipher.Block, opt IOption) ([]byte, error) {
+    aead, err := this.newAEAD(block, opt)
+    if err != nil {
+        return nil, err
+    }
+
+    iv := opt.Iv()
+    if len(iv) != aead.NonceSize() {
+        return nil, errors.New("go-cryptobin/crypto: GCMSIV iv length must be 12")
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    cryptText := aead.Seal(nil, iv, plain, additional)
+
+    return cryptText, nil
+}
+
+// 解密 / Decrypt
+func (this ModeGCMSIV) Decrypt(data []byte, block cipher.Block, opt IOption) ([]byte, error) {
+    aead, err := this.newAEAD(block, opt)
+    if err != nil {
+        return nil, err
+    }
+
+    iv := opt.Iv()
+    if len(iv) != aead.NonceSize() {
+        return nil, errors.New("go-cryptobin/crypto: GCMSIV iv length must be 12")
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    return aead.Open(nil, iv, data, additional)
+}
+
+// GCM-SIV 每个 nonce 派生新密钥, 使用当前加密类型创建分组
+func (this ModeGCMSIV) newAEAD(block cipher.Block, opt IOption) (cipher.AEAD, error) {
+    if _, ok := opt.(gcmsivKeyOption); ok {
+        return nil, errGCMSIVBlock{block}
+    }
+
+    if block.BlockSize() != 16 {
+        return nil, errors.New("go-cryptobin/crypto: GCMSIV requires 128-bit block cipher")
+    }
+
+    newEncrypt, err := getEncrypt(opt.Multiple())
+    if err != nil {
+        return nil, err
+    }
+
+    newCipher := func(key []byte) (cipher.Block, error) {
+        _, err := newEncrypt.Encrypt(nil, gcmsivKeyOption{opt, key})
+
+        b, ok := err.(errGCMSIVBlock)
+        if !ok {
+            if err == nil {
+                err = errors.New("go-cryptobin/crypto: GCMSIV requires block cipher")
+            }
+
+            return nil, err
+        }
+
+        return b.block, nil
+    }
+
+    return gcmsiv.NewGCMSIV(newCipher, opt.Key())
+}
+
+// 派生密钥时替换密钥, 用于取得对应的分组
+type gcmsivKeyOption struct {
+    IOption
+    key []byte
+}
+
+func (this gcmsivKeyOption) Key() []byte {
+    return this.key
+}
+
+// 取得分组后通过错误返回, 中断加密流程
+type errGCMSIVBlock struct {
+    block cipher.Block
+}
+
+func (this errGCMSIVBlock) Error() string {
+    return "go-cryptobin/crypto: GCMSIV block"
+}
+
+func init() {
+    UseMode.Add(GCMSIV, func() IMode {
+        return ModeGCMSIV{}
+    })
+}
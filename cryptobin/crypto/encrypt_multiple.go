@@ -9,7 +9,6 @@ import (
     "crypto/rc4"
     "crypto/cipher"
 
-    "golang.org/x/crypto/xts"
     "golang.org/x/crypto/tea"
     "golang.org/x/crypto/xtea"
     "golang.org/x/crypto/cast5"
@@ -19,6 +18,7 @@ import (
     "golang.org/x/crypto/chacha20poly1305"
 
     "github.com/deatil/go-cryptobin/cipher/sm4"
+    "github.com/deatil/go-cryptobin/mode/xts"
     "github.com/deatil/go-cryptobin/cipher/rc2"
     "github.com/deatil/go-cryptobin/cipher/rc5"
     "github.com/deatil/go-cryptobin/cipher/rc6"
@@ -42,6 +42,8 @@ import (
     "github.com/deatil/go-cryptobin/cipher/rijndael"
     "github.com/deatil/go-cryptobin/cipher/twine"
     "github.com/deatil/go-cryptobin/cipher/misty1"
+    "github.com/deatil/go-cryptobin/cipher/aegis"
+    "github.com/deatil/go-cryptobin/cipher/deoxysii"
     cryptobin_des "github.com/deatil/go-cryptobin/cipher/des"
     tool_cipher "github.com/deatil/go-cryptobin/tool/cipher"
 )
@@ -765,7 +767,7 @@ func (this EncryptRC4MD5) Decrypt(data []byte, opt IOption) ([]byte, error) {
 
 // ===================
 
-// Sectors must be a multiple of 16 bytes and less than 2²⁴ bytes.
+// Sectors must be at least 16 bytes, the last block uses ciphertext stealing.
 type EncryptXts struct {}
 
 // 加密 / Encrypt
@@ -788,7 +790,7 @@ func (this EncryptXts) Encrypt(data []byte, opt IOption) ([]byte, error) {
         return nil, err
     }
 
-    xc, err := xts.NewCipher(cip, opt.Key())
+    xc, err := newXtsCipher(cip, opt)
     if err != nil {
         return nil, err
     }
@@ -804,6 +806,10 @@ func (this EncryptXts) Encrypt(data []byte, opt IOption) ([]byte, error) {
 
     // 补码数据
     plainPadding := newPadding.Padding(data, bs, opt)
+    if len(plainPadding) < bs {
+        err := fmt.Errorf("go-cryptobin/crypto: xts data is smaller than the block size.")
+        return nil, err
+    }
 
     dst := make([]byte, len(plainPadding))
 
@@ -832,11 +838,16 @@ func (this EncryptXts) Decrypt(data []byte, opt IOption) ([]byte, error) {
         return nil, err
     }
 
-    xc, err := xts.NewCipher(cip, opt.Key())
+    xc, err := newXtsCipher(cip, opt)
     if err != nil {
         return nil, err
     }
 
+    if len(data) < 16 {
+        err := fmt.Errorf("go-cryptobin/crypto: xts data is smaller than the block size.")
+        return nil, err
+    }
+
     dst := make([]byte, len(data))
 
     xc.Decrypt(dst, data, sectorNum)
@@ -856,6 +867,16 @@ func (this EncryptXts) Decrypt(data []byte, opt IOption) ([]byte, error) {
     return dst, nil
 }
 
+
+// gb 为 true 时使用 GB/T 17964-2021 的 tweak 计算
+func newXtsCipher(cip xts.NewCipherFunc, opt IOption) (*xts.Cipher, error) {
+    if opt.Config().GetBool("gb") {
+        return xts.NewGBCipher(cip, opt.Key())
+    }
+
+    return xts.NewCipher(cip, opt.Key())
+}
+
 // ===================
 
 // Seed key is 16 bytes.
@@ -1649,3 +1670,147 @@ func init() {
         return EncryptMisty1{}
     })
 }
+
+// ===================
+
+// 16 bytes key and 16 bytes nonce
+type EncryptAegis128L struct {}
+
+// 加密 / Encrypt
+func (this EncryptAegis128L) Encrypt(data []byte, opt IOption) ([]byte, error) {
+    aead, err := aegis.New128L(opt.Key(), aegis.TagSize)
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := opt.Iv()
+    if len(nonce) != aead.NonceSize() {
+        err := fmt.Errorf("go-cryptobin/crypto: bad nonce length passed to Seal")
+        return nil, err
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    dst := aead.Seal(nil, nonce, data, additional)
+    return dst, nil
+}
+
+// 解密 / Decrypt
+func (this EncryptAegis128L) Decrypt(data []byte, opt IOption) ([]byte, error) {
+    aead, err := aegis.New128L(opt.Key(), aegis.TagSize)
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := opt.Iv()
+    if len(nonce) != aead.NonceSize() {
+        err := fmt.Errorf("go-cryptobin/crypto: bad nonce length passed to Open")
+        return nil, err
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    return aead.Open(nil, nonce, data, additional)
+}
+
+func init() {
+    UseEncrypt.Add(Aegis128L, func() IEncrypt {
+        return EncryptAegis128L{}
+    })
+}
+
+// ===================
+
+// 32 bytes key and 32 bytes nonce
+type EncryptAegis256 struct {}
+
+// 加密 / Encrypt
+func (this EncryptAegis256) Encrypt(data []byte, opt IOption) ([]byte, error) {
+    aead, err := aegis.New256(opt.Key(), aegis.TagSize)
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := opt.Iv()
+    if len(nonce) != aead.NonceSize() {
+        err := fmt.Errorf("go-cryptobin/crypto: bad nonce length passed to Seal")
+        return nil, err
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    dst := aead.Seal(nil, nonce, data, additional)
+    return dst, nil
+}
+
+// 解密 / Decrypt
+func (this EncryptAegis256) Decrypt(data []byte, opt IOption) ([]byte, error) {
+    aead, err := aegis.New256(opt.Key(), aegis.TagSize)
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := opt.Iv()
+    if len(nonce) != aead.NonceSize() {
+        err := fmt.Errorf("go-cryptobin/crypto: bad nonce length passed to Open")
+        return nil, err
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    return aead.Open(nil, nonce, data, additional)
+}
+
+func init() {
+    UseEncrypt.Add(Aegis256, func() IEncrypt {
+        return EncryptAegis256{}
+    })
+}
+
+// ===================
+
+// 32 bytes key and 15 bytes nonce
+type EncryptDeoxysII struct {}
+
+// 加密 / Encrypt
+func (this EncryptDeoxysII) Encrypt(data []byte, opt IOption) ([]byte, error) {
+    aead, err := deoxysii.New(opt.Key())
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := opt.Iv()
+    if len(nonce) != aead.NonceSize() {
+        err := fmt.Errorf("go-cryptobin/crypto: bad nonce length passed to Seal")
+        return nil, err
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    dst := aead.Seal(nil, nonce, data, additional)
+    return dst, nil
+}
+
+// 解密 / Decrypt
+func (this EncryptDeoxysII) Decrypt(data []byte, opt IOption) ([]byte, error) {
+    aead, err := deoxysii.New(opt.Key())
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := opt.Iv()
+    if len(nonce) != aead.NonceSize() {
+        err := fmt.Errorf("go-cryptobin/crypto: bad nonce length passed to Open")
+        return nil, err
+    }
+
+    additional := opt.Config().GetBytes("additional")
+
+    return aead.Open(nil, nonce, data, additional)
+}
+
+func init() {
+    UseEncrypt.Add(DeoxysII, func() IEncrypt {
+        return EncryptDeoxysII{}
+    })
+}
@@ -148,6 +148,12 @@ func (this Multiple) String() string {
             return "Twine"
         case Misty1:
             return "Misty1"
+        case Aegis128L:
+            return "Aegis128L"
+        case Aegis256:
+            return "Aegis256"
+        case DeoxysII:
+            return "DeoxysII"
         default:
             if TypeMultiple.Names().Has(this) {
                 return (TypeMultiple.Names().Get(this))()
@@ -200,6 +206,9 @@ const (
     Rijndael256
     Twine
     Misty1
+    Aegis128L
+    Aegis256
+    DeoxysII
     maxMultiple
 )
 
@@ -241,6 +250,8 @@ func (this Mode) String() string {
             return "BC"
         case HCTR:
             return "HCTR"
+        case GCMSIV:
+            return "GCMSIV"
         default:
             if TypeMode.Names().Has(this) {
                 return (TypeMode.Names().Get(this))()
@@ -266,6 +277,7 @@ const (
     CCM
     BC
     HCTR
+    GCMSIV
     maxMode
 )
 
@@ -165,6 +165,19 @@ func (this Cryptobin) Xts(cipher string, sectorNum uint64) Cryptobin {
 
     this.config.Set("cipher", cipher)
     this.config.Set("sector_num", sectorNum)
+    this.config.Set("gb", false)
+
+    return this
+}
+
+// XtsGB
+// GB/T 17964-2021 XTS, cipher 一般为 SM4
+func (this Cryptobin) XtsGB(cipher string, sectorNum uint64) Cryptobin {
+    this.multiple = Xts
+
+    this.config.Set("cipher", cipher)
+    this.config.Set("sector_num", sectorNum)
+    this.config.Set("gb", true)
 
     return this
 }
@@ -367,6 +380,42 @@ func (this Cryptobin) Misty1() Cryptobin {
     return this
 }
 
+// Aegis128L
+// key and nonce are 16 bytes
+func (this Cryptobin) Aegis128L(additional ...[]byte) Cryptobin {
+    this.multiple = Aegis128L
+
+    if len(additional) > 0 {
+        this.config.Set("additional", additional[0])
+    }
+
+    return this
+}
+
+// Aegis256
+// key and nonce are 32 bytes
+func (this Cryptobin) Aegis256(additional ...[]byte) Cryptobin {
+    this.multiple = Aegis256
+
+    if len(additional) > 0 {
+        this.config.Set("additional", additional[0])
+    }
+
+    return this
+}
+
+// DeoxysII
+// key is 32 bytes and nonce is 15 bytes
+func (this Cryptobin) DeoxysII(additional ...[]byte) Cryptobin {
+    this.multiple = DeoxysII
+
+    if len(additional) > 0 {
+        this.config.Set("additional", additional[0])
+    }
+
+    return this
+}
+
 // 使用类型
 func (this Cryptobin) MultipleBy(multiple Multiple, cfg ...map[string]any) Cryptobin {
     this.multiple = multiple
@@ -572,6 +621,18 @@ func (this Cryptobin) CCMWithNonceAndTagSize(nonceSize, tagSize int, additional
     return this
 }
 
+// GCMSIV
+// iv 长度为 12
+func (this Cryptobin) GCMSIV(additional ...[]byte) Cryptobin {
+    this.mode = GCMSIV
+
+    if len(additional) > 0 {
+        this.config.Set("additional", additional[0])
+    }
+
+    return this
+}
+
 // BC
 func (this Cryptobin) BC() Cryptobin {
     this.mode = BC
@@ -0,0 +1,351 @@
+// Package dedup splits streams into content-defined chunks with
+// hash/rabin, seals every chunk with an AEAD and saves it in a Store
+// by a keyed BLAKE3 chunk ID. A Manifest lists the chunks to join
+// and verify the stream again.
+package dedup
+
+import (
+    "io"
+    "bytes"
+    "errors"
+    "crypto/aes"
+    "crypto/rand"
+    "crypto/cipher"
+    "crypto/subtle"
+
+    "github.com/deatil/go-cryptobin/hash/blake3"
+    "github.com/deatil/go-cryptobin/hash/rabin"
+)
+
+// key derivation contexts of the secret
+const (
+    contextID       = "go-cryptobin/dedup 2024 chunk id"
+    contextKey      = "go-cryptobin/dedup 2024 chunk key"
+    contextManifest = "go-cryptobin/dedup 2024 manifest key"
+)
+
+var (
+    ErrInvalidOpts   = errors.New("go-cryptobin/dedup: invalid options")
+    ErrChunkCorrupt  = errors.New("go-cryptobin/dedup: chunk is corrupt")
+    ErrStreamCorrupt = errors.New("go-cryptobin/dedup: stream does not match the manifest")
+)
+
+// NewAEADFunc returns an AEAD with the key
+type NewAEADFunc = func(key []byte) (cipher.AEAD, error)
+
+// NewGCM returns a NewAEADFunc of GCM over the block cipher
+func NewGCM(newCipher func(key []byte) (cipher.Block, error)) NewAEADFunc {
+    return func(key []byte) (cipher.AEAD, error) {
+        block, err := newCipher(key)
+        if err != nil {
+            return nil, err
+        }
+
+        return cipher.NewGCM(block)
+    }
+}
+
+// Opts is the options of chunking and sealing
+type Opts struct {
+    // Rabin hash table, the window must be bigger than 1 and
+    // not bigger than MinSize
+    Table *rabin.Table
+
+    // chunk sizes, AvgSize must be a power of two
+    MinSize int
+    AvgSize int
+    MaxSize int
+
+    KeyMode KeyMode
+
+    // AEAD to seal the chunks and the key size of it
+    NewAEAD NewAEADFunc
+    KeySize int
+
+    // random source of the RandomKey mode and of SealManifest
+    Rand io.Reader
+}
+
+// DefaultOpts chunks about 64KiB with AES-256-GCM and convergent keys
+var DefaultOpts = Opts{
+    Table:   rabin.NewTable(rabin.Poly64, 64),
+    MinSize: 16 << 10,
+    AvgSize: 64 << 10,
+    MaxSize: 256 << 10,
+    KeyMode: Convergent,
+    NewAEAD: NewGCM(aes.NewCipher),
+    KeySize: 32,
+    Rand:    rand.Reader,
+}
+
+// Dedup splits and joins streams
+type Dedup struct {
+    opts Opts
+
+    idKey       []byte
+    chunkKey    []byte
+    manifestKey []byte
+}
+
+// New returns a Dedup with the secret and options.
+// Streams split with a secret can only be joined with the same secret.
+func New(secret []byte, opts Opts) (*Dedup, error) {
+    if opts.Table == nil ||
+        opts.MinSize <= 0 ||
+        opts.MaxSize < opts.MinSize ||
+        opts.AvgSize <= 0 ||
+        opts.AvgSize & (opts.AvgSize - 1) != 0 ||
+        opts.NewAEAD == nil ||
+        opts.KeySize <= 0 {
+        return nil, ErrInvalidOpts
+    }
+
+    if opts.KeyMode != Convergent && opts.KeyMode != RandomKey {
+        return nil, ErrInvalidOpts
+    }
+
+    // 没有窗口时 BlockSize 返回 1, 分块需要滑动窗口
+    window := rabin.New(opts.Table).BlockSize()
+    if window <= 1 || window > opts.MinSize {
+        return nil, ErrInvalidOpts
+    }
+
+    if opts.Rand == nil {
+        opts.Rand = rand.Reader
+    }
+
+    d := &Dedup{
+        opts:        opts,
+        idKey:       blake3.DeriveKey(contextID, secret, blake3.KeySize),
+        chunkKey:    blake3.DeriveKey(contextKey, secret, blake3.KeySize),
+        manifestKey: blake3.DeriveKey(contextManifest, secret, opts.KeySize),
+    }
+
+    return d, nil
+}
+
+func (d *Dedup) keyedSum(key []byte, data []byte, size int) []byte {
+    out, _ := blake3.SumKeyed(key, data, size)
+    return out
+}
+
+// Split chunks r, saves the sealed chunks in store and
+// returns the Manifest of the stream.
+func (d *Dedup) Split(r io.Reader, store Store) (*Manifest, error) {
+    // the chunker only reports lengths, keep the bytes it reads
+    var buf bytes.Buffer
+    c := rabin.NewChunker(d.opts.Table, io.TeeReader(r, &buf), d.opts.MinSize, d.opts.AvgSize, d.opts.MaxSize)
+
+    sum, _ := blake3.NewKeyed(d.idKey, blake3.Size)
+
+    m := &Manifest{
+        Version: manifestVersion,
+        KeyMode: d.opts.KeyMode,
+    }
+
+    // chunks of this stream by the keyed hash of the plaintext
+    seen := make(map[string]Chunk)
+
+    for {
+        n, err := c.Next()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            return nil, err
+        }
+
+        if n == 0 {
+            continue
+        }
+
+        data := buf.Next(n)
+        sum.Write(data)
+
+        chunk, err := d.putChunk(data, store, seen)
+        if err != nil {
+            return nil, err
+        }
+
+        m.Chunks = append(m.Chunks, chunk)
+        m.Size += int64(n)
+    }
+
+    m.Sum = sum.Sum(nil)
+
+    return m, nil
+}
+
+func (d *Dedup) putChunk(data []byte, store Store, seen map[string]Chunk) (Chunk, error) {
+    plainID := d.keyedSum(d.idKey, data, blake3.Size)
+
+    if chunk, ok := seen[string(plainID)]; ok {
+        return chunk, nil
+    }
+
+    var key, nonce []byte
+    if d.opts.KeyMode == Convergent {
+        key = d.keyedSum(d.chunkKey, data, d.opts.KeySize)
+    } else {
+        key = make([]byte, d.opts.KeySize)
+        if _, err := io.ReadFull(d.opts.Rand, key); err != nil {
+            return Chunk{}, err
+        }
+    }
+
+    aead, err := d.opts.NewAEAD(key)
+    if err != nil {
+        return Chunk{}, err
+    }
+
+    // the key of a convergent chunk is only used for equal data,
+    // so the nonce can be fixed
+    nonce = make([]byte, aead.NonceSize())
+    if d.opts.KeyMode == RandomKey {
+        if _, err := io.ReadFull(d.opts.Rand, nonce); err != nil {
+            return Chunk{}, err
+        }
+    }
+
+    sealed := aead.Seal(nonce, nonce, data, nil)
+
+    id := plainID
+    if d.opts.KeyMode == RandomKey {
+        id = d.keyedSum(d.idKey, sealed, blake3.Size)
+    }
+
+    has, err := store.Has(id)
+    if err != nil {
+        return Chunk{}, err
+    }
+
+    if !has {
+        if err := store.Put(id, sealed); err != nil {
+            return Chunk{}, err
+        }
+    }
+
+    chunk := Chunk{
+        ID:   id,
+        Key:  key,
+        Size: len(data),
+    }
+
+    seen[string(plainID)] = chunk
+
+    return chunk, nil
+}
+
+// Join reads the chunks of the Manifest from store, verifies
+// and writes them to w. An error is returned when a chunk or
+// the whole stream does not match the Manifest.
+func (d *Dedup) Join(m *Manifest, store Store, w io.Writer) error {
+    if m.KeyMode != Convergent && m.KeyMode != RandomKey {
+        return ErrInvalidOpts
+    }
+
+    sum, _ := blake3.NewKeyed(d.idKey, blake3.Size)
+
+    var size int64
+    for _, chunk := range m.Chunks {
+        data, err := d.getChunk(m.KeyMode, chunk, store)
+        if err != nil {
+            return err
+        }
+
+        sum.Write(data)
+        size += int64(len(data))
+
+        if _, err := w.Write(data); err != nil {
+            return err
+        }
+    }
+
+    if size != m.Size || subtle.ConstantTimeCompare(sum.Sum(nil), m.Sum) != 1 {
+        return ErrStreamCorrupt
+    }
+
+    return nil
+}
+
+func (d *Dedup) getChunk(mode KeyMode, chunk Chunk, store Store) ([]byte, error) {
+    sealed, err := store.Get(chunk.ID)
+    if err != nil {
+        return nil, err
+    }
+
+    if mode == RandomKey {
+        id := d.keyedSum(d.idKey, sealed, blake3.Size)
+        if subtle.ConstantTimeCompare(id, chunk.ID) != 1 {
+            return nil, ErrChunkCorrupt
+        }
+    }
+
+    aead, err := d.opts.NewAEAD(chunk.Key)
+    if err != nil {
+        return nil, err
+    }
+
+    ns := aead.NonceSize()
+    if len(sealed) < ns + aead.Overhead() {
+        return nil, ErrChunkCorrupt
+    }
+
+    data, err := aead.Open(nil, sealed[:ns], sealed[ns:], nil)
+    if err != nil {
+        return nil, ErrChunkCorrupt
+    }
+
+    if len(data) != chunk.Size {
+        return nil, ErrChunkCorrupt
+    }
+
+    if mode == Convergent {
+        id := d.keyedSum(d.idKey, data, blake3.Size)
+        if subtle.ConstantTimeCompare(id, chunk.ID) != 1 {
+            return nil, ErrChunkCorrupt
+        }
+    }
+
+    return data, nil
+}
+
+// SealManifest encodes and encrypts the Manifest with
+// a key derived from the secret.
+func (d *Dedup) SealManifest(m *Manifest) ([]byte, error) {
+    data, err := m.Marshal()
+    if err != nil {
+        return nil, err
+    }
+
+    aead, err := d.opts.NewAEAD(d.manifestKey)
+    if err != nil {
+        return nil, err
+    }
+
+    nonce := make([]byte, aead.NonceSize())
+    if _, err := io.ReadFull(d.opts.Rand, nonce); err != nil {
+        return nil, err
+    }
+
+    return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// OpenManifest decrypts and decodes a Manifest from SealManifest.
+func (d *Dedup) OpenManifest(sealed []byte) (*Manifest, error) {
+    aead, err := d.opts.NewAEAD(d.manifestKey)
+    if err != nil {
+        return nil, err
+    }
+
+    ns := aead.NonceSize()
+    if len(sealed) < ns + aead.Overhead() {
+        return nil, ErrStreamCorrupt
+    }
+
+    data, err := aead.Open(nil, sealed[:ns], sealed[ns:], nil)
+    if err != nil {
+        return nil, ErrStreamCorrupt
+    }
+
+    return ParseManifest(data)
+}
@@ -0,0 +1,319 @@
+package dedup
+
+import (
+    "bytes"
+    "testing"
+    "math/rand"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/mode/eax"
+    "github.com/deatil/go-cryptobin/mode/ccm"
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+    "github.com/deatil/go-cryptobin/hash/rabin"
+)
+
+var testSecret = []byte("dedup test secret")
+
+func testOpts(mode KeyMode) Opts {
+    opts := DefaultOpts
+    opts.Table = rabin.NewTable(rabin.Poly64, 32)
+    opts.MinSize = 256
+    opts.AvgSize = 1 << 10
+    opts.MaxSize = 4 << 10
+    opts.KeyMode = mode
+
+    return opts
+}
+
+func testData(seed int64, n int) []byte {
+    data := make([]byte, n)
+    rand.New(rand.NewSource(seed)).Read(data)
+
+    return data
+}
+
+func Test_SplitJoin(t *testing.T) {
+    sm4EAX := func(key []byte) (cipher.AEAD, error) {
+        block, err := sm4.NewCipher(key)
+        if err != nil {
+            return nil, err
+        }
+
+        return eax.NewEAX(block)
+    }
+    sm4CCM := func(key []byte) (cipher.AEAD, error) {
+        block, err := sm4.NewCipher(key)
+        if err != nil {
+            return nil, err
+        }
+
+        return ccm.NewCCM(block)
+    }
+
+    tests := []struct {
+        name    string
+        newAEAD NewAEADFunc
+        keySize int
+    }{
+        {"AES-GCM", DefaultOpts.NewAEAD, 32},
+        {"SM4-GCM", NewGCM(sm4.NewCipher), 16},
+        {"SM4-EAX", sm4EAX, 16},
+        {"SM4-CCM", sm4CCM, 16},
+    }
+
+    for _, td := range tests {
+        for _, mode := range []KeyMode{Convergent, RandomKey} {
+            t.Run(td.name + "/" + mode.String(), func(t *testing.T) {
+                opts := testOpts(mode)
+                opts.NewAEAD = td.newAEAD
+                opts.KeySize = td.keySize
+
+                d, err := New(testSecret, opts)
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                for _, n := range []int{0, 1, 300, 64 << 10} {
+                    data := testData(int64(n), n)
+                    store := NewMemoryStore()
+
+                    m, err := d.Split(bytes.NewReader(data), store)
+                    if err != nil {
+                        t.Fatal(err)
+                    }
+
+                    if m.Size != int64(n) {
+                        t.Errorf("Size got %d, want %d", m.Size, n)
+                    }
+
+                    var out bytes.Buffer
+                    if err := d.Join(m, store, &out); err != nil {
+                        t.Fatal(err)
+                    }
+
+                    if !bytes.Equal(out.Bytes(), data) {
+                        t.Errorf("Join(%d) got wrong data", n)
+                    }
+                }
+            })
+        }
+    }
+}
+
+func Test_Dedup(t *testing.T) {
+    data := testData(1, 128 << 10)
+
+    // insert some bytes near the start of the stream
+    edited := append([]byte("inserted"), data...)
+
+    t.Run("Convergent", func(t *testing.T) {
+        d, _ := New(testSecret, testOpts(Convergent))
+        store := NewMemoryStore()
+
+        m1, err := d.Split(bytes.NewReader(data), store)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        n1 := store.Len()
+
+        m2, err := d.Split(bytes.NewReader(data), store)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if store.Len() != n1 {
+            t.Errorf("same stream stored %d new chunks", store.Len() - n1)
+        }
+
+        for i := range m1.Chunks {
+            if !bytes.Equal(m1.Chunks[i].ID, m2.Chunks[i].ID) {
+                t.Fatalf("chunk %d ID changed", i)
+            }
+        }
+
+        m3, err := d.Split(bytes.NewReader(edited), store)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        // only the chunks around the edit are new
+        if added := store.Len() - n1; added > 3 {
+            t.Errorf("edited stream stored %d new chunks of %d", added, len(m3.Chunks))
+        }
+
+        var out bytes.Buffer
+        if err := d.Join(m3, store, &out); err != nil {
+            t.Fatal(err)
+        }
+
+        if !bytes.Equal(out.Bytes(), edited) {
+            t.Error("Join got wrong data")
+        }
+    })
+
+    t.Run("RandomKey", func(t *testing.T) {
+        d, _ := New(testSecret, testOpts(RandomKey))
+
+        // repeated chunks in one stream are stored once
+        repeated := append(append([]byte(nil), data...), data...)
+
+        store := NewMemoryStore()
+        m, err := d.Split(bytes.NewReader(repeated), store)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if store.Len() >= len(m.Chunks) {
+            t.Errorf("stored %d chunks of %d", store.Len(), len(m.Chunks))
+        }
+
+        n1 := store.Len()
+        if _, err := d.Split(bytes.NewReader(repeated), store); err != nil {
+            t.Fatal(err)
+        }
+
+        if store.Len() != 2 * n1 {
+            t.Errorf("random keys should not be shared, got %d chunks", store.Len())
+        }
+    })
+}
+
+func Test_Verify(t *testing.T) {
+    data := testData(2, 32 << 10)
+
+    for _, mode := range []KeyMode{Convergent, RandomKey} {
+        t.Run(mode.String(), func(t *testing.T) {
+            d, _ := New(testSecret, testOpts(mode))
+            store := NewMemoryStore()
+
+            m, err := d.Split(bytes.NewReader(data), store)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            // wrong secret
+            d2, _ := New([]byte("other secret"), testOpts(mode))
+            if err := d2.Join(m, store, &bytes.Buffer{}); err != ErrChunkCorrupt {
+                t.Errorf("wrong secret: want ErrChunkCorrupt, got %v", err)
+            }
+
+            // corrupt chunk
+            sealed, _ := store.Get(m.Chunks[1].ID)
+            sealed[len(sealed) - 1] ^= 1
+
+            bad := NewMemoryStore()
+            for i, c := range m.Chunks {
+                v, _ := store.Get(c.ID)
+                if i == 1 {
+                    v = sealed
+                }
+
+                bad.Put(c.ID, v)
+            }
+
+            if err := d.Join(m, bad, &bytes.Buffer{}); err != ErrChunkCorrupt {
+                t.Errorf("corrupt chunk: want ErrChunkCorrupt, got %v", err)
+            }
+
+            // missing chunk
+            if err := d.Join(m, NewMemoryStore(), &bytes.Buffer{}); err != ErrChunkNotFound {
+                t.Errorf("missing chunk: want ErrChunkNotFound, got %v", err)
+            }
+
+            // reordered chunks
+            m2 := *m
+            m2.Chunks = append([]Chunk(nil), m.Chunks...)
+            m2.Chunks[0], m2.Chunks[1] = m2.Chunks[1], m2.Chunks[0]
+            if err := d.Join(&m2, store, &bytes.Buffer{}); err != ErrStreamCorrupt {
+                t.Errorf("reordered: want ErrStreamCorrupt, got %v", err)
+            }
+        })
+    }
+}
+
+func Test_Manifest(t *testing.T) {
+    d, _ := New(testSecret, testOpts(RandomKey))
+    store := NewMemoryStore()
+
+    data := testData(3, 20 << 10)
+    m, err := d.Split(bytes.NewReader(data), store)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    enc, err := m.Marshal()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m2, err := ParseManifest(enc)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    sealed, err := d.SealManifest(m2)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if bytes.Contains(sealed, m.Chunks[0].Key) {
+        t.Error("sealed manifest has the chunk key")
+    }
+
+    m3, err := d.OpenManifest(sealed)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var out bytes.Buffer
+    if err := d.Join(m3, store, &out); err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(out.Bytes(), data) {
+        t.Error("Join got wrong data")
+    }
+
+    d2, _ := New([]byte("other secret"), testOpts(RandomKey))
+    if _, err := d2.OpenManifest(sealed); err != ErrStreamCorrupt {
+        t.Errorf("want ErrStreamCorrupt, got %v", err)
+    }
+
+    if _, err := ParseManifest([]byte(`{"version":2}`)); err != ErrManifestVersion {
+        t.Errorf("want ErrManifestVersion, got %v", err)
+    }
+}
+
+func Test_New(t *testing.T) {
+    opts := testOpts(Convergent)
+    opts.AvgSize = 1000
+    if _, err := New(testSecret, opts); err != ErrInvalidOpts {
+        t.Errorf("want ErrInvalidOpts, got %v", err)
+    }
+
+    opts = testOpts(KeyMode(5))
+    if _, err := New(testSecret, opts); err != ErrInvalidOpts {
+        t.Errorf("want ErrInvalidOpts, got %v", err)
+    }
+
+    // no window
+    opts = testOpts(Convergent)
+    opts.Table = rabin.NewTable(rabin.Poly64, 0)
+    if _, err := New(testSecret, opts); err != ErrInvalidOpts {
+        t.Errorf("no window: want ErrInvalidOpts, got %v", err)
+    }
+
+    // window bigger than MinSize
+    opts = testOpts(Convergent)
+    opts.Table = rabin.NewTable(rabin.Poly64, 512)
+    if _, err := New(testSecret, opts); err != ErrInvalidOpts {
+        t.Errorf("big window: want ErrInvalidOpts, got %v", err)
+    }
+
+    opts = testOpts(Convergent)
+    opts.Table = rabin.NewTable(rabin.Poly64, opts.MinSize)
+    if _, err := New(testSecret, opts); err != nil {
+        t.Errorf("window == MinSize: %v", err)
+    }
+}
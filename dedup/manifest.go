@@ -0,0 +1,75 @@
+package dedup
+
+import (
+    "errors"
+    "encoding/json"
+)
+
+// manifest format version
+const manifestVersion = 1
+
+var ErrManifestVersion = errors.New("go-cryptobin/dedup: unsupported manifest version")
+
+// KeyMode is how the chunk keys are made
+type KeyMode int
+
+const (
+    // Convergent derives the chunk key from the secret and the chunk,
+    // equal chunks get equal IDs and are stored once across manifests.
+    Convergent KeyMode = iota
+
+    // RandomKey uses a random key for every chunk, equal chunks are
+    // only stored once in the same Split.
+    RandomKey
+)
+
+func (m KeyMode) String() string {
+    switch m {
+        case Convergent:
+            return "Convergent"
+        case RandomKey:
+            return "RandomKey"
+        default:
+            return "unknown key mode"
+    }
+}
+
+// Chunk is the entry of one chunk in a Manifest
+type Chunk struct {
+    ID   []byte `json:"id"`
+    Key  []byte `json:"key"`
+    Size int    `json:"size"`
+}
+
+// Manifest lists the chunks of a stream in order.
+// It holds the chunk keys, so it should be kept secret,
+// see Dedup.SealManifest.
+type Manifest struct {
+    Version int     `json:"version"`
+    KeyMode KeyMode `json:"key_mode"`
+    Size    int64   `json:"size"`
+
+    // keyed hash of the whole stream
+    Sum []byte `json:"sum"`
+
+    Chunks []Chunk `json:"chunks"`
+}
+
+// Marshal encodes the Manifest to JSON
+func (m *Manifest) Marshal() ([]byte, error) {
+    return json.Marshal(m)
+}
+
+// ParseManifest decodes a Manifest from JSON
+func ParseManifest(data []byte) (*Manifest, error) {
+    m := &Manifest{}
+    if err := json.Unmarshal(data, m); err != nil {
+        return nil, err
+    }
+
+    if m.Version != manifestVersion {
+        return nil, ErrManifestVersion
+    }
+
+    return m, nil
+}
@@ -0,0 +1,74 @@
+package dedup
+
+import (
+    "sync"
+    "errors"
+    "encoding/hex"
+)
+
+var ErrChunkNotFound = errors.New("go-cryptobin/dedup: chunk not found")
+
+// Store saves the sealed chunks by chunk ID
+type Store interface {
+    // Has reports whether the chunk is in the store.
+    Has(id []byte) (bool, error)
+
+    // Put saves the sealed chunk, Put of an existing id is a no-op.
+    Put(id []byte, data []byte) error
+
+    // Get returns the sealed chunk or ErrChunkNotFound.
+    Get(id []byte) ([]byte, error)
+}
+
+// MemoryStore is a Store in memory
+type MemoryStore struct {
+    mu     sync.RWMutex
+    chunks map[string][]byte
+}
+
+// NewMemoryStore returns a new and empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{
+        chunks: make(map[string][]byte),
+    }
+}
+
+func (s *MemoryStore) Has(id []byte) (bool, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    _, ok := s.chunks[hex.EncodeToString(id)]
+    return ok, nil
+}
+
+func (s *MemoryStore) Put(id []byte, data []byte) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    key := hex.EncodeToString(id)
+    if _, ok := s.chunks[key]; !ok {
+        s.chunks[key] = append([]byte(nil), data...)
+    }
+
+    return nil
+}
+
+func (s *MemoryStore) Get(id []byte) ([]byte, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    data, ok := s.chunks[hex.EncodeToString(id)]
+    if !ok {
+        return nil, ErrChunkNotFound
+    }
+
+    return append([]byte(nil), data...), nil
+}
+
+// Len returns the number of chunks in the store
+func (s *MemoryStore) Len() int {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return len(s.chunks)
+}
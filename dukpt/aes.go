@@ -0,0 +1,181 @@
+package dukpt
+
+import (
+    "errors"
+    "math/bits"
+    "crypto/aes"
+    "encoding/binary"
+)
+
+var (
+    ErrInvalidKeyUsage = errors.New("go-cryptobin/dukpt: invalid key usage")
+    ErrInvalidKeyType  = errors.New("go-cryptobin/dukpt: invalid key type")
+)
+
+const (
+    // InitialKeyIDSize is the size of the AES DUKPT initial key ID,
+    // the BDK ID and the derivation ID.
+    InitialKeyIDSize = 8
+
+    // AESKSNSize is the size of the AES DUKPT key serial number,
+    // the initial key ID and the 32-bit transaction counter.
+    AESKSNSize = 12
+)
+
+// KeyUsage is the X9.24-3 key usage indicator of the derived key.
+type KeyUsage uint16
+
+const (
+    KeyUsageKeyEncryption     KeyUsage = 0x0002
+    KeyUsagePINEncryption     KeyUsage = 0x1000
+    KeyUsageMACGeneration     KeyUsage = 0x2000
+    KeyUsageMACVerification   KeyUsage = 0x2001
+    KeyUsageMACBoth           KeyUsage = 0x2002
+    KeyUsageDataEncryption    KeyUsage = 0x3000
+    KeyUsageDataDecryption    KeyUsage = 0x3001
+    KeyUsageDataBoth          KeyUsage = 0x3002
+    KeyUsageKeyDerivation     KeyUsage = 0x8000
+    KeyUsageKeyDerivationInit KeyUsage = 0x8001
+)
+
+// KeyType is the algorithm and the size of the derived key.
+type KeyType uint16
+
+const (
+    KeyType2TDEA  KeyType = 0
+    KeyType3TDEA  KeyType = 1
+    KeyTypeAES128 KeyType = 2
+    KeyTypeAES192 KeyType = 3
+    KeyTypeAES256 KeyType = 4
+)
+
+// KeySize returns the key size in bytes.
+func (t KeyType) KeySize() int {
+    switch t {
+        case KeyType2TDEA, KeyTypeAES128:
+            return 16
+        case KeyType3TDEA, KeyTypeAES192:
+            return 24
+        case KeyTypeAES256:
+            return 32
+    }
+
+    return 0
+}
+
+func aesKeyType(key []byte) (KeyType, error) {
+    switch len(key) {
+        case 16:
+            return KeyTypeAES128, nil
+        case 24:
+            return KeyTypeAES192, nil
+        case 32:
+            return KeyTypeAES256, nil
+    }
+
+    return 0, ErrInvalidBDK
+}
+
+// DeriveAESInitialKey derives the initial key of the AES DUKPT
+// from the AES base derivation key and the 8 bytes initial key ID.
+func DeriveAESInitialKey(bdk, initialKeyID []byte) ([]byte, error) {
+    keyType, err := aesKeyType(bdk)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(initialKeyID) != InitialKeyIDSize {
+        return nil, ErrInvalidKSN
+    }
+
+    return deriveAESKey(bdk, KeyUsageKeyDerivationInit, keyType, initialKeyID)
+}
+
+// DeriveAESWorkingKey derives the working key of the KSN from the
+// initial key. The working key is not stronger than the initial key.
+func DeriveAESWorkingKey(ik, ksn []byte, usage KeyUsage, keyType KeyType) ([]byte, error) {
+    ikType, err := aesKeyType(ik)
+    if err != nil {
+        return nil, ErrInvalidIPEK
+    }
+
+    if len(ksn) != AESKSNSize {
+        return nil, ErrInvalidKSN
+    }
+
+    if usage == KeyUsageKeyDerivation || usage == KeyUsageKeyDerivationInit {
+        return nil, ErrInvalidKeyUsage
+    }
+
+    if keyType.KeySize() == 0 {
+        return nil, ErrInvalidKeyType
+    }
+
+    if keyType.KeySize() > ikType.KeySize() {
+        return nil, ErrInvalidKeyType
+    }
+
+    key, err := deriveAESTransactionKey(ik, ikType, ksn)
+    if err != nil {
+        return nil, err
+    }
+
+    return deriveAESKey(key, usage, keyType, ksn[4:])
+}
+
+// 按计数器中为 1 的位从高到低依次派生中间密钥
+func deriveAESTransactionKey(ik []byte, ikType KeyType, ksn []byte) ([]byte, error) {
+    counter := binary.BigEndian.Uint32(ksn[8:])
+
+    // 计数器中最多 16 位为 1
+    if counter == 0 || bits.OnesCount32(counter) > 16 {
+        return nil, ErrInvalidKSN
+    }
+
+    var data [8]byte
+    copy(data[:4], ksn[4:8])
+
+    key := ik
+
+    var reg uint32
+    for mask := uint32(1 << 31); mask > 0; mask >>= 1 {
+        if counter & mask == 0 {
+            continue
+        }
+
+        reg |= mask
+        binary.BigEndian.PutUint32(data[4:], reg)
+
+        var err error
+        if key, err = deriveAESKey(key, KeyUsageKeyDerivation, ikType, data[:]); err != nil {
+            return nil, err
+        }
+    }
+
+    return key, nil
+}
+
+// 派生数据: 版本 || 分组计数器 || 用途 || 算法 || 长度(位) || 数据
+func deriveAESKey(key []byte, usage KeyUsage, keyType KeyType, data []byte) ([]byte, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+
+    size := keyType.KeySize()
+
+    var d [16]byte
+    d[0] = 0x01
+    binary.BigEndian.PutUint16(d[2:], uint16(usage))
+    binary.BigEndian.PutUint16(d[4:], uint16(keyType))
+    binary.BigEndian.PutUint16(d[6:], uint16(size * 8))
+    copy(d[8:], data)
+
+    out := make([]byte, (size + 15) / 16 * 16)
+    for i := 0; i < len(out) / 16; i++ {
+        d[1] = byte(i + 1)
+        block.Encrypt(out[i*16:], d[:])
+    }
+
+    return out[:size], nil
+}
@@ -0,0 +1,97 @@
+package dukpt
+
+import (
+    "testing"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
+)
+
+func Test_DeriveAESInitialKey(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    // ANSI X9.24-3:2017 Annex B
+    cases := []struct {
+        bdk string
+        ik  string
+    }{
+        {
+            "FEDCBA9876543210F1F1F1F1F1F1F1F1",
+            "1273671EA26AC29AFA4D1084127652A1",
+        },
+        {
+            "FEDCBA9876543210F1F1F1F1F1F1F1F1FEDCBA9876543210F1F1F1F1F1F1F1F1",
+            "CE9CE0C101D1138F97FB6CAD4DF045A7083D4EAE2D35A31789D01CCF0949550F",
+        },
+    }
+
+    for _, c := range cases {
+        ik, err := DeriveAESInitialKey(fromHex(c.bdk), fromHex("1234567890123456"))
+        assertNoError(err, "Test_DeriveAESInitialKey")
+        assertEqual(ik, fromHex(c.ik), "Test_DeriveAESInitialKey")
+    }
+}
+
+func Test_DeriveAESWorkingKey(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    ik := fromHex("1273671EA26AC29AFA4D1084127652A1")
+    ksn := fromHex("123456789012345600000001")
+
+    // 中间派生密钥
+    key, err := deriveAESTransactionKey(ik, KeyTypeAES128, ksn)
+    assertNoError(err, "Test_DeriveAESWorkingKey")
+    assertEqual(key, fromHex("4F21B565BAD9835E112B6465635EAE44"), "Test_DeriveAESWorkingKey-derivation")
+
+    cases := []struct {
+        usage   KeyUsage
+        keyType KeyType
+        key     string
+    }{
+        {KeyUsagePINEncryption, KeyTypeAES128, "AF8CB133A78F8DC2D1359F18527593FB"},
+        {KeyUsageMACGeneration, KeyTypeAES128, "A2DC23DE6FDE0824A2BC321E08E4B8B7"},
+        {KeyUsageDataEncryption, KeyTypeAES128, "A35C412EFD41FDB98B69797C02DCD08F"},
+        {KeyUsageKeyEncryption, KeyTypeAES128, "36A724B7BEFA5A25F5E7B5782A4554A2"},
+        {KeyUsagePINEncryption, KeyType2TDEA, ""},
+    }
+
+    for _, c := range cases {
+        key, err := DeriveAESWorkingKey(ik, ksn, c.usage, c.keyType)
+        assertNoError(err, "Test_DeriveAESWorkingKey")
+
+        assertEqual(len(key), c.keyType.KeySize(), "Test_DeriveAESWorkingKey-size")
+        if c.key != "" {
+            assertEqual(key, fromHex(c.key), "Test_DeriveAESWorkingKey-key")
+        }
+    }
+}
+
+func Test_DeriveAESWorkingKeyError(t *testing.T) {
+    ik := fromHex("1273671EA26AC29AFA4D1084127652A1")
+
+    cases := []struct {
+        ksn     string
+        usage   KeyUsage
+        keyType KeyType
+        err     error
+    }{
+        {"123456789012345600000000", KeyUsagePINEncryption, KeyTypeAES128, ErrInvalidKSN},
+        {"1234567890123456FFFF0001", KeyUsagePINEncryption, KeyTypeAES128, ErrInvalidKSN},
+        {"12345678901234560001", KeyUsagePINEncryption, KeyTypeAES128, ErrInvalidKSN},
+        {"123456789012345600000001", KeyUsageKeyDerivation, KeyTypeAES128, ErrInvalidKeyUsage},
+        {"123456789012345600000001", KeyUsagePINEncryption, KeyTypeAES256, ErrInvalidKeyType},
+        {"123456789012345600000001", KeyUsagePINEncryption, KeyType(9), ErrInvalidKeyType},
+    }
+
+    for i, c := range cases {
+        _, err := DeriveAESWorkingKey(ik, fromHex(c.ksn), c.usage, c.keyType)
+        if err != c.err {
+            t.Errorf("#%d: got %v, want %v", i, err, c.err)
+        }
+    }
+
+    if _, err := DeriveAESInitialKey(ik[:8], fromHex("1234567890123456")); err != ErrInvalidBDK {
+        t.Errorf("got %v, want ErrInvalidBDK", err)
+    }
+}
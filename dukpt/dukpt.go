@@ -0,0 +1,224 @@
+// Package dukpt implements the Derived Unique Key Per Transaction key
+// management of ANSI X9.24-1 (TDES DUKPT) and ANSI X9.24-3 (AES DUKPT).
+package dukpt
+
+import (
+    "errors"
+    "crypto/des"
+    "crypto/subtle"
+    "encoding/binary"
+)
+
+var (
+    ErrInvalidBDK  = errors.New("go-cryptobin/dukpt: invalid BDK size")
+    ErrInvalidIPEK = errors.New("go-cryptobin/dukpt: invalid initial key size")
+    ErrInvalidKSN  = errors.New("go-cryptobin/dukpt: invalid KSN size")
+)
+
+// KSNSize is the size of the TDES DUKPT key serial number.
+const KSNSize = 10
+
+// 计数器为 KSN 最右边的 21 位
+const counterMask = 0x1FFFFF
+
+// 密钥变体
+var keyMask = []byte{
+    0xC0, 0xC0, 0xC0, 0xC0, 0x00, 0x00, 0x00, 0x00,
+    0xC0, 0xC0, 0xC0, 0xC0, 0x00, 0x00, 0x00, 0x00,
+}
+
+// Variant is the X9.24-1 variant of the working key.
+type Variant int
+
+const (
+    VariantPIN Variant = iota
+    VariantMACRequest
+    VariantMACResponse
+    VariantDataRequest
+    VariantDataResponse
+)
+
+var variantMasks = map[Variant][]byte{
+    VariantPIN: {
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF,
+    },
+    VariantMACRequest: {
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, 0x00,
+    },
+    VariantMACResponse: {
+        0x00, 0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00,
+    },
+    VariantDataRequest: {
+        0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, 0x00, 0x00,
+    },
+    VariantDataResponse: {
+        0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0x00,
+    },
+}
+
+func (v Variant) String() string {
+    switch v {
+        case VariantPIN:
+            return "PIN"
+        case VariantMACRequest:
+            return "MACRequest"
+        case VariantMACResponse:
+            return "MACResponse"
+        case VariantDataRequest:
+            return "DataRequest"
+        case VariantDataResponse:
+            return "DataResponse"
+    }
+
+    return "unknown variant"
+}
+
+// DeriveIPEK derives the initial PIN encryption key from the
+// double-length TDES base derivation key and the KSN.
+func DeriveIPEK(bdk, ksn []byte) ([]byte, error) {
+    if len(bdk) != 16 {
+        return nil, ErrInvalidBDK
+    }
+
+    if len(ksn) != KSNSize {
+        return nil, ErrInvalidKSN
+    }
+
+    // 清除计数器后 KSN 最左边的 8 字节
+    var data [8]byte
+    copy(data[:], ksn)
+    data[7] &= 0xE0
+
+    ipek := make([]byte, 16)
+
+    key := make([]byte, 24)
+    copy(key, bdk)
+    copy(key[16:], bdk[:8])
+
+    for i := 0; i < 2; i++ {
+        if i == 1 {
+            subtle.XORBytes(key, key, keyMask)
+            copy(key[16:], key[:8])
+        }
+
+        block, err := des.NewTripleDESCipher(key)
+        if err != nil {
+            return nil, err
+        }
+
+        block.Encrypt(ipek[i*8:], data[:])
+    }
+
+    return ipek, nil
+}
+
+// DeriveTransactionKey derives the transaction key of the KSN
+// from the initial PIN encryption key.
+func DeriveTransactionKey(ipek, ksn []byte) ([]byte, error) {
+    if len(ipek) != 16 {
+        return nil, ErrInvalidIPEK
+    }
+
+    if len(ksn) != KSNSize {
+        return nil, ErrInvalidKSN
+    }
+
+    // KSN 最右边的 64 位, 清除计数器
+    reg := binary.BigEndian.Uint64(ksn[2:])
+    counter := reg & counterMask
+    reg &^= counterMask
+
+    key := make([]byte, 16)
+    copy(key, ipek)
+
+    for shift := uint64(1 << 20); shift > 0; shift >>= 1 {
+        if counter & shift == 0 {
+            continue
+        }
+
+        reg |= shift
+
+        var err error
+        if key, err = nonReversibleKey(key, reg); err != nil {
+            return nil, err
+        }
+    }
+
+    return key, nil
+}
+
+// DeriveWorkingKey derives the transaction key and applies the variant.
+// The data encryption variants also go through the one-way step of X9.24-1.
+func DeriveWorkingKey(ipek, ksn []byte, variant Variant) ([]byte, error) {
+    mask, ok := variantMasks[variant]
+    if !ok {
+        return nil, errors.New("go-cryptobin/dukpt: invalid variant")
+    }
+
+    key, err := DeriveTransactionKey(ipek, ksn)
+    if err != nil {
+        return nil, err
+    }
+
+    subtle.XORBytes(key, key, mask)
+
+    if variant == VariantDataRequest || variant == VariantDataResponse {
+        if err = oneWayKey(key); err != nil {
+            return nil, err
+        }
+    }
+
+    return key, nil
+}
+
+// 数据加密密钥的单向过程, 用变体密钥分别加密左右两部分
+func oneWayKey(key []byte) error {
+    k := make([]byte, 24)
+    copy(k, key)
+    copy(k[16:], key[:8])
+
+    block, err := des.NewTripleDESCipher(k)
+    if err != nil {
+        return err
+    }
+
+    block.Encrypt(key[:8], key[:8])
+    block.Encrypt(key[8:], key[8:])
+
+    return nil
+}
+
+// 不可逆密钥生成过程, 右半部分用原密钥, 左半部分用变体密钥
+func nonReversibleKey(key []byte, reg uint64) ([]byte, error) {
+    var data [8]byte
+    binary.BigEndian.PutUint64(data[:], reg)
+
+    out := make([]byte, 16)
+
+    k := make([]byte, 16)
+    copy(k, key)
+
+    for _, half := range []int{1, 0} {
+        if half == 0 {
+            subtle.XORBytes(k, k, keyMask)
+        }
+
+        block, err := des.NewCipher(k[:8])
+        if err != nil {
+            return nil, err
+        }
+
+        r := out[half*8:half*8+8]
+
+        subtle.XORBytes(r, data[:], k[8:])
+        block.Encrypt(r, r)
+        subtle.XORBytes(r, r, k[8:])
+    }
+
+    return out, nil
+}
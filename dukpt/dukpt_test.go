@@ -0,0 +1,146 @@
+package dukpt
+
+import (
+    "testing"
+    "crypto/des"
+    "crypto/subtle"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/pinblock"
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+func Test_DeriveIPEK(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    // ANSI X9.24-1:2009 Annex A
+    bdk := fromHex("0123456789ABCDEFFEDCBA9876543210")
+
+    // 计数器不影响 IPEK
+    for _, ksn := range []string{"FFFF9876543210E00000", "FFFF9876543210E00001", "FFFF9876543210FFFFFF"} {
+        ipek, err := DeriveIPEK(bdk, fromHex(ksn))
+        assertNoError(err, "Test_DeriveIPEK")
+
+        assertEqual(ipek, fromHex("6AC292FAA1315B4D858AB3A3D7D5933A"), "Test_DeriveIPEK")
+    }
+}
+
+func Test_DerivePINKey(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    // ANSI X9.24-1:2009 Annex A, PIN 1234 and PAN 4012345678909
+    ipek := fromHex("6AC292FAA1315B4D858AB3A3D7D5933A")
+
+    cases := []struct {
+        ksn      string
+        key      string
+        pinBlock string
+    }{
+        {"FFFF9876543210E00001", "042666B49184CF5C68DE9628D0397B36", "1B9C1845EB993A7A"},
+        {"FFFF9876543210E00002", "C46551CEF9FD244FAA9AD834130D3B38", "10A01C8D02C69107"},
+        {"FFFF9876543210E00003", "0DF3D9422ACA561A47676D07AD6BAD05", "18DC07B94797B466"},
+    }
+
+    clear, err := pinblock.EncodeFormat0("1234", "4012345678909")
+    assertNoError(err, "Test_DerivePINKey-EncodeFormat0")
+
+    for _, c := range cases {
+        key, err := DeriveWorkingKey(ipek, fromHex(c.ksn), VariantPIN)
+        assertNoError(err, "Test_DerivePINKey")
+        assertEqual(key, fromHex(c.key), "Test_DerivePINKey-key")
+
+        block, err := des.NewTripleDESCipher(append(key, key[:8]...))
+        assertNoError(err, "Test_DerivePINKey-NewTripleDESCipher")
+
+        encrypted, err := pinblock.Encrypt(block, clear)
+        assertNoError(err, "Test_DerivePINKey-Encrypt")
+        assertEqual(encrypted, fromHex(c.pinBlock), "Test_DerivePINKey-pinBlock")
+    }
+}
+
+func Test_DeriveWorkingKey(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    ipek := fromHex("6AC292FAA1315B4D858AB3A3D7D5933A")
+    ksn := fromHex("FFFF9876543210E00001")
+
+    key, err := DeriveTransactionKey(ipek, ksn)
+    assertNoError(err, "Test_DeriveWorkingKey")
+
+    // ANSI X9.24-1:2009 Annex A, 计数器 1 的未来密钥
+    assertEqual(key, fromHex("042666B49184CFA368DE9628D0397BC9"), "Test_DeriveWorkingKey-key")
+
+    // PIN 和 MAC 密钥只做变体
+    variants := []struct {
+        variant Variant
+        mask    string
+    }{
+        {VariantPIN, "00000000000000FF00000000000000FF"},
+        {VariantMACRequest, "000000000000FF00000000000000FF00"},
+        {VariantMACResponse, "00000000FF00000000000000FF000000"},
+    }
+
+    for _, v := range variants {
+        working, err := DeriveWorkingKey(ipek, ksn, v.variant)
+        assertNoError(err, "Test_DeriveWorkingKey-" + v.variant.String())
+
+        want := make([]byte, 16)
+        subtle.XORBytes(want, key, fromHex(v.mask))
+
+        assertEqual(working, want, "Test_DeriveWorkingKey-" + v.variant.String())
+    }
+
+    // ANSI X9.24-1:2009 Annex A, 计数器 1 的数据加密密钥
+    working, err := DeriveWorkingKey(ipek, ksn, VariantDataRequest)
+    assertNoError(err, "Test_DeriveWorkingKey-DataRequest")
+    assertEqual(working, fromHex("448D3F076D8304036A55A3D7E0055A78"), "Test_DeriveWorkingKey-DataRequest")
+
+    // 响应方向使用另一个掩码, 单向过程相同
+    variant := make([]byte, 16)
+    subtle.XORBytes(variant, key, fromHex("000000FF00000000000000FF00000000"))
+
+    block, err := des.NewTripleDESCipher(append(variant, variant[:8]...))
+    assertNoError(err, "Test_DeriveWorkingKey-NewTripleDESCipher")
+
+    want := make([]byte, 16)
+    block.Encrypt(want[:8], variant[:8])
+    block.Encrypt(want[8:], variant[8:])
+
+    working, err = DeriveWorkingKey(ipek, ksn, VariantDataResponse)
+    assertNoError(err, "Test_DeriveWorkingKey-DataResponse")
+    assertEqual(working, want, "Test_DeriveWorkingKey-DataResponse")
+
+    _, err = DeriveWorkingKey(ipek, ksn, Variant(100))
+    if err == nil {
+        t.Error("invalid variant should fail")
+    }
+}
+
+func Test_DeriveError(t *testing.T) {
+    bdk := fromHex("0123456789ABCDEFFEDCBA9876543210")
+    ksn := fromHex("FFFF9876543210E00001")
+
+    if _, err := DeriveIPEK(bdk[:8], ksn); err != ErrInvalidBDK {
+        t.Errorf("got %v, want ErrInvalidBDK", err)
+    }
+
+    if _, err := DeriveIPEK(bdk, ksn[:8]); err != ErrInvalidKSN {
+        t.Errorf("got %v, want ErrInvalidKSN", err)
+    }
+
+    if _, err := DeriveTransactionKey(bdk[:8], ksn); err != ErrInvalidIPEK {
+        t.Errorf("got %v, want ErrInvalidIPEK", err)
+    }
+
+    if _, err := DeriveTransactionKey(bdk, ksn[:9]); err != ErrInvalidKSN {
+        t.Errorf("got %v, want ErrInvalidKSN", err)
+    }
+}
@@ -0,0 +1,360 @@
+// Package blake3 implements the BLAKE3 hash function with its
+// keyed hash, derive-key and extendable output modes.
+// The subtrees of large writes are hashed on multiple goroutines.
+package blake3
+
+import (
+    "io"
+    "hash"
+    "sync"
+    "errors"
+    "runtime"
+    "math/bits"
+)
+
+// inputs smaller than this are hashed on the calling goroutine
+const parallelMinSize = 8 * ChunkSize
+
+var (
+    ErrKeySize = errors.New("go-cryptobin/blake3: invalid key size")
+    ErrSeek    = errors.New("go-cryptobin/blake3: invalid seek position")
+)
+
+// Hasher is a BLAKE3 hash.Hash
+type Hasher struct {
+    key   [8]uint32
+    flags uint32
+    size  int
+
+    chunk chunkState
+
+    // chaining values of completed subtrees,
+    // at most one per level of the tree
+    stack [54][8]uint32
+    depth int
+}
+
+func newHasher(key [8]uint32, flags uint32, size int) *Hasher {
+    if size <= 0 {
+        panic("go-cryptobin/blake3: invalid size")
+    }
+
+    return &Hasher{
+        key:   key,
+        flags: flags,
+        size:  size,
+        chunk: newChunkState(&key, 0, flags),
+    }
+}
+
+// New returns a new hash.Hash computing the BLAKE3 checksum of 32 bytes.
+func New() hash.Hash {
+    return NewWithSize(Size)
+}
+
+// NewWithSize returns a new Hasher with size bytes of output.
+func NewWithSize(size int) *Hasher {
+    return newHasher(iv, 0, size)
+}
+
+// NewKeyed returns a new Hasher in keyed hash mode, key must be 32 bytes.
+func NewKeyed(key []byte, size int) (*Hasher, error) {
+    if len(key) != KeySize {
+        return nil, ErrKeySize
+    }
+
+    return newHasher(keyToWords(key), flagKeyedHash, size), nil
+}
+
+// NewDeriveKey returns a new Hasher in derive-key mode.
+// The key material is written to the Hasher, context should be
+// a hardcoded, globally unique and application-specific string.
+func NewDeriveKey(context string, size int) *Hasher {
+    h := newHasher(iv, flagDeriveKeyContext, Size)
+    h.Write([]byte(context))
+
+    var contextKey [Size]byte
+    h.Sum(contextKey[:0])
+
+    return newHasher(keyToWords(contextKey[:]), flagDeriveKeyMaterial, size)
+}
+
+func (h *Hasher) Size() int {
+    return h.size
+}
+
+func (h *Hasher) BlockSize() int {
+    return BlockSize
+}
+
+func (h *Hasher) Reset() {
+    h.chunk = newChunkState(&h.key, 0, h.flags)
+    h.depth = 0
+}
+
+func (h *Hasher) pushStack(cv [8]uint32) {
+    h.stack[h.depth] = cv
+    h.depth++
+}
+
+// mergeStack merges the completed subtrees until
+// the stack holds one entry per set bit of total chunks.
+func (h *Hasher) mergeStack(totalChunks uint64) {
+    for h.depth > bits.OnesCount64(totalChunks) {
+        p := parentOutput(h.stack[h.depth-2], h.stack[h.depth-1], &h.key, h.flags)
+
+        h.depth -= 2
+        h.pushStack(p.chainingValue())
+    }
+}
+
+// pushCV adds the chaining value of a subtree starting at chunk counter.
+func (h *Hasher) pushCV(cv [8]uint32, counter uint64) {
+    h.mergeStack(counter)
+    h.pushStack(cv)
+}
+
+func (h *Hasher) Write(p []byte) (int, error) {
+    written := len(p)
+
+    // finish the pending chunk first
+    if h.chunk.len() > 0 {
+        n := ChunkSize - h.chunk.len()
+        if n > len(p) {
+            n = len(p)
+        }
+
+        h.chunk.update(p[:n])
+        p = p[n:]
+
+        if len(p) == 0 {
+            return written, nil
+        }
+
+        o := h.chunk.output()
+        h.pushCV(o.chainingValue(), h.chunk.counter)
+
+        h.chunk = newChunkState(&h.key, h.chunk.counter + 1, h.flags)
+    }
+
+    // hash whole subtrees while more than one chunk is left
+    for len(p) > ChunkSize {
+        counter := h.chunk.counter
+
+        // the largest power of two bytes that fits in the input
+        // and keeps the subtree aligned to the chunk counter
+        size := uint64(1) << (bits.Len64(uint64(len(p))) - 1)
+        for size > ChunkSize && (counter * ChunkSize) & (size - 1) != 0 {
+            size >>= 1
+        }
+
+        chunks := size / ChunkSize
+
+        if chunks == 1 {
+            cs := newChunkState(&h.key, counter, h.flags)
+            cs.update(p[:size])
+
+            o := cs.output()
+            h.pushCV(o.chainingValue(), counter)
+        } else {
+            // push both halves so a later merge can still pick the root
+            left, right := h.subtreeChildren(p[:size], counter, parallelDepth(int(size)))
+
+            h.pushCV(left, counter)
+            h.pushCV(right, counter + chunks / 2)
+        }
+
+        h.chunk = newChunkState(&h.key, counter + chunks, h.flags)
+        p = p[size:]
+    }
+
+    // the stack can not be the root once the chunk has input
+    if len(p) > 0 {
+        h.chunk.update(p)
+        h.mergeStack(h.chunk.counter)
+    }
+
+    return written, nil
+}
+
+// parallelDepth returns how many tree levels are split on goroutines.
+func parallelDepth(n int) int {
+    if n < parallelMinSize {
+        return 0
+    }
+
+    return bits.Len(uint(runtime.GOMAXPROCS(0)))
+}
+
+// subtreeCV returns the chaining value of the subtree of
+// a power of two chunks of input starting at counter.
+func (h *Hasher) subtreeCV(p []byte, counter uint64, depth int) [8]uint32 {
+    if len(p) <= ChunkSize {
+        cs := newChunkState(&h.key, counter, h.flags)
+        cs.update(p)
+
+        o := cs.output()
+        return o.chainingValue()
+    }
+
+    left, right := h.subtreeChildren(p, counter, depth)
+
+    o := parentOutput(left, right, &h.key, h.flags)
+    return o.chainingValue()
+}
+
+// subtreeChildren returns the chaining values of the two halves of p.
+func (h *Hasher) subtreeChildren(p []byte, counter uint64, depth int) (left, right [8]uint32) {
+    half := len(p) / 2
+    halfChunks := uint64(half / ChunkSize)
+
+    if depth <= 0 {
+        left = h.subtreeCV(p[:half], counter, 0)
+        right = h.subtreeCV(p[half:], counter + halfChunks, 0)
+
+        return
+    }
+
+    var wg sync.WaitGroup
+    wg.Add(1)
+
+    go func() {
+        defer wg.Done()
+
+        right = h.subtreeCV(p[half:], counter + halfChunks, depth - 1)
+    }()
+
+    left = h.subtreeCV(p[:half], counter, depth - 1)
+    wg.Wait()
+
+    return
+}
+
+// rootOutput returns the output of the root node
+// without changing the state of the Hasher.
+func (h *Hasher) rootOutput() output {
+    if h.depth == 0 {
+        return h.chunk.output()
+    }
+
+    var o output
+    depth := h.depth
+
+    if h.chunk.len() > 0 {
+        o = h.chunk.output()
+    } else {
+        depth -= 2
+        o = parentOutput(h.stack[depth], h.stack[depth+1], &h.key, h.flags)
+    }
+
+    for depth > 0 {
+        depth--
+        o = parentOutput(h.stack[depth], o.chainingValue(), &h.key, h.flags)
+    }
+
+    return o
+}
+
+func (h *Hasher) Sum(b []byte) []byte {
+    out := make([]byte, h.size)
+
+    r := h.XOF()
+    r.Read(out)
+
+    return append(b, out...)
+}
+
+// XOF returns a reader of the extended output,
+// the Hasher can still be written after.
+func (h *Hasher) XOF() *OutputReader {
+    return &OutputReader{
+        out: h.rootOutput(),
+    }
+}
+
+// OutputReader reads the extended output of BLAKE3, up to 2^64 - 1 bytes.
+type OutputReader struct {
+    out output
+
+    block [BlockSize]byte
+    pos   uint64
+}
+
+func (r *OutputReader) Read(p []byte) (int, error) {
+    read := len(p)
+
+    for len(p) > 0 {
+        off := r.pos % BlockSize
+        if off == 0 {
+            r.out.rootBlock(r.pos / BlockSize, &r.block)
+        }
+
+        n := copy(p, r.block[off:])
+        p = p[n:]
+        r.pos += uint64(n)
+    }
+
+    return read, nil
+}
+
+// Seek moves to the position of the output, whence is io.SeekStart or io.SeekCurrent.
+func (r *OutputReader) Seek(offset int64, whence int) (int64, error) {
+    var pos int64
+
+    switch whence {
+        case io.SeekStart:
+            pos = offset
+        case io.SeekCurrent:
+            pos = int64(r.pos) + offset
+        default:
+            return 0, ErrSeek
+    }
+
+    if pos < 0 {
+        return 0, ErrSeek
+    }
+
+    r.pos = uint64(pos)
+    if off := r.pos % BlockSize; off != 0 {
+        r.out.rootBlock(r.pos / BlockSize, &r.block)
+    }
+
+    return pos, nil
+}
+
+// Sum256 returns the BLAKE3 checksum of the data.
+func Sum256(data []byte) (out [Size]byte) {
+    h := NewWithSize(Size)
+    h.Write(data)
+    h.Sum(out[:0])
+
+    return
+}
+
+// Sum returns size bytes of BLAKE3 output of the data.
+func Sum(data []byte, size int) []byte {
+    h := NewWithSize(size)
+    h.Write(data)
+
+    return h.Sum(nil)
+}
+
+// SumKeyed returns size bytes of keyed BLAKE3 output of the data.
+func SumKeyed(key, data []byte, size int) ([]byte, error) {
+    h, err := NewKeyed(key, size)
+    if err != nil {
+        return nil, err
+    }
+
+    h.Write(data)
+
+    return h.Sum(nil), nil
+}
+
+// DeriveKey derives size bytes of key from the key material and context.
+func DeriveKey(context string, material []byte, size int) []byte {
+    h := NewDeriveKey(context, size)
+    h.Write(material)
+
+    return h.Sum(nil)
+}
@@ -0,0 +1,210 @@
+package blake3
+
+import (
+    "math/bits"
+    "encoding/binary"
+)
+
+const (
+    BlockSize = 64
+    ChunkSize = 1024
+
+    // The default hash size in bytes
+    Size = 32
+
+    KeySize = 32
+)
+
+// domain separation flags
+const (
+    flagChunkStart        = 1 << 0
+    flagChunkEnd          = 1 << 1
+    flagParent            = 1 << 2
+    flagRoot              = 1 << 3
+    flagKeyedHash         = 1 << 4
+    flagDeriveKeyContext  = 1 << 5
+    flagDeriveKeyMaterial = 1 << 6
+)
+
+var iv = [8]uint32{
+    0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A,
+    0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+}
+
+// message word schedule of every round
+var schedule = [7][16]uint8{
+    {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+    {2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8},
+    {3, 4, 10, 12, 13, 2, 7, 14, 6, 5, 9, 0, 11, 15, 8, 1},
+    {10, 7, 12, 9, 14, 3, 13, 15, 4, 0, 11, 2, 5, 8, 1, 6},
+    {12, 13, 9, 11, 15, 10, 14, 8, 7, 2, 5, 3, 0, 1, 6, 4},
+    {9, 14, 11, 5, 8, 12, 15, 1, 13, 3, 0, 10, 2, 6, 4, 7},
+    {11, 15, 5, 0, 1, 9, 8, 6, 14, 10, 2, 12, 3, 4, 7, 13},
+}
+
+func g(a, b, c, d, x, y uint32) (uint32, uint32, uint32, uint32) {
+    a += b + x
+    d = bits.RotateLeft32(d ^ a, -16)
+    c += d
+    b = bits.RotateLeft32(b ^ c, -12)
+    a += b + y
+    d = bits.RotateLeft32(d ^ a, -8)
+    c += d
+    b = bits.RotateLeft32(b ^ c, -7)
+
+    return a, b, c, d
+}
+
+// compress returns the full 16 words of the compression function output.
+func compress(cv *[8]uint32, m *[16]uint32, counter uint64, blockLen uint32, flags uint32) [16]uint32 {
+    v0, v1, v2, v3 := cv[0], cv[1], cv[2], cv[3]
+    v4, v5, v6, v7 := cv[4], cv[5], cv[6], cv[7]
+    v8, v9, v10, v11 := iv[0], iv[1], iv[2], iv[3]
+    v12, v13, v14, v15 := uint32(counter), uint32(counter >> 32), blockLen, flags
+
+    for r := 0; r < 7; r++ {
+        s := &schedule[r]
+
+        v0, v4, v8, v12 = g(v0, v4, v8, v12, m[s[0]], m[s[1]])
+        v1, v5, v9, v13 = g(v1, v5, v9, v13, m[s[2]], m[s[3]])
+        v2, v6, v10, v14 = g(v2, v6, v10, v14, m[s[4]], m[s[5]])
+        v3, v7, v11, v15 = g(v3, v7, v11, v15, m[s[6]], m[s[7]])
+
+        v0, v5, v10, v15 = g(v0, v5, v10, v15, m[s[8]], m[s[9]])
+        v1, v6, v11, v12 = g(v1, v6, v11, v12, m[s[10]], m[s[11]])
+        v2, v7, v8, v13 = g(v2, v7, v8, v13, m[s[12]], m[s[13]])
+        v3, v4, v9, v14 = g(v3, v4, v9, v14, m[s[14]], m[s[15]])
+    }
+
+    return [16]uint32{
+        v0 ^ v8, v1 ^ v9, v2 ^ v10, v3 ^ v11,
+        v4 ^ v12, v5 ^ v13, v6 ^ v14, v7 ^ v15,
+        v8 ^ cv[0], v9 ^ cv[1], v10 ^ cv[2], v11 ^ cv[3],
+        v12 ^ cv[4], v13 ^ cv[5], v14 ^ cv[6], v15 ^ cv[7],
+    }
+}
+
+func compressCV(cv *[8]uint32, m *[16]uint32, counter uint64, blockLen uint32, flags uint32) (out [8]uint32) {
+    full := compress(cv, m, counter, blockLen, flags)
+    copy(out[:], full[:8])
+
+    return
+}
+
+func bytesToWords(b []byte, m *[16]uint32) {
+    var block [BlockSize]byte
+    copy(block[:], b)
+
+    for i := range m {
+        m[i] = binary.LittleEndian.Uint32(block[4*i:])
+    }
+}
+
+func keyToWords(key []byte) (out [8]uint32) {
+    for i := range out {
+        out[i] = binary.LittleEndian.Uint32(key[4*i:])
+    }
+
+    return
+}
+
+// output is a node that has not been compressed yet,
+// it gives a chaining value or the root output.
+type output struct {
+    cv       [8]uint32
+    block    [16]uint32
+    counter  uint64
+    blockLen uint32
+    flags    uint32
+}
+
+func (o *output) chainingValue() [8]uint32 {
+    return compressCV(&o.cv, &o.block, o.counter, o.blockLen, o.flags)
+}
+
+// rootBlock returns the block of 64 bytes of root output at blockCounter.
+func (o *output) rootBlock(blockCounter uint64, out *[BlockSize]byte) {
+    words := compress(&o.cv, &o.block, blockCounter, o.blockLen, o.flags | flagRoot)
+
+    for i, w := range words {
+        binary.LittleEndian.PutUint32(out[4*i:], w)
+    }
+}
+
+func parentOutput(left, right [8]uint32, key *[8]uint32, flags uint32) output {
+    o := output{
+        cv:       *key,
+        blockLen: BlockSize,
+        flags:    flags | flagParent,
+    }
+
+    copy(o.block[:8], left[:])
+    copy(o.block[8:], right[:])
+
+    return o
+}
+
+// chunkState hashes the blocks of one chunk.
+type chunkState struct {
+    cv      [8]uint32
+    counter uint64
+
+    block    [BlockSize]byte
+    blockLen int
+
+    blocksCompressed int
+    flags            uint32
+}
+
+func newChunkState(key *[8]uint32, counter uint64, flags uint32) chunkState {
+    return chunkState{
+        cv:      *key,
+        counter: counter,
+        flags:   flags,
+    }
+}
+
+func (c *chunkState) len() int {
+    return c.blocksCompressed * BlockSize + c.blockLen
+}
+
+func (c *chunkState) startFlag() uint32 {
+    if c.blocksCompressed == 0 {
+        return flagChunkStart
+    }
+
+    return 0
+}
+
+func (c *chunkState) update(p []byte) {
+    var m [16]uint32
+
+    for len(p) > 0 {
+        // compress a full block only when more input follows,
+        // the last block is compressed by output
+        if c.blockLen == BlockSize {
+            bytesToWords(c.block[:], &m)
+            c.cv = compressCV(&c.cv, &m, c.counter, BlockSize, c.flags | c.startFlag())
+
+            c.blocksCompressed++
+            c.blockLen = 0
+        }
+
+        n := copy(c.block[c.blockLen:], p)
+        c.blockLen += n
+        p = p[n:]
+    }
+}
+
+func (c *chunkState) output() output {
+    o := output{
+        cv:       c.cv,
+        counter:  c.counter,
+        blockLen: uint32(c.blockLen),
+        flags:    c.flags | c.startFlag() | flagChunkEnd,
+    }
+
+    bytesToWords(c.block[:c.blockLen], &o.block)
+
+    return o
+}
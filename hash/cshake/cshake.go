@@ -0,0 +1,109 @@
+// Package cshake implements cSHAKE128 and cSHAKE256 and the string
+// encodings of NIST SP 800-185.
+package cshake
+
+import (
+    "io"
+    "hash"
+    "math/bits"
+    "encoding/binary"
+
+    "golang.org/x/crypto/sha3"
+)
+
+// rate in bytes of the SHA-3 sponge
+const (
+    Rate128 = 168
+    Rate256 = 136
+)
+
+// XOF is a hash.Hash that can produce output of any length.
+// Sum returns Size bytes, Read reads more output and
+// after the first Read no more data can be written.
+type XOF interface {
+    hash.Hash
+    io.Reader
+}
+
+// New128 returns a new cSHAKE128 with the function name N
+// and the customization string S.
+func New128(N, S []byte) sha3.ShakeHash {
+    return sha3.NewCShake128(N, S)
+}
+
+// New256 returns a new cSHAKE256 with the function name N
+// and the customization string S.
+func New256(N, S []byte) sha3.ShakeHash {
+    return sha3.NewCShake256(N, S)
+}
+
+// Sum128 returns length bytes of cSHAKE128(data, length*8, N, S).
+func Sum128(data []byte, length int, N, S []byte) []byte {
+    h := New128(N, S)
+    h.Write(data)
+
+    out := make([]byte, length)
+    h.Read(out)
+
+    return out
+}
+
+// Sum256 returns length bytes of cSHAKE256(data, length*8, N, S).
+func Sum256(data []byte, length int, N, S []byte) []byte {
+    h := New256(N, S)
+    h.Write(data)
+
+    out := make([]byte, length)
+    h.Read(out)
+
+    return out
+}
+
+// LeftEncode returns left_encode(x).
+func LeftEncode(x uint64) []byte {
+    n := (bits.Len64(x) + 7) / 8
+    if n == 0 {
+        n = 1
+    }
+
+    var b [9]byte
+    binary.BigEndian.PutUint64(b[1:], x)
+
+    out := b[8-n:]
+    out[0] = byte(n)
+
+    return out
+}
+
+// RightEncode returns right_encode(x).
+func RightEncode(x uint64) []byte {
+    n := (bits.Len64(x) + 7) / 8
+    if n == 0 {
+        n = 1
+    }
+
+    var b [9]byte
+    binary.BigEndian.PutUint64(b[:8], x)
+    b[8] = byte(n)
+
+    return b[8-n:]
+}
+
+// EncodeString returns encode_string(s).
+func EncodeString(s []byte) []byte {
+    out := LeftEncode(uint64(len(s)) * 8)
+
+    return append(out, s...)
+}
+
+// Bytepad returns bytepad(x, w).
+func Bytepad(x []byte, w int) []byte {
+    out := LeftEncode(uint64(w))
+    out = append(out, x...)
+
+    if pad := len(out) % w; pad != 0 {
+        out = append(out, make([]byte, w - pad)...)
+    }
+
+    return out
+}
@@ -0,0 +1,76 @@
+package cshake
+
+import (
+    "bytes"
+    "testing"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+func testData(n int) []byte {
+    data := make([]byte, n)
+    for i := range data {
+        data[i] = byte(i)
+    }
+
+    return data
+}
+
+// SP 800-185 cSHAKE samples
+func Test_Sum(t *testing.T) {
+    tests := []struct {
+        bits int
+        data []byte
+        N    string
+        S    string
+        out  string
+    }{
+        {128, testData(4), "", "Email Signature", "c1c36925b6409a04f1b504fcbca9d82b4017277cb5ed2b2065fc1d3814d5aaf5"},
+        {128, testData(200), "", "Email Signature", "c5221d50e4f822d96a2e8881a961420f294b7b24fe3d2094baed2c6524cc166b"},
+        {256, testData(4), "", "Email Signature", "d008828e2b80ac9d2218ffee1d070c48b8e4c87bff32c9699d5b6896eee0edd164020e2be0560858d9c00c037e34a96937c561a74c412bb4c746469527281c8c"},
+        {256, testData(200), "", "Email Signature", "07dc27b11e51fbac75bc7b3c1d983e8b4b85fb1defaf218912ac86430273091727f42b17ed1df63e8ec118f04b23633c1dfb1574c8fb55cb45da8e25afb092bb"},
+    }
+
+    for i, td := range tests {
+        want := fromHex(td.out)
+
+        var got []byte
+        if td.bits == 128 {
+            got = Sum128(td.data, len(want), []byte(td.N), []byte(td.S))
+        } else {
+            got = Sum256(td.data, len(want), []byte(td.N), []byte(td.S))
+        }
+
+        if !bytes.Equal(got, want) {
+            t.Errorf("test %d: got %x, want %x", i, got, want)
+        }
+    }
+}
+
+func Test_Encode(t *testing.T) {
+    tests := []struct {
+        got  []byte
+        want string
+    }{
+        {LeftEncode(0), "0100"},
+        {LeftEncode(168), "01a8"},
+        {LeftEncode(256), "020100"},
+        {RightEncode(0), "0001"},
+        {RightEncode(256), "010002"},
+        {RightEncode(1 << 63), "800000000000000008"},
+        {EncodeString([]byte("KMAC")), "01204b4d4143"},
+        {EncodeString(nil), "0100"},
+        {Bytepad([]byte{1, 2}, 4), "01040102"},
+        {Bytepad([]byte{1, 2, 3}, 4), "0104010203000000"},
+    }
+
+    for i, td := range tests {
+        if hex.EncodeToString(td.got) != td.want {
+            t.Errorf("test %d: got %x, want %s", i, td.got, td.want)
+        }
+    }
+}
@@ -0,0 +1,142 @@
+// Package kmac implements KMAC128, KMAC256, KMACXOF128 and
+// KMACXOF256 as defined in NIST SP 800-185.
+package kmac
+
+import (
+    "hash"
+
+    "golang.org/x/crypto/sha3"
+
+    "github.com/deatil/go-cryptobin/hash/cshake"
+)
+
+// The default MAC sizes in bytes
+const (
+    Size128 = 32
+    Size256 = 64
+)
+
+type kmac struct {
+    h sha3.ShakeHash
+
+    // state after absorbing the key, used by Reset
+    init sha3.ShakeHash
+
+    size int
+    rate int
+}
+
+func newKMAC(h sha3.ShakeHash, rate int, key []byte, size int) *kmac {
+    if size <= 0 {
+        panic("go-cryptobin/kmac: invalid size")
+    }
+
+    h.Write(cshake.Bytepad(cshake.EncodeString(key), rate))
+
+    return &kmac{
+        h:    h,
+        init: h.Clone(),
+        size: size,
+        rate: rate,
+    }
+}
+
+// New128 returns a new KMAC128 with size bytes of output
+// and the customization string S.
+func New128(key []byte, size int, S []byte) hash.Hash {
+    return newKMAC(cshake.New128([]byte("KMAC"), S), cshake.Rate128, key, size)
+}
+
+// New256 returns a new KMAC256 with size bytes of output
+// and the customization string S.
+func New256(key []byte, size int, S []byte) hash.Hash {
+    return newKMAC(cshake.New256([]byte("KMAC"), S), cshake.Rate256, key, size)
+}
+
+func (k *kmac) Write(p []byte) (int, error) {
+    return k.h.Write(p)
+}
+
+func (k *kmac) Size() int {
+    return k.size
+}
+
+func (k *kmac) BlockSize() int {
+    return k.rate
+}
+
+func (k *kmac) Reset() {
+    k.h = k.init.Clone()
+}
+
+func (k *kmac) Sum(b []byte) []byte {
+    h := k.h.Clone()
+    h.Write(cshake.RightEncode(uint64(k.size) * 8))
+
+    out := make([]byte, k.size)
+    h.Read(out)
+
+    return append(b, out...)
+}
+
+type kmacXOF struct {
+    *kmac
+
+    reading bool
+}
+
+// NewXOF128 returns a new KMACXOF128 with the customization string S.
+func NewXOF128(key []byte, S []byte) cshake.XOF {
+    return &kmacXOF{
+        kmac: newKMAC(cshake.New128([]byte("KMAC"), S), cshake.Rate128, key, Size128),
+    }
+}
+
+// NewXOF256 returns a new KMACXOF256 with the customization string S.
+func NewXOF256(key []byte, S []byte) cshake.XOF {
+    return &kmacXOF{
+        kmac: newKMAC(cshake.New256([]byte("KMAC"), S), cshake.Rate256, key, Size256),
+    }
+}
+
+func (k *kmacXOF) Reset() {
+    k.kmac.Reset()
+    k.reading = false
+}
+
+func (k *kmacXOF) Sum(b []byte) []byte {
+    h := k.h.Clone()
+    if !k.reading {
+        h.Write(cshake.RightEncode(0))
+    }
+
+    out := make([]byte, k.size)
+    h.Read(out)
+
+    return append(b, out...)
+}
+
+func (k *kmacXOF) Read(p []byte) (int, error) {
+    if !k.reading {
+        k.h.Write(cshake.RightEncode(0))
+        k.reading = true
+    }
+
+    return k.h.Read(p)
+}
+
+// Sum128 returns the KMAC128 of data.
+func Sum128(key, data []byte, size int, S []byte) []byte {
+    h := New128(key, size, S)
+    h.Write(data)
+
+    return h.Sum(nil)
+}
+
+// Sum256 returns the KMAC256 of data.
+func Sum256(key, data []byte, size int, S []byte) []byte {
+    h := New256(key, size, S)
+    h.Write(data)
+
+    return h.Sum(nil)
+}
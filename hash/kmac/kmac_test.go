@@ -0,0 +1,122 @@
+package kmac
+
+import (
+    "io"
+    "bytes"
+    "testing"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+func testData(n int) []byte {
+    data := make([]byte, n)
+    for i := range data {
+        data[i] = byte(i)
+    }
+
+    return data
+}
+
+var (
+    testKey = fromHex("404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f")
+    testS   = "My Tagged Application"
+)
+
+// SP 800-185 KMAC samples
+var testVectors = []struct {
+    bits int
+    xof  bool
+    data []byte
+    S    string
+    out  string
+}{
+    {128, false, testData(4), "", "e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e"},
+    {128, false, testData(4), testS, "3b1fba963cd8b0b59e8c1a6d71888b7143651af8ba0a7070c0979e2811324aa5"},
+    {128, false, testData(200), testS, "1f5b4e6cca02209e0dcb5ca635b89a15e271ecc760071dfd805faa38f9729230"},
+    {256, false, testData(4), testS, "20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd"},
+    {256, false, testData(200), "", "75358cf39e41494e949707927cee0af20a3ff553904c86b08f21cc414bcfd691589d27cf5e15369cbbff8b9a4c2eb17800855d0235ff635da82533ec6b759b69"},
+    {256, false, testData(200), testS, "b58618f71f92e1d56c1b8c55ddd7cd188b97b4ca4d99831eb2699a837da2e4d970fbacfde50033aea585f1a2708510c32d07880801bd182898fe476876fc8965"},
+    {128, true, testData(4), "", "cd83740bbd92ccc8cf032b1481a0f4460e7ca9dd12b08a0c4031178bacd6ec35"},
+    {128, true, testData(200), testS, "47026c7cd793084aa0283c253ef658490c0db61438b8326fe9bddf281b83ae0f"},
+    {256, true, testData(4), testS, "1755133f1534752aad0748f2c706fb5c784512cab835cd15676b16c0c6647fa96faa7af634a0bf8ff6df39374fa00fad9a39e322a7c92065a64eb1fb0801eb2b"},
+    {256, true, testData(200), testS, "d5be731c954ed7732846bb59dbe3a8e30f83e77a4bff4459f2f1c2b4ecebb8ce67ba01c62e8ab8578d2d499bd1bb276768781190020a306a97de281dcc30305d"},
+}
+
+func Test_KMAC(t *testing.T) {
+    for i, td := range testVectors {
+        want := fromHex(td.out)
+
+        var h io.Writer
+        var sum func() []byte
+
+        switch {
+            case td.bits == 128 && !td.xof:
+                m := New128(testKey, len(want), []byte(td.S))
+                h, sum = m, func() []byte { return m.Sum(nil) }
+            case td.bits == 256 && !td.xof:
+                m := New256(testKey, len(want), []byte(td.S))
+                h, sum = m, func() []byte { return m.Sum(nil) }
+            case td.bits == 128:
+                m := NewXOF128(testKey, []byte(td.S))
+                h, sum = m, func() []byte { return m.Sum(nil) }
+            default:
+                m := NewXOF256(testKey, []byte(td.S))
+                h, sum = m, func() []byte { return m.Sum(nil) }
+        }
+
+        h.Write(td.data[:len(td.data)/2])
+        h.Write(td.data[len(td.data)/2:])
+
+        if got := sum(); !bytes.Equal(got, want) {
+            t.Errorf("test %d: got %x, want %x", i, got, want)
+        }
+
+        // Sum does not change the state
+        if got := sum(); !bytes.Equal(got, want) {
+            t.Errorf("test %d: second Sum got %x, want %x", i, got, want)
+        }
+    }
+}
+
+func Test_KMAC_Reset(t *testing.T) {
+    h := New128(testKey, 32, nil)
+    h.Write([]byte("garbage"))
+    h.Reset()
+    h.Write(testData(4))
+
+    want := fromHex("e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e")
+    if got := h.Sum(nil); !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+
+    if got := Sum128(testKey, testData(4), 32, nil); !bytes.Equal(got, want) {
+        t.Errorf("Sum128 got %x, want %x", got, want)
+    }
+}
+
+func Test_KMACXOF_Read(t *testing.T) {
+    want := fromHex("cd83740bbd92ccc8cf032b1481a0f4460e7ca9dd12b08a0c4031178bacd6ec358560e17d2d2c2f845fc07526e6f1027e890014fc4f4a9dd7d0d9578b5bb7929b3b8fa06f4366a3a9bad9a6ccc768baa4d51411f1e73da5cbd5e7560ab48fd9f5e2228aaf")
+
+    x := NewXOF128(testKey, nil)
+    x.Write(testData(4))
+
+    got := make([]byte, len(want))
+    io.ReadFull(x, got[:7])
+    io.ReadFull(x, got[7:])
+
+    if !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+
+    x.Reset()
+    x.Write(testData(4))
+    io.ReadFull(x, got)
+
+    if !bytes.Equal(got, want) {
+        t.Errorf("after Reset got %x, want %x", got, want)
+    }
+}
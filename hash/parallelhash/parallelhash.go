@@ -0,0 +1,269 @@
+// Package parallelhash implements ParallelHash128, ParallelHash256 and
+// their XOF variants as defined in NIST SP 800-185.
+// The blocks of large writes are hashed on multiple goroutines.
+package parallelhash
+
+import (
+    "hash"
+    "sync"
+    "runtime"
+
+    "golang.org/x/crypto/sha3"
+
+    "github.com/deatil/go-cryptobin/hash/cshake"
+)
+
+// The default sizes in bytes
+const (
+    Size128 = 32
+    Size256 = 64
+)
+
+type parallelHash struct {
+    h    sha3.ShakeHash
+    init sha3.ShakeHash
+
+    // cSHAKE(X_i, 2 * security strength, "", "")
+    inner    func(data []byte, out []byte)
+    innerLen int
+
+    blockSize int
+    size      int
+    rate      int
+
+    buf []byte
+    n   uint64
+}
+
+func newParallelHash(bits int, blockSize, size int, S []byte) *parallelHash {
+    if blockSize <= 0 {
+        panic("go-cryptobin/parallelhash: invalid block size")
+    }
+
+    if size <= 0 {
+        panic("go-cryptobin/parallelhash: invalid size")
+    }
+
+    p := &parallelHash{
+        blockSize: blockSize,
+        size:      size,
+    }
+
+    if bits == 128 {
+        p.h = cshake.New128([]byte("ParallelHash"), S)
+        p.rate = cshake.Rate128
+        p.innerLen = 32
+        p.inner = func(data, out []byte) {
+            sha3.ShakeSum128(out, data)
+        }
+    } else {
+        p.h = cshake.New256([]byte("ParallelHash"), S)
+        p.rate = cshake.Rate256
+        p.innerLen = 64
+        p.inner = func(data, out []byte) {
+            sha3.ShakeSum256(out, data)
+        }
+    }
+
+    p.h.Write(cshake.LeftEncode(uint64(blockSize)))
+    p.init = p.h.Clone()
+
+    return p
+}
+
+// New128 returns a new ParallelHash128 with blockSize bytes
+// of input block, size bytes of output and the customization string S.
+func New128(blockSize, size int, S []byte) hash.Hash {
+    return newParallelHash(128, blockSize, size, S)
+}
+
+// New256 returns a new ParallelHash256 with blockSize bytes
+// of input block, size bytes of output and the customization string S.
+func New256(blockSize, size int, S []byte) hash.Hash {
+    return newParallelHash(256, blockSize, size, S)
+}
+
+func (p *parallelHash) Write(data []byte) (int, error) {
+    written := len(data)
+
+    if len(p.buf) > 0 {
+        n := copy(p.buf[len(p.buf):cap(p.buf)], data)
+        p.buf = p.buf[:len(p.buf)+n]
+        data = data[n:]
+
+        if len(p.buf) < p.blockSize {
+            return written, nil
+        }
+
+        p.hashBlocks(p.buf)
+        p.buf = p.buf[:0]
+    }
+
+    full := len(data) - len(data) % p.blockSize
+    if full > 0 {
+        p.hashBlocks(data[:full])
+        data = data[full:]
+    }
+
+    if len(data) > 0 {
+        if p.buf == nil {
+            p.buf = make([]byte, 0, p.blockSize)
+        }
+
+        p.buf = append(p.buf, data...)
+    }
+
+    return written, nil
+}
+
+// hashBlocks hashes the full blocks of data and
+// absorbs the results in order into the outer cSHAKE.
+func (p *parallelHash) hashBlocks(data []byte) {
+    blocks := len(data) / p.blockSize
+    out := make([]byte, blocks * p.innerLen)
+
+    workers := runtime.GOMAXPROCS(0)
+    if workers > blocks {
+        workers = blocks
+    }
+
+    if workers <= 1 {
+        for i := 0; i < blocks; i++ {
+            p.inner(data[i*p.blockSize:(i+1)*p.blockSize], out[i*p.innerLen:(i+1)*p.innerLen])
+        }
+    } else {
+        var wg sync.WaitGroup
+        wg.Add(workers)
+
+        for w := 0; w < workers; w++ {
+            go func(w int) {
+                defer wg.Done()
+
+                for i := w; i < blocks; i += workers {
+                    p.inner(data[i*p.blockSize:(i+1)*p.blockSize], out[i*p.innerLen:(i+1)*p.innerLen])
+                }
+            }(w)
+        }
+
+        wg.Wait()
+    }
+
+    p.h.Write(out)
+    p.n += uint64(blocks)
+}
+
+func (p *parallelHash) Size() int {
+    return p.size
+}
+
+func (p *parallelHash) BlockSize() int {
+    return p.rate
+}
+
+func (p *parallelHash) Reset() {
+    p.h = p.init.Clone()
+    p.buf = p.buf[:0]
+    p.n = 0
+}
+
+// final returns the outer cSHAKE with the last block and the
+// encoded lengths absorbed, ready to be read.
+func (p *parallelHash) final(outBits uint64) sha3.ShakeHash {
+    h := p.h.Clone()
+    n := p.n
+
+    if len(p.buf) > 0 {
+        out := make([]byte, p.innerLen)
+        p.inner(p.buf, out)
+
+        h.Write(out)
+        n++
+    }
+
+    h.Write(cshake.RightEncode(n))
+    h.Write(cshake.RightEncode(outBits))
+
+    return h
+}
+
+func (p *parallelHash) Sum(b []byte) []byte {
+    h := p.final(uint64(p.size) * 8)
+
+    out := make([]byte, p.size)
+    h.Read(out)
+
+    return append(b, out...)
+}
+
+type parallelHashXOF struct {
+    *parallelHash
+
+    reader sha3.ShakeHash
+}
+
+// NewXOF128 returns a new ParallelHashXOF128 with blockSize bytes
+// of input block and the customization string S.
+func NewXOF128(blockSize int, S []byte) cshake.XOF {
+    return &parallelHashXOF{
+        parallelHash: newParallelHash(128, blockSize, Size128, S),
+    }
+}
+
+// NewXOF256 returns a new ParallelHashXOF256 with blockSize bytes
+// of input block and the customization string S.
+func NewXOF256(blockSize int, S []byte) cshake.XOF {
+    return &parallelHashXOF{
+        parallelHash: newParallelHash(256, blockSize, Size256, S),
+    }
+}
+
+func (p *parallelHashXOF) Write(data []byte) (int, error) {
+    if p.reader != nil {
+        panic("go-cryptobin/parallelhash: Write after Read")
+    }
+
+    return p.parallelHash.Write(data)
+}
+
+func (p *parallelHashXOF) Reset() {
+    p.parallelHash.Reset()
+    p.reader = nil
+}
+
+func (p *parallelHashXOF) Sum(b []byte) []byte {
+    var h sha3.ShakeHash
+    if p.reader != nil {
+        h = p.reader.Clone()
+    } else {
+        h = p.final(0)
+    }
+
+    out := make([]byte, p.size)
+    h.Read(out)
+
+    return append(b, out...)
+}
+
+func (p *parallelHashXOF) Read(out []byte) (int, error) {
+    if p.reader == nil {
+        p.reader = p.final(0)
+    }
+
+    return p.reader.Read(out)
+}
+
+// Sum128 returns the ParallelHash128 of data.
+func Sum128(data []byte, blockSize, size int, S []byte) []byte {
+    h := New128(blockSize, size, S)
+    h.Write(data)
+
+    return h.Sum(nil)
+}
+
+// Sum256 returns the ParallelHash256 of data.
+func Sum256(data []byte, blockSize, size int, S []byte) []byte {
+    h := New256(blockSize, size, S)
+    h.Write(data)
+
+    return h.Sum(nil)
+}
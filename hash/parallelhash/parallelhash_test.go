@@ -0,0 +1,136 @@
+package parallelhash
+
+import (
+    "io"
+    "hash"
+    "bytes"
+    "testing"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+var testX = fromHex("000102030405060710111213141516172021222324252627")
+
+// SP 800-185 ParallelHash samples
+var testVectors = []struct {
+    bits int
+    xof  bool
+    S    string
+    out  string
+}{
+    {128, false, "", "ba8dc1d1d979331d3f813603c67f72609ab5e44b94a0b8f9af46514454a2b4f5"},
+    {128, false, "Parallel Data", "fc484dcb3f84dceedc353438151bee58157d6efed0445a81f165e495795b7206"},
+    {128, true, "", "fe47d661e49ffe5b7d999922c062356750caf552985b8e8ce6667f2727c3c8d3"},
+    {128, true, "Parallel Data", "ea2a793140820f7a128b8eb70a9439f93257c6e6e79b4a540d291d6dae7098d7"},
+    {256, false, "", "bc1ef124da34495e948ead207dd9842235da432d2bbc54b4c110e64c451105531b7f2a3e0ce055c02805e7c2de1fb746af97a1dd01f43b824e31b87612410429"},
+    {256, false, "Parallel Data", "cdf15289b54f6212b4bc270528b49526006dd9b54e2b6add1ef6900dda3963bb33a72491f236969ca8afaea29c682d47a393c065b38e29fae651a2091c833110"},
+    {256, true, "", "c10a052722614684144d28474850b410757e3cba87651ba167a5cbddff7f466675fbf84bcae7378ac444be681d729499afca667fb879348bfdda427863c82f1c"},
+    {256, true, "Parallel Data", "538e105f1a22f44ed2f5cc1674fbd40be803d9c99bf5f8d90a2c8193f3fe6ea768e5c1a20987e2c9c65febed03887a51d35624ed12377594b5585541dc377efc"},
+}
+
+func Test_ParallelHash(t *testing.T) {
+    for i, td := range testVectors {
+        want := fromHex(td.out)
+
+        var got []byte
+        switch {
+            case td.bits == 128 && !td.xof:
+                got = Sum128(testX, 8, len(want), []byte(td.S))
+            case td.bits == 256 && !td.xof:
+                got = Sum256(testX, 8, len(want), []byte(td.S))
+            default:
+                x := NewXOF128(8, []byte(td.S))
+                if td.bits == 256 {
+                    x = NewXOF256(8, []byte(td.S))
+                }
+
+                x.Write(testX)
+
+                got = make([]byte, len(want))
+                io.ReadFull(x, got)
+        }
+
+        if !bytes.Equal(got, want) {
+            t.Errorf("test %d: got %x, want %x", i, got, want)
+        }
+    }
+}
+
+func testData(n int) []byte {
+    data := make([]byte, n)
+    for i := range data {
+        data[i] = byte((i * 7) % 251)
+    }
+
+    return data
+}
+
+func Test_ParallelHash_Large(t *testing.T) {
+    data := testData(10000)
+
+    tests := []struct {
+        h    func() hash.Hash
+        want string
+    }{
+        {
+            func() hash.Hash { return New128(64, 32, []byte("x")) },
+            "f1063ac2ee4e0732d71de6242dec1fd3e0770b0d76587002564a7891aa357afc",
+        },
+        {
+            func() hash.Hash { return New256(1000, 48, nil) },
+            "08ef161acbe6f0b43692b2f10d10fa82b38f25f31e246d05b8db0c6cd08e03d87da2613a7f65a72182b23f43a50585eb",
+        },
+    }
+
+    for i, td := range tests {
+        want := fromHex(td.want)
+
+        // one write
+        h := td.h()
+        h.Write(data)
+        if got := h.Sum(nil); !bytes.Equal(got, want) {
+            t.Errorf("test %d: got %x, want %x", i, got, want)
+        }
+
+        // uneven writes
+        for _, step := range []int{1, 7, 63, 65, 999, 3001} {
+            h := td.h()
+            for off := 0; off < len(data); off += step {
+                end := off + step
+                if end > len(data) {
+                    end = len(data)
+                }
+
+                h.Write(data[off:end])
+            }
+
+            if got := h.Sum(nil); !bytes.Equal(got, want) {
+                t.Errorf("test %d step %d: got %x, want %x", i, step, got, want)
+            }
+        }
+    }
+}
+
+func Test_ParallelHash_Empty(t *testing.T) {
+    want := fromHex("96427c30224408859f95e89e4fa84e1c7a1478dbf2008ac982ce61a77f37a272")
+
+    if got := Sum128(nil, 8, 32, nil); !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+}
+
+func Test_ParallelHash_Reset(t *testing.T) {
+    h := New128(8, 32, nil)
+    h.Write([]byte("garbage"))
+    h.Reset()
+    h.Write(testX)
+
+    want := fromHex(testVectors[0].out)
+    if got := h.Sum(nil); !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+}
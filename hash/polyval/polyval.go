@@ -0,0 +1,175 @@
+// Package polyval implements POLYVAL, the universal hash defined in
+// RFC 8452 section 3. It is used by AES-GCM-SIV and HCTR2.
+package polyval
+
+import (
+    "hash"
+    "errors"
+    "math/bits"
+    "encoding/binary"
+)
+
+const (
+    // The size of a POLYVAL checksum in bytes.
+    Size = 16
+
+    // The blocksize of POLYVAL in bytes.
+    BlockSize = 16
+)
+
+var errKeySize = errors.New("go-cryptobin/polyval: invalid key size")
+
+// 域元素, 小端序, lo 为低 64 位
+type fieldElement struct {
+    lo, hi uint64
+}
+
+// Polyval 为 POLYVAL 哈希. 输入不足一个分组时在 Sum 中补零.
+type Polyval struct {
+    h   fieldElement
+    s   fieldElement
+    buf [BlockSize]byte
+    n   int
+}
+
+// New returns a new POLYVAL with the 16 bytes key H.
+func New(key []byte) (*Polyval, error) {
+    if len(key) != Size {
+        return nil, errKeySize
+    }
+
+    p := &Polyval{}
+    p.h = fieldElement{
+        lo: binary.LittleEndian.Uint64(key[0:]),
+        hi: binary.LittleEndian.Uint64(key[8:]),
+    }
+
+    return p, nil
+}
+
+var _ hash.Hash = (*Polyval)(nil)
+
+func (p *Polyval) Size() int {
+    return Size
+}
+
+func (p *Polyval) BlockSize() int {
+    return BlockSize
+}
+
+func (p *Polyval) Reset() {
+    p.s = fieldElement{}
+    p.n = 0
+}
+
+func (p *Polyval) Write(data []byte) (int, error) {
+    nn := len(data)
+
+    if p.n > 0 {
+        n := copy(p.buf[p.n:], data)
+        p.n += n
+        data = data[n:]
+
+        if p.n < BlockSize {
+            return nn, nil
+        }
+
+        p.update(p.buf[:])
+        p.n = 0
+    }
+
+    full := len(data) - len(data) % BlockSize
+    p.update(data[:full])
+
+    p.n = copy(p.buf[:], data[full:])
+
+    return nn, nil
+}
+
+// Sum appends the current checksum to b. A trailing partial
+// block is padded with zeros.
+func (p *Polyval) Sum(b []byte) []byte {
+    s := p.s
+
+    if p.n > 0 {
+        var block [BlockSize]byte
+        copy(block[:], p.buf[:p.n])
+
+        s.lo ^= binary.LittleEndian.Uint64(block[0:])
+        s.hi ^= binary.LittleEndian.Uint64(block[8:])
+        s = dot(s, p.h)
+    }
+
+    var out [Size]byte
+    binary.LittleEndian.PutUint64(out[0:], s.lo)
+    binary.LittleEndian.PutUint64(out[8:], s.hi)
+
+    return append(b, out[:]...)
+}
+
+// S_j = dot(S_{j-1} + X_j, H)
+func (p *Polyval) update(blocks []byte) {
+    for len(blocks) >= BlockSize {
+        p.s.lo ^= binary.LittleEndian.Uint64(blocks[0:])
+        p.s.hi ^= binary.LittleEndian.Uint64(blocks[8:])
+        p.s = dot(p.s, p.h)
+
+        blocks = blocks[BlockSize:]
+    }
+}
+
+// dot(a, b) = a * b * x^-128 mod x^128 + x^127 + x^126 + x^121 + 1
+func dot(a, b fieldElement) fieldElement {
+    // Karatsuba
+    h0, l0 := clmul(a.lo, b.lo)
+    h2, l2 := clmul(a.hi, b.hi)
+    h1, l1 := clmul(a.lo ^ a.hi, b.lo ^ b.hi)
+
+    h1 ^= h0 ^ h2
+    l1 ^= l0 ^ l2
+
+    d0 := l0
+    d1 := h0 ^ l1
+    d2 := l2 ^ h1
+    d3 := h2
+
+    // 两次折叠约去低 128 位, 多项式模 x^64 为 1
+    w0 := d1 ^ (d0 << 63) ^ (d0 << 62) ^ (d0 << 57)
+    w1 := d2 ^ d0 ^ (d0 >> 1) ^ (d0 >> 2) ^ (d0 >> 7)
+
+    r0 := w1 ^ (w0 << 63) ^ (w0 << 62) ^ (w0 << 57)
+    r1 := d3 ^ w0 ^ (w0 >> 1) ^ (w0 >> 2) ^ (w0 >> 7)
+
+    return fieldElement{r0, r1}
+}
+
+// 64 位无进位乘法, 常量时间
+func clmul(x, y uint64) (hi, lo uint64) {
+    lo = bmul64(x, y)
+    hi = bits.Reverse64(bmul64(bits.Reverse64(x), bits.Reverse64(y))) >> 1
+    return
+}
+
+// 用整数乘法计算无进位乘积的低 64 位, 每 4 位留出进位空间
+func bmul64(x, y uint64) uint64 {
+    x0 := x & 0x1111111111111111
+    x1 := x & 0x2222222222222222
+    x2 := x & 0x4444444444444444
+    x3 := x & 0x8888888888888888
+    y0 := y & 0x1111111111111111
+    y1 := y & 0x2222222222222222
+    y2 := y & 0x4444444444444444
+    y3 := y & 0x8888888888888888
+
+    z0 := (x0 * y0) ^ (x1 * y3) ^ (x2 * y2) ^ (x3 * y1)
+    z1 := (x0 * y1) ^ (x1 * y0) ^ (x2 * y3) ^ (x3 * y2)
+    z2 := (x0 * y2) ^ (x1 * y1) ^ (x2 * y0) ^ (x3 * y3)
+    z3 := (x0 * y3) ^ (x1 * y2) ^ (x2 * y1) ^ (x3 * y0)
+
+    z0 &= 0x1111111111111111
+    z1 &= 0x2222222222222222
+    z2 &= 0x4444444444444444
+    z3 &= 0x8888888888888888
+
+    return z0 | z1 | z2 | z3
+}
@@ -0,0 +1,56 @@
+package polyval
+
+import (
+    "bytes"
+    "testing"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// RFC 8452 Appendix A
+func Test_Vector(t *testing.T) {
+    h := fromHex("25629347589242761d31f826ba4b757b")
+    x := fromHex("4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362")
+    want := fromHex("f7a3b47b846119fae5b7866cf5e5b77e")
+
+    p, err := New(h)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    p.Write(x)
+    if got := p.Sum(nil); !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+
+    // 分段写入
+    p.Reset()
+    p.Write(x[:5])
+    p.Write(x[5:21])
+    p.Write(x[21:])
+    if got := p.Sum(nil); !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+}
+
+func Test_Padding(t *testing.T) {
+    h := fromHex("25629347589242761d31f826ba4b757b")
+
+    p1, _ := New(h)
+    p1.Write([]byte("abc"))
+
+    p2, _ := New(h)
+    p2.Write(append([]byte("abc"), make([]byte, 13)...))
+
+    if !bytes.Equal(p1.Sum(nil), p2.Sum(nil)) {
+        t.Error("partial block should be zero padded")
+    }
+
+    if _, err := New(h[:15]); err == nil {
+        t.Error("should fail with short key")
+    }
+}
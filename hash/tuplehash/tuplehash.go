@@ -0,0 +1,148 @@
+// Package tuplehash implements TupleHash128, TupleHash256 and their
+// XOF variants as defined in NIST SP 800-185.
+//
+// Every call to Write adds one element to the tuple, so
+// Write([]byte("ab")) differs from Write([]byte("a")) and Write([]byte("b")).
+package tuplehash
+
+import (
+    "hash"
+
+    "golang.org/x/crypto/sha3"
+
+    "github.com/deatil/go-cryptobin/hash/cshake"
+)
+
+// The default sizes in bytes
+const (
+    Size128 = 32
+    Size256 = 64
+)
+
+type tupleHash struct {
+    h    sha3.ShakeHash
+    init sha3.ShakeHash
+
+    size int
+    rate int
+}
+
+func newTupleHash(h sha3.ShakeHash, rate int, size int) *tupleHash {
+    if size <= 0 {
+        panic("go-cryptobin/tuplehash: invalid size")
+    }
+
+    return &tupleHash{
+        h:    h,
+        init: h.Clone(),
+        size: size,
+        rate: rate,
+    }
+}
+
+// New128 returns a new TupleHash128 with size bytes of output
+// and the customization string S.
+func New128(size int, S []byte) hash.Hash {
+    return newTupleHash(cshake.New128([]byte("TupleHash"), S), cshake.Rate128, size)
+}
+
+// New256 returns a new TupleHash256 with size bytes of output
+// and the customization string S.
+func New256(size int, S []byte) hash.Hash {
+    return newTupleHash(cshake.New256([]byte("TupleHash"), S), cshake.Rate256, size)
+}
+
+// Write adds p as one element of the tuple.
+func (t *tupleHash) Write(p []byte) (int, error) {
+    t.h.Write(cshake.EncodeString(p))
+
+    return len(p), nil
+}
+
+func (t *tupleHash) Size() int {
+    return t.size
+}
+
+func (t *tupleHash) BlockSize() int {
+    return t.rate
+}
+
+func (t *tupleHash) Reset() {
+    t.h = t.init.Clone()
+}
+
+func (t *tupleHash) Sum(b []byte) []byte {
+    h := t.h.Clone()
+    h.Write(cshake.RightEncode(uint64(t.size) * 8))
+
+    out := make([]byte, t.size)
+    h.Read(out)
+
+    return append(b, out...)
+}
+
+type tupleHashXOF struct {
+    *tupleHash
+
+    reading bool
+}
+
+// NewXOF128 returns a new TupleHashXOF128 with the customization string S.
+func NewXOF128(S []byte) cshake.XOF {
+    return &tupleHashXOF{
+        tupleHash: newTupleHash(cshake.New128([]byte("TupleHash"), S), cshake.Rate128, Size128),
+    }
+}
+
+// NewXOF256 returns a new TupleHashXOF256 with the customization string S.
+func NewXOF256(S []byte) cshake.XOF {
+    return &tupleHashXOF{
+        tupleHash: newTupleHash(cshake.New256([]byte("TupleHash"), S), cshake.Rate256, Size256),
+    }
+}
+
+func (t *tupleHashXOF) Reset() {
+    t.tupleHash.Reset()
+    t.reading = false
+}
+
+func (t *tupleHashXOF) Sum(b []byte) []byte {
+    h := t.h.Clone()
+    if !t.reading {
+        h.Write(cshake.RightEncode(0))
+    }
+
+    out := make([]byte, t.size)
+    h.Read(out)
+
+    return append(b, out...)
+}
+
+func (t *tupleHashXOF) Read(p []byte) (int, error) {
+    if !t.reading {
+        t.h.Write(cshake.RightEncode(0))
+        t.reading = true
+    }
+
+    return t.h.Read(p)
+}
+
+// Sum128 returns the TupleHash128 of the tuple.
+func Sum128(tuple [][]byte, size int, S []byte) []byte {
+    h := New128(size, S)
+    for _, v := range tuple {
+        h.Write(v)
+    }
+
+    return h.Sum(nil)
+}
+
+// Sum256 returns the TupleHash256 of the tuple.
+func Sum256(tuple [][]byte, size int, S []byte) []byte {
+    h := New256(size, S)
+    for _, v := range tuple {
+        h.Write(v)
+    }
+
+    return h.Sum(nil)
+}
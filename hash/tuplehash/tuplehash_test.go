@@ -0,0 +1,111 @@
+package tuplehash
+
+import (
+    "io"
+    "bytes"
+    "testing"
+    "encoding/hex"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+var (
+    tuple2 = [][]byte{
+        fromHex("000102"),
+        fromHex("101112131415"),
+    }
+    tuple3 = [][]byte{
+        fromHex("000102"),
+        fromHex("101112131415"),
+        fromHex("202122232425262728"),
+    }
+)
+
+// SP 800-185 TupleHash samples
+var testVectors = []struct {
+    bits  int
+    xof   bool
+    tuple [][]byte
+    S     string
+    out   string
+}{
+    {128, false, tuple2, "", "c5d8786c1afb9b82111ab34b65b2c0048fa64e6d48e263264ce1707d3ffc8ed1"},
+    {128, false, tuple2, "My Tuple App", "75cdb20ff4db1154e841d758e24160c54bae86eb8c13e7f5f40eb35588e96dfb"},
+    {128, false, tuple3, "My Tuple App", "e60f202c89a2631eda8d4c588ca5fd07f39e5151998deccf973adb3804bb6e84"},
+    {256, false, tuple2, "", "cfb7058caca5e668f81a12a20a2195ce97a925f1dba3e7449a56f82201ec607311ac2696b1ab5ea2352df1423bde7bd4bb78c9aed1a853c78672f9eb23bbe194"},
+    {256, false, tuple2, "My Tuple App", "147c2191d5ed7efd98dbd96d7ab5a11692576f5fe2a5065f3e33de6bba9f3aa1c4e9a068a289c61c95aab30aee1e410b0b607de3620e24a4e3bf9852a1d4367e"},
+    {256, false, tuple3, "My Tuple App", "45000be63f9b6bfd89f54717670f69a9bc763591a4f05c50d68891a744bcc6e7d6d5b5e82c018da999ed35b0bb49c9678e526abd8e85c13ed254021db9e790ce"},
+    {128, true, tuple2, "", "2f103cd7c32320353495c68de1a8129245c6325f6f2a3d608d92179c96e68488"},
+    {128, true, tuple2, "My Tuple App", "3fc8ad69453128292859a18b6c67d7ad85f01b32815e22ce839c49ec374e9b9a"},
+    {128, true, tuple3, "My Tuple App", "900fe16cad098d28e74d632ed852f99daab7f7df4d99e775657885b4bf76d6f8"},
+    {256, true, tuple2, "", "03ded4610ed6450a1e3f8bc44951d14fbc384ab0efe57b000df6b6df5aae7cd568e77377daf13f37ec75cf5fc598b6841d51dd207c991cd45d210ba60ac52eb9"},
+    {256, true, tuple2, "My Tuple App", "6483cb3c9952eb20e830af4785851fc597ee3bf93bb7602c0ef6a65d741aeca7e63c3b128981aa05c6d27438c79d2754bb1b7191f125d6620fca12ce658b2442"},
+    {256, true, tuple3, "My Tuple App", "0c59b11464f2336c34663ed51b2b950bec743610856f36c28d1d088d8a2446284dd09830a6a178dc752376199fae935d86cfdee5913d4922dfd369b66a53c897"},
+}
+
+func Test_TupleHash(t *testing.T) {
+    for i, td := range testVectors {
+        want := fromHex(td.out)
+
+        var got []byte
+        switch {
+            case td.bits == 128 && !td.xof:
+                got = Sum128(td.tuple, len(want), []byte(td.S))
+            case td.bits == 256 && !td.xof:
+                got = Sum256(td.tuple, len(want), []byte(td.S))
+            default:
+                x := NewXOF128([]byte(td.S))
+                if td.bits == 256 {
+                    x = NewXOF256([]byte(td.S))
+                }
+
+                for _, v := range td.tuple {
+                    x.Write(v)
+                }
+
+                got = make([]byte, len(want))
+                io.ReadFull(x, got)
+        }
+
+        if !bytes.Equal(got, want) {
+            t.Errorf("test %d: got %x, want %x", i, got, want)
+        }
+    }
+}
+
+func Test_TupleHash_Elements(t *testing.T) {
+    empty := Sum128(nil, 32, nil)
+    oneEmpty := Sum128([][]byte{{}}, 32, nil)
+
+    if !bytes.Equal(empty, fromHex("786aa3d4fcaadf0aa723a4818a1a72de2330d613e5de7ae4eb6cb4cdd26adba2")) {
+        t.Errorf("empty tuple got %x", empty)
+    }
+
+    if !bytes.Equal(oneEmpty, fromHex("549330469327c593eb95b1d467c48e5781939e135e10632c804ef8a69c73281c")) {
+        t.Errorf("one empty element got %x", oneEmpty)
+    }
+
+    ab := Sum128([][]byte{[]byte("ab")}, 32, nil)
+    aAndB := Sum128([][]byte{[]byte("a"), []byte("b")}, 32, nil)
+    if bytes.Equal(ab, aAndB) {
+        t.Error("tuples with different elements should differ")
+    }
+}
+
+func Test_TupleHash_Reset(t *testing.T) {
+    h := New256(64, []byte("My Tuple App"))
+    h.Write([]byte("garbage"))
+    h.Reset()
+
+    for _, v := range tuple2 {
+        h.Write(v)
+    }
+
+    want := fromHex(testVectors[4].out)
+    if got := h.Sum(nil); !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+}
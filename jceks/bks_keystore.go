@@ -0,0 +1,205 @@
+package jceks
+
+import (
+    "time"
+    "errors"
+    "crypto"
+    "crypto/x509"
+
+    "github.com/deatil/go-cryptobin/keystore"
+)
+
+// 设置带密码的密钥时使用的算法名称
+const bksSecretKeyAlgorithm = "AES"
+
+var (
+    _ keystore.KeyStore = (*BKS)(nil)
+    _ keystore.KeyStore = (*UBER)(nil)
+)
+
+// 别名列表
+func (this *BKS) Aliases() []string {
+    return keystore.SortedAliases(this.entries)
+}
+
+// 条目类型. 未解密的 sealed 条目有证书链时为私钥, 否则为密钥
+func (this *BKS) EntryType(alias string) (keystore.EntryType, error) {
+    entry, ok := this.entries[alias]
+    if !ok {
+        return keystore.UnknownEntry, keystore.ErrEntryNotFound
+    }
+
+    switch t := entry.(type) {
+        case *bksKeyEntry:
+            return bksKeyEntryType(t), nil
+        case *bksSealedKeyEntry:
+            if t.IsDecrypted() && t.nested != nil {
+                return bksKeyEntryType(t.nested), nil
+            }
+
+            if len(t.certChain) > 0 {
+                return keystore.PrivateKeyEntry, nil
+            }
+
+            return keystore.SecretKeyEntry, nil
+        case *bksSecretKeyEntry:
+            return keystore.SecretKeyEntry, nil
+        case *bksTrustedCertEntry:
+            return keystore.TrustedCertEntry, nil
+    }
+
+    return keystore.UnknownEntry, nil
+}
+
+func bksKeyEntryType(entry *bksKeyEntry) keystore.EntryType {
+    switch entry.keyType {
+        case bksKeyTypePrivate:
+            return keystore.PrivateKeyEntry
+        case bksKeyTypeSecret:
+            return keystore.SecretKeyEntry
+    }
+
+    return keystore.UnknownEntry
+}
+
+// 创建时间
+func (this *BKS) CreationDate(alias string) (time.Time, error) {
+    if _, ok := this.entries[alias]; !ok {
+        return time.Time{}, keystore.ErrEntryNotFound
+    }
+
+    return this.GetCreateDate(alias)
+}
+
+// 私钥和证书链, 没有密码的条目忽略 password
+func (this *BKS) PrivateKeyEntry(alias string, password string) (crypto.PrivateKey, []*x509.Certificate, error) {
+    entry, ok := this.entries[alias]
+    if !ok {
+        return nil, nil, keystore.ErrEntryNotFound
+    }
+
+    var private crypto.PrivateKey
+    var err error
+
+    switch entry.(type) {
+        case *bksKeyEntry:
+            private, err = this.GetKeyPrivate(alias)
+        case *bksSealedKeyEntry:
+            private, err = this.GetKeyPrivateWithPassword(alias, password)
+    }
+
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if private == nil {
+        return nil, nil, errors.New("entry is not a private key")
+    }
+
+    certChain, err := this.GetCertChain(alias)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return private, certChain, nil
+}
+
+// 信任证书
+func (this *BKS) TrustedCertEntry(alias string) (*x509.Certificate, error) {
+    if _, ok := this.entries[alias].(*bksTrustedCertEntry); !ok {
+        return nil, keystore.ErrEntryNotFound
+    }
+
+    return this.GetCert(alias)
+}
+
+// 密钥, 没有密码的条目忽略 password
+func (this *BKS) SecretKeyEntry(alias string, password string) ([]byte, error) {
+    entry, ok := this.entries[alias]
+    if !ok {
+        return nil, keystore.ErrEntryNotFound
+    }
+
+    var secret []byte
+    var err error
+
+    switch entry.(type) {
+        case *bksSecretKeyEntry:
+            secret, err = this.GetSecret(alias)
+        case *bksKeyEntry:
+            secret, err = this.GetKeySecret(alias)
+        case *bksSealedKeyEntry:
+            secret, err = this.GetKeySecretWithPassword(alias, password)
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    if secret == nil {
+        return nil, errors.New("entry is not a secret key")
+    }
+
+    return secret, nil
+}
+
+// 设置私钥和证书链, password 为空时不加密条目
+func (this *BKS) SetPrivateKeyEntry(
+    alias string,
+    key crypto.PrivateKey,
+    certChain []*x509.Certificate,
+    password string,
+    date time.Time,
+) error {
+    var err error
+    if password == "" {
+        err = this.AddKeyPrivate(alias, key, certChain)
+    } else {
+        err = this.AddKeyPrivateWithPassword(alias, key, password, certChain)
+    }
+
+    if err != nil {
+        return err
+    }
+
+    this.setEntryDate(alias, date)
+
+    return nil
+}
+
+// 设置信任证书
+func (this *BKS) SetTrustedCertEntry(alias string, cert *x509.Certificate, date time.Time) error {
+    err := this.AddCert(alias, cert, nil)
+    if err != nil {
+        return err
+    }
+
+    this.setEntryDate(alias, date)
+
+    return nil
+}
+
+// 设置密钥, password 为空时不加密条目
+func (this *BKS) SetSecretKeyEntry(alias string, key []byte, password string, date time.Time) error {
+    var err error
+    if password == "" {
+        err = this.AddSecretBytes(alias, key, nil)
+    } else {
+        err = this.AddKeySecretWithPasswordBytes(alias, key, password, bksSecretKeyAlgorithm, nil)
+    }
+
+    if err != nil {
+        return err
+    }
+
+    this.setEntryDate(alias, date)
+
+    return nil
+}
+
+func (this *BKS) setEntryDate(alias string, date time.Time) {
+    if entry, ok := this.entries[alias].(BksEntry); ok {
+        alias, _, certChain := entry.GetData()
+        entry.WithData(alias, keystore.DateOrNow(date), certChain)
+    }
+}
@@ -38,7 +38,7 @@ func (this *JCEKS) AddPrivateKeyBytes(
     certs [][]byte,
     cipher ...Cipher,
 ) error {
-    entry := privateKeyEntry{}
+    entry := &privateKeyEntry{}
     encodedKey, err := entry.Encode(privateKey, password, cipher...)
     if err != nil {
         return err
@@ -66,7 +66,7 @@ func (this *JCEKS) AddTrustedCertBytes(
     alias string,
     cert []byte,
 ) error {
-    entry := trustedCertEntry{}
+    entry := &trustedCertEntry{}
     entry.date = time.Now()
     entry.cert = cert
 
@@ -82,7 +82,7 @@ func (this *JCEKS) AddSecretKey(
     password string,
     cipher ...Cipher,
 ) error {
-    entry := secretKeyEntry{}
+    entry := &secretKeyEntry{}
     encodedKey, err := entry.Encode(secretKey, password, cipher...)
     if err != nil {
         return err
@@ -96,7 +96,7 @@ func (this *JCEKS) AddSecretKey(
     return nil
 }
 
-func (this *JCEKS) marshalPrivateKey(w io.Writer, alias string, data privateKeyEntry) error {
+func (this *JCEKS) marshalPrivateKey(w io.Writer, alias string, data *privateKeyEntry) error {
     certLen := len(data.certs)
     if certLen == 0 {
         return errors.New("privateKey cert is empty.")
@@ -144,7 +144,7 @@ func (this *JCEKS) marshalPrivateKey(w io.Writer, alias string, data privateKeyE
     return nil
 }
 
-func (this *JCEKS) marshalTrustedCert(w io.Writer, alias string, data trustedCertEntry) error {
+func (this *JCEKS) marshalTrustedCert(w io.Writer, alias string, data *trustedCertEntry) error {
     var err error
 
     err = writeInt32(w, int32(jceksTrustedCertId))
@@ -175,7 +175,7 @@ func (this *JCEKS) marshalTrustedCert(w io.Writer, alias string, data trustedCer
     return nil
 }
 
-func (this *JCEKS) marshalSecretKey(w io.Writer, alias string, data secretKeyEntry) error {
+func (this *JCEKS) marshalSecretKey(w io.Writer, alias string, data *secretKeyEntry) error {
     var err error
 
     err = writeInt32(w, int32(jceksSecretKeyId))
@@ -219,11 +219,11 @@ func (this *JCEKS) Marshal(password string) ([]byte, error) {
 
     for alias, entry := range this.entries {
         switch e := entry.(type) {
-            case privateKeyEntry:
+            case *privateKeyEntry:
                 err = this.marshalPrivateKey(buf, alias, e)
-            case trustedCertEntry:
+            case *trustedCertEntry:
                 err = this.marshalTrustedCert(buf, alias, e)
-            case secretKeyEntry:
+            case *secretKeyEntry:
                 err = this.marshalSecretKey(buf, alias, e)
         }
     }
@@ -0,0 +1,124 @@
+package jceks
+
+import (
+    "time"
+    "errors"
+    "crypto"
+    "crypto/x509"
+
+    "github.com/deatil/go-cryptobin/keystore"
+)
+
+var _ keystore.KeyStore = (*JCEKS)(nil)
+
+// 别名列表
+func (this *JCEKS) Aliases() []string {
+    return keystore.SortedAliases(this.entries)
+}
+
+// 条目类型
+func (this *JCEKS) EntryType(alias string) (keystore.EntryType, error) {
+    entry, ok := this.entries[alias]
+    if !ok {
+        return keystore.UnknownEntry, keystore.ErrEntryNotFound
+    }
+
+    switch entry.(type) {
+        case *privateKeyEntry:
+            return keystore.PrivateKeyEntry, nil
+        case *trustedCertEntry:
+            return keystore.TrustedCertEntry, nil
+        case *secretKeyEntry:
+            return keystore.SecretKeyEntry, nil
+    }
+
+    return keystore.UnknownEntry, nil
+}
+
+// 创建时间
+func (this *JCEKS) CreationDate(alias string) (time.Time, error) {
+    entry, ok := this.entries[alias]
+    if !ok {
+        return time.Time{}, keystore.ErrEntryNotFound
+    }
+
+    switch t := entry.(type) {
+        case *privateKeyEntry:
+            return t.date, nil
+        case *trustedCertEntry:
+            return t.date, nil
+        case *secretKeyEntry:
+            return t.date, nil
+    }
+
+    return time.Time{}, nil
+}
+
+// 私钥和证书链
+func (this *JCEKS) PrivateKeyEntry(alias string, password string) (crypto.PrivateKey, []*x509.Certificate, error) {
+    return this.GetPrivateKeyAndCerts(alias, password)
+}
+
+// 信任证书
+func (this *JCEKS) TrustedCertEntry(alias string) (*x509.Certificate, error) {
+    if _, ok := this.entries[alias].(*trustedCertEntry); !ok {
+        return nil, keystore.ErrEntryNotFound
+    }
+
+    return this.GetCert(alias)
+}
+
+// 密钥
+func (this *JCEKS) SecretKeyEntry(alias string, password string) ([]byte, error) {
+    if _, ok := this.entries[alias].(*secretKeyEntry); !ok {
+        return nil, keystore.ErrEntryNotFound
+    }
+
+    return this.GetSecretKey(alias, password)
+}
+
+// 设置私钥和证书链
+func (this *JCEKS) SetPrivateKeyEntry(
+    alias string,
+    key crypto.PrivateKey,
+    certChain []*x509.Certificate,
+    password string,
+    date time.Time,
+) error {
+    if len(certChain) == 0 {
+        return errors.New("privateKey cert is empty.")
+    }
+
+    err := this.AddPrivateKey(alias, key, password, certChain)
+    if err != nil {
+        return err
+    }
+
+    this.entries[alias].(*privateKeyEntry).date = keystore.DateOrNow(date)
+
+    return nil
+}
+
+// 设置信任证书
+func (this *JCEKS) SetTrustedCertEntry(alias string, cert *x509.Certificate, date time.Time) error {
+    err := this.AddTrustedCert(alias, cert)
+    if err != nil {
+        return err
+    }
+
+    this.entries[alias].(*trustedCertEntry).date = keystore.DateOrNow(date)
+
+    return nil
+}
+
+// 设置密钥
+func (this *JCEKS) SetSecretKeyEntry(alias string, key []byte, password string, date time.Time) error {
+    err := this.AddSecretKey(alias, key, password)
+    if err != nil {
+        return err
+    }
+
+    this.entries[alias].(*secretKeyEntry).date = keystore.DateOrNow(date)
+
+    return nil
+}
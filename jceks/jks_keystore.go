@@ -0,0 +1,108 @@
+package jceks
+
+import (
+    "time"
+    "sort"
+    "fmt"
+    "crypto"
+    "crypto/x509"
+
+    "github.com/deatil/go-cryptobin/keystore"
+)
+
+var _ keystore.KeyStore = (*JKS)(nil)
+
+// 别名列表
+func (this *JKS) Aliases() []string {
+    aliases := append([]string(nil), this.aliases...)
+    sort.Strings(aliases)
+
+    return aliases
+}
+
+// 条目类型
+func (this *JKS) EntryType(alias string) (keystore.EntryType, error) {
+    if _, ok := this.privateKeys[alias]; ok {
+        return keystore.PrivateKeyEntry, nil
+    }
+
+    if _, ok := this.trustedCerts[alias]; ok {
+        return keystore.TrustedCertEntry, nil
+    }
+
+    return keystore.UnknownEntry, keystore.ErrEntryNotFound
+}
+
+// 创建时间
+func (this *JKS) CreationDate(alias string) (time.Time, error) {
+    date, ok := this.dates[alias]
+    if !ok {
+        return time.Time{}, keystore.ErrEntryNotFound
+    }
+
+    return date, nil
+}
+
+// 私钥和证书链
+func (this *JKS) PrivateKeyEntry(alias string, password string) (crypto.PrivateKey, []*x509.Certificate, error) {
+    key, err := this.GetPrivateKey(alias, password)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    certChain, err := this.GetCertChain(alias)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return key, certChain, nil
+}
+
+// 信任证书
+func (this *JKS) TrustedCertEntry(alias string) (*x509.Certificate, error) {
+    if _, ok := this.trustedCerts[alias]; !ok {
+        return nil, keystore.ErrEntryNotFound
+    }
+
+    return this.GetCert(alias)
+}
+
+// JKS 不支持密钥
+func (this *JKS) SecretKeyEntry(alias string, password string) ([]byte, error) {
+    return nil, keystore.ErrEntryNotFound
+}
+
+// 设置私钥和证书链
+func (this *JKS) SetPrivateKeyEntry(
+    alias string,
+    key crypto.PrivateKey,
+    certChain []*x509.Certificate,
+    password string,
+    date time.Time,
+) error {
+    err := this.AddPrivateKey(alias, key, password, certChain)
+    if err != nil {
+        return err
+    }
+
+    this.dates[alias] = keystore.DateOrNow(date)
+
+    return nil
+}
+
+// 设置信任证书
+func (this *JKS) SetTrustedCertEntry(alias string, cert *x509.Certificate, date time.Time) error {
+    err := this.AddTrustedCert(alias, cert)
+    if err != nil {
+        return err
+    }
+
+    this.dates[alias] = keystore.DateOrNow(date)
+
+    return nil
+}
+
+// JKS 不支持密钥
+func (this *JKS) SetSecretKeyEntry(alias string, key []byte, password string, date time.Time) error {
+    return fmt.Errorf("JKS can not store secret keys: %w", keystore.ErrUnsupportedEntry)
+}
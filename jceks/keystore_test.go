@@ -0,0 +1,360 @@
+package jceks
+
+import (
+    "time"
+    "errors"
+    "testing"
+    "crypto/rand"
+    "crypto/x509"
+
+    "github.com/deatil/go-cryptobin/pkcs12"
+    "github.com/deatil/go-cryptobin/keystore"
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
+)
+
+func testKeyStoreSource(t *testing.T) *JCEKS {
+    caCerts, err := x509.ParseCertificates(decodePEM(caCert))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cert, err := x509.ParseCertificate(decodePEM(certificate))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    key, err := x509.ParsePKCS8PrivateKey(decodePEM(privateKey))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    date := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+
+    ks := NewJCEKS()
+    if err := ks.SetPrivateKeyEntry("priv-test", key, caCerts, "key-pass", date); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := ks.SetTrustedCertEntry("cert-test", cert, date); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := ks.SetSecretKeyEntry("secret-test", []byte("secret-data"), "key-pass", date); err != nil {
+        t.Fatal(err)
+    }
+
+    // 条目可以直接读取
+    if _, _, err := ks.PrivateKeyEntry("priv-test", "key-pass"); err != nil {
+        t.Fatal(err)
+    }
+
+    return ks
+}
+
+func testCheckKeyStore(t *testing.T, name string, ks keystore.KeyStore, aliases []string, password string, withDate bool) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+
+    src := testKeyStoreSource(t)
+
+    assertEqual(ks.Aliases(), aliases, name + "-Aliases")
+
+    for _, alias := range aliases {
+        typ, err := ks.EntryType(alias)
+        if err != nil {
+            t.Fatalf("%s: %v", name, err)
+        }
+
+        srcType, _ := src.EntryType(alias)
+        assertEqual(typ, srcType, name + "-EntryType-" + alias)
+
+        if withDate {
+            date, _ := ks.CreationDate(alias)
+            srcDate, _ := src.CreationDate(alias)
+            assertEqual(date.Unix(), srcDate.Unix(), name + "-CreationDate-" + alias)
+        }
+
+        switch typ {
+            case keystore.PrivateKeyEntry:
+                key, chain, err := ks.PrivateKeyEntry(alias, password)
+                if err != nil {
+                    t.Fatalf("%s: %v", name, err)
+                }
+
+                srcKey, srcChain, _ := src.PrivateKeyEntry(alias, "key-pass")
+                assertEqual(key, srcKey, name + "-PrivateKeyEntry")
+                assertEqual(chain, srcChain, name + "-PrivateKeyEntry-chain")
+            case keystore.TrustedCertEntry:
+                cert, err := ks.TrustedCertEntry(alias)
+                if err != nil {
+                    t.Fatalf("%s: %v", name, err)
+                }
+
+                srcCert, _ := src.TrustedCertEntry(alias)
+                assertEqual(cert.Raw, srcCert.Raw, name + "-TrustedCertEntry")
+            case keystore.SecretKeyEntry:
+                secret, err := ks.SecretKeyEntry(alias, password)
+                if err != nil {
+                    t.Fatalf("%s: %v", name, err)
+                }
+
+                assertEqual(secret, []byte("secret-data"), name + "-SecretKeyEntry")
+        }
+    }
+}
+
+func Test_KeyStore_Convert(t *testing.T) {
+    all := []string{"cert-test", "priv-test", "secret-test"}
+
+    passwords := keystore.Passwords{
+        Source: "key-pass",
+        Target: "new-pass",
+    }
+
+    t.Run("JCEKS", func(t *testing.T) {
+        dst := NewJCEKS()
+        res, err := keystore.Convert(testKeyStoreSource(t), dst, passwords)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if len(res.Converted) != 3 || len(res.Skipped) != 0 {
+            t.Fatalf("got %+v", res)
+        }
+
+        data, err := dst.Marshal("store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        ks, err := LoadJceksFromBytes(data, "store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        testCheckKeyStore(t, "JCEKS", ks, all, "new-pass", true)
+
+        // 解析后可以重新编码
+        data2, err := ks.Marshal("store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        ks2, err := LoadJceksFromBytes(data2, "store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        testCheckKeyStore(t, "JCEKS-2", ks2, all, "new-pass", true)
+    })
+
+    t.Run("JKS", func(t *testing.T) {
+        dst := NewJKS()
+        res, err := keystore.Convert(testKeyStoreSource(t), dst, passwords)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if len(res.Skipped) != 1 ||
+            res.Skipped[0].Alias != "secret-test" ||
+            res.Skipped[0].Type != keystore.SecretKeyEntry ||
+            !errors.Is(res.Skipped[0].Err, keystore.ErrUnsupportedEntry) {
+            t.Fatalf("got %+v", res.Skipped)
+        }
+
+        data, err := dst.Marshal("store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        ks, err := LoadJksFromBytes(data, "store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        testCheckKeyStore(t, "JKS", ks, all[:2], "new-pass", true)
+    })
+
+    t.Run("BKS", func(t *testing.T) {
+        dst := NewBKS()
+        if _, err := keystore.Convert(testKeyStoreSource(t), dst, passwords); err != nil {
+            t.Fatal(err)
+        }
+
+        data, err := dst.Marshal("store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        ks, err := LoadBksFromBytes(data, "store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        testCheckKeyStore(t, "BKS", ks, all, "new-pass", true)
+    })
+
+    t.Run("UBER", func(t *testing.T) {
+        dst := NewUBER()
+
+        // 没有条目密码时不加密条目
+        if _, err := keystore.Convert(testKeyStoreSource(t), dst, keystore.Passwords{
+            Entries: map[string]string{
+                "priv-test":   "key-pass",
+                "secret-test": "key-pass",
+            },
+            Target: "",
+        }); err != nil {
+            t.Fatal(err)
+        }
+
+        data, err := dst.Marshal("store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        ks, err := LoadUberFromBytes(data, "store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        testCheckKeyStore(t, "UBER", ks, all, "key-pass", true)
+    })
+
+    t.Run("PKCS12", func(t *testing.T) {
+        dst := pkcs12.New()
+        if _, err := keystore.Convert(testKeyStoreSource(t), dst, passwords); err != nil {
+            t.Fatal(err)
+        }
+
+        data, err := dst.Marshal(rand.Reader, "store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        ks, err := pkcs12.LoadFromBytes(data, "store-pass")
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        testCheckKeyStore(t, "PKCS12", ks, all, "", false)
+
+        // 编码前可以直接读取转换后的条目
+        testCheckKeyStore(t, "PKCS12-dst", dst, all, "", false)
+
+        // 只能保存一个私钥
+        key, chain, _ := ks.PrivateKeyEntry("priv-test", "")
+        err = dst.SetPrivateKeyEntry("priv-2", key, chain, "", time.Time{})
+        if !errors.Is(err, keystore.ErrUnsupportedEntry) {
+            t.Errorf("want ErrUnsupportedEntry, got %v", err)
+        }
+
+        // 转换回 JKS
+        jks := NewJKS()
+        res, err := keystore.Convert(ks, jks, keystore.Passwords{Target: "new-pass"})
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if len(res.Converted) != 2 || len(res.Skipped) != 1 {
+            t.Errorf("got %+v", res)
+        }
+    })
+}
+
+func Test_KeyStore_SetGet(t *testing.T) {
+    src := testKeyStoreSource(t)
+
+    key, chain, err := src.PrivateKeyEntry("priv-test", "key-pass")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cert, err := src.TrustedCertEntry("cert-test")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    date := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+
+    stores := []struct {
+        name     string
+        ks       keystore.KeyStore
+        password string
+        secret   bool
+        withDate bool
+    }{
+        {"JCEKS", NewJCEKS(), "key-pass", true, true},
+        {"JKS", NewJKS(), "key-pass", false, true},
+        {"BKS", NewBKS(), "key-pass", true, true},
+        {"UBER", NewUBER(), "key-pass", true, true},
+        {"PKCS12", pkcs12.New(), "", true, false},
+    }
+
+    for _, s := range stores {
+        t.Run(s.name, func(t *testing.T) {
+            ks := s.ks
+
+            if err := ks.SetTrustedCertEntry("cert-test", cert, date); err != nil {
+                t.Fatal(err)
+            }
+
+            // 设置后马上可以读取
+            testCheckKeyStore(t, s.name + "-cert", ks, []string{"cert-test"}, s.password, s.withDate)
+
+            if err := ks.SetPrivateKeyEntry("priv-test", key, chain, "key-pass", date); err != nil {
+                t.Fatal(err)
+            }
+
+            aliases := []string{"cert-test", "priv-test"}
+
+            err := ks.SetSecretKeyEntry("secret-test", []byte("secret-data"), "key-pass", date)
+            if s.secret {
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                aliases = append(aliases, "secret-test")
+            } else if !errors.Is(err, keystore.ErrUnsupportedEntry) {
+                t.Fatalf("want ErrUnsupportedEntry, got %v", err)
+            }
+
+            testCheckKeyStore(t, s.name, ks, aliases, s.password, s.withDate)
+
+            if _, err := ks.TrustedCertEntry("not-exists"); err != keystore.ErrEntryNotFound {
+                t.Errorf("want ErrEntryNotFound, got %v", err)
+            }
+        })
+    }
+}
+
+func Test_KeyStore_Files(t *testing.T) {
+    ks, err := LoadJksFromBytes(testMustReadFile(t, "testdata/jks/RSA_2048_truststore.jks"), "password")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dst := NewJCEKS()
+    res, err := keystore.Convert(ks, dst, keystore.Passwords{})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(res.Converted) == 0 || len(res.Converted) != len(ks.Aliases()) {
+        t.Fatalf("got %+v", res)
+    }
+
+    bks, err := LoadBksFromBytes(testMustReadFile(t, "testdata/bks/christmas.bksv2"), "12345678")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, alias := range bks.Aliases() {
+        if _, err := bks.EntryType(alias); err != nil {
+            t.Error(err)
+        }
+    }
+
+    if _, err := bks.EntryType("not-exists"); err != keystore.ErrEntryNotFound {
+        t.Errorf("want ErrEntryNotFound, got %v", err)
+    }
+}
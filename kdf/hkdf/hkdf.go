@@ -0,0 +1,108 @@
+package hkdf
+
+import (
+    "io"
+    "hash"
+    "errors"
+    "crypto/hmac"
+)
+
+// HMAC-based Extract-and-Expand Key Derivation Function (HKDF), RFC 5869.
+// Any hash.Hash works, such as SM3 or the GOST R 34.11-2012 Streebog.
+
+var ErrTooLong = errors.New("go-cryptobin/hkdf: entropy limit reached")
+
+// Extract generates a pseudorandom key for use with Expand from an input
+// secret and an optional independent salt.
+func Extract(h func() hash.Hash, secret, salt []byte) []byte {
+    if salt == nil {
+        salt = make([]byte, h().Size())
+    }
+
+    extractor := hmac.New(h, salt)
+    extractor.Write(secret)
+
+    return extractor.Sum(nil)
+}
+
+type hkdf struct {
+    expander hash.Hash
+    size     int
+
+    info    []byte
+    counter byte
+
+    prev []byte
+    buf  []byte
+}
+
+func (f *hkdf) Read(p []byte) (int, error) {
+    // Check whether enough data can be generated
+    need := len(p)
+    remains := len(f.buf) + int(255-f.counter+1)*f.size
+    if remains < need {
+        return 0, ErrTooLong
+    }
+
+    // Read any leftover from the buffer
+    n := copy(p, f.buf)
+    p = p[n:]
+
+    // Fill the rest of the buffer
+    for len(p) > 0 {
+        if f.counter > 1 {
+            f.expander.Reset()
+        }
+
+        f.expander.Write(f.prev)
+        f.expander.Write(f.info)
+        f.expander.Write([]byte{f.counter})
+
+        f.prev = f.expander.Sum(f.prev[:0])
+        f.counter++
+
+        // Copy the new batch into p
+        f.buf = f.prev
+        n = copy(p, f.buf)
+        p = p[n:]
+    }
+
+    // Save leftovers for next run
+    f.buf = f.buf[n:]
+
+    return need, nil
+}
+
+// Expand returns a Reader, from which keys can be read, using the given
+// pseudorandom key and optional info. The Reader returns ErrTooLong
+// after 255 * hash size bytes.
+func Expand(h func() hash.Hash, pseudorandomKey, info []byte) io.Reader {
+    expander := hmac.New(h, pseudorandomKey)
+
+    return &hkdf{
+        expander: expander,
+        size:     expander.Size(),
+        info:     info,
+        counter:  1,
+    }
+}
+
+// New returns a Reader, from which keys can be read, using the given hash,
+// secret, salt and context info.
+func New(h func() hash.Hash, secret, salt, info []byte) io.Reader {
+    prk := Extract(h, secret, salt)
+
+    return Expand(h, prk, info)
+}
+
+// Key derives a key of length bytes from the secret, salt and info.
+func Key(h func() hash.Hash, secret, salt, info []byte, length int) ([]byte, error) {
+    key := make([]byte, length)
+
+    _, err := io.ReadFull(New(h, secret, salt, info), key)
+    if err != nil {
+        return nil, err
+    }
+
+    return key, nil
+}
@@ -0,0 +1,130 @@
+package hkdf
+
+import (
+    "io"
+    "hash"
+    "bytes"
+    "testing"
+    "crypto/sha1"
+    "crypto/sha256"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/hash/sm3"
+    "github.com/deatil/go-cryptobin/hash/gost/gost34112012256"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// RFC 5869, Appendix A
+var testVectors = []struct {
+    hash   func() hash.Hash
+    secret []byte
+    salt   []byte
+    info   []byte
+    prk    []byte
+    okm    []byte
+}{
+    {
+        sha256.New,
+        fromHex("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+        fromHex("000102030405060708090a0b0c"),
+        fromHex("f0f1f2f3f4f5f6f7f8f9"),
+        fromHex("077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5"),
+        fromHex("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"),
+    },
+    {
+        sha256.New,
+        fromHex("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+        nil,
+        nil,
+        fromHex("19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04"),
+        fromHex("8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"),
+    },
+    {
+        sha1.New,
+        fromHex("0b0b0b0b0b0b0b0b0b0b0b"),
+        fromHex("000102030405060708090a0b0c"),
+        fromHex("f0f1f2f3f4f5f6f7f8f9"),
+        fromHex("9b6c18c432a7bf8f0e71c8eb88f4b30baa2ba243"),
+        fromHex("085a01ea1b10f36933068b56efa5ad81a4f14b822f5b091568a9cdd4f155fda2c22e422478d305f3f896"),
+    },
+    // SM3 为本实现的回归值, 已用 OpenSSL 3.0 的 HKDF 交叉验证
+    {
+        sm3.New,
+        fromHex("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+        fromHex("000102030405060708090a0b0c"),
+        fromHex("f0f1f2f3f4f5f6f7f8f9"),
+        nil,
+        fromHex("c69fe91b7aaee2dd5718d72dcaee0cce93f1b8e41f792da51261b6a517e68b36ed2c595572b01dfa359b"),
+    },
+    {
+        sm3.New,
+        fromHex("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+        nil,
+        fromHex("01"),
+        nil,
+        fromHex("2d698c2617c269a77a48b693b84e0b943876b540648892e5deedcbbdf359a456f93553f4dfa447d99134b42a7c6634f102d947b650391204ed696bee2caa27857a27edfabbba2d6509ffd18a641312615e5048627d572ada157e98513da6c4177e3b92ed"),
+    },
+}
+
+func Test_HKDF(t *testing.T) {
+    for i, tv := range testVectors {
+        prk := Extract(tv.hash, tv.secret, tv.salt)
+        if tv.prk != nil && !bytes.Equal(prk, tv.prk) {
+            t.Errorf("test %d: PRK got %x, want %x", i, prk, tv.prk)
+        }
+
+        okm, err := Key(tv.hash, tv.secret, tv.salt, tv.info, len(tv.okm))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if !bytes.Equal(okm, tv.okm) {
+            t.Errorf("test %d: OKM got %x, want %x", i, okm, tv.okm)
+        }
+    }
+}
+
+func Test_HKDF_MultiRead(t *testing.T) {
+    for i, tv := range testVectors {
+        r := Expand(tv.hash, Extract(tv.hash, tv.secret, tv.salt), tv.info)
+
+        out := make([]byte, len(tv.okm))
+        for b := 0; b < len(out); b += 5 {
+            end := b + 5
+            if end > len(out) {
+                end = len(out)
+            }
+
+            if _, err := io.ReadFull(r, out[b:end]); err != nil {
+                t.Fatal(err)
+            }
+        }
+
+        if !bytes.Equal(out, tv.okm) {
+            t.Errorf("test %d: got %x, want %x", i, out, tv.okm)
+        }
+    }
+}
+
+func Test_HKDF_Limit(t *testing.T) {
+    h := gost34112012256.New
+
+    r := New(h, []byte("secret"), []byte("salt"), nil)
+    limit := h().Size() * 255
+
+    if _, err := io.ReadFull(r, make([]byte, limit)); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := r.Read(make([]byte, 1)); err != ErrTooLong {
+        t.Errorf("got %v, want ErrTooLong", err)
+    }
+
+    if _, err := Key(h, []byte("secret"), nil, nil, limit+1); err != ErrTooLong {
+        t.Errorf("got %v, want ErrTooLong", err)
+    }
+}
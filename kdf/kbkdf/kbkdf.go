@@ -1,6 +1,7 @@
 package kbkdf
 
 import (
+    "hash"
     "encoding/binary"
 
     "github.com/deatil/go-cryptobin/tool/bytes"
@@ -97,6 +98,15 @@ func PipelineModeKey(prf PRF, key, label, context []byte, counterSize, length in
     return out
 }
 
+// KDF using KMAC, NIST SP 800-108r1 section 4.4
+// K_OUT = KMAC#(key, context, length*8, label)
+func KMACModeKey(kmac func(key []byte, size int, S []byte) hash.Hash, key, label, context []byte, length int) []byte {
+    h := kmac(key, length, label)
+    h.Write(context)
+
+    return h.Sum(nil)
+}
+
 func fillL(dst []byte, v uint64) []byte {
     switch {
         case v < 1<<8:
@@ -9,6 +9,9 @@ import (
     "crypto/sha256"
     "crypto/sha512"
 
+    "github.com/deatil/go-cryptobin/mac"
+    "github.com/deatil/go-cryptobin/hash/cmac"
+    "github.com/deatil/go-cryptobin/hash/kmac"
     "github.com/deatil/go-cryptobin/cipher/aria"
     "github.com/deatil/go-cryptobin/cipher/seed"
     "github.com/deatil/go-cryptobin/cipher/hight"
@@ -55,6 +58,107 @@ func Test_CMAC_PipelineModeKey(t *testing.T) {
     }
 }
 
+func Test_MAC_CounterModeKey(t *testing.T) {
+    cmacMAC := func(b cipher.Block) mac.BlockCipherMAC {
+        return mac.NewCMAC(b, b.BlockSize())
+    }
+
+    var got []byte
+    for idx, tc := range testcasesCMACCtr {
+        // mac.NewCMAC is for 128-bit block ciphers
+        if b, _ := tc.NewCipher(tc.KI); b.BlockSize() != 16 {
+            continue
+        }
+
+        want := tc.K0
+        got = CounterModeKey(NewMACPRF(tc.NewCipher, cmacMAC), tc.KI, tc.Label, tc.Context, tc.CounterSize/8, tc.L/8)
+
+        if !bytes.Equal(got, want) {
+            t.Errorf("failed test case %d, got %x, want %x", idx, got, want)
+        }
+    }
+}
+
+func Test_HashMAC_CounterModeKey(t *testing.T) {
+    var got []byte
+    for idx, tc := range testcasesCMACCtr {
+        newCipher := tc.NewCipher
+        cmacMAC := func(key []byte) (hash.Hash, error) {
+            b, err := newCipher(key)
+            if err != nil {
+                return nil, err
+            }
+
+            return cmac.New(b)
+        }
+
+        want := tc.K0
+        got = CounterModeKey(NewHashMACPRF(cmacMAC), tc.KI, tc.Label, tc.Context, tc.CounterSize/8, tc.L/8)
+
+        if !bytes.Equal(got, want) {
+            t.Errorf("failed test case %d, got %x, want %x", idx, got, want)
+        }
+    }
+
+    key := []byte("0123456789abcdef")
+    label := []byte("label")
+    context := []byte("context")
+
+    got = CounterModeKey(NewHashMACPRF(mac.NewSipHash24_128), key, label, context, 4, 20)
+
+    h, _ := mac.NewSipHash24_128(key)
+    h.Write([]byte{0, 0, 0, 1})
+    h.Write(label)
+    h.Write([]byte{0})
+    h.Write(context)
+    h.Write([]byte{160})
+
+    if want := h.Sum(nil); !bytes.Equal(got[:16], want) {
+        t.Errorf("SipHash PRF got %x, want %x", got[:16], want)
+    }
+}
+
+func Test_KMACModeKey(t *testing.T) {
+    tests := []struct {
+        kmac func(key []byte, size int, S []byte) hash.Hash
+        key  []byte
+        want []byte
+    }{
+        {
+            kmac.New128,
+            fromHex("000102030405060708090a0b0c0d0e0f"),
+            fromHex("b32f49d3d5bbffde313274ae5c042022e6caac53b70ec126fba263dd053024649f17ded31c11fa2b"),
+        },
+        {
+            kmac.New256,
+            fromHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"),
+            fromHex("42a9d13806b1c154f97e0c0018fb91413b8f2a70c64ed860ef7df62dd4a2517382c5117c4c5b0ac6f953656cebf8237bfa19c9b9a689b7075e4885aaf6dc98cbf668f5594d69e548edbbfb62bc685373"),
+        },
+    }
+
+    for idx, tc := range tests {
+        got := KMACModeKey(tc.kmac, tc.key, []byte("label"), []byte("context"), len(tc.want))
+        if !bytes.Equal(got, tc.want) {
+            t.Errorf("failed test case %d, got %x, want %x", idx, got, tc.want)
+        }
+    }
+}
+
+func Test_KMAC_CounterModeKey(t *testing.T) {
+    key := fromHex("000102030405060708090a0b0c0d0e0f")
+
+    got := CounterModeKey(NewKMACPRF(kmac.New128, 32, nil), key, []byte("label"), []byte("context"), 4, 40)
+    if len(got) != 40 {
+        t.Fatalf("got length %d", len(got))
+    }
+
+    // K(1) = KMAC128(key, [1]_32 || label || 0x00 || context || [L]_2)
+    block1 := kmac.Sum128(key, []byte("\x00\x00\x00\x01label\x00context\x01\x40"), 32, nil)
+    if !bytes.Equal(got[:32], block1) {
+        t.Errorf("got %x, want %x", got[:32], block1)
+    }
+}
+
 // TTAK.KO-12.0272
 
 type testVectorCMAC struct {
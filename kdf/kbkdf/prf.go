@@ -5,6 +5,7 @@ import (
     "crypto/hmac"
     "crypto/cipher"
 
+    "github.com/deatil/go-cryptobin/mac"
     "github.com/deatil/go-cryptobin/hash/cmac"
 )
 
@@ -59,3 +60,90 @@ func (prf *cmacPRF) Sum(key []byte, src ...[]byte) []byte {
 
     return h.Sum(nil)
 }
+
+// ================
+
+type macPRF struct {
+    cipher func(key []byte) (cipher.Block, error)
+    mac    func(b cipher.Block) mac.BlockCipherMAC
+}
+
+// New Pseudo-Random Functions with the block cipher MACs of the mac package,
+// e.g. func(b cipher.Block) mac.BlockCipherMAC { return mac.NewCMAC(b, 16) }
+func NewMACPRF(cip func(key []byte) (cipher.Block, error), m func(b cipher.Block) mac.BlockCipherMAC) PRF {
+    return &macPRF{
+        cipher: cip,
+        mac:    m,
+    }
+}
+
+func (prf *macPRF) Sum(key []byte, src ...[]byte) []byte {
+    b, err := prf.cipher(key)
+    if err != nil {
+        panic(err)
+    }
+
+    var data []byte
+    for _, v := range src {
+        data = append(data, v...)
+    }
+
+    return prf.mac(b).MAC(data)
+}
+
+// ================
+
+type hashMACPRF struct {
+    mac func(key []byte) (hash.Hash, error)
+}
+
+// New Pseudo-Random Functions with keyed hash.Hash MACs,
+// e.g. NewHashMACPRF(mac.NewSipHash24_128).
+// Nonce-based MACs, as mac.NewGMAC and mac.NewPoly1305, need a
+// fixed nonce in the func.
+func NewHashMACPRF(m func(key []byte) (hash.Hash, error)) PRF {
+    return &hashMACPRF{
+        mac: m,
+    }
+}
+
+func (prf *hashMACPRF) Sum(key []byte, src ...[]byte) []byte {
+    h, err := prf.mac(key)
+    if err != nil {
+        panic(err)
+    }
+
+    for _, v := range src {
+        h.Write(v)
+    }
+
+    return h.Sum(nil)
+}
+
+// ================
+
+type kmacPRF struct {
+    kmac func(key []byte, size int, S []byte) hash.Hash
+    size int
+    s    []byte
+}
+
+// New KMAC-based Pseudo-Random Functions,
+// e.g. NewKMACPRF(kmac.New128, 32, nil)
+func NewKMACPRF(kmac func(key []byte, size int, S []byte) hash.Hash, size int, S []byte) PRF {
+    return &kmacPRF{
+        kmac: kmac,
+        size: size,
+        s:    S,
+    }
+}
+
+func (prf *kmacPRF) Sum(key []byte, src ...[]byte) []byte {
+    h := prf.kmac(key, prf.size, prf.s)
+
+    for _, v := range src {
+        h.Write(v)
+    }
+
+    return h.Sum(nil)
+}
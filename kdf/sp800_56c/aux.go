@@ -0,0 +1,97 @@
+package sp800_56c
+
+import (
+    "hash"
+    "crypto/hmac"
+
+    "github.com/deatil/go-cryptobin/hash/kmac"
+)
+
+// Auxiliary function H of the One-Step Key-Derivation
+type Aux interface {
+    // Sum computes H(src...). salt is ignored by hash functions,
+    // length is the requested output and is only used by KMAC.
+    Sum(salt []byte, length int, src ...[]byte) []byte
+}
+
+type hashAux struct {
+    hash func() hash.Hash
+}
+
+// New hash-based auxiliary function, Option 1
+func NewHashAux(h func() hash.Hash) Aux {
+    return &hashAux{
+        hash: h,
+    }
+}
+
+func (aux *hashAux) Sum(salt []byte, length int, src ...[]byte) []byte {
+    h := aux.hash()
+
+    for _, v := range src {
+        h.Write(v)
+    }
+
+    return h.Sum(nil)
+}
+
+// ================
+
+type hmacAux struct {
+    hash func() hash.Hash
+}
+
+// New HMAC-based auxiliary function, Option 2
+func NewHMACAux(h func() hash.Hash) Aux {
+    return &hmacAux{
+        hash: h,
+    }
+}
+
+func (aux *hmacAux) Sum(salt []byte, length int, src ...[]byte) []byte {
+    h := hmac.New(aux.hash, salt)
+
+    for _, v := range src {
+        h.Write(v)
+    }
+
+    return h.Sum(nil)
+}
+
+// ================
+
+type kmacAux struct {
+    defaultSalt int
+    newKMAC     func(key []byte, size int, S []byte) hash.Hash
+}
+
+// New KMAC128-based auxiliary function, Option 3
+func NewKMAC128Aux() Aux {
+    return &kmacAux{
+        defaultSalt: 164,
+        newKMAC:     kmac.New128,
+    }
+}
+
+// New KMAC256-based auxiliary function, Option 3
+func NewKMAC256Aux() Aux {
+    return &kmacAux{
+        defaultSalt: 132,
+        newKMAC:     kmac.New256,
+    }
+}
+
+// KMAC#(salt, src, length, "KDF")
+func (aux *kmacAux) Sum(salt []byte, length int, src ...[]byte) []byte {
+    if salt == nil {
+        salt = make([]byte, aux.defaultSalt)
+    }
+
+    h := aux.newKMAC(salt, length, []byte("KDF"))
+
+    for _, v := range src {
+        h.Write(v)
+    }
+
+    return h.Sum(nil)
+}
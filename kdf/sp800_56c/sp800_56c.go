@@ -0,0 +1,105 @@
+package sp800_56c
+
+import (
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/kdf/kbkdf"
+)
+
+// Recommendation for Key-Derivation Methods in Key-Establishment Schemes
+// Reference: NIST SP 800-56C Rev. 2
+
+const (
+    errKeyLengthTooLong = "go-cryptobin/sp800_56c: key length too long"
+)
+
+// OneStepKey derives a key of length bytes from the shared secret z
+// and fixedInfo with the auxiliary function aux, section 4.
+// salt is used by the HMAC and KMAC auxiliary functions, nil gives the default salt.
+func OneStepKey(aux Aux, z, salt, fixedInfo []byte, length int) []byte {
+    out := make([]byte, 0, length)
+
+    var counter [4]byte
+    var i uint32 = 1
+
+    for len(out) < length {
+        if i == 0 {
+            panic(errKeyLengthTooLong)
+        }
+
+        binary.BigEndian.PutUint32(counter[:], i)
+
+        K := aux.Sum(salt, length, counter[:], z, fixedInfo)
+        out = append(out, K...)
+
+        i++
+    }
+
+    return out[:length]
+}
+
+// Extract is the randomness-extraction step of the
+// Two-Step Key-Derivation, section 5.1. The MAC may be
+// HMAC or AES-CMAC, and salt is used as its key.
+func Extract(prf kbkdf.PRF, salt, z []byte) []byte {
+    return prf.Sum(salt, z)
+}
+
+// ExpandCounter is the key-expansion step of the Two-Step
+// Key-Derivation with the SP 800-108 KDF in counter mode,
+// K(i) = PRF(KDK, [i]_32 || fixedInfo).
+func ExpandCounter(prf kbkdf.PRF, kdk, fixedInfo []byte, length int) []byte {
+    out := make([]byte, 0, length)
+
+    var counter [4]byte
+    var i uint32 = 1
+
+    for len(out) < length {
+        if i == 0 {
+            panic(errKeyLengthTooLong)
+        }
+
+        binary.BigEndian.PutUint32(counter[:], i)
+
+        K := prf.Sum(kdk, counter[:], fixedInfo)
+        out = append(out, K...)
+
+        i++
+    }
+
+    return out[:length]
+}
+
+// ExpandFeedback is the key-expansion step of the Two-Step
+// Key-Derivation with the SP 800-108 KDF in feedback mode,
+// K(i) = PRF(KDK, K(i-1) || [i]_32 || fixedInfo) and K(0) = iv.
+func ExpandFeedback(prf kbkdf.PRF, kdk, iv, fixedInfo []byte, length int) []byte {
+    out := make([]byte, 0, length)
+
+    var counter [4]byte
+    var i uint32 = 1
+
+    K := iv
+    for len(out) < length {
+        if i == 0 {
+            panic(errKeyLengthTooLong)
+        }
+
+        binary.BigEndian.PutUint32(counter[:], i)
+
+        K = prf.Sum(kdk, K, counter[:], fixedInfo)
+        out = append(out, K...)
+
+        i++
+    }
+
+    return out[:length]
+}
+
+// TwoStepKey derives a key of length bytes from the shared secret z
+// with Extract followed by ExpandCounter, section 5.
+func TwoStepKey(prf kbkdf.PRF, salt, z, fixedInfo []byte, length int) []byte {
+    kdk := Extract(prf, salt, z)
+
+    return ExpandCounter(prf, kdk, fixedInfo, length)
+}
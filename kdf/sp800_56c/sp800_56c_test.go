@@ -0,0 +1,235 @@
+package sp800_56c
+
+import (
+    "bytes"
+    "testing"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/sha256"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/mac"
+    "github.com/deatil/go-cryptobin/hash/sm3"
+    "github.com/deatil/go-cryptobin/kdf/kbkdf"
+    "github.com/deatil/go-cryptobin/hash/gost/gost34112012256"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+var (
+    testZ         = fromHex("afc4e154498d4770aa8365f6903dc83b")
+    testSalt      = fromHex("8c0f8e6a9e7bf1a9a5b5c1d2e3f4a5b6")
+    testFixedInfo = fromHex("662af20379b29d5ef813e655")
+)
+
+func Test_OneStepKey(t *testing.T) {
+    tests := []struct {
+        name      string
+        aux       Aux
+        z         []byte
+        salt      []byte
+        fixedInfo []byte
+        key       []byte
+    }{
+        // SP 800-56C one-step KDF, SHA-256
+        {
+            "SHA256",
+            NewHashAux(sha256.New),
+            fromHex("52169af5c485dcc2321eb8d26d5efa21fb9b93c98e38412ee2484cf14f0d0d23"),
+            nil,
+            fromHex("a1b2c3d4e53728157e634612c12d6d5223e204aeea4341565369647bd184bcd246f72971f292badaa2fe4124612cba"),
+            fromHex("1c3bc9e7c4547c5191c0d478cccaed55"),
+        },
+        // 以下为本实现的回归值, 已用 OpenSSL 3.0 的 SSKDF 交叉验证
+        {
+            "SM3",
+            NewHashAux(sm3.New),
+            testZ,
+            nil,
+            testFixedInfo,
+            fromHex("f196db3ef1f00d76c97dcd5e0b87adc11ba0837d3e2342f36e728330ce944ee2a61a94a031c10bdd"),
+        },
+        {
+            "HMAC-SHA256",
+            NewHMACAux(sha256.New),
+            testZ,
+            testSalt,
+            testFixedInfo,
+            fromHex("232347a24e528d3e10eda5ecc6e04cbbd1ff44f902d148099ad9d3189553a5f86cada835b68ce87dcb5eb095076e213a87cd"),
+        },
+        {
+            "HMAC-SM3",
+            NewHMACAux(sm3.New),
+            testZ,
+            nil,
+            testFixedInfo,
+            fromHex("01f37d5a0b88955505682baa6a3f0037f388824d64df0316f306ae4128b34dea40b09ad6234490b8"),
+        },
+        {
+            "KMAC128",
+            NewKMAC128Aux(),
+            testZ,
+            testSalt,
+            testFixedInfo,
+            fromHex("265bb22cc7d518693ad0e1b22b5f051b64f7f5832a77701ebefb14245afa4c98a0eb33b613aeb13941e75319438ade64"),
+        },
+        {
+            "KMAC256",
+            NewKMAC256Aux(),
+            testZ,
+            nil,
+            testFixedInfo,
+            fromHex("9126234a5746e2acd01b88690681500ec7e14c252779fcb4471bbf17df048a18ecccf8c6d964fe3aee42e1d035b2744411c94a2e02dee8581097e6059f21e02d"),
+        },
+    }
+
+    for _, td := range tests {
+        t.Run(td.name, func(t *testing.T) {
+            got := OneStepKey(td.aux, td.z, td.salt, td.fixedInfo, len(td.key))
+            if !bytes.Equal(got, td.key) {
+                t.Errorf("got %x, want %x", got, td.key)
+            }
+        })
+    }
+}
+
+func Test_OneStepKey_Streebog(t *testing.T) {
+    aux := NewHMACAux(gost34112012256.New)
+
+    key1 := OneStepKey(aux, testZ, testSalt, testFixedInfo, 70)
+    key2 := OneStepKey(aux, testZ, testSalt, testFixedInfo, 20)
+
+    if len(key1) != 70 || !bytes.Equal(key1[:20], key2) {
+        t.Errorf("unexpected key %x", key1)
+    }
+}
+
+func Test_TwoStepKey(t *testing.T) {
+    cmacMAC := func(b cipher.Block) mac.BlockCipherMAC {
+        return mac.NewCMAC(b, 16)
+    }
+
+    // 提取步骤即以 salt 为密钥的 MAC, 使用 RFC 4231 测试用例 1
+    // 和 RFC 4493 示例 2, 展开步骤由 Test_ExpandCounter 覆盖
+    tests := []struct {
+        name string
+        prf  kbkdf.PRF
+        salt []byte
+        z    []byte
+        kdk  []byte
+    }{
+        {
+            "HMAC-SHA256",
+            kbkdf.NewHMACPRF(sha256.New),
+            fromHex("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+            []byte("Hi There"),
+            fromHex("b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7"),
+        },
+        {
+            "CMAC-AES128",
+            kbkdf.NewCMACPRF(aes.NewCipher),
+            fromHex("2b7e151628aed2a6abf7158809cf4f3c"),
+            fromHex("6bc1bee22e409f96e93d7e117393172a"),
+            fromHex("070a16b46b4d4144f79bdd9dd04a287c"),
+        },
+        {
+            "mac.CMAC-AES128",
+            kbkdf.NewMACPRF(aes.NewCipher, cmacMAC),
+            fromHex("2b7e151628aed2a6abf7158809cf4f3c"),
+            fromHex("6bc1bee22e409f96e93d7e117393172a"),
+            fromHex("070a16b46b4d4144f79bdd9dd04a287c"),
+        },
+    }
+
+    for _, td := range tests {
+        t.Run(td.name, func(t *testing.T) {
+            kdk := Extract(td.prf, td.salt, td.z)
+            if !bytes.Equal(kdk, td.kdk) {
+                t.Errorf("KDK got %x, want %x", kdk, td.kdk)
+            }
+
+            want := ExpandCounter(td.prf, td.kdk, testFixedInfo, 50)
+
+            got := TwoStepKey(td.prf, td.salt, td.z, testFixedInfo, len(want))
+            if !bytes.Equal(got, want) {
+                t.Errorf("got %x, want %x", got, want)
+            }
+        })
+    }
+}
+
+// CAVP SP 800-108 KDFCTR_gen.rsp, BEFORE_FIXED, r=32, COUNT=0
+func Test_ExpandCounter(t *testing.T) {
+    tests := []struct {
+        name      string
+        prf       kbkdf.PRF
+        kdk       []byte
+        fixedInfo []byte
+        key       []byte
+    }{
+        {
+            "HMAC-SHA256",
+            kbkdf.NewHMACPRF(sha256.New),
+            fromHex("dd1d91b7d90b2bd3138533ce92b272fbf8a369316aefe242e659cc0ae238afe0"),
+            fromHex("01322b96b30acd197979444e468e1c5c6859bf1b1cf951b7e725303e237e46b864a145fab25e517b08f8683d0315bb2911d80a0e8aba17f3b413faac"),
+            fromHex("10621342bfb0fd40046c0e29f2cfdbf0"),
+        },
+        {
+            "CMAC-AES128",
+            kbkdf.NewCMACPRF(aes.NewCipher),
+            fromHex("c10b152e8c97b77e18704e0f0bd38305"),
+            fromHex("98cd4cbbbebe15d17dc86e6dbad800a2dcbd64f7c7ad0e78e9cf94ffdba89d03e97eadf6c4f7b806caf52aa38f09d0eb71d71f497bcc6906b48d36c4"),
+            fromHex("26faf61908ad9ee881b8305c221db53f"),
+        },
+    }
+
+    for _, td := range tests {
+        t.Run(td.name, func(t *testing.T) {
+            got := ExpandCounter(td.prf, td.kdk, td.fixedInfo, len(td.key))
+            if !bytes.Equal(got, td.key) {
+                t.Errorf("got %x, want %x", got, td.key)
+            }
+        })
+    }
+}
+
+// CAVP SP 800-108 FeedbackModeNOzeroiv/KDFFeedback_gen.rsp, AFTER_ITER, r=32, COUNT=0
+func Test_ExpandFeedback(t *testing.T) {
+    tests := []struct {
+        name      string
+        prf       kbkdf.PRF
+        kdk       []byte
+        iv        []byte
+        fixedInfo []byte
+        key       []byte
+    }{
+        {
+            "HMAC-SHA256",
+            kbkdf.NewHMACPRF(sha256.New),
+            fromHex("93f698e842eed75394d629d957e2e89c6e741f810b623c8b901e38376d068e7b"),
+            fromHex("9f575d9059d3e0c0803f08112f8a806de3c3471912cdf42b095388b14b33508e"),
+            fromHex("53b89c18690e2057a1d167822e636de50be0018532c431f7f5e37f77139220d5e042599ebe266af5767ee18cd2c5c19a1f0f80"),
+            fromHex("bd1476f43a4e315747cf5918e0ea5bc0d98769457477c3ab18b742def0e079a933b756365afb5541f253fee43c6fd788a44041038509e9eeb68f7d65ffbb5f95"),
+        },
+        {
+            "CMAC-AES128",
+            kbkdf.NewCMACPRF(aes.NewCipher),
+            fromHex("e96c5574da99225f1b3a2ec160cccfb4"),
+            fromHex("74507da3c0449bc40233db0d2e4de082"),
+            fromHex("8ec5d49e432398ea3bf5fb0fcd4d1928fd0c0d191d64db70a30bcc888c61d8cfb9f1c8e15e03e905cb4e49ff05d125802fb556"),
+            fromHex("064eebe2965c46ef4d3fa37447cf21f60c9bcc9e28cf3f1cac9992fda11e0d006a220664685613857ece98331f63ca84de7ffbd7e608283493f1dee412768692"),
+        },
+    }
+
+    for _, td := range tests {
+        t.Run(td.name, func(t *testing.T) {
+            got := ExpandFeedback(td.prf, td.kdk, td.iv, td.fixedInfo, len(td.key))
+            if !bytes.Equal(got, td.key) {
+                t.Errorf("got %x, want %x", got, td.key)
+            }
+        })
+    }
+}
@@ -0,0 +1,208 @@
+package keystore
+
+import (
+    "time"
+    "sort"
+    "errors"
+    "crypto"
+    "crypto/x509"
+)
+
+var (
+    // 格式不支持的条目
+    ErrUnsupportedEntry = errors.New("go-cryptobin/keystore: entry type is not supported by the keystore")
+
+    // 没有条目
+    ErrEntryNotFound = errors.New("go-cryptobin/keystore: entry not found")
+)
+
+// 条目类型
+type EntryType uint
+
+const (
+    UnknownEntry EntryType = iota
+    PrivateKeyEntry
+    TrustedCertEntry
+    SecretKeyEntry
+)
+
+func (typ EntryType) String() string {
+    switch typ {
+        case PrivateKeyEntry:
+            return "PrivateKeyEntry"
+        case TrustedCertEntry:
+            return "TrustedCertEntry"
+        case SecretKeyEntry:
+            return "SecretKeyEntry"
+    }
+
+    return "UnknownEntry"
+}
+
+// KeyStore 为 JKS, JCEKS, BKS, UBER 和 PKCS12 的通用接口
+type KeyStore interface {
+    // 别名列表, 已排序
+    Aliases() []string
+
+    // 条目类型
+    EntryType(alias string) (EntryType, error)
+
+    // 创建时间, 没有时为零值
+    CreationDate(alias string) (time.Time, error)
+
+    // 私钥和证书链
+    PrivateKeyEntry(alias string, password string) (crypto.PrivateKey, []*x509.Certificate, error)
+
+    // 信任证书
+    TrustedCertEntry(alias string) (*x509.Certificate, error)
+
+    // 密钥
+    SecretKeyEntry(alias string, password string) ([]byte, error)
+
+    // 设置私钥和证书链, date 为零值时使用当前时间
+    SetPrivateKeyEntry(alias string, key crypto.PrivateKey, certChain []*x509.Certificate, password string, date time.Time) error
+
+    // 设置信任证书
+    SetTrustedCertEntry(alias string, cert *x509.Certificate, date time.Time) error
+
+    // 设置密钥
+    SetSecretKeyEntry(alias string, key []byte, password string, date time.Time) error
+}
+
+// 转换时的密码
+type Passwords struct {
+    // 读取源条目的密码
+    Source string
+
+    // 写入目标条目的密码, 为空时使用源条目的密码
+    Target string
+
+    // 按别名设置的源条目密码
+    Entries map[string]string
+}
+
+func (this Passwords) source(alias string) string {
+    if password, ok := this.Entries[alias]; ok {
+        return password
+    }
+
+    return this.Source
+}
+
+func (this Passwords) target(alias string) string {
+    if this.Target != "" {
+        return this.Target
+    }
+
+    return this.source(alias)
+}
+
+// 未转换的条目
+type SkippedEntry struct {
+    Alias string
+    Type  EntryType
+    Err   error
+}
+
+// 转换结果
+type ConvertResult struct {
+    // 已转换的别名
+    Converted []string
+
+    // 目标格式不支持的条目
+    Skipped []SkippedEntry
+}
+
+// Convert 将 src 的条目复制到 dst, 目标格式不支持的条目记录在 Skipped 中,
+// 其他错误直接返回
+func Convert(src, dst KeyStore, passwords Passwords) (*ConvertResult, error) {
+    result := &ConvertResult{}
+
+    for _, alias := range src.Aliases() {
+        typ, err := src.EntryType(alias)
+        if err != nil {
+            return nil, err
+        }
+
+        date, err := src.CreationDate(alias)
+        if err != nil {
+            return nil, err
+        }
+
+        err = convertEntry(src, dst, alias, typ, date, passwords)
+        if err != nil {
+            if !errors.Is(err, ErrUnsupportedEntry) {
+                return nil, err
+            }
+
+            result.Skipped = append(result.Skipped, SkippedEntry{
+                Alias: alias,
+                Type:  typ,
+                Err:   err,
+            })
+
+            continue
+        }
+
+        result.Converted = append(result.Converted, alias)
+    }
+
+    return result, nil
+}
+
+func convertEntry(
+    src, dst KeyStore,
+    alias string,
+    typ EntryType,
+    date time.Time,
+    passwords Passwords,
+) error {
+    password := passwords.source(alias)
+
+    switch typ {
+        case PrivateKeyEntry:
+            key, chain, err := src.PrivateKeyEntry(alias, password)
+            if err != nil {
+                return err
+            }
+
+            return dst.SetPrivateKeyEntry(alias, key, chain, passwords.target(alias), date)
+        case TrustedCertEntry:
+            cert, err := src.TrustedCertEntry(alias)
+            if err != nil {
+                return err
+            }
+
+            return dst.SetTrustedCertEntry(alias, cert, date)
+        case SecretKeyEntry:
+            key, err := src.SecretKeyEntry(alias, password)
+            if err != nil {
+                return err
+            }
+
+            return dst.SetSecretKeyEntry(alias, key, passwords.target(alias), date)
+    }
+
+    return ErrUnsupportedEntry
+}
+
+// SortedAliases 返回排序后的别名
+func SortedAliases[T any](entries map[string]T) []string {
+    aliases := make([]string, 0, len(entries))
+    for alias := range entries {
+        aliases = append(aliases, alias)
+    }
+
+    sort.Strings(aliases)
+
+    return aliases
+}
+
+// DateOrNow 零值时返回当前时间
+func DateOrNow(date time.Time) time.Time {
+    if date.IsZero() {
+        return time.Now()
+    }
+
+    return date
+}
@@ -22,17 +22,23 @@ func NewCMAC(b cipher.Block, size int) BlockCipherMAC {
 
     blockSize := b.BlockSize()
 
+    // 64 位分组的不可约多项式为 x^64 + x^4 + x^3 + x + 1
+    var rb byte = 0b10000111
+    if blockSize == 8 {
+        rb = 0b00011011
+    }
+
     k1 := make([]byte, blockSize)
     k2 := make([]byte, blockSize)
 
     b.Encrypt(k1, k1)
 
     msb := shiftLeft(k1)
-    k1[len(k1)-1] ^= msb * 0b10000111
+    k1[len(k1)-1] ^= msb * rb
 
     copy(k2, k1)
     msb = shiftLeft(k2)
-    k2[len(k2)-1] ^= msb * 0b10000111
+    k2[len(k2)-1] ^= msb * rb
 
     return &cmac{
         b: b,
@@ -56,7 +62,7 @@ func (c *cmac) MAC(src []byte) []byte {
         tag[len(src)] ^= 0b10000000
 
         c.b.Encrypt(tag, tag)
-        return tag
+        return tag[:c.size]
     }
 
     for len(src) >= blockSize {
@@ -0,0 +1,201 @@
+package mac
+
+import (
+    "hash"
+    "errors"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/hash/polyval"
+)
+
+const gmacBlockSize = 16
+
+var (
+    errGMACBlockSize = errors.New("go-cryptobin/mac: GMAC requires a 128-bit block cipher")
+    errGMACNonceSize = errors.New("go-cryptobin/mac: invalid GMAC nonce size")
+    errGMACTagSize   = errors.New("go-cryptobin/mac: invalid GMAC tag size")
+)
+
+// gmac 为只有附加数据的 GCM 标签.
+// GHASH 由 POLYVAL 计算, 见 RFC 8452 附录 A:
+// GHASH(H, X) = ByteReverse(POLYVAL(mulX_POLYVAL(ByteReverse(H)), ByteReverse(X)))
+type gmac struct {
+    p   *polyval.Polyval
+    ej0 [gmacBlockSize]byte
+
+    buf [gmacBlockSize]byte
+    n   int
+    len uint64
+
+    tagSize int
+}
+
+// NewGMAC returns a hash.Hash computing the GMAC of NIST SP 800-38D
+// with the given 128-bit block cipher and nonce.
+// The nonce must not be reused with the same key.
+func NewGMAC(b cipher.Block, nonce []byte) (hash.Hash, error) {
+    return NewGMACWithTagSize(b, nonce, gmacBlockSize)
+}
+
+// NewGMACWithTagSize is like NewGMAC, but the tag is truncated to tagSize bytes.
+// The tag size must be between 12 and 16.
+func NewGMACWithTagSize(b cipher.Block, nonce []byte, tagSize int) (hash.Hash, error) {
+    if b.BlockSize() != gmacBlockSize {
+        return nil, errGMACBlockSize
+    }
+
+    if len(nonce) == 0 {
+        return nil, errGMACNonceSize
+    }
+
+    if tagSize < 12 || tagSize > gmacBlockSize {
+        return nil, errGMACTagSize
+    }
+
+    var h [gmacBlockSize]byte
+    b.Encrypt(h[:], h[:])
+
+    reverseBytes(h[:])
+    mulX(h[:])
+
+    p, err := polyval.New(h[:])
+    if err != nil {
+        return nil, err
+    }
+
+    g := &gmac{
+        p: p,
+        tagSize: tagSize,
+    }
+
+    // J0
+    var j0 [gmacBlockSize]byte
+    if len(nonce) == 12 {
+        copy(j0[:], nonce)
+        j0[gmacBlockSize-1] = 1
+    } else {
+        g.Write(nonce)
+
+        var lens [gmacBlockSize]byte
+        binary.BigEndian.PutUint64(lens[8:], uint64(len(nonce)) * 8)
+        g.flush(lens[:])
+
+        copy(j0[:], g.ghash())
+        g.Reset()
+    }
+
+    b.Encrypt(g.ej0[:], j0[:])
+
+    return g, nil
+}
+
+func (g *gmac) Size() int {
+    return g.tagSize
+}
+
+func (g *gmac) BlockSize() int {
+    return gmacBlockSize
+}
+
+func (g *gmac) Reset() {
+    g.p.Reset()
+    g.n = 0
+    g.len = 0
+}
+
+func (g *gmac) Write(data []byte) (int, error) {
+    nn := len(data)
+    g.len += uint64(nn)
+
+    if g.n > 0 {
+        n := copy(g.buf[g.n:], data)
+        g.n += n
+        data = data[n:]
+
+        if g.n < gmacBlockSize {
+            return nn, nil
+        }
+
+        g.writeBlock(g.buf[:])
+        g.n = 0
+    }
+
+    for len(data) >= gmacBlockSize {
+        g.writeBlock(data[:gmacBlockSize])
+        data = data[gmacBlockSize:]
+    }
+
+    g.n = copy(g.buf[:], data)
+
+    return nn, nil
+}
+
+func (g *gmac) Sum(in []byte) []byte {
+    p, n := *g.p, g.n
+    defer func() {
+        *g.p, g.n = p, n
+    }()
+
+    // 补零后加上长度分组 len(A) || len(C), 其中 len(C) 为 0
+    var lens [gmacBlockSize]byte
+    binary.BigEndian.PutUint64(lens[:], g.len * 8)
+
+    g.flush(lens[:])
+
+    tag := g.ghash()
+    subtle.XORBytes(tag, tag, g.ej0[:])
+
+    return append(in, tag[:g.tagSize]...)
+}
+
+// 补零写入剩余数据, 再写入一个完整分组
+func (g *gmac) flush(block []byte) {
+    if g.n > 0 {
+        var last [gmacBlockSize]byte
+        copy(last[:], g.buf[:g.n])
+        g.writeBlock(last[:])
+        g.n = 0
+    }
+
+    g.writeBlock(block)
+}
+
+func (g *gmac) writeBlock(block []byte) {
+    var x [gmacBlockSize]byte
+    copy(x[:], block)
+    reverseBytes(x[:])
+
+    g.p.Write(x[:])
+}
+
+func (g *gmac) ghash() []byte {
+    s := g.p.Sum(nil)
+    reverseBytes(s)
+
+    return s
+}
+
+func reverseBytes(b []byte) {
+    for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+        b[i], b[j] = b[j], b[i]
+    }
+}
+
+// POLYVAL 域中乘以 x
+func mulX(b []byte) {
+    lo := binary.LittleEndian.Uint64(b[0:])
+    hi := binary.LittleEndian.Uint64(b[8:])
+
+    msb := hi >> 63
+
+    hi = hi << 1 | lo >> 63
+    lo = lo << 1
+
+    lo ^= msb
+    hi ^= msb << 63 | msb << 62 | msb << 57
+
+    binary.LittleEndian.PutUint64(b[0:], lo)
+    binary.LittleEndian.PutUint64(b[8:], hi)
+}
@@ -1,8 +1,11 @@
 package mac
 
 import (
+    "hash"
     "bytes"
     "crypto/aes"
+    "crypto/des"
+    "crypto/cipher"
     "encoding/hex"
     "testing"
 
@@ -201,6 +204,40 @@ func TestCMAC(t *testing.T) {
     }
 }
 
+func TestCMACTDEA(t *testing.T) {
+    // Test vectors from NIST SP 800-38B, TDEA examples.
+    key, _ := hex.DecodeString("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5")
+    cases := []struct {
+        src string
+        tag string
+    }{
+        {"", "b7a688e122ffaf95"},
+        {"6bc1bee22e409f96", "8e8f293136283797"},
+        {"6bc1bee22e409f96e93d7e117393172aae2d8a57", "743ddbe0ce2dc2ed"},
+        {"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51", "33e6b1092400eae5"},
+    }
+
+    block, err := des.NewTripleDESCipher(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for i, c := range cases {
+        src, _ := hex.DecodeString(c.src)
+        tag := NewCMAC(block, 8).MAC(src)
+
+        if hex.EncodeToString(tag) != c.tag {
+            t.Errorf("#%d: expect tag %s, got %x", i, c.tag, tag)
+        }
+    }
+
+    // 截断的标签
+    tag := NewCMAC(block, 4).MAC(nil)
+    if hex.EncodeToString(tag) != "b7a688e1" {
+        t.Errorf("expect truncated tag b7a688e1, got %x", tag)
+    }
+}
+
 func TestLMAC(t *testing.T) {
     // Test vectors from GB/T 15821.1-2020 Appendix B.
     cases := []struct {
@@ -492,3 +529,193 @@ func TestCMACAES(t *testing.T) {
         }
     }
 }
+
+func TestGMAC(t *testing.T) {
+    // GCM 测试用例 1 和 2 中的标签
+    block, err := aes.NewCipher(make([]byte, 16))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    h, err := NewGMAC(block, make([]byte, 12))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := fromHex("58e2fccefa7e3061367f1d57a4e7455a")
+    if tag := h.Sum(nil); !bytes.Equal(tag, want) {
+        t.Errorf("expect tag %x, got %x", want, tag)
+    }
+
+    // 与 GCM 只有附加数据时的标签比较
+    newCiphers := []BlockCipherFunc{aes.NewCipher, sm4.NewCipher}
+
+    for i, newCipher := range newCiphers {
+        block, err := newCipher(fromHex("feffe9928665731c6d6a8f9467308308"))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for _, nonceSize := range []int{8, 12, 16, 60} {
+            nonce := make([]byte, nonceSize)
+            for j := range nonce {
+                nonce[j] = byte(j * 7)
+            }
+
+            aead, err := cipher.NewGCMWithNonceSize(block, nonceSize)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            for _, n := range []int{0, 1, 16, 20, 33, 64} {
+                ad := make([]byte, n)
+                for j := range ad {
+                    ad[j] = byte(j)
+                }
+
+                want := aead.Seal(nil, nonce, nil, ad)
+
+                h, err := NewGMAC(block, nonce)
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                h.Write(ad[:n/3])
+                h.Sum(nil)
+                h.Write(ad[n/3:])
+
+                if tag := h.Sum(nil); !bytes.Equal(tag, want) {
+                    t.Errorf("#%d-%d-%d: expect tag %x, got %x", i, nonceSize, n, want, tag)
+                }
+
+                h, err = NewGMACWithTagSize(block, nonce, 12)
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                h.Write(ad)
+                if tag := h.Sum(nil); !bytes.Equal(tag, want[:12]) {
+                    t.Errorf("#%d-%d-%d: expect tag %x, got %x", i, nonceSize, n, want[:12], tag)
+                }
+            }
+        }
+    }
+
+    if _, err := NewGMAC(block, nil); err == nil {
+        t.Error("should fail with empty nonce")
+    }
+
+    if _, err := NewGMACWithTagSize(block, make([]byte, 12), 8); err == nil {
+        t.Error("should fail with bad tag size")
+    }
+}
+
+func TestPoly1305(t *testing.T) {
+    // Poly1305-AES 论文中的例子
+    cases := []struct {
+        k     string
+        r     string
+        nonce string
+        msg   string
+        tag   string
+    }{
+        {
+            "ec074c835580741701425b623235add6",
+            "851fc40c3467ac0be05cc20404f3f700",
+            "fb447350c4e868c52ac3275cf9d4327e",
+            "f3f6",
+            "f4c633c3044fc145f84f335cb81953de",
+        },
+        {
+            "75deaa25c09f208e1dc4ce6b5cad3fbf",
+            "a0f3080000f46400d0c7e9076c834403",
+            "61ee09218d29b0aaed7e154a2c5509cc",
+            "",
+            "dd3fab2251f11ac759f0887129cc2ee7",
+        },
+    }
+
+    for i, c := range cases {
+        block, err := aes.NewCipher(fromHex(c.k))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        h, err := NewPoly1305(block, fromHex(c.r), fromHex(c.nonce))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        h.Write(fromHex(c.msg))
+        tag := h.Sum(nil)
+
+        if !bytes.Equal(tag, fromHex(c.tag)) {
+            t.Errorf("#%d: expect tag %s, got %x", i, c.tag, tag)
+        }
+
+        // Sum 后可以继续写入
+        h.Write([]byte("more"))
+        h.Reset()
+        h.Write(fromHex(c.msg))
+
+        if tag := h.Sum(nil); !bytes.Equal(tag, fromHex(c.tag)) {
+            t.Errorf("#%d: Reset expect tag %s, got %x", i, c.tag, tag)
+        }
+    }
+
+    block, _ := sm4.NewCipher(make([]byte, 16))
+    if _, err := NewPoly1305(block, make([]byte, 16), make([]byte, 12)); err == nil {
+        t.Error("should fail with bad nonce size")
+    }
+}
+
+func TestSipHash(t *testing.T) {
+    key := fromHex("000102030405060708090a0b0c0d0e0f")
+    msg := fromHex("000102030405060708090a0b0c0d0e")
+
+    // 论文附录 A 和参考实现的测试向量, SipHash-1-3-128 为回归值
+    cases := []struct {
+        newHash func(key []byte) (hash.Hash, error)
+        msg     []byte
+        sum     string
+    }{
+        {newSipHash24, nil, "310e0edd47db6f72"},
+        {newSipHash24, msg, "e545be4961ca29a1"},
+        {NewSipHash24_128, nil, "a3817f04ba25a8e66df67214c7550293"},
+        {newSipHash13, nil, "dcc40f055801acab"},
+        {newSipHash13, msg, "5699512a6dd820d3"},
+        {NewSipHash13_128, nil, "e77ebcb22788a5befd62db6add303001"},
+        {NewSipHash13_128, msg, "c17e5505b2bd526c2921cdec1e7e0109"},
+    }
+
+    for i, c := range cases {
+        h, err := c.newHash(key)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        h.Write(c.msg)
+
+        if sum := hex.EncodeToString(h.Sum(nil)); sum != c.sum {
+            t.Errorf("#%d: expect %s, got %s", i, c.sum, sum)
+        }
+    }
+
+    h, _ := NewSipHash24(key)
+    h.Write(msg)
+    if h.Sum64() != 0xa129ca6149be45e5 {
+        t.Errorf("Sum64 got %x", h.Sum64())
+    }
+
+    if _, err := NewSipHash24(key[:8]); err == nil {
+        t.Error("should fail with bad key size")
+    }
+}
+
+func newSipHash24(key []byte) (hash.Hash, error) {
+    return NewSipHash24(key)
+}
+
+func newSipHash13(key []byte) (hash.Hash, error) {
+    return NewSipHash13(key)
+}
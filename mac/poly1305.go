@@ -0,0 +1,188 @@
+package mac
+
+import (
+    "hash"
+    "errors"
+    "math/bits"
+    "crypto/cipher"
+    "encoding/binary"
+)
+
+const (
+    poly1305TagSize   = 16
+    poly1305BlockSize = 16
+)
+
+var (
+    errPoly1305BlockSize = errors.New("go-cryptobin/mac: Poly1305 requires a 128-bit block cipher")
+    errPoly1305KeySize   = errors.New("go-cryptobin/mac: invalid Poly1305 r size")
+    errPoly1305NonceSize = errors.New("go-cryptobin/mac: invalid Poly1305 nonce size")
+)
+
+// poly1305MAC 的累加器 h 为 130 位, 用 h0, h1, h2 三个字表示
+type poly1305MAC struct {
+    r0, r1 uint64
+    s0, s1 uint64
+
+    h0, h1, h2 uint64
+
+    buf [poly1305BlockSize]byte
+    n   int
+}
+
+// NewPoly1305 returns a hash.Hash computing Poly1305 with the given
+// 128-bit block cipher, e.g. Poly1305-AES and Poly1305-SM4.
+// The tag is Poly1305_r(m) + E_k(nonce) mod 2^128, r is 16 bytes
+// and is clamped. The nonce must not be reused with the same key.
+//
+// Reference: https://cr.yp.to/mac/poly1305-20050329.pdf
+func NewPoly1305(b cipher.Block, r, nonce []byte) (hash.Hash, error) {
+    if b.BlockSize() != 16 {
+        return nil, errPoly1305BlockSize
+    }
+
+    if len(r) != 16 {
+        return nil, errPoly1305KeySize
+    }
+
+    if len(nonce) != 16 {
+        return nil, errPoly1305NonceSize
+    }
+
+    var s [16]byte
+    b.Encrypt(s[:], nonce)
+
+    p := &poly1305MAC{
+        r0: binary.LittleEndian.Uint64(r[0:]) & 0x0ffffffc0fffffff,
+        r1: binary.LittleEndian.Uint64(r[8:]) & 0x0ffffffc0ffffffc,
+        s0: binary.LittleEndian.Uint64(s[0:]),
+        s1: binary.LittleEndian.Uint64(s[8:]),
+    }
+
+    return p, nil
+}
+
+func (p *poly1305MAC) Size() int {
+    return poly1305TagSize
+}
+
+func (p *poly1305MAC) BlockSize() int {
+    return poly1305BlockSize
+}
+
+func (p *poly1305MAC) Reset() {
+    p.h0, p.h1, p.h2 = 0, 0, 0
+    p.n = 0
+}
+
+func (p *poly1305MAC) Write(data []byte) (int, error) {
+    nn := len(data)
+
+    if p.n > 0 {
+        n := copy(p.buf[p.n:], data)
+        p.n += n
+        data = data[n:]
+
+        if p.n < poly1305BlockSize {
+            return nn, nil
+        }
+
+        p.block(p.buf[:], 1)
+        p.n = 0
+    }
+
+    for len(data) >= poly1305BlockSize {
+        p.block(data[:poly1305BlockSize], 1)
+        data = data[poly1305BlockSize:]
+    }
+
+    p.n = copy(p.buf[:], data)
+
+    return nn, nil
+}
+
+func (p *poly1305MAC) Sum(in []byte) []byte {
+    d := *p
+
+    // 最后不完整分组补 0x01 后不再加 2^128
+    if d.n > 0 {
+        var last [poly1305BlockSize]byte
+        copy(last[:], d.buf[:d.n])
+        last[d.n] = 1
+
+        d.block(last[:], 0)
+    }
+
+    // h mod 2^130 - 5
+    h0, h1, h2 := d.h0, d.h1, d.h2
+
+    t0, b := bits.Sub64(h0, 0xfffffffffffffffb, 0)
+    t1, b := bits.Sub64(h1, 0xffffffffffffffff, b)
+    _, b = bits.Sub64(h2, 3, b)
+
+    // b 为 0 时 h >= 2^130 - 5, 取 t
+    mask := b - 1
+    h0 = h0 &^ mask | t0 & mask
+    h1 = h1 &^ mask | t1 & mask
+
+    // 加上 s, 截取低 128 位
+    h0, c := bits.Add64(h0, d.s0, 0)
+    h1, _ = bits.Add64(h1, d.s1, c)
+
+    var tag [poly1305TagSize]byte
+    binary.LittleEndian.PutUint64(tag[0:], h0)
+    binary.LittleEndian.PutUint64(tag[8:], h1)
+
+    return append(in, tag[:]...)
+}
+
+// h = (h + m + hibit * 2^128) * r mod 2^130 - 5
+func (p *poly1305MAC) block(m []byte, hibit uint64) {
+    var c uint64
+
+    h0, h1, h2 := p.h0, p.h1, p.h2
+
+    h0, c = bits.Add64(h0, binary.LittleEndian.Uint64(m[0:]), 0)
+    h1, c = bits.Add64(h1, binary.LittleEndian.Uint64(m[8:]), c)
+    h2 += c + hibit
+
+    // h * r, h2 不超过 7, r 的高位已被截断
+    r0, r1 := p.r0, p.r1
+
+    h0r0hi, h0r0lo := bits.Mul64(h0, r0)
+    h1r0hi, h1r0lo := bits.Mul64(h1, r0)
+    h0r1hi, h0r1lo := bits.Mul64(h0, r1)
+    h1r1hi, h1r1lo := bits.Mul64(h1, r1)
+    h2r0 := h2 * r0
+    h2r1 := h2 * r1
+
+    m0 := h0r0lo
+
+    m1, c := bits.Add64(h1r0lo, h0r1lo, 0)
+    m2 := h1r0hi + h0r1hi + c
+
+    m1, c = bits.Add64(m1, h0r0hi, 0)
+    m2, c2 := bits.Add64(m2, h1r1lo, c)
+    m3 := h1r1hi + c2
+
+    m2, c = bits.Add64(m2, h2r0, 0)
+    m3 += h2r1 + c
+
+    // 2^130 = 5 (mod 2^130 - 5), 高位 cc 乘以 5 = 4 + 1
+    h0, h1, h2 = m0, m1, m2 & 3
+
+    cc0, cc1 := m2 &^ 3, m3
+
+    h0, c = bits.Add64(h0, cc0, 0)
+    h1, c = bits.Add64(h1, cc1, c)
+    h2 += c
+
+    cc0 = cc0 >> 2 | cc1 << 62
+    cc1 = cc1 >> 2
+
+    h0, c = bits.Add64(h0, cc0, 0)
+    h1, c = bits.Add64(h1, cc1, c)
+    h2 += c
+
+    p.h0, p.h1, p.h2 = h0, h1, h2
+}
@@ -0,0 +1,197 @@
+package mac
+
+import (
+    "hash"
+    "errors"
+    "math/bits"
+    "encoding/binary"
+)
+
+var (
+    errSipHashKeySize = errors.New("go-cryptobin/mac: SipHash key must be 16 bytes")
+    errSipHashSize    = errors.New("go-cryptobin/mac: SipHash size must be 8 or 16")
+)
+
+// sipHash 为 SipHash-c-d, 输出 64 或 128 位
+//
+// Reference: https://www.aumasson.jp/siphash/siphash.pdf
+type sipHash struct {
+    k0, k1 uint64
+    c, d   int
+    size   int
+
+    v0, v1, v2, v3 uint64
+
+    buf [8]byte
+    n   int
+    len uint64
+}
+
+// NewSipHash24 returns a hash.Hash64 computing SipHash-2-4 with the 16 bytes key.
+func NewSipHash24(key []byte) (hash.Hash64, error) {
+    return newSipHash(key, 2, 4, 8)
+}
+
+// NewSipHash24_128 returns a hash.Hash computing the 128-bit SipHash-2-4.
+func NewSipHash24_128(key []byte) (hash.Hash, error) {
+    return newSipHash(key, 2, 4, 16)
+}
+
+// NewSipHash13 returns a hash.Hash64 computing SipHash-1-3 with the 16 bytes key.
+func NewSipHash13(key []byte) (hash.Hash64, error) {
+    return newSipHash(key, 1, 3, 8)
+}
+
+// NewSipHash13_128 returns a hash.Hash computing the 128-bit SipHash-1-3.
+func NewSipHash13_128(key []byte) (hash.Hash, error) {
+    return newSipHash(key, 1, 3, 16)
+}
+
+// NewSipHash returns a hash.Hash computing SipHash-c-d,
+// size is 8 or 16 bytes.
+func NewSipHash(key []byte, c, d, size int) (hash.Hash, error) {
+    if c <= 0 || d <= 0 {
+        return nil, errors.New("go-cryptobin/mac: invalid SipHash rounds")
+    }
+
+    return newSipHash(key, c, d, size)
+}
+
+func newSipHash(key []byte, c, d, size int) (*sipHash, error) {
+    if len(key) != 16 {
+        return nil, errSipHashKeySize
+    }
+
+    if size != 8 && size != 16 {
+        return nil, errSipHashSize
+    }
+
+    s := &sipHash{
+        k0: binary.LittleEndian.Uint64(key[0:]),
+        k1: binary.LittleEndian.Uint64(key[8:]),
+        c: c,
+        d: d,
+        size: size,
+    }
+    s.Reset()
+
+    return s, nil
+}
+
+func (s *sipHash) Size() int {
+    return s.size
+}
+
+func (s *sipHash) BlockSize() int {
+    return 8
+}
+
+func (s *sipHash) Reset() {
+    s.v0 = s.k0 ^ 0x736f6d6570736575
+    s.v1 = s.k1 ^ 0x646f72616e646f6d
+    s.v2 = s.k0 ^ 0x6c7967656e657261
+    s.v3 = s.k1 ^ 0x7465646279746573
+
+    if s.size == 16 {
+        s.v1 ^= 0xee
+    }
+
+    s.n = 0
+    s.len = 0
+}
+
+func (s *sipHash) Write(data []byte) (int, error) {
+    nn := len(data)
+    s.len += uint64(nn)
+
+    if s.n > 0 {
+        n := copy(s.buf[s.n:], data)
+        s.n += n
+        data = data[n:]
+
+        if s.n < 8 {
+            return nn, nil
+        }
+
+        s.compress(binary.LittleEndian.Uint64(s.buf[:]))
+        s.n = 0
+    }
+
+    for len(data) >= 8 {
+        s.compress(binary.LittleEndian.Uint64(data))
+        data = data[8:]
+    }
+
+    s.n = copy(s.buf[:], data)
+
+    return nn, nil
+}
+
+func (s *sipHash) Sum(in []byte) []byte {
+    d := *s
+    d.lastBlock()
+
+    var out [16]byte
+    if s.size == 8 {
+        d.v2 ^= 0xff
+        binary.LittleEndian.PutUint64(out[0:], d.finalize())
+    } else {
+        d.v2 ^= 0xee
+        binary.LittleEndian.PutUint64(out[0:], d.finalize())
+
+        d.v1 ^= 0xdd
+        binary.LittleEndian.PutUint64(out[8:], d.finalize())
+    }
+
+    return append(in, out[:s.size]...)
+}
+
+// Sum64 returns the 64-bit sum, it is only for the 64-bit output.
+func (s *sipHash) Sum64() uint64 {
+    return binary.LittleEndian.Uint64(s.Sum(nil))
+}
+
+// 最后分组, 末字节为消息长度
+func (s *sipHash) lastBlock() {
+    var last [8]byte
+    copy(last[:], s.buf[:s.n])
+    last[7] = byte(s.len)
+
+    s.compress(binary.LittleEndian.Uint64(last[:]))
+}
+
+func (s *sipHash) finalize() uint64 {
+    for i := 0; i < s.d; i++ {
+        s.round()
+    }
+
+    return s.v0 ^ s.v1 ^ s.v2 ^ s.v3
+}
+
+func (s *sipHash) compress(m uint64) {
+    s.v3 ^= m
+    for i := 0; i < s.c; i++ {
+        s.round()
+    }
+    s.v0 ^= m
+}
+
+func (s *sipHash) round() {
+    s.v0 += s.v1
+    s.v1 = bits.RotateLeft64(s.v1, 13)
+    s.v1 ^= s.v0
+    s.v0 = bits.RotateLeft64(s.v0, 32)
+
+    s.v2 += s.v3
+    s.v3 = bits.RotateLeft64(s.v3, 16)
+    s.v3 ^= s.v2
+
+    s.v0 += s.v3
+    s.v3 = bits.RotateLeft64(s.v3, 21)
+    s.v3 ^= s.v0
+
+    s.v2 += s.v1
+    s.v1 = bits.RotateLeft64(s.v1, 17)
+    s.v1 ^= s.v2
+    s.v2 = bits.RotateLeft64(s.v2, 32)
+}
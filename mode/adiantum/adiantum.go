@@ -0,0 +1,222 @@
+// Package adiantum implements the Adiantum length-preserving encryption
+// mode, built from XChaCha12, NH, Poly1305 and AES-256.
+// See https://eprint.iacr.org/2018/720.
+package adiantum
+
+import (
+    "errors"
+    "math/bits"
+    "crypto/aes"
+    "crypto/cipher"
+    "encoding/binary"
+
+    "golang.org/x/crypto/poly1305"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+const (
+    // KeySize is the key size in bytes.
+    KeySize = 32
+
+    blockSize = 16
+)
+
+var (
+    errKeySize = errors.New("go-cryptobin/adiantum: invalid key size")
+    errRounds = errors.New("go-cryptobin/adiantum: invalid XChaCha rounds")
+)
+
+// Cipher is an instance of Adiantum. The message length is at least
+// 16 bytes and the tweak has any length.
+type Cipher struct {
+    key    [KeySize]byte
+    rounds int
+
+    block cipher.Block
+
+    // Poly1305 密钥, 只使用 r
+    keyT  [32]byte
+    keyM  [32]byte
+    keyNH [nhKeySize]byte
+}
+
+// New returns Adiantum with XChaCha12 and the 32 bytes key.
+func New(key []byte) (*Cipher, error) {
+    return NewWithRounds(key, 12)
+}
+
+// NewWithRounds returns Adiantum with XChaCha8, XChaCha12 or XChaCha20.
+func NewWithRounds(key []byte, rounds int) (*Cipher, error) {
+    if len(key) != KeySize {
+        return nil, errKeySize
+    }
+
+    if rounds != 8 && rounds != 12 && rounds != 20 {
+        return nil, errRounds
+    }
+
+    c := &Cipher{
+        rounds: rounds,
+    }
+    copy(c.key[:], key)
+
+    // 密钥流派生 AES 密钥, 两个 Poly1305 密钥和 NH 密钥
+    keys := make([]byte, 32 + 16 + 16 + nhKeySize)
+    c.streamXOR(keys, keys, nil)
+
+    block, err := aes.NewCipher(keys[:32])
+    if err != nil {
+        return nil, err
+    }
+
+    c.block = block
+
+    copy(c.keyT[:16], keys[32:])
+    copy(c.keyM[:16], keys[48:])
+    copy(c.keyNH[:], keys[64:])
+
+    return c, nil
+}
+
+// Encrypt encrypts src with the tweak into dst.
+// Dst and src must overlap entirely or not at all.
+func (c *Cipher) Encrypt(dst, src, tweak []byte) {
+    c.checkArgs(dst, src)
+
+    n := len(src) - blockSize
+    pl, pr := src[:n], src[n:]
+    cl, cr := dst[:n], dst[n:]
+
+    var h, pm, cm [blockSize]byte
+
+    // PM = PR + H(T, PL)
+    c.hash(&h, tweak, pl)
+    blockAdd(&pm, pr, h[:])
+
+    // CM = E(PM)
+    c.block.Encrypt(cm[:], pm[:])
+
+    // CL = PL ^ XChaCha(CM || 1)
+    c.streamXOR(cl, pl, cm[:])
+
+    // CR = CM - H(T, CL)
+    c.hash(&h, tweak, cl)
+    blockSub(cr, cm[:], h[:])
+}
+
+// Decrypt decrypts src with the tweak into dst.
+// Dst and src must overlap entirely or not at all.
+func (c *Cipher) Decrypt(dst, src, tweak []byte) {
+    c.checkArgs(dst, src)
+
+    n := len(src) - blockSize
+    cl, cr := src[:n], src[n:]
+    pl, pr := dst[:n], dst[n:]
+
+    var h, pm, cm [blockSize]byte
+
+    // CM = CR + H(T, CL)
+    c.hash(&h, tweak, cl)
+    blockAdd(&cm, cr, h[:])
+
+    // PL = CL ^ XChaCha(CM || 1)
+    c.streamXOR(pl, cl, cm[:])
+
+    // PM = D(CM)
+    c.block.Decrypt(pm[:], cm[:])
+
+    // PR = PM - H(T, PL)
+    c.hash(&h, tweak, pl)
+    blockSub(pr, pm[:], h[:])
+}
+
+func (c *Cipher) checkArgs(dst, src []byte) {
+    if len(src) < blockSize {
+        panic("go-cryptobin/adiantum: input is smaller than the block size")
+    }
+
+    if len(dst) < len(src) {
+        panic("go-cryptobin/adiantum: output smaller than input")
+    }
+
+    if alias.InexactOverlap(dst[:len(src)], src) {
+        panic("go-cryptobin/adiantum: invalid buffer overlap")
+    }
+}
+
+// nonce 后补 1 再补零到 24 字节
+func (c *Cipher) streamXOR(dst, src, nonce []byte) {
+    var iv [24]byte
+    n := copy(iv[:], nonce)
+    iv[n] = 1
+
+    xchachaXOR(dst, src, c.key[:], iv[:], c.rounds)
+}
+
+// H(T, M) = Poly1305(KT, bin(|M|) || T) + Poly1305(KM, NH(M))
+func (c *Cipher) hash(out *[blockSize]byte, tweak, msg []byte) {
+    var outT, outM [16]byte
+
+    t := make([]byte, blockSize + len(tweak))
+    binary.LittleEndian.PutUint64(t, uint64(len(msg)) * 8)
+    copy(t[blockSize:], tweak)
+
+    poly1305.Sum(&outT, t, &c.keyT)
+
+    mac := poly1305.New(&c.keyM)
+
+    var nh [32]byte
+    var pad [nhMessageSize]byte
+    for len(msg) > 0 {
+        chunk := msg
+        if len(chunk) > nhMessageSize {
+            chunk = chunk[:nhMessageSize]
+        }
+        msg = msg[len(chunk):]
+
+        // 补零到 16 字节倍数
+        if len(chunk) % 16 != 0 {
+            n := copy(pad[:], chunk)
+            chunk = pad[:n + 16 - n % 16]
+            for i := n; i < len(chunk); i++ {
+                chunk[i] = 0
+            }
+        }
+
+        nhSum(&nh, chunk, c.keyNH[:])
+        mac.Write(nh[:])
+    }
+
+    mac.Sum(outM[:0])
+
+    blockAdd(out, outT[:], outM[:])
+}
+
+// 128 位小端序加法
+func blockAdd(out *[blockSize]byte, x, y []byte) {
+    x0 := binary.LittleEndian.Uint64(x[0:])
+    x1 := binary.LittleEndian.Uint64(x[8:])
+    y0 := binary.LittleEndian.Uint64(y[0:])
+    y1 := binary.LittleEndian.Uint64(y[8:])
+
+    r0, carry := bits.Add64(x0, y0, 0)
+    r1, _ := bits.Add64(x1, y1, carry)
+
+    binary.LittleEndian.PutUint64(out[0:], r0)
+    binary.LittleEndian.PutUint64(out[8:], r1)
+}
+
+// 128 位小端序减法
+func blockSub(out, x, y []byte) {
+    x0 := binary.LittleEndian.Uint64(x[0:])
+    x1 := binary.LittleEndian.Uint64(x[8:])
+    y0 := binary.LittleEndian.Uint64(y[0:])
+    y1 := binary.LittleEndian.Uint64(y[8:])
+
+    r0, borrow := bits.Sub64(x0, y0, 0)
+    r1, _ := bits.Sub64(x1, y1, borrow)
+
+    binary.LittleEndian.PutUint64(out[0:], r0)
+    binary.LittleEndian.PutUint64(out[8:], r1)
+}
@@ -0,0 +1,91 @@
+package adiantum
+
+import (
+    "os"
+    "bytes"
+    "testing"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "path/filepath"
+)
+
+type testVector struct {
+    Rounds     int    `json:"rounds"`
+    Key        string `json:"key"`
+    Tweak      string `json:"tweak"`
+    Plaintext  string `json:"plaintext"`
+    Ciphertext string `json:"ciphertext"`
+}
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// 向量来自 https://github.com/google/adiantum
+func Test_Vectors(t *testing.T) {
+    data, err := os.ReadFile(filepath.Join("testdata", "adiantum.json"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var vectors []testVector
+    if err = json.Unmarshal(data, &vectors); err != nil {
+        t.Fatal(err)
+    }
+
+    for i, v := range vectors {
+        c, err := NewWithRounds(fromHex(v.Key), v.Rounds)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        tweak := fromHex(v.Tweak)
+        plaintext := fromHex(v.Plaintext)
+        ciphertext := fromHex(v.Ciphertext)
+
+        got := make([]byte, len(plaintext))
+        c.Encrypt(got, plaintext, tweak)
+        if !bytes.Equal(got, ciphertext) {
+            t.Errorf("[%d] Encrypt got %x, want %x", i, got, ciphertext)
+        }
+
+        c.Decrypt(got, got, tweak)
+        if !bytes.Equal(got, plaintext) {
+            t.Errorf("[%d] Decrypt got %x, want %x", i, got, plaintext)
+        }
+    }
+}
+
+func Test_Tweak(t *testing.T) {
+    key := make([]byte, KeySize)
+    rand.Read(key)
+
+    c, err := New(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    plaintext := make([]byte, 100)
+    rand.Read(plaintext)
+
+    ct1 := make([]byte, len(plaintext))
+    ct2 := make([]byte, len(plaintext))
+    c.Encrypt(ct1, plaintext, []byte("tweak-1"))
+    c.Encrypt(ct2, plaintext, []byte("tweak-2"))
+
+    if bytes.Equal(ct1, ct2) {
+        t.Error("different tweaks should give different ciphertexts")
+    }
+}
+
+func Test_Errors(t *testing.T) {
+    if _, err := New(make([]byte, 16)); err == nil {
+        t.Error("should fail with bad key size")
+    }
+
+    if _, err := NewWithRounds(make([]byte, KeySize), 10); err == nil {
+        t.Error("should fail with bad rounds")
+    }
+}
@@ -0,0 +1,95 @@
+package adiantum
+
+import (
+    "math/bits"
+    "encoding/binary"
+)
+
+// "expand 32-byte k"
+const (
+    c0 = 0x61707865
+    c1 = 0x3320646e
+    c2 = 0x79622d32
+    c3 = 0x6b206574
+)
+
+func quarterRound(a, b, c, d uint32) (uint32, uint32, uint32, uint32) {
+    a += b; d ^= a; d = bits.RotateLeft32(d, 16)
+    c += d; b ^= c; b = bits.RotateLeft32(b, 12)
+    a += b; d ^= a; d = bits.RotateLeft32(d, 8)
+    c += d; b ^= c; b = bits.RotateLeft32(b, 7)
+    return a, b, c, d
+}
+
+func permute(x *[16]uint32, rounds int) {
+    for i := 0; i < rounds; i += 2 {
+        x[0], x[4], x[8], x[12] = quarterRound(x[0], x[4], x[8], x[12])
+        x[1], x[5], x[9], x[13] = quarterRound(x[1], x[5], x[9], x[13])
+        x[2], x[6], x[10], x[14] = quarterRound(x[2], x[6], x[10], x[14])
+        x[3], x[7], x[11], x[15] = quarterRound(x[3], x[7], x[11], x[15])
+
+        x[0], x[5], x[10], x[15] = quarterRound(x[0], x[5], x[10], x[15])
+        x[1], x[6], x[11], x[12] = quarterRound(x[1], x[6], x[11], x[12])
+        x[2], x[7], x[8], x[13] = quarterRound(x[2], x[7], x[8], x[13])
+        x[3], x[4], x[9], x[14] = quarterRound(x[3], x[4], x[9], x[14])
+    }
+}
+
+// HChaCha 派生子密钥
+func hchacha(out *[32]byte, key, nonce []byte, rounds int) {
+    var x [16]uint32
+    x[0], x[1], x[2], x[3] = c0, c1, c2, c3
+    for i := 0; i < 8; i++ {
+        x[4+i] = binary.LittleEndian.Uint32(key[i*4:])
+    }
+    for i := 0; i < 4; i++ {
+        x[12+i] = binary.LittleEndian.Uint32(nonce[i*4:])
+    }
+
+    permute(&x, rounds)
+
+    for i := 0; i < 4; i++ {
+        binary.LittleEndian.PutUint32(out[i*4:], x[i])
+        binary.LittleEndian.PutUint32(out[16+i*4:], x[12+i])
+    }
+}
+
+// XChaCha, 64 位计数器从 0 开始, nonce 为 24 字节
+func xchachaXOR(dst, src, key, nonce []byte, rounds int) {
+    var subKey [32]byte
+    hchacha(&subKey, key, nonce[:16], rounds)
+
+    var state [16]uint32
+    state[0], state[1], state[2], state[3] = c0, c1, c2, c3
+    for i := 0; i < 8; i++ {
+        state[4+i] = binary.LittleEndian.Uint32(subKey[i*4:])
+    }
+    state[14] = binary.LittleEndian.Uint32(nonce[16:])
+    state[15] = binary.LittleEndian.Uint32(nonce[20:])
+
+    var x [16]uint32
+    var ks [64]byte
+
+    for counter := uint64(0); len(src) > 0; counter++ {
+        state[12] = uint32(counter)
+        state[13] = uint32(counter >> 32)
+
+        x = state
+        permute(&x, rounds)
+
+        for i := range x {
+            binary.LittleEndian.PutUint32(ks[i*4:], x[i] + state[i])
+        }
+
+        n := len(src)
+        if n > len(ks) {
+            n = len(ks)
+        }
+
+        for i := 0; i < n; i++ {
+            dst[i] = src[i] ^ ks[i]
+        }
+
+        dst, src = dst[n:], src[n:]
+    }
+}
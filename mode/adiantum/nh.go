@@ -0,0 +1,43 @@
+package adiantum
+
+import (
+    "encoding/binary"
+)
+
+const (
+    nhPasses      = 4
+    nhStride      = 2
+    nhMessageSize = 1024
+    nhKeySize     = nhMessageSize + (nhPasses - 1) * nhStride * 8
+)
+
+// NH 哈希, 消息长度为 16 的倍数且不超过 1024 字节
+func nhSum(out *[32]byte, msg, key []byte) {
+    var sums [nhPasses]uint64
+
+    for len(msg) >= 16 {
+        m0 := binary.LittleEndian.Uint32(msg[0:])
+        m1 := binary.LittleEndian.Uint32(msg[4:])
+        m2 := binary.LittleEndian.Uint32(msg[8:])
+        m3 := binary.LittleEndian.Uint32(msg[12:])
+
+        for i := 0; i < nhPasses; i++ {
+            k := key[i*16:]
+
+            k0 := binary.LittleEndian.Uint32(k[0:])
+            k1 := binary.LittleEndian.Uint32(k[4:])
+            k2 := binary.LittleEndian.Uint32(k[8:])
+            k3 := binary.LittleEndian.Uint32(k[12:])
+
+            sums[i] += uint64(m0 + k0) * uint64(m2 + k2)
+            sums[i] += uint64(m1 + k1) * uint64(m3 + k3)
+        }
+
+        msg = msg[16:]
+        key = key[16:]
+    }
+
+    for i := range sums {
+        binary.LittleEndian.PutUint64(out[i*8:], sums[i])
+    }
+}
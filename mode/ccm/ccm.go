@@ -202,11 +202,13 @@ func (c *ccm) Seal(dst, nonce, plaintext, data []byte) []byte {
     c.deriveCounter(&counter, nonce)
     c.cipher.Encrypt(tagMask[:], counter[:])
 
+    // 先计算 tag, plaintext 和 out 可能完全重叠
+    tag := c.auth(nonce, plaintext, data, &tagMask)
+
     counter[len(counter)-1] |= 1
     ctr := go_cipher.NewCTR(c.cipher, counter[:])
     ctr.XORKeyStream(out, plaintext)
 
-    tag := c.auth(nonce, plaintext, data, &tagMask)
     copy(out[len(plaintext):], tag)
 
     return ret
@@ -82,3 +82,39 @@ func Test_Check(t *testing.T) {
         }
     }
 }
+
+func Test_SealInPlace(t *testing.T) {
+    key := []byte{0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f}
+    nonce := []byte{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b}
+    data := []byte{0x00, 0x01, 0x02, 0x03, 0x04}
+
+    c, err := aes.NewCipher(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    aesCCM, err := NewCCMWithNonceAndTagSize(c, len(nonce), 16)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    plainText := []byte("in place seal with spare capacity")
+    expected := aesCCM.Seal(nil, nonce, plainText, data)
+
+    buf := make([]byte, len(plainText), len(plainText)+aesCCM.Overhead())
+    copy(buf, plainText)
+
+    cipherText := aesCCM.Seal(buf[:0], nonce, buf, data)
+    if !bytes.Equal(expected, cipherText) {
+        t.Errorf("in place cipher text doesn't match")
+    }
+
+    got, err := aesCCM.Open(cipherText[:0], nonce, cipherText, data)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(plainText, got) {
+        t.Errorf("plain text doesn't match")
+    }
+}
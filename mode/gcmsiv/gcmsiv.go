@@ -0,0 +1,199 @@
+// Package gcmsiv implements the GCM-SIV nonce misuse-resistant AEAD
+// defined in RFC 8452 over any 128-bit block cipher.
+package gcmsiv
+
+import (
+    "errors"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+    "github.com/deatil/go-cryptobin/hash/polyval"
+)
+
+const (
+    blockSize = 16
+    nonceSize = 12
+    tagSize   = 16
+
+    // 明文和附加数据最大长度 2^36 字节
+    maxDataSize = 1 << 36
+)
+
+var (
+    errOpen = errors.New("go-cryptobin/gcmsiv: message authentication failed")
+    errKeySize = errors.New("go-cryptobin/gcmsiv: invalid key size")
+    errBlockSize = errors.New("go-cryptobin/gcmsiv: requires 128-bit block cipher")
+)
+
+// NewCipherFunc creates a block cipher with the key.
+type NewCipherFunc = func(key []byte) (cipher.Block, error)
+
+type gcmsiv struct {
+    newCipher NewCipherFunc
+    block     cipher.Block
+    keySize   int
+}
+
+// NewGCMSIV returns the block cipher wrapped in GCM-SIV. The key is the
+// key-generating key, the per nonce keys are created with newCipher.
+// AES-GCM-SIV uses aes.NewCipher with 16 or 32 bytes key.
+func NewGCMSIV(newCipher NewCipherFunc, key []byte) (cipher.AEAD, error) {
+    if len(key) == 0 || len(key) % 8 != 0 {
+        return nil, errKeySize
+    }
+
+    block, err := newCipher(key)
+    if err != nil {
+        return nil, err
+    }
+
+    if block.BlockSize() != blockSize {
+        return nil, errBlockSize
+    }
+
+    g := &gcmsiv{
+        newCipher: newCipher,
+        block:     block,
+        keySize:   len(key),
+    }
+
+    return g, nil
+}
+
+func (g *gcmsiv) NonceSize() int {
+    return nonceSize
+}
+
+func (g *gcmsiv) Overhead() int {
+    return tagSize
+}
+
+func (g *gcmsiv) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+    if len(nonce) != nonceSize {
+        panic("go-cryptobin/gcmsiv: incorrect nonce length given to GCM-SIV")
+    }
+
+    if uint64(len(plaintext)) > maxDataSize || uint64(len(additionalData)) > maxDataSize {
+        panic("go-cryptobin/gcmsiv: message too large for GCM-SIV")
+    }
+
+    authKey, encBlock := g.deriveKeys(nonce)
+
+    var tag [tagSize]byte
+    g.calcTag(tag[:], authKey, encBlock, nonce, plaintext, additionalData)
+
+    ret, out := alias.SliceForAppend(dst, len(plaintext) + tagSize)
+    if alias.InexactOverlap(out, plaintext) {
+        panic("go-cryptobin/gcmsiv: invalid buffer overlap")
+    }
+
+    ctr(encBlock, out[:len(plaintext)], plaintext, tag[:])
+    copy(out[len(plaintext):], tag[:])
+
+    return ret
+}
+
+func (g *gcmsiv) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+    if len(nonce) != nonceSize {
+        panic("go-cryptobin/gcmsiv: incorrect nonce length given to GCM-SIV")
+    }
+
+    if len(ciphertext) < tagSize ||
+        uint64(len(ciphertext)) > maxDataSize + tagSize ||
+        uint64(len(additionalData)) > maxDataSize {
+        return nil, errOpen
+    }
+
+    tag := ciphertext[len(ciphertext)-tagSize:]
+    ciphertext = ciphertext[:len(ciphertext)-tagSize]
+
+    authKey, encBlock := g.deriveKeys(nonce)
+
+    ret, out := alias.SliceForAppend(dst, len(ciphertext))
+    if alias.InexactOverlap(out, ciphertext) {
+        panic("go-cryptobin/gcmsiv: invalid buffer overlap")
+    }
+
+    ctr(encBlock, out, ciphertext, tag)
+
+    var expectedTag [tagSize]byte
+    g.calcTag(expectedTag[:], authKey, encBlock, nonce, out, additionalData)
+
+    if subtle.ConstantTimeCompare(expectedTag[:], tag) != 1 {
+        for i := range out {
+            out[i] = 0
+        }
+
+        return nil, errOpen
+    }
+
+    return ret, nil
+}
+
+// 生成消息认证密钥和加密密钥
+func (g *gcmsiv) deriveKeys(nonce []byte) ([]byte, cipher.Block) {
+    var in, out [blockSize]byte
+    copy(in[4:], nonce)
+
+    keys := make([]byte, 16 + g.keySize)
+    for i := 0; i < len(keys) / 8; i++ {
+        binary.LittleEndian.PutUint32(in[:4], uint32(i))
+        g.block.Encrypt(out[:], in[:])
+
+        copy(keys[i*8:], out[:8])
+    }
+
+    encBlock, err := g.newCipher(keys[16:])
+    if err != nil {
+        panic("go-cryptobin/gcmsiv: " + err.Error())
+    }
+
+    return keys[:16], encBlock
+}
+
+func (g *gcmsiv) calcTag(tag, authKey []byte, encBlock cipher.Block, nonce, plaintext, additionalData []byte) {
+    p, _ := polyval.New(authKey)
+
+    var lengths [blockSize]byte
+    binary.LittleEndian.PutUint64(lengths[0:], uint64(len(additionalData)) * 8)
+    binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext)) * 8)
+
+    writePadded(p, additionalData)
+    writePadded(p, plaintext)
+    p.Write(lengths[:])
+
+    s := p.Sum(nil)
+    subtle.XORBytes(s[:nonceSize], s[:nonceSize], nonce)
+    s[15] &= 0x7f
+
+    encBlock.Encrypt(tag, s)
+}
+
+// 补零到分组长度
+func writePadded(p *polyval.Polyval, data []byte) {
+    p.Write(data)
+
+    if n := len(data) % blockSize; n > 0 {
+        var zeros [blockSize]byte
+        p.Write(zeros[:blockSize-n])
+    }
+}
+
+// 计数器为前 4 字节小端序
+func ctr(block cipher.Block, dst, src, tag []byte) {
+    var counter, ks [blockSize]byte
+    copy(counter[:], tag)
+    counter[15] |= 0x80
+
+    for len(src) > 0 {
+        block.Encrypt(ks[:], counter[:])
+
+        n := subtle.XORBytes(dst, src, ks[:])
+        dst, src = dst[n:], src[n:]
+
+        c := binary.LittleEndian.Uint32(counter[:4])
+        binary.LittleEndian.PutUint32(counter[:4], c + 1)
+    }
+}
@@ -0,0 +1,110 @@
+package gcmsiv
+
+import (
+    "bytes"
+    "testing"
+    "crypto/aes"
+    "crypto/rand"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// RFC 8452 Appendix C
+func Test_AESVectors(t *testing.T) {
+    cases := []struct {
+        key, nonce, plaintext, aad, result string
+    }{
+        {
+            key:    "01000000000000000000000000000000",
+            nonce:  "030000000000000000000000",
+            result: "dc20e2d83f25705bb49e439eca56de25",
+        },
+        {
+            key:       "01000000000000000000000000000000",
+            nonce:     "030000000000000000000000",
+            plaintext: "0100000000000000",
+            result:    "b5d839330ac7b786578782fff6013b815b287c22493a364c",
+        },
+        {
+            key:    "0100000000000000000000000000000000000000000000000000000000000000",
+            nonce:  "030000000000000000000000",
+            result: "07f5f4169bbf55a8400cd47ea6fd400f",
+        },
+    }
+
+    for i, c := range cases {
+        aead, err := NewGCMSIV(aes.NewCipher, fromHex(c.key))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        nonce := fromHex(c.nonce)
+        plaintext := fromHex(c.plaintext)
+        aad := fromHex(c.aad)
+        want := fromHex(c.result)
+
+        got := aead.Seal(nil, nonce, plaintext, aad)
+        if !bytes.Equal(got, want) {
+            t.Errorf("[%d] Seal got %x, want %x", i, got, want)
+        }
+
+        pt, err := aead.Open(nil, nonce, want, aad)
+        if err != nil {
+            t.Fatalf("[%d] Open: %v", i, err)
+        }
+
+        if !bytes.Equal(pt, plaintext) {
+            t.Errorf("[%d] Open got %x, want %x", i, pt, plaintext)
+        }
+    }
+}
+
+func Test_SM4(t *testing.T) {
+    key := make([]byte, 16)
+    nonce := make([]byte, 12)
+    rand.Read(key)
+    rand.Read(nonce)
+
+    aead, err := NewGCMSIV(sm4.NewCipher, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    aad := []byte("additional data")
+
+    for _, n := range []int{0, 1, 15, 16, 17, 100} {
+        plaintext := make([]byte, n)
+        rand.Read(plaintext)
+
+        ct := aead.Seal(nil, nonce, plaintext, aad)
+        if len(ct) != n + aead.Overhead() {
+            t.Fatalf("bad ciphertext length %d", len(ct))
+        }
+
+        pt, err := aead.Open(nil, nonce, ct, aad)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if !bytes.Equal(pt, plaintext) {
+            t.Errorf("got %x, want %x", pt, plaintext)
+        }
+
+        ct[0] ^= 1
+        if _, err := aead.Open(nil, nonce, ct, aad); err != errOpen {
+            t.Error("tampered ciphertext should fail")
+        }
+    }
+}
+
+func Test_KeySize(t *testing.T) {
+    if _, err := NewGCMSIV(aes.NewCipher, make([]byte, 15)); err == nil {
+        t.Error("should fail with bad key size")
+    }
+}
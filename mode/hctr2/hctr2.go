@@ -0,0 +1,164 @@
+// Package hctr2 implements the HCTR2 length-preserving encryption mode
+// over any 128-bit block cipher, as defined in "Length-preserving
+// encryption with HCTR2" (https://eprint.iacr.org/2021/1441).
+package hctr2
+
+import (
+    "errors"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+    "github.com/deatil/go-cryptobin/hash/polyval"
+)
+
+const blockSize = 16
+
+var errBlockSize = errors.New("go-cryptobin/hctr2: requires 128-bit block cipher")
+
+// Cipher is an instance of HCTR2 with a block cipher. The message
+// length is at least 16 bytes and the tweak has any length.
+type Cipher struct {
+    block cipher.Block
+
+    // 哈希密钥和 L
+    hkey [blockSize]byte
+    l    [blockSize]byte
+}
+
+// NewCipher returns HCTR2 with the block cipher.
+func NewCipher(block cipher.Block) (*Cipher, error) {
+    if block.BlockSize() != blockSize {
+        return nil, errBlockSize
+    }
+
+    c := &Cipher{
+        block: block,
+    }
+
+    // h = E(bin(0)), L = E(bin(1))
+    var in [blockSize]byte
+    block.Encrypt(c.hkey[:], in[:])
+
+    in[0] = 1
+    block.Encrypt(c.l[:], in[:])
+
+    return c, nil
+}
+
+// Encrypt encrypts src with the tweak into dst.
+// Dst and src must overlap entirely or not at all.
+func (c *Cipher) Encrypt(dst, src, tweak []byte) {
+    c.checkArgs(dst, src)
+
+    m, n := src[:blockSize], src[blockSize:]
+    u, v := dst[:blockSize], dst[blockSize:]
+
+    var mm, uu, s, h [blockSize]byte
+
+    // MM = M ^ H(T, N)
+    c.hash(h[:], tweak, n)
+    subtle.XORBytes(mm[:], m, h[:])
+
+    // UU = E(MM)
+    c.block.Encrypt(uu[:], mm[:])
+
+    // S = MM ^ UU ^ L
+    subtle.XORBytes(s[:], mm[:], uu[:])
+    subtle.XORBytes(s[:], s[:], c.l[:])
+
+    // V = N ^ XCTR(S)
+    c.xctr(v, n, &s)
+
+    // U = UU ^ H(T, V)
+    c.hash(h[:], tweak, v)
+    subtle.XORBytes(u, uu[:], h[:])
+}
+
+// Decrypt decrypts src with the tweak into dst.
+// Dst and src must overlap entirely or not at all.
+func (c *Cipher) Decrypt(dst, src, tweak []byte) {
+    c.checkArgs(dst, src)
+
+    u, v := src[:blockSize], src[blockSize:]
+    m, n := dst[:blockSize], dst[blockSize:]
+
+    var mm, uu, s, h [blockSize]byte
+
+    // UU = U ^ H(T, V)
+    c.hash(h[:], tweak, v)
+    subtle.XORBytes(uu[:], u, h[:])
+
+    // MM = D(UU)
+    c.block.Decrypt(mm[:], uu[:])
+
+    // S = MM ^ UU ^ L
+    subtle.XORBytes(s[:], mm[:], uu[:])
+    subtle.XORBytes(s[:], s[:], c.l[:])
+
+    // N = V ^ XCTR(S)
+    c.xctr(n, v, &s)
+
+    // M = MM ^ H(T, N)
+    c.hash(h[:], tweak, n)
+    subtle.XORBytes(m, mm[:], h[:])
+}
+
+func (c *Cipher) checkArgs(dst, src []byte) {
+    if len(src) < blockSize {
+        panic("go-cryptobin/hctr2: input is smaller than the block size")
+    }
+
+    if len(dst) < len(src) {
+        panic("go-cryptobin/hctr2: output smaller than input")
+    }
+
+    if alias.InexactOverlap(dst[:len(src)], src) {
+        panic("go-cryptobin/hctr2: invalid buffer overlap")
+    }
+}
+
+// H(T, M) = POLYVAL(h, bin(2|T| + 2 或 3) || pad(T) || pad(M))
+func (c *Cipher) hash(out, tweak, msg []byte) {
+    p, _ := polyval.New(c.hkey[:])
+
+    var first [blockSize]byte
+    lenTag := uint64(len(tweak)) * 8 * 2 + 2
+    if len(msg) % blockSize != 0 {
+        lenTag++
+    }
+    binary.LittleEndian.PutUint64(first[:], lenTag)
+
+    p.Write(first[:])
+
+    var zeros [blockSize]byte
+    p.Write(tweak)
+    if n := len(tweak) % blockSize; n > 0 {
+        p.Write(zeros[:blockSize-n])
+    }
+
+    // 不完整分组补 1 后补零, 由 Sum 补零
+    p.Write(msg)
+    if len(msg) % blockSize != 0 {
+        p.Write([]byte{1})
+    }
+
+    p.Sum(out[:0])
+}
+
+// XCTR, 第 i 个密钥流分组为 E(S ^ bin(i)), i 从 1 开始
+func (c *Cipher) xctr(dst, src []byte, s *[blockSize]byte) {
+    var ctr, ks [blockSize]byte
+
+    s0 := binary.LittleEndian.Uint64(s[:8])
+    copy(ctr[8:], s[8:])
+
+    for i := uint64(1); len(src) > 0; i++ {
+        binary.LittleEndian.PutUint64(ctr[:8], s0 ^ i)
+        c.block.Encrypt(ks[:], ctr[:])
+
+        n := subtle.XORBytes(dst, src, ks[:])
+        dst, src = dst[n:], src[n:]
+    }
+}
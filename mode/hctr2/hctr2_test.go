@@ -0,0 +1,140 @@
+package hctr2
+
+import (
+    "bytes"
+    "testing"
+    "crypto/aes"
+    "crypto/rand"
+    "crypto/cipher"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// AES 和 SM4 的结果均为本实现的回归值,
+// 组件 POLYVAL 和 AES 已分别用 RFC 8452 和标准库验证.
+// TODO: 导入 google/hctr2 test_vectors/ours/HCTR2 或 Linux
+// crypto/testmgr.h aes_hctr2_tv_template 的 AES-128 和 AES-256 向量
+func Test_Vectors(t *testing.T) {
+    key := fromHex("000102030405060708090a0b0c0d0e0f")
+
+    plaintext := make([]byte, 64)
+    for i := range plaintext {
+        plaintext[i] = byte(i)
+    }
+
+    tweak := make([]byte, 32)
+    for i := range tweak {
+        tweak[i] = byte(0xf0 + i)
+    }
+
+    cases := []struct {
+        name     string
+        size     int
+        tweakLen int
+        want     string
+    }{
+        {"aes", 16, 0, "71e3fa3f04b168b129b0f2ee156bdb93"},
+        {"aes", 16, 32, "d74735a62a50a77596dd8cd09424b314"},
+        {"aes", 17, 0, "a8756b409d5fa8c4c265f57303a710ac5f"},
+        {"aes", 17, 32, "b8b3f95e3a2e24817e529810a4f160589a"},
+        {"aes", 32, 0, "7749077b6e549297a7cbcf2af000078b80e7f8191eb4745a2b5d0ef7c0d6ca92"},
+        {"aes", 32, 32, "bcc64009aace693e50b5d59ffa98f272c94918ce17f0c9d4eb6f9fe2d93eb102"},
+        {"aes", 47, 0, "cf70a05894c816aaa1175c359a97f49628d9e3352f1fd34dbdc17fb875f3f771353edf3e4006c304919c97d8825bbf"},
+        {"aes", 47, 32, "b354c4d25b6022d993b9e3933519e3c2c9c4ff855070bc55791e4cca65365c7b50be4534501e308f2dd1a689967232"},
+        {"aes", 64, 0, "1e5778c5aa884ea7da314f27ab7cc6cfc29df0002800f3dd333dd4c6bcec9555da0f7bf439a4f3ab4a2d21eaf0d52c4ee892391c9d1b628b2a2b01ab99a5740b"},
+        {"aes", 64, 32, "13594362b22236f0a96516c6a04d962565494208c7f0efecc6e38154f768b75825be3a60dbd2712d54b05932399508f8f7b77c729b0a0dd3bd1f74d7411af49d"},
+        {"sm4", 16, 0, "63331375582fce4d7bc82f3041ae9438"},
+        {"sm4", 16, 32, "c8e4d32c72f6aedc6fcec7ab6cb7ea9c"},
+        {"sm4", 17, 0, "cb1cb0d1caa70d83d845f8aaae6cd39aba"},
+        {"sm4", 17, 32, "dc8c3126e7fcc47e5089b85f30c3dbe775"},
+        {"sm4", 32, 0, "f28d12b8fe72fee2dde4c6676d7994357eec94730ddfaddf5c54e7f2a1ca5949"},
+        {"sm4", 32, 32, "bfb88b7ddd8b2423c6324a2015b4308b57e6cf272a9c9f85919fa7b10f85ff09"},
+        {"sm4", 47, 0, "fd54717811cf32598902c2d292bc0df199ad9ce222bdb16c38ee0f55723f1f9b6358063202a15efdf633f5d9eb2d05"},
+        {"sm4", 47, 32, "82f79134f4124ce7d23a7906be128fa02cd15c2ea6c7df49430260e347a9983473568a00d5a848b9c706ab6ec18ec0"},
+        {"sm4", 64, 0, "dafc25bd760817cd127e2037d5d3fbbad46fdaf6a8610c818b8382a4a0048beb7a30053e2f29a43ad0fe3356df2992a7da7d88e8e8c240cd965fb73b20887358"},
+        {"sm4", 64, 32, "289f71ae4960273c5e5c1b554e17c973cd27bae1b03a5a2626d38922eb699ad75c71baafcd9dd3741a944630994a2ddc62228071aa685271fb5a84a9d7548c2a"},
+    }
+
+    for i, c := range cases {
+        var block cipher.Block
+        if c.name == "aes" {
+            block, _ = aes.NewCipher(key)
+        } else {
+            block, _ = sm4.NewCipher(key)
+        }
+
+        h, err := NewCipher(block)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        want := fromHex(c.want)
+
+        got := make([]byte, c.size)
+        h.Encrypt(got, plaintext[:c.size], tweak[:c.tweakLen])
+        if !bytes.Equal(got, want) {
+            t.Errorf("[%d] Encrypt got %x, want %x", i, got, want)
+        }
+
+        h.Decrypt(got, got, tweak[:c.tweakLen])
+        if !bytes.Equal(got, plaintext[:c.size]) {
+            t.Errorf("[%d] Decrypt got %x", i, got)
+        }
+    }
+}
+
+func Test_WideBlock(t *testing.T) {
+    key := make([]byte, 16)
+    rand.Read(key)
+
+    block, _ := sm4.NewCipher(key)
+    h, _ := NewCipher(block)
+
+    plaintext := make([]byte, 255)
+    rand.Read(plaintext)
+
+    ct1 := make([]byte, len(plaintext))
+    h.Encrypt(ct1, plaintext, nil)
+
+    // 修改最后一字节, 密文的每个分组都改变
+    plaintext[len(plaintext)-1] ^= 1
+
+    ct2 := make([]byte, len(plaintext))
+    h.Encrypt(ct2, plaintext, nil)
+
+    for i := 0; i < len(ct1); i += 16 {
+        end := i + 16
+        if end > len(ct1) {
+            end = len(ct1)
+        }
+
+        if bytes.Equal(ct1[i:end], ct2[i:end]) {
+            t.Errorf("block %d not changed", i / 16)
+        }
+    }
+
+    got := make([]byte, len(ct2))
+    h.Decrypt(got, ct2, nil)
+    if !bytes.Equal(got, plaintext) {
+        t.Error("Decrypt mismatch")
+    }
+}
+
+func Test_Errors(t *testing.T) {
+    block, _ := aes.NewCipher(make([]byte, 16))
+    h, _ := NewCipher(block)
+
+    defer func() {
+        if recover() == nil {
+            t.Error("short input should panic")
+        }
+    }()
+
+    h.Encrypt(make([]byte, 15), make([]byte, 15), nil)
+}
@@ -0,0 +1,186 @@
+package mode
+
+import (
+    "errors"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+)
+
+// SP 800-38F section 6.4 ICV3
+var defaultTKWIv = []byte{
+    0xA6, 0xA6, 0xA6, 0xA6,
+}
+
+// TKW 半分组为 32 位
+const tkwSemiblock = 4
+
+// Plaintext limit of SP 800-38F TKW: 2^28 - 1 semiblocks.
+const TKW_MAX = uint64(1) << 30
+
+type tkw struct {
+    b         cipher.Block
+    blockSize int
+    iv        []byte
+}
+
+func newTKW(b cipher.Block, iv []byte) *tkw {
+    if b.BlockSize() != 8 {
+        panic("go-cryptobin/tkw: TKW requires a 64-bit block cipher")
+    }
+
+    c := &tkw{
+        b:         b,
+        blockSize: b.BlockSize(),
+        iv:        make([]byte, tkwSemiblock),
+    }
+
+    if iv == nil {
+        iv = defaultTKWIv
+    }
+
+    if len(iv) != tkwSemiblock {
+        panic("go-cryptobin/tkw: IV length must be 4 bytes")
+    }
+
+    copy(c.iv, iv)
+
+    return c
+}
+
+type tkwEncrypter tkw
+
+// NewTKWEncrypter returns the TDEA key wrap (TKW) of NIST SP 800-38F.
+// The plaintext is a multiple of 4 bytes and at least 8 bytes,
+// dst needs len(src) + 4 bytes. A nil iv uses the default ICV3.
+func NewTKWEncrypter(b cipher.Block, iv []byte) cipher.BlockMode {
+    return (*tkwEncrypter)(newTKW(b, iv))
+}
+
+func (x *tkwEncrypter) BlockSize() int {
+    return tkwSemiblock
+}
+
+// TW: C = W(ICV3 || P)
+func (x *tkwEncrypter) CryptBlocks(dst, src []byte) {
+    if len(src) % tkwSemiblock != 0 {
+        panic("go-cryptobin/tkw: input not full semiblocks")
+    }
+
+    if len(src) < 2*tkwSemiblock || uint64(len(src)) > TKW_MAX {
+        panic("go-cryptobin/tkw: invalid src")
+    }
+
+    if len(dst) < len(src) + tkwSemiblock {
+        panic("go-cryptobin/tkw: output smaller than input")
+    }
+
+    n := len(src) / tkwSemiblock
+
+    var A [tkwSemiblock]byte
+    var B [8]byte
+
+    copy(A[:], x.iv)
+    R := make([]byte, len(src))
+    copy(R, src)
+
+    t := uint32(1)
+    for j := 0; j < 6; j++ {
+        for i := 0; i < n; i, t = i+1, t+1 {
+            r := R[i*tkwSemiblock:(i+1)*tkwSemiblock]
+
+            copy(B[:4], A[:])
+            copy(B[4:], r)
+            x.b.Encrypt(B[:], B[:])
+
+            binary.BigEndian.PutUint32(A[:], binary.BigEndian.Uint32(B[:4]) ^ t)
+            copy(r, B[4:])
+        }
+    }
+
+    copy(dst, A[:])
+    copy(dst[tkwSemiblock:], R)
+}
+
+func (x *tkwEncrypter) SetIV(iv []byte) {
+    if len(iv) != len(x.iv) {
+        panic("go-cryptobin/tkw: incorrect length IV")
+    }
+
+    copy(x.iv, iv)
+}
+
+type tkwDecrypter tkw
+
+// NewTKWDecrypter returns the TDEA key unwrap (TKW) of NIST SP 800-38F.
+// dst needs len(src) - 4 bytes, it is zeroed when the ICV check fails.
+func NewTKWDecrypter(b cipher.Block, iv []byte) cipher.BlockMode {
+    return (*tkwDecrypter)(newTKW(b, iv))
+}
+
+func (x *tkwDecrypter) BlockSize() int {
+    return tkwSemiblock
+}
+
+// TW^-1: ICV3 || P = W^-1(C)
+func (x *tkwDecrypter) CryptBlocks(dst, src []byte) {
+    if len(src) % tkwSemiblock != 0 {
+        panic("go-cryptobin/tkw: input not full semiblocks")
+    }
+
+    if len(dst) < len(src) - tkwSemiblock {
+        panic("go-cryptobin/tkw: output smaller than input")
+    }
+
+    if len(src) == 0 {
+        return
+    }
+
+    out := dst[:len(src) - tkwSemiblock]
+
+    A, err := x.cryptBlocks(out, src)
+    if err != nil || subtle.ConstantTimeCompare(A, x.iv) != 1 {
+        for i := range out {
+            out[i] = 0
+        }
+    }
+}
+
+func (x *tkwDecrypter) cryptBlocks(out, in []byte) ([]byte, error) {
+    inlen := len(in) - tkwSemiblock
+    if inlen < 2*tkwSemiblock || uint64(inlen) > TKW_MAX {
+        return nil, errors.New("invalid src")
+    }
+
+    n := inlen / tkwSemiblock
+
+    var A [tkwSemiblock]byte
+    var B [8]byte
+
+    copy(A[:], in[:tkwSemiblock])
+    copy(out, in[tkwSemiblock:])
+
+    t := uint32(6 * n)
+    for j := 0; j < 6; j++ {
+        for i := n - 1; i >= 0; i, t = i-1, t-1 {
+            r := out[i*tkwSemiblock:(i+1)*tkwSemiblock]
+
+            binary.BigEndian.PutUint32(B[:4], binary.BigEndian.Uint32(A[:]) ^ t)
+            copy(B[4:], r)
+            x.b.Decrypt(B[:], B[:])
+
+            copy(A[:], B[:4])
+            copy(r, B[4:])
+        }
+    }
+
+    return A[:], nil
+}
+
+func (x *tkwDecrypter) SetIV(iv []byte) {
+    if len(iv) != len(x.iv) {
+        panic("go-cryptobin/tkw: incorrect length IV")
+    }
+
+    copy(x.iv, iv)
+}
@@ -0,0 +1,105 @@
+package mode
+
+import (
+    "bytes"
+    "testing"
+    "crypto/des"
+    "crypto/cipher"
+    "encoding/binary"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
+)
+
+// SP 800-38F 算法 1 的移位寄存器写法.
+// TODO: 导入 CAVP KWVS 的 TKW_AE.txt 和 TKW_AD.txt 向量
+func tkwReferenceW(b cipher.Block, S []byte) []byte {
+    n := len(S) / 4
+    s := 6 * (n - 1)
+
+    A := append([]byte{}, S[:4]...)
+    R := make([][]byte, n)
+    for i := 1; i < n; i++ {
+        R[i] = append([]byte{}, S[i*4:(i+1)*4]...)
+    }
+
+    for t := 1; t <= s; t++ {
+        var B [8]byte
+        copy(B[:4], A)
+        copy(B[4:], R[1])
+        b.Encrypt(B[:], B[:])
+
+        A = make([]byte, 4)
+        binary.BigEndian.PutUint32(A, binary.BigEndian.Uint32(B[:4]) ^ uint32(t))
+
+        for i := 1; i < n-1; i++ {
+            R[i] = R[i+1]
+        }
+        R[n-1] = append([]byte{}, B[4:]...)
+    }
+
+    C := append([]byte{}, A...)
+    for i := 1; i < n; i++ {
+        C = append(C, R[i]...)
+    }
+
+    return C
+}
+
+func Test_TKW(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    key := []byte("kkinjkijeel22plo12345678")
+
+    c, err := des.NewTripleDESCipher(key)
+    assertNoError(err, "Test_TKW")
+
+    for _, n := range []int{8, 12, 16, 24, 36, 100} {
+        plaintext := make([]byte, n)
+        for i := range plaintext {
+            plaintext[i] = byte(i * 3)
+        }
+
+        ciphertext := make([]byte, n+4)
+        NewTKWEncrypter(c, nil).CryptBlocks(ciphertext, plaintext)
+
+        want := tkwReferenceW(c, append([]byte{0xA6, 0xA6, 0xA6, 0xA6}, plaintext...))
+        assertEqual(ciphertext, want, "Test_TKW-W")
+
+        plaintext2 := make([]byte, n)
+        NewTKWDecrypter(c, nil).CryptBlocks(plaintext2, ciphertext)
+
+        assertEqual(plaintext2, plaintext, "Test_TKW-Equal")
+
+        // 密文被修改时输出为零
+        ciphertext[n] ^= 1
+        NewTKWDecrypter(c, nil).CryptBlocks(plaintext2, ciphertext)
+
+        if !bytes.Equal(plaintext2, make([]byte, n)) {
+            t.Errorf("Test_TKW: tampered ciphertext should be rejected, got %x", plaintext2)
+        }
+    }
+}
+
+func Test_TKWWithIV(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    key := []byte("kkinjkijeel22plo12345678")
+    iv := []byte("1234")
+    plaintext := []byte("kjinjkijkolkdplo")
+
+    c, err := des.NewTripleDESCipher(key)
+    assertNoError(err, "Test_TKWWithIV")
+
+    ciphertext := make([]byte, len(plaintext)+4)
+    NewTKWEncrypter(c, iv).CryptBlocks(ciphertext, plaintext)
+
+    plaintext2 := make([]byte, len(plaintext))
+    NewTKWDecrypter(c, iv).CryptBlocks(plaintext2, ciphertext)
+
+    assertEqual(plaintext2, plaintext, "Test_TKWWithIV-Equal")
+
+    NewTKWDecrypter(c, nil).CryptBlocks(plaintext2, ciphertext)
+    assertEqual(plaintext2, make([]byte, len(plaintext)), "Test_TKWWithIV-WrongIV")
+}
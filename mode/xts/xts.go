@@ -0,0 +1,278 @@
+// Package xts implements the XTS cipher mode as specified in IEEE P1619/D16
+// and the SM4 variant of GB/T 17964-2021, over any 128-bit block cipher.
+//
+// Data which is not a multiple of the block size is handled with
+// ciphertext stealing, so any length from one block up is accepted.
+package xts
+
+import (
+    "errors"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/tool/alias"
+)
+
+const blockSize = 16
+
+var (
+    errKeySize = errors.New("go-cryptobin/xts: invalid key size")
+    errBlockSize = errors.New("go-cryptobin/xts: cipher does not have a block size of 16")
+)
+
+// NewCipherFunc creates a block cipher with the key.
+type NewCipherFunc = func(key []byte) (cipher.Block, error)
+
+// Cipher contains an expanded key structure. It is safe for concurrent use if
+// the underlying block cipher is safe for concurrent use.
+type Cipher struct {
+    k1, k2 cipher.Block
+
+    // GB/T 17964-2021 tweak
+    gb bool
+}
+
+// NewCipher creates a Cipher given a function for creating the underlying
+// block cipher (which must have a block size of 16 bytes). The key must be
+// twice the length of the underlying cipher's key.
+func NewCipher(cipherFunc NewCipherFunc, key []byte) (*Cipher, error) {
+    return newCipher(cipherFunc, key, false)
+}
+
+// NewGBCipher creates a Cipher with the tweak multiplication of
+// GB/T 17964-2021. It is used with SM4.
+func NewGBCipher(cipherFunc NewCipherFunc, key []byte) (*Cipher, error) {
+    return newCipher(cipherFunc, key, true)
+}
+
+func newCipher(cipherFunc NewCipherFunc, key []byte, gb bool) (*Cipher, error) {
+    if len(key) == 0 || len(key) % 2 != 0 {
+        return nil, errKeySize
+    }
+
+    k1, err := cipherFunc(key[:len(key)/2])
+    if err != nil {
+        return nil, err
+    }
+
+    k2, err := cipherFunc(key[len(key)/2:])
+    if err != nil {
+        return nil, err
+    }
+
+    if k1.BlockSize() != blockSize {
+        return nil, errBlockSize
+    }
+
+    c := &Cipher{
+        k1: k1,
+        k2: k2,
+        gb: gb,
+    }
+
+    return c, nil
+}
+
+// Encrypt encrypts a sector of plaintext and puts the result into ciphertext.
+// Plaintext and ciphertext must overlap entirely or not at all.
+// Sectors must be at least 16 bytes. The sector number is encoded
+// as a little-endian tweak.
+func (c *Cipher) Encrypt(ciphertext, plaintext []byte, sectorNum uint64) {
+    var tweak [blockSize]byte
+    binary.LittleEndian.PutUint64(tweak[:8], sectorNum)
+
+    c.EncryptWithTweak(ciphertext, plaintext, tweak[:])
+}
+
+// Decrypt decrypts a sector of ciphertext and puts the result into plaintext.
+// Plaintext and ciphertext must overlap entirely or not at all.
+// Sectors must be at least 16 bytes.
+func (c *Cipher) Decrypt(plaintext, ciphertext []byte, sectorNum uint64) {
+    var tweak [blockSize]byte
+    binary.LittleEndian.PutUint64(tweak[:8], sectorNum)
+
+    c.DecryptWithTweak(plaintext, ciphertext, tweak[:])
+}
+
+// EncryptWithTweak encrypts plaintext with the 16 bytes tweak.
+func (c *Cipher) EncryptWithTweak(ciphertext, plaintext, tweak []byte) {
+    c.checkArgs(ciphertext, plaintext, tweak)
+
+    var t [blockSize]byte
+    c.k2.Encrypt(t[:], tweak)
+
+    full := len(plaintext) / blockSize * blockSize
+    tail := len(plaintext) - full
+    if tail > 0 {
+        // 最后一个完整分组参与密文窃取
+        full -= blockSize
+    }
+
+    for i := 0; i < full; i += blockSize {
+        c.encryptBlock(ciphertext[i:], plaintext[i:], &t)
+        c.mul2(&t)
+    }
+
+    if tail > 0 {
+        var cc [blockSize]byte
+
+        // CC = E(P_{m-1}), C_m = 前 tail 字节, C_{m-1} = E(P_m || CC 尾部)
+        c.encryptBlock(cc[:], plaintext[full:], &t)
+        c.mul2(&t)
+
+        var pp [blockSize]byte
+        copy(pp[:], plaintext[full+blockSize:])
+        copy(pp[tail:], cc[tail:])
+
+        copy(ciphertext[full+blockSize:], cc[:tail])
+        c.encryptBlock(ciphertext[full:], pp[:], &t)
+    }
+}
+
+// DecryptWithTweak decrypts ciphertext with the 16 bytes tweak.
+func (c *Cipher) DecryptWithTweak(plaintext, ciphertext, tweak []byte) {
+    c.checkArgs(plaintext, ciphertext, tweak)
+
+    var t [blockSize]byte
+    c.k2.Encrypt(t[:], tweak)
+
+    full := len(ciphertext) / blockSize * blockSize
+    tail := len(ciphertext) - full
+    if tail > 0 {
+        full -= blockSize
+    }
+
+    for i := 0; i < full; i += blockSize {
+        c.decryptBlock(plaintext[i:], ciphertext[i:], &t)
+        c.mul2(&t)
+    }
+
+    if tail > 0 {
+        // 倒数第二个分组用下一个 tweak 解密
+        t2 := t
+        c.mul2(&t2)
+
+        var pp [blockSize]byte
+        c.decryptBlock(pp[:], ciphertext[full:], &t2)
+
+        var cc [blockSize]byte
+        copy(cc[:], ciphertext[full+blockSize:])
+        copy(cc[tail:], pp[tail:])
+
+        copy(plaintext[full+blockSize:], pp[:tail])
+        c.decryptBlock(plaintext[full:], cc[:], &t)
+    }
+}
+
+// EncryptSectors encrypts data made of consecutive sectors of sectorSize
+// bytes, starting from sector firstSector. The last sector may be shorter
+// but not less than 16 bytes.
+func (c *Cipher) EncryptSectors(ciphertext, plaintext []byte, sectorSize int, firstSector uint64) {
+    c.checkSectors(ciphertext, plaintext, sectorSize)
+
+    sectorNum := firstSector
+    for i := 0; i < len(plaintext); i += sectorSize {
+        end := i + sectorSize
+        if end > len(plaintext) {
+            end = len(plaintext)
+        }
+
+        c.Encrypt(ciphertext[i:end], plaintext[i:end], sectorNum)
+        sectorNum++
+    }
+}
+
+// DecryptSectors decrypts data made of consecutive sectors of sectorSize
+// bytes, starting from sector firstSector.
+func (c *Cipher) DecryptSectors(plaintext, ciphertext []byte, sectorSize int, firstSector uint64) {
+    c.checkSectors(plaintext, ciphertext, sectorSize)
+
+    sectorNum := firstSector
+    for i := 0; i < len(ciphertext); i += sectorSize {
+        end := i + sectorSize
+        if end > len(ciphertext) {
+            end = len(ciphertext)
+        }
+
+        c.Decrypt(plaintext[i:end], ciphertext[i:end], sectorNum)
+        sectorNum++
+    }
+}
+
+func (c *Cipher) checkArgs(dst, src, tweak []byte) {
+    if len(tweak) != blockSize {
+        panic("go-cryptobin/xts: invalid tweak length")
+    }
+
+    if len(src) < blockSize {
+        panic("go-cryptobin/xts: input is smaller than the block size")
+    }
+
+    if len(dst) < len(src) {
+        panic("go-cryptobin/xts: output smaller than input")
+    }
+
+    if alias.InexactOverlap(dst[:len(src)], src) {
+        panic("go-cryptobin/xts: invalid buffer overlap")
+    }
+}
+
+func (c *Cipher) checkSectors(dst, src []byte, sectorSize int) {
+    if sectorSize < blockSize {
+        panic("go-cryptobin/xts: sector size is smaller than the block size")
+    }
+
+    if len(src) % sectorSize != 0 && len(src) % sectorSize < blockSize {
+        panic("go-cryptobin/xts: last sector is smaller than the block size")
+    }
+
+    if len(dst) < len(src) {
+        panic("go-cryptobin/xts: output smaller than input")
+    }
+}
+
+func (c *Cipher) encryptBlock(dst, src []byte, t *[blockSize]byte) {
+    subtle.XORBytes(dst[:blockSize], src[:blockSize], t[:])
+    c.k1.Encrypt(dst, dst)
+    subtle.XORBytes(dst[:blockSize], dst[:blockSize], t[:])
+}
+
+func (c *Cipher) decryptBlock(dst, src []byte, t *[blockSize]byte) {
+    subtle.XORBytes(dst[:blockSize], src[:blockSize], t[:])
+    c.k1.Decrypt(dst, dst)
+    subtle.XORBytes(dst[:blockSize], dst[:blockSize], t[:])
+}
+
+func (c *Cipher) mul2(t *[blockSize]byte) {
+    if c.gb {
+        mul2GB(t)
+    } else {
+        mul2(t)
+    }
+}
+
+// tweak 为小端序 128 位整数, 乘以 x
+func mul2(t *[blockSize]byte) {
+    var carryIn byte
+    for j := range t {
+        carryOut := t[j] >> 7
+        t[j] = (t[j] << 1) | carryIn
+        carryIn = carryOut
+    }
+
+    // 常量时间约减, x^128 + x^7 + x^2 + x + 1
+    t[0] ^= 0x87 & (0 - carryIn)
+}
+
+// GB/T 17964-2021, 系数按大端序排列且每字节内位序反转
+func mul2GB(t *[blockSize]byte) {
+    var carryIn byte
+    for j := range t {
+        carryOut := t[j] << 7
+        t[j] = (t[j] >> 1) | carryIn
+        carryIn = carryOut
+    }
+
+    t[0] ^= 0xe1 & (0 - (carryIn >> 7))
+}
@@ -0,0 +1,145 @@
+package xts
+
+import (
+    "bytes"
+    "testing"
+    "crypto/aes"
+    "crypto/rand"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// IEEE P1619/D16 Vector 1
+func Test_AESVector(t *testing.T) {
+    c, err := NewCipher(aes.NewCipher, make([]byte, 32))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := fromHex("917cf69ebd68b2ec9b9fe9a3eadda692cd43d2f59598ed858c02c2652fbf922e")
+
+    got := make([]byte, 32)
+    c.Encrypt(got, make([]byte, 32), 0)
+    if !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+
+    c.Decrypt(got, got, 0)
+    if !bytes.Equal(got, make([]byte, 32)) {
+        t.Errorf("Decrypt got %x", got)
+    }
+}
+
+func Test_SM4Vectors(t *testing.T) {
+    key := fromHex("2b7e151628aed2a6abf7158809cf4f3c000102030405060708090a0b0c0d0e0f")
+    tweak := fromHex("f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+    plaintext := fromHex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+
+    cases := []struct {
+        gb   bool
+        size int
+        want string
+    }{
+        {false, 64, "e9538251c71d7b80bbe4483fef497bd1b3db1a3e60408c575d63ff7db39f832627d16c0db6d2cfc741314642ed88079d5036738c357cd24d0781c9f477f2d316"},
+        {true, 64, "e9538251c71d7b80bbe4483fef497bd12c5c581bd6242fc51e08964fb4f60fdb903be7f93a1b3479d04feccfb820302cdd775df32ae47354cf4e925252d50796"},
+        {false, 49, "e9538251c71d7b80bbe4483fef497bd1b3db1a3e60408c575d63ff7db39f8326831d31e6e644d132628e4a40c135b47427"},
+        {true, 49, "e9538251c71d7b80bbe4483fef497bd12c5c581bd6242fc51e08964fb4f60fdb9a2b5b0991aae83038f074084d4f6fd090"},
+    }
+
+    for i, cs := range cases {
+        var c *Cipher
+        var err error
+        if cs.gb {
+            c, err = NewGBCipher(sm4.NewCipher, key)
+        } else {
+            c, err = NewCipher(sm4.NewCipher, key)
+        }
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        want := fromHex(cs.want)
+
+        got := make([]byte, cs.size)
+        c.EncryptWithTweak(got, plaintext[:cs.size], tweak)
+        if !bytes.Equal(got, want) {
+            t.Errorf("[%d] got %x, want %x", i, got, want)
+        }
+
+        c.DecryptWithTweak(got, got, tweak)
+        if !bytes.Equal(got, plaintext[:cs.size]) {
+            t.Errorf("[%d] Decrypt got %x", i, got)
+        }
+    }
+}
+
+func Test_Sectors(t *testing.T) {
+    key := make([]byte, 32)
+    rand.Read(key)
+
+    c, _ := NewCipher(aes.NewCipher, key)
+
+    // 最后一个扇区不完整
+    plaintext := make([]byte, 512*3 + 100)
+    rand.Read(plaintext)
+
+    ciphertext := make([]byte, len(plaintext))
+    c.EncryptSectors(ciphertext, plaintext, 512, 7)
+
+    sector := make([]byte, 512)
+    c.Encrypt(sector, plaintext[512:1024], 8)
+    if !bytes.Equal(sector, ciphertext[512:1024]) {
+        t.Error("sector 8 mismatch")
+    }
+
+    got := make([]byte, len(ciphertext))
+    c.DecryptSectors(got, ciphertext, 512, 7)
+    if !bytes.Equal(got, plaintext) {
+        t.Error("DecryptSectors mismatch")
+    }
+}
+
+func Test_CiphertextStealing(t *testing.T) {
+    key := make([]byte, 32)
+    rand.Read(key)
+
+    for _, gb := range []bool{false, true} {
+        c, _ := newCipher(sm4.NewCipher, key, gb)
+
+        for n := 16; n < 80; n++ {
+            plaintext := make([]byte, n)
+            rand.Read(plaintext)
+
+            ciphertext := make([]byte, n)
+            c.Encrypt(ciphertext, plaintext, uint64(n))
+
+            got := make([]byte, n)
+            c.Decrypt(got, ciphertext, uint64(n))
+            if !bytes.Equal(got, plaintext) {
+                t.Errorf("gb=%v n=%d: got %x, want %x", gb, n, got, plaintext)
+            }
+        }
+    }
+}
+
+func Test_Errors(t *testing.T) {
+    if _, err := NewCipher(aes.NewCipher, make([]byte, 31)); err == nil {
+        t.Error("should fail with odd key size")
+    }
+
+    c, _ := NewCipher(aes.NewCipher, make([]byte, 32))
+
+    defer func() {
+        if recover() == nil {
+            t.Error("short input should panic")
+        }
+    }()
+
+    c.Encrypt(make([]byte, 15), make([]byte, 15), 0)
+}
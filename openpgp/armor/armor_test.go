@@ -0,0 +1,67 @@
+package armor
+
+import (
+    "io"
+    "bytes"
+    "strings"
+    "testing"
+)
+
+func Test_EncodeDecode(t *testing.T) {
+    data := bytes.Repeat([]byte("go-cryptobin openpgp armor "), 20)
+
+    for _, checksum := range []bool{true, false} {
+        var buf bytes.Buffer
+        w, err := EncodeWithChecksumOption(&buf, "PGP MESSAGE", map[string]string{"Comment": "test", "Version": "1"}, checksum)
+        if err != nil {
+            t.Fatal(err)
+        }
+        w.Write(data)
+        if err = w.Close(); err != nil {
+            t.Fatal(err)
+        }
+
+        armored := buf.String()
+        if hasChecksum := strings.Contains(armored, "\n="); hasChecksum != checksum {
+            t.Errorf("checksum %v: got checksum line %v", checksum, hasChecksum)
+        }
+        if strings.Index(armored, "Comment:") > strings.Index(armored, "Version:") {
+            t.Error("headers are not sorted")
+        }
+
+        block, err := Decode(&buf)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if block.Type != "PGP MESSAGE" || block.Header["Comment"] != "test" {
+            t.Errorf("unexpected block: %+v", block)
+        }
+
+        got, err := io.ReadAll(block.Body)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !bytes.Equal(got, data) {
+            t.Error("decoded data mismatch")
+        }
+    }
+}
+
+func Test_DecodeBadChecksum(t *testing.T) {
+    var buf bytes.Buffer
+    w, _ := Encode(&buf, "PGP SIGNATURE", nil)
+    w.Write([]byte("signature"))
+    w.Close()
+
+    armored := buf.String()
+    i := strings.Index(armored, "\n=")
+    bad := armored[:i+2] + "AAAA" + armored[i+6:]
+
+    block, err := Decode(strings.NewReader(bad))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err = io.ReadAll(block.Body); err == nil {
+        t.Error("bad checksum was accepted")
+    }
+}
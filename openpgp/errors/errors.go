@@ -0,0 +1,76 @@
+// Package errors contains common error types for the OpenPGP packages.
+package errors
+
+import (
+    "strconv"
+)
+
+// A StructuralError is returned when OpenPGP data is found to be syntactically
+// invalid.
+type StructuralError string
+
+func (s StructuralError) Error() string {
+    return "go-cryptobin/openpgp: invalid data: " + string(s)
+}
+
+// UnsupportedError indicates that, although the OpenPGP data is valid, it
+// makes use of currently unimplemented features.
+type UnsupportedError string
+
+func (s UnsupportedError) Error() string {
+    return "go-cryptobin/openpgp: unsupported feature: " + string(s)
+}
+
+// InvalidArgumentError indicates that the caller is in error and passed an
+// incorrect value.
+type InvalidArgumentError string
+
+func (i InvalidArgumentError) Error() string {
+    return "go-cryptobin/openpgp: invalid argument: " + string(i)
+}
+
+// SignatureError indicates that a syntactically valid signature failed to
+// validate.
+type SignatureError string
+
+func (b SignatureError) Error() string {
+    return "go-cryptobin/openpgp: invalid signature: " + string(b)
+}
+
+type keyIncorrectError int
+
+func (ki keyIncorrectError) Error() string {
+    return "go-cryptobin/openpgp: incorrect key"
+}
+
+var ErrKeyIncorrect error = keyIncorrectError(0)
+
+type unknownIssuerError int
+
+func (unknownIssuerError) Error() string {
+    return "go-cryptobin/openpgp: signature made by unknown entity"
+}
+
+var ErrUnknownIssuer error = unknownIssuerError(0)
+
+type keyRevokedError int
+
+func (keyRevokedError) Error() string {
+    return "go-cryptobin/openpgp: signature made by revoked key"
+}
+
+var ErrKeyRevoked error = keyRevokedError(0)
+
+type UnknownPacketTypeError uint8
+
+func (upte UnknownPacketTypeError) Error() string {
+    return "go-cryptobin/openpgp: unknown packet type: " + strconv.Itoa(int(upte))
+}
+
+// ErrDummyPrivateKey results when operations are attempted on a private key
+// that is just a GNU dummy key, which carries no secret material.
+type ErrDummyPrivateKey string
+
+func (dke ErrDummyPrivateKey) Error() string {
+    return "go-cryptobin/openpgp: s2k GNU dummy key: " + string(dke)
+}
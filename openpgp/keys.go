@@ -0,0 +1,876 @@
+package openpgp
+
+import (
+    "io"
+    "time"
+    "crypto"
+    "strconv"
+    "crypto/rsa"
+    "crypto/ecdh"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "crypto/elliptic"
+
+    "github.com/deatil/go-cryptobin/pubkey/x448"
+    "github.com/deatil/go-cryptobin/pubkey/ed448"
+    "github.com/deatil/go-cryptobin/openpgp/armor"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+    "github.com/deatil/go-cryptobin/openpgp/packet"
+)
+
+// PublicKeyType is the armor type for a PGP public key.
+var PublicKeyType = "PGP PUBLIC KEY BLOCK"
+
+// PrivateKeyType is the armor type for a PGP private key.
+var PrivateKeyType = "PGP PRIVATE KEY BLOCK"
+
+// An Entity represents the components of an OpenPGP key: a primary public key
+// (which must be a signing key), one or more identities claimed by that key,
+// and zero or more subkeys, which may be encryption keys.
+type Entity struct {
+    PrimaryKey  *packet.PublicKey
+    PrivateKey  *packet.PrivateKey
+    Identities  map[string]*Identity // indexed by Identity.Name
+    Revocations []*packet.Signature
+    Subkeys     []Subkey
+    // DirectSignature is the direct-key self-signature of the primary key,
+    // which carries key flags and preferences of v6 keys.
+    DirectSignature *packet.Signature
+}
+
+// An Identity represents an identity claimed by an Entity and zero or more
+// assertions by other entities about that claim.
+type Identity struct {
+    Name          string // by convention, has the form "Full Name (comment) <email@example.com>"
+    UserId        *packet.UserId
+    SelfSignature *packet.Signature
+    Signatures    []*packet.Signature
+}
+
+// A Subkey is an additional public key in an Entity. Subkeys can be used for
+// encryption.
+type Subkey struct {
+    PublicKey  *packet.PublicKey
+    PrivateKey *packet.PrivateKey
+    Sig        *packet.Signature
+}
+
+// A Key identifies a specific public key in an Entity. This is either the
+// Entity's primary key or a subkey.
+type Key struct {
+    Entity        *Entity
+    PublicKey     *packet.PublicKey
+    PrivateKey    *packet.PrivateKey
+    SelfSignature *packet.Signature
+}
+
+// A KeyRing provides access to public and private keys.
+type KeyRing interface {
+    // KeysById returns the set of keys that have the given key id.
+    KeysById(id uint64) []Key
+    // KeysByIdUsage returns the set of keys with the given id
+    // that also meet the key usage given by requiredUsage.
+    // The requiredUsage is expressed as the bitwise-OR of
+    // packet.KeyFlag* values.
+    KeysByIdUsage(id uint64, requiredUsage byte) []Key
+    // DecryptionKeys returns all private keys that are valid for
+    // decryption.
+    DecryptionKeys() []Key
+}
+
+// primaryIdentity returns the Identity marked as primary or the first identity
+// if none are so marked.
+func (e *Entity) primaryIdentity() *Identity {
+    var firstIdentity *Identity
+    for _, ident := range e.Identities {
+        if firstIdentity == nil {
+            firstIdentity = ident
+        }
+        if ident.SelfSignature.IsPrimaryId != nil && *ident.SelfSignature.IsPrimaryId {
+            return ident
+        }
+    }
+    return firstIdentity
+}
+
+// primarySelfSignature returns the self-signature that carries the key flags
+// and preferences of the primary key. The direct-key signature is used if
+// the primary identity has no key flags.
+func (e *Entity) primarySelfSignature() *packet.Signature {
+    i := e.primaryIdentity()
+    if e.DirectSignature != nil && (i == nil || !i.SelfSignature.FlagsValid) {
+        return e.DirectSignature
+    }
+    if i == nil {
+        return nil
+    }
+    return i.SelfSignature
+}
+
+// encryptionKey returns the best candidate Key for encrypting a message to the
+// given Entity.
+func (e *Entity) encryptionKey(now time.Time) (Key, bool) {
+    candidateSubkey := -1
+
+    // Iterate the keys to find the newest key
+    var maxTime time.Time
+    for i, subkey := range e.Subkeys {
+        if subkey.Sig.FlagsValid &&
+            subkey.Sig.FlagEncryptCommunications &&
+            subkey.PublicKey.PubKeyAlgo.CanEncrypt() &&
+            !subkey.Sig.KeyExpired(now) &&
+            (maxTime.IsZero() || subkey.Sig.CreationTime.After(maxTime)) {
+            candidateSubkey = i
+            maxTime = subkey.Sig.CreationTime
+        }
+    }
+
+    if candidateSubkey != -1 {
+        subkey := e.Subkeys[candidateSubkey]
+        return Key{e, subkey.PublicKey, subkey.PrivateKey, subkey.Sig}, true
+    }
+
+    // If we don't have any candidate subkeys for encryption and
+    // the primary key doesn't have any usage metadata then we
+    // assume that the primary key is ok. Or, if the primary key is
+    // marked as ok to encrypt to, then we can obviously use it.
+    selfSig := e.primarySelfSignature()
+    if !selfSig.FlagsValid || selfSig.FlagEncryptCommunications &&
+        e.PrimaryKey.PubKeyAlgo.CanEncrypt() &&
+        !selfSig.KeyExpired(now) {
+        return Key{e, e.PrimaryKey, e.PrivateKey, selfSig}, true
+    }
+
+    // This Entity appears to be signing only.
+    return Key{}, false
+}
+
+// signingKey return the best candidate Key for signing a message with this
+// Entity.
+func (e *Entity) signingKey(now time.Time) (Key, bool) {
+    candidateSubkey := -1
+
+    for i, subkey := range e.Subkeys {
+        if subkey.Sig.FlagsValid &&
+            subkey.Sig.FlagSign &&
+            subkey.PublicKey.PubKeyAlgo.CanSign() &&
+            !subkey.Sig.KeyExpired(now) {
+            candidateSubkey = i
+            break
+        }
+    }
+
+    if candidateSubkey != -1 {
+        subkey := e.Subkeys[candidateSubkey]
+        return Key{e, subkey.PublicKey, subkey.PrivateKey, subkey.Sig}, true
+    }
+
+    // If we have no candidate subkey then we assume that it's ok to sign
+    // with the primary key.
+    selfSig := e.primarySelfSignature()
+    if !selfSig.FlagsValid || selfSig.FlagSign &&
+        !selfSig.KeyExpired(now) {
+        return Key{e, e.PrimaryKey, e.PrivateKey, selfSig}, true
+    }
+
+    return Key{}, false
+}
+
+// An EntityList contains one or more Entities.
+type EntityList []*Entity
+
+// KeysById returns the set of keys that have the given key id.
+func (el EntityList) KeysById(id uint64) (keys []Key) {
+    for _, e := range el {
+        if e.PrimaryKey.KeyId == id {
+            keys = append(keys, Key{e, e.PrimaryKey, e.PrivateKey, e.primarySelfSignature()})
+        }
+
+        for _, subKey := range e.Subkeys {
+            if subKey.PublicKey.KeyId == id {
+                keys = append(keys, Key{e, subKey.PublicKey, subKey.PrivateKey, subKey.Sig})
+            }
+        }
+    }
+    return
+}
+
+// KeysByIdUsage returns the set of keys with the given id that also meet
+// the key usage given by requiredUsage.  The requiredUsage is expressed as
+// the bitwise-OR of packet.KeyFlag* values.
+func (el EntityList) KeysByIdUsage(id uint64, requiredUsage byte) (keys []Key) {
+    for _, key := range el.KeysById(id) {
+        if len(key.Entity.Revocations) > 0 {
+            continue
+        }
+
+        if key.SelfSignature.RevocationReason != nil {
+            continue
+        }
+
+        if key.SelfSignature.FlagsValid && requiredUsage != 0 {
+            var usage byte
+            if key.SelfSignature.FlagCertify {
+                usage |= packet.KeyFlagCertify
+            }
+            if key.SelfSignature.FlagSign {
+                usage |= packet.KeyFlagSign
+            }
+            if key.SelfSignature.FlagEncryptCommunications {
+                usage |= packet.KeyFlagEncryptCommunications
+            }
+            if key.SelfSignature.FlagEncryptStorage {
+                usage |= packet.KeyFlagEncryptStorage
+            }
+            if usage&requiredUsage != requiredUsage {
+                continue
+            }
+        }
+
+        keys = append(keys, key)
+    }
+    return
+}
+
+// DecryptionKeys returns all private keys that are valid for decryption.
+func (el EntityList) DecryptionKeys() (keys []Key) {
+    for _, e := range el {
+        for _, subKey := range e.Subkeys {
+            if subKey.PrivateKey != nil && (!subKey.Sig.FlagsValid || subKey.Sig.FlagEncryptStorage || subKey.Sig.FlagEncryptCommunications) {
+                keys = append(keys, Key{e, subKey.PublicKey, subKey.PrivateKey, subKey.Sig})
+            }
+        }
+    }
+    return
+}
+
+// ReadArmoredKeyRing reads one or more public/private keys from an armor keyring file.
+func ReadArmoredKeyRing(r io.Reader) (EntityList, error) {
+    block, err := armor.Decode(r)
+    if err == io.EOF {
+        return nil, errors.InvalidArgumentError("no armored data found")
+    }
+    if err != nil {
+        return nil, err
+    }
+    if block.Type != PublicKeyType && block.Type != PrivateKeyType {
+        return nil, errors.InvalidArgumentError("expected public or private key block, got: " + block.Type)
+    }
+
+    return ReadKeyRing(block.Body)
+}
+
+// ReadKeyRing reads one or more public/private keys. Unsupported keys are
+// ignored as long as at least a single valid key is found.
+func ReadKeyRing(r io.Reader) (el EntityList, err error) {
+    packets := packet.NewReader(r)
+    var lastUnsupportedError error
+
+    for {
+        var e *Entity
+        e, err = ReadEntity(packets)
+        if err != nil {
+            // TODO: warn about skipped unsupported/unreadable keys
+            if _, ok := err.(errors.UnsupportedError); ok {
+                lastUnsupportedError = err
+                err = readToNextPublicKey(packets)
+            } else if _, ok := err.(errors.StructuralError); ok {
+                // Skip unreadable, badly-formatted keys
+                lastUnsupportedError = err
+                err = readToNextPublicKey(packets)
+            }
+            if err == io.EOF {
+                err = nil
+                break
+            }
+            if err != nil {
+                el = nil
+                break
+            }
+        } else {
+            el = append(el, e)
+        }
+    }
+
+    if len(el) == 0 && err == nil {
+        err = lastUnsupportedError
+    }
+    return
+}
+
+// readToNextPublicKey reads packets until the start of the entity and leaves
+// the first packet of the new entity in the Reader.
+func readToNextPublicKey(packets *packet.Reader) (err error) {
+    var p packet.Packet
+    for {
+        p, err = packets.Next()
+        if err == io.EOF {
+            return
+        } else if err != nil {
+            if _, ok := err.(errors.UnsupportedError); ok {
+                err = nil
+                continue
+            }
+            return
+        }
+
+        if pk, ok := p.(*packet.PublicKey); ok && !pk.IsSubkey {
+            packets.Unread(p)
+            return
+        }
+    }
+}
+
+// ReadEntity reads an entity (public key, identities, subkeys etc) from the
+// given Reader.
+func ReadEntity(packets *packet.Reader) (*Entity, error) {
+    e := new(Entity)
+    e.Identities = make(map[string]*Identity)
+
+    p, err := packets.Next()
+    if err != nil {
+        return nil, err
+    }
+
+    var ok bool
+    if e.PrimaryKey, ok = p.(*packet.PublicKey); !ok {
+        if e.PrivateKey, ok = p.(*packet.PrivateKey); !ok {
+            packets.Unread(p)
+            return nil, errors.StructuralError("first packet was not a public/private key")
+        }
+        e.PrimaryKey = &e.PrivateKey.PublicKey
+    }
+
+    if !e.PrimaryKey.PubKeyAlgo.CanSign() {
+        return nil, errors.StructuralError("primary key cannot be used for signatures")
+    }
+
+    var revocations []*packet.Signature
+EachPacket:
+    for {
+        p, err := packets.Next()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            return nil, err
+        }
+
+        switch pkt := p.(type) {
+            case *packet.UserId:
+                if err := addUserID(e, packets, pkt); err != nil {
+                    return nil, err
+                }
+            case *packet.Signature:
+                if pkt.SigType == packet.SigTypeKeyRevocation {
+                    revocations = append(revocations, pkt)
+                } else if pkt.SigType == packet.SigTypeDirectSignature {
+                    // 只使用主密钥自身的直接签名
+                    if pkt.IssuerKeyId == nil || *pkt.IssuerKeyId != e.PrimaryKey.KeyId {
+                        break
+                    }
+                    if err := e.PrimaryKey.VerifyDirectKeySignature(pkt); err != nil {
+                        return nil, errors.StructuralError("direct-key signature invalid: " + err.Error())
+                    }
+                    if e.DirectSignature == nil || pkt.CreationTime.After(e.DirectSignature.CreationTime) {
+                        e.DirectSignature = pkt
+                    }
+                }
+                // Else, ignoring the signature as it does not follow anything
+                // we would know to attach it to.
+            case *packet.PrivateKey:
+                if pkt.IsSubkey == false {
+                    packets.Unread(p)
+                    break EachPacket
+                }
+                err = addSubkey(e, packets, &pkt.PublicKey, pkt)
+                if err != nil {
+                    return nil, err
+                }
+            case *packet.PublicKey:
+                if pkt.IsSubkey == false {
+                    packets.Unread(p)
+                    break EachPacket
+                }
+                err = addSubkey(e, packets, pkt, nil)
+                if err != nil {
+                    return nil, err
+                }
+            default:
+                // we ignore unknown packets
+        }
+    }
+
+    if len(e.Identities) == 0 && e.DirectSignature == nil {
+        return nil, errors.StructuralError("entity without any identities")
+    }
+
+    for _, revocation := range revocations {
+        err = e.PrimaryKey.VerifyRevocationSignature(revocation)
+        if err == nil {
+            e.Revocations = append(e.Revocations, revocation)
+        } else {
+            // TODO: RFC 4880 5.2.3.15 defines revocation keys.
+            return nil, errors.StructuralError("revocation signature signed by alternate key")
+        }
+    }
+
+    return e, nil
+}
+
+func addUserID(e *Entity, packets *packet.Reader, pkt *packet.UserId) error {
+    // Make a new Identity object, that we might wind up throwing away.
+    // We'll only add it if we get a valid self-signature over this
+    // userID.
+    identity := new(Identity)
+    identity.Name = pkt.Id
+    identity.UserId = pkt
+
+    for {
+        p, err := packets.Next()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            return err
+        }
+
+        sig, ok := p.(*packet.Signature)
+        if !ok {
+            packets.Unread(p)
+            break
+        }
+
+        if (sig.SigType == packet.SigTypePositiveCert || sig.SigType == packet.SigTypeGenericCert) && sig.IssuerKeyId != nil && *sig.IssuerKeyId == e.PrimaryKey.KeyId {
+            if err = e.PrimaryKey.VerifyUserIdSignature(pkt.Id, e.PrimaryKey, sig); err != nil {
+                return errors.StructuralError("user ID self-signature invalid: " + err.Error())
+            }
+            identity.SelfSignature = sig
+            e.Identities[pkt.Id] = identity
+        } else {
+            identity.Signatures = append(identity.Signatures, sig)
+        }
+    }
+
+    return nil
+}
+
+func addSubkey(e *Entity, packets *packet.Reader, pub *packet.PublicKey, priv *packet.PrivateKey) error {
+    var subKey Subkey
+    subKey.PublicKey = pub
+    subKey.PrivateKey = priv
+
+    for {
+        p, err := packets.Next()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            return errors.StructuralError("subkey signature invalid: " + err.Error())
+        }
+
+        sig, ok := p.(*packet.Signature)
+        if !ok {
+            packets.Unread(p)
+            break
+        }
+
+        if sig.SigType != packet.SigTypeSubkeyBinding && sig.SigType != packet.SigTypeSubkeyRevocation {
+            return errors.StructuralError("subkey signature with wrong type")
+        }
+
+        if err := e.PrimaryKey.VerifyKeySignature(subKey.PublicKey, sig); err != nil {
+            return errors.StructuralError("subkey signature invalid: " + err.Error())
+        }
+
+        switch sig.SigType {
+            case packet.SigTypeSubkeyRevocation:
+                subKey.Sig = sig
+            case packet.SigTypeSubkeyBinding:
+
+                if shouldReplaceSubkeySig(subKey.Sig, sig) {
+                    subKey.Sig = sig
+                }
+        }
+    }
+
+    if subKey.Sig == nil {
+        return errors.StructuralError("subkey packet not followed by signature")
+    }
+
+    e.Subkeys = append(e.Subkeys, subKey)
+
+    return nil
+}
+
+func shouldReplaceSubkeySig(existingSig, potentialNewSig *packet.Signature) bool {
+    if potentialNewSig == nil {
+        return false
+    }
+
+    if existingSig == nil {
+        return true
+    }
+
+    if existingSig.SigType == packet.SigTypeSubkeyRevocation {
+        return false // never override a revocation signature
+    }
+
+    return potentialNewSig.CreationTime.After(existingSig.CreationTime)
+}
+
+const defaultRSAKeyBits = 2048
+
+// newCurve25519LegacyKey 生成 Curve25519Legacy 的 ECDH 私钥.
+// GnuPG 只接受已经钳制的标量, 所以存储前先钳制
+func newCurve25519LegacyKey(random io.Reader) (*ecdh.PrivateKey, error) {
+    priv, err := ecdh.X25519().GenerateKey(random)
+    if err != nil {
+        return nil, err
+    }
+
+    b := priv.Bytes()
+    b[0] &= 248
+    b[31] &= 127
+    b[31] |= 64
+
+    return ecdh.X25519().NewPrivateKey(b)
+}
+
+// newEntityKeys 根据配置生成签名主密钥及加密子密钥
+func newEntityKeys(creationTime time.Time, config *packet.Config) (signing, encrypting *packet.PrivateKey, err error) {
+    random := config.Random()
+
+    switch config.PublicKeyAlgorithm() {
+        case packet.PubKeyAlgoRSA:
+            bits := defaultRSAKeyBits
+            if config != nil && config.RSABits != 0 {
+                bits = config.RSABits
+            }
+
+            signingPriv, err := rsa.GenerateKey(random, bits)
+            if err != nil {
+                return nil, nil, err
+            }
+            encryptingPriv, err := rsa.GenerateKey(random, bits)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            signing = packet.NewRSAPrivateKey(creationTime, signingPriv)
+            encrypting = packet.NewRSAPrivateKey(creationTime, encryptingPriv)
+        case packet.PubKeyAlgoECDSA:
+            signingPriv, err := ecdsa.GenerateKey(elliptic.P256(), random)
+            if err != nil {
+                return nil, nil, err
+            }
+            encryptingPriv, err := ecdh.P256().GenerateKey(random)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            signing = packet.NewECDSAPrivateKey(creationTime, signingPriv)
+            encrypting = packet.NewECDHPrivateKey(creationTime, encryptingPriv)
+        case packet.PubKeyAlgoEdDSA:
+            _, signingPriv, err := ed25519.GenerateKey(random)
+            if err != nil {
+                return nil, nil, err
+            }
+            encryptingPriv, err := newCurve25519LegacyKey(random)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            signing = packet.NewEdDSAPrivateKey(creationTime, signingPriv)
+            encrypting = packet.NewECDHPrivateKey(creationTime, encryptingPriv)
+        case packet.PubKeyAlgoEd25519:
+            _, signingPriv, err := ed25519.GenerateKey(random)
+            if err != nil {
+                return nil, nil, err
+            }
+            encryptingPriv, err := ecdh.X25519().GenerateKey(random)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            signing = packet.NewEd25519PrivateKey(creationTime, signingPriv)
+            encrypting = packet.NewX25519PrivateKey(creationTime, encryptingPriv)
+        case packet.PubKeyAlgoEd448:
+            _, signingPriv, err := ed448.GenerateKey(random)
+            if err != nil {
+                return nil, nil, err
+            }
+            _, encryptingPriv, err := x448.GenerateKey(random)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            signing = packet.NewEd448PrivateKey(creationTime, signingPriv)
+            encrypting = packet.NewX448PrivateKey(creationTime, encryptingPriv)
+        default:
+            return nil, nil, errors.UnsupportedError("public key algorithm for new entity: " + strconv.Itoa(int(config.PublicKeyAlgorithm())))
+    }
+
+    if config.V6() {
+        if err = signing.UpgradeToV6(); err != nil {
+            return nil, nil, err
+        }
+        if err = encrypting.UpgradeToV6(); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    return
+}
+
+// NewEntity returns an Entity that contains a fresh keypair with a single
+// identity composed of the given full name, comment and email, any of which
+// may be empty but must not contain any of "()<>\x00". The algorithm of the
+// keys is chosen by config.Algorithm, RSA by default, and config.V6Keys
+// creates v6 keys.
+// If config is nil, sensible defaults will be used.
+func NewEntity(name, comment, email string, config *packet.Config) (*Entity, error) {
+    creationTime := config.Now()
+
+    uid := packet.NewUserId(name, comment, email)
+    if uid == nil {
+        return nil, errors.InvalidArgumentError("user id field contained invalid characters")
+    }
+
+    signingPriv, encryptingPriv, err := newEntityKeys(creationTime, config)
+    if err != nil {
+        return nil, err
+    }
+
+    hashFunc := config.Hash()
+    if signingPriv.PubKeyAlgo == packet.PubKeyAlgoEd448 && (config == nil || config.DefaultHash == 0) {
+        hashFunc = crypto.SHA512
+    }
+
+    e := &Entity{
+        PrimaryKey: &signingPriv.PublicKey,
+        PrivateKey: signingPriv,
+        Identities: make(map[string]*Identity),
+    }
+
+    // 密钥的标识及偏好设置
+    selfSignature := func(sigType packet.SignatureType) *packet.Signature {
+        sig := &packet.Signature{
+            Version:           e.PrimaryKey.Version,
+            CreationTime:      creationTime,
+            SigType:           sigType,
+            PubKeyAlgo:        e.PrimaryKey.PubKeyAlgo,
+            Hash:              hashFunc,
+            FlagsValid:        true,
+            FlagSign:          true,
+            FlagCertify:       true,
+            IssuerKeyId:       &e.PrimaryKey.KeyId,
+            IssuerFingerprint: e.PrimaryKey.Fingerprint,
+            MDC:               true,
+        }
+
+        // If the user passes in a DefaultHash via packet.Config,
+        // set the PreferredHash for the SelfSignature.
+        if config != nil && config.DefaultHash != 0 {
+            sig.PreferredHash = []uint8{hashToHashId(config.DefaultHash)}
+        }
+
+        // Likewise for DefaultCipher.
+        if config != nil && config.DefaultCipher != 0 {
+            sig.PreferredSymmetric = []uint8{uint8(config.DefaultCipher)}
+        }
+
+        // AEAD 加密偏好
+        if config.AEAD() != nil || config.V6() {
+            sig.SEIPDv2 = true
+            sig.PreferredCipherSuites = [][2]uint8{
+                {uint8(config.Cipher()), uint8(config.AEAD().Mode())},
+            }
+        }
+
+        return sig
+    }
+
+    if config.V6() {
+        e.DirectSignature = selfSignature(packet.SigTypeDirectSignature)
+        err = e.DirectSignature.SignDirectKey(e.PrivateKey, config)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    isPrimaryId := true
+    e.Identities[uid.Id] = &Identity{
+        Name:          uid.Id,
+        UserId:        uid,
+        SelfSignature: selfSignature(packet.SigTypePositiveCert),
+    }
+    e.Identities[uid.Id].SelfSignature.IsPrimaryId = &isPrimaryId
+    err = e.Identities[uid.Id].SelfSignature.SignUserId(uid.Id, e.PrimaryKey, e.PrivateKey, config)
+    if err != nil {
+        return nil, err
+    }
+
+    encryptingPriv.IsSubkey = true
+    e.Subkeys = make([]Subkey, 1)
+    e.Subkeys[0] = Subkey{
+        PublicKey:  &encryptingPriv.PublicKey,
+        PrivateKey: encryptingPriv,
+        Sig: &packet.Signature{
+            Version:                   e.PrimaryKey.Version,
+            CreationTime:              creationTime,
+            SigType:                   packet.SigTypeSubkeyBinding,
+            PubKeyAlgo:                e.PrimaryKey.PubKeyAlgo,
+            Hash:                      hashFunc,
+            FlagsValid:                true,
+            FlagEncryptStorage:        true,
+            FlagEncryptCommunications: true,
+            IssuerKeyId:               &e.PrimaryKey.KeyId,
+            IssuerFingerprint:         e.PrimaryKey.Fingerprint,
+        },
+    }
+    err = e.Subkeys[0].Sig.SignKey(e.Subkeys[0].PublicKey, e.PrivateKey, config)
+    if err != nil {
+        return nil, err
+    }
+    return e, nil
+}
+
+// SerializePrivate serializes an Entity, including private key material, but
+// excluding signatures from other entities, to the given Writer.
+// Identities and subkeys are re-signed in case they changed since NewEntry.
+// If config is nil, sensible defaults will be used.
+func (e *Entity) SerializePrivate(w io.Writer, config *packet.Config) (err error) {
+    return e.serializePrivate(w, config, true)
+}
+
+// SerializePrivateWithoutSigning serializes an Entity, including private key
+// material, but excluding signatures from other entities, to the given
+// Writer. The existing self-signatures are kept, so that the private keys
+// may still be encrypted.
+func (e *Entity) SerializePrivateWithoutSigning(w io.Writer) (err error) {
+    return e.serializePrivate(w, nil, false)
+}
+
+func (e *Entity) serializePrivate(w io.Writer, config *packet.Config, reSign bool) (err error) {
+    if e.PrivateKey == nil {
+        return errors.InvalidArgumentError("entity has no private key")
+    }
+
+    err = e.PrivateKey.Serialize(w)
+    if err != nil {
+        return
+    }
+    if e.DirectSignature != nil {
+        if reSign {
+            err = e.DirectSignature.SignDirectKey(e.PrivateKey, config)
+            if err != nil {
+                return
+            }
+        }
+        err = e.DirectSignature.Serialize(w)
+        if err != nil {
+            return
+        }
+    }
+    for _, ident := range e.Identities {
+        err = ident.UserId.Serialize(w)
+        if err != nil {
+            return
+        }
+        if reSign {
+            err = ident.SelfSignature.SignUserId(ident.UserId.Id, e.PrimaryKey, e.PrivateKey, config)
+            if err != nil {
+                return
+            }
+        }
+        err = ident.SelfSignature.Serialize(w)
+        if err != nil {
+            return
+        }
+    }
+    for _, subkey := range e.Subkeys {
+        if subkey.PrivateKey == nil {
+            return errors.InvalidArgumentError("subkey has no private key")
+        }
+        err = subkey.PrivateKey.Serialize(w)
+        if err != nil {
+            return
+        }
+        if reSign {
+            err = subkey.Sig.SignKey(subkey.PublicKey, e.PrivateKey, config)
+            if err != nil {
+                return
+            }
+        }
+        err = subkey.Sig.Serialize(w)
+        if err != nil {
+            return
+        }
+    }
+    return nil
+}
+
+// Serialize writes the public part of the given Entity to w, including
+// signatures from other entities. No private key material will be output.
+func (e *Entity) Serialize(w io.Writer) error {
+    err := e.PrimaryKey.Serialize(w)
+    if err != nil {
+        return err
+    }
+    if e.DirectSignature != nil {
+        err = e.DirectSignature.Serialize(w)
+        if err != nil {
+            return err
+        }
+    }
+    for _, ident := range e.Identities {
+        err = ident.UserId.Serialize(w)
+        if err != nil {
+            return err
+        }
+        err = ident.SelfSignature.Serialize(w)
+        if err != nil {
+            return err
+        }
+        for _, sig := range ident.Signatures {
+            err = sig.Serialize(w)
+            if err != nil {
+                return err
+            }
+        }
+    }
+    for _, subkey := range e.Subkeys {
+        err = subkey.PublicKey.Serialize(w)
+        if err != nil {
+            return err
+        }
+        err = subkey.Sig.Serialize(w)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// SignIdentity adds a signature to e, from signer, attesting that identity is
+// associated with e. The provided identity must already be an element of
+// e.Identities and the private key of signer must have been decrypted if
+// necessary.
+// If config is nil, sensible defaults will be used.
+func (e *Entity) SignIdentity(identity string, signer *Entity, config *packet.Config) error {
+    if signer.PrivateKey == nil {
+        return errors.InvalidArgumentError("signing Entity must have a private key")
+    }
+    if signer.PrivateKey.Encrypted {
+        return errors.InvalidArgumentError("signing Entity's private key must be decrypted")
+    }
+    ident, ok := e.Identities[identity]
+    if !ok {
+        return errors.InvalidArgumentError("given identity string not found in Entity")
+    }
+
+    sig := newSignature(signer.PrivateKey, packet.SigTypeGenericCert, config.Hash(), config)
+    if err := sig.SignUserId(identity, e.PrimaryKey, signer.PrivateKey, config); err != nil {
+        return err
+    }
+    ident.Signatures = append(ident.Signatures, sig)
+    return nil
+}
@@ -0,0 +1,535 @@
+package openpgp
+
+import (
+    "io"
+    "os"
+    "bytes"
+    "testing"
+    "crypto/ecdh"
+    "encoding/hex"
+    "path/filepath"
+
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+    "github.com/deatil/go-cryptobin/openpgp/armor"
+    "github.com/deatil/go-cryptobin/openpgp/packet"
+    "github.com/deatil/go-cryptobin/openpgp/clearsign"
+)
+
+// testdata 下的文件由 GnuPG 2.2 生成, 私钥密码为 testpass
+const testPassphrase = "testpass"
+
+var testKeyNames = []string{"rsa", "dsa", "ecc", "nist"}
+
+func readTestFile(t *testing.T, name string) []byte {
+    data, err := os.ReadFile(filepath.Join("testdata", name))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return data
+}
+
+func readTestKeyRing(t *testing.T, name string) EntityList {
+    el, err := ReadArmoredKeyRing(bytes.NewReader(readTestFile(t, name)))
+    if err != nil {
+        t.Fatalf("%s: %s", name, err)
+    }
+    if len(el) != 1 {
+        t.Fatalf("%s: got %d entities, want 1", name, len(el))
+    }
+
+    return el
+}
+
+func decryptTestEntity(t *testing.T, e *Entity, passphrase []byte) {
+    if e.PrivateKey == nil {
+        t.Fatal("no private key")
+    }
+
+    if e.PrivateKey.Encrypted {
+        if err := e.PrivateKey.Decrypt(passphrase); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    for _, sub := range e.Subkeys {
+        if sub.PrivateKey != nil && sub.PrivateKey.Encrypted {
+            if err := sub.PrivateKey.Decrypt(passphrase); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+}
+
+func readMessageBody(t *testing.T, md *MessageDetails) []byte {
+    body, err := io.ReadAll(md.UnverifiedBody)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if md.SignatureError != nil {
+        t.Fatalf("signature error: %s", md.SignatureError)
+    }
+
+    return body
+}
+
+func Test_GnuPGKeyRing(t *testing.T) {
+    for _, name := range testKeyNames {
+        t.Run(name, func(t *testing.T) {
+            pub := readTestKeyRing(t, name+".pub.asc")
+            sec := readTestKeyRing(t, name+".sec.asc")
+
+            if pub[0].PrimaryKey.KeyId != sec[0].PrimaryKey.KeyId {
+                t.Error("public and secret key ids differ")
+            }
+            if len(sec[0].Subkeys) != 1 {
+                t.Fatalf("got %d subkeys, want 1", len(sec[0].Subkeys))
+            }
+
+            // 错误密码
+            if err := sec[0].PrivateKey.Decrypt([]byte("wrong")); err == nil {
+                t.Error("decrypt with wrong passphrase succeeded")
+            }
+
+            decryptTestEntity(t, sec[0], []byte(testPassphrase))
+
+            // 导出再导入
+            var buf bytes.Buffer
+            if err := pub[0].Serialize(&buf); err != nil {
+                t.Fatal(err)
+            }
+
+            el, err := ReadKeyRing(&buf)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if el[0].PrimaryKey.KeyId != pub[0].PrimaryKey.KeyId {
+                t.Error("key id mismatch after round trip")
+            }
+
+            buf.Reset()
+            if err := sec[0].SerializePrivate(&buf, nil); err != nil {
+                t.Fatal(err)
+            }
+
+            el, err = ReadKeyRing(&buf)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if el[0].PrivateKey == nil || el[0].PrivateKey.Encrypted {
+                t.Error("private key lost after round trip")
+            }
+        })
+    }
+}
+
+func Test_GnuPGSignatures(t *testing.T) {
+    msg := readTestFile(t, "msg.txt")
+
+    for _, name := range testKeyNames {
+        t.Run(name, func(t *testing.T) {
+            kring := readTestKeyRing(t, name+".pub.asc")
+
+            signer, err := CheckArmoredDetachedSignature(kring, bytes.NewReader(msg), bytes.NewReader(readTestFile(t, name+".sig.asc")))
+            if err != nil {
+                t.Fatalf("detached: %s", err)
+            }
+            if signer != kring[0] {
+                t.Error("wrong signer")
+            }
+
+            _, err = CheckArmoredDetachedSignature(kring, bytes.NewReader([]byte("hello world!\n")), bytes.NewReader(readTestFile(t, name+".sig.asc")))
+            if err == nil {
+                t.Error("signature verified over modified message")
+            }
+
+            b, _ := clearsign.Decode(readTestFile(t, name+".clear.asc"))
+            if b == nil {
+                t.Fatal("clearsign decode failed")
+            }
+            if !bytes.Equal(b.Plaintext, msg) {
+                t.Errorf("clearsign plaintext: got %q", b.Plaintext)
+            }
+
+            _, err = CheckDetachedSignature(kring, bytes.NewReader(b.Bytes), b.ArmoredSignature.Body)
+            if err != nil {
+                t.Fatalf("clearsign: %s", err)
+            }
+
+            block, err := armor.Decode(bytes.NewReader(readTestFile(t, name+".signed.asc")))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            md, err := ReadMessage(block.Body, kring, nil, nil)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !md.IsSigned || md.SignedBy == nil {
+                t.Error("signed message signer not found")
+            }
+            if body := readMessageBody(t, md); !bytes.Equal(body, msg) {
+                t.Errorf("signed body: got %q", body)
+            }
+        })
+    }
+}
+
+func Test_GnuPGDecrypt(t *testing.T) {
+    msg := readTestFile(t, "msg.txt")
+
+    for _, name := range testKeyNames {
+        t.Run(name, func(t *testing.T) {
+            kring := readTestKeyRing(t, name+".sec.asc")
+
+            block, err := armor.Decode(bytes.NewReader(readTestFile(t, name+".enc.asc")))
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            prompt := func(keys []Key, symmetric bool) ([]byte, error) {
+                for _, k := range keys {
+                    if err := k.PrivateKey.Decrypt([]byte(testPassphrase)); err != nil {
+                        return nil, err
+                    }
+                }
+                return nil, nil
+            }
+
+            md, err := ReadMessage(block.Body, kring, prompt, nil)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !md.IsEncrypted {
+                t.Error("message not marked as encrypted")
+            }
+            if body := readMessageBody(t, md); !bytes.Equal(body, msg) {
+                t.Errorf("got %q, want %q", body, msg)
+            }
+        })
+    }
+}
+
+func Test_GnuPGSymmetric(t *testing.T) {
+    block, err := armor.Decode(bytes.NewReader(readTestFile(t, "sym.asc")))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    prompt := func(keys []Key, symmetric bool) ([]byte, error) {
+        return []byte("symmpass"), nil
+    }
+
+    md, err := ReadMessage(block.Body, nil, prompt, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !md.IsSymmetricallyEncrypted {
+        t.Error("message not marked as symmetrically encrypted")
+    }
+    if body := readMessageBody(t, md); !bytes.Equal(body, readTestFile(t, "msg.txt")) {
+        t.Errorf("got %q", body)
+    }
+}
+
+// testdata/v6.sec.asc 为 RFC 9580 附录 A.4 的示例私钥
+func Test_V6SampleKey(t *testing.T) {
+    el := readTestKeyRing(t, "v6.sec.asc")
+    e := el[0]
+
+    fp := "cb186c4f0609a697e4d52dfa6c722b0c1f1e27c18a56708f6525ec27bad9acc9"
+    if got := hex.EncodeToString(e.PrimaryKey.Fingerprint); got != fp {
+        t.Errorf("got fingerprint %s, want %s", got, fp)
+    }
+    if e.PrimaryKey.Version != 6 || e.DirectSignature == nil {
+        t.Error("not a v6 key with direct key signature")
+    }
+    if len(e.Subkeys) != 1 || e.Subkeys[0].PublicKey.PubKeyAlgo != packet.PubKeyAlgoX25519 {
+        t.Fatal("X25519 subkey not found")
+    }
+
+    testEncryptDecrypt(t, e, &packet.Config{
+        AEADConfig: &packet.AEADConfig{DefaultMode: packet.AEADModeOCB},
+    })
+}
+
+// testdata/v6.enc.asc 为 RFC 9580 附录 A.8 发给示例私钥的 X25519-AEAD-OCB 消息
+func Test_V6SampleMessage(t *testing.T) {
+    el := readTestKeyRing(t, "v6.sec.asc")
+
+    block, err := armor.Decode(bytes.NewReader(readTestFile(t, "v6.enc.asc")))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    md, err := ReadMessage(block.Body, el, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !md.IsEncrypted || md.DecryptedWith.PublicKey.Version != 6 {
+        t.Errorf("unexpected details: %+v", md)
+    }
+    if body := readMessageBody(t, md); string(body) != "Hello, world!" {
+        t.Errorf("got %q", body)
+    }
+}
+
+// RFC 9580 附录 A.9, v6 SKESK 和 AEAD-EAX 的 v2 SEIPD, 密码为 password
+func Test_V6SampleSymmetric(t *testing.T) {
+    msg, _ := hex.DecodeString("c340061e07010b0308a5ae579d1fc5d82bff69224f919993b3506fa3b59a6a73cff8c5efc5f41c57fb54e1c226815d7828f5f92c454eb65ebe00ab5986c68e6e7c55d269020701069ff90e3b321964f3a42913c8dcc6619325015227efb7eaeaa49f04c2e674175d4a3d226ed6afcb9ca9ac122c1470e11c63d4c0ab241c6a938ad48bf99a5a99b90bba8325de61047540258ab7959a95ad051dda96eb15431dfef5f5e2255ca78261546e339a")
+
+    prompt := func(keys []Key, symmetric bool) ([]byte, error) {
+        return []byte("password"), nil
+    }
+
+    md, err := ReadMessage(bytes.NewReader(msg), nil, prompt, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !md.IsSymmetricallyEncrypted {
+        t.Error("message not marked as symmetrically encrypted")
+    }
+    if body := readMessageBody(t, md); string(body) != "Hello, world!" {
+        t.Errorf("got %q", body)
+    }
+}
+
+func testEncryptDecrypt(t *testing.T, e *Entity, config *packet.Config) {
+    msg := []byte("a secret message, which is long enough to span chunks")
+
+    var buf bytes.Buffer
+    w, err := Encrypt(&buf, []*Entity{e}, e, nil, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err = w.Write(msg); err != nil {
+        t.Fatal(err)
+    }
+    if err = w.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    md, err := ReadMessage(&buf, EntityList{e}, nil, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !md.IsEncrypted || !md.IsSigned || md.SignedBy == nil {
+        t.Errorf("unexpected details: %+v", md)
+    }
+    if body := readMessageBody(t, md); !bytes.Equal(body, msg) {
+        t.Errorf("got %q, want %q", body, msg)
+    }
+}
+
+func Test_NewEntity(t *testing.T) {
+    cases := []struct {
+        name   string
+        config *packet.Config
+    }{
+        {"RSA", &packet.Config{RSABits: 2048}},
+        {"ECDSA", &packet.Config{Algorithm: packet.PubKeyAlgoECDSA}},
+        {"EdDSA", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA}},
+        {"Ed25519", &packet.Config{Algorithm: packet.PubKeyAlgoEd25519}},
+        {"Ed448", &packet.Config{Algorithm: packet.PubKeyAlgoEd448}},
+        {"Ed25519-v6-OCB", &packet.Config{
+            Algorithm:  packet.PubKeyAlgoEd25519,
+            V6Keys:     true,
+            AEADConfig: &packet.AEADConfig{DefaultMode: packet.AEADModeOCB, ChunkSize: 64},
+        }},
+        {"Ed448-v6-EAX", &packet.Config{
+            Algorithm:  packet.PubKeyAlgoEd448,
+            V6Keys:     true,
+            AEADConfig: &packet.AEADConfig{DefaultMode: packet.AEADModeEAX},
+        }},
+        {"Ed25519-v6-GCM", &packet.Config{
+            Algorithm:  packet.PubKeyAlgoEd25519,
+            V6Keys:     true,
+            AEADConfig: &packet.AEADConfig{DefaultMode: packet.AEADModeGCM, ChunkSize: 64},
+        }},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            e, err := NewEntity("Test", "", "test@example.com", c.config)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if c.config.V6Keys && e.PrimaryKey.Version != 6 {
+                t.Errorf("got version %d, want 6", e.PrimaryKey.Version)
+            }
+
+            testEncryptDecrypt(t, e, c.config)
+
+            // 导出再导入
+            var buf bytes.Buffer
+            if err = e.SerializePrivate(&buf, c.config); err != nil {
+                t.Fatal(err)
+            }
+
+            el, err := ReadKeyRing(&buf)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !bytes.Equal(el[0].PrimaryKey.Fingerprint, e.PrimaryKey.Fingerprint) {
+                t.Error("fingerprint mismatch after round trip")
+            }
+
+            testEncryptDecrypt(t, el[0], c.config)
+
+            // 签名
+            msg := []byte("signed message")
+
+            var sig bytes.Buffer
+            if err = ArmoredDetachSign(&sig, e, bytes.NewReader(msg), c.config); err != nil {
+                t.Fatal(err)
+            }
+            if _, err = CheckArmoredDetachedSignature(el, bytes.NewReader(msg), &sig); err != nil {
+                t.Fatal(err)
+            }
+
+            // 明文签名
+            var clear bytes.Buffer
+            w, err := clearsign.Encode(&clear, e.PrivateKey, c.config)
+            if err != nil {
+                t.Fatal(err)
+            }
+            w.Write(msg)
+            if err = w.Close(); err != nil {
+                t.Fatal(err)
+            }
+
+            b, _ := clearsign.Decode(clear.Bytes())
+            if b == nil {
+                t.Fatal("clearsign decode failed")
+            }
+            if _, err = CheckDetachedSignature(el, bytes.NewReader(b.Bytes), b.ArmoredSignature.Body); err != nil {
+                t.Fatal(err)
+            }
+        })
+    }
+}
+
+// GnuPG 只接受钳制后的 Curve25519Legacy 私钥
+func Test_NewEntityCurve25519Legacy(t *testing.T) {
+    config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA}
+
+    for i := 0; i < 4; i++ {
+        e, err := NewEntity("Test", "", "test@example.com", config)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        var buf bytes.Buffer
+        if err = e.SerializePrivate(&buf, config); err != nil {
+            t.Fatal(err)
+        }
+        el, err := ReadKeyRing(&buf)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        for _, sub := range []Subkey{e.Subkeys[0], el[0].Subkeys[0]} {
+            b := sub.PrivateKey.PrivateKey.(*ecdh.PrivateKey).Bytes()
+            if b[0]&7 != 0 || b[31]&0xc0 != 0x40 {
+                t.Fatalf("scalar not clamped: %x", b)
+            }
+        }
+    }
+}
+
+func Test_PrivateKeyEncrypt(t *testing.T) {
+    configs := map[string]*packet.Config{
+        "v4-CFB": &packet.Config{Algorithm: packet.PubKeyAlgoEd25519},
+        "v4-AEAD": &packet.Config{
+            Algorithm:  packet.PubKeyAlgoEd25519,
+            AEADConfig: &packet.AEADConfig{},
+        },
+        "v6-AEAD-Argon2": &packet.Config{
+            Algorithm:  packet.PubKeyAlgoEd25519,
+            V6Keys:     true,
+            AEADConfig: &packet.AEADConfig{},
+            S2KConfig: &s2k.Config{
+                S2KMode:      s2k.Argon2S2K,
+                Argon2Config: &s2k.Argon2Config{NumberOfPasses: 1, DegreeOfParallelism: 1, MemoryExponent: 10},
+            },
+        },
+    }
+
+    for name, config := range configs {
+        t.Run(name, func(t *testing.T) {
+            e, err := NewEntity("Test", "", "test@example.com", config)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            passphrase := []byte("passphrase")
+            if err = e.PrivateKey.EncryptWithConfig(passphrase, config); err != nil {
+                t.Fatal(err)
+            }
+
+            var buf bytes.Buffer
+            if err = e.SerializePrivateWithoutSigning(&buf); err != nil {
+                t.Fatal(err)
+            }
+
+            el, err := ReadKeyRing(&buf)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            priv := el[0].PrivateKey
+            if !priv.Encrypted {
+                t.Fatal("private key is not encrypted")
+            }
+            if err = priv.Decrypt([]byte("wrong")); err == nil {
+                t.Error("decrypt with wrong passphrase succeeded")
+            }
+            if err = priv.Decrypt(passphrase); err != nil {
+                t.Fatal(err)
+            }
+        })
+    }
+}
+
+func Test_SymmetricallyEncrypt(t *testing.T) {
+    configs := map[string]*packet.Config{
+        "v4": nil,
+        "v6-Argon2": &packet.Config{
+            AEADConfig: &packet.AEADConfig{DefaultMode: packet.AEADModeEAX},
+            S2KConfig: &s2k.Config{
+                S2KMode:      s2k.Argon2S2K,
+                Argon2Config: &s2k.Argon2Config{NumberOfPasses: 1, DegreeOfParallelism: 1, MemoryExponent: 10},
+            },
+        },
+    }
+
+    msg := []byte("symmetrically encrypted")
+    passphrase := []byte("passphrase")
+
+    for name, config := range configs {
+        t.Run(name, func(t *testing.T) {
+            var buf bytes.Buffer
+            w, err := SymmetricallyEncrypt(&buf, passphrase, nil, config)
+            if err != nil {
+                t.Fatal(err)
+            }
+            w.Write(msg)
+            if err = w.Close(); err != nil {
+                t.Fatal(err)
+            }
+
+            prompt := func(keys []Key, symmetric bool) ([]byte, error) {
+                return passphrase, nil
+            }
+
+            md, err := ReadMessage(&buf, nil, prompt, config)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if body := readMessageBody(t, md); !bytes.Equal(body, msg) {
+                t.Errorf("got %q, want %q", body, msg)
+            }
+        })
+    }
+}
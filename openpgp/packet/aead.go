@@ -0,0 +1,104 @@
+package packet
+
+import (
+    "crypto/cipher"
+    "crypto/sha256"
+
+    "github.com/deatil/go-cryptobin/kdf/hkdf"
+    "github.com/deatil/go-cryptobin/mode/eax"
+    "github.com/deatil/go-cryptobin/mode/ocb"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// AEADMode represents the different Authenticated Encryption with Associated
+// Data specified for OpenPGP. See RFC 9580, section 9.6.
+type AEADMode uint8
+
+const (
+    AEADModeEAX AEADMode = 1
+    AEADModeOCB AEADMode = 2
+    AEADModeGCM AEADMode = 3
+)
+
+// tag 长度都为 16 字节
+const aeadTagLength = 16
+
+// NonceLength returns the length of the nonce, in bytes, of mode.
+func (mode AEADMode) NonceLength() int {
+    switch mode {
+        case AEADModeEAX:
+            return 16
+        case AEADModeOCB:
+            return 15
+        case AEADModeGCM:
+            return 12
+    }
+    return 0
+}
+
+// TagLength returns the length of the tag, in bytes, of mode.
+func (mode AEADMode) TagLength() int {
+    return aeadTagLength
+}
+
+// IsSupported returns true if the mode is supported.
+func (mode AEADMode) IsSupported() bool {
+    return mode.NonceLength() > 0
+}
+
+// new returns a fresh instance of the given mode.
+func (mode AEADMode) new(block cipher.Block) (cipher.AEAD, error) {
+    if block.BlockSize() != 16 {
+        return nil, errors.UnsupportedError("AEAD needs 16 bytes block cipher")
+    }
+
+    switch mode {
+        case AEADModeEAX:
+            return eax.NewEAXWithNonceAndTagSize(block, mode.NonceLength(), aeadTagLength)
+        case AEADModeOCB:
+            return ocb.NewWithNonceAndTagSize(block, mode.NonceLength(), aeadTagLength)
+        case AEADModeGCM:
+            return cipher.NewGCMWithNonceSize(block, mode.NonceLength())
+    }
+
+    return nil, errors.UnsupportedError("unsupported AEAD mode")
+}
+
+// AEADConfig collects a number of AEAD parameters along with sensible
+// defaults. A nil *AEADConfig is valid and results in all default values.
+type AEADConfig struct {
+    // DefaultMode is the AEAD mode of operation. If zero, OCB is used.
+    DefaultMode AEADMode
+    // ChunkSize is the maximum size of a chunk, in bytes. It is rounded
+    // down to a power of two between 64 bytes and 4 MiB.
+    // If zero, 256 KiB is used.
+    ChunkSize uint64
+}
+
+// Mode returns the AEAD mode of operation.
+func (conf *AEADConfig) Mode() AEADMode {
+    if conf == nil || conf.DefaultMode == 0 {
+        return AEADModeOCB
+    }
+    return conf.DefaultMode
+}
+
+// ChunkSizeByte returns the encoded chunk size.
+func (conf *AEADConfig) ChunkSizeByte() byte {
+    if conf == nil || conf.ChunkSize == 0 {
+        return 12 // 1 << (12 + 6) == 256 KiB
+    }
+
+    var c byte
+    for c = 16; c > 0; c-- {
+        if conf.ChunkSize >= uint64(1)<<(c+6) {
+            break
+        }
+    }
+    return c
+}
+
+// deriveAEADKey 使用 HKDF-SHA256 派生 AEAD 加密密钥
+func deriveAEADKey(key, salt, info []byte, length int) ([]byte, error) {
+    return hkdf.Key(sha256.New, key, salt, info, length)
+}
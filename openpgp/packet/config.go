@@ -0,0 +1,132 @@
+package packet
+
+import (
+    "io"
+    "time"
+    "crypto"
+    "crypto/rand"
+
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+)
+
+// Config collects a number of parameters along with sensible defaults.
+// A nil *Config is valid and results in all default values.
+type Config struct {
+    // Rand provides the source of entropy.
+    // If nil, the crypto/rand Reader is used.
+    Rand io.Reader
+    // DefaultHash is the default hash function to be used.
+    // If zero, SHA-256 is used.
+    DefaultHash crypto.Hash
+    // DefaultCipher is the cipher to be used.
+    // If zero, AES-128 is used.
+    DefaultCipher CipherFunction
+    // Time returns the current time as the number of seconds since the
+    // epoch. If Time is nil, time.Now is used.
+    Time func() time.Time
+    // DefaultCompressionAlgo is the compression algorithm to be
+    // applied to the plaintext before encryption. If zero, no
+    // compression is done.
+    DefaultCompressionAlgo CompressionAlgo
+    // CompressionConfig configures the compression settings.
+    CompressionConfig *CompressionConfig
+    // S2KCount is only used for symmetric encryption. It
+    // determines the strength of the passphrase stretching when
+    // the said passphrase is hashed to produce a key. S2KCount
+    // should be between 1024 and 65011712, inclusive. If Config
+    // is nil or S2KCount is 0, the value 65536 used. Not all
+    // values in the above range can be represented. S2KCount will
+    // be rounded up to the next representable value if it cannot
+    // be encoded exactly. When set, it is strongly encrouraged to
+    // use a value that is at least 65536. See RFC 4880 Section
+    // 3.7.1.3.
+    S2KCount int
+    // RSABits is the number of bits in new RSA keys made with NewEntity.
+    // If zero, then 2048 bit keys are created.
+    RSABits int
+    // Algorithm is the public key algorithm to use for new keys made with
+    // NewEntity. If zero, RSA is used. PubKeyAlgoEdDSA creates an EdDSA
+    // primary key with a Curve25519 ECDH subkey. PubKeyAlgoEd25519 and
+    // PubKeyAlgoEd448 create native keys with X25519 and X448 subkeys.
+    Algorithm PublicKeyAlgorithm
+    // V6Keys 为 true 时, NewEntity 生成 v6 密钥
+    V6Keys bool
+    // AEADConfig configures the AEAD mode of symmetric encryption. If
+    // not nil, SEIPD v2 packets are used for encryption.
+    AEADConfig *AEADConfig
+    // S2KConfig configures the S2K used when encrypting private keys
+    // and messages with a passphrase. If nil, an iterated and salted
+    // S2K using Hash and S2KCount is used.
+    S2KConfig *s2k.Config
+}
+
+func (c *Config) Random() io.Reader {
+    if c == nil || c.Rand == nil {
+        return rand.Reader
+    }
+    return c.Rand
+}
+
+func (c *Config) Hash() crypto.Hash {
+    if c == nil || uint(c.DefaultHash) == 0 {
+        return crypto.SHA256
+    }
+    return c.DefaultHash
+}
+
+func (c *Config) Cipher() CipherFunction {
+    if c == nil || uint8(c.DefaultCipher) == 0 {
+        return CipherAES128
+    }
+    return c.DefaultCipher
+}
+
+func (c *Config) Now() time.Time {
+    if c == nil || c.Time == nil {
+        return time.Now()
+    }
+    return c.Time()
+}
+
+func (c *Config) Compression() CompressionAlgo {
+    if c == nil {
+        return CompressionNone
+    }
+    return c.DefaultCompressionAlgo
+}
+
+func (c *Config) PasswordHashIterations() int {
+    if c == nil || c.S2KCount == 0 {
+        return 0
+    }
+    return c.S2KCount
+}
+
+func (c *Config) PublicKeyAlgorithm() PublicKeyAlgorithm {
+    if c == nil || c.Algorithm == 0 {
+        return PubKeyAlgoRSA
+    }
+    return c.Algorithm
+}
+
+func (c *Config) V6() bool {
+    return c != nil && c.V6Keys
+}
+
+func (c *Config) AEAD() *AEADConfig {
+    if c == nil {
+        return nil
+    }
+    return c.AEADConfig
+}
+
+func (c *Config) S2K() *s2k.Config {
+    if c != nil && c.S2KConfig != nil {
+        return c.S2KConfig
+    }
+
+    return &s2k.Config{
+        Hash:     c.Hash(),
+        S2KCount: c.PasswordHashIterations(),
+    }
+}
@@ -0,0 +1,550 @@
+package packet
+
+import (
+    "io"
+    "bytes"
+    "crypto"
+    "strconv"
+    "math/big"
+    "crypto/rsa"
+    "crypto/ecdh"
+    "crypto/sha256"
+    "crypto/sha512"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/mode"
+    "github.com/deatil/go-cryptobin/kdf/hkdf"
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+    "github.com/deatil/go-cryptobin/pubkey/x448"
+    "github.com/deatil/go-cryptobin/pubkey/elgamal"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// EncryptedKey represents a public-key encrypted session key. See RFC 9580,
+// section 5.1.
+type EncryptedKey struct {
+    // Version 为 3 或者 6
+    Version    int
+    KeyId      uint64
+    // v6 使用, 为空时表示匿名接收者
+    KeyVersion     int
+    KeyFingerprint []byte
+    Algo       PublicKeyAlgorithm
+    CipherFunc CipherFunction // only valid after a successful Decrypt of a v3 packet
+    Key        []byte         // only valid after a successful Decrypt
+
+    encryptedMPI1, encryptedMPI2 parsedMPI
+    // ECDH, X25519 及 X448 使用
+    ephemeral    []byte
+    encryptedKey []byte
+}
+
+func (e *EncryptedKey) parse(r io.Reader) (err error) {
+    var buf [8]byte
+    if _, err = readFull(r, buf[:1]); err != nil {
+        return
+    }
+    e.Version = int(buf[0])
+
+    switch e.Version {
+        case 3:
+            if _, err = readFull(r, buf[:8]); err != nil {
+                return
+            }
+            e.KeyId = binary.BigEndian.Uint64(buf[:8])
+        case 6:
+            if _, err = readFull(r, buf[:1]); err != nil {
+                return
+            }
+            if buf[0] > 0 {
+                recipient := make([]byte, buf[0])
+                if _, err = readFull(r, recipient); err != nil {
+                    return
+                }
+
+                e.KeyVersion = int(recipient[0])
+                e.KeyFingerprint = recipient[1:]
+                switch {
+                    case e.KeyVersion == 6 && len(e.KeyFingerprint) == 32:
+                        e.KeyId = binary.BigEndian.Uint64(e.KeyFingerprint[:8])
+                    case e.KeyVersion == 4 && len(e.KeyFingerprint) == 20:
+                        e.KeyId = binary.BigEndian.Uint64(e.KeyFingerprint[12:])
+                    default:
+                        return errors.StructuralError("invalid recipient in EncryptedKey")
+                }
+            }
+        default:
+            return errors.UnsupportedError("unknown EncryptedKey version " + strconv.Itoa(e.Version))
+    }
+
+    if _, err = readFull(r, buf[:1]); err != nil {
+        return
+    }
+    e.Algo = PublicKeyAlgorithm(buf[0])
+
+    switch e.Algo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly:
+            e.encryptedMPI1.bytes, e.encryptedMPI1.bitLength, err = readMPI(r)
+            if err != nil {
+                return
+            }
+        case PubKeyAlgoElGamal:
+            e.encryptedMPI1.bytes, e.encryptedMPI1.bitLength, err = readMPI(r)
+            if err != nil {
+                return
+            }
+            e.encryptedMPI2.bytes, e.encryptedMPI2.bitLength, err = readMPI(r)
+            if err != nil {
+                return
+            }
+        case PubKeyAlgoECDH:
+            e.encryptedMPI1.bytes, e.encryptedMPI1.bitLength, err = readMPI(r)
+            if err != nil {
+                return
+            }
+            e.ephemeral = e.encryptedMPI1.bytes
+
+            if _, err = readFull(r, buf[:1]); err != nil {
+                return
+            }
+            e.encryptedKey = make([]byte, buf[0])
+            if _, err = readFull(r, e.encryptedKey); err != nil {
+                return
+            }
+        case PubKeyAlgoX25519, PubKeyAlgoX448:
+            size := 32
+            if e.Algo == PubKeyAlgoX448 {
+                size = x448.PublicKeySize
+            }
+
+            e.ephemeral = make([]byte, size)
+            if _, err = readFull(r, e.ephemeral); err != nil {
+                return
+            }
+
+            if _, err = readFull(r, buf[:1]); err != nil {
+                return
+            }
+            fields := make([]byte, buf[0])
+            if _, err = readFull(r, fields); err != nil {
+                return
+            }
+
+            if e.Version == 3 {
+                // v3 的算法标识不加密
+                if len(fields) == 0 {
+                    return errors.StructuralError("truncated EncryptedKey")
+                }
+                e.CipherFunc = CipherFunction(fields[0])
+                fields = fields[1:]
+            }
+            e.encryptedKey = fields
+    }
+
+    _, err = consumeAll(r)
+    return
+}
+
+func checksumKeyMaterial(key []byte) uint16 {
+    var checksum uint16
+    for _, v := range key {
+        checksum += uint16(v)
+    }
+    return checksum
+}
+
+// Decrypt decrypts an encrypted session key with the given private key. The
+// private key must have been decrypted first.
+// If config is nil, sensible defaults will be used.
+func (e *EncryptedKey) Decrypt(priv *PrivateKey, config *Config) error {
+    if e.KeyId != 0 && e.KeyId != priv.KeyId {
+        return errors.InvalidArgumentError("cannot decrypt encrypted session key for key id " + strconv.FormatUint(e.KeyId, 16) + " with private key id " + strconv.FormatUint(priv.KeyId, 16))
+    }
+    if e.Algo != priv.PubKeyAlgo {
+        return errors.InvalidArgumentError("cannot decrypt encrypted session key of type " + strconv.Itoa(int(e.Algo)) + " with private key of type " + strconv.Itoa(int(priv.PubKeyAlgo)))
+    }
+    if priv.Dummy() {
+        return errors.ErrDummyPrivateKey("dummy key found")
+    }
+    if priv.PrivateKey == nil {
+        return errors.InvalidArgumentError("private key is not decrypted")
+    }
+
+    var err error
+    var b []byte
+
+    switch priv.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly:
+            // Supports both *rsa.PrivateKey and crypto.Decrypter
+            k := priv.PrivateKey.(crypto.Decrypter)
+            b, err = k.Decrypt(config.Random(), padToKeySize(k.Public().(*rsa.PublicKey), e.encryptedMPI1.bytes), nil)
+        case PubKeyAlgoElGamal:
+            c1 := new(big.Int).SetBytes(e.encryptedMPI1.bytes)
+            c2 := new(big.Int).SetBytes(e.encryptedMPI2.bytes)
+            b, err = elgamal.Decrypt(priv.PrivateKey.(*elgamal.PrivateKey), c1, c2)
+        case PubKeyAlgoECDH:
+            b, err = ecdhDecrypt(&priv.PublicKey, priv.PrivateKey.(*ecdh.PrivateKey), e.ephemeral, e.encryptedKey)
+        case PubKeyAlgoX25519, PubKeyAlgoX448:
+            e.Key, err = nativeDecrypt(priv, e.ephemeral, e.encryptedKey)
+            if err != nil {
+                return err
+            }
+            if e.Version == 3 && !e.CipherFunc.IsSupported() {
+                return errors.UnsupportedError("unsupported encryption function")
+            }
+            return nil
+        default:
+            err = errors.InvalidArgumentError("cannot decrypt encrypted session key with private key of type " + strconv.Itoa(int(priv.PubKeyAlgo)))
+    }
+
+    if err != nil {
+        return err
+    }
+
+    if e.Version == 3 {
+        if len(b) < 1 {
+            return errors.StructuralError("EncryptedKey too short")
+        }
+        e.CipherFunc = CipherFunction(b[0])
+        b = b[1:]
+        if !e.CipherFunc.IsSupported() {
+            return errors.UnsupportedError("unsupported encryption function")
+        }
+    }
+
+    if len(b) < 2 {
+        return errors.StructuralError("EncryptedKey too short")
+    }
+
+    e.Key = b[:len(b)-2]
+    expectedChecksum := uint16(b[len(b)-2])<<8 | uint16(b[len(b)-1])
+    checksum := checksumKeyMaterial(e.Key)
+    if checksum != expectedChecksum {
+        return errors.StructuralError("EncryptedKey checksum incorrect")
+    }
+
+    return nil
+}
+
+// Serialize writes the encrypted key packet, e, to w.
+func (e *EncryptedKey) Serialize(w io.Writer) error {
+    var buf bytes.Buffer
+
+    version := e.Version
+    if version == 0 {
+        version = 3
+    }
+
+    buf.WriteByte(byte(version))
+    if version == 6 {
+        if e.KeyFingerprint == nil {
+            buf.WriteByte(0)
+        } else {
+            buf.WriteByte(byte(1 + len(e.KeyFingerprint)))
+            buf.WriteByte(byte(e.KeyVersion))
+            buf.Write(e.KeyFingerprint)
+        }
+    } else {
+        binary.Write(&buf, binary.BigEndian, e.KeyId)
+    }
+    buf.WriteByte(byte(e.Algo))
+
+    switch e.Algo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly:
+            writeMPIs(&buf, e.encryptedMPI1)
+        case PubKeyAlgoElGamal:
+            writeMPIs(&buf, e.encryptedMPI1, e.encryptedMPI2)
+        case PubKeyAlgoECDH:
+            writeMPIs(&buf, e.encryptedMPI1)
+            buf.WriteByte(byte(len(e.encryptedKey)))
+            buf.Write(e.encryptedKey)
+        case PubKeyAlgoX25519, PubKeyAlgoX448:
+            buf.Write(e.ephemeral)
+            if version == 3 {
+                buf.WriteByte(byte(1 + len(e.encryptedKey)))
+                buf.WriteByte(byte(e.CipherFunc))
+            } else {
+                buf.WriteByte(byte(len(e.encryptedKey)))
+            }
+            buf.Write(e.encryptedKey)
+        default:
+            return errors.InvalidArgumentError("don't know how to serialize encrypted key type " + strconv.Itoa(int(e.Algo)))
+    }
+
+    err := serializeHeader(w, packetTypeEncryptedKey, buf.Len())
+    if err != nil {
+        return err
+    }
+
+    _, err = w.Write(buf.Bytes())
+    return err
+}
+
+// SerializeEncryptedKey serializes a v3 encrypted key packet to w that
+// contains key, encrypted to pub.
+// If config is nil, sensible defaults will be used.
+func SerializeEncryptedKey(w io.Writer, pub *PublicKey, cipherFunc CipherFunction, key []byte, config *Config) error {
+    return serializeEncryptedKey(w, 3, pub, cipherFunc, key, config)
+}
+
+// SerializeEncryptedKeyV6 serializes a v6 encrypted key packet to w that
+// contains key, encrypted to pub. It is used with SEIPD v2 packets.
+// If config is nil, sensible defaults will be used.
+func SerializeEncryptedKeyV6(w io.Writer, pub *PublicKey, key []byte, config *Config) error {
+    return serializeEncryptedKey(w, 6, pub, 0, key, config)
+}
+
+func serializeEncryptedKey(w io.Writer, version int, pub *PublicKey, cipherFunc CipherFunction, key []byte, config *Config) (err error) {
+    e := &EncryptedKey{
+        Version:    version,
+        KeyId:      pub.KeyId,
+        Algo:       pub.PubKeyAlgo,
+        CipherFunc: cipherFunc,
+    }
+    if version == 6 {
+        e.KeyVersion = pub.Version
+        e.KeyFingerprint = pub.Fingerprint
+    }
+
+    // 原始密钥数据, v3 带有算法标识
+    var keyBlock []byte
+    if version == 3 {
+        keyBlock = append(keyBlock, byte(cipherFunc))
+    }
+    keyBlock = append(keyBlock, key...)
+    checksum := checksumKeyMaterial(key)
+    keyBlock = append(keyBlock, byte(checksum>>8), byte(checksum))
+
+    switch pub.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly:
+            cipherText, err := rsa.EncryptPKCS1v15(config.Random(), pub.PublicKey.(*rsa.PublicKey), keyBlock)
+            if err != nil {
+                return errors.InvalidArgumentError("RSA encryption failed: " + err.Error())
+            }
+            e.encryptedMPI1 = parsedMPI{cipherText, 8 * uint16(len(cipherText))}
+        case PubKeyAlgoElGamal:
+            c1, c2, err := elgamal.Encrypt(config.Random(), pub.PublicKey.(*elgamal.PublicKey), keyBlock)
+            if err != nil {
+                return errors.InvalidArgumentError("ElGamal encryption failed: " + err.Error())
+            }
+            e.encryptedMPI1 = fromBig(c1)
+            e.encryptedMPI2 = fromBig(c2)
+        case PubKeyAlgoECDH:
+            e.ephemeral, e.encryptedKey, err = ecdhEncrypt(config.Random(), pub, keyBlock)
+            if err != nil {
+                return err
+            }
+            e.encryptedMPI1 = newPointMPI(e.ephemeral)
+        case PubKeyAlgoX25519, PubKeyAlgoX448:
+            e.ephemeral, e.encryptedKey, err = nativeEncrypt(config.Random(), pub, key)
+            if err != nil {
+                return err
+            }
+        case PubKeyAlgoDSA, PubKeyAlgoRSASignOnly, PubKeyAlgoECDSA, PubKeyAlgoEdDSA, PubKeyAlgoEd25519, PubKeyAlgoEd448:
+            return errors.InvalidArgumentError("cannot encrypt to public key of type " + strconv.Itoa(int(pub.PubKeyAlgo)))
+        default:
+            return errors.UnsupportedError("encrypting a key to public key of type " + strconv.Itoa(int(pub.PubKeyAlgo)))
+    }
+
+    return e.Serialize(w)
+}
+
+// ecdhParam returns the KDF parameters of ECDH. See RFC 9580, section 11.5.
+func ecdhParam(pub *PublicKey) []byte {
+    hashId, _ := s2k.HashToHashId(pub.ecdh.KdfHash)
+
+    var param bytes.Buffer
+    param.WriteByte(byte(len(pub.ec.oid)))
+    param.Write(pub.ec.oid)
+    param.WriteByte(byte(PubKeyAlgoECDH))
+    param.Write([]byte{0x03, 0x01, hashId, byte(pub.ecdh.KdfAlgo)})
+    param.WriteString("Anonymous Sender    ")
+    param.Write(pub.Fingerprint)
+
+    return param.Bytes()
+}
+
+// ecdhKEK 从共享密钥派生 KEK
+func ecdhKEK(pub *PublicKey, shared []byte) []byte {
+    h := pub.ecdh.KdfHash.New()
+    h.Write([]byte{0x00, 0x00, 0x00, 0x01})
+    h.Write(shared)
+    h.Write(ecdhParam(pub))
+
+    return h.Sum(nil)[:pub.ecdh.KdfAlgo.KeySize()]
+}
+
+// ecdhPoint 编码 ECDH 公钥点, Curve25519Legacy 带有 0x40 前缀
+func ecdhPoint(pub *ecdh.PublicKey) []byte {
+    if pub.Curve() == ecdh.X25519() {
+        return append([]byte{0x40}, pub.Bytes()...)
+    }
+
+    return pub.Bytes()
+}
+
+func ecdhEncrypt(rand io.Reader, pub *PublicKey, keyBlock []byte) (ephemeral, wrapped []byte, err error) {
+    pubKey := pub.PublicKey.(*ecdh.PublicKey)
+
+    priv, err := pubKey.Curve().GenerateKey(rand)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    shared, err := priv.ECDH(pubKey)
+    if err != nil {
+        return nil, nil, errors.InvalidArgumentError("ECDH failed: " + err.Error())
+    }
+
+    // PKCS5 填充到 8 字节的倍数
+    padding := 8 - len(keyBlock)%8
+    padded := make([]byte, len(keyBlock)+padding)
+    copy(padded, keyBlock)
+    for i := len(keyBlock); i < len(padded); i++ {
+        padded[i] = byte(padding)
+    }
+
+    kek := ecdhKEK(pub, shared)
+    wrapped = make([]byte, len(padded)+8)
+    mode.NewWrapEncrypter(pub.ecdh.KdfAlgo.new(kek), nil).CryptBlocks(wrapped, padded)
+
+    return ecdhPoint(priv.PublicKey()), wrapped, nil
+}
+
+func ecdhDecrypt(pub *PublicKey, priv *ecdh.PrivateKey, ephemeral, wrapped []byte) ([]byte, error) {
+    point := ephemeral
+    if priv.Curve() == ecdh.X25519() {
+        if len(point) != 33 || point[0] != 0x40 {
+            return nil, errors.StructuralError("invalid ECDH ephemeral point")
+        }
+        point = point[1:]
+    }
+
+    ephemeralPub, err := priv.Curve().NewPublicKey(point)
+    if err != nil {
+        return nil, errors.StructuralError("invalid ECDH ephemeral point")
+    }
+
+    shared, err := priv.ECDH(ephemeralPub)
+    if err != nil {
+        return nil, errors.StructuralError("ECDH failed")
+    }
+
+    if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+        return nil, errors.StructuralError("invalid ECDH wrapped key")
+    }
+
+    kek := ecdhKEK(pub, shared)
+    padded := make([]byte, len(wrapped)-8)
+    mode.NewWrapDecrypter(pub.ecdh.KdfAlgo.new(kek), nil).CryptBlocks(padded, wrapped)
+
+    // 发送方可以将会话密钥填充到 40 字节, See RFC 9580, section 11.5.
+    // 解包失败时输出为全零, 填充检查同时覆盖该情况
+    padding := int(padded[len(padded)-1])
+    if padding == 0 || padding > len(padded) {
+        return nil, errors.StructuralError("ECDH session key unwrap failed")
+    }
+    for _, b := range padded[len(padded)-padding:] {
+        if int(b) != padding {
+            return nil, errors.StructuralError("ECDH session key unwrap failed")
+        }
+    }
+
+    return padded[:len(padded)-padding], nil
+}
+
+// nativeKEK 使用 HKDF 派生 X25519 或 X448 的 KEK. See RFC 9580, section 5.1.6.
+func nativeKEK(algo PublicKeyAlgorithm, ephemeral, public, shared []byte) (CipherFunction, []byte, error) {
+    ikm := make([]byte, 0, len(ephemeral)+len(public)+len(shared))
+    ikm = append(ikm, ephemeral...)
+    ikm = append(ikm, public...)
+    ikm = append(ikm, shared...)
+
+    if algo == PubKeyAlgoX448 {
+        kek, err := hkdf.Key(sha512.New, ikm, nil, []byte("OpenPGP X448"), 32)
+        return CipherAES256, kek, err
+    }
+
+    kek, err := hkdf.Key(sha256.New, ikm, nil, []byte("OpenPGP X25519"), 16)
+    return CipherAES128, kek, err
+}
+
+func nativeEncrypt(rand io.Reader, pub *PublicKey, key []byte) (ephemeral, wrapped []byte, err error) {
+    if len(key)%8 != 0 || len(key) < 16 {
+        return nil, nil, errors.InvalidArgumentError("invalid session key length")
+    }
+
+    var shared []byte
+    switch pub.PubKeyAlgo {
+        case PubKeyAlgoX25519:
+            priv, err := ecdh.X25519().GenerateKey(rand)
+            if err != nil {
+                return nil, nil, err
+            }
+            shared, err = priv.ECDH(pub.PublicKey.(*ecdh.PublicKey))
+            if err != nil {
+                return nil, nil, errors.InvalidArgumentError("X25519 failed: " + err.Error())
+            }
+            ephemeral = priv.PublicKey().Bytes()
+        case PubKeyAlgoX448:
+            ephPub, ephPriv, err := x448.GenerateKey(rand)
+            if err != nil {
+                return nil, nil, err
+            }
+            shared, err = x448.X448(ephPriv.Seed(), pub.native)
+            if err != nil {
+                return nil, nil, errors.InvalidArgumentError("X448 failed: " + err.Error())
+            }
+            ephemeral = ephPub
+    }
+
+    cipherFunc, kek, err := nativeKEK(pub.PubKeyAlgo, ephemeral, pub.native, shared)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    wrapped = make([]byte, len(key)+8)
+    mode.NewWrapEncrypter(cipherFunc.new(kek), nil).CryptBlocks(wrapped, key)
+
+    return ephemeral, wrapped, nil
+}
+
+func nativeDecrypt(priv *PrivateKey, ephemeral, wrapped []byte) ([]byte, error) {
+    var shared []byte
+    var err error
+
+    switch priv.PubKeyAlgo {
+        case PubKeyAlgoX25519:
+            var ephemeralPub *ecdh.PublicKey
+            ephemeralPub, err = ecdh.X25519().NewPublicKey(ephemeral)
+            if err != nil {
+                return nil, errors.StructuralError("invalid X25519 ephemeral key")
+            }
+            shared, err = priv.PrivateKey.(*ecdh.PrivateKey).ECDH(ephemeralPub)
+        case PubKeyAlgoX448:
+            shared, err = x448.X448(priv.PrivateKey.(x448.PrivateKey).Seed(), ephemeral)
+    }
+    if err != nil {
+        return nil, errors.StructuralError("key agreement failed")
+    }
+
+    if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+        return nil, errors.StructuralError("invalid wrapped session key")
+    }
+
+    cipherFunc, kek, err := nativeKEK(priv.PubKeyAlgo, ephemeral, priv.native, shared)
+    if err != nil {
+        return nil, err
+    }
+
+    key := make([]byte, len(wrapped)-8)
+    mode.NewWrapDecrypter(cipherFunc.new(kek), nil).CryptBlocks(key, wrapped)
+
+    // 解包失败时输出为全零
+    if subtle.ConstantTimeCompare(key, make([]byte, len(key))) == 1 {
+        return nil, errors.StructuralError("session key unwrap failed")
+    }
+
+    return key, nil
+}
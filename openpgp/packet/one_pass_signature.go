@@ -0,0 +1,120 @@
+package packet
+
+import (
+    "io"
+    "crypto"
+    "strconv"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// OnePassSignature represents a one-pass signature packet. See RFC 9580,
+// section 5.4.
+type OnePassSignature struct {
+    Version    int
+    SigType    SignatureType
+    Hash       crypto.Hash
+    PubKeyAlgo PublicKeyAlgorithm
+    KeyId      uint64
+    IsLast     bool
+    // v6 签名使用
+    Salt           []byte
+    KeyFingerprint []byte
+}
+
+func (ops *OnePassSignature) parse(r io.Reader) (err error) {
+    var buf [8]byte
+
+    _, err = readFull(r, buf[:4])
+    if err != nil {
+        return
+    }
+    if buf[0] != 3 && buf[0] != 6 {
+        return errors.UnsupportedError("one-pass-signature packet version " + strconv.Itoa(int(buf[0])))
+    }
+    ops.Version = int(buf[0])
+
+    var ok bool
+    ops.Hash, ok = s2k.HashIdToHash(buf[2])
+    if !ok {
+        return errors.UnsupportedError("hash function: " + strconv.Itoa(int(buf[2])))
+    }
+
+    ops.SigType = SignatureType(buf[1])
+    ops.PubKeyAlgo = PublicKeyAlgorithm(buf[3])
+
+    if ops.Version == 6 {
+        if _, err = readFull(r, buf[:1]); err != nil {
+            return
+        }
+        ops.Salt = make([]byte, buf[0])
+        if _, err = readFull(r, ops.Salt); err != nil {
+            return
+        }
+        var saltLen int
+        if saltLen, err = saltLength(ops.Hash); err != nil {
+            return
+        }
+        if len(ops.Salt) != saltLen {
+            return errors.StructuralError("one-pass-signature salt length mismatch")
+        }
+
+        ops.KeyFingerprint = make([]byte, 32)
+        if _, err = readFull(r, ops.KeyFingerprint); err != nil {
+            return
+        }
+        ops.KeyId = binary.BigEndian.Uint64(ops.KeyFingerprint[:8])
+    } else {
+        if _, err = readFull(r, buf[:8]); err != nil {
+            return
+        }
+        ops.KeyId = binary.BigEndian.Uint64(buf[:8])
+    }
+
+    if _, err = readFull(r, buf[:1]); err != nil {
+        return
+    }
+    ops.IsLast = buf[0] != 0
+
+    return
+}
+
+// Serialize marshals the given OnePassSignature to w.
+func (ops *OnePassSignature) Serialize(w io.Writer) error {
+    version := ops.Version
+    if version == 0 {
+        version = 3
+    }
+
+    hashId, ok := s2k.HashToHashId(ops.Hash)
+    if !ok {
+        return errors.UnsupportedError("hash type: " + strconv.Itoa(int(ops.Hash)))
+    }
+
+    buf := []byte{byte(version), uint8(ops.SigType), hashId, uint8(ops.PubKeyAlgo)}
+    if version == 6 {
+        if len(ops.KeyFingerprint) != 32 {
+            return errors.InvalidArgumentError("v6 one-pass-signature needs a v6 key fingerprint")
+        }
+
+        buf = append(buf, byte(len(ops.Salt)))
+        buf = append(buf, ops.Salt...)
+        buf = append(buf, ops.KeyFingerprint...)
+    } else {
+        buf = binary.BigEndian.AppendUint64(buf, ops.KeyId)
+    }
+
+    if ops.IsLast {
+        buf = append(buf, 1)
+    } else {
+        buf = append(buf, 0)
+    }
+
+    if err := serializeHeader(w, packetTypeOnePassSignature, len(buf)); err != nil {
+        return err
+    }
+    _, err := w.Write(buf)
+    return err
+}
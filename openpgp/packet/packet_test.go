@@ -0,0 +1,184 @@
+package packet
+
+import (
+    "io"
+    "time"
+    "bytes"
+    "crypto"
+    "testing"
+    "crypto/ecdh"
+    "crypto/rand"
+
+    "github.com/deatil/go-cryptobin/mode"
+)
+
+func Test_OnePassSignature(t *testing.T) {
+    for _, version := range []int{3, 6} {
+        ops := &OnePassSignature{
+            Version:    version,
+            SigType:    SigTypeBinary,
+            Hash:       crypto.SHA256,
+            PubKeyAlgo: PubKeyAlgoEd25519,
+            KeyId:      0x0102030405060708,
+            IsLast:     true,
+        }
+        if version == 6 {
+            ops.Salt = bytes.Repeat([]byte{1}, 16)
+            ops.KeyFingerprint = bytes.Repeat([]byte{2}, 32)
+        }
+
+        var buf bytes.Buffer
+        if err := ops.Serialize(&buf); err != nil {
+            t.Fatal(err)
+        }
+
+        p, err := Read(&buf)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        got, ok := p.(*OnePassSignature)
+        if !ok {
+            t.Fatalf("got %T", p)
+        }
+        if got.Version != version || got.Hash != ops.Hash || !got.IsLast ||
+            !bytes.Equal(got.Salt, ops.Salt) || !bytes.Equal(got.KeyFingerprint, ops.KeyFingerprint) {
+            t.Errorf("v%d: got %+v", version, got)
+        }
+        if version == 3 && got.KeyId != ops.KeyId {
+            t.Errorf("got key id %x", got.KeyId)
+        }
+    }
+}
+
+func Test_Padding(t *testing.T) {
+    var buf bytes.Buffer
+    if err := SerializePadding(&buf, 32, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    p, err := Read(&buf)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if padding, ok := p.(*Padding); !ok || len(padding.Contents) != 32 {
+        t.Errorf("got %T", p)
+    }
+}
+
+func testSymmetricallyEncrypted(t *testing.T, config *Config, tamper bool) {
+    key := bytes.Repeat([]byte{3}, CipherAES256.KeySize())
+    msg := bytes.Repeat([]byte("0123456789"), 100)
+
+    var buf bytes.Buffer
+    w, err := SerializeSymmetricallyEncrypted(&buf, CipherAES256, key, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    w.Write(msg)
+    if err = w.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    data := buf.Bytes()
+    if tamper {
+        data[len(data)/2] ^= 1
+    }
+
+    p, err := Read(bytes.NewReader(data))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    se := p.(*SymmetricallyEncrypted)
+    r, err := se.Decrypt(CipherAES256, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    got, err := io.ReadAll(r)
+    if err == nil {
+        err = r.Close()
+    }
+
+    if tamper {
+        if err == nil {
+            t.Error("tampered ciphertext was accepted")
+        }
+        return
+    }
+
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(got, msg) {
+        t.Error("decrypted data mismatch")
+    }
+}
+
+func Test_SymmetricallyEncrypted(t *testing.T) {
+    configs := map[string]*Config{
+        "v1":  nil,
+        "EAX": &Config{AEADConfig: &AEADConfig{DefaultMode: AEADModeEAX, ChunkSize: 64}},
+        "OCB": &Config{AEADConfig: &AEADConfig{DefaultMode: AEADModeOCB, ChunkSize: 64}},
+        "GCM": &Config{AEADConfig: &AEADConfig{DefaultMode: AEADModeGCM}},
+    }
+
+    for name, config := range configs {
+        t.Run(name, func(t *testing.T) {
+            testSymmetricallyEncrypted(t, config, false)
+            testSymmetricallyEncrypted(t, config, true)
+        })
+    }
+}
+
+// OpenPGP.js 等实现会将会话密钥填充到 40 字节
+func Test_ECDHPadding(t *testing.T) {
+    priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    pk := NewECDHPrivateKey(time.Now(), priv)
+
+    ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    shared, err := ephemeral.ECDH(priv.PublicKey())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    wrap := func(padded []byte) []byte {
+        kek := ecdhKEK(&pk.PublicKey, shared)
+        wrapped := make([]byte, len(padded)+8)
+        mode.NewWrapEncrypter(pk.PublicKey.ecdh.KdfAlgo.new(kek), nil).CryptBlocks(wrapped, padded)
+        return wrapped
+    }
+
+    // 算法 + AES-128 密钥 + 校验和, 共 19 字节
+    keyBlock := bytes.Repeat([]byte{7}, 19)
+    point := ecdhPoint(ephemeral.PublicKey())
+
+    for _, size := range []int{24, 40} {
+        padded := append([]byte(nil), keyBlock...)
+        for len(padded) < size {
+            padded = append(padded, byte(size-len(keyBlock)))
+        }
+
+        got, err := ecdhDecrypt(&pk.PublicKey, priv, point, wrap(padded))
+        if err != nil {
+            t.Fatalf("padded to %d: %s", size, err)
+        }
+        if !bytes.Equal(got, keyBlock) {
+            t.Errorf("padded to %d: got %x", size, got)
+        }
+    }
+
+    // 填充字节不一致
+    padded := append(append([]byte(nil), keyBlock...), bytes.Repeat([]byte{21}, 21)...)
+    padded[len(keyBlock)] = 20
+    if _, err := ecdhDecrypt(&pk.PublicKey, priv, point, wrap(padded)); err == nil {
+        t.Error("bad padding was accepted")
+    }
+}
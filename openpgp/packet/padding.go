@@ -0,0 +1,63 @@
+package packet
+
+import (
+    "io"
+    "bytes"
+
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// Marker represents a marker packet, which is ignored on reading.
+// See RFC 9580, section 5.8.
+type Marker struct{}
+
+func (m *Marker) parse(r io.Reader) error {
+    var buf [3]byte
+    if _, err := readFull(r, buf[:]); err != nil {
+        return err
+    }
+    if !bytes.Equal(buf[:], []byte("PGP")) {
+        return errors.StructuralError("invalid marker packet")
+    }
+
+    _, err := consumeAll(r)
+    return err
+}
+
+// Trust represents a trust packet, which is implementation specific and
+// ignored on reading. See RFC 9580, section 5.10.
+type Trust struct {
+    Contents []byte
+}
+
+func (t *Trust) parse(r io.Reader) (err error) {
+    t.Contents, err = io.ReadAll(r)
+    return
+}
+
+// Padding represents a padding packet, whose contents are random and
+// ignored. See RFC 9580, section 5.14.
+type Padding struct {
+    Contents []byte
+}
+
+func (p *Padding) parse(r io.Reader) (err error) {
+    p.Contents, err = io.ReadAll(r)
+    return
+}
+
+// SerializePadding writes a padding packet with length random bytes to w.
+// If config is nil, sensible defaults will be used.
+func SerializePadding(w io.Writer, length int, config *Config) (err error) {
+    contents := make([]byte, length)
+    if _, err = io.ReadFull(config.Random(), contents); err != nil {
+        return
+    }
+
+    if err = serializeHeader(w, packetTypePadding, length); err != nil {
+        return
+    }
+
+    _, err = w.Write(contents)
+    return
+}
@@ -0,0 +1,802 @@
+package packet
+
+import (
+    "io"
+    "time"
+    "bytes"
+    "crypto"
+    "strconv"
+    "math/big"
+    "crypto/dsa"
+    "crypto/rsa"
+    "crypto/ecdh"
+    "crypto/sha1"
+    "crypto/ecdsa"
+    "crypto/cipher"
+    "crypto/subtle"
+    "crypto/ed25519"
+
+    "github.com/deatil/go-cryptobin/pubkey/x448"
+    "github.com/deatil/go-cryptobin/pubkey/ed448"
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+    "github.com/deatil/go-cryptobin/pubkey/elgamal"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// S2K usage octets. See RFC 9580, section 3.7.2.1.
+const (
+    s2kUsageNone        = 0
+    s2kUsageAEAD        = 253
+    s2kUsageCFB         = 254
+    s2kUsageMalleableCFB = 255
+)
+
+// PrivateKey represents a possibly encrypted private key. See RFC 9580,
+// section 5.5.3.
+type PrivateKey struct {
+    PublicKey
+    Encrypted     bool // if true then the private key is unavailable until Decrypt has been called.
+    encryptedData []byte
+    cipher        CipherFunction
+    aead          AEADMode
+    s2k           func(out, in []byte)
+    s2kParams     *s2k.Params
+    s2kUsage      uint8
+    // *rsa.PrivateKey, *dsa.PrivateKey, *elgamal.PrivateKey, *ecdsa.PrivateKey,
+    // *ecdh.PrivateKey, ed25519.PrivateKey, ed448.PrivateKey, x448.PrivateKey
+    // or crypto.Signer/crypto.Decrypter (Decryptor RSA only).
+    PrivateKey    interface{}
+    iv            []byte
+}
+
+func NewRSAPrivateKey(creationTime time.Time, priv *rsa.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewRSAPublicKey(creationTime, &priv.PublicKey)
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewDSAPrivateKey(creationTime time.Time, priv *dsa.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewDSAPublicKey(creationTime, &priv.PublicKey)
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewElGamalPrivateKey(creationTime time.Time, priv *elgamal.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewElGamalPublicKey(creationTime, &priv.PublicKey)
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewECDSAPrivateKey(creationTime time.Time, priv *ecdsa.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewECDSAPublicKey(creationTime, &priv.PublicKey)
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewECDHPrivateKey(creationTime time.Time, priv *ecdh.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewECDHPublicKey(creationTime, priv.PublicKey())
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewEdDSAPrivateKey(creationTime time.Time, priv ed25519.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewEdDSAPublicKey(creationTime, priv.Public().(ed25519.PublicKey))
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewX25519PrivateKey(creationTime time.Time, priv *ecdh.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewX25519PublicKey(creationTime, priv.PublicKey())
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewX448PrivateKey(creationTime time.Time, priv x448.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewX448PublicKey(creationTime, priv.Public().(x448.PublicKey))
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewEd25519PrivateKey(creationTime time.Time, priv ed25519.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewEd25519PublicKey(creationTime, priv.Public().(ed25519.PublicKey))
+    pk.PrivateKey = priv
+    return pk
+}
+
+func NewEd448PrivateKey(creationTime time.Time, priv ed448.PrivateKey) *PrivateKey {
+    pk := new(PrivateKey)
+    pk.PublicKey = *NewEd448PublicKey(creationTime, priv.Public().(ed448.PublicKey))
+    pk.PrivateKey = priv
+    return pk
+}
+
+// NewSignerPrivateKey creates a PrivateKey from a crypto.Signer that
+// implements RSA or ECDSA.
+func NewSignerPrivateKey(creationTime time.Time, signer crypto.Signer) *PrivateKey {
+    pk := new(PrivateKey)
+    // In general, the public Keys should be used as pointers. We still
+    // type-switch on the values, for backwards-compatibility.
+    switch pubkey := signer.Public().(type) {
+        case *rsa.PublicKey:
+            pk.PublicKey = *NewRSAPublicKey(creationTime, pubkey)
+        case rsa.PublicKey:
+            pk.PublicKey = *NewRSAPublicKey(creationTime, &pubkey)
+        case *ecdsa.PublicKey:
+            pk.PublicKey = *NewECDSAPublicKey(creationTime, pubkey)
+        case ecdsa.PublicKey:
+            pk.PublicKey = *NewECDSAPublicKey(creationTime, &pubkey)
+        default:
+            panic("go-cryptobin/openpgp: unknown crypto.Signer type in NewSignerPrivateKey")
+    }
+    pk.PrivateKey = signer
+    return pk
+}
+
+// Dummy returns true if the private key is a GNU dummy key, which
+// carries no secret key material.
+func (pk *PrivateKey) Dummy() bool {
+    return pk.s2kParams != nil && pk.s2kParams.Dummy()
+}
+
+func (pk *PrivateKey) parse(r io.Reader) (err error) {
+    err = (&pk.PublicKey).parse(r)
+    if err != nil {
+        return
+    }
+    var buf [1]byte
+    _, err = readFull(r, buf[:])
+    if err != nil {
+        return
+    }
+
+    pk.s2kUsage = buf[0]
+
+    switch pk.s2kUsage {
+        case s2kUsageNone:
+            pk.s2k = nil
+            pk.Encrypted = false
+        case s2kUsageAEAD, s2kUsageCFB, s2kUsageMalleableCFB:
+            if pk.Version == 6 {
+                if pk.s2kUsage == s2kUsageMalleableCFB {
+                    return errors.StructuralError("v6 private key with malleable CFB")
+                }
+
+                // 后续字段的长度, 不需要
+                if _, err = readFull(r, buf[:]); err != nil {
+                    return
+                }
+            }
+
+            _, err = readFull(r, buf[:])
+            if err != nil {
+                return
+            }
+            pk.cipher = CipherFunction(buf[0])
+
+            if pk.s2kUsage == s2kUsageAEAD {
+                if _, err = readFull(r, buf[:]); err != nil {
+                    return
+                }
+                pk.aead = AEADMode(buf[0])
+                if !pk.aead.IsSupported() {
+                    return errors.UnsupportedError("unsupported AEAD mode in private key: " + strconv.Itoa(int(pk.aead)))
+                }
+            }
+
+            s2kReader := r
+            if pk.Version == 6 {
+                if _, err = readFull(r, buf[:]); err != nil {
+                    return
+                }
+                s2kReader = &spanReader{r, int64(buf[0])}
+            }
+
+            pk.s2kParams, err = s2k.ParseIntoParams(s2kReader)
+            if err != nil {
+                return
+            }
+            if pk.Dummy() {
+                // GNU dummy 密钥没有私钥数据
+                pk.encryptedData, err = io.ReadAll(r)
+                return
+            }
+
+            pk.s2k, err = pk.s2kParams.Function()
+            if err != nil {
+                return
+            }
+            pk.Encrypted = true
+        default:
+            return errors.UnsupportedError("deprecated s2k function in private key")
+    }
+
+    if pk.Encrypted {
+        ivSize := pk.cipher.blockSize()
+        if pk.s2kUsage == s2kUsageAEAD {
+            ivSize = pk.aead.NonceLength()
+        }
+        if pk.cipher.blockSize() == 0 {
+            return errors.UnsupportedError("unsupported cipher in private key: " + strconv.Itoa(int(pk.cipher)))
+        }
+        pk.iv = make([]byte, ivSize)
+        _, err = readFull(r, pk.iv)
+        if err != nil {
+            return
+        }
+    }
+
+    pk.encryptedData, err = io.ReadAll(r)
+    if err != nil {
+        return
+    }
+
+    if !pk.Encrypted {
+        data := pk.encryptedData
+        if pk.Version != 6 {
+            // v4 未加密私钥带有 2 字节校验和
+            if len(data) < 2 {
+                return errors.StructuralError("truncated private key data")
+            }
+            checksum := mod64kHash(data[:len(data)-2])
+            if data[len(data)-2] != uint8(checksum>>8) ||
+                data[len(data)-1] != uint8(checksum) {
+                return errors.StructuralError("private key checksum failure")
+            }
+            data = data[:len(data)-2]
+        }
+
+        return pk.parsePrivateKey(data)
+    }
+
+    return
+}
+
+func mod64kHash(d []byte) uint16 {
+    var h uint16
+    for _, b := range d {
+        h += uint16(b)
+    }
+    return h
+}
+
+// serializeS2K writes the S2K usage and its parameters.
+func (pk *PrivateKey) serializeS2K(w *bytes.Buffer) (err error) {
+    w.WriteByte(pk.s2kUsage)
+    if pk.s2kUsage == s2kUsageNone {
+        return nil
+    }
+
+    var s2kBuf bytes.Buffer
+    if err = pk.s2kParams.Serialize(&s2kBuf); err != nil {
+        return
+    }
+
+    var fields bytes.Buffer
+    fields.WriteByte(byte(pk.cipher))
+    if pk.s2kUsage == s2kUsageAEAD {
+        fields.WriteByte(byte(pk.aead))
+    }
+    if pk.Version == 6 {
+        fields.WriteByte(byte(s2kBuf.Len()))
+    }
+    fields.Write(s2kBuf.Bytes())
+    if !pk.Dummy() {
+        fields.Write(pk.iv)
+    }
+
+    if pk.Version == 6 {
+        w.WriteByte(byte(fields.Len()))
+    }
+    w.Write(fields.Bytes())
+
+    return nil
+}
+
+func (pk *PrivateKey) Serialize(w io.Writer) (err error) {
+    buf := bytes.NewBuffer(nil)
+    err = pk.PublicKey.serializeWithoutHeaders(buf)
+    if err != nil {
+        return
+    }
+
+    var privateKeyBytes []byte
+    if pk.Encrypted || pk.Dummy() {
+        if err = pk.serializeS2K(buf); err != nil {
+            return
+        }
+
+        privateKeyBytes = pk.encryptedData
+    } else {
+        buf.WriteByte(s2kUsageNone)
+
+        privateKeyBuf := bytes.NewBuffer(nil)
+        if err = pk.serializePrivateKey(privateKeyBuf); err != nil {
+            return
+        }
+
+        privateKeyBytes = privateKeyBuf.Bytes()
+        if pk.Version != 6 {
+            checksum := mod64kHash(privateKeyBytes)
+            privateKeyBytes = append(privateKeyBytes, byte(checksum>>8), byte(checksum))
+        }
+    }
+
+    ptype := packetTypePrivateKey
+    contents := buf.Bytes()
+    if pk.IsSubkey {
+        ptype = packetTypePrivateSubkey
+    }
+    err = serializeHeader(w, ptype, len(contents)+len(privateKeyBytes))
+    if err != nil {
+        return
+    }
+    _, err = w.Write(contents)
+    if err != nil {
+        return
+    }
+    _, err = w.Write(privateKeyBytes)
+
+    return
+}
+
+// serializePrivateKey writes the algorithm-specific secret key material.
+func (pk *PrivateKey) serializePrivateKey(w io.Writer) (err error) {
+    switch priv := pk.PrivateKey.(type) {
+        case *rsa.PrivateKey:
+            err = serializeRSAPrivateKey(w, priv)
+        case *dsa.PrivateKey:
+            err = writeBig(w, priv.X)
+        case *elgamal.PrivateKey:
+            err = writeBig(w, priv.X)
+        case *ecdsa.PrivateKey:
+            err = writeBig(w, priv.D)
+        case *ecdh.PrivateKey:
+            if pk.PubKeyAlgo == PubKeyAlgoX25519 {
+                _, err = w.Write(priv.Bytes())
+                return
+            }
+
+            d := priv.Bytes()
+            if priv.Curve() == ecdh.X25519() {
+                // Curve25519Legacy 私钥以大端序存储
+                d = reverseBytes(d)
+            }
+            err = writeBig(w, new(big.Int).SetBytes(d))
+        case ed25519.PrivateKey:
+            if pk.PubKeyAlgo == PubKeyAlgoEdDSA {
+                err = writeBig(w, new(big.Int).SetBytes(priv.Seed()))
+                return
+            }
+            _, err = w.Write(priv.Seed())
+        case x448.PrivateKey:
+            _, err = w.Write(priv.Seed())
+        case ed448.PrivateKey:
+            _, err = w.Write(priv[:ed448.SeedSize])
+        default:
+            err = errors.InvalidArgumentError("unknown private key type")
+    }
+
+    return
+}
+
+func serializeRSAPrivateKey(w io.Writer, priv *rsa.PrivateKey) error {
+    err := writeBig(w, priv.D)
+    if err != nil {
+        return err
+    }
+    err = writeBig(w, priv.Primes[1])
+    if err != nil {
+        return err
+    }
+    err = writeBig(w, priv.Primes[0])
+    if err != nil {
+        return err
+    }
+    return writeBig(w, priv.Precomputed.Qinv)
+}
+
+// aeadInfo returns the packet header octet, version, cipher and AEAD mode,
+// used as HKDF info of S2K usage 253.
+func (pk *PrivateKey) aeadInfo() []byte {
+    tag := byte(0xC0 | packetTypePrivateKey)
+    if pk.IsSubkey {
+        tag = byte(0xC0 | packetTypePrivateSubkey)
+    }
+    return []byte{tag, byte(pk.Version), byte(pk.cipher), byte(pk.aead)}
+}
+
+// aeadAdditionalData returns the packet header octet and the public key
+// packet body.
+func (pk *PrivateKey) aeadAdditionalData() []byte {
+    var ad bytes.Buffer
+    ad.WriteByte(pk.aeadInfo()[0])
+    pk.PublicKey.serializeWithoutHeaders(&ad)
+    return ad.Bytes()
+}
+
+// newSecretKeyAEAD returns the AEAD used to protect the secret key material.
+func (pk *PrivateKey) newSecretKeyAEAD(key []byte) (cipher.AEAD, error) {
+    kek, err := deriveAEADKey(key, nil, pk.aeadInfo(), pk.cipher.KeySize())
+    if err != nil {
+        return nil, err
+    }
+
+    return pk.aead.new(pk.cipher.new(kek))
+}
+
+// Encrypt encrypts an unencrypted private key using a passphrase.
+func (pk *PrivateKey) Encrypt(passphrase []byte) error {
+    return pk.EncryptWithConfig(passphrase, nil)
+}
+
+// EncryptWithConfig encrypts an unencrypted private key using a passphrase.
+// v6 keys, or keys with config.AEADConfig set, are protected with AEAD
+// (S2K usage 253). Otherwise CFB with a SHA-1 check is used (S2K usage 254).
+func (pk *PrivateKey) EncryptWithConfig(passphrase []byte, config *Config) (err error) {
+    if pk.Encrypted {
+        return nil
+    }
+    if pk.PrivateKey == nil {
+        return errors.InvalidArgumentError("no private key to encrypt")
+    }
+
+    privateKeyBuf := bytes.NewBuffer(nil)
+    if err = pk.serializePrivateKey(privateKeyBuf); err != nil {
+        return
+    }
+    data := privateKeyBuf.Bytes()
+
+    pk.s2kParams, err = s2k.Generate(config.Random(), config.S2K())
+    if err != nil {
+        return
+    }
+    pk.s2k, err = pk.s2kParams.Function()
+    if err != nil {
+        return
+    }
+
+    pk.cipher = config.Cipher()
+    if pk.cipher.blockSize() == 0 {
+        return errors.InvalidArgumentError("unknown cipher")
+    }
+
+    key := make([]byte, pk.cipher.KeySize())
+    pk.s2k(key, passphrase)
+
+    if config.AEAD() != nil || pk.Version == 6 {
+        pk.s2kUsage = s2kUsageAEAD
+        pk.aead = config.AEAD().Mode()
+
+        aead, err := pk.newSecretKeyAEAD(key)
+        if err != nil {
+            return err
+        }
+
+        pk.iv = make([]byte, pk.aead.NonceLength())
+        if _, err = io.ReadFull(config.Random(), pk.iv); err != nil {
+            return err
+        }
+
+        pk.encryptedData = aead.Seal(nil, pk.iv, data, pk.aeadAdditionalData())
+    } else {
+        pk.s2kUsage = s2kUsageCFB
+
+        pk.iv = make([]byte, pk.cipher.blockSize())
+        if _, err = io.ReadFull(config.Random(), pk.iv); err != nil {
+            return err
+        }
+
+        h := sha1.New()
+        h.Write(data)
+        data = h.Sum(data)
+
+        pk.encryptedData = make([]byte, len(data))
+        cfb := cipher.NewCFBEncrypter(pk.cipher.new(key), pk.iv)
+        cfb.XORKeyStream(pk.encryptedData, data)
+    }
+
+    pk.Encrypted = true
+    pk.PrivateKey = nil
+
+    return nil
+}
+
+// Decrypt decrypts an encrypted private key using a passphrase.
+func (pk *PrivateKey) Decrypt(passphrase []byte) error {
+    if pk.Dummy() {
+        return errors.ErrDummyPrivateKey("dummy key found")
+    }
+    if !pk.Encrypted {
+        return nil
+    }
+
+    key := make([]byte, pk.cipher.KeySize())
+    pk.s2k(key, passphrase)
+
+    var data []byte
+    switch pk.s2kUsage {
+        case s2kUsageAEAD:
+            aead, err := pk.newSecretKeyAEAD(key)
+            if err != nil {
+                return err
+            }
+
+            data, err = aead.Open(nil, pk.iv, pk.encryptedData, pk.aeadAdditionalData())
+            if err != nil {
+                return errors.ErrKeyIncorrect
+            }
+        case s2kUsageCFB:
+            data = make([]byte, len(pk.encryptedData))
+            cfb := cipher.NewCFBDecrypter(pk.cipher.new(key), pk.iv)
+            cfb.XORKeyStream(data, pk.encryptedData)
+
+            if len(data) < sha1.Size {
+                return errors.StructuralError("truncated private key data")
+            }
+            h := sha1.New()
+            h.Write(data[:len(data)-sha1.Size])
+            sum := h.Sum(nil)
+            if subtle.ConstantTimeCompare(sum, data[len(data)-sha1.Size:]) != 1 {
+                return errors.StructuralError("private key checksum failure")
+            }
+            data = data[:len(data)-sha1.Size]
+        default:
+            data = make([]byte, len(pk.encryptedData))
+            cfb := cipher.NewCFBDecrypter(pk.cipher.new(key), pk.iv)
+            cfb.XORKeyStream(data, pk.encryptedData)
+
+            if len(data) < 2 {
+                return errors.StructuralError("truncated private key data")
+            }
+            sum := mod64kHash(data[:len(data)-2])
+            if data[len(data)-2] != uint8(sum>>8) ||
+                data[len(data)-1] != uint8(sum) {
+                return errors.StructuralError("private key checksum failure")
+            }
+            data = data[:len(data)-2]
+    }
+
+    return pk.parsePrivateKey(data)
+}
+
+func (pk *PrivateKey) parsePrivateKey(data []byte) (err error) {
+    switch pk.PublicKey.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSASignOnly, PubKeyAlgoRSAEncryptOnly:
+            err = pk.parseRSAPrivateKey(data)
+        case PubKeyAlgoDSA:
+            err = pk.parseDSAPrivateKey(data)
+        case PubKeyAlgoElGamal:
+            err = pk.parseElGamalPrivateKey(data)
+        case PubKeyAlgoECDSA:
+            err = pk.parseECDSAPrivateKey(data)
+        case PubKeyAlgoECDH:
+            err = pk.parseECDHPrivateKey(data)
+        case PubKeyAlgoEdDSA:
+            err = pk.parseEdDSAPrivateKey(data)
+        case PubKeyAlgoX25519, PubKeyAlgoX448, PubKeyAlgoEd25519, PubKeyAlgoEd448:
+            err = pk.parseNativePrivateKey(data)
+        default:
+            err = errors.UnsupportedError("public key type: " + strconv.Itoa(int(pk.PubKeyAlgo)))
+    }
+    if err != nil {
+        return
+    }
+
+    pk.Encrypted = false
+    pk.encryptedData = nil
+
+    return nil
+}
+
+func (pk *PrivateKey) parseRSAPrivateKey(data []byte) (err error) {
+    rsaPub := pk.PublicKey.PublicKey.(*rsa.PublicKey)
+    rsaPriv := new(rsa.PrivateKey)
+    rsaPriv.PublicKey = *rsaPub
+
+    buf := bytes.NewBuffer(data)
+    d, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+    p, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+    q, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+
+    rsaPriv.D = new(big.Int).SetBytes(d)
+    rsaPriv.Primes = make([]*big.Int, 2)
+    rsaPriv.Primes[0] = new(big.Int).SetBytes(p)
+    rsaPriv.Primes[1] = new(big.Int).SetBytes(q)
+    if err := rsaPriv.Validate(); err != nil {
+        return err
+    }
+    rsaPriv.Precompute()
+    pk.PrivateKey = rsaPriv
+
+    return nil
+}
+
+func (pk *PrivateKey) parseDSAPrivateKey(data []byte) (err error) {
+    dsaPub := pk.PublicKey.PublicKey.(*dsa.PublicKey)
+    dsaPriv := new(dsa.PrivateKey)
+    dsaPriv.PublicKey = *dsaPub
+
+    buf := bytes.NewBuffer(data)
+    x, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+
+    dsaPriv.X = new(big.Int).SetBytes(x)
+    pk.PrivateKey = dsaPriv
+
+    return nil
+}
+
+func (pk *PrivateKey) parseElGamalPrivateKey(data []byte) (err error) {
+    pub := pk.PublicKey.PublicKey.(*elgamal.PublicKey)
+    priv := new(elgamal.PrivateKey)
+    priv.PublicKey = *pub
+
+    buf := bytes.NewBuffer(data)
+    x, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+
+    priv.X = new(big.Int).SetBytes(x)
+    pk.PrivateKey = priv
+
+    return nil
+}
+
+func (pk *PrivateKey) parseECDSAPrivateKey(data []byte) (err error) {
+    ecdsaPub := pk.PublicKey.PublicKey.(*ecdsa.PublicKey)
+
+    buf := bytes.NewBuffer(data)
+    d, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+
+    pk.PrivateKey = &ecdsa.PrivateKey{
+        PublicKey: *ecdsaPub,
+        D:         new(big.Int).SetBytes(d),
+    }
+
+    return nil
+}
+
+func (pk *PrivateKey) parseECDHPrivateKey(data []byte) (err error) {
+    pub := pk.PublicKey.PublicKey.(*ecdh.PublicKey)
+
+    buf := bytes.NewBuffer(data)
+    d, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+
+    var size int
+    switch pub.Curve() {
+        case ecdh.X25519():
+            size = 32
+        case ecdh.P256():
+            size = 32
+        case ecdh.P384():
+            size = 48
+        case ecdh.P521():
+            size = 66
+    }
+    if len(d) > size {
+        return errors.StructuralError("ECDH private key too long")
+    }
+
+    scalar := make([]byte, size)
+    copy(scalar[size-len(d):], d)
+    if pub.Curve() == ecdh.X25519() {
+        // Curve25519Legacy 私钥以大端序存储
+        scalar = reverseBytes(scalar)
+    }
+
+    priv, err := pub.Curve().NewPrivateKey(scalar)
+    if err != nil {
+        return errors.StructuralError("invalid ECDH private key")
+    }
+    if !priv.PublicKey().Equal(pub) {
+        return errors.StructuralError("ECDH private key does not match public key")
+    }
+
+    pk.PrivateKey = priv
+
+    return nil
+}
+
+func (pk *PrivateKey) parseEdDSAPrivateKey(data []byte) (err error) {
+    pub := pk.PublicKey.PublicKey.(ed25519.PublicKey)
+
+    buf := bytes.NewBuffer(data)
+    d, _, err := readMPI(buf)
+    if err != nil {
+        return
+    }
+    if len(d) > ed25519.SeedSize {
+        return errors.StructuralError("EdDSA private key too long")
+    }
+
+    seed := make([]byte, ed25519.SeedSize)
+    copy(seed[ed25519.SeedSize-len(d):], d)
+
+    priv := ed25519.NewKeyFromSeed(seed)
+    if !pub.Equal(priv.Public()) {
+        return errors.StructuralError("EdDSA private key does not match public key")
+    }
+
+    pk.PrivateKey = priv
+
+    return nil
+}
+
+func (pk *PrivateKey) parseNativePrivateKey(data []byte) (err error) {
+    var size int
+    switch pk.PubKeyAlgo {
+        case PubKeyAlgoX25519:
+            size = 32
+        case PubKeyAlgoX448:
+            size = x448.SeedSize
+        case PubKeyAlgoEd25519:
+            size = ed25519.SeedSize
+        case PubKeyAlgoEd448:
+            size = ed448.SeedSize
+    }
+    if len(data) < size {
+        return errors.StructuralError("truncated private key data")
+    }
+    seed := data[:size]
+
+    var public []byte
+    switch pk.PubKeyAlgo {
+        case PubKeyAlgoX25519:
+            priv, err := ecdh.X25519().NewPrivateKey(seed)
+            if err != nil {
+                return errors.StructuralError("invalid X25519 private key")
+            }
+            pk.PrivateKey, public = priv, priv.PublicKey().Bytes()
+        case PubKeyAlgoX448:
+            priv := x448.NewKeyFromSeed(seed)
+            pk.PrivateKey, public = priv, priv[x448.SeedSize:]
+        case PubKeyAlgoEd25519:
+            priv := ed25519.NewKeyFromSeed(seed)
+            pk.PrivateKey, public = priv, priv[ed25519.SeedSize:]
+        case PubKeyAlgoEd448:
+            priv := ed448.NewKeyFromSeed(seed)
+            pk.PrivateKey, public = priv, priv[ed448.SeedSize:]
+    }
+
+    if subtle.ConstantTimeCompare(public, pk.native) != 1 {
+        pk.PrivateKey = nil
+        return errors.StructuralError("private key does not match public key")
+    }
+
+    return nil
+}
+
+func reverseBytes(b []byte) []byte {
+    r := make([]byte, len(b))
+    for i := range b {
+        r[len(b)-1-i] = b[i]
+    }
+    return r
+}
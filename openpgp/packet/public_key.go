@@ -0,0 +1,990 @@
+package packet
+
+import (
+    "io"
+    "fmt"
+    "hash"
+    "time"
+    "bytes"
+    "strconv"
+    "math/big"
+    "crypto"
+    "crypto/dsa"
+    "crypto/rsa"
+    "crypto/ecdh"
+    "crypto/sha1"
+    "crypto/ecdsa"
+    "crypto/sha256"
+    "crypto/ed25519"
+    "crypto/elliptic"
+    "encoding/binary"
+    _ "crypto/sha512"
+
+    "github.com/deatil/go-cryptobin/pubkey/x448"
+    "github.com/deatil/go-cryptobin/pubkey/ed448"
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+    "github.com/deatil/go-cryptobin/pubkey/elgamal"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+var (
+    // NIST curve P-256
+    oidCurveP256 []byte = []byte{0x2A, 0x86, 0x48, 0xCE, 0x3D, 0x03, 0x01, 0x07}
+    // NIST curve P-384
+    oidCurveP384 []byte = []byte{0x2B, 0x81, 0x04, 0x00, 0x22}
+    // NIST curve P-521
+    oidCurveP521 []byte = []byte{0x2B, 0x81, 0x04, 0x00, 0x23}
+    // Curve25519Legacy, 只用于 ECDH
+    oidCurve25519 []byte = []byte{0x2B, 0x06, 0x01, 0x04, 0x01, 0x97, 0x55, 0x01, 0x05, 0x01}
+    // Ed25519Legacy, 只用于 EdDSALegacy
+    oidEd25519 []byte = []byte{0x2B, 0x06, 0x01, 0x04, 0x01, 0xDA, 0x47, 0x0F, 0x01}
+)
+
+const maxOIDLength = 10
+
+// ecKey stores the algorithm-specific fields for ECDSA, ECDH and
+// EdDSALegacy keys as defined in RFC 9580, Section 5.5.5.
+type ecKey struct {
+    // oid contains the OID byte sequence identifying the elliptic curve used
+    oid []byte
+    // p contains the elliptic curve point that represents the public key
+    p parsedMPI
+}
+
+// parseOID reads the OID for the curve as defined in RFC 6637, Section 9.
+func parseOID(r io.Reader) (oid []byte, err error) {
+    buf := make([]byte, maxOIDLength)
+    if _, err = readFull(r, buf[:1]); err != nil {
+        return
+    }
+    oidLen := buf[0]
+    if oidLen == 0 || int(oidLen) > len(buf) {
+        err = errors.UnsupportedError("invalid oid length: " + strconv.Itoa(int(oidLen)))
+        return
+    }
+    oid = buf[:oidLen]
+    _, err = readFull(r, oid)
+    return
+}
+
+func (f *ecKey) parse(r io.Reader) (err error) {
+    if f.oid, err = parseOID(r); err != nil {
+        return err
+    }
+    f.p.bytes, f.p.bitLength, err = readMPI(r)
+    return
+}
+
+func (f *ecKey) serialize(w io.Writer) (err error) {
+    buf := make([]byte, maxOIDLength+1)
+    buf[0] = byte(len(f.oid))
+    copy(buf[1:], f.oid)
+    if _, err = w.Write(buf[:len(f.oid)+1]); err != nil {
+        return
+    }
+    return writeMPIs(w, f.p)
+}
+
+func (f *ecKey) curve() (elliptic.Curve, error) {
+    switch {
+        case bytes.Equal(f.oid, oidCurveP256):
+            return elliptic.P256(), nil
+        case bytes.Equal(f.oid, oidCurveP384):
+            return elliptic.P384(), nil
+        case bytes.Equal(f.oid, oidCurveP521):
+            return elliptic.P521(), nil
+    }
+
+    return nil, errors.UnsupportedError(fmt.Sprintf("unsupported oid: %x", f.oid))
+}
+
+func (f *ecKey) newECDSA() (*ecdsa.PublicKey, error) {
+    c, err := f.curve()
+    if err != nil {
+        return nil, err
+    }
+    x, y := elliptic.Unmarshal(c, f.p.bytes)
+    if x == nil {
+        return nil, errors.UnsupportedError("failed to parse EC point")
+    }
+    return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
+func (f *ecKey) newECDH() (*ecdh.PublicKey, error) {
+    var c ecdh.Curve
+    point := f.p.bytes
+
+    switch {
+        case bytes.Equal(f.oid, oidCurve25519):
+            // 公钥点带有 0x40 前缀
+            if len(point) != 33 || point[0] != 0x40 {
+                return nil, errors.UnsupportedError("failed to parse Curve25519 point")
+            }
+            c, point = ecdh.X25519(), point[1:]
+        case bytes.Equal(f.oid, oidCurveP256):
+            c = ecdh.P256()
+        case bytes.Equal(f.oid, oidCurveP384):
+            c = ecdh.P384()
+        case bytes.Equal(f.oid, oidCurveP521):
+            c = ecdh.P521()
+        default:
+            return nil, errors.UnsupportedError(fmt.Sprintf("unsupported oid: %x", f.oid))
+    }
+
+    pub, err := c.NewPublicKey(point)
+    if err != nil {
+        return nil, errors.UnsupportedError("failed to parse EC point")
+    }
+
+    return pub, nil
+}
+
+func (f *ecKey) newEdDSA() (ed25519.PublicKey, error) {
+    if !bytes.Equal(f.oid, oidEd25519) {
+        return nil, errors.UnsupportedError(fmt.Sprintf("unsupported oid: %x", f.oid))
+    }
+
+    point := f.p.bytes
+    if len(point) != 1+ed25519.PublicKeySize || point[0] != 0x40 {
+        return nil, errors.UnsupportedError("failed to parse Ed25519 point")
+    }
+
+    return ed25519.PublicKey(append([]byte{}, point[1:]...)), nil
+}
+
+func (f *ecKey) bitLength() uint16 {
+    switch {
+        case bytes.Equal(f.oid, oidCurveP256):
+            return 256
+        case bytes.Equal(f.oid, oidCurveP384):
+            return 384
+        case bytes.Equal(f.oid, oidCurveP521):
+            return 521
+    }
+
+    return 255
+}
+
+func (f *ecKey) byteLen() int {
+    return 1 + len(f.oid) + 2 + len(f.p.bytes)
+}
+
+// ecdhKdf stores key derivation function parameters
+// used for ECDH encryption. See RFC 9580, Section 5.5.5.6.
+type ecdhKdf struct {
+    KdfHash crypto.Hash
+    KdfAlgo CipherFunction
+}
+
+func (f *ecdhKdf) parse(r io.Reader) (err error) {
+    buf := make([]byte, 1)
+    if _, err = readFull(r, buf); err != nil {
+        return
+    }
+    kdfLen := int(buf[0])
+    if kdfLen < 3 {
+        return errors.UnsupportedError("Unsupported ECDH KDF length: " + strconv.Itoa(kdfLen))
+    }
+    buf = make([]byte, kdfLen)
+    if _, err = readFull(r, buf); err != nil {
+        return
+    }
+    reserved := int(buf[0])
+    if reserved != 0x01 {
+        return errors.UnsupportedError("Unsupported KDF reserved field: " + strconv.Itoa(reserved))
+    }
+
+    var ok bool
+    if f.KdfHash, ok = s2k.HashIdToHash(buf[1]); !ok {
+        return errors.UnsupportedError("Unsupported ECDH KDF hash: " + strconv.Itoa(int(buf[1])))
+    }
+
+    f.KdfAlgo = CipherFunction(buf[2])
+    switch f.KdfAlgo {
+        case CipherAES128, CipherAES192, CipherAES256:
+        default:
+            return errors.UnsupportedError("Unsupported ECDH KDF cipher: " + strconv.Itoa(int(buf[2])))
+    }
+
+    return
+}
+
+func (f *ecdhKdf) serialize(w io.Writer) (err error) {
+    hashId, _ := s2k.HashToHashId(f.KdfHash)
+
+    buf := make([]byte, 4)
+    // See RFC 6637, Section 9, Algorithm-Specific Fields for ECDH keys.
+    buf[0] = byte(0x03) // Length of the following fields
+    buf[1] = byte(0x01) // Reserved for future extensions, must be 1 for now
+    buf[2] = hashId
+    buf[3] = byte(f.KdfAlgo)
+    _, err = w.Write(buf[:])
+    return
+}
+
+func (f *ecdhKdf) byteLen() int {
+    return 4
+}
+
+// PublicKey represents an OpenPGP public key. See RFC 9580, section 5.5.2.
+type PublicKey struct {
+    // Version 为 4 或者 6
+    Version      int
+    CreationTime time.Time
+    PubKeyAlgo   PublicKeyAlgorithm
+    // *rsa.PublicKey, *dsa.PublicKey, *elgamal.PublicKey, *ecdsa.PublicKey,
+    // *ecdh.PublicKey, ed25519.PublicKey, ed448.PublicKey or x448.PublicKey
+    PublicKey    interface{}
+    // v4 为 20 字节, v6 为 32 字节
+    Fingerprint  []byte
+    KeyId        uint64
+    IsSubkey     bool
+
+    n, e, p, q, g, y parsedMPI
+
+    // ECDSA, ECDH and EdDSALegacy fields
+    ec   *ecKey
+    ecdh *ecdhKdf
+
+    // X25519, X448, Ed25519 和 Ed448 的原始公钥
+    native []byte
+}
+
+// signingKey provides a convenient abstraction over signature verification
+// for v4 and v6 public keys.
+type signingKey interface {
+    SerializeSignaturePrefix(io.Writer)
+    serializeWithoutHeaders(io.Writer) error
+}
+
+func fromBig(n *big.Int) parsedMPI {
+    return parsedMPI{
+        bytes:     n.Bytes(),
+        bitLength: uint16(n.BitLen()),
+    }
+}
+
+// NewRSAPublicKey returns a PublicKey that wraps the given rsa.PublicKey.
+func NewRSAPublicKey(creationTime time.Time, pub *rsa.PublicKey) *PublicKey {
+    pk := &PublicKey{
+        Version:      4,
+        CreationTime: creationTime,
+        PubKeyAlgo:   PubKeyAlgoRSA,
+        PublicKey:    pub,
+        n:            fromBig(pub.N),
+        e:            fromBig(big.NewInt(int64(pub.E))),
+    }
+
+    pk.setFingerPrintAndKeyId()
+    return pk
+}
+
+// NewDSAPublicKey returns a PublicKey that wraps the given dsa.PublicKey.
+func NewDSAPublicKey(creationTime time.Time, pub *dsa.PublicKey) *PublicKey {
+    pk := &PublicKey{
+        Version:      4,
+        CreationTime: creationTime,
+        PubKeyAlgo:   PubKeyAlgoDSA,
+        PublicKey:    pub,
+        p:            fromBig(pub.P),
+        q:            fromBig(pub.Q),
+        g:            fromBig(pub.G),
+        y:            fromBig(pub.Y),
+    }
+
+    pk.setFingerPrintAndKeyId()
+    return pk
+}
+
+// NewElGamalPublicKey returns a PublicKey that wraps the given elgamal.PublicKey.
+func NewElGamalPublicKey(creationTime time.Time, pub *elgamal.PublicKey) *PublicKey {
+    pk := &PublicKey{
+        Version:      4,
+        CreationTime: creationTime,
+        PubKeyAlgo:   PubKeyAlgoElGamal,
+        PublicKey:    pub,
+        p:            fromBig(pub.P),
+        g:            fromBig(pub.G),
+        y:            fromBig(pub.Y),
+    }
+
+    pk.setFingerPrintAndKeyId()
+    return pk
+}
+
+// NewECDSAPublicKey returns a PublicKey that wraps the given ecdsa.PublicKey.
+func NewECDSAPublicKey(creationTime time.Time, pub *ecdsa.PublicKey) *PublicKey {
+    pk := &PublicKey{
+        Version:      4,
+        CreationTime: creationTime,
+        PubKeyAlgo:   PubKeyAlgoECDSA,
+        PublicKey:    pub,
+        ec:           new(ecKey),
+    }
+
+    switch pub.Curve {
+        case elliptic.P256():
+            pk.ec.oid = oidCurveP256
+        case elliptic.P384():
+            pk.ec.oid = oidCurveP384
+        case elliptic.P521():
+            pk.ec.oid = oidCurveP521
+        default:
+            panic("unknown elliptic curve")
+    }
+
+    pk.ec.p = newPointMPI(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+
+    pk.setFingerPrintAndKeyId()
+    return pk
+}
+
+// NewECDHPublicKey returns a PublicKey that wraps the given ecdh.PublicKey.
+// X25519 keys are encoded with the Curve25519Legacy OID.
+func NewECDHPublicKey(creationTime time.Time, pub *ecdh.PublicKey) *PublicKey {
+    pk := &PublicKey{
+        Version:      4,
+        CreationTime: creationTime,
+        PubKeyAlgo:   PubKeyAlgoECDH,
+        PublicKey:    pub,
+        ec:           new(ecKey),
+        ecdh:         new(ecdhKdf),
+    }
+
+    point := pub.Bytes()
+    switch pub.Curve() {
+        case ecdh.X25519():
+            pk.ec.oid = oidCurve25519
+            point = append([]byte{0x40}, point...)
+            pk.ecdh.KdfHash, pk.ecdh.KdfAlgo = crypto.SHA256, CipherAES128
+        case ecdh.P256():
+            pk.ec.oid = oidCurveP256
+            pk.ecdh.KdfHash, pk.ecdh.KdfAlgo = crypto.SHA256, CipherAES128
+        case ecdh.P384():
+            pk.ec.oid = oidCurveP384
+            pk.ecdh.KdfHash, pk.ecdh.KdfAlgo = crypto.SHA384, CipherAES192
+        case ecdh.P521():
+            pk.ec.oid = oidCurveP521
+            pk.ecdh.KdfHash, pk.ecdh.KdfAlgo = crypto.SHA512, CipherAES256
+        default:
+            panic("unknown ecdh curve")
+    }
+
+    pk.ec.p = newPointMPI(point)
+
+    pk.setFingerPrintAndKeyId()
+    return pk
+}
+
+// NewEdDSAPublicKey returns a v4 EdDSALegacy PublicKey that wraps the
+// given ed25519.PublicKey.
+func NewEdDSAPublicKey(creationTime time.Time, pub ed25519.PublicKey) *PublicKey {
+    pk := &PublicKey{
+        Version:      4,
+        CreationTime: creationTime,
+        PubKeyAlgo:   PubKeyAlgoEdDSA,
+        PublicKey:    pub,
+        ec: &ecKey{
+            oid: oidEd25519,
+            p:   newPointMPI(append([]byte{0x40}, pub...)),
+        },
+    }
+
+    pk.setFingerPrintAndKeyId()
+    return pk
+}
+
+// NewX25519PublicKey returns a PublicKey that wraps the given X25519 key.
+func NewX25519PublicKey(creationTime time.Time, pub *ecdh.PublicKey) *PublicKey {
+    if pub.Curve() != ecdh.X25519() {
+        panic("not a X25519 public key")
+    }
+
+    return newNativePublicKey(creationTime, PubKeyAlgoX25519, pub, pub.Bytes())
+}
+
+// NewX448PublicKey returns a PublicKey that wraps the given x448.PublicKey.
+func NewX448PublicKey(creationTime time.Time, pub x448.PublicKey) *PublicKey {
+    return newNativePublicKey(creationTime, PubKeyAlgoX448, pub, pub)
+}
+
+// NewEd25519PublicKey returns a PublicKey that wraps the given ed25519.PublicKey.
+func NewEd25519PublicKey(creationTime time.Time, pub ed25519.PublicKey) *PublicKey {
+    return newNativePublicKey(creationTime, PubKeyAlgoEd25519, pub, pub)
+}
+
+// NewEd448PublicKey returns a PublicKey that wraps the given ed448.PublicKey.
+func NewEd448PublicKey(creationTime time.Time, pub ed448.PublicKey) *PublicKey {
+    return newNativePublicKey(creationTime, PubKeyAlgoEd448, pub, pub)
+}
+
+func newNativePublicKey(creationTime time.Time, algo PublicKeyAlgorithm, pub interface{}, native []byte) *PublicKey {
+    pk := &PublicKey{
+        Version:      4,
+        CreationTime: creationTime,
+        PubKeyAlgo:   algo,
+        PublicKey:    pub,
+        native:       append([]byte{}, native...),
+    }
+
+    pk.setFingerPrintAndKeyId()
+    return pk
+}
+
+// newPointMPI 将 EC 公钥点编码为 MPI
+func newPointMPI(point []byte) parsedMPI {
+    return parsedMPI{
+        bytes:     point,
+        bitLength: uint16(new(big.Int).SetBytes(point).BitLen()),
+    }
+}
+
+// UpgradeToV6 converts the key to a v6 key and recomputes its fingerprint.
+// Legacy Curve25519 and Ed25519 keys can not be used as v6 keys.
+func (pk *PublicKey) UpgradeToV6() error {
+    if err := pk.checkV6(6); err != nil {
+        return err
+    }
+
+    pk.Version = 6
+    pk.setFingerPrintAndKeyId()
+    return nil
+}
+
+func (pk *PublicKey) checkV6(version int) error {
+    if version != 6 {
+        return nil
+    }
+
+    switch {
+        case pk.PubKeyAlgo == PubKeyAlgoEdDSA,
+            pk.PubKeyAlgo == PubKeyAlgoECDH && bytes.Equal(pk.ec.oid, oidCurve25519):
+            return errors.StructuralError("v6 keys can not use legacy curve25519 algorithms")
+    }
+
+    return nil
+}
+
+func (pk *PublicKey) parse(r io.Reader) (err error) {
+    // RFC 9580, section 5.5.2
+    var buf [6]byte
+    _, err = readFull(r, buf[:])
+    if err != nil {
+        return
+    }
+    if buf[0] != 4 && buf[0] != 6 {
+        return errors.UnsupportedError("public key version " + strconv.Itoa(int(buf[0])))
+    }
+    pk.Version = int(buf[0])
+    pk.CreationTime = time.Unix(int64(binary.BigEndian.Uint32(buf[1:5])), 0)
+    pk.PubKeyAlgo = PublicKeyAlgorithm(buf[5])
+
+    var material *spanReader
+    if pk.Version == 6 {
+        // v6 密钥带有 4 字节的密钥数据长度
+        var lenBuf [4]byte
+        if _, err = readFull(r, lenBuf[:]); err != nil {
+            return
+        }
+        material = &spanReader{r, int64(binary.BigEndian.Uint32(lenBuf[:]))}
+        r = material
+    }
+
+    switch pk.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly, PubKeyAlgoRSASignOnly:
+            err = pk.parseRSA(r)
+        case PubKeyAlgoDSA:
+            err = pk.parseDSA(r)
+        case PubKeyAlgoElGamal:
+            err = pk.parseElGamal(r)
+        case PubKeyAlgoECDSA:
+            pk.ec = new(ecKey)
+            if err = pk.ec.parse(r); err != nil {
+                return err
+            }
+            pk.PublicKey, err = pk.ec.newECDSA()
+        case PubKeyAlgoECDH:
+            pk.ec = new(ecKey)
+            if err = pk.ec.parse(r); err != nil {
+                return
+            }
+            pk.ecdh = new(ecdhKdf)
+            if err = pk.ecdh.parse(r); err != nil {
+                return
+            }
+            pk.PublicKey, err = pk.ec.newECDH()
+        case PubKeyAlgoEdDSA:
+            pk.ec = new(ecKey)
+            if err = pk.ec.parse(r); err != nil {
+                return
+            }
+            pk.PublicKey, err = pk.ec.newEdDSA()
+        case PubKeyAlgoX25519:
+            if err = pk.parseNative(r, 32); err != nil {
+                return
+            }
+            pk.PublicKey, err = ecdh.X25519().NewPublicKey(pk.native)
+        case PubKeyAlgoX448:
+            err = pk.parseNative(r, x448.PublicKeySize)
+            pk.PublicKey = x448.PublicKey(pk.native)
+        case PubKeyAlgoEd25519:
+            err = pk.parseNative(r, ed25519.PublicKeySize)
+            pk.PublicKey = ed25519.PublicKey(pk.native)
+        case PubKeyAlgoEd448:
+            err = pk.parseNative(r, ed448.PublicKeySize)
+            pk.PublicKey = ed448.PublicKey(pk.native)
+        default:
+            err = errors.UnsupportedError("public key type: " + strconv.Itoa(int(pk.PubKeyAlgo)))
+    }
+    if err != nil {
+        return
+    }
+
+    if material != nil && material.n != 0 {
+        return errors.StructuralError("v6 public key material length mismatch")
+    }
+    if err = pk.checkV6(pk.Version); err != nil {
+        return
+    }
+
+    pk.setFingerPrintAndKeyId()
+    return
+}
+
+// parseNative 读取固定长度的公钥数据
+func (pk *PublicKey) parseNative(r io.Reader, size int) (err error) {
+    pk.native = make([]byte, size)
+    _, err = readFull(r, pk.native)
+    return
+}
+
+func (pk *PublicKey) setFingerPrintAndKeyId() {
+    // RFC 9580, section 5.5.4
+    if pk.Version == 6 {
+        fingerPrint := sha256.New()
+        pk.SerializeSignaturePrefix(fingerPrint)
+        pk.serializeWithoutHeaders(fingerPrint)
+        pk.Fingerprint = fingerPrint.Sum(nil)
+        pk.KeyId = binary.BigEndian.Uint64(pk.Fingerprint[:8])
+        return
+    }
+
+    fingerPrint := sha1.New()
+    pk.SerializeSignaturePrefix(fingerPrint)
+    pk.serializeWithoutHeaders(fingerPrint)
+    pk.Fingerprint = fingerPrint.Sum(nil)
+    pk.KeyId = binary.BigEndian.Uint64(pk.Fingerprint[12:20])
+}
+
+// parseRSA parses RSA public key material from the given Reader. See RFC 4880,
+// section 5.5.2.
+func (pk *PublicKey) parseRSA(r io.Reader) (err error) {
+    pk.n.bytes, pk.n.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+    pk.e.bytes, pk.e.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+
+    if len(pk.e.bytes) > 3 {
+        err = errors.UnsupportedError("large public exponent")
+        return
+    }
+    rsa := &rsa.PublicKey{
+        N: new(big.Int).SetBytes(pk.n.bytes),
+        E: 0,
+    }
+    for i := 0; i < len(pk.e.bytes); i++ {
+        rsa.E <<= 8
+        rsa.E |= int(pk.e.bytes[i])
+    }
+    pk.PublicKey = rsa
+    return
+}
+
+// parseDSA parses DSA public key material from the given Reader. See RFC 4880,
+// section 5.5.2.
+func (pk *PublicKey) parseDSA(r io.Reader) (err error) {
+    pk.p.bytes, pk.p.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+    pk.q.bytes, pk.q.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+    pk.g.bytes, pk.g.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+    pk.y.bytes, pk.y.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+
+    dsa := new(dsa.PublicKey)
+    dsa.P = new(big.Int).SetBytes(pk.p.bytes)
+    dsa.Q = new(big.Int).SetBytes(pk.q.bytes)
+    dsa.G = new(big.Int).SetBytes(pk.g.bytes)
+    dsa.Y = new(big.Int).SetBytes(pk.y.bytes)
+    pk.PublicKey = dsa
+    return
+}
+
+// parseElGamal parses ElGamal public key material from the given Reader. See
+// RFC 4880, section 5.5.2.
+func (pk *PublicKey) parseElGamal(r io.Reader) (err error) {
+    pk.p.bytes, pk.p.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+    pk.g.bytes, pk.g.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+    pk.y.bytes, pk.y.bitLength, err = readMPI(r)
+    if err != nil {
+        return
+    }
+
+    elgamal := new(elgamal.PublicKey)
+    elgamal.P = new(big.Int).SetBytes(pk.p.bytes)
+    elgamal.G = new(big.Int).SetBytes(pk.g.bytes)
+    elgamal.Y = new(big.Int).SetBytes(pk.y.bytes)
+    pk.PublicKey = elgamal
+    return
+}
+
+// keyMaterialLength returns the length of the algorithm-specific fields.
+func (pk *PublicKey) keyMaterialLength() (length int) {
+    switch pk.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly, PubKeyAlgoRSASignOnly:
+            length += 2 + len(pk.n.bytes)
+            length += 2 + len(pk.e.bytes)
+        case PubKeyAlgoDSA:
+            length += 2 + len(pk.p.bytes)
+            length += 2 + len(pk.q.bytes)
+            length += 2 + len(pk.g.bytes)
+            length += 2 + len(pk.y.bytes)
+        case PubKeyAlgoElGamal:
+            length += 2 + len(pk.p.bytes)
+            length += 2 + len(pk.g.bytes)
+            length += 2 + len(pk.y.bytes)
+        case PubKeyAlgoECDSA, PubKeyAlgoEdDSA:
+            length += pk.ec.byteLen()
+        case PubKeyAlgoECDH:
+            length += pk.ec.byteLen()
+            length += pk.ecdh.byteLen()
+        case PubKeyAlgoX25519, PubKeyAlgoX448, PubKeyAlgoEd25519, PubKeyAlgoEd448:
+            length += len(pk.native)
+        default:
+            panic("unknown public key algorithm")
+    }
+
+    return
+}
+
+// bodyLength returns the length of the packet body.
+func (pk *PublicKey) bodyLength() int {
+    length := 6
+    if pk.Version == 6 {
+        length += 4
+    }
+
+    return length + pk.keyMaterialLength()
+}
+
+// SerializeSignaturePrefix writes the prefix for this public key to the given Writer.
+// The prefix is used when calculating a signature over this public key. See
+// RFC 9580, section 5.2.4.
+func (pk *PublicKey) SerializeSignaturePrefix(h io.Writer) {
+    length := pk.bodyLength()
+    if pk.Version == 6 {
+        h.Write([]byte{0x9B, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)})
+        return
+    }
+
+    h.Write([]byte{0x99, byte(length >> 8), byte(length)})
+}
+
+func (pk *PublicKey) Serialize(w io.Writer) (err error) {
+    packetType := packetTypePublicKey
+    if pk.IsSubkey {
+        packetType = packetTypePublicSubkey
+    }
+    err = serializeHeader(w, packetType, pk.bodyLength())
+    if err != nil {
+        return
+    }
+    return pk.serializeWithoutHeaders(w)
+}
+
+// serializeWithoutHeaders marshals the PublicKey to w in the form of an
+// OpenPGP public key packet, not including the packet header.
+func (pk *PublicKey) serializeWithoutHeaders(w io.Writer) (err error) {
+    var buf [10]byte
+    buf[0] = byte(pk.Version)
+    binary.BigEndian.PutUint32(buf[1:5], uint32(pk.CreationTime.Unix()))
+    buf[5] = byte(pk.PubKeyAlgo)
+
+    n := 6
+    if pk.Version == 6 {
+        binary.BigEndian.PutUint32(buf[6:], uint32(pk.keyMaterialLength()))
+        n = 10
+    }
+
+    _, err = w.Write(buf[:n])
+    if err != nil {
+        return
+    }
+
+    switch pk.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly, PubKeyAlgoRSASignOnly:
+            return writeMPIs(w, pk.n, pk.e)
+        case PubKeyAlgoDSA:
+            return writeMPIs(w, pk.p, pk.q, pk.g, pk.y)
+        case PubKeyAlgoElGamal:
+            return writeMPIs(w, pk.p, pk.g, pk.y)
+        case PubKeyAlgoECDSA, PubKeyAlgoEdDSA:
+            return pk.ec.serialize(w)
+        case PubKeyAlgoECDH:
+            if err = pk.ec.serialize(w); err != nil {
+                return
+            }
+            return pk.ecdh.serialize(w)
+        case PubKeyAlgoX25519, PubKeyAlgoX448, PubKeyAlgoEd25519, PubKeyAlgoEd448:
+            _, err = w.Write(pk.native)
+            return
+    }
+    return errors.InvalidArgumentError("bad public-key algorithm")
+}
+
+// CanSign returns true iff this public key can generate signatures
+func (pk *PublicKey) CanSign() bool {
+    return pk.PubKeyAlgo.CanSign()
+}
+
+// VerifySignature returns nil iff sig is a valid signature, made by this
+// public key, of the data hashed into signed. signed is mutated by this call.
+func (pk *PublicKey) VerifySignature(signed hash.Hash, sig *Signature) (err error) {
+    if !pk.CanSign() {
+        return errors.InvalidArgumentError("public key cannot generate signatures")
+    }
+    if (sig.Version == 6) != (pk.Version == 6) {
+        return errors.SignatureError("signature and key version mismatch")
+    }
+
+    signed.Write(sig.HashSuffix)
+    hashBytes := signed.Sum(nil)
+
+    if hashBytes[0] != sig.HashTag[0] || hashBytes[1] != sig.HashTag[1] {
+        return errors.SignatureError("hash tag doesn't match")
+    }
+
+    if pk.PubKeyAlgo != sig.PubKeyAlgo {
+        return errors.InvalidArgumentError("public key and signature use different algorithms")
+    }
+
+    switch pk.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSASignOnly:
+            rsaPublicKey, _ := pk.PublicKey.(*rsa.PublicKey)
+            err = rsa.VerifyPKCS1v15(rsaPublicKey, sig.Hash, hashBytes, padToKeySize(rsaPublicKey, sig.RSASignature.bytes))
+            if err != nil {
+                return errors.SignatureError("RSA verification failure")
+            }
+            return nil
+        case PubKeyAlgoDSA:
+            dsaPublicKey, _ := pk.PublicKey.(*dsa.PublicKey)
+            // Need to truncate hashBytes to match FIPS 186-3 section 4.6.
+            subgroupSize := (dsaPublicKey.Q.BitLen() + 7) / 8
+            if len(hashBytes) > subgroupSize {
+                hashBytes = hashBytes[:subgroupSize]
+            }
+            if !dsa.Verify(dsaPublicKey, hashBytes, new(big.Int).SetBytes(sig.DSASigR.bytes), new(big.Int).SetBytes(sig.DSASigS.bytes)) {
+                return errors.SignatureError("DSA verification failure")
+            }
+            return nil
+        case PubKeyAlgoECDSA:
+            ecdsaPublicKey := pk.PublicKey.(*ecdsa.PublicKey)
+            if !ecdsa.Verify(ecdsaPublicKey, hashBytes, new(big.Int).SetBytes(sig.ECDSASigR.bytes), new(big.Int).SetBytes(sig.ECDSASigS.bytes)) {
+                return errors.SignatureError("ECDSA verification failure")
+            }
+            return nil
+        case PubKeyAlgoEdDSA:
+            r, s := sig.EdDSASigR.bytes, sig.EdDSASigS.bytes
+            if len(r) > 32 || len(s) > 32 {
+                return errors.SignatureError("EdDSA verification failure")
+            }
+            // r 和 s 以 MPI 存储, 需要补齐前导零
+            signature := make([]byte, ed25519.SignatureSize)
+            copy(signature[32-len(r):32], r)
+            copy(signature[64-len(s):], s)
+            if !ed25519.Verify(pk.PublicKey.(ed25519.PublicKey), hashBytes, signature) {
+                return errors.SignatureError("EdDSA verification failure")
+            }
+            return nil
+        case PubKeyAlgoEd25519:
+            if !ed25519.Verify(pk.PublicKey.(ed25519.PublicKey), hashBytes, sig.EdSig) {
+                return errors.SignatureError("Ed25519 verification failure")
+            }
+            return nil
+        case PubKeyAlgoEd448:
+            if !ed448.Verify(pk.PublicKey.(ed448.PublicKey), hashBytes, sig.EdSig) {
+                return errors.SignatureError("Ed448 verification failure")
+            }
+            return nil
+        default:
+            return errors.SignatureError("Unsupported public key algorithm used in signature")
+    }
+}
+
+// keySignatureHash hashes the message that needs to be signed for
+// pk to assert a subkey relationship to signed.
+func keySignatureHash(pk, signed signingKey, h hash.Hash) {
+    // RFC 9580, section 5.2.4
+    pk.SerializeSignaturePrefix(h)
+    pk.serializeWithoutHeaders(h)
+    signed.SerializeSignaturePrefix(h)
+    signed.serializeWithoutHeaders(h)
+}
+
+// VerifyKeySignature returns nil iff sig is a valid signature, made by this
+// public key, of signed.
+func (pk *PublicKey) VerifyKeySignature(signed *PublicKey, sig *Signature) error {
+    h, err := sig.PrepareVerify()
+    if err != nil {
+        return err
+    }
+    keySignatureHash(pk, signed, h)
+    if err = pk.VerifySignature(h, sig); err != nil {
+        return err
+    }
+
+    if sig.FlagSign {
+        // Signing subkeys must be cross-signed. See
+        // https://www.gnupg.org/faq/subkey-cross-certify.html.
+        if sig.EmbeddedSignature == nil {
+            return errors.StructuralError("signing subkey is missing cross-signature")
+        }
+        // Verify the cross-signature. This is calculated over the same
+        // data as the main signature, so we cannot just recursively
+        // call signed.VerifyKeySignature(...)
+        if h, err = sig.EmbeddedSignature.PrepareVerify(); err != nil {
+            return errors.StructuralError("error while hashing for cross-signature: " + err.Error())
+        }
+        keySignatureHash(pk, signed, h)
+        if err := signed.VerifySignature(h, sig.EmbeddedSignature); err != nil {
+            return errors.StructuralError("error while verifying cross-signature: " + err.Error())
+        }
+    }
+
+    return nil
+}
+
+func keyRevocationHash(pk signingKey, h hash.Hash) {
+    // RFC 9580, section 5.2.4
+    pk.SerializeSignaturePrefix(h)
+    pk.serializeWithoutHeaders(h)
+}
+
+// VerifyRevocationSignature returns nil iff sig is a valid signature, made by this
+// public key.
+func (pk *PublicKey) VerifyRevocationSignature(sig *Signature) (err error) {
+    h, err := sig.PrepareVerify()
+    if err != nil {
+        return err
+    }
+    keyRevocationHash(pk, h)
+    return pk.VerifySignature(h, sig)
+}
+
+// VerifyDirectKeySignature returns nil iff sig is a valid direct-key
+// signature, made by this public key over itself.
+func (pk *PublicKey) VerifyDirectKeySignature(sig *Signature) (err error) {
+    h, err := sig.PrepareVerify()
+    if err != nil {
+        return err
+    }
+    keyRevocationHash(pk, h)
+    return pk.VerifySignature(h, sig)
+}
+
+// userIdSignatureHash hashes the message that needs to be signed
+// to assert that pk is a valid key for id.
+func userIdSignatureHash(id string, pk *PublicKey, h hash.Hash) {
+    // RFC 9580, section 5.2.4
+    pk.SerializeSignaturePrefix(h)
+    pk.serializeWithoutHeaders(h)
+
+    var buf [5]byte
+    buf[0] = 0xb4
+    binary.BigEndian.PutUint32(buf[1:], uint32(len(id)))
+    h.Write(buf[:])
+    h.Write([]byte(id))
+}
+
+// VerifyUserIdSignature returns nil iff sig is a valid signature, made by this
+// public key, that id is the identity of pub.
+func (pk *PublicKey) VerifyUserIdSignature(id string, pub *PublicKey, sig *Signature) (err error) {
+    h, err := sig.PrepareVerify()
+    if err != nil {
+        return err
+    }
+    userIdSignatureHash(id, pub, h)
+    return pk.VerifySignature(h, sig)
+}
+
+// KeyIdString returns the public key's key id in capital hex
+// (e.g. "6C7EE1B8621CC013").
+func (pk *PublicKey) KeyIdString() string {
+    return fmt.Sprintf("%016X", pk.KeyId)
+}
+
+// KeyIdShortString returns the short form of public key's key id
+// in capital hex, as shown by gpg --list-keys (e.g. "621CC013").
+func (pk *PublicKey) KeyIdShortString() string {
+    return fmt.Sprintf("%08X", uint32(pk.KeyId))
+}
+
+// A parsedMPI is used to store the contents of a big integer, along with the
+// bit length that was specified in the original input. This allows the MPI to
+// be reserialized exactly.
+type parsedMPI struct {
+    bytes     []byte
+    bitLength uint16
+}
+
+// writeMPIs is a utility function for serializing several big integers to the
+// given Writer.
+func writeMPIs(w io.Writer, mpis ...parsedMPI) (err error) {
+    for _, mpi := range mpis {
+        err = writeMPI(w, mpi.bitLength, mpi.bytes)
+        if err != nil {
+            return
+        }
+    }
+    return
+}
+
+// BitLength returns the bit length for the given public key.
+func (pk *PublicKey) BitLength() (bitLength uint16, err error) {
+    switch pk.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSAEncryptOnly, PubKeyAlgoRSASignOnly:
+            bitLength = pk.n.bitLength
+        case PubKeyAlgoDSA:
+            bitLength = pk.p.bitLength
+        case PubKeyAlgoElGamal:
+            bitLength = pk.p.bitLength
+        case PubKeyAlgoECDSA, PubKeyAlgoECDH, PubKeyAlgoEdDSA:
+            bitLength = pk.ec.bitLength()
+        case PubKeyAlgoX25519, PubKeyAlgoEd25519:
+            bitLength = 255
+        case PubKeyAlgoX448, PubKeyAlgoEd448:
+            bitLength = 448
+        default:
+            err = errors.InvalidArgumentError("bad public-key algorithm")
+    }
+    return
+}
@@ -0,0 +1,1012 @@
+package packet
+
+import (
+    "io"
+    "hash"
+    "time"
+    "bytes"
+    "crypto"
+    "strconv"
+    "math/big"
+    "crypto/dsa"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "encoding/asn1"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+    "github.com/deatil/go-cryptobin/pubkey/ed448"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+const (
+    // See RFC 9580, section 5.2.3.29 for details.
+    KeyFlagCertify = 1 << iota
+    KeyFlagSign
+    KeyFlagEncryptCommunications
+    KeyFlagEncryptStorage
+)
+
+const (
+    // See RFC 9580, section 5.2.3.32 for details.
+    featureSEIPDv1 = 0x01
+    featureSEIPDv2 = 0x08
+)
+
+// Signature represents a signature. See RFC 9580, section 5.2.
+type Signature struct {
+    // Version 为 4 或者 6
+    Version    int
+    SigType    SignatureType
+    PubKeyAlgo PublicKeyAlgorithm
+    Hash       crypto.Hash
+
+    // Salt 为 v6 签名的盐值, 在被签名数据之前写入摘要
+    Salt []byte
+
+    // HashSuffix is extra data that is hashed in after the signed data.
+    HashSuffix []byte
+    // HashTag contains the first two bytes of the hash for fast rejection
+    // of bad signed data.
+    HashTag      [2]byte
+    CreationTime time.Time
+
+    RSASignature         parsedMPI
+    DSASigR, DSASigS     parsedMPI
+    ECDSASigR, ECDSASigS parsedMPI
+    EdDSASigR, EdDSASigS parsedMPI
+    // EdSig 为 Ed25519 和 Ed448 签名数据
+    EdSig []byte
+
+    // rawSubpackets contains the unparsed subpackets, in order.
+    rawSubpackets []outputSubpacket
+
+    // The following are optional so are nil when not included in the
+    // signature.
+
+    SigLifetimeSecs, KeyLifetimeSecs                        *uint32
+    PreferredSymmetric, PreferredHash, PreferredCompression []uint8
+    IssuerKeyId                                             *uint64
+    IsPrimaryId                                             *bool
+
+    // IssuerFingerprint 为签名者的指纹
+    IssuerFingerprint []byte
+    // PreferredCipherSuites 为偏好的 AEAD 算法组合, 每项为 [cipher, aead]
+    PreferredCipherSuites [][2]uint8
+
+    // FlagsValid is set if any flags were given. See RFC 4880, section
+    // 5.2.3.21 for details.
+    FlagsValid                                                           bool
+    FlagCertify, FlagSign, FlagEncryptCommunications, FlagEncryptStorage bool
+
+    // RevocationReason is set if this signature has been revoked.
+    // See RFC 4880, section 5.2.3.23 for details.
+    RevocationReason     *uint8
+    RevocationReasonText string
+
+    // MDC is set if this signature has a feature packet that indicates
+    // support for MDC subpackets.
+    MDC bool
+    // SEIPDv2 is set if this signature has a feature packet that indicates
+    // support for v2 SEIPD packets.
+    SEIPDv2 bool
+
+    // EmbeddedSignature, if non-nil, is a signature of the parent key, by
+    // this key. This prevents an attacker from claiming another's signing
+    // subkey as their own.
+    EmbeddedSignature *Signature
+
+    outSubpackets []outputSubpacket
+}
+
+// subpacketsLengthSize returns the size of the subpacket area length field.
+func (sig *Signature) subpacketsLengthSize() int {
+    if sig.Version == 6 {
+        return 4
+    }
+    return 2
+}
+
+func (sig *Signature) parse(r io.Reader) (err error) {
+    // RFC 9580, section 5.2.3
+    var buf [8]byte
+    _, err = readFull(r, buf[:1])
+    if err != nil {
+        return
+    }
+    if buf[0] != 4 && buf[0] != 6 {
+        err = errors.UnsupportedError("signature packet version " + strconv.Itoa(int(buf[0])))
+        return
+    }
+    sig.Version = int(buf[0])
+    lenSize := sig.subpacketsLengthSize()
+
+    _, err = readFull(r, buf[:3+lenSize])
+    if err != nil {
+        return
+    }
+    sig.SigType = SignatureType(buf[0])
+    sig.PubKeyAlgo = PublicKeyAlgorithm(buf[1])
+    switch sig.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSASignOnly, PubKeyAlgoDSA, PubKeyAlgoECDSA,
+            PubKeyAlgoEdDSA, PubKeyAlgoEd25519, PubKeyAlgoEd448:
+        default:
+            err = errors.UnsupportedError("public key algorithm " + strconv.Itoa(int(sig.PubKeyAlgo)))
+            return
+    }
+
+    var ok bool
+    sig.Hash, ok = s2k.HashIdToHash(buf[2])
+    if !ok {
+        return errors.UnsupportedError("hash function " + strconv.Itoa(int(buf[2])))
+    }
+
+    hashedSubpacketsLength := readSubpacketsLength(buf[3:3+lenSize])
+    if hashedSubpacketsLength > 1<<20 {
+        return errors.StructuralError("signature hashed subpackets too long")
+    }
+
+    l := 4 + lenSize + hashedSubpacketsLength
+    sig.HashSuffix = make([]byte, l+6)
+    sig.HashSuffix[0] = byte(sig.Version)
+    copy(sig.HashSuffix[1:], buf[:3+lenSize])
+    hashedSubpackets := sig.HashSuffix[4+lenSize:l]
+    _, err = readFull(r, hashedSubpackets)
+    if err != nil {
+        return
+    }
+    // See RFC 9580, section 5.2.4
+    trailer := sig.HashSuffix[l:]
+    trailer[0] = byte(sig.Version)
+    trailer[1] = 0xff
+    binary.BigEndian.PutUint32(trailer[2:], uint32(l))
+
+    err = parseSignatureSubpackets(sig, hashedSubpackets, true)
+    if err != nil {
+        return
+    }
+
+    _, err = readFull(r, buf[:lenSize])
+    if err != nil {
+        return
+    }
+    unhashedSubpacketsLength := readSubpacketsLength(buf[:lenSize])
+    if unhashedSubpacketsLength > 1<<20 {
+        return errors.StructuralError("signature unhashed subpackets too long")
+    }
+    unhashedSubpackets := make([]byte, unhashedSubpacketsLength)
+    _, err = readFull(r, unhashedSubpackets)
+    if err != nil {
+        return
+    }
+    err = parseSignatureSubpackets(sig, unhashedSubpackets, false)
+    if err != nil {
+        return
+    }
+
+    _, err = readFull(r, sig.HashTag[:2])
+    if err != nil {
+        return
+    }
+
+    if sig.Version == 6 {
+        // v6 签名带有盐值
+        _, err = readFull(r, buf[:1])
+        if err != nil {
+            return
+        }
+        saltLen, err := saltLength(sig.Hash)
+        if err != nil {
+            return err
+        }
+        if int(buf[0]) != saltLen {
+            return errors.StructuralError("signature salt length mismatch")
+        }
+        sig.Salt = make([]byte, saltLen)
+        if _, err = readFull(r, sig.Salt); err != nil {
+            return err
+        }
+    }
+
+    switch sig.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSASignOnly:
+            sig.RSASignature.bytes, sig.RSASignature.bitLength, err = readMPI(r)
+        case PubKeyAlgoDSA:
+            sig.DSASigR.bytes, sig.DSASigR.bitLength, err = readMPI(r)
+            if err == nil {
+                sig.DSASigS.bytes, sig.DSASigS.bitLength, err = readMPI(r)
+            }
+        case PubKeyAlgoECDSA:
+            sig.ECDSASigR.bytes, sig.ECDSASigR.bitLength, err = readMPI(r)
+            if err == nil {
+                sig.ECDSASigS.bytes, sig.ECDSASigS.bitLength, err = readMPI(r)
+            }
+        case PubKeyAlgoEdDSA:
+            sig.EdDSASigR.bytes, sig.EdDSASigR.bitLength, err = readMPI(r)
+            if err == nil {
+                sig.EdDSASigS.bytes, sig.EdDSASigS.bitLength, err = readMPI(r)
+            }
+        case PubKeyAlgoEd25519:
+            sig.EdSig = make([]byte, ed25519.SignatureSize)
+            _, err = readFull(r, sig.EdSig)
+        case PubKeyAlgoEd448:
+            sig.EdSig = make([]byte, ed448.SignatureSize)
+            _, err = readFull(r, sig.EdSig)
+        default:
+            panic("unreachable")
+    }
+    return
+}
+
+func readSubpacketsLength(buf []byte) int {
+    length := 0
+    for _, b := range buf {
+        length = length<<8 | int(b)
+    }
+    return length
+}
+
+func putSubpacketsLength(buf []byte, length int) {
+    for i := len(buf) - 1; i >= 0; i-- {
+        buf[i] = byte(length)
+        length >>= 8
+    }
+}
+
+// saltLength returns the salt size of v6 signatures for the hash.
+// See RFC 9580, section 9.5.
+func saltLength(h crypto.Hash) (int, error) {
+    switch h {
+        case crypto.SHA224, crypto.SHA256, crypto.SHA3_256:
+            return 16, nil
+        case crypto.SHA384:
+            return 24, nil
+        case crypto.SHA512, crypto.SHA3_512:
+            return 32, nil
+    }
+
+    return 0, errors.UnsupportedError("hash function for v6 signature: " + strconv.Itoa(int(h)))
+}
+
+// parseSignatureSubpackets parses subpackets of the main signature packet. See
+// RFC 4880, section 5.2.3.1.
+func parseSignatureSubpackets(sig *Signature, subpackets []byte, isHashed bool) (err error) {
+    for len(subpackets) > 0 {
+        subpackets, err = parseSignatureSubpacket(sig, subpackets, isHashed)
+        if err != nil {
+            return
+        }
+    }
+
+    if sig.CreationTime.IsZero() {
+        err = errors.StructuralError("no creation time in signature")
+    }
+
+    return
+}
+
+type signatureSubpacketType uint8
+
+const (
+    creationTimeSubpacket        signatureSubpacketType = 2
+    signatureExpirationSubpacket signatureSubpacketType = 3
+    keyExpirationSubpacket       signatureSubpacketType = 9
+    prefSymmetricAlgosSubpacket  signatureSubpacketType = 11
+    issuerSubpacket              signatureSubpacketType = 16
+    prefHashAlgosSubpacket       signatureSubpacketType = 21
+    prefCompressionSubpacket     signatureSubpacketType = 22
+    primaryUserIdSubpacket       signatureSubpacketType = 25
+    keyFlagsSubpacket            signatureSubpacketType = 27
+    reasonForRevocationSubpacket signatureSubpacketType = 29
+    featuresSubpacket            signatureSubpacketType = 30
+    embeddedSignatureSubpacket   signatureSubpacketType = 32
+    issuerFingerprintSubpacket   signatureSubpacketType = 33
+    prefCipherSuitesSubpacket    signatureSubpacketType = 39
+)
+
+// parseSignatureSubpacket parses a single subpacket. len(subpacket) is >= 1.
+func parseSignatureSubpacket(sig *Signature, subpacket []byte, isHashed bool) (rest []byte, err error) {
+    // RFC 4880, section 5.2.3.1
+    var (
+        length     uint32
+        packetType signatureSubpacketType
+        isCritical bool
+    )
+    switch {
+        case subpacket[0] < 192:
+            length = uint32(subpacket[0])
+            subpacket = subpacket[1:]
+        case subpacket[0] < 255:
+            if len(subpacket) < 2 {
+                goto Truncated
+            }
+            length = uint32(subpacket[0]-192)<<8 + uint32(subpacket[1]) + 192
+            subpacket = subpacket[2:]
+        default:
+            if len(subpacket) < 5 {
+                goto Truncated
+            }
+            length = uint32(subpacket[1])<<24 |
+                uint32(subpacket[2])<<16 |
+                uint32(subpacket[3])<<8 |
+                uint32(subpacket[4])
+            subpacket = subpacket[5:]
+    }
+    if length > uint32(len(subpacket)) {
+        goto Truncated
+    }
+    rest = subpacket[length:]
+    subpacket = subpacket[:length]
+    if len(subpacket) == 0 {
+        err = errors.StructuralError("zero length signature subpacket")
+        return
+    }
+    packetType = signatureSubpacketType(subpacket[0] & 0x7f)
+    isCritical = subpacket[0]&0x80 == 0x80
+    subpacket = subpacket[1:]
+    sig.rawSubpackets = append(sig.rawSubpackets, outputSubpacket{isHashed, packetType, isCritical, subpacket})
+    switch packetType {
+        case creationTimeSubpacket:
+            if !isHashed {
+                err = errors.StructuralError("signature creation time in non-hashed area")
+                return
+            }
+            if len(subpacket) != 4 {
+                err = errors.StructuralError("signature creation time not four bytes")
+                return
+            }
+            t := binary.BigEndian.Uint32(subpacket)
+            sig.CreationTime = time.Unix(int64(t), 0)
+        case signatureExpirationSubpacket:
+            // Signature expiration time, section 5.2.3.10
+            if !isHashed {
+                return
+            }
+            if len(subpacket) != 4 {
+                err = errors.StructuralError("expiration subpacket with bad length")
+                return
+            }
+            sig.SigLifetimeSecs = new(uint32)
+            *sig.SigLifetimeSecs = binary.BigEndian.Uint32(subpacket)
+        case keyExpirationSubpacket:
+            // Key expiration time, section 5.2.3.6
+            if !isHashed {
+                return
+            }
+            if len(subpacket) != 4 {
+                err = errors.StructuralError("key expiration subpacket with bad length")
+                return
+            }
+            sig.KeyLifetimeSecs = new(uint32)
+            *sig.KeyLifetimeSecs = binary.BigEndian.Uint32(subpacket)
+        case prefSymmetricAlgosSubpacket:
+            // Preferred symmetric algorithms, section 5.2.3.7
+            if !isHashed {
+                return
+            }
+            sig.PreferredSymmetric = make([]byte, len(subpacket))
+            copy(sig.PreferredSymmetric, subpacket)
+        case issuerSubpacket:
+            // Issuer, section 5.2.3.5
+            if len(subpacket) != 8 {
+                err = errors.StructuralError("issuer subpacket with bad length")
+                return
+            }
+            if sig.IssuerKeyId == nil {
+                sig.IssuerKeyId = new(uint64)
+            }
+            *sig.IssuerKeyId = binary.BigEndian.Uint64(subpacket)
+        case prefHashAlgosSubpacket:
+            // Preferred hash algorithms, section 5.2.3.8
+            if !isHashed {
+                return
+            }
+            sig.PreferredHash = make([]byte, len(subpacket))
+            copy(sig.PreferredHash, subpacket)
+        case prefCompressionSubpacket:
+            // Preferred compression algorithms, section 5.2.3.9
+            if !isHashed {
+                return
+            }
+            sig.PreferredCompression = make([]byte, len(subpacket))
+            copy(sig.PreferredCompression, subpacket)
+        case primaryUserIdSubpacket:
+            // Primary User ID, section 5.2.3.19
+            if !isHashed {
+                return
+            }
+            if len(subpacket) != 1 {
+                err = errors.StructuralError("primary user id subpacket with bad length")
+                return
+            }
+            sig.IsPrimaryId = new(bool)
+            if subpacket[0] > 0 {
+                *sig.IsPrimaryId = true
+            }
+        case keyFlagsSubpacket:
+            // Key flags, section 5.2.3.21
+            if !isHashed {
+                return
+            }
+            if len(subpacket) == 0 {
+                err = errors.StructuralError("empty key flags subpacket")
+                return
+            }
+            sig.FlagsValid = true
+            if subpacket[0]&KeyFlagCertify != 0 {
+                sig.FlagCertify = true
+            }
+            if subpacket[0]&KeyFlagSign != 0 {
+                sig.FlagSign = true
+            }
+            if subpacket[0]&KeyFlagEncryptCommunications != 0 {
+                sig.FlagEncryptCommunications = true
+            }
+            if subpacket[0]&KeyFlagEncryptStorage != 0 {
+                sig.FlagEncryptStorage = true
+            }
+        case reasonForRevocationSubpacket:
+            // Reason For Revocation, section 5.2.3.23
+            if !isHashed {
+                return
+            }
+            if len(subpacket) == 0 {
+                err = errors.StructuralError("empty revocation reason subpacket")
+                return
+            }
+            sig.RevocationReason = new(uint8)
+            *sig.RevocationReason = subpacket[0]
+            sig.RevocationReasonText = string(subpacket[1:])
+        case featuresSubpacket:
+            // Features subpacket, section 5.2.3.32 specifies a very general
+            // mechanism for OpenPGP implementations to signal support for new
+            // features.
+            if len(subpacket) >= 1 {
+                sig.MDC = subpacket[0]&featureSEIPDv1 != 0
+                sig.SEIPDv2 = subpacket[0]&featureSEIPDv2 != 0
+            }
+        case issuerFingerprintSubpacket:
+            // Issuer fingerprint, section 5.2.3.35
+            if len(subpacket) == 0 {
+                err = errors.StructuralError("empty issuer fingerprint subpacket")
+                return
+            }
+            var fp []byte
+            switch subpacket[0] {
+                case 4:
+                    if len(subpacket) != 21 {
+                        err = errors.StructuralError("issuer fingerprint subpacket with bad length")
+                        return
+                    }
+                    fp = subpacket[1:]
+                    if sig.IssuerKeyId == nil {
+                        sig.IssuerKeyId = new(uint64)
+                        *sig.IssuerKeyId = binary.BigEndian.Uint64(fp[12:])
+                    }
+                case 6:
+                    if len(subpacket) != 33 {
+                        err = errors.StructuralError("issuer fingerprint subpacket with bad length")
+                        return
+                    }
+                    fp = subpacket[1:]
+                    if sig.IssuerKeyId == nil {
+                        sig.IssuerKeyId = new(uint64)
+                        *sig.IssuerKeyId = binary.BigEndian.Uint64(fp[:8])
+                    }
+                default:
+                    return
+            }
+            sig.IssuerFingerprint = append([]byte{}, fp...)
+        case prefCipherSuitesSubpacket:
+            // Preferred AEAD ciphersuites, section 5.2.3.15
+            if !isHashed {
+                return
+            }
+            if len(subpacket)%2 != 0 {
+                err = errors.StructuralError("invalid preferred ciphersuites subpacket length")
+                return
+            }
+            sig.PreferredCipherSuites = nil
+            for i := 0; i < len(subpacket); i += 2 {
+                sig.PreferredCipherSuites = append(sig.PreferredCipherSuites, [2]uint8{subpacket[i], subpacket[i+1]})
+            }
+        case embeddedSignatureSubpacket:
+            // Only usage is in signatures that cross-certify
+            // signing subkeys. section 5.2.3.26 describes the
+            // format, with its usage described in section 11.1
+            if sig.EmbeddedSignature != nil {
+                err = errors.StructuralError("Cannot have multiple embedded signatures")
+                return
+            }
+            sig.EmbeddedSignature = new(Signature)
+            if err := sig.EmbeddedSignature.parse(bytes.NewBuffer(subpacket)); err != nil {
+                return nil, err
+            }
+            if sigType := sig.EmbeddedSignature.SigType; sigType != SigTypePrimaryKeyBinding {
+                return nil, errors.StructuralError("cross-signature has unexpected type " + strconv.Itoa(int(sigType)))
+            }
+        default:
+            if isCritical {
+                err = errors.UnsupportedError("unknown critical signature subpacket type " + strconv.Itoa(int(packetType)))
+                return
+            }
+    }
+    return
+
+Truncated:
+    err = errors.StructuralError("signature subpacket truncated")
+    return
+}
+
+// subpacketLengthLength returns the length, in bytes, of an encoded length value.
+func subpacketLengthLength(length int) int {
+    if length < 192 {
+        return 1
+    }
+    if length < 16320 {
+        return 2
+    }
+    return 5
+}
+
+// serializeSubpacketLength marshals the given length into to.
+func serializeSubpacketLength(to []byte, length int) int {
+    // RFC 4880, Section 4.2.2.
+    if length < 192 {
+        to[0] = byte(length)
+        return 1
+    }
+    if length < 16320 {
+        length -= 192
+        to[0] = byte((length >> 8) + 192)
+        to[1] = byte(length)
+        return 2
+    }
+    to[0] = 255
+    to[1] = byte(length >> 24)
+    to[2] = byte(length >> 16)
+    to[3] = byte(length >> 8)
+    to[4] = byte(length)
+    return 5
+}
+
+// subpacketsLength returns the serialized length, in bytes, of the given
+// subpackets.
+func subpacketsLength(subpackets []outputSubpacket, hashed bool) (length int) {
+    for _, subpacket := range subpackets {
+        if subpacket.hashed == hashed {
+            length += subpacketLengthLength(len(subpacket.contents) + 1)
+            length += 1 // type byte
+            length += len(subpacket.contents)
+        }
+    }
+    return
+}
+
+// serializeSubpackets marshals the given subpackets into to.
+func serializeSubpackets(to []byte, subpackets []outputSubpacket, hashed bool) {
+    for _, subpacket := range subpackets {
+        if subpacket.hashed == hashed {
+            n := serializeSubpacketLength(to, len(subpacket.contents)+1)
+            to[n] = byte(subpacket.subpacketType)
+            if subpacket.isCritical {
+                to[n] |= 0x80
+            }
+            to = to[1+n:]
+            n = copy(to, subpacket.contents)
+            to = to[n:]
+        }
+    }
+    return
+}
+
+// KeyExpired returns whether sig is a self-signature of a key that has
+// expired.
+func (sig *Signature) KeyExpired(currentTime time.Time) bool {
+    if sig.KeyLifetimeSecs == nil {
+        return false
+    }
+    expiry := sig.CreationTime.Add(time.Duration(*sig.KeyLifetimeSecs) * time.Second)
+    return currentTime.After(expiry)
+}
+
+// PrepareSign returns a hash to be used for signing. A new salt is
+// generated and hashed for v6 signatures. sig.Version must be set first.
+func (sig *Signature) PrepareSign(config *Config) (hash.Hash, error) {
+    if !sig.Hash.Available() {
+        return nil, errors.UnsupportedError("hash function")
+    }
+
+    h := sig.Hash.New()
+    if sig.Version == 6 {
+        saltLen, err := saltLength(sig.Hash)
+        if err != nil {
+            return nil, err
+        }
+
+        sig.Salt = make([]byte, saltLen)
+        if _, err = io.ReadFull(config.Random(), sig.Salt); err != nil {
+            return nil, err
+        }
+
+        h.Write(sig.Salt)
+    }
+
+    return h, nil
+}
+
+// PrepareVerify returns a hash to be used for verifying the signature.
+// The salt of v6 signatures is hashed first.
+func (sig *Signature) PrepareVerify() (hash.Hash, error) {
+    if !sig.Hash.Available() {
+        return nil, errors.UnsupportedError("hash function")
+    }
+
+    h := sig.Hash.New()
+    if sig.Version == 6 {
+        h.Write(sig.Salt)
+    }
+
+    return h, nil
+}
+
+// buildHashSuffix constructs the HashSuffix member of sig in preparation for signing.
+func (sig *Signature) buildHashSuffix() (err error) {
+    hashedSubpacketsLen := subpacketsLength(sig.outSubpackets, true)
+    lenSize := sig.subpacketsLengthSize()
+
+    var ok bool
+    l := 4 + lenSize + hashedSubpacketsLen
+    sig.HashSuffix = make([]byte, l+6)
+    sig.HashSuffix[0] = byte(sig.Version)
+    sig.HashSuffix[1] = uint8(sig.SigType)
+    sig.HashSuffix[2] = uint8(sig.PubKeyAlgo)
+    sig.HashSuffix[3], ok = s2k.HashToHashId(sig.Hash)
+    if !ok {
+        sig.HashSuffix = nil
+        return errors.InvalidArgumentError("hash cannot be represented in OpenPGP: " + strconv.Itoa(int(sig.Hash)))
+    }
+    putSubpacketsLength(sig.HashSuffix[4:4+lenSize], hashedSubpacketsLen)
+    serializeSubpackets(sig.HashSuffix[4+lenSize:l], sig.outSubpackets, true)
+    trailer := sig.HashSuffix[l:]
+    trailer[0] = byte(sig.Version)
+    trailer[1] = 0xff
+    binary.BigEndian.PutUint32(trailer[2:], uint32(l))
+    return
+}
+
+func (sig *Signature) signPrepareHash(h hash.Hash) (digest []byte, err error) {
+    err = sig.buildHashSuffix()
+    if err != nil {
+        return
+    }
+
+    h.Write(sig.HashSuffix)
+    digest = h.Sum(nil)
+    copy(sig.HashTag[:], digest)
+    return
+}
+
+// Sign signs a message with a private key. The hash, h, must contain
+// the hash of the message to be signed and will be mutated by this function.
+// On success, the signature is stored in sig. Call Serialize to write it out.
+// If config is nil, sensible defaults will be used.
+func (sig *Signature) Sign(h hash.Hash, priv *PrivateKey, config *Config) (err error) {
+    if priv.Dummy() {
+        return errors.ErrDummyPrivateKey("dummy key found")
+    }
+    if sig.Version == 0 {
+        sig.Version = priv.Version
+    }
+    if sig.Version != priv.Version {
+        return errors.InvalidArgumentError("signature version does not match key version")
+    }
+    if sig.Version == 6 && len(sig.Salt) == 0 {
+        return errors.InvalidArgumentError("v6 signature without salt, call PrepareSign first")
+    }
+
+    sig.outSubpackets = sig.buildSubpackets()
+    digest, err := sig.signPrepareHash(h)
+    if err != nil {
+        return
+    }
+
+    switch priv.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSASignOnly:
+            // supports both *rsa.PrivateKey and crypto.Signer
+            sig.RSASignature.bytes, err = priv.PrivateKey.(crypto.Signer).Sign(config.Random(), digest, sig.Hash)
+            sig.RSASignature.bitLength = uint16(8 * len(sig.RSASignature.bytes))
+        case PubKeyAlgoDSA:
+            dsaPriv := priv.PrivateKey.(*dsa.PrivateKey)
+
+            // Need to truncate hashBytes to match FIPS 186-3 section 4.6.
+            subgroupSize := (dsaPriv.Q.BitLen() + 7) / 8
+            if len(digest) > subgroupSize {
+                digest = digest[:subgroupSize]
+            }
+            r, s, err := dsa.Sign(config.Random(), dsaPriv, digest)
+            if err == nil {
+                sig.DSASigR.bytes = r.Bytes()
+                sig.DSASigR.bitLength = uint16(8 * len(sig.DSASigR.bytes))
+                sig.DSASigS.bytes = s.Bytes()
+                sig.DSASigS.bitLength = uint16(8 * len(sig.DSASigS.bytes))
+            }
+        case PubKeyAlgoECDSA:
+            var r, s *big.Int
+            if pk, ok := priv.PrivateKey.(*ecdsa.PrivateKey); ok {
+                // direct support, avoid asn1 wrapping/unwrapping
+                r, s, err = ecdsa.Sign(config.Random(), pk, digest)
+            } else {
+                var b []byte
+                b, err = priv.PrivateKey.(crypto.Signer).Sign(config.Random(), digest, sig.Hash)
+                if err == nil {
+                    r, s, err = unwrapECDSASig(b)
+                }
+            }
+            if err == nil {
+                sig.ECDSASigR = fromBig(r)
+                sig.ECDSASigS = fromBig(s)
+            }
+        case PubKeyAlgoEdDSA:
+            signature := ed25519.Sign(priv.PrivateKey.(ed25519.PrivateKey), digest)
+            sig.EdDSASigR = fromBig(new(big.Int).SetBytes(signature[:32]))
+            sig.EdDSASigS = fromBig(new(big.Int).SetBytes(signature[32:]))
+        case PubKeyAlgoEd25519:
+            sig.EdSig = ed25519.Sign(priv.PrivateKey.(ed25519.PrivateKey), digest)
+        case PubKeyAlgoEd448:
+            sig.EdSig = ed448.Sign(priv.PrivateKey.(ed448.PrivateKey), digest)
+        default:
+            err = errors.UnsupportedError("public key algorithm: " + strconv.Itoa(int(sig.PubKeyAlgo)))
+    }
+
+    return
+}
+
+// unwrapECDSASig parses the two integer components of an ASN.1-encoded ECDSA
+// signature.
+func unwrapECDSASig(b []byte) (r, s *big.Int, err error) {
+    var ecsdaSig struct {
+        R, S *big.Int
+    }
+    _, err = asn1.Unmarshal(b, &ecsdaSig)
+    if err != nil {
+        return
+    }
+    return ecsdaSig.R, ecsdaSig.S, nil
+}
+
+// SignUserId computes a signature from priv, asserting that pub is a valid
+// key for the identity id.  On success, the signature is stored in sig. Call
+// Serialize to write it out.
+// If config is nil, sensible defaults will be used.
+func (sig *Signature) SignUserId(id string, pub *PublicKey, priv *PrivateKey, config *Config) error {
+    if sig.Version == 0 {
+        sig.Version = priv.Version
+    }
+    h, err := sig.PrepareSign(config)
+    if err != nil {
+        return err
+    }
+    userIdSignatureHash(id, pub, h)
+    return sig.Sign(h, priv, config)
+}
+
+// SignKey computes a signature from priv, asserting that pub is a subkey. On
+// success, the signature is stored in sig. Call Serialize to write it out.
+// If config is nil, sensible defaults will be used.
+func (sig *Signature) SignKey(pub *PublicKey, priv *PrivateKey, config *Config) error {
+    if sig.Version == 0 {
+        sig.Version = priv.Version
+    }
+    h, err := sig.PrepareSign(config)
+    if err != nil {
+        return err
+    }
+    keySignatureHash(&priv.PublicKey, pub, h)
+    return sig.Sign(h, priv, config)
+}
+
+// CrossSignKey computes the primary key binding signature from the signing
+// subkey priv over the primary key pub. On success, the signature is stored
+// in sig. If config is nil, sensible defaults will be used.
+func (sig *Signature) CrossSignKey(pub *PublicKey, priv *PrivateKey, config *Config) error {
+    if sig.Version == 0 {
+        sig.Version = priv.Version
+    }
+    h, err := sig.PrepareSign(config)
+    if err != nil {
+        return err
+    }
+    keySignatureHash(pub, &priv.PublicKey, h)
+    return sig.Sign(h, priv, config)
+}
+
+// SignDirectKey computes a direct-key signature from priv over its own
+// public key, used by v6 keys to carry key flags and preferences.
+// If config is nil, sensible defaults will be used.
+func (sig *Signature) SignDirectKey(priv *PrivateKey, config *Config) error {
+    if sig.Version == 0 {
+        sig.Version = priv.Version
+    }
+    h, err := sig.PrepareSign(config)
+    if err != nil {
+        return err
+    }
+    keyRevocationHash(&priv.PublicKey, h)
+    return sig.Sign(h, priv, config)
+}
+
+// Serialize marshals sig to w. Sign, SignUserId or SignKey must have been
+// called first.
+func (sig *Signature) Serialize(w io.Writer) (err error) {
+    var buf bytes.Buffer
+    if err = sig.serializeBody(&buf); err != nil {
+        return
+    }
+
+    err = serializeHeader(w, packetTypeSignature, buf.Len())
+    if err != nil {
+        return
+    }
+
+    _, err = w.Write(buf.Bytes())
+    return
+}
+
+// serializeBody marshals the signature packet body, without the header.
+func (sig *Signature) serializeBody(w io.Writer) (err error) {
+    if len(sig.outSubpackets) == 0 {
+        sig.outSubpackets = sig.rawSubpackets
+    }
+    if sig.RSASignature.bytes == nil && sig.DSASigR.bytes == nil && sig.ECDSASigR.bytes == nil &&
+        sig.EdDSASigR.bytes == nil && sig.EdSig == nil {
+        return errors.InvalidArgumentError("Signature: need to call Sign, SignUserId or SignKey before Serialize")
+    }
+
+    lenSize := sig.subpacketsLengthSize()
+
+    _, err = w.Write(sig.HashSuffix[:len(sig.HashSuffix)-6])
+    if err != nil {
+        return
+    }
+
+    unhashedSubpacketsLen := subpacketsLength(sig.outSubpackets, false)
+
+    unhashedSubpackets := make([]byte, lenSize+unhashedSubpacketsLen)
+    putSubpacketsLength(unhashedSubpackets[:lenSize], unhashedSubpacketsLen)
+    serializeSubpackets(unhashedSubpackets[lenSize:], sig.outSubpackets, false)
+
+    _, err = w.Write(unhashedSubpackets)
+    if err != nil {
+        return
+    }
+    _, err = w.Write(sig.HashTag[:])
+    if err != nil {
+        return
+    }
+
+    if sig.Version == 6 {
+        _, err = w.Write(append([]byte{byte(len(sig.Salt))}, sig.Salt...))
+        if err != nil {
+            return
+        }
+    }
+
+    switch sig.PubKeyAlgo {
+        case PubKeyAlgoRSA, PubKeyAlgoRSASignOnly:
+            err = writeMPIs(w, sig.RSASignature)
+        case PubKeyAlgoDSA:
+            err = writeMPIs(w, sig.DSASigR, sig.DSASigS)
+        case PubKeyAlgoECDSA:
+            err = writeMPIs(w, sig.ECDSASigR, sig.ECDSASigS)
+        case PubKeyAlgoEdDSA:
+            err = writeMPIs(w, sig.EdDSASigR, sig.EdDSASigS)
+        case PubKeyAlgoEd25519, PubKeyAlgoEd448:
+            _, err = w.Write(sig.EdSig)
+        default:
+            panic("impossible")
+    }
+    return
+}
+
+// outputSubpacket represents a subpacket to be marshaled.
+type outputSubpacket struct {
+    hashed        bool // true if this subpacket is in the hashed area.
+    subpacketType signatureSubpacketType
+    isCritical    bool
+    contents      []byte
+}
+
+func (sig *Signature) buildSubpackets() (subpackets []outputSubpacket) {
+    creationTime := make([]byte, 4)
+    binary.BigEndian.PutUint32(creationTime, uint32(sig.CreationTime.Unix()))
+    subpackets = append(subpackets, outputSubpacket{true, creationTimeSubpacket, false, creationTime})
+
+    if sig.IssuerKeyId != nil && sig.Version == 4 {
+        keyId := make([]byte, 8)
+        binary.BigEndian.PutUint64(keyId, *sig.IssuerKeyId)
+        subpackets = append(subpackets, outputSubpacket{true, issuerSubpacket, false, keyId})
+    }
+
+    if len(sig.IssuerFingerprint) > 0 {
+        contents := append([]byte{byte(sig.Version)}, sig.IssuerFingerprint...)
+        subpackets = append(subpackets, outputSubpacket{true, issuerFingerprintSubpacket, sig.Version == 6, contents})
+    }
+
+    if sig.SigLifetimeSecs != nil && *sig.SigLifetimeSecs != 0 {
+        sigLifetime := make([]byte, 4)
+        binary.BigEndian.PutUint32(sigLifetime, *sig.SigLifetimeSecs)
+        subpackets = append(subpackets, outputSubpacket{true, signatureExpirationSubpacket, true, sigLifetime})
+    }
+
+    // Key flags may only appear in self-signatures or certification signatures.
+
+    if sig.FlagsValid {
+        var flags byte
+        if sig.FlagCertify {
+            flags |= KeyFlagCertify
+        }
+        if sig.FlagSign {
+            flags |= KeyFlagSign
+        }
+        if sig.FlagEncryptCommunications {
+            flags |= KeyFlagEncryptCommunications
+        }
+        if sig.FlagEncryptStorage {
+            flags |= KeyFlagEncryptStorage
+        }
+        subpackets = append(subpackets, outputSubpacket{true, keyFlagsSubpacket, false, []byte{flags}})
+    }
+
+    // The following subpackets may only appear in self-signatures
+
+    if sig.KeyLifetimeSecs != nil && *sig.KeyLifetimeSecs != 0 {
+        keyLifetime := make([]byte, 4)
+        binary.BigEndian.PutUint32(keyLifetime, *sig.KeyLifetimeSecs)
+        subpackets = append(subpackets, outputSubpacket{true, keyExpirationSubpacket, true, keyLifetime})
+    }
+
+    if sig.IsPrimaryId != nil && *sig.IsPrimaryId {
+        subpackets = append(subpackets, outputSubpacket{true, primaryUserIdSubpacket, false, []byte{1}})
+    }
+
+    if len(sig.PreferredSymmetric) > 0 {
+        subpackets = append(subpackets, outputSubpacket{true, prefSymmetricAlgosSubpacket, false, sig.PreferredSymmetric})
+    }
+
+    if len(sig.PreferredHash) > 0 {
+        subpackets = append(subpackets, outputSubpacket{true, prefHashAlgosSubpacket, false, sig.PreferredHash})
+    }
+
+    if len(sig.PreferredCompression) > 0 {
+        subpackets = append(subpackets, outputSubpacket{true, prefCompressionSubpacket, false, sig.PreferredCompression})
+    }
+
+    if len(sig.PreferredCipherSuites) > 0 {
+        var contents []byte
+        for _, cs := range sig.PreferredCipherSuites {
+            contents = append(contents, cs[0], cs[1])
+        }
+        subpackets = append(subpackets, outputSubpacket{true, prefCipherSuitesSubpacket, false, contents})
+    }
+
+    if sig.MDC || sig.SEIPDv2 {
+        var features byte
+        if sig.MDC {
+            features |= featureSEIPDv1
+        }
+        if sig.SEIPDv2 {
+            features |= featureSEIPDv2
+        }
+        subpackets = append(subpackets, outputSubpacket{true, featuresSubpacket, false, []byte{features}})
+    }
+
+    if sig.EmbeddedSignature != nil {
+        var buf bytes.Buffer
+        if err := sig.EmbeddedSignature.serializeBody(&buf); err == nil {
+            subpackets = append(subpackets, outputSubpacket{true, embeddedSignatureSubpacket, false, buf.Bytes()})
+        }
+    }
+
+    return
+}
@@ -0,0 +1,257 @@
+package packet
+
+import (
+    "io"
+    "bytes"
+    "strconv"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/openpgp/s2k"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// This is the largest session key that we'll support. Since no 512-bit cipher
+// has even been seriously used, this is comfortably large.
+const maxSessionKeySizeInBytes = 64
+
+// SymmetricKeyEncrypted represents a passphrase protected session key. See RFC
+// 9580, section 5.3.
+type SymmetricKeyEncrypted struct {
+    // Version 为 4 或者 6
+    Version      int
+    CipherFunc   CipherFunction
+    // Mode 为 v6 使用的 AEAD 模式
+    Mode         AEADMode
+    s2k          func(out, in []byte)
+    iv           []byte
+    encryptedKey []byte
+}
+
+func (ske *SymmetricKeyEncrypted) parse(r io.Reader) error {
+    var buf [2]byte
+    if _, err := readFull(r, buf[:1]); err != nil {
+        return err
+    }
+    ske.Version = int(buf[0])
+    if ske.Version != 4 && ske.Version != 6 {
+        return errors.UnsupportedError("SymmetricKeyEncrypted version " + strconv.Itoa(ske.Version))
+    }
+
+    if ske.Version == 6 {
+        // 后续字段的长度, 不需要
+        if _, err := readFull(r, buf[:1]); err != nil {
+            return err
+        }
+    }
+
+    if _, err := readFull(r, buf[:1]); err != nil {
+        return err
+    }
+    ske.CipherFunc = CipherFunction(buf[0])
+    if !ske.CipherFunc.IsSupported() {
+        return errors.UnsupportedError("unknown cipher: " + strconv.Itoa(int(buf[0])))
+    }
+
+    s2kReader := r
+    if ske.Version == 6 {
+        if _, err := readFull(r, buf[:2]); err != nil {
+            return err
+        }
+
+        ske.Mode = AEADMode(buf[0])
+        if !ske.Mode.IsSupported() {
+            return errors.UnsupportedError("unknown AEAD mode: " + strconv.Itoa(int(buf[0])))
+        }
+
+        s2kReader = &spanReader{r, int64(buf[1])}
+    }
+
+    var err error
+    ske.s2k, err = s2k.Parse(s2kReader)
+    if err != nil {
+        return err
+    }
+
+    if ske.Version == 6 {
+        ske.iv = make([]byte, ske.Mode.NonceLength())
+        if _, err = readFull(r, ske.iv); err != nil {
+            return err
+        }
+    }
+
+    encryptedKey := make([]byte, maxSessionKeySizeInBytes+aeadTagLength)
+    // The session key may follow. We just have to try and read to find
+    // out. If it exists then we limit it to maxSessionKeySizeInBytes.
+    n, err := readFull(r, encryptedKey)
+    if err != nil && err != io.ErrUnexpectedEOF {
+        return err
+    }
+
+    if n != 0 {
+        if n == len(encryptedKey) {
+            return errors.UnsupportedError("oversized encrypted session key")
+        }
+        ske.encryptedKey = encryptedKey[:n]
+    }
+
+    if ske.Version == 6 && len(ske.encryptedKey) <= aeadTagLength {
+        return errors.StructuralError("v6 SymmetricKeyEncrypted without session key")
+    }
+
+    return nil
+}
+
+// aeadInfo returns the packet header octet, version, cipher and AEAD mode.
+func (ske *SymmetricKeyEncrypted) aeadInfo() []byte {
+    return []byte{0xC0 | byte(packetTypeSymmetricKeyEncrypted), byte(ske.Version), byte(ske.CipherFunc), byte(ske.Mode)}
+}
+
+// newAEAD 派生 KEK 并返回 AEAD
+func (ske *SymmetricKeyEncrypted) newAEAD(key []byte) (cipher.AEAD, error) {
+    kek, err := deriveAEADKey(key, nil, ske.aeadInfo(), ske.CipherFunc.KeySize())
+    if err != nil {
+        return nil, err
+    }
+
+    return ske.Mode.new(ske.CipherFunc.new(kek))
+}
+
+// Decrypt attempts to decrypt an encrypted session key and returns the key and
+// the cipher to use when decrypting a subsequent Symmetrically Encrypted Data
+// packet.
+func (ske *SymmetricKeyEncrypted) Decrypt(passphrase []byte) ([]byte, CipherFunction, error) {
+    key := make([]byte, ske.CipherFunc.KeySize())
+    ske.s2k(key, passphrase)
+
+    if ske.Version == 6 {
+        aead, err := ske.newAEAD(key)
+        if err != nil {
+            return nil, ske.CipherFunc, err
+        }
+
+        plaintextKey, err := aead.Open(nil, ske.iv, ske.encryptedKey, ske.aeadInfo())
+        if err != nil {
+            return nil, ske.CipherFunc, errors.ErrKeyIncorrect
+        }
+
+        return plaintextKey, ske.CipherFunc, nil
+    }
+
+    if len(ske.encryptedKey) == 0 {
+        return key, ske.CipherFunc, nil
+    }
+
+    // the IV is all zeros
+    iv := make([]byte, ske.CipherFunc.blockSize())
+    c := cipher.NewCFBDecrypter(ske.CipherFunc.new(key), iv)
+    plaintextKey := make([]byte, len(ske.encryptedKey))
+    c.XORKeyStream(plaintextKey, ske.encryptedKey)
+    cipherFunc := CipherFunction(plaintextKey[0])
+    if cipherFunc.blockSize() == 0 {
+        return nil, ske.CipherFunc, errors.UnsupportedError("unknown cipher: " + strconv.Itoa(int(cipherFunc)))
+    }
+    plaintextKey = plaintextKey[1:]
+    if l, cipherKeySize := len(plaintextKey), cipherFunc.KeySize(); l != cipherFunc.KeySize() {
+        return nil, cipherFunc, errors.StructuralError("length of decrypted key (" + strconv.Itoa(l) + ") " +
+            "not equal to cipher keysize (" + strconv.Itoa(cipherKeySize) + ")")
+    }
+    return plaintextKey, cipherFunc, nil
+}
+
+// SerializeSymmetricKeyEncrypted serializes a symmetric key packet to w. The
+// packet contains a random session key, encrypted by a key derived from the
+// given passphrase. The session key is returned and must be passed to
+// SerializeSymmetricallyEncrypted.
+// If config.AEADConfig is set, a v6 packet is written, otherwise a v4 one.
+// If config is nil, sensible defaults will be used.
+func SerializeSymmetricKeyEncrypted(w io.Writer, passphrase []byte, config *Config) (key []byte, err error) {
+    cipherFunc := config.Cipher()
+    keySize := cipherFunc.KeySize()
+    if keySize == 0 {
+        return nil, errors.UnsupportedError("unknown cipher: " + strconv.Itoa(int(cipherFunc)))
+    }
+
+    sessionKey := make([]byte, keySize)
+    _, err = io.ReadFull(config.Random(), sessionKey)
+    if err != nil {
+        return
+    }
+
+    err = SerializeSymmetricKeyEncryptedReuseKey(w, sessionKey, passphrase, config)
+    if err != nil {
+        return
+    }
+
+    key = sessionKey
+    return
+}
+
+// SerializeSymmetricKeyEncryptedReuseKey serializes a symmetric key packet
+// to w, which protects the given session key with the passphrase.
+// If config is nil, sensible defaults will be used.
+func SerializeSymmetricKeyEncryptedReuseKey(w io.Writer, sessionKey []byte, passphrase []byte, config *Config) (err error) {
+    ske := &SymmetricKeyEncrypted{
+        Version:    4,
+        CipherFunc: config.Cipher(),
+    }
+    if config.AEAD() != nil {
+        ske.Version = 6
+        ske.Mode = config.AEAD().Mode()
+    }
+
+    keySize := ske.CipherFunc.KeySize()
+    if keySize == 0 {
+        return errors.UnsupportedError("unknown cipher: " + strconv.Itoa(int(ske.CipherFunc)))
+    }
+
+    s2kBuf := new(bytes.Buffer)
+    keyEncryptingKey := make([]byte, keySize)
+    // s2k.Serialize salts and stretches the passphrase, and writes the
+    // resulting key to keyEncryptingKey and the s2k descriptor to s2kBuf.
+    err = s2k.Serialize(s2kBuf, keyEncryptingKey, config.Random(), passphrase, config.S2K())
+    if err != nil {
+        return
+    }
+    s2kBytes := s2kBuf.Bytes()
+
+    var buf bytes.Buffer
+    buf.WriteByte(byte(ske.Version))
+
+    if ske.Version == 6 {
+        aead, err := ske.newAEAD(keyEncryptingKey)
+        if err != nil {
+            return err
+        }
+
+        ske.iv = make([]byte, ske.Mode.NonceLength())
+        if _, err = io.ReadFull(config.Random(), ske.iv); err != nil {
+            return err
+        }
+
+        buf.WriteByte(byte(3 + len(s2kBytes) + len(ske.iv)))
+        buf.WriteByte(byte(ske.CipherFunc))
+        buf.WriteByte(byte(ske.Mode))
+        buf.WriteByte(byte(len(s2kBytes)))
+        buf.Write(s2kBytes)
+        buf.Write(ske.iv)
+        buf.Write(aead.Seal(nil, ske.iv, sessionKey, ske.aeadInfo()))
+    } else {
+        buf.WriteByte(byte(ske.CipherFunc))
+        buf.Write(s2kBytes)
+
+        iv := make([]byte, ske.CipherFunc.blockSize())
+        c := cipher.NewCFBEncrypter(ske.CipherFunc.new(keyEncryptingKey), iv)
+        encryptedCipherAndKey := make([]byte, len(sessionKey)+1)
+        c.XORKeyStream(encryptedCipherAndKey, []byte{byte(ske.CipherFunc)})
+        c.XORKeyStream(encryptedCipherAndKey[1:], sessionKey)
+        buf.Write(encryptedCipherAndKey)
+    }
+
+    err = serializeHeader(w, packetTypeSymmetricKeyEncrypted, buf.Len())
+    if err != nil {
+        return
+    }
+
+    _, err = w.Write(buf.Bytes())
+    return
+}
@@ -0,0 +1,623 @@
+package packet
+
+import (
+    "io"
+    "hash"
+    "bytes"
+    "strconv"
+    "crypto/sha1"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/mode"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// SymmetricallyEncrypted represents a symmetrically encrypted byte string. The
+// encrypted contents will consist of more OpenPGP packets. See RFC 9580,
+// sections 5.7 and 5.13.
+type SymmetricallyEncrypted struct {
+    // Version 为 1 或者 2, 只在 MDC 为 true 时有效
+    Version  int
+    MDC      bool // true iff this is a type 18 packet and thus has an embedded MAC.
+    contents io.Reader
+    prefix   []byte
+
+    // v2 使用
+    Cipher        CipherFunction
+    Mode          AEADMode
+    ChunkSizeByte byte
+    Salt          [32]byte
+}
+
+const (
+    symmetricallyEncryptedVersionMdc  = 1
+    symmetricallyEncryptedVersionAEAD = 2
+)
+
+func (se *SymmetricallyEncrypted) parse(r io.Reader) error {
+    if se.MDC {
+        // See RFC 9580, section 5.13.
+        var buf [1]byte
+        _, err := readFull(r, buf[:])
+        if err != nil {
+            return err
+        }
+
+        se.Version = int(buf[0])
+        switch se.Version {
+            case symmetricallyEncryptedVersionMdc:
+            case symmetricallyEncryptedVersionAEAD:
+                if err = se.parseAEADHeader(r); err != nil {
+                    return err
+                }
+            default:
+                return errors.UnsupportedError("unknown SymmetricallyEncrypted version " + strconv.Itoa(se.Version))
+        }
+    }
+
+    se.contents = r
+    return nil
+}
+
+func (se *SymmetricallyEncrypted) parseAEADHeader(r io.Reader) error {
+    var buf [3]byte
+    if _, err := readFull(r, buf[:]); err != nil {
+        return err
+    }
+
+    se.Cipher = CipherFunction(buf[0])
+    if se.Cipher.blockSize() != 16 {
+        return errors.UnsupportedError("unsupported cipher for SEIPD v2: " + strconv.Itoa(int(buf[0])))
+    }
+
+    se.Mode = AEADMode(buf[1])
+    if !se.Mode.IsSupported() {
+        return errors.UnsupportedError("unknown AEAD mode: " + strconv.Itoa(int(buf[1])))
+    }
+
+    se.ChunkSizeByte = buf[2]
+    if se.ChunkSizeByte > 16 {
+        return errors.UnsupportedError("invalid chunk size: " + strconv.Itoa(int(buf[2])))
+    }
+
+    _, err := readFull(r, se.Salt[:])
+    return err
+}
+
+// Decrypt returns a ReadCloser, from which the decrypted contents of the
+// packet can be read. An incorrect key can, with high probability, be detected
+// immediately and this will result in a KeyIncorrect error being returned.
+// For v2 packets the cipher is taken from the packet and c is ignored.
+func (se *SymmetricallyEncrypted) Decrypt(c CipherFunction, key []byte) (io.ReadCloser, error) {
+    if se.Version == symmetricallyEncryptedVersionAEAD {
+        return se.decryptAEAD(key)
+    }
+
+    keySize := c.KeySize()
+    if keySize == 0 {
+        return nil, errors.UnsupportedError("unknown cipher: " + strconv.Itoa(int(c)))
+    }
+    if len(key) != keySize {
+        return nil, errors.InvalidArgumentError("SymmetricallyEncrypted: incorrect key length")
+    }
+
+    if se.prefix == nil {
+        se.prefix = make([]byte, c.blockSize()+2)
+        _, err := readFull(se.contents, se.prefix)
+        if err != nil {
+            return nil, err
+        }
+    } else if len(se.prefix) != c.blockSize()+2 {
+        return nil, errors.InvalidArgumentError("can't try ciphers with different block lengths")
+    }
+
+    ocfbResync := mode.OCFBResync
+    if se.MDC {
+        // MDC packets use a different form of OCFB mode.
+        ocfbResync = mode.OCFBNoResync
+    }
+
+    block := c.new(key)
+    if !ocfbQuickCheck(block, se.prefix) {
+        return nil, errors.ErrKeyIncorrect
+    }
+
+    s := mode.NewOCFBDecrypter(block, se.prefix, ocfbResync)
+
+    plaintext := cipher.StreamReader{S: s, R: se.contents}
+
+    if se.MDC {
+        // MDC packets have an embedded hash that we need to check.
+        h := sha1.New()
+        h.Write(se.prefix)
+        return &seMDCReader{in: plaintext, h: h}, nil
+    }
+
+    // Otherwise, we just need to wrap plaintext so that it's a valid ReadCloser.
+    return seReader{plaintext}, nil
+}
+
+// ocfbQuickCheck 检测前缀最后两个字节是否重复, 用于快速判断密钥是否错误
+func ocfbQuickCheck(block cipher.Block, prefix []byte) bool {
+    blockSize := block.BlockSize()
+
+    fre := make([]byte, blockSize)
+    block.Encrypt(fre, fre)
+    a := prefix[blockSize-2] ^ fre[blockSize-2]
+    b := prefix[blockSize-1] ^ fre[blockSize-1]
+
+    block.Encrypt(fre, prefix[:blockSize])
+    c := prefix[blockSize] ^ fre[0]
+    d := prefix[blockSize+1] ^ fre[1]
+
+    return a == c && b == d
+}
+
+// seReader wraps an io.Reader with a no-op Close method.
+type seReader struct {
+    in io.Reader
+}
+
+func (ser seReader) Read(buf []byte) (int, error) {
+    return ser.in.Read(buf)
+}
+
+func (ser seReader) Close() error {
+    return nil
+}
+
+const mdcTrailerSize = 1 /* tag byte */ + 1 /* length byte */ + sha1.Size
+
+// An seMDCReader wraps an io.Reader, maintains a running hash and keeps hold
+// of the most recent 22 bytes (mdcTrailerSize). Upon EOF, those bytes form an
+// MDC packet containing a hash of the previous contents which is checked
+// against the running hash. See RFC 9580, section 5.13.1.
+type seMDCReader struct {
+    in          io.Reader
+    h           hash.Hash
+    trailer     [mdcTrailerSize]byte
+    scratch     [mdcTrailerSize]byte
+    trailerUsed int
+    error       bool
+    eof         bool
+}
+
+func (ser *seMDCReader) Read(buf []byte) (n int, err error) {
+    if ser.error {
+        err = io.ErrUnexpectedEOF
+        return
+    }
+    if ser.eof {
+        err = io.EOF
+        return
+    }
+
+    // If we haven't yet filled the trailer buffer then we must do that
+    // first.
+    for ser.trailerUsed < mdcTrailerSize {
+        n, err = ser.in.Read(ser.trailer[ser.trailerUsed:])
+        ser.trailerUsed += n
+        if err == io.EOF {
+            if ser.trailerUsed != mdcTrailerSize {
+                n = 0
+                err = io.ErrUnexpectedEOF
+                ser.error = true
+                return
+            }
+            ser.eof = true
+            n = 0
+            return
+        }
+
+        if err != nil {
+            n = 0
+            return
+        }
+    }
+
+    // If it's a short read then we read into a temporary buffer and shift
+    // the data into the caller's buffer.
+    if len(buf) <= mdcTrailerSize {
+        n, err = readFull(ser.in, ser.scratch[:len(buf)])
+        copy(buf, ser.trailer[:n])
+        ser.h.Write(buf[:n])
+        copy(ser.trailer[:], ser.trailer[n:])
+        copy(ser.trailer[mdcTrailerSize-n:], ser.scratch[:])
+        if n < len(buf) {
+            ser.eof = true
+            err = io.EOF
+        }
+        return
+    }
+
+    n, err = ser.in.Read(buf[mdcTrailerSize:])
+    copy(buf, ser.trailer[:])
+    ser.h.Write(buf[:n])
+    copy(ser.trailer[:], buf[n:])
+
+    if err == io.EOF {
+        ser.eof = true
+    }
+    return
+}
+
+// This is a new-format packet tag byte for a type 19 (MDC) packet.
+const mdcPacketTagByte = byte(0x80) | 0x40 | 19
+
+func (ser *seMDCReader) Close() error {
+    if ser.error {
+        return errors.SignatureError("error during reading")
+    }
+
+    for !ser.eof {
+        // We haven't seen EOF so we need to read to the end
+        var buf [1024]byte
+        _, err := ser.Read(buf[:])
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return errors.SignatureError("error during reading")
+        }
+    }
+
+    if ser.trailer[0] != mdcPacketTagByte || ser.trailer[1] != sha1.Size {
+        return errors.SignatureError("MDC packet not found")
+    }
+    ser.h.Write(ser.trailer[:2])
+
+    final := ser.h.Sum(nil)
+    if subtle.ConstantTimeCompare(final, ser.trailer[2:]) != 1 {
+        return errors.SignatureError("hash mismatch")
+    }
+    return nil
+}
+
+// An seMDCWriter writes through to an io.WriteCloser while maintains a running
+// hash of the data written. On close, it emits an MDC packet containing the
+// running hash.
+type seMDCWriter struct {
+    w io.WriteCloser
+    h hash.Hash
+}
+
+func (w *seMDCWriter) Write(buf []byte) (n int, err error) {
+    w.h.Write(buf)
+    return w.w.Write(buf)
+}
+
+func (w *seMDCWriter) Close() (err error) {
+    var buf [mdcTrailerSize]byte
+
+    buf[0] = mdcPacketTagByte
+    buf[1] = sha1.Size
+    w.h.Write(buf[:2])
+    digest := w.h.Sum(nil)
+    copy(buf[2:], digest)
+
+    _, err = w.w.Write(buf[:])
+    if err != nil {
+        return
+    }
+    return w.w.Close()
+}
+
+// noOpCloser is like an io.NopCloser, but for an io.Writer.
+type noOpCloser struct {
+    w io.Writer
+}
+
+func (c noOpCloser) Write(data []byte) (n int, err error) {
+    return c.w.Write(data)
+}
+
+func (c noOpCloser) Close() error {
+    return nil
+}
+
+// SerializeSymmetricallyEncrypted serializes a symmetrically encrypted packet
+// to w and returns a WriteCloser to which the to-be-encrypted packets can be
+// written. If config.AEADConfig is set, a v2 packet is written.
+// If config is nil, sensible defaults will be used.
+func SerializeSymmetricallyEncrypted(w io.Writer, c CipherFunction, key []byte, config *Config) (contents io.WriteCloser, err error) {
+    if c.KeySize() != len(key) {
+        return nil, errors.InvalidArgumentError("SymmetricallyEncrypted.Serialize: bad key length")
+    }
+    writeCloser := noOpCloser{w}
+    ciphertext, err := serializeStreamHeader(writeCloser, packetTypeSymmetricallyEncryptedMDC)
+    if err != nil {
+        return
+    }
+
+    if config.AEAD() != nil {
+        return serializeSymmetricallyEncryptedAEAD(ciphertext, c, key, config)
+    }
+
+    _, err = ciphertext.Write([]byte{symmetricallyEncryptedVersionMdc})
+    if err != nil {
+        return
+    }
+
+    block := c.new(key)
+    blockSize := block.BlockSize()
+    iv := make([]byte, blockSize)
+    _, err = io.ReadFull(config.Random(), iv)
+    if err != nil {
+        return
+    }
+    s, prefix := mode.NewOCFBEncrypter(block, iv, mode.OCFBNoResync)
+    _, err = ciphertext.Write(prefix)
+    if err != nil {
+        return
+    }
+    plaintext := cipher.StreamWriter{S: s, W: ciphertext}
+
+    h := sha1.New()
+    h.Write(iv)
+    h.Write(iv[blockSize-2:])
+    contents = &seMDCWriter{w: plaintext, h: h}
+    return
+}
+
+// aeadInfo returns the packet header octet, version, cipher, AEAD mode and
+// chunk size octet.
+func (se *SymmetricallyEncrypted) aeadInfo() []byte {
+    return []byte{
+        0xC0 | byte(packetTypeSymmetricallyEncryptedMDC),
+        symmetricallyEncryptedVersionAEAD,
+        byte(se.Cipher),
+        byte(se.Mode),
+        se.ChunkSizeByte,
+    }
+}
+
+// aeadCrypter 派生消息密钥及 IV
+func (se *SymmetricallyEncrypted) aeadCrypter(key []byte) (*aeadCrypter, error) {
+    if len(key) != se.Cipher.KeySize() {
+        return nil, errors.InvalidArgumentError("SymmetricallyEncrypted: incorrect key length")
+    }
+
+    info := se.aeadInfo()
+    ivLen := se.Mode.NonceLength() - 8
+
+    derived, err := deriveAEADKey(key, se.Salt[:], info, len(key)+ivLen)
+    if err != nil {
+        return nil, err
+    }
+
+    aead, err := se.Mode.new(se.Cipher.new(derived[:len(key)]))
+    if err != nil {
+        return nil, err
+    }
+
+    return &aeadCrypter{
+        aead:      aead,
+        iv:        derived[len(key):],
+        ad:        info,
+        chunkSize: 1 << (se.ChunkSizeByte + 6),
+    }, nil
+}
+
+func (se *SymmetricallyEncrypted) decryptAEAD(key []byte) (io.ReadCloser, error) {
+    c, err := se.aeadCrypter(key)
+    if err != nil {
+        return nil, err
+    }
+
+    return &aeadDecrypter{
+        aeadCrypter: c,
+        r:           se.contents,
+        buf:         make([]byte, 0, c.chunkSize+2*aeadTagLength),
+    }, nil
+}
+
+func serializeSymmetricallyEncryptedAEAD(ciphertext io.WriteCloser, c CipherFunction, key []byte, config *Config) (io.WriteCloser, error) {
+    se := &SymmetricallyEncrypted{
+        Version:       symmetricallyEncryptedVersionAEAD,
+        MDC:           true,
+        Cipher:        c,
+        Mode:          config.AEAD().Mode(),
+        ChunkSizeByte: config.AEAD().ChunkSizeByte(),
+    }
+    if c.blockSize() != 16 {
+        return nil, errors.InvalidArgumentError("SEIPD v2 needs 16 bytes block cipher")
+    }
+
+    if _, err := io.ReadFull(config.Random(), se.Salt[:]); err != nil {
+        return nil, err
+    }
+
+    crypter, err := se.aeadCrypter(key)
+    if err != nil {
+        return nil, err
+    }
+
+    header := append([]byte{symmetricallyEncryptedVersionAEAD}, se.aeadInfo()[2:]...)
+    header = append(header, se.Salt[:]...)
+    if _, err = ciphertext.Write(header); err != nil {
+        return nil, err
+    }
+
+    return &aeadEncrypter{
+        aeadCrypter: crypter,
+        w:           ciphertext,
+        buf:         make([]byte, 0, crypter.chunkSize),
+    }, nil
+}
+
+// aeadCrypter 为 SEIPD v2 分块加解密的公共部分. See RFC 9580, section 5.13.2.
+type aeadCrypter struct {
+    aead      cipher.AEAD
+    iv        []byte
+    ad        []byte
+    chunkSize int
+    index     uint64
+    total     uint64
+}
+
+// nonce 为 IV 与块序号的拼接
+func (c *aeadCrypter) nonce() []byte {
+    nonce := make([]byte, len(c.iv)+8)
+    copy(nonce, c.iv)
+    binary.BigEndian.PutUint64(nonce[len(c.iv):], c.index)
+    return nonce
+}
+
+// finalAD 为最终认证标签的附加数据
+func (c *aeadCrypter) finalAD() []byte {
+    return binary.BigEndian.AppendUint64(bytes.Clone(c.ad), c.total)
+}
+
+// aeadDecrypter 分块解密并校验 SEIPD v2 数据
+type aeadDecrypter struct {
+    *aeadCrypter
+    r     io.Reader
+    buf   []byte
+    plain []byte
+    err   error
+}
+
+func (d *aeadDecrypter) Read(p []byte) (n int, err error) {
+    for len(d.plain) == 0 {
+        if d.err != nil {
+            return 0, d.err
+        }
+
+        d.plain, d.err = d.next()
+    }
+
+    n = copy(p, d.plain)
+    d.plain = d.plain[n:]
+    return
+}
+
+// next 读取并解密下一个分块, 最后一块之后校验最终标签
+func (d *aeadDecrypter) next() ([]byte, error) {
+    // 多读取一个标签长度, 用于判断是否为最后一块
+    start := len(d.buf)
+    d.buf = d.buf[:cap(d.buf)]
+    n, err := io.ReadFull(d.r, d.buf[start:])
+    d.buf = d.buf[:start+n]
+
+    eof := false
+    switch err {
+        case nil:
+        case io.EOF, io.ErrUnexpectedEOF:
+            eof = true
+        default:
+            return nil, err
+    }
+
+    if !eof {
+        chunk := d.buf[:d.chunkSize+aeadTagLength]
+        plain, err := d.openChunk(chunk)
+        if err != nil {
+            return nil, err
+        }
+
+        rest := copy(d.buf, d.buf[len(chunk):])
+        d.buf = d.buf[:rest]
+        return plain, nil
+    }
+
+    if len(d.buf) < aeadTagLength {
+        return nil, io.ErrUnexpectedEOF
+    }
+
+    var plain []byte
+    if len(d.buf) > aeadTagLength {
+        if len(d.buf) < 2*aeadTagLength {
+            return nil, io.ErrUnexpectedEOF
+        }
+
+        plain, err = d.openChunk(d.buf[:len(d.buf)-aeadTagLength])
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    finalTag := d.buf[len(d.buf)-aeadTagLength:]
+    if _, err = d.aead.Open(nil, d.nonce(), finalTag, d.finalAD()); err != nil {
+        return nil, errors.SignatureError("AEAD final tag mismatch")
+    }
+
+    return plain, io.EOF
+}
+
+func (d *aeadDecrypter) openChunk(chunk []byte) ([]byte, error) {
+    plain, err := d.aead.Open(nil, d.nonce(), chunk, d.ad)
+    if err != nil {
+        return nil, errors.SignatureError("AEAD chunk authentication failed")
+    }
+
+    d.index++
+    d.total += uint64(len(plain))
+    return plain, nil
+}
+
+func (d *aeadDecrypter) Close() error {
+    // 需要读取到结尾以校验最终标签
+    var buf [1024]byte
+    for {
+        _, err := d.Read(buf[:])
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+    }
+}
+
+// aeadEncrypter 分块加密 SEIPD v2 数据
+type aeadEncrypter struct {
+    *aeadCrypter
+    w   io.WriteCloser
+    buf []byte
+}
+
+func (e *aeadEncrypter) Write(p []byte) (n int, err error) {
+    for len(p) > 0 {
+        m := copy(e.buf[len(e.buf):cap(e.buf)], p)
+        e.buf = e.buf[:len(e.buf)+m]
+        p = p[m:]
+        n += m
+
+        if len(e.buf) == e.chunkSize {
+            if err = e.sealChunk(); err != nil {
+                return
+            }
+        }
+    }
+
+    return
+}
+
+func (e *aeadEncrypter) sealChunk() error {
+    ciphertext := e.aead.Seal(nil, e.nonce(), e.buf, e.ad)
+    if _, err := e.w.Write(ciphertext); err != nil {
+        return err
+    }
+
+    e.index++
+    e.total += uint64(len(e.buf))
+    e.buf = e.buf[:0]
+    return nil
+}
+
+func (e *aeadEncrypter) Close() error {
+    if len(e.buf) > 0 {
+        if err := e.sealChunk(); err != nil {
+            return err
+        }
+    }
+
+    finalTag := e.aead.Seal(nil, e.nonce(), nil, e.finalAD())
+    if _, err := e.w.Write(finalTag); err != nil {
+        return err
+    }
+
+    return e.w.Close()
+}
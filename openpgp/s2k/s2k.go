@@ -0,0 +1,480 @@
+// Package s2k implements the various OpenPGP string-to-key transforms as
+// specified in RFC 9580 section 3.7.
+package s2k
+
+import (
+    "io"
+    "hash"
+    "crypto"
+    "strconv"
+
+    _ "golang.org/x/crypto/sha3"
+
+    "github.com/deatil/go-cryptobin/kdf/argon2"
+    "github.com/deatil/go-cryptobin/kdf/pgp_s2k"
+    "github.com/deatil/go-cryptobin/openpgp/errors"
+)
+
+// Mode 为 S2K 类型. See RFC 9580 section 3.7.1.
+type Mode uint8
+
+const (
+    SimpleS2K         Mode = 0
+    SaltedS2K         Mode = 1
+    IteratedSaltedS2K Mode = 3
+    Argon2S2K         Mode = 4
+    GnuS2K            Mode = 101
+)
+
+// Argon2Config 为 Argon2 参数
+type Argon2Config struct {
+    // NumberOfPasses 为 t, 默认为 3
+    NumberOfPasses uint8
+    // DegreeOfParallelism 为 p, 默认为 4
+    DegreeOfParallelism uint8
+    // MemoryExponent 为 m, 使用内存为 2^m KiB, 默认为 16 (64 MiB)
+    MemoryExponent uint8
+}
+
+func (c *Argon2Config) passes() uint8 {
+    if c == nil || c.NumberOfPasses == 0 {
+        return 3
+    }
+    return c.NumberOfPasses
+}
+
+func (c *Argon2Config) parallelism() uint8 {
+    if c == nil || c.DegreeOfParallelism == 0 {
+        return 4
+    }
+    return c.DegreeOfParallelism
+}
+
+func (c *Argon2Config) memoryExponent() uint8 {
+    // 最小值为 3 + ceil(log2(p))
+    min := uint8(3)
+    for p := c.parallelism() - 1; p > 0; p >>= 1 {
+        min++
+    }
+
+    if c == nil || c.MemoryExponent == 0 {
+        if min > 16 {
+            return min
+        }
+        return 16
+    }
+    if c.MemoryExponent < min {
+        return min
+    }
+    if c.MemoryExponent > 31 {
+        return 31
+    }
+    return c.MemoryExponent
+}
+
+// Config collects configuration parameters for s2k key-stretching
+// transformatioms. A nil *Config is valid and results in all default
+// values. Currently, Config is used only by the Serialize function in
+// this package.
+type Config struct {
+    // S2KMode 为 S2K 类型, 默认为 IteratedSaltedS2K
+    S2KMode Mode
+    // Hash is the default hash function to be used. If
+    // nil, SHA1 is used.
+    Hash crypto.Hash
+    // S2KCount is only used for symmetric encryption. It
+    // determines the strength of the passphrase stretching when
+    // the said passphrase is hashed to produce a key. S2KCount
+    // should be between 1024 and 65011712, inclusive. If Config
+    // is nil or S2KCount is 0, the value 65536 used. Not all
+    // values in the above range can be represented. S2KCount will
+    // be rounded up to the next representable value if it cannot
+    // be encoded exactly. When set, it is strongly encrouraged to
+    // use a value that is at least 65536. See RFC 4880 Section
+    // 3.7.1.3.
+    S2KCount int
+    // Argon2Config 为 Argon2S2K 使用的参数
+    Argon2Config *Argon2Config
+}
+
+func (c *Config) mode() Mode {
+    if c == nil || c.S2KMode == SimpleS2K {
+        return IteratedSaltedS2K
+    }
+
+    return c.S2KMode
+}
+
+func (c *Config) hash() crypto.Hash {
+    if c == nil || uint(c.Hash) == 0 {
+        // SHA1 is the historical default in this package.
+        return crypto.SHA1
+    }
+
+    return c.Hash
+}
+
+func (c *Config) encodedCount() uint8 {
+    if c == nil || c.S2KCount == 0 {
+        return 96 // The common case. Correspoding to 65536
+    }
+
+    i := c.S2KCount
+    switch {
+        // Behave like GPG. Should we make 65536 the lowest value used?
+        case i < 1024:
+            i = 1024
+        case i > 65011712:
+            i = 65011712
+    }
+
+    return encodeCount(i)
+}
+
+// encodeCount converts an iterative "count" in the range 1024 to
+// 65011712, inclusive, to an encoded count. The return value is the
+// octet that is actually stored in the GPG file. encodeCount panics
+// if i is not in the above range (encodedCount above takes care to
+// pass i in the correct range). See RFC 4880 Section 3.7.7.1.
+func encodeCount(i int) uint8 {
+    if i < 1024 || i > 65011712 {
+        panic("count arg i outside the required range")
+    }
+
+    for encoded := 0; encoded < 256; encoded++ {
+        count := decodeCount(uint8(encoded))
+        if count >= i {
+            return uint8(encoded)
+        }
+    }
+
+    return 255
+}
+
+// decodeCount returns the s2k mode 3 iterative "count" corresponding to
+// the encoded octet c.
+func decodeCount(c uint8) int {
+    return (16 + int(c&15)) << (uint32(c>>4) + 6)
+}
+
+// Simple writes to out the result of computing the Simple S2K function (RFC
+// 9580, section 3.7.1.1) using the given hash and input passphrase.
+func Simple(out []byte, h hash.Hash, in []byte) {
+    Salted(out, h, in, nil)
+}
+
+// Salted writes to out the result of computing the Salted S2K function (RFC
+// 9580, section 3.7.1.2) using the given hash, input passphrase and salt.
+func Salted(out []byte, h hash.Hash, in []byte, salt []byte) {
+    Iterated(out, h, in, salt, 1)
+}
+
+// Iterated writes to out the result of computing the Iterated and Salted S2K
+// function (RFC 9580, section 3.7.1.3) using the given hash, input passphrase,
+// salt and iteration count.
+func Iterated(out []byte, h hash.Hash, in []byte, salt []byte, count int) {
+    newHash := func() hash.Hash {
+        return h
+    }
+
+    copy(out, pgp_s2k.Key(newHash, in, salt, count, len(out)))
+}
+
+// Argon2 writes to out the result of computing the Argon2 S2K function
+// (RFC 9580, section 3.7.1.4) using the given passphrase, salt and params.
+func Argon2(out []byte, in []byte, salt []byte, passes, parallelism, memoryExp uint8) {
+    key := argon2.IDKey(in, salt, uint32(passes), uint32(1)<<memoryExp, parallelism, uint32(len(out)))
+    copy(out, key)
+}
+
+// Params 为解析后的 S2K 参数
+type Params struct {
+    mode Mode
+
+    // Simple, Salted 和 Iterated 使用
+    hashId       uint8
+    encodedCount uint8
+
+    // Argon2 使用
+    passes      uint8
+    parallelism uint8
+    memoryExp   uint8
+
+    salt []byte
+}
+
+// Mode 返回 S2K 类型
+func (params *Params) Mode() Mode {
+    return params.mode
+}
+
+// Dummy 返回是否为 GNU dummy S2K, 此时私钥不可用
+func (params *Params) Dummy() bool {
+    return params.mode == GnuS2K
+}
+
+// ParseIntoParams reads a binary specification for a string-to-key
+// transformation from r and returns its parameters.
+func ParseIntoParams(r io.Reader) (params *Params, err error) {
+    var buf [16]byte
+
+    _, err = io.ReadFull(r, buf[:1])
+    if err != nil {
+        return
+    }
+
+    params = &Params{
+        mode: Mode(buf[0]),
+    }
+
+    switch params.mode {
+        case SimpleS2K, SaltedS2K, IteratedSaltedS2K:
+            _, err = io.ReadFull(r, buf[:1])
+            if err != nil {
+                return
+            }
+            params.hashId = buf[0]
+
+            if params.mode == SimpleS2K {
+                return params, nil
+            }
+
+            params.salt = make([]byte, 8)
+            if _, err = io.ReadFull(r, params.salt); err != nil {
+                return
+            }
+
+            if params.mode == IteratedSaltedS2K {
+                if _, err = io.ReadFull(r, buf[:1]); err != nil {
+                    return
+                }
+                params.encodedCount = buf[0]
+            }
+
+            return params, nil
+        case Argon2S2K:
+            params.salt = make([]byte, 16)
+            if _, err = io.ReadFull(r, params.salt); err != nil {
+                return
+            }
+            if _, err = io.ReadFull(r, buf[:3]); err != nil {
+                return
+            }
+            params.passes = buf[0]
+            params.parallelism = buf[1]
+            params.memoryExp = buf[2]
+
+            if params.passes == 0 || params.parallelism == 0 || params.memoryExp > 31 {
+                return nil, errors.StructuralError("invalid argon2 params")
+            }
+
+            return params, nil
+        case GnuS2K:
+            // GNU 扩展: hash, "GNU", mode
+            if _, err = io.ReadFull(r, buf[:5]); err != nil {
+                return
+            }
+            if string(buf[1:4]) != "GNU" {
+                return nil, errors.UnsupportedError("GNU S2K extension")
+            }
+            if buf[4] != 1 {
+                return nil, errors.UnsupportedError("GNU S2K extension mode: " + strconv.Itoa(int(buf[4])))
+            }
+            params.hashId = buf[0]
+
+            return params, nil
+    }
+
+    return nil, errors.UnsupportedError("S2K function")
+}
+
+// Function returns a function which performs the transform.
+func (params *Params) Function() (f func(out, in []byte), err error) {
+    switch params.mode {
+        case SimpleS2K, SaltedS2K, IteratedSaltedS2K:
+            hash, ok := HashIdToHash(params.hashId)
+            if !ok {
+                return nil, errors.UnsupportedError("hash for S2K function: " + strconv.Itoa(int(params.hashId)))
+            }
+            if !hash.Available() {
+                return nil, errors.UnsupportedError("hash not available: " + strconv.Itoa(int(hash)))
+            }
+
+            switch params.mode {
+                case SimpleS2K:
+                    f = func(out, in []byte) {
+                        Simple(out, hash.New(), in)
+                    }
+                case SaltedS2K:
+                    f = func(out, in []byte) {
+                        Salted(out, hash.New(), in, params.salt)
+                    }
+                default:
+                    count := decodeCount(params.encodedCount)
+                    f = func(out, in []byte) {
+                        Iterated(out, hash.New(), in, params.salt, count)
+                    }
+            }
+
+            return f, nil
+        case Argon2S2K:
+            f = func(out, in []byte) {
+                Argon2(out, in, params.salt, params.passes, params.parallelism, params.memoryExp)
+            }
+
+            return f, nil
+        case GnuS2K:
+            return nil, errors.ErrDummyPrivateKey("dummy key found")
+    }
+
+    return nil, errors.UnsupportedError("S2K function")
+}
+
+// Serialize writes the binary specification of params to w.
+func (params *Params) Serialize(w io.Writer) (err error) {
+    buf := []byte{byte(params.mode)}
+
+    switch params.mode {
+        case SimpleS2K:
+            buf = append(buf, params.hashId)
+        case SaltedS2K:
+            buf = append(buf, params.hashId)
+            buf = append(buf, params.salt...)
+        case IteratedSaltedS2K:
+            buf = append(buf, params.hashId)
+            buf = append(buf, params.salt...)
+            buf = append(buf, params.encodedCount)
+        case Argon2S2K:
+            buf = append(buf, params.salt...)
+            buf = append(buf, params.passes, params.parallelism, params.memoryExp)
+        case GnuS2K:
+            buf = append(buf, params.hashId, 'G', 'N', 'U', 1)
+        default:
+            return errors.UnsupportedError("S2K function")
+    }
+
+    _, err = w.Write(buf)
+    return
+}
+
+// Generate 根据配置生成新的 S2K 参数
+func Generate(rand io.Reader, c *Config) (*Params, error) {
+    params := &Params{
+        mode: c.mode(),
+    }
+
+    switch params.mode {
+        case SaltedS2K, IteratedSaltedS2K:
+            hashId, ok := HashToHashId(c.hash())
+            if !ok {
+                return nil, errors.UnsupportedError("no such hash")
+            }
+
+            params.hashId = hashId
+            params.salt = make([]byte, 8)
+            params.encodedCount = c.encodedCount()
+        case Argon2S2K:
+            var argon2Config *Argon2Config
+            if c != nil {
+                argon2Config = c.Argon2Config
+            }
+
+            params.salt = make([]byte, 16)
+            params.passes = argon2Config.passes()
+            params.parallelism = argon2Config.parallelism()
+            params.memoryExp = argon2Config.memoryExponent()
+        default:
+            return nil, errors.InvalidArgumentError("unsupported S2K mode: " + strconv.Itoa(int(params.mode)))
+    }
+
+    if _, err := io.ReadFull(rand, params.salt); err != nil {
+        return nil, err
+    }
+
+    return params, nil
+}
+
+// Parse reads a binary specification for a string-to-key transformation from r
+// and returns a function which performs that transform.
+func Parse(r io.Reader) (f func(out, in []byte), err error) {
+    params, err := ParseIntoParams(r)
+    if err != nil {
+        return nil, err
+    }
+
+    return params.Function()
+}
+
+// Serialize salts and stretches the given passphrase and writes the
+// resulting key into key. It also serializes an S2K descriptor to
+// w. The key stretching can be configured with c, which may be
+// nil. In that case, sensible defaults will be used.
+func Serialize(w io.Writer, key []byte, rand io.Reader, passphrase []byte, c *Config) error {
+    params, err := Generate(rand, c)
+    if err != nil {
+        return err
+    }
+
+    if err = params.Serialize(w); err != nil {
+        return err
+    }
+
+    f, err := params.Function()
+    if err != nil {
+        return err
+    }
+
+    f(key, passphrase)
+    return nil
+}
+
+// hashToHashIdMapping contains pairs relating OpenPGP's hash identifier with
+// Go's crypto.Hash type. See RFC 9580, section 9.5.
+var hashToHashIdMapping = []struct {
+    id   byte
+    hash crypto.Hash
+    name string
+}{
+    {1, crypto.MD5, "MD5"},
+    {2, crypto.SHA1, "SHA1"},
+    {3, crypto.RIPEMD160, "RIPEMD160"},
+    {8, crypto.SHA256, "SHA256"},
+    {9, crypto.SHA384, "SHA384"},
+    {10, crypto.SHA512, "SHA512"},
+    {11, crypto.SHA224, "SHA224"},
+    {12, crypto.SHA3_256, "SHA3-256"},
+    {14, crypto.SHA3_512, "SHA3-512"},
+}
+
+// HashIdToHash returns a crypto.Hash which corresponds to the given OpenPGP
+// hash id.
+func HashIdToHash(id byte) (h crypto.Hash, ok bool) {
+    for _, m := range hashToHashIdMapping {
+        if m.id == id {
+            return m.hash, true
+        }
+    }
+    return 0, false
+}
+
+// HashIdToString returns the name of the hash function corresponding to the
+// given OpenPGP hash id.
+func HashIdToString(id byte) (name string, ok bool) {
+    for _, m := range hashToHashIdMapping {
+        if m.id == id {
+            return m.name, true
+        }
+    }
+
+    return "", false
+}
+
+// HashToHashId returns an OpenPGP hash id which corresponds the given Hash.
+func HashToHashId(h crypto.Hash) (id byte, ok bool) {
+    for _, m := range hashToHashIdMapping {
+        if m.hash == h {
+            return m.id, true
+        }
+    }
+    return 0, false
+}
@@ -0,0 +1,131 @@
+package pinblock
+
+import (
+    "io"
+    "crypto/rand"
+    "crypto/cipher"
+    "crypto/subtle"
+)
+
+const format4Size = 16
+
+// EncryptFormat4 returns the ISO 9564 format 4 enciphered PIN block
+// with the AES PIN encryption key:
+// E(E(PIN field) XOR PAN field).
+func EncryptFormat4(b cipher.Block, pin, pan string) ([]byte, error) {
+    return encryptFormat4(rand.Reader, b, pin, pan)
+}
+
+func encryptFormat4(random io.Reader, b cipher.Block, pin, pan string) ([]byte, error) {
+    if b.BlockSize() != format4Size {
+        return nil, errFormat4BlockSize
+    }
+
+    pinField, err := format4PINField(random, pin)
+    if err != nil {
+        return nil, err
+    }
+
+    panField, err := format4PANField(pan)
+    if err != nil {
+        return nil, err
+    }
+
+    out := make([]byte, format4Size)
+    b.Encrypt(out, pinField)
+    subtle.XORBytes(out, out, panField)
+    b.Encrypt(out, out)
+
+    return out, nil
+}
+
+// DecryptFormat4 returns the PIN of the format 4 enciphered PIN block.
+func DecryptFormat4(b cipher.Block, pinBlock []byte, pan string) (string, error) {
+    if b.BlockSize() != format4Size {
+        return "", errFormat4BlockSize
+    }
+
+    if len(pinBlock) != format4Size {
+        return "", ErrInvalidPINBlock
+    }
+
+    panField, err := format4PANField(pan)
+    if err != nil {
+        return "", err
+    }
+
+    pinField := make([]byte, format4Size)
+    b.Decrypt(pinField, pinBlock)
+    subtle.XORBytes(pinField, pinField, panField)
+    b.Decrypt(pinField, pinField)
+
+    nibbles := unpackNibbles(pinField[:8])
+    if nibbles[0] != format4 {
+        return "", ErrInvalidPINBlock
+    }
+
+    pin, err := parsePIN(nibbles)
+    if err != nil {
+        return "", err
+    }
+
+    for _, c := range nibbles[2+len(pin):] {
+        if c != 0xA {
+            return "", ErrInvalidPINBlock
+        }
+    }
+
+    return pin, nil
+}
+
+// PIN 字段: 4 || N || PIN || 填充 A || 8 字节随机数
+func format4PINField(random io.Reader, pin string) ([]byte, error) {
+    if !isPIN(pin) {
+        return nil, ErrInvalidPIN
+    }
+
+    var nibbles [16]byte
+    nibbles[0] = format4
+    nibbles[1] = byte(len(pin))
+
+    for i := 0; i < len(pin); i++ {
+        nibbles[2+i] = pin[i] - '0'
+    }
+
+    for i := 2 + len(pin); i < len(nibbles); i++ {
+        nibbles[i] = 0xA
+    }
+
+    field := make([]byte, format4Size)
+    copy(field, packNibbles(nibbles[:]))
+
+    if _, err := io.ReadFull(random, field[8:]); err != nil {
+        return nil, err
+    }
+
+    return field, nil
+}
+
+// PAN 字段: M || PAN || 填充 0, M 为 PAN 长度减 12.
+// 不足 12 位的 PAN 左边补 0
+func format4PANField(pan string) ([]byte, error) {
+    if len(pan) == 0 || len(pan) > 19 || !isDigits(pan) {
+        return nil, ErrInvalidPAN
+    }
+
+    m := 0
+    if len(pan) < 12 {
+        pan = "000000000000"[len(pan):] + pan
+    } else {
+        m = len(pan) - 12
+    }
+
+    var nibbles [32]byte
+    nibbles[0] = byte(m)
+
+    for i := 0; i < len(pan); i++ {
+        nibbles[1+i] = pan[i] - '0'
+    }
+
+    return packNibbles(nibbles[:]), nil
+}
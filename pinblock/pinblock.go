@@ -0,0 +1,246 @@
+// Package pinblock implements the ISO 9564-1 PIN block formats 0, 1, 3
+// and the AES PIN block format 4.
+package pinblock
+
+import (
+    "io"
+    "errors"
+    "crypto/rand"
+    "crypto/cipher"
+    "crypto/subtle"
+)
+
+var (
+    ErrInvalidPIN      = errors.New("go-cryptobin/pinblock: PIN must be 4 to 12 digits")
+    ErrInvalidPAN      = errors.New("go-cryptobin/pinblock: invalid PAN")
+    ErrInvalidPINBlock = errors.New("go-cryptobin/pinblock: invalid PIN block")
+
+    errFormat4BlockSize = errors.New("go-cryptobin/pinblock: format 4 requires a 128-bit block cipher")
+)
+
+// 格式的控制字段
+const (
+    format0 = 0x0
+    format1 = 0x1
+    format3 = 0x3
+    format4 = 0x4
+)
+
+// EncodeFormat0 returns the ISO 9564 format 0 (ANSI X9.8) clear PIN block.
+// PAN is the primary account number with the check digit.
+func EncodeFormat0(pin, pan string) ([]byte, error) {
+    return encode(format0, pin, pan, nil)
+}
+
+// DecodeFormat0 returns the PIN of the format 0 clear PIN block.
+func DecodeFormat0(pinBlock []byte, pan string) (string, error) {
+    return decode(format0, pinBlock, pan)
+}
+
+// EncodeFormat1 returns the ISO 9564 format 1 clear PIN block,
+// the fill digits are random.
+func EncodeFormat1(pin string) ([]byte, error) {
+    return encode(format1, pin, "", rand.Reader)
+}
+
+// DecodeFormat1 returns the PIN of the format 1 clear PIN block.
+func DecodeFormat1(pinBlock []byte) (string, error) {
+    return decode(format1, pinBlock, "")
+}
+
+// EncodeFormat3 returns the ISO 9564 format 3 clear PIN block,
+// the fill digits are random values from A to F.
+func EncodeFormat3(pin, pan string) ([]byte, error) {
+    return encode(format3, pin, pan, rand.Reader)
+}
+
+// DecodeFormat3 returns the PIN of the format 3 clear PIN block.
+func DecodeFormat3(pinBlock []byte, pan string) (string, error) {
+    return decode(format3, pinBlock, pan)
+}
+
+// 格式 0, 1, 3 的 PIN 字段: C || N || PIN || 填充
+func encode(format byte, pin, pan string, random io.Reader) ([]byte, error) {
+    if !isPIN(pin) {
+        return nil, ErrInvalidPIN
+    }
+
+    var nibbles [16]byte
+    nibbles[0] = format
+    nibbles[1] = byte(len(pin))
+
+    for i := 0; i < len(pin); i++ {
+        nibbles[2+i] = pin[i] - '0'
+    }
+
+    fill := nibbles[2+len(pin):]
+
+    switch format {
+        case format0:
+            for i := range fill {
+                fill[i] = 0xF
+            }
+        case format1:
+            if _, err := io.ReadFull(random, fill); err != nil {
+                return nil, err
+            }
+
+            for i := range fill {
+                fill[i] &= 0xF
+            }
+        case format3:
+            if _, err := io.ReadFull(random, fill); err != nil {
+                return nil, err
+            }
+
+            // 0xA 到 0xF
+            for i := range fill {
+                fill[i] = 0xA + fill[i] % 6
+            }
+    }
+
+    pinBlock := packNibbles(nibbles[:])
+
+    if format != format1 {
+        panField, err := panField(pan)
+        if err != nil {
+            return nil, err
+        }
+
+        subtle.XORBytes(pinBlock, pinBlock, panField)
+    }
+
+    return pinBlock, nil
+}
+
+func decode(format byte, pinBlock []byte, pan string) (string, error) {
+    if len(pinBlock) != 8 {
+        return "", ErrInvalidPINBlock
+    }
+
+    block := make([]byte, 8)
+    copy(block, pinBlock)
+
+    if format != format1 {
+        panField, err := panField(pan)
+        if err != nil {
+            return "", err
+        }
+
+        subtle.XORBytes(block, block, panField)
+    }
+
+    nibbles := unpackNibbles(block)
+    if nibbles[0] != format {
+        return "", ErrInvalidPINBlock
+    }
+
+    pin, err := parsePIN(nibbles)
+    if err != nil {
+        return "", err
+    }
+
+    for _, c := range nibbles[2+len(pin):] {
+        switch {
+            case format == format0 && c != 0xF,
+                format == format3 && c < 0xA:
+                return "", ErrInvalidPINBlock
+        }
+    }
+
+    return pin, nil
+}
+
+// PAN 字段: 0000 || 去掉校验位后最右边的 12 位
+func panField(pan string) ([]byte, error) {
+    if len(pan) < 13 || len(pan) > 19 || !isDigits(pan) {
+        return nil, ErrInvalidPAN
+    }
+
+    digits := pan[len(pan)-13 : len(pan)-1]
+
+    var nibbles [16]byte
+    for i := 0; i < len(digits); i++ {
+        nibbles[4+i] = digits[i] - '0'
+    }
+
+    return packNibbles(nibbles[:]), nil
+}
+
+// N || PIN
+func parsePIN(nibbles []byte) (string, error) {
+    n := int(nibbles[1])
+    if n < 4 || n > 12 {
+        return "", ErrInvalidPINBlock
+    }
+
+    pin := make([]byte, n)
+    for i := range pin {
+        c := nibbles[2+i]
+        if c > 9 {
+            return "", ErrInvalidPINBlock
+        }
+
+        pin[i] = '0' + c
+    }
+
+    return string(pin), nil
+}
+
+func isPIN(pin string) bool {
+    return len(pin) >= 4 && len(pin) <= 12 && isDigits(pin)
+}
+
+func isDigits(s string) bool {
+    for i := 0; i < len(s); i++ {
+        if s[i] < '0' || s[i] > '9' {
+            return false
+        }
+    }
+
+    return true
+}
+
+func packNibbles(nibbles []byte) []byte {
+    out := make([]byte, len(nibbles) / 2)
+    for i := range out {
+        out[i] = nibbles[2*i] << 4 | nibbles[2*i+1]
+    }
+
+    return out
+}
+
+func unpackNibbles(b []byte) []byte {
+    out := make([]byte, len(b) * 2)
+    for i, c := range b {
+        out[2*i] = c >> 4
+        out[2*i+1] = c & 0xF
+    }
+
+    return out
+}
+
+// Encrypt encrypts the format 0, 1 or 3 clear PIN block with the
+// TDEA PIN encryption key.
+func Encrypt(b cipher.Block, pinBlock []byte) ([]byte, error) {
+    if len(pinBlock) != b.BlockSize() {
+        return nil, ErrInvalidPINBlock
+    }
+
+    out := make([]byte, len(pinBlock))
+    b.Encrypt(out, pinBlock)
+
+    return out, nil
+}
+
+// Decrypt decrypts the encrypted format 0, 1 or 3 PIN block.
+func Decrypt(b cipher.Block, pinBlock []byte) ([]byte, error) {
+    if len(pinBlock) != b.BlockSize() {
+        return nil, ErrInvalidPINBlock
+    }
+
+    out := make([]byte, len(pinBlock))
+    b.Decrypt(out, pinBlock)
+
+    return out, nil
+}
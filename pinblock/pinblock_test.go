@@ -0,0 +1,212 @@
+package pinblock
+
+import (
+    "bytes"
+    "testing"
+    "crypto/aes"
+    "crypto/des"
+    "encoding/hex"
+
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+func Test_Format0(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    cases := []struct {
+        pin      string
+        pan      string
+        pinBlock string
+    }{
+        {"1234", "43219876543210987", "0412AC89ABCDEF67"},
+        {"1234", "4012345678909", "041274EDCBA9876F"},
+        {"123456789012", "4111111111111111", "0C122547698103EE"},
+    }
+
+    for _, c := range cases {
+        pinBlock, err := EncodeFormat0(c.pin, c.pan)
+        assertNoError(err, "Test_Format0")
+        assertEqual(pinBlock, fromHex(c.pinBlock), "Test_Format0-Encode")
+
+        pin, err := DecodeFormat0(pinBlock, c.pan)
+        assertNoError(err, "Test_Format0-Decode")
+        assertEqual(pin, c.pin, "Test_Format0-Decode")
+    }
+
+    // PAN 不匹配
+    _, err := DecodeFormat0(fromHex("0412AC89ABCDEF67"), "43219876543210988")
+    assertNoError(err, "Test_Format0-check digit")
+
+    _, err = DecodeFormat0(fromHex("0412AC89ABCDEF67"), "43219876543210977")
+    assertEqual(err, ErrInvalidPINBlock, "Test_Format0-PAN")
+}
+
+func Test_Format1(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    pinBlock, err := encode(format1, "1234", "", bytes.NewReader(bytes.Repeat([]byte{0x5C}, 16)))
+    assertNoError(err, "Test_Format1")
+    assertEqual(pinBlock, fromHex("141234CCCCCCCCCC"), "Test_Format1-Encode")
+
+    for _, pin := range []string{"1234", "12345", "123456789012"} {
+        pinBlock, err := EncodeFormat1(pin)
+        assertNoError(err, "Test_Format1")
+
+        pin2, err := DecodeFormat1(pinBlock)
+        assertNoError(err, "Test_Format1-Decode")
+        assertEqual(pin2, pin, "Test_Format1-Decode")
+    }
+}
+
+func Test_Format3(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    pan := "43219876543210987"
+
+    // 随机数 0..5 对应填充 A..F
+    random := bytes.NewReader([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+    pinBlock, err := encode(format3, "1234", pan, random)
+    assertNoError(err, "Test_Format3")
+    assertEqual(pinBlock, fromHex("3412ACDD99DDBB55"), "Test_Format3-Encode")
+
+    for i := 0; i < 10; i++ {
+        pinBlock, err := EncodeFormat3("98765", pan)
+        assertNoError(err, "Test_Format3")
+
+        panField, _ := panField(pan)
+
+        clear := make([]byte, 8)
+        for j := range clear {
+            clear[j] = pinBlock[j] ^ panField[j]
+        }
+
+        for _, c := range unpackNibbles(clear)[7:] {
+            if c < 0xA {
+                t.Fatalf("fill digit %X should be A to F", c)
+            }
+        }
+
+        pin, err := DecodeFormat3(pinBlock, pan)
+        assertNoError(err, "Test_Format3-Decode")
+        assertEqual(pin, "98765", "Test_Format3-Decode")
+    }
+
+    // 格式 0 的 PIN 块不是格式 3
+    pinBlock, _ = EncodeFormat0("1234", pan)
+    _, err = DecodeFormat3(pinBlock, pan)
+    assertEqual(err, ErrInvalidPINBlock, "Test_Format3-format0")
+}
+
+func Test_Format4(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    block, err := aes.NewCipher(fromHex("00112233445566778899AABBCCDDEEFF"))
+    assertNoError(err, "Test_Format4")
+
+    random := bytes.NewReader(fromHex("0123456789ABCDEF"))
+    pinBlock, err := encryptFormat4(random, block, "1234", "1234567890123456789")
+    assertNoError(err, "Test_Format4-Encrypt")
+
+    // 解开两层加密检查 PIN 字段和 PAN 字段
+    inner := make([]byte, 16)
+    block.Decrypt(inner, pinBlock)
+
+    panField := fromHex("71234567890123456789000000000000")
+    for i := range inner {
+        inner[i] ^= panField[i]
+    }
+
+    block.Decrypt(inner, inner)
+    assertEqual(inner, fromHex("441234AAAAAAAAAA0123456789ABCDEF"), "Test_Format4-PIN field")
+
+    cases := []struct {
+        pin string
+        pan string
+    }{
+        {"1234", "1234567890123456789"},
+        {"123456789012", "4111111111111111"},
+        {"0000", "123456789012"},
+        {"5678", "12345"},
+    }
+
+    for _, c := range cases {
+        pinBlock, err := EncryptFormat4(block, c.pin, c.pan)
+        assertNoError(err, "Test_Format4-Encrypt")
+
+        pin, err := DecryptFormat4(block, pinBlock, c.pan)
+        assertNoError(err, "Test_Format4-Decrypt")
+        assertEqual(pin, c.pin, "Test_Format4-Decrypt")
+
+        // 最后一位 PAN 不同
+        pan := c.pan[:len(c.pan)-1] + "9"
+        if c.pan[len(c.pan)-1] == '9' {
+            pan = c.pan[:len(c.pan)-1] + "8"
+        }
+
+        _, err = DecryptFormat4(block, pinBlock, pan)
+        assertEqual(err, ErrInvalidPINBlock, "Test_Format4-PAN")
+    }
+
+    // 短 PAN 左边补 0
+    field, err := format4PANField("12345")
+    assertNoError(err, "Test_Format4-PAN field")
+    assertEqual(field, fromHex("00000000123450000000000000000000"), "Test_Format4-PAN field")
+
+    desBlock, _ := des.NewCipher(fromHex("0123456789ABCDEF"))
+    _, err = EncryptFormat4(desBlock, "1234", "12345")
+    assertEqual(err, errFormat4BlockSize, "Test_Format4-block size")
+}
+
+func Test_Encrypt(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    block, err := des.NewTripleDESCipher(fromHex("0123456789ABCDEFFEDCBA98765432100123456789ABCDEF"))
+    assertNoError(err, "Test_Encrypt")
+
+    pinBlock, _ := EncodeFormat0("1234", "43219876543210987")
+
+    encrypted, err := Encrypt(block, pinBlock)
+    assertNoError(err, "Test_Encrypt-Encrypt")
+
+    decrypted, err := Decrypt(block, encrypted)
+    assertNoError(err, "Test_Encrypt-Decrypt")
+    assertEqual(decrypted, pinBlock, "Test_Encrypt-Decrypt")
+
+    _, err = Encrypt(block, pinBlock[:7])
+    assertEqual(err, ErrInvalidPINBlock, "Test_Encrypt-size")
+}
+
+func Test_Error(t *testing.T) {
+    pan := "43219876543210987"
+
+    for _, pin := range []string{"", "123", "1234567890123", "12a4"} {
+        if _, err := EncodeFormat0(pin, pan); err != ErrInvalidPIN {
+            t.Errorf("pin %q: got %v, want ErrInvalidPIN", pin, err)
+        }
+    }
+
+    for _, pan := range []string{"", "123456789012", "12345678901234567890", "4321987654321098a"} {
+        if _, err := EncodeFormat0("1234", pan); err != ErrInvalidPAN {
+            t.Errorf("pan %q: got %v, want ErrInvalidPAN", pan, err)
+        }
+    }
+
+    if _, err := DecodeFormat0(fromHex("0412AC89ABCDEF"), pan); err != ErrInvalidPINBlock {
+        t.Errorf("got %v, want ErrInvalidPINBlock", err)
+    }
+
+    // PIN 长度为 3
+    if _, err := DecodeFormat1(fromHex("13123FFFFFFFFFFF")); err != ErrInvalidPINBlock {
+        t.Errorf("got %v, want ErrInvalidPINBlock", err)
+    }
+}
@@ -169,23 +169,32 @@ type MacData struct {
 }
 
 func (this MacData) Verify(message []byte, password []byte) (err error) {
-    var h func() hash.Hash
+    var newMAC func(key []byte) (hash.Hash, error)
     var key []byte
 
     switch {
         case this.Mac.Algorithm.Algorithm.Equal(oidPBMAC1):
-            h, key, err = parsePBMAC1Param(this.Mac.Algorithm.Parameters.FullBytes, password)
+            newMAC, key, err = parsePBMAC1Param(this.Mac.Algorithm.Parameters.FullBytes, password)
             if err != nil {
                 return err
             }
         default:
+            var h func() hash.Hash
             h, key, err = this.parseMacParam(password)
             if err != nil {
                 return err
             }
+
+            newMAC = func(key []byte) (hash.Hash, error) {
+                return hmac.New(h, key), nil
+            }
+    }
+
+    mac, err := newMAC(key)
+    if err != nil {
+        return err
     }
 
-    mac := hmac.New(h, key)
     mac.Write(message)
     expectedMAC := mac.Sum(nil)
 
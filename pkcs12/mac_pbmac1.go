@@ -4,6 +4,7 @@ import (
     "fmt"
     "hash"
     "errors"
+    "crypto/aes"
     "crypto/rand"
     "crypto/hmac"
     "crypto/md5"
@@ -15,7 +16,9 @@ import (
 
     "golang.org/x/crypto/pbkdf2"
 
+    "github.com/deatil/go-cryptobin/mac"
     "github.com/deatil/go-cryptobin/hash/sm3"
+    "github.com/deatil/go-cryptobin/hash/kmac"
     "github.com/deatil/go-cryptobin/hash/gost/gost34112012256"
     "github.com/deatil/go-cryptobin/hash/gost/gost34112012512"
 )
@@ -36,6 +39,13 @@ const (
     PBMAC1SM3
     PBMAC1GOST34112012256
     PBMAC1GOST34112012512
+
+    // only used as HMACHash
+    PBMAC1KMAC128
+    PBMAC1KMAC256
+    PBMAC1AES128GMAC
+    PBMAC1AES192GMAC
+    PBMAC1AES256GMAC
 )
 
 var (
@@ -65,6 +75,15 @@ var (
 
     oidHMACWithGOST34112012256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 4, 1}
     oidHMACWithGOST34112012512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 4, 2}
+
+    // KMAC oid, RFC 8702
+    oidKMACWithSHAKE128 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 19}
+    oidKMACWithSHAKE256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 20}
+
+    // AES-GMAC oid, RFC 9044
+    oidAES128GMAC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 9}
+    oidAES192GMAC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 29}
+    oidAES256GMAC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 49}
 )
 
 // get Hash type
@@ -122,11 +141,109 @@ func pbmac1OIDByHash(h PBMAC1Hash) (asn1.ObjectIdentifier, error) {
             return oidHMACWithGOST34112012256, nil
         case PBMAC1GOST34112012512:
             return oidHMACWithGOST34112012512, nil
+        case PBMAC1KMAC128:
+            return oidKMACWithSHAKE128, nil
+        case PBMAC1KMAC256:
+            return oidKMACWithSHAKE256, nil
+        case PBMAC1AES128GMAC:
+            return oidAES128GMAC, nil
+        case PBMAC1AES192GMAC:
+            return oidAES192GMAC, nil
+        case PBMAC1AES256GMAC:
+            return oidAES256GMAC, nil
     }
 
     return nil, errors.New("go-cryptobin/pkcs12: unsupported hash function")
 }
 
+//  KMACwithSHAKE128-params ::= SEQUENCE {
+//      kMACOutputLength     INTEGER DEFAULT 256, -- Output length in bits
+//      customizationString  OCTET STRING DEFAULT ''H
+//  }
+type kmacParams struct {
+    OutputLength        int    `asn1:"optional"`
+    CustomizationString []byte `asn1:"optional"`
+}
+
+//  GMACParameters ::= SEQUENCE {
+//      nonce   OCTET STRING, -- recommended size is 12 octets
+//      length  MACLength DEFAULT 12
+//  }
+type gmacParams struct {
+    Nonce  []byte
+    Length int `asn1:"optional,default:12"`
+}
+
+// get MAC func and MAC key size
+func pbmac1MACByAlgorithm(alg pkix.AlgorithmIdentifier) (func(key []byte) (hash.Hash, error), int, error) {
+    var newKMAC func(key []byte, size int, S []byte) hash.Hash
+    var outputLength, keySize int
+
+    switch {
+        case alg.Algorithm.Equal(oidAES128GMAC):
+            return pbmac1GMACByParams(alg.Parameters.FullBytes, 16)
+        case alg.Algorithm.Equal(oidAES192GMAC):
+            return pbmac1GMACByParams(alg.Parameters.FullBytes, 24)
+        case alg.Algorithm.Equal(oidAES256GMAC):
+            return pbmac1GMACByParams(alg.Parameters.FullBytes, 32)
+        case alg.Algorithm.Equal(oidKMACWithSHAKE128):
+            newKMAC, outputLength, keySize = kmac.New128, 256, 32
+        case alg.Algorithm.Equal(oidKMACWithSHAKE256):
+            newKMAC, outputLength, keySize = kmac.New256, 512, 64
+        default:
+            h, err := pbmac1PRFByOID(alg.Algorithm)
+            if err != nil {
+                return nil, 0, err
+            }
+
+            newMAC := func(key []byte) (hash.Hash, error) {
+                return hmac.New(h, key), nil
+            }
+
+            return newMAC, 0, nil
+    }
+
+    var params kmacParams
+    if len(alg.Parameters.FullBytes) > 0 && alg.Parameters.Tag != asn1.TagNull {
+        if err := unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+            return nil, 0, err
+        }
+    }
+
+    if params.OutputLength > 0 {
+        outputLength = params.OutputLength
+    }
+
+    if outputLength % 8 != 0 {
+        return nil, 0, errors.New("go-cryptobin/pkcs12: invalid KMAC output length")
+    }
+
+    newMAC := func(key []byte) (hash.Hash, error) {
+        return newKMAC(key, outputLength / 8, params.CustomizationString), nil
+    }
+
+    return newMAC, keySize, nil
+}
+
+// AES-GMAC, the key size is the AES key size
+func pbmac1GMACByParams(param []byte, keySize int) (func(key []byte) (hash.Hash, error), int, error) {
+    var params gmacParams
+    if err := unmarshal(param, &params); err != nil {
+        return nil, 0, err
+    }
+
+    newMAC := func(key []byte) (hash.Hash, error) {
+        block, err := aes.NewCipher(key)
+        if err != nil {
+            return nil, err
+        }
+
+        return mac.NewGMACWithTagSize(block, params.Nonce, params.Length)
+    }
+
+    return newMAC, keySize, nil
+}
+
 //  PBMAC1-params ::= SEQUENCE {
 //      keyDerivationFunc AlgorithmIdentifier {{PBMAC1-KDFs}},
 //      messageAuthScheme AlgorithmIdentifier {{PBMAC1-MACs}}
@@ -144,7 +261,8 @@ type pbmac1Pbkdf2Params struct {
     PrfParam       pkix.AlgorithmIdentifier `asn1:"optional"`
 }
 
-func (this pbmac1Pbkdf2Params) DeriveKey(password []byte) (key []byte, err error) {
+// macKeySize is used as the key length when KeyLength is not set, 0 means the PRF size
+func (this pbmac1Pbkdf2Params) DeriveKey(password []byte, macKeySize int) (key []byte, err error) {
     var alg asn1.ObjectIdentifier
     var h func() hash.Hash
 
@@ -166,6 +284,9 @@ func (this pbmac1Pbkdf2Params) DeriveKey(password []byte) (key []byte, err error
     }
 
     size := h().Size()
+    if macKeySize > 0 {
+        size = macKeySize
+    }
 
     // when set KeyLength and use it
     if this.KeyLength > 0 {
@@ -177,7 +298,7 @@ func (this pbmac1Pbkdf2Params) DeriveKey(password []byte) (key []byte, err error
     return
 }
 
-func parsePBMAC1Param(param []byte, password []byte) (h func() hash.Hash, key []byte, err error) {
+func parsePBMAC1Param(param []byte, password []byte) (newMAC func(key []byte) (hash.Hash, error), key []byte, err error) {
     var params pbmac1Params
     if err = unmarshal(param, &params); err != nil {
         return
@@ -193,12 +314,12 @@ func parsePBMAC1Param(param []byte, password []byte) (h func() hash.Hash, key []
         return
     }
 
-    h, err = pbmac1PRFByOID(params.MessageAuthScheme.Algorithm)
+    newMAC, macKeySize, err := pbmac1MACByAlgorithm(params.MessageAuthScheme)
     if err != nil {
         return
     }
 
-    key, err = kdfparams.DeriveKey([]byte(originalPassword))
+    key, err = kdfparams.DeriveKey([]byte(originalPassword), macKeySize)
     if err != nil {
         return
     }
@@ -222,12 +343,47 @@ func (this PBMAC1Opts) Compute(message []byte, password []byte) (data MacKDFPara
         return nil, err
     }
 
-    h, err := pbmac1PRFByOID(alg)
+    messageAuthScheme := pkix.AlgorithmIdentifier{
+        Algorithm:  alg,
+        Parameters: asn1.RawValue{
+            Tag: asn1.TagNull,
+        },
+    }
+
+    switch {
+        // KMAC uses the default parameters
+        case alg.Equal(oidKMACWithSHAKE128),
+            alg.Equal(oidKMACWithSHAKE256):
+            messageAuthScheme.Parameters = asn1.RawValue{}
+
+        // GMAC uses a random nonce
+        case alg.Equal(oidAES128GMAC),
+            alg.Equal(oidAES192GMAC),
+            alg.Equal(oidAES256GMAC):
+            nonce := make([]byte, 12)
+            if _, err = rand.Read(nonce); err != nil {
+                return nil, err
+            }
+
+            gmacParam, err := asn1.Marshal(gmacParams{
+                Nonce:  nonce,
+                Length: 12,
+            })
+            if err != nil {
+                return nil, err
+            }
+
+            messageAuthScheme.Parameters = asn1.RawValue{
+                FullBytes: gmacParam,
+            }
+    }
+
+    newMAC, macKeySize, err := pbmac1MACByAlgorithm(messageAuthScheme)
     if err != nil {
         return nil, err
     }
 
-    key, kdf, err := this.computeKDF(password)
+    key, kdf, err := this.computeKDF(password, macKeySize)
     if err != nil {
         return nil, err
     }
@@ -239,12 +395,7 @@ func (this PBMAC1Opts) Compute(message []byte, password []byte) (data MacKDFPara
             FullBytes: kdf,
         },
     }
-    params.MessageAuthScheme = pkix.AlgorithmIdentifier{
-        Algorithm:  alg,
-        Parameters: asn1.RawValue{
-            Tag: asn1.TagNull,
-        },
-    }
+    params.MessageAuthScheme = messageAuthScheme
 
     encodedParams, err := asn1.Marshal(params)
     if err != nil {
@@ -258,9 +409,13 @@ func (this PBMAC1Opts) Compute(message []byte, password []byte) (data MacKDFPara
         },
     }
 
-    mac := hmac.New(h, key)
-    mac.Write(message)
-    digest := mac.Sum(nil)
+    h, err := newMAC(key)
+    if err != nil {
+        return nil, err
+    }
+
+    h.Write(message)
+    digest := h.Sum(nil)
 
     data = MacData{
         Mac: DigestInfo{
@@ -274,7 +429,8 @@ func (this PBMAC1Opts) Compute(message []byte, password []byte) (data MacKDFPara
     return
 }
 
-func (this PBMAC1Opts) computeKDF(password []byte) (key []byte, kdf []byte, err error) {
+// macKeySize is the MAC key length, 0 means the KDF hash size
+func (this PBMAC1Opts) computeKDF(password []byte, macKeySize int) (key []byte, kdf []byte, err error) {
     var alg asn1.ObjectIdentifier
     var prfParam pkix.AlgorithmIdentifier
 
@@ -310,6 +466,9 @@ func (this PBMAC1Opts) computeKDF(password []byte) (key []byte, kdf []byte, err
     }
 
     size := h().Size()
+    if macKeySize > 0 {
+        size = macKeySize
+    }
 
     kdfParams := pbmac1Pbkdf2Params{
         Salt:           salt,
@@ -317,8 +476,8 @@ func (this PBMAC1Opts) computeKDF(password []byte) (key []byte, kdf []byte, err
         PrfParam:       prfParam,
     }
 
-    // set KeyLength
-    if this.HasKeyLength {
+    // set KeyLength, it is required by KMAC
+    if this.HasKeyLength || macKeySize > 0 {
         kdfParams.KeyLength = size
     }
 
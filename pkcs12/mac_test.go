@@ -2,8 +2,13 @@ package pkcs12
 
 import (
     "testing"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/x509/pkix"
     "encoding/asn1"
 
+    "github.com/deatil/go-cryptobin/hash/kmac"
+
     cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
 )
 
@@ -35,3 +40,155 @@ func Test_prfByOIDPBMAC1_fail(t *testing.T) {
     assertEqual(err.Error(), check, "Test_hashByOID_fail")
 }
 
+
+func Test_PBMAC1_KMAC(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    password, err := bmpStringZeroTerminated("1234")
+    assertNoError(err, "Test_PBMAC1_KMAC")
+
+    wrongPassword, err := bmpStringZeroTerminated("4321")
+    assertNoError(err, "Test_PBMAC1_KMAC")
+
+    message := []byte("test-data")
+
+    for _, h := range []PBMAC1Hash{PBMAC1KMAC128, PBMAC1KMAC256} {
+        opts := PBMAC1Opts{
+            SaltSize:       16,
+            IterationCount: 1000,
+            KDFHash:        PBMAC1SHA256,
+            HMACHash:       h,
+        }
+
+        data, err := opts.Compute(message, password)
+        assertNoError(err, "Test_PBMAC1_KMAC-Compute")
+
+        macData := data.(MacData)
+
+        size := 32
+        if h == PBMAC1KMAC256 {
+            size = 64
+        }
+
+        assertEqual(len(macData.Mac.Digest), size, "Test_PBMAC1_KMAC-Digest")
+
+        assertNoError(data.Verify(message, password), "Test_PBMAC1_KMAC-Verify")
+
+        if err := data.Verify(message, wrongPassword); err != ErrIncorrectPassword {
+            t.Errorf("got %v, want ErrIncorrectPassword", err)
+        }
+
+        if err := data.Verify([]byte("test-data2"), password); err != ErrIncorrectPassword {
+            t.Errorf("got %v, want ErrIncorrectPassword", err)
+        }
+    }
+}
+
+func Test_PBMAC1_KMACParams(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    params, err := asn1.Marshal(kmacParams{
+        OutputLength:        384,
+        CustomizationString: []byte("custom"),
+    })
+    assertNoError(err, "Test_PBMAC1_KMACParams")
+
+    newMAC, keySize, err := pbmac1MACByAlgorithm(pkix.AlgorithmIdentifier{
+        Algorithm:  oidKMACWithSHAKE128,
+        Parameters: asn1.RawValue{
+            FullBytes: params,
+        },
+    })
+    assertNoError(err, "Test_PBMAC1_KMACParams")
+
+    assertEqual(keySize, 32, "Test_PBMAC1_KMACParams-keySize")
+    h, err := newMAC([]byte("key"))
+    assertNoError(err, "Test_PBMAC1_KMACParams-newMAC")
+    assertEqual(h.Size(), 48, "Test_PBMAC1_KMACParams-Size")
+
+    want := kmac.Sum128([]byte("key"), []byte("data"), 48, []byte("custom"))
+
+    h.Write([]byte("data"))
+    assertEqual(h.Sum(nil), want, "Test_PBMAC1_KMACParams-Sum")
+}
+
+func Test_PBMAC1_GMAC(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    password, err := bmpStringZeroTerminated("1234")
+    assertNoError(err, "Test_PBMAC1_GMAC")
+
+    wrongPassword, err := bmpStringZeroTerminated("4321")
+    assertNoError(err, "Test_PBMAC1_GMAC")
+
+    message := []byte("test-data")
+
+    for _, h := range []PBMAC1Hash{PBMAC1AES128GMAC, PBMAC1AES192GMAC, PBMAC1AES256GMAC} {
+        opts := PBMAC1Opts{
+            SaltSize:       16,
+            IterationCount: 1000,
+            KDFHash:        PBMAC1SHA256,
+            HMACHash:       h,
+        }
+
+        data, err := opts.Compute(message, password)
+        assertNoError(err, "Test_PBMAC1_GMAC-Compute")
+
+        macData := data.(MacData)
+
+        assertEqual(len(macData.Mac.Digest), 12, "Test_PBMAC1_GMAC-Digest")
+
+        assertNoError(data.Verify(message, password), "Test_PBMAC1_GMAC-Verify")
+
+        if err := data.Verify(message, wrongPassword); err != ErrIncorrectPassword {
+            t.Errorf("got %v, want ErrIncorrectPassword", err)
+        }
+
+        if err := data.Verify([]byte("test-data2"), password); err != ErrIncorrectPassword {
+            t.Errorf("got %v, want ErrIncorrectPassword", err)
+        }
+    }
+}
+
+func Test_PBMAC1_GMACParams(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    nonce := []byte("123456789012")
+
+    params, err := asn1.Marshal(gmacParams{
+        Nonce:  nonce,
+        Length: 16,
+    })
+    assertNoError(err, "Test_PBMAC1_GMACParams")
+
+    newMAC, keySize, err := pbmac1MACByAlgorithm(pkix.AlgorithmIdentifier{
+        Algorithm:  oidAES256GMAC,
+        Parameters: asn1.RawValue{
+            FullBytes: params,
+        },
+    })
+    assertNoError(err, "Test_PBMAC1_GMACParams")
+
+    assertEqual(keySize, 32, "Test_PBMAC1_GMACParams-keySize")
+
+    key := make([]byte, 32)
+
+    h, err := newMAC(key)
+    assertNoError(err, "Test_PBMAC1_GMACParams-newMAC")
+    assertEqual(h.Size(), 16, "Test_PBMAC1_GMACParams-Size")
+
+    block, _ := aes.NewCipher(key)
+    aead, _ := cipher.NewGCM(block)
+    want := aead.Seal(nil, nonce, nil, []byte("data"))
+
+    h.Write([]byte("data"))
+    assertEqual(h.Sum(nil), want, "Test_PBMAC1_GMACParams-Sum")
+
+    if _, err := newMAC(key[:20]); err == nil {
+        t.Error("should fail with bad key size")
+    }
+}
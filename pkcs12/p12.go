@@ -143,6 +143,10 @@ type PKCS12 struct {
     // localKeyId
     localKeyId []byte
 
+    // 私钥和密钥的 friendlyName
+    keyFriendlyName       string
+    secretKeyFriendlyName string
+
     // 解析后数据
     parsedData map[string][]ISafeBagData
 
@@ -141,6 +141,20 @@ func (this *PKCS12) makeFriendlyNameAttr(friendlyName string) (PKCS12Attribute,
     return friendlyNameAttr, nil
 }
 
+// 有名称时添加 friendlyName
+func (this *PKCS12) appendFriendlyNameAttr(attrs []PKCS12Attribute, friendlyName string) ([]PKCS12Attribute, error) {
+    if friendlyName == "" {
+        return attrs, nil
+    }
+
+    friendlyNameAttr, err := this.makeFriendlyNameAttr(friendlyName)
+    if err != nil {
+        return nil, errors.New("go-cryptobin/pkcs12: " + err.Error())
+    }
+
+    return append(attrs, friendlyNameAttr), nil
+}
+
 func (this *PKCS12) makeLocalKeyIdAttr(data []byte) (PKCS12Attribute, error) {
     var fingerprint []byte
 
@@ -200,6 +214,10 @@ func (this *PKCS12) marshalPrivateKey(rand io.Reader, password []byte, opt Opts)
 
     keyBag.Attributes = append(keyBag.Attributes, localKeyIdAttr)
 
+    if keyBag.Attributes, err = this.appendFriendlyNameAttr(keyBag.Attributes, this.keyFriendlyName); err != nil {
+        return
+    }
+
     return this.makeSafeContents(rand, []SafeBag{keyBag}, nil, Opts{})
 }
 
@@ -216,9 +234,14 @@ func (this *PKCS12) marshalCert(rand io.Reader, password []byte, opt Opts) (ci C
 
     var certBags []SafeBag
 
+    certAttrs, err := this.appendFriendlyNameAttr([]PKCS12Attribute{localKeyIdAttr}, this.keyFriendlyName)
+    if err != nil {
+        return
+    }
+
     // 证书
     var certBag *SafeBag
-    if certBag, err = NewCertBagEntry().MakeCertBag(certificate, []PKCS12Attribute{localKeyIdAttr}); err != nil {
+    if certBag, err = NewCertBagEntry().MakeCertBag(certificate, certAttrs); err != nil {
         return
     }
 
@@ -345,6 +368,10 @@ func (this *PKCS12) marshalSecretKey(rand io.Reader, password []byte, opt Opts)
     }
     keyBag.Attributes = append(keyBag.Attributes, localKeyIdAttr)
 
+    if keyBag.Attributes, err = this.appendFriendlyNameAttr(keyBag.Attributes, this.secretKeyFriendlyName); err != nil {
+        return
+    }
+
     return this.makeSafeContents(rand, []SafeBag{keyBag}, nil, Opts{})
 }
 
@@ -359,9 +386,6 @@ func (this *PKCS12) Marshal(rand io.Reader, password string, opts ...Opts) (pfxD
         return nil, err
     }
 
-    var pfx PfxPdu
-    pfx.Version = PKCS12Version
-
     authenticatedSafe := make([]ContentInfo, 0)
 
     // 私钥
@@ -424,6 +448,14 @@ func (this *PKCS12) Marshal(rand io.Reader, password string, opts ...Opts) (pfxD
         authenticatedSafe = append(authenticatedSafe, ci)
     }
 
+    return this.marshalPfx(authenticatedSafe, encodedPassword, opt)
+}
+
+// 生成 PFX 数据, 按设置计算 MAC
+func (this *PKCS12) marshalPfx(authenticatedSafe []ContentInfo, encodedPassword []byte, opt Opts) (pfxData []byte, err error) {
+    var pfx PfxPdu
+    pfx.Version = PKCS12Version
+
     var authenticatedSafeBytes []byte
     if authenticatedSafeBytes, err = asn1.Marshal(authenticatedSafe[:]); err != nil {
         return nil, err
@@ -0,0 +1,332 @@
+package pkcs12
+
+import (
+    "fmt"
+    "time"
+    "bytes"
+    "errors"
+    "crypto"
+    "crypto/x509"
+
+    "github.com/deatil/go-cryptobin/keystore"
+)
+
+var _ keystore.KeyStore = (*PKCS12)(nil)
+
+var errPrivateKeyNoCert = errors.New("go-cryptobin/pkcs12: no certificate matches the private key localKeyId")
+
+// 没有 friendlyName 时的别名
+const (
+    keystorePrivateKeyAlias = "privatekey"
+    keystoreTrustStoreAlias = "truststore"
+    keystoreSecretKeyAlias  = "secretkey"
+)
+
+type keystoreEntry struct {
+    typ  keystore.EntryType
+    data ISafeBagData
+
+    // 待编码私钥的证书链
+    certs [][]byte
+}
+
+// 待编码的数据, 只有 friendlyName
+type keystorePendingData struct {
+    data         []byte
+    friendlyName string
+}
+
+func (this keystorePendingData) Attributes() map[string]string {
+    attrs := make(map[string]string)
+    if this.friendlyName != "" {
+        attrs["friendlyName"] = this.friendlyName
+    }
+
+    return attrs
+}
+
+func (this keystorePendingData) Data() []byte {
+    return this.data
+}
+
+func (this keystorePendingData) Attrs() PKCS12Attributes {
+    return NewPKCS12AttributesEmpty()
+}
+
+func (this keystorePendingData) FriendlyName() string {
+    return this.friendlyName
+}
+
+// 解析后和待编码的条目, 别名为 friendlyName
+func (this *PKCS12) keystoreEntries() map[string]keystoreEntry {
+    entries := make(map[string]keystoreEntry)
+
+    add := func(entry keystoreEntry, i int, defaultAlias string) {
+        alias := entry.data.FriendlyName()
+        if alias == "" {
+            alias = defaultAlias
+            if i > 0 {
+                alias = fmt.Sprintf("%s-%d", defaultAlias, i)
+            }
+        }
+
+        base := alias
+        for n := 1; ; n++ {
+            if _, ok := entries[alias]; !ok {
+                break
+            }
+
+            alias = fmt.Sprintf("%s-%d", base, n)
+        }
+
+        entries[alias] = entry
+    }
+
+    addParsed := func(name string, typ keystore.EntryType, defaultAlias string) {
+        for i, data := range this.parsedData[name] {
+            add(keystoreEntry{typ: typ, data: data}, i, defaultAlias)
+        }
+    }
+
+    addParsed("privateKey", keystore.PrivateKeyEntry, keystorePrivateKeyAlias)
+    addParsed("trustStore", keystore.TrustedCertEntry, keystoreTrustStoreAlias)
+    addParsed("secretKey", keystore.SecretKeyEntry, keystoreSecretKeyAlias)
+
+    // 待编码的数据
+    if this.privateKey != nil {
+        var certs [][]byte
+        if this.cert != nil {
+            certs = append(certs, this.cert)
+        }
+
+        certs = append(certs, this.caCerts...)
+
+        add(keystoreEntry{
+            typ:   keystore.PrivateKeyEntry,
+            data:  keystorePendingData{this.privateKey, this.keyFriendlyName},
+            certs: certs,
+        }, len(this.parsedData["privateKey"]), keystorePrivateKeyAlias)
+    }
+
+    for i, ts := range this.trustStores {
+        add(keystoreEntry{
+            typ:  keystore.TrustedCertEntry,
+            data: keystorePendingData{ts.Cert, ts.FriendlyName},
+        }, len(this.parsedData["trustStore"]) + i, keystoreTrustStoreAlias)
+    }
+
+    if this.secretKey != nil {
+        add(keystoreEntry{
+            typ:  keystore.SecretKeyEntry,
+            data: keystorePendingData{this.secretKey, this.secretKeyFriendlyName},
+        }, len(this.parsedData["secretKey"]), keystoreSecretKeyAlias)
+    }
+
+    return entries
+}
+
+func (this *PKCS12) keystoreEntry(alias string, typ keystore.EntryType) (keystoreEntry, error) {
+    entry, ok := this.keystoreEntries()[alias]
+    if !ok || entry.typ != typ {
+        return keystoreEntry{}, keystore.ErrEntryNotFound
+    }
+
+    return entry, nil
+}
+
+// 解析和待编码数据的别名列表
+func (this *PKCS12) Aliases() []string {
+    return keystore.SortedAliases(this.keystoreEntries())
+}
+
+// 条目类型
+func (this *PKCS12) EntryType(alias string) (keystore.EntryType, error) {
+    entry, ok := this.keystoreEntries()[alias]
+    if !ok {
+        return keystore.UnknownEntry, keystore.ErrEntryNotFound
+    }
+
+    return entry.typ, nil
+}
+
+// PKCS12 没有创建时间, 返回零值
+func (this *PKCS12) CreationDate(alias string) (time.Time, error) {
+    if _, ok := this.keystoreEntries()[alias]; !ok {
+        return time.Time{}, keystore.ErrEntryNotFound
+    }
+
+    return time.Time{}, nil
+}
+
+// 私钥和证书链, 第一个证书为 localKeyId 相同的证书,
+// 其余证书按签发者关系依次查找.
+// PKCS12 使用文件密码, 忽略 password
+func (this *PKCS12) PrivateKeyEntry(alias string, password string) (crypto.PrivateKey, []*x509.Certificate, error) {
+    entry, err := this.keystoreEntry(alias, keystore.PrivateKeyEntry)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    data := entry.data
+
+    privateKey, err := ParsePKCS8PrivateKey(data.Data())
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if _, ok := data.(keystorePendingData); ok {
+        var certChain []*x509.Certificate
+        for _, certData := range entry.certs {
+            certs, err := this.formatCert(certData)
+            if err != nil {
+                return nil, nil, err
+            }
+
+            certChain = append(certChain, certs...)
+        }
+
+        return privateKey, certChain, nil
+    }
+
+    keyId, ok := data.Attributes()["localKeyId"]
+    if !ok {
+        return nil, nil, errPrivateKeyNoCert
+    }
+
+    var leaf *x509.Certificate
+    var others []*x509.Certificate
+
+    for _, name := range []string{"cert", "caCert"} {
+        for _, cert := range this.parsedData[name] {
+            certs, err := this.formatCert(cert.Data())
+            if err != nil {
+                return nil, nil, err
+            }
+
+            if leaf == nil && name == "cert" && len(certs) > 0 &&
+                cert.Attributes()["localKeyId"] == keyId {
+                leaf, certs = certs[0], certs[1:]
+            }
+
+            others = append(others, certs...)
+        }
+    }
+
+    if leaf == nil {
+        return nil, nil, errPrivateKeyNoCert
+    }
+
+    return privateKey, buildCertChain(leaf, others), nil
+}
+
+// 从 leaf 开始按签发者查找证书链, 到自签名证书或找不到签发者为止
+func buildCertChain(leaf *x509.Certificate, certs []*x509.Certificate) []*x509.Certificate {
+    chain := []*x509.Certificate{leaf}
+    used := make([]bool, len(certs))
+
+    cert := leaf
+    for !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+        found := -1
+        for i, issuer := range certs {
+            if !used[i] && isIssuerOf(issuer, cert) {
+                found = i
+                break
+            }
+        }
+
+        if found < 0 {
+            break
+        }
+
+        used[found] = true
+        cert = certs[found]
+        chain = append(chain, cert)
+    }
+
+    return chain
+}
+
+// 名称相同, 且都有密钥标识时标识也要相同
+func isIssuerOf(issuer, cert *x509.Certificate) bool {
+    if !bytes.Equal(issuer.RawSubject, cert.RawIssuer) {
+        return false
+    }
+
+    if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 {
+        return bytes.Equal(cert.AuthorityKeyId, issuer.SubjectKeyId)
+    }
+
+    return true
+}
+
+// 信任证书
+func (this *PKCS12) TrustedCertEntry(alias string) (*x509.Certificate, error) {
+    entry, err := this.keystoreEntry(alias, keystore.TrustedCertEntry)
+    if err != nil {
+        return nil, err
+    }
+
+    certs, err := this.formatCert(entry.data.Data())
+    if err != nil {
+        return nil, err
+    }
+
+    return certs[0], nil
+}
+
+// 密钥, 忽略 password
+func (this *PKCS12) SecretKeyEntry(alias string, password string) ([]byte, error) {
+    entry, err := this.keystoreEntry(alias, keystore.SecretKeyEntry)
+    if err != nil {
+        return nil, err
+    }
+
+    return entry.data.Data(), nil
+}
+
+// 设置编码的私钥和证书链, 只能设置一个私钥. 忽略 password 和 date
+func (this *PKCS12) SetPrivateKeyEntry(
+    alias string,
+    key crypto.PrivateKey,
+    certChain []*x509.Certificate,
+    password string,
+    date time.Time,
+) error {
+    if this.privateKey != nil {
+        return fmt.Errorf("go-cryptobin/pkcs12: only one private key can be stored: %w", keystore.ErrUnsupportedEntry)
+    }
+
+    if len(certChain) == 0 {
+        return errors.New("go-cryptobin/pkcs12: private key has no certificates")
+    }
+
+    err := this.AddPrivateKey(key)
+    if err != nil {
+        return err
+    }
+
+    this.AddCert(certChain[0])
+    this.AddCaCerts(certChain[1:])
+    this.keyFriendlyName = alias
+
+    return nil
+}
+
+// 设置编码的信任证书, 忽略 date
+func (this *PKCS12) SetTrustedCertEntry(alias string, cert *x509.Certificate, date time.Time) error {
+    this.AddTrustStoreEntry(cert, alias)
+
+    return nil
+}
+
+// 设置编码的密钥, 只能设置一个密钥. 忽略 password 和 date
+func (this *PKCS12) SetSecretKeyEntry(alias string, key []byte, password string, date time.Time) error {
+    if this.secretKey != nil {
+        return fmt.Errorf("go-cryptobin/pkcs12: only one secret key can be stored: %w", keystore.ErrUnsupportedEntry)
+    }
+
+    this.AddSecretKey(key)
+    this.secretKeyFriendlyName = alias
+
+    return nil
+}
@@ -0,0 +1,140 @@
+package pkcs12
+
+import (
+    "time"
+    "testing"
+    "math/big"
+    "crypto/rand"
+    "crypto/x509"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/x509/pkix"
+)
+
+func testKeyStoreCert(t *testing.T, name string, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tmpl := &x509.Certificate{
+        SerialNumber:          big.NewInt(serial),
+        Subject:               pkix.Name{CommonName: name},
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(time.Hour),
+        BasicConstraintsValid: true,
+        IsCA:                  parent == nil || name != "leaf",
+    }
+
+    if parent == nil {
+        parent, parentKey = tmpl, key
+    }
+
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cert, err := x509.ParseCertificate(der)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return cert, key
+}
+
+func Test_PrivateKeyEntry_MultipleKeys(t *testing.T) {
+    ca1, ca1Key := testKeyStoreCert(t, "ca-1", 1, nil, nil)
+    leaf1, key1 := testKeyStoreCert(t, "leaf", 2, ca1, ca1Key)
+
+    ca2, ca2Key := testKeyStoreCert(t, "ca-2", 3, nil, nil)
+    inter2, inter2Key := testKeyStoreCert(t, "inter-2", 4, ca2, ca2Key)
+    leaf2, key2 := testKeyStoreCert(t, "leaf", 5, inter2, inter2Key)
+
+    _, key3 := testKeyStoreCert(t, "leaf", 6, ca1, ca1Key)
+
+    password := "store-pass"
+    encodedPassword, err := bmpStringZeroTerminated(password)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    newEntry := func(alias string, keyId string, key *ecdsa.PrivateKey, chain []*x509.Certificate) *PKCS12 {
+        p12 := New().WithLocalKeyId([]byte(keyId))
+        if err := p12.SetPrivateKeyEntry(alias, key, chain, "", time.Time{}); err != nil {
+            t.Fatal(err)
+        }
+
+        return p12
+    }
+
+    p1 := newEntry("one", "key-1", key1, []*x509.Certificate{leaf1, ca1})
+    p2 := newEntry("two", "key-2", key2, []*x509.Certificate{leaf2, ca2, inter2})
+    p3 := newEntry("three", "key-3", key3, []*x509.Certificate{leaf1})
+
+    // 第二个密钥的证书在前, 第三个密钥没有证书
+    var authenticatedSafe []ContentInfo
+    for _, marshal := range []func(*PKCS12) (ContentInfo, error){
+        func(p *PKCS12) (ContentInfo, error) { return p.marshalPrivateKey(rand.Reader, encodedPassword, DefaultOpts) },
+        func(p *PKCS12) (ContentInfo, error) { return p.marshalCert(rand.Reader, encodedPassword, DefaultOpts) },
+    } {
+        for _, p := range []*PKCS12{p2, p1} {
+            ci, err := marshal(p)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            authenticatedSafe = append(authenticatedSafe, ci)
+        }
+    }
+
+    ci, err := p3.marshalPrivateKey(rand.Reader, encodedPassword, DefaultOpts)
+    if err != nil {
+        t.Fatal(err)
+    }
+    authenticatedSafe = append(authenticatedSafe, ci)
+
+    pfxData, err := p1.marshalPfx(authenticatedSafe, encodedPassword, DefaultOpts)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    ks, err := LoadFromBytes(pfxData, password)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cases := []struct {
+        alias string
+        key   *ecdsa.PrivateKey
+        chain []*x509.Certificate
+    }{
+        {"one", key1, []*x509.Certificate{leaf1, ca1}},
+        {"two", key2, []*x509.Certificate{leaf2, inter2, ca2}},
+    }
+
+    for _, c := range cases {
+        key, chain, err := ks.PrivateKeyEntry(c.alias, "")
+        if err != nil {
+            t.Fatalf("%s: %s", c.alias, err)
+        }
+
+        if !c.key.Equal(key) {
+            t.Errorf("%s: wrong private key", c.alias)
+        }
+
+        if len(chain) != len(c.chain) {
+            t.Fatalf("%s: got %d certificates, want %d", c.alias, len(chain), len(c.chain))
+        }
+
+        for i := range chain {
+            if !chain[i].Equal(c.chain[i]) {
+                t.Errorf("%s: certificate %d is %s, want %s", c.alias, i, chain[i].Subject, c.chain[i].Subject)
+            }
+        }
+    }
+
+    if _, _, err := ks.PrivateKeyEntry("three", ""); err != errPrivateKeyNoCert {
+        t.Errorf("three: got %v, want errPrivateKeyNoCert", err)
+    }
+}
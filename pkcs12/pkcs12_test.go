@@ -279,6 +279,66 @@ func Test_Encode(t *testing.T) {
         },
     }
     test_Encode(t, LegacyPBMAC1Opts5, "1234", "LegacyPBMAC1Opts5")
+
+    var LegacyPBMAC1Opts6 = Opts{
+        KeyCipher:  pbes2.AES256CBC,
+        KeyKDFOpts: PBKDF2Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+        },
+        CertCipher:  pbes2.AES256CBC,
+        CertKDFOpts: PBKDF2Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+        },
+        MacKDFOpts: PBMAC1Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+            KDFHash:        PBMAC1SHA256,
+            HMACHash:       PBMAC1KMAC128,
+        },
+    }
+    test_Encode(t, LegacyPBMAC1Opts6, "1234", "LegacyPBMAC1Opts6")
+
+    var LegacyPBMAC1Opts7 = Opts{
+        KeyCipher:  pbes2.AES256CBC,
+        KeyKDFOpts: PBKDF2Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+        },
+        CertCipher:  pbes2.AES256CBC,
+        CertKDFOpts: PBKDF2Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+        },
+        MacKDFOpts: PBMAC1Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+            KDFHash:        PBMAC1SHA512,
+            HMACHash:       PBMAC1KMAC256,
+        },
+    }
+    test_Encode(t, LegacyPBMAC1Opts7, "1234", "LegacyPBMAC1Opts7")
+
+    var LegacyPBMAC1Opts8 = Opts{
+        KeyCipher:  pbes2.AES256CBC,
+        KeyKDFOpts: PBKDF2Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+        },
+        CertCipher:  pbes2.AES256CBC,
+        CertKDFOpts: PBKDF2Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+        },
+        MacKDFOpts: PBMAC1Opts{
+            SaltSize:       8,
+            IterationCount: 2048,
+            KDFHash:        PBMAC1SHA256,
+            HMACHash:       PBMAC1AES256GMAC,
+        },
+    }
+    test_Encode(t, LegacyPBMAC1Opts8, "1234", "LegacyPBMAC1Opts8")
 }
 
 func test_Encode(t *testing.T, opts Opts, password string, name string) {
@@ -0,0 +1,208 @@
+package entropy
+
+import (
+    "fmt"
+    "math"
+)
+
+// Reference: NIST SP 800-90B, section 4.4 Approved Continuous Health Tests
+
+const (
+    // false positive probability alpha = 2^-20
+    alphaExponent = 20
+
+    // window size of the Adaptive Proportion Test
+    BinaryWindowSize    = 1024
+    NonBinaryWindowSize = 512
+
+    // number of samples used by the start-up tests
+    StartupSamples = 1024
+)
+
+// HealthError is returned when a health test detects a failure of the noise source.
+type HealthError struct {
+    // Test is the name of the failed test
+    Test string
+
+    // Sample is the value that triggered the failure
+    Sample byte
+
+    // Count is the number of observations of Sample
+    Count int
+
+    // Cutoff is the cutoff value of the test
+    Cutoff int
+}
+
+func (e *HealthError) Error() string {
+    return fmt.Sprintf("go-cryptobin/entropy: %s test failed, sample 0x%02x seen %d times (cutoff %d)", e.Test, e.Sample, e.Count, e.Cutoff)
+}
+
+// HealthTest is a continuous health test run on every noise sample.
+type HealthTest interface {
+    // Test feeds one noise sample to the test.
+    Test(sample byte) error
+
+    // Reset clears the state of the test.
+    Reset()
+}
+
+// RepetitionCountTest detects a catastrophic failure that
+// causes the noise source to output the same value for a long time.
+type RepetitionCountTest struct {
+    cutoff int
+
+    last    byte
+    count   int
+    started bool
+}
+
+// NewRepetitionCountTest returns a Repetition Count Test for a noise
+// source with minEntropy bits of min-entropy per sample.
+func NewRepetitionCountTest(minEntropy float64) *RepetitionCountTest {
+    return &RepetitionCountTest{
+        cutoff: RepetitionCountCutoff(minEntropy),
+    }
+}
+
+// Cutoff returns the cutoff value C.
+func (t *RepetitionCountTest) Cutoff() int {
+    return t.cutoff
+}
+
+func (t *RepetitionCountTest) Test(sample byte) error {
+    if !t.started || sample != t.last {
+        t.last = sample
+        t.count = 1
+        t.started = true
+
+        return nil
+    }
+
+    t.count++
+    if t.count >= t.cutoff {
+        return &HealthError{
+            Test:   "repetition count",
+            Sample: sample,
+            Count:  t.count,
+            Cutoff: t.cutoff,
+        }
+    }
+
+    return nil
+}
+
+func (t *RepetitionCountTest) Reset() {
+    t.last = 0
+    t.count = 0
+    t.started = false
+}
+
+// AdaptiveProportionTest detects a large loss of entropy that
+// makes one sample value far more frequent than expected.
+type AdaptiveProportionTest struct {
+    cutoff int
+    window int
+
+    first byte
+    count int
+    seen  int
+}
+
+// NewAdaptiveProportionTest returns an Adaptive Proportion Test for a noise
+// source with minEntropy bits of min-entropy per sample.
+// window is BinaryWindowSize for binary noise sources and
+// NonBinaryWindowSize for the others.
+func NewAdaptiveProportionTest(minEntropy float64, window int) *AdaptiveProportionTest {
+    return &AdaptiveProportionTest{
+        cutoff: AdaptiveProportionCutoff(minEntropy, window),
+        window: window,
+    }
+}
+
+// Cutoff returns the cutoff value C.
+func (t *AdaptiveProportionTest) Cutoff() int {
+    return t.cutoff
+}
+
+func (t *AdaptiveProportionTest) Test(sample byte) error {
+    if t.seen == 0 {
+        t.first = sample
+        t.count = 1
+        t.seen = 1
+
+        return nil
+    }
+
+    if sample == t.first {
+        t.count++
+        if t.count >= t.cutoff {
+            return &HealthError{
+                Test:   "adaptive proportion",
+                Sample: sample,
+                Count:  t.count,
+                Cutoff: t.cutoff,
+            }
+        }
+    }
+
+    t.seen++
+    if t.seen == t.window {
+        t.seen = 0
+    }
+
+    return nil
+}
+
+func (t *AdaptiveProportionTest) Reset() {
+    t.first = 0
+    t.count = 0
+    t.seen = 0
+}
+
+// RepetitionCountCutoff returns C = 1 + ceil(20 / H).
+func RepetitionCountCutoff(minEntropy float64) int {
+    checkMinEntropy(minEntropy)
+
+    return 1 + int(math.Ceil(alphaExponent/minEntropy))
+}
+
+// AdaptiveProportionCutoff returns C = 1 + CRITBINOM(W, 2^-H, 1 - alpha).
+func AdaptiveProportionCutoff(minEntropy float64, window int) int {
+    checkMinEntropy(minEntropy)
+
+    if window <= 0 {
+        panic("go-cryptobin/entropy: invalid window size")
+    }
+
+    p := math.Exp2(-minEntropy)
+    target := 1 - math.Exp2(-alphaExponent)
+
+    if p >= 1 {
+        return window
+    }
+
+    n := float64(window)
+    lgn, _ := math.Lgamma(n + 1)
+
+    var cdf float64
+    for k := 0; k <= window; k++ {
+        x := float64(k)
+
+        lgk, _ := math.Lgamma(x + 1)
+        lgnk, _ := math.Lgamma(n - x + 1)
+
+        cdf += math.Exp(lgn - lgk - lgnk + x*math.Log(p) + (n-x)*math.Log1p(-p))
+        if cdf >= target {
+            return 1 + k
+        }
+    }
+
+    return window
+}
+
+func checkMinEntropy(minEntropy float64) {
+    if !(minEntropy > 0 && minEntropy <= 8) {
+        panic("go-cryptobin/entropy: min-entropy must be in (0, 8]")
+    }
+}
@@ -0,0 +1,108 @@
+package entropy
+
+import (
+    "errors"
+    "testing"
+)
+
+func Test_RepetitionCountCutoff(t *testing.T) {
+    tests := []struct {
+        h      float64
+        cutoff int
+    }{
+        {1, 21},
+        {2, 11},
+        {3, 8},
+        {8, 4},
+        {0.5, 41},
+    }
+
+    for _, td := range tests {
+        got := RepetitionCountCutoff(td.h)
+        if got != td.cutoff {
+            t.Errorf("H=%v, got %d, want %d", td.h, got, td.cutoff)
+        }
+    }
+}
+
+// SP 800-90B, Table 2
+func Test_AdaptiveProportionCutoff(t *testing.T) {
+    tests := []struct {
+        h      float64
+        window int
+        cutoff int
+    }{
+        {0.5, NonBinaryWindowSize, 410},
+        {1, NonBinaryWindowSize, 311},
+        {2, NonBinaryWindowSize, 177},
+        {4, NonBinaryWindowSize, 62},
+        {8, NonBinaryWindowSize, 13},
+        {1, BinaryWindowSize, 589},
+    }
+
+    for _, td := range tests {
+        got := AdaptiveProportionCutoff(td.h, td.window)
+        if got != td.cutoff {
+            t.Errorf("H=%v W=%d, got %d, want %d", td.h, td.window, got, td.cutoff)
+        }
+    }
+}
+
+func Test_RepetitionCountTest(t *testing.T) {
+    rct := NewRepetitionCountTest(8)
+
+    for _, s := range []byte{1, 1, 1, 2, 2, 2, 1} {
+        if err := rct.Test(s); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    rct.Test(1)
+    rct.Test(1)
+
+    err := rct.Test(1)
+
+    var he *HealthError
+    if !errors.As(err, &he) {
+        t.Fatalf("got %v, want HealthError", err)
+    }
+
+    if he.Count != 4 || he.Cutoff != 4 || he.Sample != 1 {
+        t.Errorf("unexpected error %+v", he)
+    }
+}
+
+func Test_AdaptiveProportionTest(t *testing.T) {
+    apt := NewAdaptiveProportionTest(8, NonBinaryWindowSize)
+
+    // first sample repeated 12 times per window passes
+    for w := 0; w < 3; w++ {
+        for i := 0; i < NonBinaryWindowSize; i++ {
+            s := byte(i % 128)
+            if i < 12 {
+                s = 0xAA
+            }
+
+            if err := apt.Test(s); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+
+    apt.Reset()
+
+    var err error
+    for i := 0; i < 13 && err == nil; i++ {
+        err = apt.Test(0xAA)
+        apt.Test(byte(i))
+    }
+
+    var he *HealthError
+    if !errors.As(err, &he) {
+        t.Fatalf("got %v, want HealthError", err)
+    }
+
+    if he.Test != "adaptive proportion" || he.Count != 13 {
+        t.Errorf("unexpected error %+v", he)
+    }
+}
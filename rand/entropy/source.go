@@ -0,0 +1,156 @@
+package entropy
+
+import (
+    "io"
+    "hash"
+    "math"
+    "errors"
+    "encoding/binary"
+)
+
+// Reference: NIST SP 800-90B, section 3.1.5 Conditioning Components
+
+// The conditioned output has full entropy when the
+// input holds at least 64 bits more entropy than the output.
+const fullEntropyMargin = 64
+
+var ErrInvalidMinEntropy = errors.New("go-cryptobin/entropy: min-entropy must be in (0, 8]")
+
+// NoiseFunc adapts a function to the noise source interface.
+// Every byte read from a noise source is one sample.
+type NoiseFunc func(p []byte) (int, error)
+
+func (f NoiseFunc) Read(p []byte) (int, error) {
+    return f(p)
+}
+
+// Source is an entropy source built from a noise source, the
+// continuous health tests and a vetted hash conditioning component.
+type Source struct {
+    noise      io.Reader
+    minEntropy float64
+    conditioner func() hash.Hash
+
+    tests []HealthTest
+
+    // samples hashed for every conditioned block
+    samplesPerBlock int
+
+    buf []byte
+    err error
+}
+
+// NewSource returns an entropy source over noise, where every byte
+// read from noise is one sample with minEntropy bits of min-entropy.
+// The start-up tests are run before NewSource returns.
+func NewSource(noise io.Reader, minEntropy float64, conditioner func() hash.Hash) (*Source, error) {
+    if !(minEntropy > 0 && minEntropy <= 8) {
+        return nil, ErrInvalidMinEntropy
+    }
+
+    s := &Source{
+        noise:       noise,
+        minEntropy:  minEntropy,
+        conditioner: conditioner,
+        tests: []HealthTest{
+            NewRepetitionCountTest(minEntropy),
+            NewAdaptiveProportionTest(minEntropy, NonBinaryWindowSize),
+        },
+    }
+
+    outBits := conditioner().Size() * 8
+    s.samplesPerBlock = int(math.Ceil(float64(outBits + fullEntropyMargin) / minEntropy))
+
+    if err := s.Startup(); err != nil {
+        return nil, err
+    }
+
+    return s, nil
+}
+
+// Startup clears a previous failure and runs the health
+// tests on StartupSamples samples that are then discarded.
+func (s *Source) Startup() error {
+    for _, t := range s.tests {
+        t.Reset()
+    }
+
+    s.err = nil
+    s.buf = nil
+
+    _, err := s.samples(StartupSamples)
+
+    return err
+}
+
+// Err returns the failure of the source, if any.
+// A failed source stays failed until Startup succeeds.
+func (s *Source) Err() error {
+    return s.err
+}
+
+// Read fills p with full entropy conditioned output.
+func (s *Source) Read(p []byte) (n int, err error) {
+    for n < len(p) {
+        if len(s.buf) == 0 {
+            if s.buf, err = s.block(); err != nil {
+                return n, err
+            }
+        }
+
+        c := copy(p[n:], s.buf)
+        s.buf = s.buf[c:]
+        n += c
+    }
+
+    return n, nil
+}
+
+// Entropy returns length bytes of full entropy output.
+func (s *Source) Entropy(length int) ([]byte, error) {
+    out := make([]byte, length)
+    if _, err := s.Read(out); err != nil {
+        return nil, err
+    }
+
+    return out, nil
+}
+
+func (s *Source) block() ([]byte, error) {
+    samples, err := s.samples(s.samplesPerBlock)
+    if err != nil {
+        return nil, err
+    }
+
+    var n [8]byte
+    binary.BigEndian.PutUint64(n[:], uint64(len(samples)))
+
+    h := s.conditioner()
+    h.Write(n[:])
+    h.Write(samples)
+
+    return h.Sum(nil), nil
+}
+
+func (s *Source) samples(n int) ([]byte, error) {
+    if s.err != nil {
+        return nil, s.err
+    }
+
+    out := make([]byte, n)
+    if _, err := io.ReadFull(s.noise, out); err != nil {
+        s.err = err
+        return nil, err
+    }
+
+    for _, sample := range out {
+        for _, t := range s.tests {
+            if err := t.Test(sample); err != nil {
+                s.err = err
+                return nil, err
+            }
+        }
+    }
+
+    return out, nil
+}
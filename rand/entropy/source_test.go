@@ -0,0 +1,151 @@
+package entropy
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+    "crypto/sha256"
+    "math/rand"
+
+    "github.com/deatil/go-cryptobin/hash/sm3"
+)
+
+func testNoise(seed int64) NoiseFunc {
+    r := rand.New(rand.NewSource(seed))
+
+    return func(p []byte) (int, error) {
+        return r.Read(p)
+    }
+}
+
+func Test_Source(t *testing.T) {
+    s, err := NewSource(testNoise(1), 7, sha256.New)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    out1, err := s.Entropy(100)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    out2, err := s.Entropy(100)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if bytes.Equal(out1, out2) {
+        t.Error("outputs should differ")
+    }
+
+    s2, err := NewSource(testNoise(1), 7, sha256.New)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    out3, _ := s2.Entropy(100)
+    if !bytes.Equal(out1, out3) {
+        t.Error("same noise should give same output")
+    }
+}
+
+func Test_Source_SM3(t *testing.T) {
+    s, err := NewSource(testNoise(2), 4, sm3.New)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err = s.Entropy(64); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func Test_Source_StartupFailure(t *testing.T) {
+    stuck := NoiseFunc(func(p []byte) (int, error) {
+        for i := range p {
+            p[i] = 0x55
+        }
+
+        return len(p), nil
+    })
+
+    _, err := NewSource(stuck, 4, sha256.New)
+
+    var he *HealthError
+    if !errors.As(err, &he) {
+        t.Fatalf("got %v, want HealthError", err)
+    }
+
+    if he.Test != "repetition count" {
+        t.Errorf("got test %q", he.Test)
+    }
+}
+
+func Test_Source_RuntimeFailure(t *testing.T) {
+    good := testNoise(3)
+    broken := false
+
+    noise := NoiseFunc(func(p []byte) (int, error) {
+        if broken {
+            for i := range p {
+                p[i] = 0
+            }
+
+            return len(p), nil
+        }
+
+        return good(p)
+    })
+
+    s, err := NewSource(noise, 4, sha256.New)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err = s.Entropy(32); err != nil {
+        t.Fatal(err)
+    }
+
+    broken = true
+
+    _, err = s.Entropy(32)
+
+    var he *HealthError
+    if !errors.As(err, &he) {
+        t.Fatalf("got %v, want HealthError", err)
+    }
+
+    // the failure is sticky
+    broken = false
+    if _, err = s.Entropy(32); err != he {
+        t.Errorf("got %v, want sticky failure", err)
+    }
+
+    if err = s.Startup(); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err = s.Entropy(32); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func Test_Source_ReadError(t *testing.T) {
+    errNoise := errors.New("noise error")
+
+    noise := NoiseFunc(func(p []byte) (int, error) {
+        return 0, errNoise
+    })
+
+    _, err := NewSource(noise, 4, sha256.New)
+    if err != errNoise {
+        t.Errorf("got %v, want %v", err, errNoise)
+    }
+}
+
+func Test_NewSource_InvalidMinEntropy(t *testing.T) {
+    _, err := NewSource(testNoise(4), 0, sha256.New)
+    if err != ErrInvalidMinEntropy {
+        t.Errorf("got %v", err)
+    }
+}
@@ -0,0 +1,86 @@
+package rbg
+
+import (
+    "hash"
+
+    "github.com/deatil/go-cryptobin/rand/drbg"
+)
+
+// DRBG is the interface implemented by the rand/drbg mechanisms.
+type DRBG interface {
+    Reseed(entropy, additional []byte) error
+    Generate(out, additional []byte) error
+}
+
+// Mechanism describes how to instantiate a DRBG.
+type Mechanism struct {
+    // Strength is the security strength in bits
+    Strength int
+
+    // MaxRequest is the max number of bytes of one Generate call
+    MaxRequest int
+
+    // New instantiates the DRBG
+    New func(entropy, nonce, personal []byte) (DRBG, error)
+}
+
+// max_number_of_bits_per_request = 2^19 bits
+const maxRequest = 1 << 16
+
+// CTR returns a CTR_DRBG mechanism.
+func CTR(cip drbg.BlockCipher, keyLen int) Mechanism {
+    return Mechanism{
+        Strength:   keyLen * 8,
+        MaxRequest: maxRequest,
+        New: func(entropy, nonce, personal []byte) (DRBG, error) {
+            return drbg.NewCTR(cip, keyLen, entropy, nonce, personal)
+        },
+    }
+}
+
+// Hash returns a NIST Hash_DRBG mechanism.
+func Hash(h func() hash.Hash) Mechanism {
+    return Mechanism{
+        Strength:   hashStrength(h),
+        MaxRequest: maxRequest,
+        New: func(entropy, nonce, personal []byte) (DRBG, error) {
+            return drbg.NewNISTHash(h(), entropy, nonce, personal)
+        },
+    }
+}
+
+// GMHash returns a GM/T 0105 Hash_DRBG mechanism.
+func GMHash(h func() hash.Hash) Mechanism {
+    return Mechanism{
+        Strength:   hashStrength(h),
+        MaxRequest: h().Size(),
+        New: func(entropy, nonce, personal []byte) (DRBG, error) {
+            return drbg.NewGMHash(h(), entropy, nonce, personal)
+        },
+    }
+}
+
+// HMAC returns a HMAC_DRBG mechanism.
+func HMAC(h func() hash.Hash) Mechanism {
+    return Mechanism{
+        Strength:   hashStrength(h),
+        MaxRequest: maxRequest,
+        New: func(entropy, nonce, personal []byte) (DRBG, error) {
+            return drbg.NewHMAC(h, entropy, nonce, personal)
+        },
+    }
+}
+
+// SP 800-57 Part 1, Table 3
+func hashStrength(h func() hash.Hash) int {
+    size := h().Size() * 8
+
+    switch {
+        case size >= 256:
+            return 256
+        case size >= 224:
+            return 192
+        default:
+            return 128
+    }
+}
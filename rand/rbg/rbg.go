@@ -0,0 +1,215 @@
+package rbg
+
+import (
+    "io"
+    "errors"
+
+    "github.com/deatil/go-cryptobin/rand/drbg"
+)
+
+// Reference: NIST SP 800-90C, Recommendation for Random Bit Generator (RBG) Constructions
+//
+// The entropy source is any io.Reader that returns full entropy
+// output, such as the Source of the rand/entropy package.
+// Errors of the entropy source, for example a *entropy.HealthError,
+// are returned unchanged.
+
+// RBG2 is a DRBG seeded by a physical or non-physical entropy source
+// that is available for reseeding. (SP 800-90C, section 5)
+type RBG2 struct {
+    src  io.Reader
+    mech Mechanism
+    drbg DRBG
+
+    predictionResistance bool
+}
+
+// NewRBG2 instantiates mech with 3s/2 bits of entropy read from src,
+// where s is the security strength of mech. With predictionResistance
+// the DRBG is reseeded from src before every request.
+func NewRBG2(src io.Reader, mech Mechanism, personal []byte, predictionResistance bool) (*RBG2, error) {
+    strength := mech.Strength / 8
+
+    entropy, err := readEntropy(src, strength)
+    if err != nil {
+        return nil, err
+    }
+
+    nonce, err := readEntropy(src, strength / 2)
+    if err != nil {
+        return nil, err
+    }
+
+    d, err := mech.New(entropy, nonce, personal)
+    if err != nil {
+        return nil, err
+    }
+
+    return &RBG2{
+        src:  src,
+        mech: mech,
+        drbg: d,
+        predictionResistance: predictionResistance,
+    }, nil
+}
+
+// Reseed reseeds the DRBG with s bits of entropy read from src.
+func (r *RBG2) Reseed(additional []byte) error {
+    entropy, err := readEntropy(r.src, r.mech.Strength / 8)
+    if err != nil {
+        return err
+    }
+
+    return r.drbg.Reseed(entropy, additional)
+}
+
+// Generate fills out with random bytes.
+func (r *RBG2) Generate(out, additional []byte) error {
+    for len(out) > 0 {
+        n := len(out)
+        if n > r.mech.MaxRequest {
+            n = r.mech.MaxRequest
+        }
+
+        // 附加输入已用于重新播种时, 生成时置空. (SP 800-90A, section 9.3.1)
+        add := additional
+        if r.predictionResistance {
+            if err := r.Reseed(add); err != nil {
+                return err
+            }
+
+            add = nil
+        }
+
+        err := r.drbg.Generate(out[:n], add)
+        if errors.Is(err, drbg.ErrReseedRequired) {
+            if err = r.Reseed(add); err == nil {
+                err = r.drbg.Generate(out[:n], nil)
+            }
+        }
+
+        if err != nil {
+            return err
+        }
+
+        out = out[n:]
+    }
+
+    return nil
+}
+
+// Read implements io.Reader.
+func (r *RBG2) Read(p []byte) (int, error) {
+    if err := r.Generate(p, nil); err != nil {
+        return 0, err
+    }
+
+    return len(p), nil
+}
+
+// RBG3XOR combines the output of an RBG2 with full entropy bits from
+// the entropy source, so every output bit has full entropy. (SP 800-90C, section 6.4)
+type RBG3XOR struct {
+    src io.Reader
+    rbg *RBG2
+}
+
+// NewRBG3XOR returns a RBG3(XOR) construction.
+func NewRBG3XOR(src io.Reader, mech Mechanism, personal []byte) (*RBG3XOR, error) {
+    r, err := NewRBG2(src, mech, personal, false)
+    if err != nil {
+        return nil, err
+    }
+
+    return &RBG3XOR{
+        src: src,
+        rbg: r,
+    }, nil
+}
+
+// Generate fills out with full entropy random bytes.
+func (r *RBG3XOR) Generate(out, additional []byte) error {
+    if err := r.rbg.Generate(out, additional); err != nil {
+        return err
+    }
+
+    entropy, err := readEntropy(r.src, len(out))
+    if err != nil {
+        return err
+    }
+
+    for i := range out {
+        out[i] ^= entropy[i]
+    }
+
+    return nil
+}
+
+// Read implements io.Reader.
+func (r *RBG3XOR) Read(p []byte) (int, error) {
+    if err := r.Generate(p, nil); err != nil {
+        return 0, err
+    }
+
+    return len(p), nil
+}
+
+// RBG3RS reseeds the DRBG with s bits of full entropy
+// before producing every s bits of output. (SP 800-90C, section 6.5)
+type RBG3RS struct {
+    rbg *RBG2
+}
+
+// NewRBG3RS returns a RBG3(RS) construction.
+func NewRBG3RS(src io.Reader, mech Mechanism, personal []byte) (*RBG3RS, error) {
+    r, err := NewRBG2(src, mech, personal, false)
+    if err != nil {
+        return nil, err
+    }
+
+    return &RBG3RS{
+        rbg: r,
+    }, nil
+}
+
+// Generate fills out with full entropy random bytes.
+func (r *RBG3RS) Generate(out, additional []byte) error {
+    size := r.rbg.mech.Strength / 8
+
+    for len(out) > 0 {
+        n := len(out)
+        if n > size {
+            n = size
+        }
+
+        if err := r.rbg.Reseed(additional); err != nil {
+            return err
+        }
+
+        if err := r.rbg.drbg.Generate(out[:n], nil); err != nil {
+            return err
+        }
+
+        out = out[n:]
+    }
+
+    return nil
+}
+
+// Read implements io.Reader.
+func (r *RBG3RS) Read(p []byte) (int, error) {
+    if err := r.Generate(p, nil); err != nil {
+        return 0, err
+    }
+
+    return len(p), nil
+}
+
+func readEntropy(src io.Reader, n int) ([]byte, error) {
+    buf := make([]byte, n)
+    if _, err := io.ReadFull(src, buf); err != nil {
+        return nil, err
+    }
+
+    return buf, nil
+}
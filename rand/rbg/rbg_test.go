@@ -0,0 +1,241 @@
+package rbg
+
+import (
+    "bytes"
+    "errors"
+    "strings"
+    "testing"
+    "math/rand"
+    "crypto/aes"
+    "crypto/sha256"
+    "crypto/sha512"
+
+    "github.com/deatil/go-cryptobin/hash/sm3"
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+    "github.com/deatil/go-cryptobin/rand/drbg"
+    "github.com/deatil/go-cryptobin/rand/entropy"
+)
+
+func newSource(t *testing.T, seed int64) *entropy.Source {
+    r := rand.New(rand.NewSource(seed))
+
+    src, err := entropy.NewSource(entropy.NoiseFunc(r.Read), 6, sha256.New)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return src
+}
+
+var mechanisms = []struct {
+    name string
+    mech Mechanism
+}{
+    {"CTR-AES128", CTR(aes.NewCipher, 16)},
+    {"CTR-AES256", CTR(aes.NewCipher, 32)},
+    {"CTR-SM4", CTR(sm4.NewCipher, 16)},
+    {"Hash-SHA256", Hash(sha256.New)},
+    {"Hash-SHA512", Hash(sha512.New)},
+    {"GMHash-SM3", GMHash(sm3.New)},
+    {"HMAC-SHA256", HMAC(sha256.New)},
+    {"HMAC-SM3", HMAC(sm3.New)},
+}
+
+func Test_Strength(t *testing.T) {
+    tests := map[string]int{
+        "CTR-AES128":  128,
+        "CTR-AES256":  256,
+        "Hash-SHA256": 256,
+        "GMHash-SM3":  256,
+    }
+
+    for _, m := range mechanisms {
+        if want, ok := tests[m.name]; ok && m.mech.Strength != want {
+            t.Errorf("%s: got %d, want %d", m.name, m.mech.Strength, want)
+        }
+    }
+}
+
+func Test_RBG2(t *testing.T) {
+    for _, m := range mechanisms {
+        for _, pr := range []bool{false, true} {
+            r, err := NewRBG2(newSource(t, 1), m.mech, []byte("personal"), pr)
+            if err != nil {
+                t.Fatalf("%s: %v", m.name, err)
+            }
+
+            out1 := make([]byte, 1000)
+            if _, err = r.Read(out1); err != nil {
+                t.Fatalf("%s: %v", m.name, err)
+            }
+
+            out2 := make([]byte, 1000)
+            if err = r.Generate(out2, []byte("additional")); err != nil {
+                t.Fatalf("%s: %v", m.name, err)
+            }
+
+            if bytes.Equal(out1, out2) {
+                t.Errorf("%s: outputs should differ", m.name)
+            }
+        }
+    }
+}
+
+func Test_RBG2_Deterministic(t *testing.T) {
+    mech := HMAC(sha256.New)
+
+    r1, _ := NewRBG2(newSource(t, 5), mech, nil, false)
+    r2, _ := NewRBG2(newSource(t, 5), mech, nil, false)
+
+    out1 := make([]byte, 64)
+    out2 := make([]byte, 64)
+    r1.Read(out1)
+    r2.Read(out2)
+
+    if !bytes.Equal(out1, out2) {
+        t.Error("same entropy should give same output")
+    }
+}
+
+// 记录每次调用的附加输入
+type recordDRBG struct {
+    calls  []string
+    reseed bool
+}
+
+func (d *recordDRBG) Reseed(entropy, additional []byte) error {
+    d.calls = append(d.calls, "reseed:" + string(additional))
+    d.reseed = false
+
+    return nil
+}
+
+func (d *recordDRBG) Generate(out, additional []byte) error {
+    if d.reseed {
+        d.calls = append(d.calls, "required:" + string(additional))
+        return drbg.ErrReseedRequired
+    }
+
+    d.calls = append(d.calls, "generate:" + string(additional))
+
+    return nil
+}
+
+func Test_RBG2_ReseedAdditional(t *testing.T) {
+    d := &recordDRBG{}
+    mech := Mechanism{
+        Strength:   128,
+        MaxRequest: 16,
+        New: func(entropy, nonce, personal []byte) (DRBG, error) {
+            return d, nil
+        },
+    }
+
+    tests := []struct {
+        name   string
+        pr     bool
+        reseed bool
+        want   []string
+    }{
+        {"no reseed", false, false, []string{"generate:add"}},
+        {"reseed required", false, true, []string{"required:add", "reseed:add", "generate:"}},
+        {"prediction resistance", true, false, []string{"reseed:add", "generate:"}},
+    }
+
+    for _, test := range tests {
+        r, err := NewRBG2(newSource(t, 4), mech, nil, test.pr)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        d.calls, d.reseed = nil, test.reseed
+
+        if err := r.Generate(make([]byte, 16), []byte("add")); err != nil {
+            t.Fatalf("%s: %v", test.name, err)
+        }
+
+        if strings.Join(d.calls, ",") != strings.Join(test.want, ",") {
+            t.Errorf("%s: got %v, want %v", test.name, d.calls, test.want)
+        }
+    }
+
+    rs, err := NewRBG3RS(newSource(t, 4), mech, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    d.calls, d.reseed = nil, false
+
+    if err := rs.Generate(make([]byte, 16), []byte("add")); err != nil {
+        t.Fatal(err)
+    }
+
+    if strings.Join(d.calls, ",") != "reseed:add,generate:" {
+        t.Errorf("RBG3RS: got %v", d.calls)
+    }
+}
+
+func Test_RBG3(t *testing.T) {
+    for _, m := range mechanisms {
+        xor, err := NewRBG3XOR(newSource(t, 2), m.mech, nil)
+        if err != nil {
+            t.Fatalf("%s: %v", m.name, err)
+        }
+
+        rs, err := NewRBG3RS(newSource(t, 2), m.mech, nil)
+        if err != nil {
+            t.Fatalf("%s: %v", m.name, err)
+        }
+
+        out1 := make([]byte, 300)
+        out2 := make([]byte, 300)
+
+        if _, err = xor.Read(out1); err != nil {
+            t.Fatalf("%s: %v", m.name, err)
+        }
+
+        if _, err = rs.Read(out2); err != nil {
+            t.Fatalf("%s: %v", m.name, err)
+        }
+
+        if bytes.Equal(out1, out2) || bytes.Equal(out1, make([]byte, 300)) {
+            t.Errorf("%s: unexpected output", m.name)
+        }
+    }
+}
+
+func Test_HealthFailure(t *testing.T) {
+    r := rand.New(rand.NewSource(3))
+    broken := false
+
+    noise := entropy.NoiseFunc(func(p []byte) (int, error) {
+        if broken {
+            for i := range p {
+                p[i] = 7
+            }
+
+            return len(p), nil
+        }
+
+        return r.Read(p)
+    })
+
+    src, err := entropy.NewSource(noise, 6, sha256.New)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    rbg, err := NewRBG3RS(src, CTR(aes.NewCipher, 16), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    broken = true
+
+    _, err = rbg.Read(make([]byte, 1024))
+
+    var he *entropy.HealthError
+    if !errors.As(err, &he) {
+        t.Fatalf("got %v, want HealthError", err)
+    }
+}
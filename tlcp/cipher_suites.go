@@ -0,0 +1,165 @@
+package tlcp
+
+import (
+    "hash"
+    "crypto/hmac"
+    "crypto/cipher"
+
+    "github.com/deatil/go-cryptobin/hash/sm3"
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+)
+
+// TLCP 密码套件, GB/T 38636-2020 6.4.5.2.1
+const (
+    ECDHE_SM4_CBC_SM3 uint16 = 0xe011
+    ECDHE_SM4_GCM_SM3 uint16 = 0xe051
+    ECC_SM4_CBC_SM3   uint16 = 0xe013
+    ECC_SM4_GCM_SM3   uint16 = 0xe053
+)
+
+// 默认密码套件
+var defaultCipherSuites = []uint16{
+    ECC_SM4_GCM_SM3,
+    ECC_SM4_CBC_SM3,
+    ECDHE_SM4_GCM_SM3,
+    ECDHE_SM4_CBC_SM3,
+}
+
+const (
+    // suiteECDHE indicates that the cipher suite involves SM2 key exchange
+    // with ephemeral keys, which needs the client's encryption certificate.
+    suiteECDHE = 1 << iota
+)
+
+// A cipherSuite is a TLCP cipher suite.
+type cipherSuite struct {
+    id uint16
+    // the lengths, in bytes, of the key material needed for each component.
+    keyLen int
+    macLen int
+    ivLen  int
+    ka     func() keyAgreement
+    flags  int
+    cipher func(key []byte) cipher.Block
+    mac    func(key []byte) hash.Hash
+    aead   func(key, fixedNonce []byte) aead
+}
+
+var cipherSuites = []*cipherSuite{
+    {ECC_SM4_GCM_SM3, 16, 0, 4, eccKA, 0, nil, nil, aeadSM4GCM},
+    {ECC_SM4_CBC_SM3, 16, 32, 16, eccKA, 0, cipherSM4, macSM3, nil},
+    {ECDHE_SM4_GCM_SM3, 16, 0, 4, ecdheKA, suiteECDHE, nil, nil, aeadSM4GCM},
+    {ECDHE_SM4_CBC_SM3, 16, 32, 16, ecdheKA, suiteECDHE, cipherSM4, macSM3, nil},
+}
+
+// CipherSuiteName returns the standard name for the passed cipher suite ID
+func CipherSuiteName(id uint16) string {
+    switch id {
+        case ECDHE_SM4_CBC_SM3:
+            return "ECDHE_SM4_CBC_SM3"
+        case ECDHE_SM4_GCM_SM3:
+            return "ECDHE_SM4_GCM_SM3"
+        case ECC_SM4_CBC_SM3:
+            return "ECC_SM4_CBC_SM3"
+        case ECC_SM4_GCM_SM3:
+            return "ECC_SM4_GCM_SM3"
+    }
+
+    return "unknown"
+}
+
+func cipherSuiteByID(id uint16) *cipherSuite {
+    for _, suite := range cipherSuites {
+        if suite.id == id {
+            return suite
+        }
+    }
+
+    return nil
+}
+
+// mutualCipherSuite returns a cipherSuite given a list of supported
+// ciphersuites and the id requested by the peer.
+func mutualCipherSuite(have []uint16, want uint16) *cipherSuite {
+    for _, id := range have {
+        if id == want {
+            return cipherSuiteByID(id)
+        }
+    }
+
+    return nil
+}
+
+func eccKA() keyAgreement {
+    return &eccKeyAgreement{}
+}
+
+func ecdheKA() keyAgreement {
+    return &ecdheKeyAgreement{}
+}
+
+func cipherSM4(key []byte) cipher.Block {
+    block, _ := sm4.NewCipher(key)
+    return block
+}
+
+func macSM3(key []byte) hash.Hash {
+    return hmac.New(sm3.New, key)
+}
+
+// aead 记录层 AEAD 加密
+type aead interface {
+    cipher.AEAD
+
+    // explicitNonceLen returns the number of bytes of explicit nonce
+    // included in each record.
+    explicitNonceLen() int
+}
+
+const (
+    aeadNonceLength   = 12
+    noncePrefixLength = 4
+)
+
+// prefixNonceAEAD wraps an AEAD and prefixes a fixed portion of the nonce to
+// each call.
+type prefixNonceAEAD struct {
+    // nonce contains the fixed part of the nonce in the first four bytes.
+    nonce [aeadNonceLength]byte
+    aead  cipher.AEAD
+}
+
+func (f *prefixNonceAEAD) NonceSize() int        { return aeadNonceLength - noncePrefixLength }
+func (f *prefixNonceAEAD) Overhead() int         { return f.aead.Overhead() }
+func (f *prefixNonceAEAD) explicitNonceLen() int { return f.NonceSize() }
+
+func (f *prefixNonceAEAD) Seal(out, nonce, plaintext, additionalData []byte) []byte {
+    copy(f.nonce[4:], nonce)
+    return f.aead.Seal(out, f.nonce[:], plaintext, additionalData)
+}
+
+func (f *prefixNonceAEAD) Open(out, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+    copy(f.nonce[4:], nonce)
+    return f.aead.Open(out, f.nonce[:], ciphertext, additionalData)
+}
+
+func aeadSM4GCM(key, noncePrefix []byte) aead {
+    if len(noncePrefix) != noncePrefixLength {
+        panic("tlcp: internal error: wrong nonce length")
+    }
+
+    block, err := sm4.NewCipher(key)
+    if err != nil {
+        panic(err)
+    }
+
+    gcm, err := cipher.NewGCM(block)
+    if err != nil {
+        panic(err)
+    }
+
+    ret := &prefixNonceAEAD{aead: gcm}
+    copy(ret.nonce[:], noncePrefix)
+
+    return ret
+}
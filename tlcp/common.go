@@ -0,0 +1,337 @@
+package tlcp
+
+import (
+    "io"
+    "sync"
+    "time"
+    "errors"
+    "crypto"
+    "crypto/rand"
+    "container/list"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+const (
+    // TLCP 1.1, GB/T 38636-2020
+    VersionTLCP = 0x0101
+)
+
+const (
+    maxPlaintext       = 16384        // maximum plaintext payload length
+    maxCiphertext      = 16384 + 2048 // maximum ciphertext payload length
+    recordHeaderLen    = 5            // record header length
+    maxHandshake       = 65536        // maximum handshake we support (protocol max is 16 MB)
+    maxUselessRecords  = 16           // maximum number of consecutive non-advancing records
+)
+
+// TLCP record types.
+type recordType uint8
+
+const (
+    recordTypeChangeCipherSpec recordType = 20
+    recordTypeAlert            recordType = 21
+    recordTypeHandshake        recordType = 22
+    recordTypeApplicationData  recordType = 23
+)
+
+// TLCP handshake message types.
+const (
+    typeClientHello        uint8 = 1
+    typeServerHello        uint8 = 2
+    typeCertificate        uint8 = 11
+    typeServerKeyExchange  uint8 = 12
+    typeCertificateRequest uint8 = 13
+    typeServerHelloDone    uint8 = 14
+    typeCertificateVerify  uint8 = 15
+    typeClientKeyExchange  uint8 = 16
+    typeFinished           uint8 = 20
+)
+
+// TLCP compression types.
+const (
+    compressionNone uint8 = 0
+)
+
+// TLCP elliptic curve parameters
+const (
+    curveTypeNamedCurve uint8  = 3
+    curveSM2            uint16 = 41 // sm2p256v1
+)
+
+// TLCP client certificate types
+const (
+    certTypeRSASign    = 1
+    certTypeECDSASign  = 64
+    certTypeIBCParams  = 80
+)
+
+// ClientAuthType declares the policy the server will follow for
+// TLCP Client Authentication.
+type ClientAuthType int
+
+const (
+    // NoClientCert indicates that no client certificate should be requested
+    // during the handshake. ECDHE cipher suites always request one.
+    NoClientCert ClientAuthType = iota
+    // RequestClientCert indicates that a client certificate should be requested
+    // during the handshake, but does not require that the client send any
+    // certificates.
+    RequestClientCert
+    // RequireAnyClientCert indicates that a client certificate should be requested
+    // during the handshake, and that at least one certificate is required to be
+    // sent by the client, but that certificate is not required to be valid.
+    RequireAnyClientCert
+    // VerifyClientCertIfGiven indicates that a client certificate should be requested
+    // during the handshake, but does not require that the client sends a
+    // certificate. If the client does send a certificate it is required to be
+    // valid.
+    VerifyClientCertIfGiven
+    // RequireAndVerifyClientCert indicates that a client certificate should be requested
+    // during the handshake, and that at least one valid certificate is required
+    // to be sent by the client.
+    RequireAndVerifyClientCert
+)
+
+// requiresClientCert reports whether the ClientAuthType requires a client
+// certificate to be provided.
+func requiresClientCert(c ClientAuthType) bool {
+    switch c {
+        case RequireAnyClientCert, RequireAndVerifyClientCert:
+            return true
+        default:
+            return false
+    }
+}
+
+// ConnectionState records basic TLCP details about the connection.
+type ConnectionState struct {
+    Version           uint16              // TLCP version used by the connection
+    HandshakeComplete bool                // TLCP handshake is complete
+    DidResume         bool                // connection resumes a previous TLCP connection
+    CipherSuite       uint16              // cipher suite in use
+    ServerName        string              // server name requested by client
+    PeerCertificates  []*x509.Certificate // certificate chain presented by remote peer, signing certificate first
+    VerifiedChains    [][]*x509.Certificate // verified chains built from PeerCertificates
+    SessionID         []byte              // session id of the connection
+}
+
+// Certificate 证书和私钥
+type Certificate struct {
+    // DER 编码的证书链
+    Certificate [][]byte
+
+    // 私钥, 需要实现 crypto.Signer, 加密证书的私钥需要为 *sm2.PrivateKey
+    PrivateKey crypto.PrivateKey
+
+    // 解析后的证书, 为空时会按需解析
+    Leaf *x509.Certificate
+}
+
+// 解析证书
+func (c *Certificate) leaf() (*x509.Certificate, error) {
+    if c.Leaf != nil {
+        return c.Leaf, nil
+    }
+
+    if len(c.Certificate) == 0 {
+        return nil, errors.New("tlcp: empty certificate")
+    }
+
+    return x509.ParseCertificate(c.Certificate[0])
+}
+
+// SessionState 可恢复的会话
+type SessionState struct {
+    sessionID        []byte
+    cipherSuite      uint16
+    masterSecret     []byte
+    peerCertificates []*x509.Certificate
+    verifiedChains   [][]*x509.Certificate
+    createdAt        time.Time
+}
+
+// SessionCache 会话缓存, 客户端使用服务端地址作为 key, 服务端使用会话 ID 作为 key
+type SessionCache interface {
+    // Get searches for a SessionState associated with the given key.
+    // On return, ok is true if one was found.
+    Get(key string) (session *SessionState, ok bool)
+
+    // Put adds the SessionState to the cache with the given key. It is
+    // called with a nil session to remove the entry.
+    Put(key string, session *SessionState)
+}
+
+// Config 配置
+type Config struct {
+    // Rand provides the source of entropy for nonces and SM2 operations.
+    // If Rand is nil, crypto/rand.Reader is used.
+    Rand io.Reader
+
+    // Time returns the current time. If Time is nil, time.Now is used.
+    Time func() time.Time
+
+    // Certificates 签名证书在前, 加密证书在后.
+    // 服务端必须设置, 客户端在服务端请求证书时发送
+    Certificates []Certificate
+
+    // RootCAs 客户端验证服务端证书使用的根证书
+    RootCAs *x509.CertPool
+
+    // ServerName 用于验证服务端证书的主机名
+    ServerName string
+
+    // ClientAuth 服务端对客户端证书的策略
+    ClientAuth ClientAuthType
+
+    // ClientCAs 服务端验证客户端证书使用的根证书
+    ClientCAs *x509.CertPool
+
+    // InsecureSkipVerify 跳过对端证书验证
+    InsecureSkipVerify bool
+
+    // CipherSuites 支持的密码套件, 为空时使用默认列表
+    CipherSuites []uint16
+
+    // SessionCache 会话缓存, 为空时不使用会话恢复
+    SessionCache SessionCache
+
+    // SessionTimeout 会话有效时间, 为 0 时为 24 小时
+    SessionTimeout time.Duration
+}
+
+// Clone returns a shallow clone of c.
+func (c *Config) Clone() *Config {
+    if c == nil {
+        return nil
+    }
+
+    cc := *c
+    return &cc
+}
+
+func (c *Config) rand() io.Reader {
+    if c.Rand == nil {
+        return rand.Reader
+    }
+
+    return c.Rand
+}
+
+func (c *Config) time() time.Time {
+    if c.Time == nil {
+        return time.Now()
+    }
+
+    return c.Time()
+}
+
+func (c *Config) sessionTimeout() time.Duration {
+    if c.SessionTimeout == 0 {
+        return 24 * time.Hour
+    }
+
+    return c.SessionTimeout
+}
+
+func (c *Config) cipherSuites() []uint16 {
+    if len(c.CipherSuites) == 0 {
+        return defaultCipherSuites
+    }
+
+    return c.CipherSuites
+}
+
+// 签名证书和加密证书
+func (c *Config) certificatePair() (sign, enc *Certificate, err error) {
+    if len(c.Certificates) < 2 {
+        return nil, nil, errors.New("tlcp: signing and encryption certificates are required")
+    }
+
+    return &c.Certificates[0], &c.Certificates[1], nil
+}
+
+// ==========
+
+type lruSessionCacheEntry struct {
+    sessionKey string
+    state      *SessionState
+}
+
+// lruSessionCache is a SessionCache implementation that uses an LRU
+// caching strategy.
+type lruSessionCache struct {
+    sync.Mutex
+
+    m        map[string]*list.Element
+    q        *list.List
+    capacity int
+}
+
+// NewLRUSessionCache returns a SessionCache with the given capacity that uses
+// an LRU strategy. If capacity is < 1, a default capacity is used instead.
+func NewLRUSessionCache(capacity int) SessionCache {
+    const defaultSessionCacheCapacity = 64
+
+    if capacity < 1 {
+        capacity = defaultSessionCacheCapacity
+    }
+
+    return &lruSessionCache{
+        m:        make(map[string]*list.Element),
+        q:        list.New(),
+        capacity: capacity,
+    }
+}
+
+// Put adds the provided (sessionKey, cs) pair to the cache. If cs is nil, the entry
+// corresponding to sessionKey is removed from the cache instead.
+func (c *lruSessionCache) Put(sessionKey string, cs *SessionState) {
+    c.Lock()
+    defer c.Unlock()
+
+    if elem, ok := c.m[sessionKey]; ok {
+        if cs == nil {
+            c.q.Remove(elem)
+            delete(c.m, sessionKey)
+        } else {
+            entry := elem.Value.(*lruSessionCacheEntry)
+            entry.state = cs
+            c.q.MoveToFront(elem)
+        }
+
+        return
+    }
+
+    if cs == nil {
+        return
+    }
+
+    if c.q.Len() < c.capacity {
+        entry := &lruSessionCacheEntry{sessionKey, cs}
+        c.m[sessionKey] = c.q.PushFront(entry)
+        return
+    }
+
+    elem := c.q.Back()
+    entry := elem.Value.(*lruSessionCacheEntry)
+    delete(c.m, entry.sessionKey)
+    entry.sessionKey = sessionKey
+    entry.state = cs
+    c.q.MoveToFront(elem)
+    c.m[sessionKey] = elem
+}
+
+// Get returns the SessionState value associated with a given key. It
+// returns (nil, false) if no value is found.
+func (c *lruSessionCache) Get(sessionKey string) (*SessionState, bool) {
+    c.Lock()
+    defer c.Unlock()
+
+    if elem, ok := c.m[sessionKey]; ok {
+        c.q.MoveToFront(elem)
+        return elem.Value.(*lruSessionCacheEntry).state, true
+    }
+
+    return nil, false
+}
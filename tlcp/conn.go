@@ -0,0 +1,1136 @@
+package tlcp
+
+import (
+    "io"
+    "net"
+    "sync"
+    "time"
+    "hash"
+    "bytes"
+    "errors"
+    "fmt"
+    "crypto/hmac"
+    "crypto/cipher"
+    "crypto/subtle"
+    "sync/atomic"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+// A Conn represents a secured connection.
+// It implements the net.Conn interface.
+type Conn struct {
+    // constant
+    conn        net.Conn
+    isClient    bool
+    handshakeFn func() error // (*Conn).clientHandshake or serverHandshake
+
+    // isHandshakeComplete is true if the connection is currently transferring
+    // application data (i.e. is not currently processing a handshake).
+    isHandshakeComplete atomic.Bool
+
+    // constant after handshake; protected by handshakeMutex
+    handshakeMutex sync.Mutex
+    handshakeErr   error   // error resulting from handshake
+    vers           uint16  // TLCP version
+    haveVers       bool    // version has been negotiated
+    config         *Config // configuration passed to constructor
+    didResume      bool    // whether this connection was a session resumption
+    cipherSuite    uint16
+    sessionID      []byte
+
+    // peerCertificates 对端证书, 签名证书在前, 加密证书在后
+    peerCertificates []*x509.Certificate
+    verifiedChains   [][]*x509.Certificate
+    serverName       string
+
+    // input/output
+    in, out   halfConn
+    rawInput  bytes.Buffer // raw input, starting with a record header
+    input     bytes.Reader // application data waiting to be read, from rawInput.Next
+    hand      bytes.Buffer // handshake data waiting to be read
+    buffering bool         // whether records are buffered in sendBuf
+    sendBuf   []byte       // a buffer of records waiting to be sent
+
+    // retryCount counts the number of consecutive non-advancing records
+    // received by Conn.readRecord.
+    retryCount int
+
+    // activeCall indicates whether Close has been call in the low bit.
+    // the rest of the bits are the number of goroutines in Conn.Write.
+    activeCall atomic.Int32
+
+    closeNotifyErr  error
+    closeNotifySent bool
+
+    tmp [16]byte
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+    return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+    return c.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read and write deadlines associated with the connection.
+// A zero value for t means Read and Write will not time out.
+// After a Write has timed out, the TLCP state is corrupt and all future writes will return the same error.
+func (c *Conn) SetDeadline(t time.Time) error {
+    return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline on the underlying connection.
+// A zero value for t means Read will not time out.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+    return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline on the underlying connection.
+// A zero value for t means Write will not time out.
+// After a Write has timed out, the TLCP state is corrupt and all future writes will return the same error.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+    return c.conn.SetWriteDeadline(t)
+}
+
+// NetConn returns the underlying connection that is wrapped by c.
+func (c *Conn) NetConn() net.Conn {
+    return c.conn
+}
+
+// A halfConn represents one direction of the record layer
+// connection, either sending or receiving.
+type halfConn struct {
+    sync.Mutex
+
+    err     error     // first permanent error
+    version uint16    // protocol version
+    cipher  any       // cipher algorithm, cipher.Block or aead
+    mac     hash.Hash // MAC algorithm
+    seq     [8]byte   // 64-bit sequence number
+
+    scratchBuf [13]byte // to avoid allocs; interface method args escape
+
+    nextCipher any       // next encryption state
+    nextMac    hash.Hash // next MAC algorithm
+}
+
+type permanentError struct {
+    err net.Error
+}
+
+func (e *permanentError) Error() string   { return e.err.Error() }
+func (e *permanentError) Unwrap() error   { return e.err }
+func (e *permanentError) Timeout() bool   { return e.err.Timeout() }
+func (e *permanentError) Temporary() bool { return false }
+
+func (hc *halfConn) setErrorLocked(err error) error {
+    if e, ok := err.(net.Error); ok {
+        hc.err = &permanentError{err: e}
+    } else {
+        hc.err = err
+    }
+
+    return hc.err
+}
+
+// prepareCipherSpec sets the encryption and MAC states
+// that a subsequent changeCipherSpec will use.
+func (hc *halfConn) prepareCipherSpec(version uint16, cipher any, mac hash.Hash) {
+    hc.version = version
+    hc.nextCipher = cipher
+    hc.nextMac = mac
+}
+
+// changeCipherSpec changes the encryption and MAC states
+// to the ones previously passed to prepareCipherSpec.
+func (hc *halfConn) changeCipherSpec() error {
+    if hc.nextCipher == nil {
+        return alertInternalError
+    }
+
+    hc.cipher = hc.nextCipher
+    hc.mac = hc.nextMac
+    hc.nextCipher = nil
+    hc.nextMac = nil
+    for i := range hc.seq {
+        hc.seq[i] = 0
+    }
+
+    return nil
+}
+
+// incSeq increments the sequence number.
+func (hc *halfConn) incSeq() {
+    for i := 7; i >= 0; i-- {
+        hc.seq[i]++
+        if hc.seq[i] != 0 {
+            return
+        }
+    }
+
+    // Not allowed to let sequence number wrap.
+    // Instead, must renegotiate before it does.
+    // Not likely enough to bother.
+    panic("TLCP: sequence number wraparound")
+}
+
+// explicitNonceLen returns the number of bytes of explicit nonce or IV included
+// in each record. Explicit nonces are present only in CBC modes and AEAD
+// modes.
+func (hc *halfConn) explicitNonceLen() int {
+    if hc.cipher == nil {
+        return 0
+    }
+
+    switch c := hc.cipher.(type) {
+        case aead:
+            return c.explicitNonceLen()
+        case cipher.Block:
+            return c.BlockSize()
+        default:
+            panic("unknown cipher type")
+    }
+}
+
+// extractPadding returns, in constant time, the length of the padding to remove
+// from the end of payload. It also returns a byte which is equal to 255 if the
+// padding was valid and 0 otherwise.
+func extractPadding(payload []byte) (toRemove int, good byte) {
+    if len(payload) < 1 {
+        return 0, 0
+    }
+
+    paddingLen := payload[len(payload)-1]
+    t := uint(len(payload)-1) - uint(paddingLen)
+    // if len(payload) >= (paddingLen - 1) then the MSB of t is zero
+    good = byte(int32(^t) >> 31)
+
+    // The maximum possible padding length plus the actual length field
+    toCheck := 256
+    // The length of the padded data is public, so we can use an if here
+    if toCheck > len(payload) {
+        toCheck = len(payload)
+    }
+
+    for i := 0; i < toCheck; i++ {
+        t := uint(paddingLen) - uint(i)
+        // if i <= paddingLen then the MSB of t is zero
+        mask := byte(int32(^t) >> 31)
+        b := payload[len(payload)-1-i]
+        good &^= mask&paddingLen ^ mask&b
+    }
+
+    // We AND together the bits of good and replicate the result across
+    // all the bits.
+    good &= good << 4
+    good &= good << 2
+    good &= good << 1
+    good = uint8(int8(good) >> 7)
+
+    // Zero the padding length on error. This ensures any unchecked bytes
+    // are included in the MAC. Otherwise, an attacker that could
+    // distinguish MAC failures from padding failures could mount an attack
+    // similar to POODLE in SSL 3.0: given a good ciphertext that uses a
+    // full block's worth of padding, replace the final block with another
+    // block. If the MAC check passed but the padding check failed, the
+    // last byte of that block decrypted to the block size.
+    //
+    // See also macAndPaddingGood logic below.
+    paddingLen &= good
+
+    toRemove = int(paddingLen) + 1
+    return
+}
+
+func roundUp(a, b int) int {
+    return a + (b-a%b)%b
+}
+
+// decrypt authenticates and decrypts the record if protection is active at
+// this stage. The returned plaintext might overlap with the input.
+func (hc *halfConn) decrypt(record []byte) ([]byte, recordType, error) {
+    var plaintext []byte
+    typ := recordType(record[0])
+    payload := record[recordHeaderLen:]
+
+    paddingGood := byte(255)
+    paddingLen := 0
+
+    explicitNonceLen := hc.explicitNonceLen()
+
+    if hc.cipher != nil {
+        switch c := hc.cipher.(type) {
+            case aead:
+                if len(payload) < explicitNonceLen {
+                    return nil, 0, alertBadRecordMAC
+                }
+
+                nonce := payload[:explicitNonceLen]
+                payload = payload[explicitNonceLen:]
+
+                n := len(payload) - c.Overhead()
+                if n < 0 {
+                    return nil, 0, alertBadRecordMAC
+                }
+
+                additionalData := append(hc.scratchBuf[:0], hc.seq[:]...)
+                additionalData = append(additionalData, record[:3]...)
+                additionalData = append(additionalData, byte(n>>8), byte(n))
+
+                var err error
+                plaintext, err = c.Open(payload[:0], nonce, payload, additionalData)
+                if err != nil {
+                    return nil, 0, alertBadRecordMAC
+                }
+            case cipher.Block:
+                blockSize := c.BlockSize()
+                minPayload := explicitNonceLen + roundUp(hc.mac.Size()+1, blockSize)
+                if len(payload)%blockSize != 0 || len(payload) < minPayload {
+                    return nil, 0, alertBadRecordMAC
+                }
+
+                iv := payload[:explicitNonceLen]
+                payload = payload[explicitNonceLen:]
+
+                cipher.NewCBCDecrypter(c, iv).CryptBlocks(payload, payload)
+
+                // In a limited attempt to protect against CBC padding oracles like
+                // Lucky13, the data past paddingLen (which is secret) is passed to
+                // the MAC function as extra data, to be fed into the HMAC after
+                // computing the digest. This makes the MAC roughly constant time as
+                // long as the digest computation is constant time and does not
+                // affect the subsequent write, modulo cache effects.
+                paddingLen, paddingGood = extractPadding(payload)
+            default:
+                panic("unknown cipher type")
+        }
+    } else {
+        plaintext = payload
+    }
+
+    if hc.mac != nil {
+        macSize := hc.mac.Size()
+        if len(payload) < macSize {
+            return nil, 0, alertBadRecordMAC
+        }
+
+        n := len(payload) - macSize - paddingLen
+        n = subtle.ConstantTimeSelect(int(uint32(n)>>31), 0, n) // if n < 0 { n = 0 }
+        record[3] = byte(n >> 8)
+        record[4] = byte(n)
+        remoteMAC := payload[n : n+macSize]
+        localMAC := hc.computeMAC(hc.seq[:], record[:recordHeaderLen], payload[:n])
+
+        // This is equivalent to checking the MACs and paddingGood
+        // separately, but in constant-time to prevent distinguishing
+        // padding failures from MAC failures. Depending on what value
+        // of paddingLen was returned on bad padding, distinguishing
+        // bad MAC from bad padding can lead to an attack.
+        //
+        // See also the logic at the end of extractPadding.
+        macAndPaddingGood := subtle.ConstantTimeCompare(localMAC, remoteMAC) & int(paddingGood)
+        if macAndPaddingGood != 1 {
+            return nil, 0, alertBadRecordMAC
+        }
+
+        plaintext = payload[:n]
+    }
+
+    hc.incSeq()
+
+    return plaintext, typ, nil
+}
+
+func (hc *halfConn) computeMAC(seq, header, data []byte) []byte {
+    hc.mac.Reset()
+    hc.mac.Write(seq)
+    hc.mac.Write(header)
+    hc.mac.Write(data)
+
+    return hc.mac.Sum(nil)
+}
+
+// sliceForAppend extends the input slice by n bytes. head is the full extended
+// slice, while tail is the appended part. If the original slice has sufficient
+// capacity no allocation is performed.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+    if total := len(in) + n; cap(in) >= total {
+        head = in[:total]
+    } else {
+        head = make([]byte, total)
+        copy(head, in)
+    }
+
+    tail = head[len(in):]
+    return
+}
+
+// encrypt encrypts payload, adding the appropriate nonce and/or MAC, and
+// appends it to record, which must already contain the record header.
+func (hc *halfConn) encrypt(record, payload []byte, rand io.Reader) ([]byte, error) {
+    if hc.cipher == nil {
+        return append(record, payload...), nil
+    }
+
+    var explicitNonce []byte
+    if explicitNonceLen := hc.explicitNonceLen(); explicitNonceLen > 0 {
+        record, explicitNonce = sliceForAppend(record, explicitNonceLen)
+        if _, isCBC := hc.cipher.(cipher.Block); !isCBC && explicitNonceLen < 16 {
+            // The AES-GCM construction in TLS has an explicit nonce so that the
+            // nonce can be random. However, the nonce is only 8 bytes which is
+            // too small for a secure, random nonce. Therefore we use the
+            // sequence number as the nonce.
+            copy(explicitNonce, hc.seq[:])
+        } else {
+            if _, err := io.ReadFull(rand, explicitNonce); err != nil {
+                return nil, err
+            }
+        }
+    }
+
+    var dst []byte
+    switch c := hc.cipher.(type) {
+        case aead:
+            nonce := explicitNonce
+
+            additionalData := append(hc.scratchBuf[:0], hc.seq[:]...)
+            additionalData = append(additionalData, record[:recordHeaderLen]...)
+
+            record = c.Seal(record, nonce, payload, additionalData)
+        case cipher.Block:
+            mac := hc.computeMAC(hc.seq[:], record[:recordHeaderLen], payload)
+
+            blockSize := c.BlockSize()
+            plaintextLen := len(payload) + len(mac)
+            paddingLen := blockSize - plaintextLen%blockSize
+            record, dst = sliceForAppend(record, plaintextLen+paddingLen)
+            copy(dst, payload)
+            copy(dst[len(payload):], mac)
+            for i := plaintextLen; i < len(dst); i++ {
+                dst[i] = byte(paddingLen - 1)
+            }
+
+            if len(dst) != len(payload)+len(mac)+paddingLen {
+                panic("tlcp: internal error: wrong CBC padding")
+            }
+
+            cipher.NewCBCEncrypter(c, explicitNonce).CryptBlocks(dst, dst)
+        default:
+            panic("unknown cipher type")
+    }
+
+    // Update length to include nonce, MAC and any block padding needed.
+    n := len(record) - recordHeaderLen
+    record[3] = byte(n >> 8)
+    record[4] = byte(n)
+    hc.incSeq()
+
+    return record, nil
+}
+
+// RecordHeaderError is returned when a TLCP record header is invalid.
+type RecordHeaderError struct {
+    // Msg contains a human readable string that describes the error.
+    Msg string
+    // RecordHeader contains the five bytes of TLCP record header that
+    // triggered the error.
+    RecordHeader [5]byte
+    // Conn provides the underlying net.Conn in the case that a client
+    // sent an initial handshake that didn't look like TLCP.
+    // It is nil if there's already been a handshake or a TLCP alert has
+    // been written to the connection.
+    Conn net.Conn
+}
+
+func (e RecordHeaderError) Error() string {
+    return "tlcp: " + e.Msg
+}
+
+func (c *Conn) newRecordHeaderError(conn net.Conn, msg string) (err RecordHeaderError) {
+    err.Msg = msg
+    err.Conn = conn
+    copy(err.RecordHeader[:], c.rawInput.Bytes())
+    return err
+}
+
+func (c *Conn) readRecord() error {
+    return c.readRecordOrCCS(false)
+}
+
+func (c *Conn) readChangeCipherSpec() error {
+    return c.readRecordOrCCS(true)
+}
+
+// readRecordOrCCS reads one or more TLCP records from the connection and
+// updates the record layer state. Some invariants:
+//   - c.in must be locked
+//   - c.input must be empty
+//
+// During the handshake one and only one of the following will happen:
+//   - c.hand grows
+//   - c.in.changeCipherSpec is called
+//   - an error is returned
+//
+// After the handshake one and only one of the following will happen:
+//   - c.hand grows
+//   - c.input is set
+//   - an error is returned
+func (c *Conn) readRecordOrCCS(expectChangeCipherSpec bool) error {
+    if c.in.err != nil {
+        return c.in.err
+    }
+
+    handshakeComplete := c.isHandshakeComplete.Load()
+
+    // This function modifies c.rawInput, which owns the c.input memory.
+    if c.input.Len() != 0 {
+        return c.in.setErrorLocked(errors.New("tlcp: internal error: attempted to read record with pending application data"))
+    }
+    c.input.Reset(nil)
+
+    // Read header, payload.
+    if err := c.readFromUntil(c.conn, recordHeaderLen); err != nil {
+        // RFC 8446, Section 6.1 suggests that EOF without an alertCloseNotify
+        // is an error, but popular web sites seem to do this, so we accept it
+        // if and only if at the record boundary.
+        if err == io.ErrUnexpectedEOF && c.rawInput.Len() == 0 {
+            err = io.EOF
+        }
+
+        if e, ok := err.(net.Error); !ok || !e.Temporary() {
+            c.in.setErrorLocked(err)
+        }
+
+        return err
+    }
+
+    hdr := c.rawInput.Bytes()[:recordHeaderLen]
+    typ := recordType(hdr[0])
+
+    vers := uint16(hdr[1])<<8 | uint16(hdr[2])
+    n := int(hdr[3])<<8 | int(hdr[4])
+    if c.haveVers && vers != c.vers {
+        c.sendAlert(alertProtocolVersion)
+        msg := fmt.Sprintf("received record with version %x when expecting version %x", vers, c.vers)
+        return c.in.setErrorLocked(c.newRecordHeaderError(nil, msg))
+    }
+
+    if !c.haveVers {
+        // First message, be extra suspicious: this might not be a TLCP
+        // client. Bail out before reading a full 'body', if possible.
+        // The current max version is 1.1 so if the version is >= 16.0,
+        // it's probably not real.
+        if (typ != recordTypeAlert && typ != recordTypeHandshake) || vers >= 0x1000 {
+            return c.in.setErrorLocked(c.newRecordHeaderError(c.conn, "first record does not look like a TLCP handshake"))
+        }
+    }
+
+    if n > maxCiphertext {
+        c.sendAlert(alertRecordOverflow)
+        msg := fmt.Sprintf("oversized record received with length %d", n)
+        return c.in.setErrorLocked(c.newRecordHeaderError(nil, msg))
+    }
+
+    if err := c.readFromUntil(c.conn, recordHeaderLen+n); err != nil {
+        if e, ok := err.(net.Error); !ok || !e.Temporary() {
+            c.in.setErrorLocked(err)
+        }
+
+        return err
+    }
+
+    // Process message.
+    record := c.rawInput.Next(recordHeaderLen + n)
+    data, typ, err := c.in.decrypt(record)
+    if err != nil {
+        return c.in.setErrorLocked(c.sendAlert(err.(alert)))
+    }
+
+    if len(data) > maxPlaintext {
+        return c.in.setErrorLocked(c.sendAlert(alertRecordOverflow))
+    }
+
+    // Application Data messages are always protected.
+    if c.in.cipher == nil && typ == recordTypeApplicationData {
+        return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+    }
+
+    if typ != recordTypeAlert && typ != recordTypeChangeCipherSpec && len(data) > 0 {
+        // This is a state-advancing message: reset the retry count.
+        c.retryCount = 0
+    }
+
+    switch typ {
+        default:
+            return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+
+        case recordTypeAlert:
+            if len(data) != 2 {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            if alert(data[1]) == alertCloseNotify {
+                return c.in.setErrorLocked(io.EOF)
+            }
+
+            switch data[0] {
+                case alertLevelWarning:
+                    // Drop the record on the floor and retry.
+                    return c.retryReadRecord(expectChangeCipherSpec)
+                case alertLevelError:
+                    return c.in.setErrorLocked(&net.OpError{Op: "remote error", Err: alert(data[1])})
+                default:
+                    return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+        case recordTypeChangeCipherSpec:
+            if len(data) != 1 || data[0] != 1 {
+                return c.in.setErrorLocked(c.sendAlert(alertDecodeError))
+            }
+
+            // Handshake messages are not allowed to fragment across the CCS.
+            if c.hand.Len() > 0 {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            if !expectChangeCipherSpec {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            if err := c.in.changeCipherSpec(); err != nil {
+                return c.in.setErrorLocked(c.sendAlert(err.(alert)))
+            }
+
+        case recordTypeApplicationData:
+            if !handshakeComplete || expectChangeCipherSpec {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            // Some OpenSSL servers send empty records in order to randomize the
+            // CBC IV. Ignore a limited number of empty records.
+            if len(data) == 0 {
+                return c.retryReadRecord(expectChangeCipherSpec)
+            }
+
+            // Note that data is owned by c.rawInput, following the Next call above,
+            // to avoid copying the plaintext. This is safe because c.rawInput is
+            // not read from or written to until c.input is drained.
+            c.input.Reset(data)
+
+        case recordTypeHandshake:
+            if len(data) == 0 || expectChangeCipherSpec {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            c.hand.Write(data)
+    }
+
+    return nil
+}
+
+// retryReadRecord recurs into readRecordOrCCS to drop a non-advancing record, like
+// a warning alert, empty application_data, or a change_cipher_spec in TLS 1.3.
+func (c *Conn) retryReadRecord(expectChangeCipherSpec bool) error {
+    c.retryCount++
+    if c.retryCount > maxUselessRecords {
+        c.sendAlert(alertUnexpectedMessage)
+        return c.in.setErrorLocked(errors.New("tlcp: too many ignored records"))
+    }
+
+    return c.readRecordOrCCS(expectChangeCipherSpec)
+}
+
+// atLeastReader reads from R, stopping with EOF once at least N bytes have been
+// read. It is different from an io.LimitedReader in that it doesn't cut short
+// the last Read call, and in that it considers an early EOF an error.
+type atLeastReader struct {
+    R io.Reader
+    N int64
+}
+
+func (r *atLeastReader) Read(p []byte) (int, error) {
+    if r.N <= 0 {
+        return 0, io.EOF
+    }
+
+    n, err := r.R.Read(p)
+    r.N -= int64(n) // won't underflow unless len(p) >= n > 9223372036854775809
+    if r.N > 0 && err == io.EOF {
+        return n, io.ErrUnexpectedEOF
+    }
+
+    if r.N <= 0 && err == nil {
+        return n, io.EOF
+    }
+
+    return n, err
+}
+
+// readFromUntil reads from r into c.rawInput until c.rawInput contains
+// at least n bytes or else returns an error.
+func (c *Conn) readFromUntil(r io.Reader, n int) error {
+    if c.rawInput.Len() >= n {
+        return nil
+    }
+
+    needs := n - c.rawInput.Len()
+    // There might be extra input waiting on the wire. Make a best effort
+    // attempt to fetch it so that it can be used in (*Conn).Read to
+    // "predict" closeNotify alerts.
+    c.rawInput.Grow(needs + bytes.MinRead)
+    _, err := c.rawInput.ReadFrom(&atLeastReader{r, int64(needs)})
+
+    return err
+}
+
+// sendAlertLocked sends a TLCP alert message.
+func (c *Conn) sendAlertLocked(err alert) error {
+    switch err {
+        case alertNoRenegotiation, alertCloseNotify:
+            c.tmp[0] = alertLevelWarning
+        default:
+            c.tmp[0] = alertLevelError
+    }
+
+    c.tmp[1] = byte(err)
+
+    _, writeErr := c.writeRecordLocked(recordTypeAlert, c.tmp[0:2])
+    if err == alertCloseNotify {
+        // closeNotify is a special case in that it isn't an error.
+        return writeErr
+    }
+
+    return c.out.setErrorLocked(&net.OpError{Op: "local error", Err: err})
+}
+
+// sendAlert sends a TLCP alert message.
+func (c *Conn) sendAlert(err alert) error {
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    return c.sendAlertLocked(err)
+}
+
+func (c *Conn) write(data []byte) (int, error) {
+    if c.buffering {
+        c.sendBuf = append(c.sendBuf, data...)
+        return len(data), nil
+    }
+
+    return c.conn.Write(data)
+}
+
+func (c *Conn) flush() (int, error) {
+    if len(c.sendBuf) == 0 {
+        return 0, nil
+    }
+
+    n, err := c.conn.Write(c.sendBuf)
+    c.sendBuf = nil
+    c.buffering = false
+
+    return n, err
+}
+
+// writeRecordLocked writes a TLCP record with the given type and payload to the
+// connection and updates the record layer state.
+func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
+    var n int
+    for len(data) > 0 {
+        m := len(data)
+        if m > maxPlaintext {
+            m = maxPlaintext
+        }
+
+        outBuf := make([]byte, recordHeaderLen, recordHeaderLen+m+256)
+        outBuf[0] = byte(typ)
+
+        vers := c.vers
+        if vers == 0 {
+            vers = VersionTLCP
+        }
+
+        outBuf[1] = byte(vers >> 8)
+        outBuf[2] = byte(vers)
+        outBuf[3] = byte(m >> 8)
+        outBuf[4] = byte(m)
+
+        var err error
+        outBuf, err = c.out.encrypt(outBuf, data[:m], c.config.rand())
+        if err != nil {
+            return n, err
+        }
+
+        if _, err := c.write(outBuf); err != nil {
+            return n, err
+        }
+
+        n += m
+        data = data[m:]
+    }
+
+    if typ == recordTypeChangeCipherSpec {
+        if err := c.out.changeCipherSpec(); err != nil {
+            return n, c.sendAlertLocked(err.(alert))
+        }
+    }
+
+    return n, nil
+}
+
+// writeHandshakeRecord writes a handshake message to the connection and updates
+// the record layer state. If transcript is non-nil the marshalled message is
+// written to it.
+func (c *Conn) writeHandshakeRecord(msg handshakeMessage, transcript *finishedHash) (int, error) {
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    data, err := msg.marshal()
+    if err != nil {
+        return 0, err
+    }
+
+    if transcript != nil {
+        transcript.Write(data)
+    }
+
+    return c.writeRecordLocked(recordTypeHandshake, data)
+}
+
+// writeChangeCipherRecord writes a ChangeCipherSpec message to the connection and
+// updates the record layer state.
+func (c *Conn) writeChangeCipherRecord() error {
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    _, err := c.writeRecordLocked(recordTypeChangeCipherSpec, []byte{1})
+    return err
+}
+
+// readHandshake reads the next handshake message from
+// the record layer. If transcript is non-nil, the message
+// is written to the passed transcriptHash.
+func (c *Conn) readHandshake(transcript *finishedHash) (any, error) {
+    for c.hand.Len() < 4 {
+        if err := c.readRecord(); err != nil {
+            return nil, err
+        }
+    }
+
+    data := c.hand.Bytes()
+    n := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+    if n > maxHandshake {
+        c.sendAlertLocked(alertInternalError)
+        return nil, c.in.setErrorLocked(fmt.Errorf("tlcp: handshake message of length %d bytes exceeds maximum of %d bytes", n, maxHandshake))
+    }
+
+    for c.hand.Len() < 4+n {
+        if err := c.readRecord(); err != nil {
+            return nil, err
+        }
+    }
+
+    data = c.hand.Next(4 + n)
+
+    var m handshakeMessage
+    switch data[0] {
+        case typeClientHello:
+            m = new(clientHelloMsg)
+        case typeServerHello:
+            m = new(serverHelloMsg)
+        case typeCertificate:
+            m = new(certificateMsg)
+        case typeCertificateRequest:
+            m = new(certificateRequestMsg)
+        case typeCertificateVerify:
+            m = new(certificateVerifyMsg)
+        case typeServerKeyExchange:
+            m = new(serverKeyExchangeMsg)
+        case typeServerHelloDone:
+            m = new(serverHelloDoneMsg)
+        case typeClientKeyExchange:
+            m = new(clientKeyExchangeMsg)
+        case typeFinished:
+            m = new(finishedMsg)
+        default:
+            return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+    }
+
+    // The handshake message unmarshalers
+    // expect to be able to keep references to data,
+    // so pass in a fresh copy that won't be overwritten.
+    data = append([]byte(nil), data...)
+
+    if !m.unmarshal(data) {
+        return nil, c.in.setErrorLocked(c.sendAlert(alertDecodeError))
+    }
+
+    if transcript != nil {
+        transcript.Write(data)
+    }
+
+    return m, nil
+}
+
+var (
+    errShutdown = errors.New("tlcp: protocol is shutdown")
+)
+
+// Write writes data to the connection.
+func (c *Conn) Write(b []byte) (int, error) {
+    // interlock with Close below
+    for {
+        x := c.activeCall.Load()
+        if x&1 != 0 {
+            return 0, net.ErrClosed
+        }
+
+        if c.activeCall.CompareAndSwap(x, x+2) {
+            break
+        }
+    }
+    defer c.activeCall.Add(-2)
+
+    if err := c.Handshake(); err != nil {
+        return 0, err
+    }
+
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    if err := c.out.err; err != nil {
+        return 0, err
+    }
+
+    if !c.isHandshakeComplete.Load() {
+        return 0, alertInternalError
+    }
+
+    if c.closeNotifySent {
+        return 0, errShutdown
+    }
+
+    n, err := c.writeRecordLocked(recordTypeApplicationData, b)
+
+    return n, c.out.setErrorLocked(err)
+}
+
+// Read reads data from the connection.
+func (c *Conn) Read(b []byte) (int, error) {
+    if err := c.Handshake(); err != nil {
+        return 0, err
+    }
+
+    if len(b) == 0 {
+        // Put this after Handshake, in case people were calling
+        // Read(nil) for the side effect of the Handshake.
+        return 0, nil
+    }
+
+    c.in.Lock()
+    defer c.in.Unlock()
+
+    for c.input.Len() == 0 {
+        if err := c.readRecord(); err != nil {
+            return 0, err
+        }
+
+        // 不支持重新协商
+        if c.hand.Len() > 0 {
+            c.sendAlert(alertNoRenegotiation)
+            c.hand.Reset()
+        }
+    }
+
+    n, _ := c.input.Read(b)
+
+    // If a close-notify alert is waiting, read it so that we can return (n,
+    // EOF) instead of (n, nil), to signal to the HTTP response reading
+    // goroutine that the connection is now closed. This eliminates a race
+    // where the HTTP response reading goroutine would otherwise not observe
+    // the EOF until its next read, by which time a client goroutine might
+    // have already tried to reuse the HTTP connection for a new request.
+    if n != 0 && c.input.Len() == 0 && c.rawInput.Len() > 0 &&
+        recordType(c.rawInput.Bytes()[0]) == recordTypeAlert {
+        if err := c.readRecord(); err != nil {
+            return n, err // will be io.EOF on closeNotify
+        }
+    }
+
+    return n, nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+    // Interlock with Conn.Write above.
+    var x int32
+    for {
+        x = c.activeCall.Load()
+        if x&1 != 0 {
+            return net.ErrClosed
+        }
+
+        if c.activeCall.CompareAndSwap(x, x|1) {
+            break
+        }
+    }
+
+    if x != 0 {
+        // io.Writer and io.Closer should not be used concurrently.
+        // If Close is called while a Write is currently in-flight,
+        // interpret that as a sign that this Close is really just
+        // being used to break the Write and/or clean up resources and
+        // avoid sending the alertCloseNotify, which may block
+        // waiting on handshakeMutex or the c.out mutex.
+        return c.conn.Close()
+    }
+
+    var alertErr error
+    if c.isHandshakeComplete.Load() {
+        if err := c.closeNotify(); err != nil {
+            alertErr = fmt.Errorf("tlcp: failed to send closeNotify alert (but connection was closed anyway): %w", err)
+        }
+    }
+
+    if err := c.conn.Close(); err != nil {
+        return err
+    }
+
+    return alertErr
+}
+
+var errEarlyCloseWrite = errors.New("tlcp: CloseWrite called before handshake complete")
+
+// CloseWrite shuts down the writing side of the connection. It should only be
+// called once the handshake has completed and does not call CloseWrite on the
+// underlying connection. Most callers should just use Close.
+func (c *Conn) CloseWrite() error {
+    if !c.isHandshakeComplete.Load() {
+        return errEarlyCloseWrite
+    }
+
+    return c.closeNotify()
+}
+
+func (c *Conn) closeNotify() error {
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    if !c.closeNotifySent {
+        // Set a Write Deadline to prevent possibly blocking forever.
+        c.SetWriteDeadline(time.Now().Add(time.Second * 5))
+        c.closeNotifyErr = c.sendAlertLocked(alertCloseNotify)
+        c.closeNotifySent = true
+        // Any subsequent writes will fail.
+        c.SetWriteDeadline(time.Now())
+    }
+
+    return c.closeNotifyErr
+}
+
+// Handshake runs the client or server handshake
+// protocol if it has not yet been run.
+//
+// Most uses of this package need not call Handshake explicitly: the
+// first Read or Write will call it automatically.
+func (c *Conn) Handshake() error {
+    c.handshakeMutex.Lock()
+    defer c.handshakeMutex.Unlock()
+
+    if err := c.handshakeErr; err != nil {
+        return err
+    }
+
+    if c.isHandshakeComplete.Load() {
+        return nil
+    }
+
+    c.in.Lock()
+    defer c.in.Unlock()
+
+    c.handshakeErr = c.handshakeFn()
+    if c.handshakeErr == nil {
+        c.isHandshakeComplete.Store(true)
+    } else {
+        // If an error occurred during the handshake try to flush the
+        // alert that might be left in the buffer.
+        c.flush()
+    }
+
+    return c.handshakeErr
+}
+
+// ConnectionState returns basic TLCP details about the connection.
+func (c *Conn) ConnectionState() ConnectionState {
+    c.handshakeMutex.Lock()
+    defer c.handshakeMutex.Unlock()
+
+    var state ConnectionState
+    state.HandshakeComplete = c.isHandshakeComplete.Load()
+    state.Version = c.vers
+    state.DidResume = c.didResume
+    state.CipherSuite = c.cipherSuite
+    state.ServerName = c.serverName
+    state.PeerCertificates = c.peerCertificates
+    state.VerifiedChains = c.verifiedChains
+    state.SessionID = c.sessionID
+
+    return state
+}
+
+// VerifyHostname checks that the peer certificate chain is valid for
+// connecting to host. If so, it returns nil; if not, it returns an error
+// describing the problem.
+func (c *Conn) VerifyHostname(host string) error {
+    c.handshakeMutex.Lock()
+    defer c.handshakeMutex.Unlock()
+
+    if !c.isClient {
+        return errors.New("tlcp: VerifyHostname called on TLCP server connection")
+    }
+
+    if !c.isHandshakeComplete.Load() {
+        return errors.New("tlcp: handshake has not yet been performed")
+    }
+
+    if len(c.verifiedChains) == 0 {
+        return errors.New("tlcp: handshake did not verify certificate chain")
+    }
+
+    return c.peerCertificates[0].VerifyHostname(host)
+}
+
+// 设置记录层密钥
+func (c *Conn) prepareKeys(suite *cipherSuite, masterSecret, clientRandom, serverRandom []byte) {
+    clientMAC, serverMAC, clientKey, serverKey, clientIV, serverIV :=
+        keysFromMasterSecret(masterSecret, clientRandom, serverRandom, suite.macLen, suite.keyLen, suite.ivLen)
+
+    var clientCipher, serverCipher any
+    var clientHash, serverHash hash.Hash
+    if suite.aead == nil {
+        clientCipher = suite.cipher(clientKey)
+        clientHash = suite.mac(clientMAC)
+        serverCipher = suite.cipher(serverKey)
+        serverHash = suite.mac(serverMAC)
+    } else {
+        clientCipher = suite.aead(clientKey, clientIV)
+        serverCipher = suite.aead(serverKey, serverIV)
+    }
+
+    if c.isClient {
+        c.in.prepareCipherSpec(c.vers, serverCipher, serverHash)
+        c.out.prepareCipherSpec(c.vers, clientCipher, clientHash)
+    } else {
+        c.in.prepareCipherSpec(c.vers, clientCipher, clientHash)
+        c.out.prepareCipherSpec(c.vers, serverCipher, serverHash)
+    }
+}
+
+// 比较 finished 数据
+func verifyFinished(expected, got []byte) bool {
+    return hmac.Equal(expected, got)
+}
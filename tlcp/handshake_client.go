@@ -0,0 +1,437 @@
+package tlcp
+
+import (
+    "io"
+    "net"
+    "bytes"
+    "errors"
+    "fmt"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+type clientHandshakeState struct {
+    c            *Conn
+    serverHello  *serverHelloMsg
+    hello        *clientHelloMsg
+    suite        *cipherSuite
+    finishedHash finishedHash
+    masterSecret []byte
+    session      *SessionState
+}
+
+func (c *Conn) clientHandshake() error {
+    if c.config == nil {
+        c.config = &Config{}
+    }
+
+    hello, err := c.makeClientHello()
+    if err != nil {
+        return err
+    }
+
+    cacheKey := clientSessionCacheKey(c.conn.RemoteAddr(), c.config)
+
+    session := c.loadSession(cacheKey, hello)
+
+    hs := &clientHandshakeState{
+        c:            c,
+        hello:        hello,
+        session:      session,
+        finishedHash: newFinishedHash(),
+    }
+
+    if _, err := c.writeHandshakeRecord(hello, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    msg, err := c.readHandshake(&hs.finishedHash)
+    if err != nil {
+        return err
+    }
+
+    serverHello, ok := msg.(*serverHelloMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(serverHello, msg)
+    }
+
+    hs.serverHello = serverHello
+
+    if err := hs.handshake(); err != nil {
+        return err
+    }
+
+    // 保存会话
+    if c.config.SessionCache != nil && len(serverHello.sessionId) > 0 && !c.didResume {
+        c.config.SessionCache.Put(cacheKey, &SessionState{
+            sessionID:        serverHello.sessionId,
+            cipherSuite:      hs.suite.id,
+            masterSecret:     hs.masterSecret,
+            peerCertificates: c.peerCertificates,
+            verifiedChains:   c.verifiedChains,
+            createdAt:        c.config.time(),
+        })
+    }
+
+    return nil
+}
+
+func (c *Conn) makeClientHello() (*clientHelloMsg, error) {
+    config := c.config
+
+    hello := &clientHelloMsg{
+        vers:               VersionTLCP,
+        random:             make([]byte, 32),
+        cipherSuites:       config.cipherSuites(),
+        compressionMethods: []uint8{compressionNone},
+    }
+
+    // gmt_unix_time + random_bytes
+    t := uint32(config.time().Unix())
+    hello.random[0] = byte(t >> 24)
+    hello.random[1] = byte(t >> 16)
+    hello.random[2] = byte(t >> 8)
+    hello.random[3] = byte(t)
+
+    if _, err := io.ReadFull(config.rand(), hello.random[4:]); err != nil {
+        return nil, errors.New("tlcp: short read from Rand: " + err.Error())
+    }
+
+    return hello, nil
+}
+
+// 加载可恢复的会话
+func (c *Conn) loadSession(cacheKey string, hello *clientHelloMsg) *SessionState {
+    config := c.config
+    if config.SessionCache == nil {
+        return nil
+    }
+
+    session, ok := config.SessionCache.Get(cacheKey)
+    if !ok || session == nil {
+        return nil
+    }
+
+    if config.time().After(session.createdAt.Add(config.sessionTimeout())) {
+        config.SessionCache.Put(cacheKey, nil)
+        return nil
+    }
+
+    if mutualCipherSuite(hello.cipherSuites, session.cipherSuite) == nil {
+        return nil
+    }
+
+    hello.sessionId = session.sessionID
+
+    return session
+}
+
+func (hs *clientHandshakeState) handshake() error {
+    c := hs.c
+
+    if hs.serverHello.vers != VersionTLCP {
+        c.sendAlert(alertProtocolVersion)
+        return fmt.Errorf("tlcp: server selected unsupported protocol version %x", hs.serverHello.vers)
+    }
+
+    c.vers = hs.serverHello.vers
+    c.haveVers = true
+
+    if hs.serverHello.compressionMethod != compressionNone {
+        c.sendAlert(alertUnexpectedMessage)
+        return errors.New("tlcp: server selected unsupported compression format")
+    }
+
+    hs.suite = mutualCipherSuite(hs.hello.cipherSuites, hs.serverHello.cipherSuite)
+    if hs.suite == nil {
+        c.sendAlert(alertHandshakeFailure)
+        return errors.New("tlcp: server chose an unconfigured cipher suite")
+    }
+
+    c.cipherSuite = hs.suite.id
+    c.sessionID = hs.serverHello.sessionId
+
+    if hs.serverResumedSession() {
+        c.didResume = true
+        hs.masterSecret = hs.session.masterSecret
+        c.peerCertificates = hs.session.peerCertificates
+        c.verifiedChains = hs.session.verifiedChains
+
+        c.prepareKeys(hs.suite, hs.masterSecret, hs.hello.random, hs.serverHello.random)
+
+        if err := hs.readFinished(); err != nil {
+            return err
+        }
+
+        c.buffering = true
+        if err := hs.sendFinished(); err != nil {
+            return err
+        }
+
+        if _, err := c.flush(); err != nil {
+            return err
+        }
+
+        return nil
+    }
+
+    if err := hs.doFullHandshake(); err != nil {
+        return err
+    }
+
+    c.prepareKeys(hs.suite, hs.masterSecret, hs.hello.random, hs.serverHello.random)
+
+    if err := hs.sendFinished(); err != nil {
+        return err
+    }
+
+    if _, err := c.flush(); err != nil {
+        return err
+    }
+
+    return hs.readFinished()
+}
+
+func (hs *clientHandshakeState) serverResumedSession() bool {
+    // If the server responded with the same sessionId then it means the
+    // sessionTicket is being used to resume a TLCP session.
+    return hs.session != nil && hs.hello.sessionId != nil &&
+        bytes.Equal(hs.serverHello.sessionId, hs.hello.sessionId) &&
+        hs.serverHello.cipherSuite == hs.session.cipherSuite
+}
+
+func (hs *clientHandshakeState) doFullHandshake() error {
+    c := hs.c
+
+    msg, err := c.readHandshake(&hs.finishedHash)
+    if err != nil {
+        return err
+    }
+
+    certMsg, ok := msg.(*certificateMsg)
+    if !ok || len(certMsg.certificates) < 2 {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(certMsg, msg)
+    }
+
+    if err := c.verifyPeerCertificates(certMsg.certificates, x509.ExtKeyUsageServerAuth, !c.config.InsecureSkipVerify); err != nil {
+        return err
+    }
+
+    msg, err = c.readHandshake(&hs.finishedHash)
+    if err != nil {
+        return err
+    }
+
+    skx, ok := msg.(*serverKeyExchangeMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(skx, msg)
+    }
+
+    keyAgreement := hs.suite.ka()
+
+    err = keyAgreement.processServerKeyExchange(c.config, hs.hello, hs.serverHello, c.peerCertificates[0], c.peerCertificates[1], skx)
+    if err != nil {
+        c.sendAlert(alertHandshakeFailure)
+        return err
+    }
+
+    msg, err = c.readHandshake(&hs.finishedHash)
+    if err != nil {
+        return err
+    }
+
+    var certRequested bool
+    if _, ok := msg.(*certificateRequestMsg); ok {
+        certRequested = true
+
+        msg, err = c.readHandshake(&hs.finishedHash)
+        if err != nil {
+            return err
+        }
+    }
+
+    shd, ok := msg.(*serverHelloDoneMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(shd, msg)
+    }
+
+    c.buffering = true
+
+    // If the server requested a certificate then we have to send a
+    // Certificate message, even if it's empty because we don't have a
+    // certificate to send.
+    var signCert, encCert *Certificate
+    if certRequested {
+        certMsg := new(certificateMsg)
+
+        if len(c.config.Certificates) >= 2 {
+            signCert, encCert, _ = c.config.certificatePair()
+            certMsg.certificates = certificateChain(signCert, encCert)
+        }
+
+        if _, err := c.writeHandshakeRecord(certMsg, &hs.finishedHash); err != nil {
+            return err
+        }
+    }
+
+    preMasterSecret, ckx, err := keyAgreement.generateClientKeyExchange(c.config, hs.hello, c.peerCertificates[1], encCert)
+    if err != nil {
+        c.sendAlert(alertInternalError)
+        return err
+    }
+
+    if _, err := c.writeHandshakeRecord(ckx, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    if signCert != nil {
+        sig, err := signHandshake(c.config.rand(), signCert.PrivateKey, hs.finishedHash.Sum())
+        if err != nil {
+            c.sendAlert(alertInternalError)
+            return err
+        }
+
+        certVerify := &certificateVerifyMsg{
+            signature: sig,
+        }
+
+        if _, err := c.writeHandshakeRecord(certVerify, &hs.finishedHash); err != nil {
+            return err
+        }
+    }
+
+    hs.masterSecret = masterFromPreMasterSecret(preMasterSecret, hs.hello.random, hs.serverHello.random)
+
+    return nil
+}
+
+func (hs *clientHandshakeState) readFinished() error {
+    c := hs.c
+
+    if err := c.readChangeCipherSpec(); err != nil {
+        return err
+    }
+
+    // finishedMsg is included in the transcript, but not until after we
+    // check the client version, since the state before this message was
+    // sent is used during verification.
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    serverFinished, ok := msg.(*finishedMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(serverFinished, msg)
+    }
+
+    verify := hs.finishedHash.serverSum(hs.masterSecret)
+    if !verifyFinished(verify, serverFinished.verifyData) {
+        c.sendAlert(alertHandshakeFailure)
+        return errors.New("tlcp: server's Finished message was incorrect")
+    }
+
+    hs.finishedHash.Write(serverFinished.raw)
+
+    return nil
+}
+
+func (hs *clientHandshakeState) sendFinished() error {
+    c := hs.c
+
+    if err := c.writeChangeCipherRecord(); err != nil {
+        return err
+    }
+
+    finished := new(finishedMsg)
+    finished.verifyData = hs.finishedHash.clientSum(hs.masterSecret)
+
+    if _, err := c.writeHandshakeRecord(finished, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+// verifyPeerCertificates 解析并验证对端证书, 签名证书在前, 加密证书在后
+func (c *Conn) verifyPeerCertificates(certificates [][]byte, usage x509.ExtKeyUsage, verify bool) error {
+    certs := make([]*x509.Certificate, len(certificates))
+    for i, asn1Data := range certificates {
+        cert, err := x509.ParseCertificate(asn1Data)
+        if err != nil {
+            c.sendAlert(alertBadCertificate)
+            return errors.New("tlcp: failed to parse certificate: " + err.Error())
+        }
+
+        certs[i] = cert
+    }
+
+    config := c.config
+    if verify {
+        roots := config.RootCAs
+        dnsName := config.ServerName
+        if !c.isClient {
+            roots = config.ClientCAs
+            dnsName = ""
+        }
+
+        intermediates := x509.NewCertPool()
+        for _, cert := range certs[2:] {
+            intermediates.AddCert(cert)
+        }
+
+        for i, cert := range certs[:2] {
+            opts := x509.VerifyOptions{
+                Roots:         roots,
+                CurrentTime:   config.time(),
+                Intermediates: intermediates,
+                KeyUsages:     []x509.ExtKeyUsage{usage},
+            }
+
+            // 主机名只验证签名证书
+            if i == 0 {
+                opts.DNSName = dnsName
+            }
+
+            chains, err := cert.Verify(opts)
+            if err != nil {
+                c.sendAlert(alertBadCertificate)
+                return err
+            }
+
+            if i == 0 {
+                c.verifiedChains = chains
+            }
+        }
+    }
+
+    c.peerCertificates = certs
+
+    return nil
+}
+
+// certificateChain 证书消息, 签名证书, 加密证书, 签名证书的证书链
+func certificateChain(signCert, encCert *Certificate) [][]byte {
+    certs := [][]byte{signCert.Certificate[0], encCert.Certificate[0]}
+
+    return append(certs, signCert.Certificate[1:]...)
+}
+
+// clientSessionCacheKey returns a key used to cache sessions for
+// the given server.
+func clientSessionCacheKey(serverAddr net.Addr, config *Config) string {
+    if len(config.ServerName) > 0 {
+        return config.ServerName
+    }
+
+    return serverAddr.String()
+}
+
+func unexpectedMessageError(wanted, got any) error {
+    return fmt.Errorf("tlcp: received unexpected handshake message of type %T when waiting for %T", got, wanted)
+}
@@ -0,0 +1,426 @@
+package tlcp
+
+import (
+    "golang.org/x/crypto/cryptobyte"
+)
+
+// handshakeMessage 握手消息
+type handshakeMessage interface {
+    marshal() ([]byte, error)
+    unmarshal([]byte) bool
+}
+
+// 添加握手消息头
+func marshalHandshake(typ uint8, f cryptobyte.BuilderContinuation) ([]byte, error) {
+    var b cryptobyte.Builder
+    b.AddUint8(typ)
+    b.AddUint24LengthPrefixed(f)
+
+    return b.Bytes()
+}
+
+// 读取握手消息体
+func readHandshakeBody(data []byte) cryptobyte.String {
+    s := cryptobyte.String(data)
+
+    var body cryptobyte.String
+    if !s.Skip(1) || !s.ReadUint24LengthPrefixed(&body) || !s.Empty() {
+        return nil
+    }
+
+    return body
+}
+
+// ==========
+
+type clientHelloMsg struct {
+    raw                []byte
+    vers               uint16
+    random             []byte
+    sessionId          []byte
+    cipherSuites       []uint16
+    compressionMethods []uint8
+}
+
+func (m *clientHelloMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeClientHello, func(b *cryptobyte.Builder) {
+        b.AddUint16(m.vers)
+        b.AddBytes(m.random)
+        b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.sessionId)
+        })
+        b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+            for _, suite := range m.cipherSuites {
+                b.AddUint16(suite)
+            }
+        })
+        b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.compressionMethods)
+        })
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *clientHelloMsg) unmarshal(data []byte) bool {
+    *m = clientHelloMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    if !s.ReadUint16(&m.vers) || !s.ReadBytes(&m.random, 32) ||
+        !readUint8LengthPrefixed(&s, &m.sessionId) {
+        return false
+    }
+
+    if len(m.sessionId) > 32 {
+        return false
+    }
+
+    var cipherSuites cryptobyte.String
+    if !s.ReadUint16LengthPrefixed(&cipherSuites) {
+        return false
+    }
+
+    for !cipherSuites.Empty() {
+        var suite uint16
+        if !cipherSuites.ReadUint16(&suite) {
+            return false
+        }
+
+        m.cipherSuites = append(m.cipherSuites, suite)
+    }
+
+    if !readUint8LengthPrefixed(&s, &m.compressionMethods) {
+        return false
+    }
+
+    // 忽略扩展
+    return true
+}
+
+// ==========
+
+type serverHelloMsg struct {
+    raw               []byte
+    vers              uint16
+    random            []byte
+    sessionId         []byte
+    cipherSuite       uint16
+    compressionMethod uint8
+}
+
+func (m *serverHelloMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeServerHello, func(b *cryptobyte.Builder) {
+        b.AddUint16(m.vers)
+        b.AddBytes(m.random)
+        b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.sessionId)
+        })
+        b.AddUint16(m.cipherSuite)
+        b.AddUint8(m.compressionMethod)
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *serverHelloMsg) unmarshal(data []byte) bool {
+    *m = serverHelloMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    if !s.ReadUint16(&m.vers) || !s.ReadBytes(&m.random, 32) ||
+        !readUint8LengthPrefixed(&s, &m.sessionId) ||
+        !s.ReadUint16(&m.cipherSuite) ||
+        !s.ReadUint8(&m.compressionMethod) {
+        return false
+    }
+
+    return len(m.sessionId) <= 32
+}
+
+// ==========
+
+type certificateMsg struct {
+    raw          []byte
+    certificates [][]byte
+}
+
+func (m *certificateMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeCertificate, func(b *cryptobyte.Builder) {
+        b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+            for _, cert := range m.certificates {
+                b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+                    b.AddBytes(cert)
+                })
+            }
+        })
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *certificateMsg) unmarshal(data []byte) bool {
+    *m = certificateMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    var certs cryptobyte.String
+    if !s.ReadUint24LengthPrefixed(&certs) || !s.Empty() {
+        return false
+    }
+
+    for !certs.Empty() {
+        var cert []byte
+        if !readUint24LengthPrefixed(&certs, &cert) {
+            return false
+        }
+
+        m.certificates = append(m.certificates, cert)
+    }
+
+    return true
+}
+
+// ==========
+
+type serverKeyExchangeMsg struct {
+    raw []byte
+    key []byte
+}
+
+func (m *serverKeyExchangeMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeServerKeyExchange, func(b *cryptobyte.Builder) {
+        b.AddBytes(m.key)
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *serverKeyExchangeMsg) unmarshal(data []byte) bool {
+    m.raw = data
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    m.key = s
+
+    return true
+}
+
+// ==========
+
+type certificateRequestMsg struct {
+    raw                    []byte
+    certificateTypes       []byte
+    certificateAuthorities [][]byte
+}
+
+func (m *certificateRequestMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeCertificateRequest, func(b *cryptobyte.Builder) {
+        b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.certificateTypes)
+        })
+        b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+            for _, ca := range m.certificateAuthorities {
+                b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                    b.AddBytes(ca)
+                })
+            }
+        })
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *certificateRequestMsg) unmarshal(data []byte) bool {
+    *m = certificateRequestMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    var auths cryptobyte.String
+    if !readUint8LengthPrefixed(&s, &m.certificateTypes) ||
+        !s.ReadUint16LengthPrefixed(&auths) || !s.Empty() {
+        return false
+    }
+
+    for !auths.Empty() {
+        var ca []byte
+        if !readUint16LengthPrefixed(&auths, &ca) || len(ca) == 0 {
+            return false
+        }
+
+        m.certificateAuthorities = append(m.certificateAuthorities, ca)
+    }
+
+    return true
+}
+
+// ==========
+
+type serverHelloDoneMsg struct{}
+
+func (m *serverHelloDoneMsg) marshal() ([]byte, error) {
+    return []byte{typeServerHelloDone, 0, 0, 0}, nil
+}
+
+func (m *serverHelloDoneMsg) unmarshal(data []byte) bool {
+    return len(data) == 4
+}
+
+// ==========
+
+type clientKeyExchangeMsg struct {
+    raw        []byte
+    ciphertext []byte
+}
+
+func (m *clientKeyExchangeMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeClientKeyExchange, func(b *cryptobyte.Builder) {
+        b.AddBytes(m.ciphertext)
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *clientKeyExchangeMsg) unmarshal(data []byte) bool {
+    m.raw = data
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    m.ciphertext = s
+
+    return true
+}
+
+// ==========
+
+type certificateVerifyMsg struct {
+    raw       []byte
+    signature []byte
+}
+
+func (m *certificateVerifyMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeCertificateVerify, func(b *cryptobyte.Builder) {
+        b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.signature)
+        })
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *certificateVerifyMsg) unmarshal(data []byte) bool {
+    m.raw = data
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    return readUint16LengthPrefixed(&s, &m.signature) && s.Empty()
+}
+
+// ==========
+
+type finishedMsg struct {
+    raw        []byte
+    verifyData []byte
+}
+
+func (m *finishedMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeFinished, func(b *cryptobyte.Builder) {
+        b.AddBytes(m.verifyData)
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *finishedMsg) unmarshal(data []byte) bool {
+    m.raw = data
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    m.verifyData = s
+
+    return len(m.verifyData) == finishedVerifyLength
+}
+
+// ==========
+
+// readUint8LengthPrefixed acts like s.ReadUint8LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func readUint8LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+    return s.ReadUint8LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// readUint16LengthPrefixed acts like s.ReadUint16LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func readUint16LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+    return s.ReadUint16LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// readUint24LengthPrefixed acts like s.ReadUint24LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func readUint24LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+    return s.ReadUint24LengthPrefixed((*cryptobyte.String)(out))
+}
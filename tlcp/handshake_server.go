@@ -0,0 +1,474 @@
+package tlcp
+
+import (
+    "io"
+    "errors"
+    "fmt"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+// serverHandshakeState contains details of a server handshake in progress.
+// It's discarded once the handshake has completed.
+type serverHandshakeState struct {
+    c            *Conn
+    clientHello  *clientHelloMsg
+    hello        *serverHelloMsg
+    suite        *cipherSuite
+    finishedHash finishedHash
+    masterSecret []byte
+    session      *SessionState
+    signCert     *Certificate
+    encCert      *Certificate
+}
+
+// serverHandshake performs a TLCP handshake as a server.
+func (c *Conn) serverHandshake() error {
+    if c.config == nil {
+        return errors.New("tlcp: server config is required")
+    }
+
+    hs := &serverHandshakeState{
+        c:            c,
+        finishedHash: newFinishedHash(),
+    }
+
+    msg, err := c.readHandshake(&hs.finishedHash)
+    if err != nil {
+        return err
+    }
+
+    clientHello, ok := msg.(*clientHelloMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(clientHello, msg)
+    }
+
+    hs.clientHello = clientHello
+
+    if err := hs.processClientHello(); err != nil {
+        return err
+    }
+
+    c.buffering = true
+
+    if hs.checkForResumption() {
+        // The client has included a session ticket and so we do an abbreviated handshake.
+        c.didResume = true
+        if err := hs.doResumeHandshake(); err != nil {
+            return err
+        }
+
+        if err := hs.sendFinished(); err != nil {
+            return err
+        }
+
+        if _, err := c.flush(); err != nil {
+            return err
+        }
+
+        return hs.readFinished()
+    }
+
+    // The client didn't include a session ticket, or it wasn't
+    // valid so we do a full handshake.
+    if err := hs.doFullHandshake(); err != nil {
+        return err
+    }
+
+    if err := hs.readFinished(); err != nil {
+        return err
+    }
+
+    c.buffering = true
+    if err := hs.sendFinished(); err != nil {
+        return err
+    }
+
+    if _, err := c.flush(); err != nil {
+        return err
+    }
+
+    // 保存会话
+    if c.config.SessionCache != nil {
+        c.config.SessionCache.Put(string(hs.hello.sessionId), &SessionState{
+            sessionID:        hs.hello.sessionId,
+            cipherSuite:      hs.suite.id,
+            masterSecret:     hs.masterSecret,
+            peerCertificates: c.peerCertificates,
+            verifiedChains:   c.verifiedChains,
+            createdAt:        c.config.time(),
+        })
+    }
+
+    return nil
+}
+
+func (hs *serverHandshakeState) processClientHello() error {
+    c := hs.c
+    config := c.config
+
+    if hs.clientHello.vers != VersionTLCP {
+        c.vers = VersionTLCP
+        c.sendAlert(alertProtocolVersion)
+        return fmt.Errorf("tlcp: client offered unsupported version %x", hs.clientHello.vers)
+    }
+
+    c.vers = VersionTLCP
+    c.haveVers = true
+
+    var foundCompression bool
+    for _, compression := range hs.clientHello.compressionMethods {
+        if compression == compressionNone {
+            foundCompression = true
+            break
+        }
+    }
+
+    if !foundCompression {
+        c.sendAlert(alertHandshakeFailure)
+        return errors.New("tlcp: client does not support uncompressed connections")
+    }
+
+    var err error
+    hs.signCert, hs.encCert, err = config.certificatePair()
+    if err != nil {
+        c.sendAlert(alertInternalError)
+        return err
+    }
+
+    // 按客户端的顺序选择密码套件
+    for _, id := range hs.clientHello.cipherSuites {
+        if suite := mutualCipherSuite(config.cipherSuites(), id); suite != nil {
+            hs.suite = suite
+            break
+        }
+    }
+
+    if hs.suite == nil {
+        c.sendAlert(alertHandshakeFailure)
+        return errors.New("tlcp: no cipher suite supported by both client and server")
+    }
+
+    hs.hello = &serverHelloMsg{
+        vers:              VersionTLCP,
+        random:            make([]byte, 32),
+        compressionMethod: compressionNone,
+    }
+
+    t := uint32(config.time().Unix())
+    hs.hello.random[0] = byte(t >> 24)
+    hs.hello.random[1] = byte(t >> 16)
+    hs.hello.random[2] = byte(t >> 8)
+    hs.hello.random[3] = byte(t)
+
+    if _, err := io.ReadFull(config.rand(), hs.hello.random[4:]); err != nil {
+        c.sendAlert(alertInternalError)
+        return err
+    }
+
+    return nil
+}
+
+// checkForResumption reports whether we should perform resumption on this connection.
+func (hs *serverHandshakeState) checkForResumption() bool {
+    c := hs.c
+    config := c.config
+
+    if config.SessionCache == nil || len(hs.clientHello.sessionId) == 0 {
+        return false
+    }
+
+    session, ok := config.SessionCache.Get(string(hs.clientHello.sessionId))
+    if !ok || session == nil {
+        return false
+    }
+
+    if config.time().After(session.createdAt.Add(config.sessionTimeout())) {
+        config.SessionCache.Put(string(hs.clientHello.sessionId), nil)
+        return false
+    }
+
+    // Check that the client is still offering the ciphersuite in the session.
+    var offered bool
+    for _, id := range hs.clientHello.cipherSuites {
+        if id == session.cipherSuite {
+            offered = true
+            break
+        }
+    }
+
+    if !offered {
+        return false
+    }
+
+    suite := mutualCipherSuite(config.cipherSuites(), session.cipherSuite)
+    if suite == nil {
+        return false
+    }
+
+    sessionHasClientCerts := len(session.peerCertificates) != 0
+    needClientCerts := requiresClientCert(config.ClientAuth)
+    if needClientCerts && !sessionHasClientCerts {
+        return false
+    }
+
+    hs.suite = suite
+    hs.session = session
+
+    return true
+}
+
+func (hs *serverHandshakeState) doResumeHandshake() error {
+    c := hs.c
+
+    hs.hello.cipherSuite = hs.suite.id
+    c.cipherSuite = hs.suite.id
+
+    // We echo the client's session ID in the ServerHello to let it know
+    // that we're doing a resumption.
+    hs.hello.sessionId = hs.clientHello.sessionId
+    c.sessionID = hs.hello.sessionId
+
+    if _, err := c.writeHandshakeRecord(hs.hello, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    c.peerCertificates = hs.session.peerCertificates
+    c.verifiedChains = hs.session.verifiedChains
+    hs.masterSecret = hs.session.masterSecret
+
+    c.prepareKeys(hs.suite, hs.masterSecret, hs.clientHello.random, hs.hello.random)
+
+    return nil
+}
+
+func (hs *serverHandshakeState) doFullHandshake() error {
+    c := hs.c
+    config := c.config
+
+    hs.hello.cipherSuite = hs.suite.id
+    c.cipherSuite = hs.suite.id
+
+    hs.hello.sessionId = make([]byte, 32)
+    if _, err := io.ReadFull(config.rand(), hs.hello.sessionId); err != nil {
+        c.sendAlert(alertInternalError)
+        return err
+    }
+
+    c.sessionID = hs.hello.sessionId
+
+    if _, err := c.writeHandshakeRecord(hs.hello, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    certMsg := new(certificateMsg)
+    certMsg.certificates = certificateChain(hs.signCert, hs.encCert)
+    if _, err := c.writeHandshakeRecord(certMsg, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    keyAgreement := hs.suite.ka()
+
+    skx, err := keyAgreement.generateServerKeyExchange(config, hs.signCert, hs.encCert, hs.clientHello, hs.hello)
+    if err != nil {
+        c.sendAlert(alertHandshakeFailure)
+        return err
+    }
+
+    if _, err := c.writeHandshakeRecord(skx, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    // ECDHE 需要客户端的加密证书
+    needClientCert := config.ClientAuth >= RequestClientCert ||
+        hs.suite.flags&suiteECDHE != 0
+
+    if needClientCert {
+        // Request a client certificate
+        certReq := new(certificateRequestMsg)
+        certReq.certificateTypes = []byte{
+            byte(certTypeRSASign),
+            byte(certTypeECDSASign),
+        }
+
+        // An empty list of certificateAuthorities signals to
+        // the client that it may send any certificate in response
+        // to our request. When we know the CAs we trust, then
+        // we can send them down, so that the client can choose
+        // an appropriate certificate to give to us.
+        if config.ClientCAs != nil {
+            certReq.certificateAuthorities = config.ClientCAs.Subjects()
+        }
+
+        if _, err := c.writeHandshakeRecord(certReq, &hs.finishedHash); err != nil {
+            return err
+        }
+    }
+
+    helloDone := new(serverHelloDoneMsg)
+    if _, err := c.writeHandshakeRecord(helloDone, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    if _, err := c.flush(); err != nil {
+        return err
+    }
+
+    msg, err := c.readHandshake(&hs.finishedHash)
+    if err != nil {
+        return err
+    }
+
+    // If we requested a client certificate, then the client must send a
+    // certificate message, even if it's empty.
+    if needClientCert {
+        certMsg, ok := msg.(*certificateMsg)
+        if !ok {
+            c.sendAlert(alertUnexpectedMessage)
+            return unexpectedMessageError(certMsg, msg)
+        }
+
+        if err := hs.processCertsFromClient(certMsg.certificates); err != nil {
+            return err
+        }
+
+        if len(certMsg.certificates) == 0 && hs.suite.flags&suiteECDHE != 0 {
+            c.sendAlert(alertHandshakeFailure)
+            return errors.New("tlcp: client didn't provide a certificate for ECDHE cipher suite")
+        }
+
+        msg, err = c.readHandshake(&hs.finishedHash)
+        if err != nil {
+            return err
+        }
+    }
+
+    // Get client key exchange
+    ckx, ok := msg.(*clientKeyExchangeMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(ckx, msg)
+    }
+
+    var clientEncCert *x509.Certificate
+    if len(c.peerCertificates) >= 2 {
+        clientEncCert = c.peerCertificates[1]
+    }
+
+    preMasterSecret, err := keyAgreement.processClientKeyExchange(config, hs.encCert, ckx, clientEncCert)
+    if err != nil {
+        c.sendAlert(alertHandshakeFailure)
+        return err
+    }
+
+    hs.masterSecret = masterFromPreMasterSecret(preMasterSecret, hs.clientHello.random, hs.hello.random)
+
+    // If we received a client cert in response to our certificate request message,
+    // the client will send us a certificateVerifyMsg immediately after the
+    // clientKeyExchangeMsg. This message is a digest of all preceding
+    // handshake-layer messages that is signed using the private key corresponding
+    // to the client's certificate. This allows us to verify that the client is in
+    // possession of the private key of the certificate.
+    if len(c.peerCertificates) > 0 {
+        // certificateVerifyMsg is included in the transcript, but not until
+        // after we verify the handshake signature, since the state before
+        // this message was sent is used.
+        msg, err = c.readHandshake(nil)
+        if err != nil {
+            return err
+        }
+
+        certVerify, ok := msg.(*certificateVerifyMsg)
+        if !ok {
+            c.sendAlert(alertUnexpectedMessage)
+            return unexpectedMessageError(certVerify, msg)
+        }
+
+        err := verifyHandshake(c.peerCertificates[0].PublicKey, hs.finishedHash.Sum(), certVerify.signature)
+        if err != nil {
+            c.sendAlert(alertDecryptError)
+            return errors.New("tlcp: invalid signature by the client certificate: " + err.Error())
+        }
+
+        hs.finishedHash.Write(certVerify.raw)
+    }
+
+    c.prepareKeys(hs.suite, hs.masterSecret, hs.clientHello.random, hs.hello.random)
+
+    return nil
+}
+
+func (hs *serverHandshakeState) readFinished() error {
+    c := hs.c
+
+    if err := c.readChangeCipherSpec(); err != nil {
+        return err
+    }
+
+    // finishedMsg is included in the transcript, but not until after we
+    // check the client version, since the state before this message was
+    // sent is used during verification.
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    clientFinished, ok := msg.(*finishedMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(clientFinished, msg)
+    }
+
+    verify := hs.finishedHash.clientSum(hs.masterSecret)
+    if !verifyFinished(verify, clientFinished.verifyData) {
+        c.sendAlert(alertHandshakeFailure)
+        return errors.New("tlcp: client's Finished message is incorrect")
+    }
+
+    hs.finishedHash.Write(clientFinished.raw)
+
+    return nil
+}
+
+func (hs *serverHandshakeState) sendFinished() error {
+    c := hs.c
+
+    if err := c.writeChangeCipherRecord(); err != nil {
+        return err
+    }
+
+    finished := new(finishedMsg)
+    finished.verifyData = hs.finishedHash.serverSum(hs.masterSecret)
+
+    if _, err := c.writeHandshakeRecord(finished, &hs.finishedHash); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+// processCertsFromClient takes a chain of client certificates from a
+// Certificate message and verifies them.
+func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) error {
+    c := hs.c
+    config := c.config
+
+    if len(certificates) == 0 {
+        if requiresClientCert(config.ClientAuth) {
+            c.sendAlert(alertBadCertificate)
+            return errors.New("tlcp: client didn't provide a certificate")
+        }
+
+        return nil
+    }
+
+    if len(certificates) < 2 {
+        c.sendAlert(alertBadCertificate)
+        return errors.New("tlcp: client didn't provide signing and encryption certificates")
+    }
+
+    verify := config.ClientAuth >= VerifyClientCertIfGiven && !config.InsecureSkipVerify
+
+    return c.verifyPeerCertificates(certificates, x509.ExtKeyUsageClientAuth, verify)
+}
@@ -0,0 +1,389 @@
+package tlcp
+
+import (
+    "io"
+    "errors"
+    "math/big"
+    "crypto"
+
+    "golang.org/x/crypto/cryptobyte"
+
+    "github.com/deatil/go-cryptobin/x509"
+    "github.com/deatil/go-cryptobin/gm/sm2"
+    "github.com/deatil/go-cryptobin/hash/sm3"
+)
+
+var errClientKeyExchange = errors.New("tlcp: invalid ClientKeyExchange message")
+var errServerKeyExchange = errors.New("tlcp: invalid ServerKeyExchange message")
+
+// SM2 加密预主密钥的编码方式, GM/T 0009 ASN.1 C1C3C2
+var encrypterOpts = sm2.EncrypterOpts{
+    Mode:     sm2.C1C3C2,
+    Hash:     sm3.New,
+    Encoding: sm2.EncodingASN1,
+}
+
+// keyAgreement 密钥交换
+type keyAgreement interface {
+    // On the server side, the first two methods are called in order.
+
+    // In the case that the key agreement protocol doesn't use a
+    // ServerKeyExchange message, generateServerKeyExchange can return nil,
+    // nil.
+    generateServerKeyExchange(config *Config, signCert, encCert *Certificate, clientHello *clientHelloMsg, hello *serverHelloMsg) (*serverKeyExchangeMsg, error)
+    processClientKeyExchange(config *Config, encCert *Certificate, ckx *clientKeyExchangeMsg, clientEncCert *x509.Certificate) ([]byte, error)
+
+    // On the client side, the next two methods are called in order.
+
+    // This method may not be called if the server doesn't send a
+    // ServerKeyExchange message.
+    processServerKeyExchange(config *Config, clientHello *clientHelloMsg, serverHello *serverHelloMsg, serverSignCert, serverEncCert *x509.Certificate, skx *serverKeyExchangeMsg) error
+    generateClientKeyExchange(config *Config, clientHello *clientHelloMsg, serverEncCert *x509.Certificate, clientEncCert *Certificate) ([]byte, *clientKeyExchangeMsg, error)
+}
+
+// eccKeyAgreement implements the ECC_SM4_* key agreement: the client
+// encrypts the pre-master secret with the server's encryption certificate.
+type eccKeyAgreement struct{}
+
+func (ka *eccKeyAgreement) generateServerKeyExchange(config *Config, signCert, encCert *Certificate, clientHello *clientHelloMsg, hello *serverHelloMsg) (*serverKeyExchangeMsg, error) {
+    msg, err := eccSignedParams(clientHello.random, hello.random, encCert.Certificate[0])
+    if err != nil {
+        return nil, err
+    }
+
+    sig, err := signHandshake(config.rand(), signCert.PrivateKey, msg)
+    if err != nil {
+        return nil, err
+    }
+
+    skx := new(serverKeyExchangeMsg)
+    skx.key, err = addUint16LengthPrefixed(sig)
+
+    return skx, err
+}
+
+func (ka *eccKeyAgreement) processClientKeyExchange(config *Config, encCert *Certificate, ckx *clientKeyExchangeMsg, _ *x509.Certificate) ([]byte, error) {
+    s := cryptobyte.String(ckx.ciphertext)
+
+    var ciphertext []byte
+    if !readUint16LengthPrefixed(&s, &ciphertext) || !s.Empty() {
+        return nil, errClientKeyExchange
+    }
+
+    priv, ok := encCert.PrivateKey.(*sm2.PrivateKey)
+    if !ok {
+        return nil, errors.New("tlcp: encryption certificate private key is not a SM2 key")
+    }
+
+    preMasterSecret, err := priv.DecryptASN1(ciphertext, encrypterOpts)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(preMasterSecret) != 48 {
+        return nil, errClientKeyExchange
+    }
+
+    return preMasterSecret, nil
+}
+
+func (ka *eccKeyAgreement) processServerKeyExchange(config *Config, clientHello *clientHelloMsg, serverHello *serverHelloMsg, serverSignCert, serverEncCert *x509.Certificate, skx *serverKeyExchangeMsg) error {
+    s := cryptobyte.String(skx.key)
+
+    var sig []byte
+    if !readUint16LengthPrefixed(&s, &sig) || !s.Empty() {
+        return errServerKeyExchange
+    }
+
+    msg, err := eccSignedParams(clientHello.random, serverHello.random, serverEncCert.Raw)
+    if err != nil {
+        return err
+    }
+
+    return verifyHandshake(serverSignCert.PublicKey, msg, sig)
+}
+
+func (ka *eccKeyAgreement) generateClientKeyExchange(config *Config, clientHello *clientHelloMsg, serverEncCert *x509.Certificate, _ *Certificate) ([]byte, *clientKeyExchangeMsg, error) {
+    pub, ok := serverEncCert.PublicKey.(*sm2.PublicKey)
+    if !ok {
+        return nil, nil, errors.New("tlcp: server encryption certificate is not a SM2 certificate")
+    }
+
+    preMasterSecret := make([]byte, 48)
+    preMasterSecret[0] = byte(clientHello.vers >> 8)
+    preMasterSecret[1] = byte(clientHello.vers)
+    if _, err := io.ReadFull(config.rand(), preMasterSecret[2:]); err != nil {
+        return nil, nil, err
+    }
+
+    encrypted, err := sm2.EncryptASN1(config.rand(), pub, preMasterSecret, encrypterOpts)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    ckx := new(clientKeyExchangeMsg)
+    ckx.ciphertext, err = addUint16LengthPrefixed(encrypted)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    return preMasterSecret, ckx, nil
+}
+
+// ECC 签名数据, client_random + server_random + 加密证书
+func eccSignedParams(clientRandom, serverRandom, encCert []byte) ([]byte, error) {
+    var b cryptobyte.Builder
+    b.AddBytes(clientRandom)
+    b.AddBytes(serverRandom)
+    b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+        b.AddBytes(encCert)
+    })
+
+    return b.Bytes()
+}
+
+// ==========
+
+// ecdheKeyAgreement implements the ECDHE_SM4_* key agreement with the SM2
+// key exchange protocol. The server is the initiator and sends its ephemeral
+// key first, both sides use their encryption certificates.
+type ecdheKeyAgreement struct {
+    ke *sm2.KeyExchange
+
+    // 客户端收到的服务端临时公钥
+    peerEphemeral *sm2.PublicKey
+}
+
+func (ka *ecdheKeyAgreement) generateServerKeyExchange(config *Config, signCert, encCert *Certificate, clientHello *clientHelloMsg, hello *serverHelloMsg) (*serverKeyExchangeMsg, error) {
+    priv, ok := encCert.PrivateKey.(*sm2.PrivateKey)
+    if !ok {
+        return nil, errors.New("tlcp: encryption certificate private key is not a SM2 key")
+    }
+
+    ke, err := sm2.NewKeyExchange(priv, nil, nil, nil, 48, false)
+    if err != nil {
+        return nil, err
+    }
+
+    ephemeral, err := ke.Init(config.rand())
+    if err != nil {
+        return nil, err
+    }
+
+    ka.ke = ke
+
+    params := marshalECDHEParams(ephemeral)
+
+    var b cryptobyte.Builder
+    b.AddBytes(clientHello.random)
+    b.AddBytes(hello.random)
+    b.AddBytes(params)
+
+    msg, err := b.Bytes()
+    if err != nil {
+        return nil, err
+    }
+
+    sig, err := signHandshake(config.rand(), signCert.PrivateKey, msg)
+    if err != nil {
+        return nil, err
+    }
+
+    sigBytes, err := addUint16LengthPrefixed(sig)
+    if err != nil {
+        return nil, err
+    }
+
+    skx := new(serverKeyExchangeMsg)
+    skx.key = append(params, sigBytes...)
+
+    return skx, nil
+}
+
+func (ka *ecdheKeyAgreement) processClientKeyExchange(config *Config, encCert *Certificate, ckx *clientKeyExchangeMsg, clientEncCert *x509.Certificate) ([]byte, error) {
+    if ka.ke == nil {
+        return nil, errors.New("tlcp: missing ServerKeyExchange state")
+    }
+
+    if clientEncCert == nil {
+        return nil, errors.New("tlcp: ECDHE cipher suites require the client encryption certificate")
+    }
+
+    clientPub, ok := clientEncCert.PublicKey.(*sm2.PublicKey)
+    if !ok {
+        return nil, errors.New("tlcp: client encryption certificate is not a SM2 certificate")
+    }
+
+    s := cryptobyte.String(ckx.ciphertext)
+
+    ephemeral, ok := readECDHEParams(&s)
+    if !ok || !s.Empty() {
+        return nil, errClientKeyExchange
+    }
+
+    if err := ka.ke.SetPeerParameters(clientPub, nil); err != nil {
+        return nil, err
+    }
+
+    preMasterSecret, _, err := ka.ke.ConfirmResponder(ephemeral, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    ka.ke.Reset()
+
+    return preMasterSecret, nil
+}
+
+func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHello *clientHelloMsg, serverHello *serverHelloMsg, serverSignCert, serverEncCert *x509.Certificate, skx *serverKeyExchangeMsg) error {
+    s := cryptobyte.String(skx.key)
+    params := s
+
+    ephemeral, ok := readECDHEParams(&s)
+    if !ok {
+        return errServerKeyExchange
+    }
+
+    params = params[:len(params)-len(s)]
+
+    var sig []byte
+    if !readUint16LengthPrefixed(&s, &sig) || !s.Empty() {
+        return errServerKeyExchange
+    }
+
+    var b cryptobyte.Builder
+    b.AddBytes(clientHello.random)
+    b.AddBytes(serverHello.random)
+    b.AddBytes(params)
+
+    msg, err := b.Bytes()
+    if err != nil {
+        return err
+    }
+
+    if err := verifyHandshake(serverSignCert.PublicKey, msg, sig); err != nil {
+        return err
+    }
+
+    ka.peerEphemeral = ephemeral
+
+    return nil
+}
+
+func (ka *ecdheKeyAgreement) generateClientKeyExchange(config *Config, clientHello *clientHelloMsg, serverEncCert *x509.Certificate, clientEncCert *Certificate) ([]byte, *clientKeyExchangeMsg, error) {
+    if ka.peerEphemeral == nil {
+        return nil, nil, errors.New("tlcp: missing ServerKeyExchange message")
+    }
+
+    if clientEncCert == nil {
+        return nil, nil, errors.New("tlcp: ECDHE cipher suites require the client encryption certificate")
+    }
+
+    priv, ok := clientEncCert.PrivateKey.(*sm2.PrivateKey)
+    if !ok {
+        return nil, nil, errors.New("tlcp: client encryption certificate private key is not a SM2 key")
+    }
+
+    serverPub, ok := serverEncCert.PublicKey.(*sm2.PublicKey)
+    if !ok {
+        return nil, nil, errors.New("tlcp: server encryption certificate is not a SM2 certificate")
+    }
+
+    ke, err := sm2.NewKeyExchange(priv, serverPub, nil, nil, 48, false)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer ke.Reset()
+
+    ephemeral, _, err := ke.Repond(config.rand(), ka.peerEphemeral)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    preMasterSecret, err := ke.ConfirmInitiator(nil)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    ckx := new(clientKeyExchangeMsg)
+    ckx.ciphertext = marshalECDHEParams(ephemeral)
+
+    return preMasterSecret, ckx, nil
+}
+
+// ECParameters + ECPoint
+func marshalECDHEParams(pub *sm2.PublicKey) []byte {
+    byteLen := (pub.Curve.Params().BitSize + 7) / 8
+
+    point := make([]byte, 1+2*byteLen)
+    point[0] = 4
+    pub.X.FillBytes(point[1 : 1+byteLen])
+    pub.Y.FillBytes(point[1+byteLen:])
+
+    params := []byte{curveTypeNamedCurve, byte(curveSM2 >> 8), byte(curveSM2), byte(len(point))}
+
+    return append(params, point...)
+}
+
+func readECDHEParams(s *cryptobyte.String) (*sm2.PublicKey, bool) {
+    var curveType uint8
+    var curveID uint16
+    var point []byte
+    if !s.ReadUint8(&curveType) || !s.ReadUint16(&curveID) ||
+        !readUint8LengthPrefixed(s, &point) {
+        return nil, false
+    }
+
+    if curveType != curveTypeNamedCurve || curveID != curveSM2 {
+        return nil, false
+    }
+
+    curve := sm2.P256()
+    byteLen := (curve.Params().BitSize + 7) / 8
+    if len(point) != 1+2*byteLen || point[0] != 4 {
+        return nil, false
+    }
+
+    x := new(big.Int).SetBytes(point[1 : 1+byteLen])
+    y := new(big.Int).SetBytes(point[1+byteLen:])
+    if !curve.IsOnCurve(x, y) {
+        return nil, false
+    }
+
+    return &sm2.PublicKey{Curve: curve, X: x, Y: y}, true
+}
+
+// ==========
+
+// 使用签名私钥签名
+func signHandshake(rand io.Reader, key crypto.PrivateKey, msg []byte) ([]byte, error) {
+    priv, ok := key.(*sm2.PrivateKey)
+    if !ok {
+        return nil, errors.New("tlcp: signing certificate private key is not a SM2 key")
+    }
+
+    return priv.Sign(rand, msg, sm2.DefaultSignerOpts)
+}
+
+// 使用签名证书公钥验证
+func verifyHandshake(key crypto.PublicKey, msg, sig []byte) error {
+    pub, ok := key.(*sm2.PublicKey)
+    if !ok {
+        return errors.New("tlcp: signing certificate is not a SM2 certificate")
+    }
+
+    if !pub.Verify(msg, sig, sm2.DefaultSignerOpts) {
+        return errors.New("tlcp: invalid signature")
+    }
+
+    return nil
+}
+
+func addUint16LengthPrefixed(data []byte) ([]byte, error) {
+    var b cryptobyte.Builder
+    b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+        b.AddBytes(data)
+    })
+
+    return b.Bytes()
+}
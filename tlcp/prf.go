@@ -0,0 +1,123 @@
+package tlcp
+
+import (
+    "hash"
+    "crypto/hmac"
+
+    "github.com/deatil/go-cryptobin/hash/sm3"
+)
+
+const (
+    masterSecretLength   = 48 // Length of a master secret
+    finishedVerifyLength = 12 // Length of verify_data in a Finished message
+)
+
+var (
+    masterSecretLabel   = []byte("master secret")
+    keyExpansionLabel   = []byte("key expansion")
+    clientFinishedLabel = []byte("client finished")
+    serverFinishedLabel = []byte("server finished")
+)
+
+// pHash implements the P_hash function, as defined in RFC 4346, Section 5.
+func pHash(result, secret, seed []byte, h func() hash.Hash) {
+    mac := hmac.New(h, secret)
+    mac.Write(seed)
+    a := mac.Sum(nil)
+
+    j := 0
+    for j < len(result) {
+        mac.Reset()
+        mac.Write(a)
+        mac.Write(seed)
+        b := mac.Sum(nil)
+        copy(result[j:], b)
+        j += len(b)
+
+        mac.Reset()
+        mac.Write(a)
+        a = mac.Sum(nil)
+    }
+}
+
+// prf 使用 SM3 的 PRF, GB/T 38636-2020 6.5
+func prf(result, secret, label, seed []byte) {
+    labelAndSeed := make([]byte, len(label)+len(seed))
+    copy(labelAndSeed, label)
+    copy(labelAndSeed[len(label):], seed)
+
+    pHash(result, secret, labelAndSeed, sm3.New)
+}
+
+// masterFromPreMasterSecret generates the master secret from the pre-master
+// secret.
+func masterFromPreMasterSecret(preMasterSecret, clientRandom, serverRandom []byte) []byte {
+    seed := make([]byte, 0, len(clientRandom)+len(serverRandom))
+    seed = append(seed, clientRandom...)
+    seed = append(seed, serverRandom...)
+
+    masterSecret := make([]byte, masterSecretLength)
+    prf(masterSecret, preMasterSecret, masterSecretLabel, seed)
+
+    return masterSecret
+}
+
+// keysFromMasterSecret generates the connection keys from the master
+// secret, given the lengths of the MAC key, cipher key and IV.
+func keysFromMasterSecret(masterSecret, clientRandom, serverRandom []byte, macLen, keyLen, ivLen int) (clientMAC, serverMAC, clientKey, serverKey, clientIV, serverIV []byte) {
+    seed := make([]byte, 0, len(serverRandom)+len(clientRandom))
+    seed = append(seed, serverRandom...)
+    seed = append(seed, clientRandom...)
+
+    n := 2*macLen + 2*keyLen + 2*ivLen
+    keyMaterial := make([]byte, n)
+    prf(keyMaterial, masterSecret, keyExpansionLabel, seed)
+
+    clientMAC = keyMaterial[:macLen]
+    keyMaterial = keyMaterial[macLen:]
+    serverMAC = keyMaterial[:macLen]
+    keyMaterial = keyMaterial[macLen:]
+    clientKey = keyMaterial[:keyLen]
+    keyMaterial = keyMaterial[keyLen:]
+    serverKey = keyMaterial[:keyLen]
+    keyMaterial = keyMaterial[keyLen:]
+    clientIV = keyMaterial[:ivLen]
+    keyMaterial = keyMaterial[ivLen:]
+    serverIV = keyMaterial[:ivLen]
+
+    return
+}
+
+// A finishedHash calculates the hash of a set of handshake messages suitable
+// for including in a Finished message.
+type finishedHash struct {
+    hash hash.Hash
+}
+
+func newFinishedHash() finishedHash {
+    return finishedHash{sm3.New()}
+}
+
+func (h *finishedHash) Write(msg []byte) (n int, err error) {
+    return h.hash.Write(msg)
+}
+
+func (h finishedHash) Sum() []byte {
+    return h.hash.Sum(nil)
+}
+
+// clientSum returns the contents of the verify_data member of a client's
+// Finished message.
+func (h finishedHash) clientSum(masterSecret []byte) []byte {
+    out := make([]byte, finishedVerifyLength)
+    prf(out, masterSecret, clientFinishedLabel, h.Sum())
+    return out
+}
+
+// serverSum returns the contents of the verify_data member of a server's
+// Finished message.
+func (h finishedHash) serverSum(masterSecret []byte) []byte {
+    out := make([]byte, finishedVerifyLength)
+    prf(out, masterSecret, serverFinishedLabel, h.Sum())
+    return out
+}
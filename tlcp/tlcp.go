@@ -0,0 +1,257 @@
+package tlcp
+
+import (
+    "os"
+    "net"
+    "time"
+    "errors"
+    "context"
+    "strings"
+    "encoding/pem"
+
+    "github.com/deatil/go-cryptobin/x509"
+    "github.com/deatil/go-cryptobin/gm/sm2"
+)
+
+// Server returns a new TLCP server side connection
+// using conn as the underlying transport.
+// The configuration config must be non-nil and must include
+// the signing and encryption certificates.
+func Server(conn net.Conn, config *Config) *Conn {
+    c := &Conn{
+        conn:   conn,
+        config: config,
+    }
+    c.handshakeFn = c.serverHandshake
+
+    return c
+}
+
+// Client returns a new TLCP client side connection
+// using conn as the underlying transport.
+// The config cannot be nil: users must set either ServerName or
+// InsecureSkipVerify in the config.
+func Client(conn net.Conn, config *Config) *Conn {
+    c := &Conn{
+        conn:     conn,
+        config:   config,
+        isClient: true,
+    }
+    c.handshakeFn = c.clientHandshake
+
+    return c
+}
+
+// A listener implements a network listener (net.Listener) for TLCP connections.
+type listener struct {
+    net.Listener
+    config *Config
+}
+
+// Accept waits for and returns the next incoming TLCP connection.
+// The returned connection is of type *Conn.
+func (l *listener) Accept() (net.Conn, error) {
+    c, err := l.Listener.Accept()
+    if err != nil {
+        return nil, err
+    }
+
+    return Server(c, l.config), nil
+}
+
+// NewListener creates a Listener which accepts connections from an inner
+// Listener and wraps each connection with Server.
+// The configuration config must be non-nil and must include
+// the signing and encryption certificates.
+func NewListener(inner net.Listener, config *Config) net.Listener {
+    l := new(listener)
+    l.Listener = inner
+    l.config = config
+
+    return l
+}
+
+// Listen creates a TLCP listener accepting connections on the
+// given network address using net.Listen.
+func Listen(network, laddr string, config *Config) (net.Listener, error) {
+    if config == nil || len(config.Certificates) < 2 {
+        return nil, errors.New("tlcp: signing and encryption certificates are required in Config")
+    }
+
+    l, err := net.Listen(network, laddr)
+    if err != nil {
+        return nil, err
+    }
+
+    return NewListener(l, config), nil
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "tlcp: DialWithDialer timed out" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// DialWithDialer connects to the given network address using dialer.Dial and
+// then initiates a TLCP handshake, returning the resulting TLCP connection. Any
+// timeout or deadline given in the dialer apply to connection and TLCP
+// handshake as a whole.
+func DialWithDialer(dialer *net.Dialer, network, addr string, config *Config) (*Conn, error) {
+    return dial(context.Background(), dialer, network, addr, config)
+}
+
+func dial(ctx context.Context, netDialer *net.Dialer, network, addr string, config *Config) (*Conn, error) {
+    if netDialer.Timeout != 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, netDialer.Timeout)
+        defer cancel()
+    }
+
+    if !netDialer.Deadline.IsZero() {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithDeadline(ctx, netDialer.Deadline)
+        defer cancel()
+    }
+
+    rawConn, err := netDialer.DialContext(ctx, network, addr)
+    if err != nil {
+        return nil, err
+    }
+
+    colonPos := strings.LastIndex(addr, ":")
+    if colonPos == -1 {
+        colonPos = len(addr)
+    }
+    hostname := addr[:colonPos]
+
+    if config == nil {
+        config = &Config{}
+    }
+
+    // If no ServerName is set, infer the ServerName
+    // from the hostname we're connecting to.
+    if config.ServerName == "" {
+        // Make a copy to avoid polluting argument or default.
+        c := config.Clone()
+        c.ServerName = hostname
+        config = c
+    }
+
+    conn := Client(rawConn, config)
+
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    if err := conn.Handshake(); err != nil {
+        rawConn.Close()
+
+        if ctx.Err() == context.DeadlineExceeded {
+            return nil, timeoutError{}
+        }
+
+        return nil, err
+    }
+
+    conn.SetDeadline(time.Time{})
+
+    return conn, nil
+}
+
+// Dial connects to the given network address using net.Dial
+// and then initiates a TLCP handshake, returning the resulting
+// TLCP connection.
+func Dial(network, addr string, config *Config) (*Conn, error) {
+    return DialWithDialer(new(net.Dialer), network, addr, config)
+}
+
+// LoadX509KeyPair reads and parses a public/private key pair from a pair
+// of files. The files must contain PEM encoded data. The certificate file
+// may contain intermediate certificates following the leaf certificate to
+// form a certificate chain.
+func LoadX509KeyPair(certFile, keyFile string) (Certificate, error) {
+    certPEMBlock, err := os.ReadFile(certFile)
+    if err != nil {
+        return Certificate{}, err
+    }
+
+    keyPEMBlock, err := os.ReadFile(keyFile)
+    if err != nil {
+        return Certificate{}, err
+    }
+
+    return X509KeyPair(certPEMBlock, keyPEMBlock)
+}
+
+// X509KeyPair parses a public/private key pair from a pair of
+// PEM encoded data. The private key must be a SM2 key in PKCS#8
+// or SEC 1 form.
+func X509KeyPair(certPEMBlock, keyPEMBlock []byte) (Certificate, error) {
+    fail := func(err error) (Certificate, error) { return Certificate{}, err }
+
+    var cert Certificate
+    var certDERBlock *pem.Block
+    for {
+        certDERBlock, certPEMBlock = pem.Decode(certPEMBlock)
+        if certDERBlock == nil {
+            break
+        }
+
+        if certDERBlock.Type == "CERTIFICATE" {
+            cert.Certificate = append(cert.Certificate, certDERBlock.Bytes)
+        }
+    }
+
+    if len(cert.Certificate) == 0 {
+        return fail(errors.New("tlcp: failed to find any PEM data in certificate input"))
+    }
+
+    var keyDERBlock *pem.Block
+    for {
+        keyDERBlock, keyPEMBlock = pem.Decode(keyPEMBlock)
+        if keyDERBlock == nil {
+            return fail(errors.New("tlcp: failed to find any PEM data in key input"))
+        }
+
+        if keyDERBlock.Type == "PRIVATE KEY" || strings.HasSuffix(keyDERBlock.Type, " PRIVATE KEY") {
+            break
+        }
+    }
+
+    x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+    if err != nil {
+        return fail(err)
+    }
+
+    priv, err := parsePrivateKey(keyDERBlock.Bytes)
+    if err != nil {
+        return fail(err)
+    }
+
+    pub, ok := x509Cert.PublicKey.(*sm2.PublicKey)
+    if !ok {
+        return fail(errors.New("tlcp: certificate is not a SM2 certificate"))
+    }
+
+    if !priv.PublicKey.Equal(pub) {
+        return fail(errors.New("tlcp: private key does not match public key"))
+    }
+
+    cert.PrivateKey = priv
+    cert.Leaf = x509Cert
+
+    return cert, nil
+}
+
+// 解析 SM2 私钥
+func parsePrivateKey(der []byte) (*sm2.PrivateKey, error) {
+    if key, err := sm2.ParsePrivateKey(der); err == nil {
+        return key, nil
+    }
+
+    if key, err := sm2.ParseSM2PrivateKey(der); err == nil {
+        return key, nil
+    }
+
+    return nil, errors.New("tlcp: failed to parse SM2 private key")
+}
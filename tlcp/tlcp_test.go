@@ -0,0 +1,353 @@
+package tlcp
+
+import (
+    "io"
+    "net"
+    "time"
+    "bytes"
+    "testing"
+    "math/big"
+    "crypto/rand"
+    "encoding/pem"
+    "crypto/x509/pkix"
+
+    "github.com/deatil/go-cryptobin/x509"
+    "github.com/deatil/go-cryptobin/gm/sm2"
+)
+
+type testPKI struct {
+    pool       *x509.CertPool
+    serverSign Certificate
+    serverEnc  Certificate
+    clientSign Certificate
+    clientEnc  Certificate
+}
+
+var serial int64 = 1
+
+func newTestCert(t *testing.T, cn string, usage x509.KeyUsage, extUsage []x509.ExtKeyUsage, parent *x509.Certificate, parentKey *sm2.PrivateKey) (Certificate, *x509.Certificate, *sm2.PrivateKey) {
+    priv, err := sm2.GenerateKey(rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    serial++
+
+    template := &x509.Certificate{
+        SerialNumber:       big.NewInt(serial),
+        Subject:            pkix.Name{CommonName: cn},
+        NotBefore:          time.Now().Add(-time.Hour),
+        NotAfter:           time.Now().Add(time.Hour),
+        KeyUsage:           usage,
+        ExtKeyUsage:        extUsage,
+        SignatureAlgorithm: x509.SM2WithSM3,
+    }
+
+    if parent == nil {
+        template.IsCA = true
+        template.BasicConstraintsValid = true
+        parent = template
+        parentKey = priv
+    } else {
+        template.DNSNames = []string{cn}
+    }
+
+    der, err := x509.CreateCertificate(rand.Reader, template, parent, &priv.PublicKey, parentKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cert, err := x509.ParseCertificate(der)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return Certificate{Certificate: [][]byte{der}, PrivateKey: priv, Leaf: cert}, cert, priv
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+    _, ca, caKey := newTestCert(t, "Test CA", x509.KeyUsageCertSign, nil, nil, nil)
+
+    pool := x509.NewCertPool()
+    pool.AddCert(ca)
+
+    sign := x509.KeyUsageDigitalSignature
+    enc := x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment | x509.KeyUsageKeyAgreement
+    server := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+    client := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+    p := &testPKI{pool: pool}
+    p.serverSign, _, _ = newTestCert(t, "server.test", sign, server, ca, caKey)
+    p.serverEnc, _, _ = newTestCert(t, "server.test", enc, server, ca, caKey)
+    p.clientSign, _, _ = newTestCert(t, "client.test", sign, client, ca, caKey)
+    p.clientEnc, _, _ = newTestCert(t, "client.test", enc, client, ca, caKey)
+
+    return p
+}
+
+func (p *testPKI) configs() (*Config, *Config) {
+    serverConfig := &Config{
+        Certificates: []Certificate{p.serverSign, p.serverEnc},
+        ClientCAs:    p.pool,
+    }
+    clientConfig := &Config{
+        Certificates: []Certificate{p.clientSign, p.clientEnc},
+        RootCAs:      p.pool,
+        ServerName:   "server.test",
+    }
+
+    return serverConfig, clientConfig
+}
+
+// 使用 net.Pipe 完成握手并交换数据
+func runPipe(t *testing.T, serverConfig, clientConfig *Config) (ConnectionState, ConnectionState, error) {
+    c, s := net.Pipe()
+    defer c.Close()
+    defer s.Close()
+
+    server := Server(s, serverConfig)
+    client := Client(c, clientConfig)
+
+    type result struct {
+        state ConnectionState
+        err   error
+    }
+
+    done := make(chan result, 1)
+    go func() {
+        if err := server.Handshake(); err != nil {
+            s.Close()
+            done <- result{err: err}
+            return
+        }
+
+        buf := make([]byte, 5)
+        if _, err := io.ReadFull(server, buf); err != nil {
+            done <- result{err: err}
+            return
+        }
+
+        if _, err := server.Write(bytes.ToUpper(buf)); err != nil {
+            done <- result{err: err}
+            return
+        }
+
+        done <- result{state: server.ConnectionState()}
+    }()
+
+    if err := client.Handshake(); err != nil {
+        c.Close()
+        <-done
+        return ConnectionState{}, ConnectionState{}, err
+    }
+
+    if _, err := client.Write([]byte("hello")); err != nil {
+        return ConnectionState{}, ConnectionState{}, err
+    }
+
+    buf := make([]byte, 5)
+    if _, err := io.ReadFull(client, buf); err != nil {
+        return ConnectionState{}, ConnectionState{}, err
+    }
+
+    if string(buf) != "HELLO" {
+        t.Errorf("got %q, want %q", buf, "HELLO")
+    }
+
+    res := <-done
+    if res.err != nil {
+        return ConnectionState{}, ConnectionState{}, res.err
+    }
+
+    return res.state, client.ConnectionState(), nil
+}
+
+func Test_Handshake(t *testing.T) {
+    p := newTestPKI(t)
+
+    for _, id := range defaultCipherSuites {
+        t.Run(CipherSuiteName(id), func(t *testing.T) {
+            serverConfig, clientConfig := p.configs()
+            clientConfig.CipherSuites = []uint16{id}
+
+            ss, cs, err := runPipe(t, serverConfig, clientConfig)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if cs.CipherSuite != id || ss.CipherSuite != id {
+                t.Errorf("CipherSuite got %x/%x, want %x", cs.CipherSuite, ss.CipherSuite, id)
+            }
+
+            if cs.Version != VersionTLCP {
+                t.Errorf("Version got %x", cs.Version)
+            }
+
+            if len(cs.PeerCertificates) != 2 || len(cs.VerifiedChains) == 0 {
+                t.Error("client should verify the server certificate pair")
+            }
+        })
+    }
+}
+
+func Test_ClientAuth(t *testing.T) {
+    p := newTestPKI(t)
+
+    serverConfig, clientConfig := p.configs()
+    serverConfig.ClientAuth = RequireAndVerifyClientCert
+    clientConfig.CipherSuites = []uint16{ECC_SM4_CBC_SM3}
+
+    ss, _, err := runPipe(t, serverConfig, clientConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(ss.PeerCertificates) != 2 || len(ss.VerifiedChains) == 0 {
+        t.Error("server should verify the client certificate pair")
+    }
+
+    clientConfig.Certificates = nil
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("handshake without a required client certificate should fail")
+    }
+}
+
+func Test_Resume(t *testing.T) {
+    p := newTestPKI(t)
+
+    for _, id := range []uint16{ECC_SM4_GCM_SM3, ECDHE_SM4_CBC_SM3} {
+        t.Run(CipherSuiteName(id), func(t *testing.T) {
+            serverConfig, clientConfig := p.configs()
+            serverConfig.SessionCache = NewLRUSessionCache(0)
+            clientConfig.SessionCache = NewLRUSessionCache(0)
+            clientConfig.CipherSuites = []uint16{id}
+
+            _, cs, err := runPipe(t, serverConfig, clientConfig)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if cs.DidResume {
+                t.Fatal("first connection should not resume")
+            }
+
+            ss, cs2, err := runPipe(t, serverConfig, clientConfig)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if !cs2.DidResume || !ss.DidResume {
+                t.Fatal("second connection should resume")
+            }
+
+            if !bytes.Equal(cs.SessionID, cs2.SessionID) {
+                t.Error("resumed session id mismatch")
+            }
+
+            if len(cs2.PeerCertificates) != 2 {
+                t.Error("resumed session should keep peer certificates")
+            }
+        })
+    }
+}
+
+func Test_BadRoot(t *testing.T) {
+    p := newTestPKI(t)
+    other := newTestPKI(t)
+
+    serverConfig, clientConfig := p.configs()
+    clientConfig.RootCAs = other.pool
+
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("handshake with an untrusted server should fail")
+    }
+
+    clientConfig.ServerName = "other.test"
+    clientConfig.RootCAs = p.pool
+
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("handshake with a wrong server name should fail")
+    }
+}
+
+func Test_ECDHEWithoutClientCert(t *testing.T) {
+    p := newTestPKI(t)
+
+    serverConfig, clientConfig := p.configs()
+    clientConfig.Certificates = nil
+    clientConfig.CipherSuites = []uint16{ECDHE_SM4_GCM_SM3}
+
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("ECDHE handshake without client certificates should fail")
+    }
+}
+
+func Test_X509KeyPair(t *testing.T) {
+    p := newTestPKI(t)
+
+    certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.serverSign.Certificate[0]})
+
+    der, err := sm2.MarshalPrivateKey(p.serverSign.PrivateKey.(*sm2.PrivateKey))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+    cert, err := X509KeyPair(certPEM, keyPEM)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if cert.Leaf == nil || len(cert.Certificate) != 1 {
+        t.Error("X509KeyPair parse fail")
+    }
+
+    otherDER, _ := sm2.MarshalPrivateKey(p.serverEnc.PrivateKey.(*sm2.PrivateKey))
+    otherPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER})
+
+    if _, err := X509KeyPair(certPEM, otherPEM); err == nil {
+        t.Error("mismatched key should fail")
+    }
+}
+
+func Test_Listen(t *testing.T) {
+    p := newTestPKI(t)
+    serverConfig, clientConfig := p.configs()
+
+    ln, err := Listen("tcp", "127.0.0.1:0", serverConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ln.Close()
+
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+
+        io.Copy(conn, conn)
+    }()
+
+    conn, err := Dial("tcp", ln.Addr().String(), clientConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+
+    msg := []byte("tlcp echo")
+    if _, err := conn.Write(msg); err != nil {
+        t.Fatal(err)
+    }
+
+    buf := make([]byte, len(msg))
+    if _, err := io.ReadFull(conn, buf); err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(buf, msg) {
+        t.Errorf("got %q, want %q", buf, msg)
+    }
+}
@@ -0,0 +1,80 @@
+package tls13
+
+import (
+    "strconv"
+)
+
+type alert uint8
+
+const (
+    // alert level
+    alertLevelWarning = 1
+    alertLevelError   = 2
+)
+
+const (
+    alertCloseNotify            alert = 0
+    alertUnexpectedMessage      alert = 10
+    alertBadRecordMAC           alert = 20
+    alertRecordOverflow         alert = 22
+    alertHandshakeFailure       alert = 40
+    alertBadCertificate         alert = 42
+    alertUnsupportedCertificate alert = 43
+    alertCertificateRevoked     alert = 44
+    alertCertificateExpired     alert = 45
+    alertCertificateUnknown     alert = 46
+    alertIllegalParameter       alert = 47
+    alertUnknownCA              alert = 48
+    alertAccessDenied           alert = 49
+    alertDecodeError            alert = 50
+    alertDecryptError           alert = 51
+    alertProtocolVersion        alert = 70
+    alertInsufficientSecurity   alert = 71
+    alertInternalError          alert = 80
+    alertUserCanceled           alert = 90
+    alertMissingExtension       alert = 109
+    alertUnsupportedExtension   alert = 110
+    alertUnrecognizedName       alert = 112
+    alertCertificateRequired    alert = 116
+    alertNoApplicationProtocol  alert = 120
+)
+
+var alertText = map[alert]string{
+    alertCloseNotify:            "close notify",
+    alertUnexpectedMessage:      "unexpected message",
+    alertBadRecordMAC:           "bad record MAC",
+    alertRecordOverflow:         "record overflow",
+    alertHandshakeFailure:       "handshake failure",
+    alertBadCertificate:         "bad certificate",
+    alertUnsupportedCertificate: "unsupported certificate",
+    alertCertificateRevoked:     "revoked certificate",
+    alertCertificateExpired:     "expired certificate",
+    alertCertificateUnknown:     "unknown certificate",
+    alertIllegalParameter:       "illegal parameter",
+    alertUnknownCA:              "unknown certificate authority",
+    alertAccessDenied:           "access denied",
+    alertDecodeError:            "error decoding message",
+    alertDecryptError:           "error decrypting message",
+    alertProtocolVersion:        "protocol version not supported",
+    alertInsufficientSecurity:   "insufficient security level",
+    alertInternalError:          "internal error",
+    alertUserCanceled:           "user canceled",
+    alertMissingExtension:       "missing extension",
+    alertUnsupportedExtension:   "unsupported extension",
+    alertUnrecognizedName:       "unrecognized name",
+    alertCertificateRequired:    "certificate required",
+    alertNoApplicationProtocol:  "no application protocol",
+}
+
+func (e alert) String() string {
+    s, ok := alertText[e]
+    if ok {
+        return "tls13: " + s
+    }
+
+    return "tls13: alert(" + strconv.Itoa(int(e)) + ")"
+}
+
+func (e alert) Error() string {
+    return e.String()
+}
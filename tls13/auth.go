@@ -0,0 +1,185 @@
+package tls13
+
+import (
+    "io"
+    "hash"
+    "bytes"
+    "errors"
+    "crypto"
+    "crypto/rsa"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "crypto/elliptic"
+
+    "github.com/deatil/go-cryptobin/gm/sm2"
+    "github.com/deatil/go-cryptobin/hash/sm3"
+)
+
+// RFC 8998 3.2.1, sm2sig_sm3 使用的用户身份
+var sm2SignerOpts = sm2.SignerOpts{
+    Uid:  []byte("TLSv1.3+GM+Cipher+Suite"),
+    Hash: sm3.New,
+}
+
+const (
+    serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+    clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+var signaturePadding = []byte{
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+    0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+}
+
+// signedMessage returns the message to be signed for the CertificateVerify
+// message, RFC 8446, Section 4.4.3.
+func signedMessage(context string, transcript hash.Hash) []byte {
+    var b bytes.Buffer
+    b.Write(signaturePadding)
+    b.WriteString(context)
+    b.Write(transcript.Sum(nil))
+
+    return b.Bytes()
+}
+
+// 签名算法使用的摘要, SM2 和 Ed25519 直接签名消息
+func hashForScheme(scheme SignatureScheme) (crypto.Hash, error) {
+    switch scheme {
+        case PSSWithSHA256, ECDSAWithP256AndSHA256:
+            return crypto.SHA256, nil
+        case PSSWithSHA384, ECDSAWithP384AndSHA384:
+            return crypto.SHA384, nil
+        case PSSWithSHA512, ECDSAWithP521AndSHA512:
+            return crypto.SHA512, nil
+        case Ed25519, SM2SigSM3:
+            return 0, nil
+    }
+
+    return 0, errors.New("tls13: unsupported signature algorithm")
+}
+
+// signatureSchemesForKey 公钥支持的签名算法
+func signatureSchemesForKey(pub crypto.PublicKey) []SignatureScheme {
+    switch pub := pub.(type) {
+        case *sm2.PublicKey:
+            return []SignatureScheme{SM2SigSM3}
+        case *ecdsa.PublicKey:
+            switch pub.Curve {
+                case elliptic.P256():
+                    return []SignatureScheme{ECDSAWithP256AndSHA256}
+                case elliptic.P384():
+                    return []SignatureScheme{ECDSAWithP384AndSHA384}
+                case elliptic.P521():
+                    return []SignatureScheme{ECDSAWithP521AndSHA512}
+            }
+        case ed25519.PublicKey:
+            return []SignatureScheme{Ed25519}
+        case *rsa.PublicKey:
+            return []SignatureScheme{PSSWithSHA256, PSSWithSHA384, PSSWithSHA512}
+    }
+
+    return nil
+}
+
+// selectSignatureScheme 按本地顺序选择对端和密钥都支持的签名算法
+func selectSignatureScheme(pub crypto.PublicKey, ours, peers []SignatureScheme) (SignatureScheme, bool) {
+    supported := signatureSchemesForKey(pub)
+
+    for _, scheme := range ours {
+        if isSupportedSignatureScheme(scheme, supported) &&
+            isSupportedSignatureScheme(scheme, peers) {
+            return scheme, true
+        }
+    }
+
+    return 0, false
+}
+
+func isSupportedSignatureScheme(scheme SignatureScheme, schemes []SignatureScheme) bool {
+    for _, s := range schemes {
+        if s == scheme {
+            return true
+        }
+    }
+
+    return false
+}
+
+// signHandshake 签名 CertificateVerify 消息
+func signHandshake(rand io.Reader, key crypto.PrivateKey, scheme SignatureScheme, msg []byte) ([]byte, error) {
+    if priv, ok := key.(*sm2.PrivateKey); ok {
+        if scheme != SM2SigSM3 {
+            return nil, errors.New("tls13: SM2 key can only be used with sm2sig_sm3")
+        }
+
+        return priv.Sign(rand, msg, sm2SignerOpts)
+    }
+
+    signer, ok := key.(crypto.Signer)
+    if !ok {
+        return nil, errors.New("tls13: private key does not implement crypto.Signer")
+    }
+
+    h, err := hashForScheme(scheme)
+    if err != nil {
+        return nil, err
+    }
+
+    var opts crypto.SignerOpts = h
+    if h != 0 {
+        hasher := h.New()
+        hasher.Write(msg)
+        msg = hasher.Sum(nil)
+    }
+
+    switch scheme {
+        case PSSWithSHA256, PSSWithSHA384, PSSWithSHA512:
+            opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h}
+    }
+
+    return signer.Sign(rand, msg, opts)
+}
+
+// verifyHandshake 验证 CertificateVerify 签名
+func verifyHandshake(pub crypto.PublicKey, scheme SignatureScheme, msg, sig []byte) error {
+    if !isSupportedSignatureScheme(scheme, signatureSchemesForKey(pub)) {
+        return errors.New("tls13: signature algorithm does not match the certificate")
+    }
+
+    h, err := hashForScheme(scheme)
+    if err != nil {
+        return err
+    }
+
+    digest := msg
+    if h != 0 {
+        hasher := h.New()
+        hasher.Write(msg)
+        digest = hasher.Sum(nil)
+    }
+
+    var valid bool
+    switch pub := pub.(type) {
+        case *sm2.PublicKey:
+            valid = pub.Verify(msg, sig, sm2SignerOpts)
+        case *ecdsa.PublicKey:
+            valid = ecdsa.VerifyASN1(pub, digest, sig)
+        case ed25519.PublicKey:
+            valid = ed25519.Verify(pub, msg, sig)
+        case *rsa.PublicKey:
+            opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+            valid = rsa.VerifyPSS(pub, h, digest, sig, opts) == nil
+    }
+
+    if !valid {
+        return errors.New("tls13: invalid signature")
+    }
+
+    return nil
+}
@@ -0,0 +1,190 @@
+package tls13
+
+import (
+    "hash"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/sha256"
+    "crypto/sha512"
+
+    "golang.org/x/crypto/chacha20poly1305"
+
+    "github.com/deatil/go-cryptobin/hash/sm3"
+    "github.com/deatil/go-cryptobin/mode/ccm"
+    "github.com/deatil/go-cryptobin/cipher/sm4"
+)
+
+// TLS 1.3 密码套件, RFC 8446 B.4 和 RFC 8998
+const (
+    TLS_AES_128_GCM_SHA256       uint16 = 0x1301
+    TLS_AES_256_GCM_SHA384       uint16 = 0x1302
+    TLS_CHACHA20_POLY1305_SHA256 uint16 = 0x1303
+    TLS_SM4_GCM_SM3              uint16 = 0x00c6
+    TLS_SM4_CCM_SM3              uint16 = 0x00c7
+)
+
+// 默认密码套件
+var defaultCipherSuites = []uint16{
+    TLS_AES_128_GCM_SHA256,
+    TLS_SM4_GCM_SM3,
+    TLS_CHACHA20_POLY1305_SHA256,
+    TLS_AES_256_GCM_SHA384,
+    TLS_SM4_CCM_SM3,
+}
+
+// A cipherSuite is a TLS 1.3 cipher suite.
+type cipherSuite struct {
+    id     uint16
+    keyLen int
+    aead   func(key, fixedNonce []byte) cipher.AEAD
+    hash   func() hash.Hash
+}
+
+var cipherSuites = []*cipherSuite{
+    {TLS_AES_128_GCM_SHA256, 16, aeadAESGCM, sha256.New},
+    {TLS_CHACHA20_POLY1305_SHA256, 32, aeadChaCha20Poly1305, sha256.New},
+    {TLS_AES_256_GCM_SHA384, 32, aeadAESGCM, sha512.New384},
+    {TLS_SM4_GCM_SM3, 16, aeadSM4GCM, sm3.New},
+    {TLS_SM4_CCM_SM3, 16, aeadSM4CCM, sm3.New},
+}
+
+// CipherSuiteName returns the standard name for the passed cipher suite ID
+func CipherSuiteName(id uint16) string {
+    switch id {
+        case TLS_AES_128_GCM_SHA256:
+            return "TLS_AES_128_GCM_SHA256"
+        case TLS_AES_256_GCM_SHA384:
+            return "TLS_AES_256_GCM_SHA384"
+        case TLS_CHACHA20_POLY1305_SHA256:
+            return "TLS_CHACHA20_POLY1305_SHA256"
+        case TLS_SM4_GCM_SM3:
+            return "TLS_SM4_GCM_SM3"
+        case TLS_SM4_CCM_SM3:
+            return "TLS_SM4_CCM_SM3"
+    }
+
+    return "unknown"
+}
+
+func cipherSuiteByID(id uint16) *cipherSuite {
+    for _, suite := range cipherSuites {
+        if suite.id == id {
+            return suite
+        }
+    }
+
+    return nil
+}
+
+// mutualCipherSuite returns a cipherSuite given a list of supported
+// ciphersuites and the id requested by the peer.
+func mutualCipherSuite(have []uint16, want uint16) *cipherSuite {
+    for _, id := range have {
+        if id == want {
+            return cipherSuiteByID(id)
+        }
+    }
+
+    return nil
+}
+
+const (
+    aeadNonceLength = 12
+)
+
+// xorNonceAEAD wraps an AEAD by XORing in a fixed pattern to the nonce
+// before each call.
+type xorNonceAEAD struct {
+    nonceMask [aeadNonceLength]byte
+    aead      cipher.AEAD
+}
+
+func (f *xorNonceAEAD) NonceSize() int { return 8 } // 64-bit sequence number
+func (f *xorNonceAEAD) Overhead() int  { return f.aead.Overhead() }
+
+func (f *xorNonceAEAD) Seal(out, nonce, plaintext, additionalData []byte) []byte {
+    for i, b := range nonce {
+        f.nonceMask[4+i] ^= b
+    }
+    result := f.aead.Seal(out, f.nonceMask[:], plaintext, additionalData)
+    for i, b := range nonce {
+        f.nonceMask[4+i] ^= b
+    }
+
+    return result
+}
+
+func (f *xorNonceAEAD) Open(out, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+    for i, b := range nonce {
+        f.nonceMask[4+i] ^= b
+    }
+    result, err := f.aead.Open(out, f.nonceMask[:], ciphertext, additionalData)
+    for i, b := range nonce {
+        f.nonceMask[4+i] ^= b
+    }
+
+    return result, err
+}
+
+func newXorNonceAEAD(aead cipher.AEAD, nonceMask []byte) cipher.AEAD {
+    if len(nonceMask) != aeadNonceLength {
+        panic("tls13: internal error: wrong nonce length")
+    }
+
+    ret := &xorNonceAEAD{aead: aead}
+    copy(ret.nonceMask[:], nonceMask)
+
+    return ret
+}
+
+func aeadAESGCM(key, nonceMask []byte) cipher.AEAD {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        panic(err)
+    }
+
+    gcm, err := cipher.NewGCM(block)
+    if err != nil {
+        panic(err)
+    }
+
+    return newXorNonceAEAD(gcm, nonceMask)
+}
+
+func aeadChaCha20Poly1305(key, nonceMask []byte) cipher.AEAD {
+    aead, err := chacha20poly1305.New(key)
+    if err != nil {
+        panic(err)
+    }
+
+    return newXorNonceAEAD(aead, nonceMask)
+}
+
+func aeadSM4GCM(key, nonceMask []byte) cipher.AEAD {
+    block, err := sm4.NewCipher(key)
+    if err != nil {
+        panic(err)
+    }
+
+    gcm, err := cipher.NewGCM(block)
+    if err != nil {
+        panic(err)
+    }
+
+    return newXorNonceAEAD(gcm, nonceMask)
+}
+
+// RFC 8998 使用 12 字节 nonce 和 16 字节 tag
+func aeadSM4CCM(key, nonceMask []byte) cipher.AEAD {
+    block, err := sm4.NewCipher(key)
+    if err != nil {
+        panic(err)
+    }
+
+    aead, err := ccm.NewCCMWithNonceAndTagSize(block, aeadNonceLength, 16)
+    if err != nil {
+        panic(err)
+    }
+
+    return newXorNonceAEAD(aead, nonceMask)
+}
@@ -0,0 +1,295 @@
+package tls13
+
+import (
+    "io"
+    "time"
+    "errors"
+    "crypto"
+    "crypto/rand"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+const (
+    VersionTLS12 = 0x0303
+    VersionTLS13 = 0x0304
+)
+
+const (
+    maxPlaintext      = 16384       // maximum plaintext payload length
+    maxCiphertext     = 16384 + 256 // maximum ciphertext payload length
+    recordHeaderLen   = 5           // record header length
+    maxHandshake      = 65536       // maximum handshake we support (protocol max is 16 MB)
+    maxUselessRecords = 16          // maximum number of consecutive non-advancing records
+)
+
+// TLS record types.
+type recordType uint8
+
+const (
+    recordTypeChangeCipherSpec recordType = 20
+    recordTypeAlert            recordType = 21
+    recordTypeHandshake        recordType = 22
+    recordTypeApplicationData  recordType = 23
+)
+
+// TLS handshake message types.
+const (
+    typeClientHello         uint8 = 1
+    typeServerHello         uint8 = 2
+    typeNewSessionTicket    uint8 = 4
+    typeEncryptedExtensions uint8 = 8
+    typeCertificate         uint8 = 11
+    typeCertificateRequest  uint8 = 13
+    typeCertificateVerify   uint8 = 15
+    typeFinished            uint8 = 20
+    typeKeyUpdate           uint8 = 24
+    typeMessageHash         uint8 = 254 // synthetic message
+)
+
+// TLS compression types.
+const (
+    compressionNone uint8 = 0
+)
+
+// TLS extension numbers
+const (
+    extensionServerName             uint16 = 0
+    extensionSupportedCurves        uint16 = 10
+    extensionSignatureAlgorithms    uint16 = 13
+    extensionSupportedVersions      uint16 = 43
+    extensionCookie                 uint16 = 44
+    extensionCertificateAuthorities uint16 = 47
+    extensionKeyShare               uint16 = 51
+)
+
+// helloRetryRequestRandom is set as the Random value of a ServerHello
+// to signal that the message is actually a HelloRetryRequest.
+var helloRetryRequestRandom = []byte{
+    0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+    0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+    0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+    0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+// CurveID is the type of a TLS identifier for an elliptic curve. See
+// https://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-8.
+type CurveID uint16
+
+const (
+    CurveP256 CurveID = 23
+    CurveP384 CurveID = 24
+    X25519    CurveID = 29
+
+    // CurveSM2 is curveSM2 from RFC 8998
+    CurveSM2 CurveID = 41
+)
+
+var defaultCurvePreferences = []CurveID{X25519, CurveP256, CurveSM2, CurveP384}
+
+// SignatureScheme identifies a signature algorithm supported by TLS. See
+// RFC 8446, Section 4.2.3.
+type SignatureScheme uint16
+
+const (
+    // RSASSA-PSS algorithms with public key OID rsaEncryption.
+    PSSWithSHA256 SignatureScheme = 0x0804
+    PSSWithSHA384 SignatureScheme = 0x0805
+    PSSWithSHA512 SignatureScheme = 0x0806
+
+    // ECDSA algorithms. Only constrained to a specific curve in TLS 1.3.
+    ECDSAWithP256AndSHA256 SignatureScheme = 0x0403
+    ECDSAWithP384AndSHA384 SignatureScheme = 0x0503
+    ECDSAWithP521AndSHA512 SignatureScheme = 0x0603
+
+    // EdDSA algorithms.
+    Ed25519 SignatureScheme = 0x0807
+
+    // SM2SigSM3 is sm2sig_sm3 from RFC 8998
+    SM2SigSM3 SignatureScheme = 0x0708
+)
+
+var defaultSignatureSchemes = []SignatureScheme{
+    SM2SigSM3,
+    ECDSAWithP256AndSHA256,
+    Ed25519,
+    PSSWithSHA256,
+    ECDSAWithP384AndSHA384,
+    ECDSAWithP521AndSHA512,
+    PSSWithSHA384,
+    PSSWithSHA512,
+}
+
+// ClientAuthType declares the policy the server will follow for
+// TLS Client Authentication.
+type ClientAuthType int
+
+const (
+    // NoClientCert indicates that no client certificate should be requested
+    // during the handshake, and if any certificates are sent they will not
+    // be verified.
+    NoClientCert ClientAuthType = iota
+    // RequestClientCert indicates that a client certificate should be requested
+    // during the handshake, but does not require that the client send any
+    // certificates.
+    RequestClientCert
+    // RequireAnyClientCert indicates that a client certificate should be requested
+    // during the handshake, and that at least one certificate is required to be
+    // sent by the client, but that certificate is not required to be valid.
+    RequireAnyClientCert
+    // VerifyClientCertIfGiven indicates that a client certificate should be requested
+    // during the handshake, but does not require that the client sends a
+    // certificate. If the client does send a certificate it is required to be
+    // valid.
+    VerifyClientCertIfGiven
+    // RequireAndVerifyClientCert indicates that a client certificate should be requested
+    // during the handshake, and that at least one valid certificate is required
+    // to be sent by the client.
+    RequireAndVerifyClientCert
+)
+
+// requiresClientCert reports whether the ClientAuthType requires a client
+// certificate to be provided.
+func requiresClientCert(c ClientAuthType) bool {
+    switch c {
+        case RequireAnyClientCert, RequireAndVerifyClientCert:
+            return true
+        default:
+            return false
+    }
+}
+
+// ConnectionState records basic TLS details about the connection.
+type ConnectionState struct {
+    Version           uint16                // TLS version used by the connection
+    HandshakeComplete bool                  // TLS handshake is complete
+    CipherSuite       uint16                // cipher suite in use
+    CurveID           CurveID               // key exchange group in use
+    ServerName        string                // server name requested by client
+    PeerCertificates  []*x509.Certificate   // certificate chain presented by remote peer
+    VerifiedChains    [][]*x509.Certificate // verified chains built from PeerCertificates
+}
+
+// Certificate 证书链和私钥
+type Certificate struct {
+    // DER 编码的证书链, 第一个为叶子证书
+    Certificate [][]byte
+
+    // 私钥, 需要实现 crypto.Signer, 支持 SM2, ECDSA, Ed25519 和 RSA
+    PrivateKey crypto.PrivateKey
+
+    // 解析后的叶子证书, 为空时会按需解析
+    Leaf *x509.Certificate
+}
+
+// 解析证书
+func (c *Certificate) leaf() (*x509.Certificate, error) {
+    if c.Leaf != nil {
+        return c.Leaf, nil
+    }
+
+    if len(c.Certificate) == 0 {
+        return nil, errors.New("tls13: empty certificate")
+    }
+
+    return x509.ParseCertificate(c.Certificate[0])
+}
+
+// Config 配置
+type Config struct {
+    // Rand provides the source of entropy for nonces and key shares.
+    // If Rand is nil, crypto/rand.Reader is used.
+    Rand io.Reader
+
+    // Time returns the current time. If Time is nil, time.Now is used.
+    Time func() time.Time
+
+    // Certificates 证书列表, 服务端按客户端支持的签名算法选择证书,
+    // 客户端在服务端请求证书时选择
+    Certificates []Certificate
+
+    // RootCAs 客户端验证服务端证书使用的根证书
+    RootCAs *x509.CertPool
+
+    // ServerName 用于验证服务端证书的主机名, 并作为 SNI 发送
+    ServerName string
+
+    // ClientAuth 服务端对客户端证书的策略
+    ClientAuth ClientAuthType
+
+    // ClientCAs 服务端验证客户端证书使用的根证书
+    ClientCAs *x509.CertPool
+
+    // InsecureSkipVerify 跳过对端证书验证
+    InsecureSkipVerify bool
+
+    // CipherSuites 支持的密码套件, 为空时使用默认列表
+    CipherSuites []uint16
+
+    // CurvePreferences 支持的密钥交换曲线, 按优先级排列.
+    // 客户端只发送第一个曲线的 key share
+    CurvePreferences []CurveID
+
+    // SignatureSchemes 支持的签名算法, 为空时使用默认列表
+    SignatureSchemes []SignatureScheme
+}
+
+// Clone returns a shallow clone of c.
+func (c *Config) Clone() *Config {
+    if c == nil {
+        return nil
+    }
+
+    cc := *c
+    return &cc
+}
+
+func (c *Config) rand() io.Reader {
+    if c.Rand == nil {
+        return rand.Reader
+    }
+
+    return c.Rand
+}
+
+func (c *Config) time() time.Time {
+    if c.Time == nil {
+        return time.Now()
+    }
+
+    return c.Time()
+}
+
+func (c *Config) cipherSuites() []uint16 {
+    if len(c.CipherSuites) == 0 {
+        return defaultCipherSuites
+    }
+
+    return c.CipherSuites
+}
+
+func (c *Config) curvePreferences() []CurveID {
+    if len(c.CurvePreferences) == 0 {
+        return defaultCurvePreferences
+    }
+
+    return c.CurvePreferences
+}
+
+func (c *Config) supportsCurve(curve CurveID) bool {
+    for _, cc := range c.curvePreferences() {
+        if cc == curve {
+            return true
+        }
+    }
+
+    return false
+}
+
+func (c *Config) signatureSchemes() []SignatureScheme {
+    if len(c.SignatureSchemes) == 0 {
+        return defaultSignatureSchemes
+    }
+
+    return c.SignatureSchemes
+}
@@ -0,0 +1,944 @@
+package tls13
+
+import (
+    "io"
+    "net"
+    "sync"
+    "time"
+    "bytes"
+    "errors"
+    "fmt"
+    "crypto/hmac"
+    "crypto/cipher"
+    "sync/atomic"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+// A Conn represents a secured connection.
+// It implements the net.Conn interface.
+type Conn struct {
+    // constant
+    conn        net.Conn
+    isClient    bool
+    handshakeFn func() error // (*Conn).clientHandshake or serverHandshake
+
+    // isHandshakeComplete is true if the connection is currently transferring
+    // application data (i.e. is not currently processing a handshake).
+    isHandshakeComplete atomic.Bool
+
+    // constant after handshake; protected by handshakeMutex
+    handshakeMutex sync.Mutex
+    handshakeErr   error   // error resulting from handshake
+    vers           uint16  // TLS version
+    haveVers       bool    // version has been negotiated
+    config         *Config // configuration passed to constructor
+    cipherSuite    uint16
+    curveID        CurveID
+
+    peerCertificates []*x509.Certificate
+    verifiedChains   [][]*x509.Certificate
+    serverName       string
+
+    // input/output
+    in, out   halfConn
+    rawInput  bytes.Buffer // raw input, starting with a record header
+    input     bytes.Reader // application data waiting to be read, from rawInput.Next
+    hand      bytes.Buffer // handshake data waiting to be read
+    buffering bool         // whether records are buffered in sendBuf
+    sendBuf   []byte       // a buffer of records waiting to be sent
+
+    // retryCount counts the number of consecutive non-advancing records
+    // received by Conn.readRecord.
+    retryCount int
+
+    // activeCall indicates whether Close has been call in the low bit.
+    // the rest of the bits are the number of goroutines in Conn.Write.
+    activeCall atomic.Int32
+
+    closeNotifyErr  error
+    closeNotifySent bool
+
+    tmp [16]byte
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+    return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+    return c.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read and write deadlines associated with the connection.
+// A zero value for t means Read and Write will not time out.
+// After a Write has timed out, the TLS state is corrupt and all future writes will return the same error.
+func (c *Conn) SetDeadline(t time.Time) error {
+    return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline on the underlying connection.
+// A zero value for t means Read will not time out.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+    return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline on the underlying connection.
+// A zero value for t means Write will not time out.
+// After a Write has timed out, the TLS state is corrupt and all future writes will return the same error.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+    return c.conn.SetWriteDeadline(t)
+}
+
+// NetConn returns the underlying connection that is wrapped by c.
+func (c *Conn) NetConn() net.Conn {
+    return c.conn
+}
+
+// A halfConn represents one direction of the record layer
+// connection, either sending or receiving.
+type halfConn struct {
+    sync.Mutex
+
+    err    error       // first permanent error
+    cipher cipher.AEAD // AEAD with the traffic key, nil before the handshake keys
+    seq    [8]byte     // 64-bit sequence number
+
+    suite         *cipherSuite
+    trafficSecret []byte // current TLS 1.3 traffic secret
+}
+
+type permanentError struct {
+    err net.Error
+}
+
+func (e *permanentError) Error() string   { return e.err.Error() }
+func (e *permanentError) Unwrap() error   { return e.err }
+func (e *permanentError) Timeout() bool   { return e.err.Timeout() }
+func (e *permanentError) Temporary() bool { return false }
+
+func (hc *halfConn) setErrorLocked(err error) error {
+    if e, ok := err.(net.Error); ok {
+        hc.err = &permanentError{err: e}
+    } else {
+        hc.err = err
+    }
+
+    return hc.err
+}
+
+// setTrafficSecret sets the traffic secret and derives the record protection
+// keys from it, resetting the sequence number.
+func (hc *halfConn) setTrafficSecret(suite *cipherSuite, secret []byte) {
+    hc.suite = suite
+    hc.trafficSecret = secret
+
+    key, iv := suite.trafficKey(secret)
+    hc.cipher = suite.aead(key, iv)
+
+    for i := range hc.seq {
+        hc.seq[i] = 0
+    }
+}
+
+// incSeq increments the sequence number.
+func (hc *halfConn) incSeq() {
+    for i := 7; i >= 0; i-- {
+        hc.seq[i]++
+        if hc.seq[i] != 0 {
+            return
+        }
+    }
+
+    // Not allowed to let sequence number wrap.
+    // Instead, must send a KeyUpdate before it does.
+    // Not likely enough to bother.
+    panic("tls13: sequence number wraparound")
+}
+
+// decrypt authenticates and decrypts the record if protection is active at
+// this stage. The returned plaintext might overlap with the input.
+func (hc *halfConn) decrypt(record []byte) ([]byte, recordType, error) {
+    typ := recordType(record[0])
+    payload := record[recordHeaderLen:]
+
+    // In TLS 1.3, change_cipher_spec messages are to be ignored without being
+    // decrypted. See RFC 8446, Appendix D.4.
+    if typ == recordTypeChangeCipherSpec {
+        return payload, typ, nil
+    }
+
+    if hc.cipher == nil {
+        return payload, typ, nil
+    }
+
+    if typ != recordTypeApplicationData {
+        return nil, 0, alertUnexpectedMessage
+    }
+
+    if len(payload) > maxCiphertext {
+        return nil, 0, alertRecordOverflow
+    }
+
+    plaintext, err := hc.cipher.Open(payload[:0], hc.seq[:], payload, record[:recordHeaderLen])
+    if err != nil {
+        return nil, 0, alertBadRecordMAC
+    }
+
+    // Remove padding and find the ContentType scanning from the end.
+    i := len(plaintext) - 1
+    for i >= 0 && plaintext[i] == 0 {
+        i--
+    }
+
+    if i < 0 {
+        return nil, 0, alertUnexpectedMessage
+    }
+
+    typ = recordType(plaintext[i])
+    plaintext = plaintext[:i]
+
+    hc.incSeq()
+
+    return plaintext, typ, nil
+}
+
+// sliceForAppend extends the input slice by n bytes. head is the full extended
+// slice, while tail is the appended part. If the original slice has sufficient
+// capacity no allocation is performed.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+    if total := len(in) + n; cap(in) >= total {
+        head = in[:total]
+    } else {
+        head = make([]byte, total)
+        copy(head, in)
+    }
+
+    tail = head[len(in):]
+    return
+}
+
+// encrypt encrypts payload, adding the content type and the AEAD tag, and
+// appends it to record, which must already contain the record header.
+func (hc *halfConn) encrypt(record, payload []byte) []byte {
+    if hc.cipher == nil {
+        return append(record, payload...)
+    }
+
+    typ := record[0]
+
+    // TLSInnerPlaintext, 实际类型放在数据后面
+    var plaintext []byte
+    record, plaintext = sliceForAppend(record, len(payload)+1)
+    copy(plaintext, payload)
+    plaintext[len(payload)] = typ
+
+    record = record[:recordHeaderLen]
+
+    // The outer record type is always application data.
+    record[0] = byte(recordTypeApplicationData)
+
+    n := len(payload) + 1 + hc.cipher.Overhead()
+    record[3] = byte(n >> 8)
+    record[4] = byte(n)
+
+    record = hc.cipher.Seal(record, hc.seq[:], plaintext, record[:recordHeaderLen])
+    hc.incSeq()
+
+    return record
+}
+
+// RecordHeaderError is returned when a TLS record header is invalid.
+type RecordHeaderError struct {
+    // Msg contains a human readable string that describes the error.
+    Msg string
+    // RecordHeader contains the five bytes of TLS record header that
+    // triggered the error.
+    RecordHeader [5]byte
+    // Conn provides the underlying net.Conn in the case that a client
+    // sent an initial handshake that didn't look like TLS.
+    // It is nil if there's already been a handshake or a TLS alert has
+    // been written to the connection.
+    Conn net.Conn
+}
+
+func (e RecordHeaderError) Error() string {
+    return "tls13: " + e.Msg
+}
+
+func (c *Conn) newRecordHeaderError(conn net.Conn, msg string) (err RecordHeaderError) {
+    err.Msg = msg
+    err.Conn = conn
+    copy(err.RecordHeader[:], c.rawInput.Bytes())
+    return err
+}
+
+// readRecord reads one or more TLS records from the connection and
+// updates the record layer state. Some invariants:
+//   - c.in must be locked
+//   - c.input must be empty
+//
+// One and only one of the following will happen:
+//   - c.hand grows
+//   - c.input is set
+//   - an error is returned
+func (c *Conn) readRecord() error {
+    if c.in.err != nil {
+        return c.in.err
+    }
+
+    handshakeComplete := c.isHandshakeComplete.Load()
+
+    // This function modifies c.rawInput, which owns the c.input memory.
+    if c.input.Len() != 0 {
+        return c.in.setErrorLocked(errors.New("tls13: internal error: attempted to read record with pending application data"))
+    }
+    c.input.Reset(nil)
+
+    // Read header, payload.
+    if err := c.readFromUntil(c.conn, recordHeaderLen); err != nil {
+        // RFC 8446, Section 6.1 suggests that EOF without an alertCloseNotify
+        // is an error, but popular web sites seem to do this, so we accept it
+        // if and only if at the record boundary.
+        if err == io.ErrUnexpectedEOF && c.rawInput.Len() == 0 {
+            err = io.EOF
+        }
+
+        if e, ok := err.(net.Error); !ok || !e.Temporary() {
+            c.in.setErrorLocked(err)
+        }
+
+        return err
+    }
+
+    hdr := c.rawInput.Bytes()[:recordHeaderLen]
+    typ := recordType(hdr[0])
+
+    vers := uint16(hdr[1])<<8 | uint16(hdr[2])
+    n := int(hdr[3])<<8 | int(hdr[4])
+
+    // legacy_record_version 只检查主版本号
+    if !c.haveVers {
+        // First message, be extra suspicious: this might not be a TLS
+        // client. Bail out before reading a full 'body', if possible.
+        if (typ != recordTypeAlert && typ != recordTypeHandshake) || vers>>8 != 3 {
+            return c.in.setErrorLocked(c.newRecordHeaderError(c.conn, "first record does not look like a TLS handshake"))
+        }
+    } else if vers>>8 != 3 {
+        c.sendAlert(alertProtocolVersion)
+        msg := fmt.Sprintf("received record with version %x", vers)
+        return c.in.setErrorLocked(c.newRecordHeaderError(nil, msg))
+    }
+
+    if n > maxCiphertext {
+        c.sendAlert(alertRecordOverflow)
+        msg := fmt.Sprintf("oversized record received with length %d", n)
+        return c.in.setErrorLocked(c.newRecordHeaderError(nil, msg))
+    }
+
+    if err := c.readFromUntil(c.conn, recordHeaderLen+n); err != nil {
+        if e, ok := err.(net.Error); !ok || !e.Temporary() {
+            c.in.setErrorLocked(err)
+        }
+
+        return err
+    }
+
+    // Process message.
+    record := c.rawInput.Next(recordHeaderLen + n)
+    data, typ, err := c.in.decrypt(record)
+    if err != nil {
+        return c.in.setErrorLocked(c.sendAlert(err.(alert)))
+    }
+
+    if len(data) > maxPlaintext {
+        return c.in.setErrorLocked(c.sendAlert(alertRecordOverflow))
+    }
+
+    // Application Data messages are always protected.
+    if c.in.cipher == nil && typ == recordTypeApplicationData {
+        return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+    }
+
+    if typ != recordTypeAlert && typ != recordTypeChangeCipherSpec && len(data) > 0 {
+        // This is a state-advancing message: reset the retry count.
+        c.retryCount = 0
+    }
+
+    switch typ {
+        default:
+            return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+
+        case recordTypeAlert:
+            if len(data) != 2 {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            if alert(data[1]) == alertCloseNotify {
+                return c.in.setErrorLocked(io.EOF)
+            }
+
+            // 除 close_notify 和 user_canceled 外, TLS 1.3 的告警都是致命的
+            if alert(data[1]) == alertUserCanceled {
+                return c.retryReadRecord()
+            }
+
+            return c.in.setErrorLocked(&net.OpError{Op: "remote error", Err: alert(data[1])})
+
+        case recordTypeChangeCipherSpec:
+            // 兼容模式的 change_cipher_spec 只能在握手期间出现并直接忽略
+            if len(data) != 1 || data[0] != 1 || handshakeComplete {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            // Handshake messages are not allowed to fragment across the CCS.
+            if c.hand.Len() > 0 {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            return c.retryReadRecord()
+
+        case recordTypeApplicationData:
+            if !handshakeComplete {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            // Ignore a limited number of empty records.
+            if len(data) == 0 {
+                return c.retryReadRecord()
+            }
+
+            // Note that data is owned by c.rawInput, following the Next call above,
+            // to avoid copying the plaintext. This is safe because c.rawInput is
+            // not read from or written to until c.input is drained.
+            c.input.Reset(data)
+
+        case recordTypeHandshake:
+            if len(data) == 0 {
+                return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+            }
+
+            c.hand.Write(data)
+    }
+
+    return nil
+}
+
+// retryReadRecord recurs into readRecord to drop a non-advancing record, like
+// a warning alert, empty application_data, or a change_cipher_spec in TLS 1.3.
+func (c *Conn) retryReadRecord() error {
+    c.retryCount++
+    if c.retryCount > maxUselessRecords {
+        c.sendAlert(alertUnexpectedMessage)
+        return c.in.setErrorLocked(errors.New("tls13: too many ignored records"))
+    }
+
+    return c.readRecord()
+}
+
+// atLeastReader reads from R, stopping with EOF once at least N bytes have been
+// read. It is different from an io.LimitedReader in that it doesn't cut short
+// the last Read call, and in that it considers an early EOF an error.
+type atLeastReader struct {
+    R io.Reader
+    N int64
+}
+
+func (r *atLeastReader) Read(p []byte) (int, error) {
+    if r.N <= 0 {
+        return 0, io.EOF
+    }
+
+    n, err := r.R.Read(p)
+    r.N -= int64(n) // won't underflow unless len(p) >= n > 9223372036854775809
+    if r.N > 0 && err == io.EOF {
+        return n, io.ErrUnexpectedEOF
+    }
+
+    if r.N <= 0 && err == nil {
+        return n, io.EOF
+    }
+
+    return n, err
+}
+
+// readFromUntil reads from r into c.rawInput until c.rawInput contains
+// at least n bytes or else returns an error.
+func (c *Conn) readFromUntil(r io.Reader, n int) error {
+    if c.rawInput.Len() >= n {
+        return nil
+    }
+
+    needs := n - c.rawInput.Len()
+    // There might be extra input waiting on the wire. Make a best effort
+    // attempt to fetch it so that it can be used in (*Conn).Read to
+    // "predict" closeNotify alerts.
+    c.rawInput.Grow(needs + bytes.MinRead)
+    _, err := c.rawInput.ReadFrom(&atLeastReader{r, int64(needs)})
+
+    return err
+}
+
+// sendAlertLocked sends a TLS alert message.
+func (c *Conn) sendAlertLocked(err alert) error {
+    switch err {
+        case alertCloseNotify, alertUserCanceled:
+            c.tmp[0] = alertLevelWarning
+        default:
+            c.tmp[0] = alertLevelError
+    }
+
+    c.tmp[1] = byte(err)
+
+    _, writeErr := c.writeRecordLocked(recordTypeAlert, c.tmp[0:2])
+    if err == alertCloseNotify {
+        // closeNotify is a special case in that it isn't an error.
+        return writeErr
+    }
+
+    return c.out.setErrorLocked(&net.OpError{Op: "local error", Err: err})
+}
+
+// sendAlert sends a TLS alert message.
+func (c *Conn) sendAlert(err alert) error {
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    return c.sendAlertLocked(err)
+}
+
+func (c *Conn) write(data []byte) (int, error) {
+    if c.buffering {
+        c.sendBuf = append(c.sendBuf, data...)
+        return len(data), nil
+    }
+
+    return c.conn.Write(data)
+}
+
+func (c *Conn) flush() (int, error) {
+    if len(c.sendBuf) == 0 {
+        return 0, nil
+    }
+
+    n, err := c.conn.Write(c.sendBuf)
+    c.sendBuf = nil
+    c.buffering = false
+
+    return n, err
+}
+
+// writeRecordLocked writes a TLS record with the given type and payload to the
+// connection and updates the record layer state.
+func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
+    var n int
+    for len(data) > 0 {
+        m := len(data)
+        if m > maxPlaintext {
+            m = maxPlaintext
+        }
+
+        outBuf := make([]byte, recordHeaderLen, recordHeaderLen+m+1+64)
+        outBuf[0] = byte(typ)
+
+        // legacy_record_version, 第一个 ClientHello 使用 TLS 1.0
+        vers := uint16(VersionTLS12)
+        if !c.haveVers && c.isClient {
+            vers = 0x0301
+        }
+
+        outBuf[1] = byte(vers >> 8)
+        outBuf[2] = byte(vers)
+        outBuf[3] = byte(m >> 8)
+        outBuf[4] = byte(m)
+
+        outBuf = c.out.encrypt(outBuf, data[:m])
+
+        if _, err := c.write(outBuf); err != nil {
+            return n, err
+        }
+
+        n += m
+        data = data[m:]
+    }
+
+    return n, nil
+}
+
+// writeHandshakeRecord writes a handshake message to the connection and updates
+// the record layer state. If transcript is non-nil the marshalled message is
+// written to it.
+func (c *Conn) writeHandshakeRecord(msg handshakeMessage, transcript io.Writer) (int, error) {
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    data, err := msg.marshal()
+    if err != nil {
+        return 0, err
+    }
+
+    if transcript != nil {
+        transcript.Write(data)
+    }
+
+    return c.writeRecordLocked(recordTypeHandshake, data)
+}
+
+// readHandshake reads the next handshake message from
+// the record layer. If transcript is non-nil, the message
+// is written to the passed transcript.
+func (c *Conn) readHandshake(transcript io.Writer) (any, error) {
+    for c.hand.Len() < 4 {
+        if err := c.readRecord(); err != nil {
+            return nil, err
+        }
+    }
+
+    data := c.hand.Bytes()
+    n := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+    if n > maxHandshake {
+        c.sendAlertLocked(alertInternalError)
+        return nil, c.in.setErrorLocked(fmt.Errorf("tls13: handshake message of length %d bytes exceeds maximum of %d bytes", n, maxHandshake))
+    }
+
+    for c.hand.Len() < 4+n {
+        if err := c.readRecord(); err != nil {
+            return nil, err
+        }
+    }
+
+    data = c.hand.Next(4 + n)
+
+    var m handshakeMessage
+    switch data[0] {
+        case typeClientHello:
+            m = new(clientHelloMsg)
+        case typeServerHello:
+            m = new(serverHelloMsg)
+        case typeNewSessionTicket:
+            m = new(newSessionTicketMsg)
+        case typeEncryptedExtensions:
+            m = new(encryptedExtensionsMsg)
+        case typeCertificate:
+            m = new(certificateMsg)
+        case typeCertificateRequest:
+            m = new(certificateRequestMsg)
+        case typeCertificateVerify:
+            m = new(certificateVerifyMsg)
+        case typeFinished:
+            m = new(finishedMsg)
+        case typeKeyUpdate:
+            m = new(keyUpdateMsg)
+        default:
+            return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+    }
+
+    // The handshake message unmarshalers
+    // expect to be able to keep references to data,
+    // so pass in a fresh copy that won't be overwritten.
+    data = append([]byte(nil), data...)
+
+    if !m.unmarshal(data) {
+        return nil, c.in.setErrorLocked(c.sendAlert(alertDecodeError))
+    }
+
+    if transcript != nil {
+        transcript.Write(data)
+    }
+
+    return m, nil
+}
+
+var (
+    errShutdown = errors.New("tls13: protocol is shutdown")
+)
+
+// Write writes data to the connection.
+func (c *Conn) Write(b []byte) (int, error) {
+    // interlock with Close below
+    for {
+        x := c.activeCall.Load()
+        if x&1 != 0 {
+            return 0, net.ErrClosed
+        }
+
+        if c.activeCall.CompareAndSwap(x, x+2) {
+            break
+        }
+    }
+    defer c.activeCall.Add(-2)
+
+    if err := c.Handshake(); err != nil {
+        return 0, err
+    }
+
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    if err := c.out.err; err != nil {
+        return 0, err
+    }
+
+    if !c.isHandshakeComplete.Load() {
+        return 0, alertInternalError
+    }
+
+    if c.closeNotifySent {
+        return 0, errShutdown
+    }
+
+    n, err := c.writeRecordLocked(recordTypeApplicationData, b)
+
+    return n, c.out.setErrorLocked(err)
+}
+
+// handlePostHandshakeMessage processes a handshake message arrived after the
+// handshake is complete, NewSessionTicket or KeyUpdate in TLS 1.3.
+func (c *Conn) handlePostHandshakeMessage() error {
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    c.retryCount++
+    if c.retryCount > maxUselessRecords {
+        c.sendAlert(alertUnexpectedMessage)
+        return c.in.setErrorLocked(errors.New("tls13: too many non-advancing records"))
+    }
+
+    switch msg := msg.(type) {
+        case *newSessionTicketMsg:
+            // 不支持会话恢复, 忽略
+            return nil
+        case *keyUpdateMsg:
+            return c.handleKeyUpdate(msg)
+    }
+
+    c.sendAlert(alertUnexpectedMessage)
+    return fmt.Errorf("tls13: received unexpected handshake message of type %T", msg)
+}
+
+func (c *Conn) handleKeyUpdate(keyUpdate *keyUpdateMsg) error {
+    suite := c.in.suite
+    if suite == nil {
+        return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+    }
+
+    newSecret := suite.nextTrafficSecret(c.in.trafficSecret)
+    c.in.setTrafficSecret(suite, newSecret)
+
+    if keyUpdate.updateRequested {
+        c.out.Lock()
+        defer c.out.Unlock()
+
+        msg := &keyUpdateMsg{}
+        msgBytes, err := msg.marshal()
+        if err != nil {
+            return err
+        }
+
+        _, err = c.writeRecordLocked(recordTypeHandshake, msgBytes)
+        if err != nil {
+            // Surface the error at the next write.
+            c.out.setErrorLocked(err)
+            return nil
+        }
+
+        newSecret := suite.nextTrafficSecret(c.out.trafficSecret)
+        c.out.setTrafficSecret(suite, newSecret)
+    }
+
+    return nil
+}
+
+// Read reads data from the connection.
+func (c *Conn) Read(b []byte) (int, error) {
+    if err := c.Handshake(); err != nil {
+        return 0, err
+    }
+
+    if len(b) == 0 {
+        // Put this after Handshake, in case people were calling
+        // Read(nil) for the side effect of the Handshake.
+        return 0, nil
+    }
+
+    c.in.Lock()
+    defer c.in.Unlock()
+
+    for c.input.Len() == 0 {
+        if err := c.readRecord(); err != nil {
+            return 0, err
+        }
+
+        for c.hand.Len() > 0 {
+            if err := c.handlePostHandshakeMessage(); err != nil {
+                return 0, err
+            }
+        }
+    }
+
+    n, _ := c.input.Read(b)
+
+    // If a close-notify alert is waiting, read it so that we can return (n,
+    // EOF) instead of (n, nil), to signal to the HTTP response reading
+    // goroutine that the connection is now closed. This eliminates a race
+    // where the HTTP response reading goroutine would otherwise not observe
+    // the EOF until its next read, by which time a client goroutine might
+    // have already tried to reuse the HTTP connection for a new request.
+    // See https://golang.org/cl/76400046 and https://golang.org/issue/3514
+    if n != 0 && c.input.Len() == 0 && c.rawInput.Len() > 0 &&
+        recordType(c.rawInput.Bytes()[0]) == recordTypeAlert {
+        if err := c.readRecord(); err != nil {
+            return n, err // will be io.EOF on closeNotify
+        }
+    }
+
+    return n, nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+    // Interlock with Conn.Write above.
+    var x int32
+    for {
+        x = c.activeCall.Load()
+        if x&1 != 0 {
+            return net.ErrClosed
+        }
+
+        if c.activeCall.CompareAndSwap(x, x|1) {
+            break
+        }
+    }
+
+    if x != 0 {
+        // io.Writer and io.Closer should not be used concurrently.
+        // If Close is called while a Write is currently in-flight,
+        // interpret that as a sign that this Close is really just
+        // being used to break the Write and/or clean up resources and
+        // avoid sending the alertCloseNotify, which may block
+        // waiting on handshakeMutex or the c.out mutex.
+        return c.conn.Close()
+    }
+
+    var alertErr error
+    if c.isHandshakeComplete.Load() {
+        if err := c.closeNotify(); err != nil {
+            alertErr = fmt.Errorf("tls13: failed to send closeNotify alert (but connection was closed anyway): %w", err)
+        }
+    }
+
+    if err := c.conn.Close(); err != nil {
+        return err
+    }
+
+    return alertErr
+}
+
+var errEarlyCloseWrite = errors.New("tls13: CloseWrite called before handshake complete")
+
+// CloseWrite shuts down the writing side of the connection. It should only be
+// called once the handshake has completed and does not call CloseWrite on the
+// underlying connection. Most callers should just use Close.
+func (c *Conn) CloseWrite() error {
+    if !c.isHandshakeComplete.Load() {
+        return errEarlyCloseWrite
+    }
+
+    return c.closeNotify()
+}
+
+func (c *Conn) closeNotify() error {
+    c.out.Lock()
+    defer c.out.Unlock()
+
+    if !c.closeNotifySent {
+        // Set a Write Deadline to prevent possibly blocking forever.
+        c.SetWriteDeadline(time.Now().Add(time.Second * 5))
+        c.closeNotifyErr = c.sendAlertLocked(alertCloseNotify)
+        c.closeNotifySent = true
+        // Any subsequent writes will fail.
+        c.SetWriteDeadline(time.Now())
+    }
+
+    return c.closeNotifyErr
+}
+
+// Handshake runs the client or server handshake
+// protocol if it has not yet been run.
+//
+// Most uses of this package need not call Handshake explicitly: the
+// first Read or Write will call it automatically.
+func (c *Conn) Handshake() error {
+    c.handshakeMutex.Lock()
+    defer c.handshakeMutex.Unlock()
+
+    if err := c.handshakeErr; err != nil {
+        return err
+    }
+
+    if c.isHandshakeComplete.Load() {
+        return nil
+    }
+
+    c.in.Lock()
+    defer c.in.Unlock()
+
+    c.handshakeErr = c.handshakeFn()
+    if c.handshakeErr == nil {
+        c.isHandshakeComplete.Store(true)
+    } else {
+        // If an error occurred during the handshake try to flush the
+        // alert that might be left in the buffer.
+        c.flush()
+    }
+
+    return c.handshakeErr
+}
+
+// ConnectionState returns basic TLS details about the connection.
+func (c *Conn) ConnectionState() ConnectionState {
+    c.handshakeMutex.Lock()
+    defer c.handshakeMutex.Unlock()
+
+    var state ConnectionState
+    state.HandshakeComplete = c.isHandshakeComplete.Load()
+    state.Version = c.vers
+    state.CipherSuite = c.cipherSuite
+    state.CurveID = c.curveID
+    state.ServerName = c.serverName
+    state.PeerCertificates = c.peerCertificates
+    state.VerifiedChains = c.verifiedChains
+
+    return state
+}
+
+// VerifyHostname checks that the peer certificate chain is valid for
+// connecting to host. If so, it returns nil; if not, it returns an error
+// describing the problem.
+func (c *Conn) VerifyHostname(host string) error {
+    c.handshakeMutex.Lock()
+    defer c.handshakeMutex.Unlock()
+
+    if !c.isClient {
+        return errors.New("tls13: VerifyHostname called on TLS server connection")
+    }
+
+    if !c.isHandshakeComplete.Load() {
+        return errors.New("tls13: handshake has not yet been performed")
+    }
+
+    if len(c.verifiedChains) == 0 {
+        return errors.New("tls13: handshake did not verify certificate chain")
+    }
+
+    return c.peerCertificates[0].VerifyHostname(host)
+}
+
+// 比较 finished 数据
+func verifyFinished(expected, got []byte) bool {
+    return hmac.Equal(expected, got)
+}
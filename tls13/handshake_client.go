@@ -0,0 +1,578 @@
+package tls13
+
+import (
+    "io"
+    "net"
+    "hash"
+    "bytes"
+    "errors"
+    "fmt"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+type clientHandshakeState struct {
+    c            *Conn
+    hello        *clientHelloMsg
+    serverHello  *serverHelloMsg
+    keyShare     keyShare
+    suite        *cipherSuite
+    transcript   hash.Hash
+    certReq      *certificateRequestMsg
+    masterSecret []byte
+
+    clientHandshakeSecret []byte
+    serverHandshakeSecret []byte
+    trafficSecret         []byte // client_application_traffic_secret_0
+}
+
+func (c *Conn) clientHandshake() error {
+    if c.config == nil {
+        c.config = &Config{}
+    }
+
+    config := c.config
+    if len(config.ServerName) == 0 && !config.InsecureSkipVerify {
+        return errors.New("tls13: either ServerName or InsecureSkipVerify must be specified in the tls13.Config")
+    }
+
+    hello, keyShare, err := c.makeClientHello()
+    if err != nil {
+        return err
+    }
+
+    c.serverName = hello.serverName
+
+    // 协商出密码套件前无法确定摘要算法, 先不写入 transcript
+    if _, err := c.writeHandshakeRecord(hello, nil); err != nil {
+        return err
+    }
+
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    serverHello, ok := msg.(*serverHelloMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(serverHello, msg)
+    }
+
+    hs := &clientHandshakeState{
+        c:           c,
+        hello:       hello,
+        serverHello: serverHello,
+        keyShare:    keyShare,
+    }
+
+    return hs.handshake()
+}
+
+func (c *Conn) makeClientHello() (*clientHelloMsg, keyShare, error) {
+    config := c.config
+
+    hello := &clientHelloMsg{
+        vers:                         VersionTLS12,
+        random:                       make([]byte, 32),
+        sessionId:                    make([]byte, 32),
+        cipherSuites:                 config.cipherSuites(),
+        compressionMethods:           []uint8{compressionNone},
+        supportedCurves:              config.curvePreferences(),
+        supportedSignatureAlgorithms: config.signatureSchemes(),
+        supportedVersions:            []uint16{VersionTLS13},
+    }
+
+    // IP 地址不作为 SNI 发送
+    if net.ParseIP(config.ServerName) == nil {
+        hello.serverName = config.ServerName
+    }
+
+    if _, err := io.ReadFull(config.rand(), hello.random); err != nil {
+        return nil, nil, errors.New("tls13: short read from Rand: " + err.Error())
+    }
+
+    // 兼容模式的 legacy_session_id, RFC 8446 D.4
+    if _, err := io.ReadFull(config.rand(), hello.sessionId); err != nil {
+        return nil, nil, errors.New("tls13: short read from Rand: " + err.Error())
+    }
+
+    // 只发送首选曲线的 key share, 服务端不支持时通过 HelloRetryRequest 重试
+    ks, err := generateKeyShare(config.rand(), hello.supportedCurves[0])
+    if err != nil {
+        return nil, nil, err
+    }
+
+    hello.keyShares = []keyShareEntry{{group: ks.curveID(), data: ks.publicKey()}}
+
+    return hello, ks, nil
+}
+
+func (hs *clientHandshakeState) handshake() error {
+    c := hs.c
+
+    c.vers = VersionTLS13
+    c.haveVers = true
+
+    if err := hs.checkServerHelloOrHRR(); err != nil {
+        return err
+    }
+
+    hs.transcript = hs.suite.hash()
+    hs.transcript.Write(hs.hello.raw)
+
+    if bytes.Equal(hs.serverHello.random, helloRetryRequestRandom) {
+        if err := hs.processHelloRetryRequest(); err != nil {
+            return err
+        }
+    }
+
+    hs.transcript.Write(hs.serverHello.raw)
+
+    c.buffering = true
+
+    if err := hs.establishHandshakeKeys(); err != nil {
+        return err
+    }
+
+    if err := hs.readServerParameters(); err != nil {
+        return err
+    }
+
+    if err := hs.readServerCertificate(); err != nil {
+        return err
+    }
+
+    if err := hs.readServerFinished(); err != nil {
+        return err
+    }
+
+    if err := hs.sendClientCertificate(); err != nil {
+        return err
+    }
+
+    if err := hs.sendClientFinished(); err != nil {
+        return err
+    }
+
+    if _, err := c.flush(); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+// checkServerHelloOrHRR does validity checks that apply to both ServerHello and
+// HelloRetryRequest messages. It sets hs.suite.
+func (hs *clientHandshakeState) checkServerHelloOrHRR() error {
+    c := hs.c
+
+    if hs.serverHello.supportedVersion == 0 {
+        c.sendAlert(alertMissingExtension)
+        return errors.New("tls13: server selected TLS 1.3 using the legacy version field")
+    }
+
+    if hs.serverHello.supportedVersion != VersionTLS13 {
+        c.sendAlert(alertProtocolVersion)
+        return errors.New("tls13: server selected an invalid version")
+    }
+
+    if hs.serverHello.vers != VersionTLS12 {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server sent an incorrect legacy version")
+    }
+
+    if hs.serverHello.compressionMethod != compressionNone {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server selected unsupported compression format")
+    }
+
+    if !bytes.Equal(hs.hello.sessionId, hs.serverHello.sessionId) {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server did not echo the legacy session ID")
+    }
+
+    selectedSuite := mutualCipherSuite(hs.hello.cipherSuites, hs.serverHello.cipherSuite)
+    if hs.suite != nil && selectedSuite != hs.suite {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server changed cipher suite after a HelloRetryRequest")
+    }
+
+    if selectedSuite == nil {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server chose an unconfigured cipher suite")
+    }
+
+    hs.suite = selectedSuite
+    c.cipherSuite = hs.suite.id
+
+    return nil
+}
+
+// processHelloRetryRequest handles the HRR in hs.serverHello, modifies and
+// resends hs.hello, and reads the new ServerHello into hs.serverHello.
+func (hs *clientHandshakeState) processHelloRetryRequest() error {
+    c := hs.c
+
+    // The first ClientHello gets double-hashed into the transcript upon a
+    // HelloRetryRequest. See RFC 8446, Section 4.4.1.
+    chHash := hs.transcript.Sum(nil)
+    hs.transcript.Reset()
+    hs.transcript.Write([]byte{typeMessageHash, 0, 0, uint8(len(chHash))})
+    hs.transcript.Write(chHash)
+    hs.transcript.Write(hs.serverHello.raw)
+
+    curveID := hs.serverHello.selectedGroup
+    if curveID == 0 {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server sent an unnecessary HelloRetryRequest message")
+    }
+
+    // The only HelloRetryRequest extensions we support are key_share and
+    // cookie, and clients must abort the handshake if the HRR would not
+    // result in any change in the ClientHello.
+    supported := false
+    for _, curve := range hs.hello.supportedCurves {
+        if curve == curveID {
+            supported = true
+            break
+        }
+    }
+
+    if !supported || curveID == hs.keyShare.curveID() {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server selected unsupported group")
+    }
+
+    ks, err := generateKeyShare(c.config.rand(), curveID)
+    if err != nil {
+        c.sendAlert(alertInternalError)
+        return err
+    }
+
+    hs.keyShare = ks
+
+    hello := *hs.hello
+    hello.raw = nil
+    hello.keyShares = []keyShareEntry{{group: ks.curveID(), data: ks.publicKey()}}
+    hello.cookie = hs.serverHello.cookie
+    hs.hello = &hello
+
+    if _, err := c.writeHandshakeRecord(hs.hello, hs.transcript); err != nil {
+        return err
+    }
+
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    serverHello, ok := msg.(*serverHelloMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(serverHello, msg)
+    }
+
+    hs.serverHello = serverHello
+
+    if bytes.Equal(serverHello.random, helloRetryRequestRandom) {
+        c.sendAlert(alertUnexpectedMessage)
+        return errors.New("tls13: server sent two HelloRetryRequest messages")
+    }
+
+    return hs.checkServerHelloOrHRR()
+}
+
+// 计算握手密钥
+func (hs *clientHandshakeState) establishHandshakeKeys() error {
+    c := hs.c
+
+    if len(hs.serverHello.cookie) != 0 {
+        c.sendAlert(alertUnsupportedExtension)
+        return errors.New("tls13: server sent a cookie in a normal ServerHello")
+    }
+
+    if hs.serverHello.selectedGroup != 0 {
+        c.sendAlert(alertDecodeError)
+        return errors.New("tls13: malformed key_share extension")
+    }
+
+    if hs.serverHello.serverShare.group == 0 {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server did not send a key share")
+    }
+
+    if hs.serverHello.serverShare.group != hs.keyShare.curveID() {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: server selected unsupported group")
+    }
+
+    sharedKey, err := hs.keyShare.sharedKey(hs.serverHello.serverShare.data)
+    if err != nil {
+        c.sendAlert(alertIllegalParameter)
+        return err
+    }
+
+    c.curveID = hs.keyShare.curveID()
+
+    handshakeSecret := hs.suite.handshakeSecret(sharedKey)
+
+    hs.clientHandshakeSecret = hs.suite.deriveSecret(handshakeSecret, clientHandshakeTrafficLabel, hs.transcript)
+    hs.serverHandshakeSecret = hs.suite.deriveSecret(handshakeSecret, serverHandshakeTrafficLabel, hs.transcript)
+
+    c.out.setTrafficSecret(hs.suite, hs.clientHandshakeSecret)
+    c.in.setTrafficSecret(hs.suite, hs.serverHandshakeSecret)
+
+    hs.masterSecret = hs.suite.masterSecret(handshakeSecret)
+
+    return nil
+}
+
+func (hs *clientHandshakeState) readServerParameters() error {
+    c := hs.c
+
+    msg, err := c.readHandshake(hs.transcript)
+    if err != nil {
+        return err
+    }
+
+    encryptedExtensions, ok := msg.(*encryptedExtensionsMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(encryptedExtensions, msg)
+    }
+
+    return nil
+}
+
+func (hs *clientHandshakeState) readServerCertificate() error {
+    c := hs.c
+
+    msg, err := c.readHandshake(hs.transcript)
+    if err != nil {
+        return err
+    }
+
+    certReq, ok := msg.(*certificateRequestMsg)
+    if ok {
+        hs.certReq = certReq
+
+        msg, err = c.readHandshake(hs.transcript)
+        if err != nil {
+            return err
+        }
+    }
+
+    certMsg, ok := msg.(*certificateMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(certMsg, msg)
+    }
+
+    if len(certMsg.certificates) == 0 {
+        c.sendAlert(alertDecodeError)
+        return errors.New("tls13: received empty certificates message")
+    }
+
+    if err := c.verifyPeerCertificates(certMsg.certificates, x509.ExtKeyUsageServerAuth, !c.config.InsecureSkipVerify); err != nil {
+        return err
+    }
+
+    // certificateVerifyMsg is included in the transcript, but not until
+    // after we verify the handshake signature, since the state before
+    // this message was sent is used.
+    msg, err = c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    certVerify, ok := msg.(*certificateVerifyMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(certVerify, msg)
+    }
+
+    // See RFC 8446, Section 4.4.3.
+    if !isSupportedSignatureScheme(certVerify.signatureAlgorithm, hs.hello.supportedSignatureAlgorithms) {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: certificate used with invalid signature algorithm")
+    }
+
+    signed := signedMessage(serverSignatureContext, hs.transcript)
+    if err := verifyHandshake(c.peerCertificates[0].PublicKey, certVerify.signatureAlgorithm, signed, certVerify.signature); err != nil {
+        c.sendAlert(alertDecryptError)
+        return errors.New("tls13: invalid signature by the server certificate: " + err.Error())
+    }
+
+    hs.transcript.Write(certVerify.raw)
+
+    return nil
+}
+
+func (hs *clientHandshakeState) readServerFinished() error {
+    c := hs.c
+
+    // finishedMsg is included in the transcript, but not until after we
+    // check the client version, since the state before this message was
+    // sent is used during verification.
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    finished, ok := msg.(*finishedMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(finished, msg)
+    }
+
+    expectedMAC := hs.suite.finishedHash(hs.serverHandshakeSecret, hs.transcript)
+    if !verifyFinished(expectedMAC, finished.verifyData) {
+        c.sendAlert(alertDecryptError)
+        return errors.New("tls13: invalid server finished hash")
+    }
+
+    hs.transcript.Write(finished.raw)
+
+    // Derive secrets that take context through the server Finished.
+    hs.trafficSecret = hs.suite.deriveSecret(hs.masterSecret, clientApplicationTrafficLabel, hs.transcript)
+    serverSecret := hs.suite.deriveSecret(hs.masterSecret, serverApplicationTrafficLabel, hs.transcript)
+    c.in.setTrafficSecret(hs.suite, serverSecret)
+
+    return nil
+}
+
+func (hs *clientHandshakeState) sendClientCertificate() error {
+    c := hs.c
+
+    if hs.certReq == nil {
+        return nil
+    }
+
+    cert, scheme := c.getClientCertificate(hs.certReq)
+
+    certMsg := new(certificateMsg)
+    if cert != nil {
+        certMsg.certificates = cert.Certificate
+    }
+
+    if _, err := c.writeHandshakeRecord(certMsg, hs.transcript); err != nil {
+        return err
+    }
+
+    // If we sent an empty certificate message, skip the CertificateVerify.
+    if cert == nil {
+        return nil
+    }
+
+    certVerify := new(certificateVerifyMsg)
+    certVerify.signatureAlgorithm = scheme
+
+    signed := signedMessage(clientSignatureContext, hs.transcript)
+    sig, err := signHandshake(c.config.rand(), cert.PrivateKey, scheme, signed)
+    if err != nil {
+        c.sendAlert(alertInternalError)
+        return errors.New("tls13: failed to sign handshake: " + err.Error())
+    }
+
+    certVerify.signature = sig
+
+    if _, err := c.writeHandshakeRecord(certVerify, hs.transcript); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+// getClientCertificate 选择服务端签名算法支持的证书
+func (c *Conn) getClientCertificate(certReq *certificateRequestMsg) (*Certificate, SignatureScheme) {
+    for i := range c.config.Certificates {
+        cert := &c.config.Certificates[i]
+
+        leaf, err := cert.leaf()
+        if err != nil {
+            continue
+        }
+
+        scheme, ok := selectSignatureScheme(leaf.PublicKey, c.config.signatureSchemes(), certReq.supportedSignatureAlgorithms)
+        if ok {
+            return cert, scheme
+        }
+    }
+
+    return nil, 0
+}
+
+func (hs *clientHandshakeState) sendClientFinished() error {
+    c := hs.c
+
+    finished := &finishedMsg{
+        verifyData: hs.suite.finishedHash(hs.clientHandshakeSecret, hs.transcript),
+    }
+
+    if _, err := c.writeHandshakeRecord(finished, hs.transcript); err != nil {
+        return err
+    }
+
+    c.out.setTrafficSecret(hs.suite, hs.trafficSecret)
+
+    return nil
+}
+
+// verifyPeerCertificates 解析并验证对端证书链
+func (c *Conn) verifyPeerCertificates(certificates [][]byte, usage x509.ExtKeyUsage, verify bool) error {
+    certs := make([]*x509.Certificate, len(certificates))
+    for i, asn1Data := range certificates {
+        cert, err := x509.ParseCertificate(asn1Data)
+        if err != nil {
+            c.sendAlert(alertBadCertificate)
+            return errors.New("tls13: failed to parse certificate: " + err.Error())
+        }
+
+        certs[i] = cert
+    }
+
+    config := c.config
+    if verify {
+        roots := config.RootCAs
+        dnsName := config.ServerName
+        if !c.isClient {
+            roots = config.ClientCAs
+            dnsName = ""
+        }
+
+        intermediates := x509.NewCertPool()
+        for _, cert := range certs[1:] {
+            intermediates.AddCert(cert)
+        }
+
+        opts := x509.VerifyOptions{
+            Roots:         roots,
+            CurrentTime:   config.time(),
+            DNSName:       dnsName,
+            Intermediates: intermediates,
+            KeyUsages:     []x509.ExtKeyUsage{usage},
+        }
+
+        chains, err := certs[0].Verify(opts)
+        if err != nil {
+            c.sendAlert(alertBadCertificate)
+            return err
+        }
+
+        c.verifiedChains = chains
+    }
+
+    if signatureSchemesForKey(certs[0].PublicKey) == nil {
+        c.sendAlert(alertUnsupportedCertificate)
+        return fmt.Errorf("tls13: unsupported certificate public key type %T", certs[0].PublicKey)
+    }
+
+    c.peerCertificates = certs
+
+    return nil
+}
+
+func unexpectedMessageError(wanted, got any) error {
+    return fmt.Errorf("tls13: received unexpected handshake message of type %T when waiting for %T", got, wanted)
+}
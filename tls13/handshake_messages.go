@@ -0,0 +1,863 @@
+package tls13
+
+import (
+    "golang.org/x/crypto/cryptobyte"
+)
+
+// handshakeMessage 握手消息
+type handshakeMessage interface {
+    marshal() ([]byte, error)
+    unmarshal([]byte) bool
+}
+
+// 添加握手消息头
+func marshalHandshake(typ uint8, f cryptobyte.BuilderContinuation) ([]byte, error) {
+    var b cryptobyte.Builder
+    b.AddUint8(typ)
+    b.AddUint24LengthPrefixed(f)
+
+    return b.Bytes()
+}
+
+// 读取握手消息体
+func readHandshakeBody(data []byte) cryptobyte.String {
+    s := cryptobyte.String(data)
+
+    var body cryptobyte.String
+    if !s.Skip(1) || !s.ReadUint24LengthPrefixed(&body) || !s.Empty() {
+        return nil
+    }
+
+    return body
+}
+
+// keyShareEntry TLS 1.3 Key Share. See RFC 8446, Section 4.2.8.
+type keyShareEntry struct {
+    group CurveID
+    data  []byte
+}
+
+// ==========
+
+type clientHelloMsg struct {
+    raw                          []byte
+    vers                         uint16
+    random                       []byte
+    sessionId                    []byte
+    cipherSuites                 []uint16
+    compressionMethods           []uint8
+    serverName                   string
+    supportedCurves              []CurveID
+    supportedSignatureAlgorithms []SignatureScheme
+    supportedVersions            []uint16
+    keyShares                    []keyShareEntry
+    cookie                       []byte
+}
+
+func (m *clientHelloMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    var exts cryptobyte.Builder
+    if len(m.serverName) > 0 {
+        // RFC 6066, Section 3
+        exts.AddUint16(extensionServerName)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                exts.AddUint8(0) // name_type = host_name
+                exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                    exts.AddBytes([]byte(m.serverName))
+                })
+            })
+        })
+    }
+
+    if len(m.supportedCurves) > 0 {
+        // RFC 4492, sections 5.1.1 and RFC 8446, Section 4.2.7
+        exts.AddUint16(extensionSupportedCurves)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                for _, curve := range m.supportedCurves {
+                    exts.AddUint16(uint16(curve))
+                }
+            })
+        })
+    }
+
+    if len(m.supportedSignatureAlgorithms) > 0 {
+        // RFC 5246, Section 7.4.1.4.1
+        exts.AddUint16(extensionSignatureAlgorithms)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                for _, sigAlgo := range m.supportedSignatureAlgorithms {
+                    exts.AddUint16(uint16(sigAlgo))
+                }
+            })
+        })
+    }
+
+    if len(m.supportedVersions) > 0 {
+        // RFC 8446, Section 4.2.1
+        exts.AddUint16(extensionSupportedVersions)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint8LengthPrefixed(func(exts *cryptobyte.Builder) {
+                for _, vers := range m.supportedVersions {
+                    exts.AddUint16(vers)
+                }
+            })
+        })
+    }
+
+    if len(m.cookie) > 0 {
+        // RFC 8446, Section 4.2.2
+        exts.AddUint16(extensionCookie)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                exts.AddBytes(m.cookie)
+            })
+        })
+    }
+
+    if len(m.keyShares) > 0 {
+        // RFC 8446, Section 4.2.8
+        exts.AddUint16(extensionKeyShare)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                for _, ks := range m.keyShares {
+                    exts.AddUint16(uint16(ks.group))
+                    exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                        exts.AddBytes(ks.data)
+                    })
+                }
+            })
+        })
+    }
+
+    extBytes, err := exts.Bytes()
+    if err != nil {
+        return nil, err
+    }
+
+    raw, err := marshalHandshake(typeClientHello, func(b *cryptobyte.Builder) {
+        b.AddUint16(m.vers)
+        b.AddBytes(m.random)
+        b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.sessionId)
+        })
+        b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+            for _, suite := range m.cipherSuites {
+                b.AddUint16(suite)
+            }
+        })
+        b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.compressionMethods)
+        })
+
+        if len(extBytes) > 0 {
+            b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                b.AddBytes(extBytes)
+            })
+        }
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *clientHelloMsg) unmarshal(data []byte) bool {
+    *m = clientHelloMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    if !s.ReadUint16(&m.vers) || !s.ReadBytes(&m.random, 32) ||
+        !readUint8LengthPrefixed(&s, &m.sessionId) {
+        return false
+    }
+
+    if len(m.sessionId) > 32 {
+        return false
+    }
+
+    var cipherSuites cryptobyte.String
+    if !s.ReadUint16LengthPrefixed(&cipherSuites) {
+        return false
+    }
+
+    for !cipherSuites.Empty() {
+        var suite uint16
+        if !cipherSuites.ReadUint16(&suite) {
+            return false
+        }
+
+        m.cipherSuites = append(m.cipherSuites, suite)
+    }
+
+    if !readUint8LengthPrefixed(&s, &m.compressionMethods) {
+        return false
+    }
+
+    if s.Empty() {
+        // ClientHello is optionally followed by extension data
+        return true
+    }
+
+    var extensions cryptobyte.String
+    if !s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+        return false
+    }
+
+    seenExts := make(map[uint16]bool)
+    for !extensions.Empty() {
+        var extension uint16
+        var extData cryptobyte.String
+        if !extensions.ReadUint16(&extension) ||
+            !extensions.ReadUint16LengthPrefixed(&extData) {
+            return false
+        }
+
+        if seenExts[extension] {
+            return false
+        }
+        seenExts[extension] = true
+
+        switch extension {
+            case extensionServerName:
+                // RFC 6066, Section 3
+                var nameList cryptobyte.String
+                if !extData.ReadUint16LengthPrefixed(&nameList) || nameList.Empty() {
+                    return false
+                }
+
+                for !nameList.Empty() {
+                    var nameType uint8
+                    var serverName cryptobyte.String
+                    if !nameList.ReadUint8(&nameType) ||
+                        !nameList.ReadUint16LengthPrefixed(&serverName) ||
+                        serverName.Empty() {
+                        return false
+                    }
+
+                    if nameType != 0 {
+                        continue
+                    }
+
+                    if len(m.serverName) != 0 {
+                        // Multiple names of the same name_type are prohibited.
+                        return false
+                    }
+
+                    m.serverName = string(serverName)
+                }
+            case extensionSupportedCurves:
+                // RFC 4492, sections 5.1.1 and RFC 8446, Section 4.2.7
+                var curves cryptobyte.String
+                if !extData.ReadUint16LengthPrefixed(&curves) || curves.Empty() {
+                    return false
+                }
+
+                for !curves.Empty() {
+                    var curve uint16
+                    if !curves.ReadUint16(&curve) {
+                        return false
+                    }
+
+                    m.supportedCurves = append(m.supportedCurves, CurveID(curve))
+                }
+            case extensionSignatureAlgorithms:
+                // RFC 5246, Section 7.4.1.4.1
+                var sigAndAlgs cryptobyte.String
+                if !extData.ReadUint16LengthPrefixed(&sigAndAlgs) || sigAndAlgs.Empty() {
+                    return false
+                }
+
+                for !sigAndAlgs.Empty() {
+                    var sigAndAlg uint16
+                    if !sigAndAlgs.ReadUint16(&sigAndAlg) {
+                        return false
+                    }
+
+                    m.supportedSignatureAlgorithms = append(m.supportedSignatureAlgorithms, SignatureScheme(sigAndAlg))
+                }
+            case extensionSupportedVersions:
+                // RFC 8446, Section 4.2.1
+                var versList cryptobyte.String
+                if !extData.ReadUint8LengthPrefixed(&versList) || versList.Empty() {
+                    return false
+                }
+
+                for !versList.Empty() {
+                    var vers uint16
+                    if !versList.ReadUint16(&vers) {
+                        return false
+                    }
+
+                    m.supportedVersions = append(m.supportedVersions, vers)
+                }
+            case extensionCookie:
+                // RFC 8446, Section 4.2.2
+                if !readUint16LengthPrefixed(&extData, &m.cookie) || len(m.cookie) == 0 {
+                    return false
+                }
+            case extensionKeyShare:
+                // RFC 8446, Section 4.2.8
+                var clientShares cryptobyte.String
+                if !extData.ReadUint16LengthPrefixed(&clientShares) {
+                    return false
+                }
+
+                for !clientShares.Empty() {
+                    var ks keyShareEntry
+                    if !clientShares.ReadUint16((*uint16)(&ks.group)) ||
+                        !readUint16LengthPrefixed(&clientShares, &ks.data) ||
+                        len(ks.data) == 0 {
+                        return false
+                    }
+
+                    m.keyShares = append(m.keyShares, ks)
+                }
+            default:
+                // Ignore unknown extensions.
+                continue
+        }
+
+        if !extData.Empty() {
+            return false
+        }
+    }
+
+    return true
+}
+
+// ==========
+
+type serverHelloMsg struct {
+    raw               []byte
+    vers              uint16
+    random            []byte
+    sessionId         []byte
+    cipherSuite       uint16
+    compressionMethod uint8
+    supportedVersion  uint16
+    serverShare       keyShareEntry
+    cookie            []byte
+
+    // HelloRetryRequest extensions
+    selectedGroup CurveID
+}
+
+func (m *serverHelloMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    var exts cryptobyte.Builder
+    if m.supportedVersion != 0 {
+        exts.AddUint16(extensionSupportedVersions)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16(m.supportedVersion)
+        })
+    }
+
+    if m.serverShare.group != 0 {
+        exts.AddUint16(extensionKeyShare)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16(uint16(m.serverShare.group))
+            exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                exts.AddBytes(m.serverShare.data)
+            })
+        })
+    }
+
+    if m.selectedGroup != 0 {
+        exts.AddUint16(extensionKeyShare)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16(uint16(m.selectedGroup))
+        })
+    }
+
+    if len(m.cookie) > 0 {
+        exts.AddUint16(extensionCookie)
+        exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+            exts.AddUint16LengthPrefixed(func(exts *cryptobyte.Builder) {
+                exts.AddBytes(m.cookie)
+            })
+        })
+    }
+
+    extBytes, err := exts.Bytes()
+    if err != nil {
+        return nil, err
+    }
+
+    raw, err := marshalHandshake(typeServerHello, func(b *cryptobyte.Builder) {
+        b.AddUint16(m.vers)
+        b.AddBytes(m.random)
+        b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.sessionId)
+        })
+        b.AddUint16(m.cipherSuite)
+        b.AddUint8(m.compressionMethod)
+
+        if len(extBytes) > 0 {
+            b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                b.AddBytes(extBytes)
+            })
+        }
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *serverHelloMsg) unmarshal(data []byte) bool {
+    *m = serverHelloMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    if !s.ReadUint16(&m.vers) || !s.ReadBytes(&m.random, 32) ||
+        !readUint8LengthPrefixed(&s, &m.sessionId) ||
+        !s.ReadUint16(&m.cipherSuite) ||
+        !s.ReadUint8(&m.compressionMethod) {
+        return false
+    }
+
+    if len(m.sessionId) > 32 {
+        return false
+    }
+
+    if s.Empty() {
+        // ServerHello is optionally followed by extension data
+        return true
+    }
+
+    var extensions cryptobyte.String
+    if !s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+        return false
+    }
+
+    isHRR := string(m.random) == string(helloRetryRequestRandom)
+
+    seenExts := make(map[uint16]bool)
+    for !extensions.Empty() {
+        var extension uint16
+        var extData cryptobyte.String
+        if !extensions.ReadUint16(&extension) ||
+            !extensions.ReadUint16LengthPrefixed(&extData) {
+            return false
+        }
+
+        if seenExts[extension] {
+            return false
+        }
+        seenExts[extension] = true
+
+        switch extension {
+            case extensionSupportedVersions:
+                if !extData.ReadUint16(&m.supportedVersion) {
+                    return false
+                }
+            case extensionCookie:
+                if !readUint16LengthPrefixed(&extData, &m.cookie) || len(m.cookie) == 0 {
+                    return false
+                }
+            case extensionKeyShare:
+                // This extension has different formats in SH and HRR, accept either
+                // and let the handshake logic decide. See RFC 8446, Section 4.2.8.
+                if isHRR {
+                    if !extData.ReadUint16((*uint16)(&m.selectedGroup)) {
+                        return false
+                    }
+                } else {
+                    if !extData.ReadUint16((*uint16)(&m.serverShare.group)) ||
+                        !readUint16LengthPrefixed(&extData, &m.serverShare.data) {
+                        return false
+                    }
+                }
+            default:
+                // Ignore unknown extensions.
+                continue
+        }
+
+        if !extData.Empty() {
+            return false
+        }
+    }
+
+    return true
+}
+
+// ==========
+
+type encryptedExtensionsMsg struct {
+    raw []byte
+}
+
+func (m *encryptedExtensionsMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeEncryptedExtensions, func(b *cryptobyte.Builder) {
+        b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {})
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *encryptedExtensionsMsg) unmarshal(data []byte) bool {
+    *m = encryptedExtensionsMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    // 忽略扩展内容
+    var extensions cryptobyte.String
+    if !s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+        return false
+    }
+
+    for !extensions.Empty() {
+        var extension uint16
+        var extData cryptobyte.String
+        if !extensions.ReadUint16(&extension) ||
+            !extensions.ReadUint16LengthPrefixed(&extData) {
+            return false
+        }
+    }
+
+    return true
+}
+
+// ==========
+
+type certificateRequestMsg struct {
+    raw                          []byte
+    supportedSignatureAlgorithms []SignatureScheme
+    certificateAuthorities       [][]byte
+}
+
+func (m *certificateRequestMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeCertificateRequest, func(b *cryptobyte.Builder) {
+        // certificate_request_context (SHALL be zero length unless used for
+        // post-handshake authentication)
+        b.AddUint8(0)
+
+        b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddUint16(extensionSignatureAlgorithms)
+            b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                    for _, sigAlgo := range m.supportedSignatureAlgorithms {
+                        b.AddUint16(uint16(sigAlgo))
+                    }
+                })
+            })
+
+            if len(m.certificateAuthorities) > 0 {
+                b.AddUint16(extensionCertificateAuthorities)
+                b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                    b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                        for _, ca := range m.certificateAuthorities {
+                            b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+                                b.AddBytes(ca)
+                            })
+                        }
+                    })
+                })
+            }
+        })
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *certificateRequestMsg) unmarshal(data []byte) bool {
+    *m = certificateRequestMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    var context, extensions cryptobyte.String
+    if !s.ReadUint8LengthPrefixed(&context) ||
+        !s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+        return false
+    }
+
+    for !extensions.Empty() {
+        var extension uint16
+        var extData cryptobyte.String
+        if !extensions.ReadUint16(&extension) ||
+            !extensions.ReadUint16LengthPrefixed(&extData) {
+            return false
+        }
+
+        switch extension {
+            case extensionSignatureAlgorithms:
+                var sigAndAlgs cryptobyte.String
+                if !extData.ReadUint16LengthPrefixed(&sigAndAlgs) || sigAndAlgs.Empty() {
+                    return false
+                }
+
+                for !sigAndAlgs.Empty() {
+                    var sigAndAlg uint16
+                    if !sigAndAlgs.ReadUint16(&sigAndAlg) {
+                        return false
+                    }
+
+                    m.supportedSignatureAlgorithms = append(m.supportedSignatureAlgorithms, SignatureScheme(sigAndAlg))
+                }
+            case extensionCertificateAuthorities:
+                var auths cryptobyte.String
+                if !extData.ReadUint16LengthPrefixed(&auths) || auths.Empty() {
+                    return false
+                }
+
+                for !auths.Empty() {
+                    var ca []byte
+                    if !readUint16LengthPrefixed(&auths, &ca) || len(ca) == 0 {
+                        return false
+                    }
+
+                    m.certificateAuthorities = append(m.certificateAuthorities, ca)
+                }
+            default:
+                // Ignore unknown extensions.
+                continue
+        }
+
+        if !extData.Empty() {
+            return false
+        }
+    }
+
+    return len(m.supportedSignatureAlgorithms) > 0
+}
+
+// ==========
+
+type certificateMsg struct {
+    raw          []byte
+    certificates [][]byte
+}
+
+func (m *certificateMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeCertificate, func(b *cryptobyte.Builder) {
+        b.AddUint8(0) // certificate_request_context
+        b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+            for _, cert := range m.certificates {
+                b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+                    b.AddBytes(cert)
+                })
+
+                // 不支持证书扩展
+                b.AddUint16(0)
+            }
+        })
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *certificateMsg) unmarshal(data []byte) bool {
+    *m = certificateMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    var context, certList cryptobyte.String
+    if !s.ReadUint8LengthPrefixed(&context) ||
+        !s.ReadUint24LengthPrefixed(&certList) || !s.Empty() {
+        return false
+    }
+
+    for !certList.Empty() {
+        var cert []byte
+        var extensions cryptobyte.String
+        if !readUint24LengthPrefixed(&certList, &cert) ||
+            !certList.ReadUint16LengthPrefixed(&extensions) {
+            return false
+        }
+
+        m.certificates = append(m.certificates, cert)
+    }
+
+    return true
+}
+
+// ==========
+
+type certificateVerifyMsg struct {
+    raw                []byte
+    signatureAlgorithm SignatureScheme
+    signature          []byte
+}
+
+func (m *certificateVerifyMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeCertificateVerify, func(b *cryptobyte.Builder) {
+        b.AddUint16(uint16(m.signatureAlgorithm))
+        b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+            b.AddBytes(m.signature)
+        })
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *certificateVerifyMsg) unmarshal(data []byte) bool {
+    *m = certificateVerifyMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    return s.ReadUint16((*uint16)(&m.signatureAlgorithm)) &&
+        readUint16LengthPrefixed(&s, &m.signature) && s.Empty()
+}
+
+// ==========
+
+type finishedMsg struct {
+    raw        []byte
+    verifyData []byte
+}
+
+func (m *finishedMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeFinished, func(b *cryptobyte.Builder) {
+        b.AddBytes(m.verifyData)
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *finishedMsg) unmarshal(data []byte) bool {
+    *m = finishedMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    m.verifyData = s
+
+    return true
+}
+
+// ==========
+
+type keyUpdateMsg struct {
+    raw             []byte
+    updateRequested bool
+}
+
+func (m *keyUpdateMsg) marshal() ([]byte, error) {
+    if m.raw != nil {
+        return m.raw, nil
+    }
+
+    raw, err := marshalHandshake(typeKeyUpdate, func(b *cryptobyte.Builder) {
+        if m.updateRequested {
+            b.AddUint8(1)
+        } else {
+            b.AddUint8(0)
+        }
+    })
+
+    m.raw = raw
+    return raw, err
+}
+
+func (m *keyUpdateMsg) unmarshal(data []byte) bool {
+    *m = keyUpdateMsg{raw: data}
+
+    s := readHandshakeBody(data)
+    if s == nil {
+        return false
+    }
+
+    var updateRequested uint8
+    if !s.ReadUint8(&updateRequested) || !s.Empty() {
+        return false
+    }
+
+    switch updateRequested {
+        case 0:
+            m.updateRequested = false
+        case 1:
+            m.updateRequested = true
+        default:
+            return false
+    }
+
+    return true
+}
+
+// ==========
+
+// newSessionTicketMsg 不支持会话恢复, 收到后忽略
+type newSessionTicketMsg struct {
+    raw []byte
+}
+
+func (m *newSessionTicketMsg) marshal() ([]byte, error) {
+    return m.raw, nil
+}
+
+func (m *newSessionTicketMsg) unmarshal(data []byte) bool {
+    m.raw = data
+
+    return readHandshakeBody(data) != nil
+}
+
+// ==========
+
+// readUint8LengthPrefixed acts like s.ReadUint8LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func readUint8LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+    return s.ReadUint8LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// readUint16LengthPrefixed acts like s.ReadUint16LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func readUint16LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+    return s.ReadUint16LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// readUint24LengthPrefixed acts like s.ReadUint24LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func readUint24LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+    return s.ReadUint24LengthPrefixed((*cryptobyte.String)(out))
+}
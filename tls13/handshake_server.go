@@ -0,0 +1,477 @@
+package tls13
+
+import (
+    "io"
+    "hash"
+    "errors"
+
+    "github.com/deatil/go-cryptobin/x509"
+)
+
+type serverHandshakeState struct {
+    c            *Conn
+    clientHello  *clientHelloMsg
+    hello        *serverHelloMsg
+    suite        *cipherSuite
+    cert         *Certificate
+    sigScheme    SignatureScheme
+    curveID      CurveID
+    clientShare  []byte
+    transcript   hash.Hash
+    masterSecret []byte
+
+    clientHandshakeSecret []byte
+    trafficSecret         []byte // client_application_traffic_secret_0
+}
+
+func (c *Conn) serverHandshake() error {
+    if c.config == nil || len(c.config.Certificates) == 0 {
+        return errors.New("tls13: no certificates configured")
+    }
+
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    clientHello, ok := msg.(*clientHelloMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(clientHello, msg)
+    }
+
+    hs := &serverHandshakeState{
+        c:           c,
+        clientHello: clientHello,
+    }
+
+    return hs.handshake()
+}
+
+func (hs *serverHandshakeState) handshake() error {
+    c := hs.c
+
+    if err := hs.processClientHello(); err != nil {
+        return err
+    }
+
+    if err := hs.checkForHelloRetryRequest(); err != nil {
+        return err
+    }
+
+    c.buffering = true
+
+    if err := hs.sendServerParameters(); err != nil {
+        return err
+    }
+
+    if err := hs.sendServerCertificate(); err != nil {
+        return err
+    }
+
+    if err := hs.sendServerFinished(); err != nil {
+        return err
+    }
+
+    // 服务端 Finished 之后等待客户端的证书和 Finished
+    if _, err := c.flush(); err != nil {
+        return err
+    }
+
+    if err := hs.readClientCertificate(); err != nil {
+        return err
+    }
+
+    if err := hs.readClientFinished(); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (hs *serverHandshakeState) processClientHello() error {
+    c := hs.c
+    config := c.config
+
+    c.vers = VersionTLS13
+    c.haveVers = true
+
+    supported := false
+    for _, v := range hs.clientHello.supportedVersions {
+        if v == VersionTLS13 {
+            supported = true
+            break
+        }
+    }
+
+    if !supported {
+        c.sendAlert(alertProtocolVersion)
+        return errors.New("tls13: client does not support TLS 1.3")
+    }
+
+    if len(hs.clientHello.compressionMethods) != 1 ||
+        hs.clientHello.compressionMethods[0] != compressionNone {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: TLS 1.3 client supports illegal compression methods")
+    }
+
+    hs.hello = &serverHelloMsg{
+        vers:              VersionTLS12,
+        random:            make([]byte, 32),
+        sessionId:         hs.clientHello.sessionId,
+        compressionMethod: compressionNone,
+        supportedVersion:  VersionTLS13,
+    }
+
+    if _, err := io.ReadFull(config.rand(), hs.hello.random); err != nil {
+        c.sendAlert(alertInternalError)
+        return err
+    }
+
+    // 按客户端的优先级选择密码套件
+    for _, id := range hs.clientHello.cipherSuites {
+        if hs.suite = mutualCipherSuite(config.cipherSuites(), id); hs.suite != nil {
+            break
+        }
+    }
+
+    if hs.suite == nil {
+        c.sendAlert(alertHandshakeFailure)
+        return errors.New("tls13: no cipher suite supported by both client and server")
+    }
+
+    c.cipherSuite = hs.suite.id
+    hs.hello.cipherSuite = hs.suite.id
+
+    // 优先选择客户端已经发送 key share 的曲线
+    for _, curve := range config.curvePreferences() {
+        for _, ks := range hs.clientHello.keyShares {
+            if ks.group == curve {
+                hs.curveID = curve
+                hs.clientShare = ks.data
+                break
+            }
+        }
+
+        if hs.curveID != 0 {
+            break
+        }
+    }
+
+    if hs.curveID == 0 {
+        for _, curve := range config.curvePreferences() {
+            for _, cc := range hs.clientHello.supportedCurves {
+                if cc == curve {
+                    hs.curveID = curve
+                    break
+                }
+            }
+
+            if hs.curveID != 0 {
+                break
+            }
+        }
+    }
+
+    if hs.curveID == 0 {
+        c.sendAlert(alertHandshakeFailure)
+        return errors.New("tls13: no ECDHE curve supported by both client and server")
+    }
+
+    c.curveID = hs.curveID
+    c.serverName = hs.clientHello.serverName
+
+    return hs.pickCertificate()
+}
+
+// pickCertificate 按客户端支持的签名算法选择证书
+func (hs *serverHandshakeState) pickCertificate() error {
+    c := hs.c
+
+    for i := range c.config.Certificates {
+        cert := &c.config.Certificates[i]
+
+        leaf, err := cert.leaf()
+        if err != nil {
+            continue
+        }
+
+        scheme, ok := selectSignatureScheme(leaf.PublicKey, c.config.signatureSchemes(), hs.clientHello.supportedSignatureAlgorithms)
+        if ok {
+            hs.cert = cert
+            hs.sigScheme = scheme
+            return nil
+        }
+    }
+
+    c.sendAlert(alertHandshakeFailure)
+    return errors.New("tls13: no certificate compatible with the client's signature algorithms")
+}
+
+// checkForHelloRetryRequest 客户端没有发送所选曲线的 key share 时发送 HelloRetryRequest
+func (hs *serverHandshakeState) checkForHelloRetryRequest() error {
+    c := hs.c
+
+    hs.transcript = hs.suite.hash()
+    hs.transcript.Write(hs.clientHello.raw)
+
+    if hs.clientShare != nil {
+        return nil
+    }
+
+    // The first ClientHello gets double-hashed into the transcript upon a
+    // HelloRetryRequest. See RFC 8446, Section 4.4.1.
+    chHash := hs.transcript.Sum(nil)
+    hs.transcript.Reset()
+    hs.transcript.Write([]byte{typeMessageHash, 0, 0, uint8(len(chHash))})
+    hs.transcript.Write(chHash)
+
+    helloRetryRequest := &serverHelloMsg{
+        vers:              hs.hello.vers,
+        random:            helloRetryRequestRandom,
+        sessionId:         hs.hello.sessionId,
+        cipherSuite:       hs.hello.cipherSuite,
+        compressionMethod: hs.hello.compressionMethod,
+        supportedVersion:  hs.hello.supportedVersion,
+        selectedGroup:     hs.curveID,
+    }
+
+    if _, err := c.writeHandshakeRecord(helloRetryRequest, hs.transcript); err != nil {
+        return err
+    }
+
+    msg, err := c.readHandshake(hs.transcript)
+    if err != nil {
+        return err
+    }
+
+    clientHello, ok := msg.(*clientHelloMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(clientHello, msg)
+    }
+
+    if len(clientHello.keyShares) != 1 || clientHello.keyShares[0].group != hs.curveID {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: client sent invalid key share in second ClientHello")
+    }
+
+    if mutualCipherSuite(clientHello.cipherSuites, hs.suite.id) == nil {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: client removed the selected cipher suite in second ClientHello")
+    }
+
+    hs.clientHello = clientHello
+    hs.clientShare = clientHello.keyShares[0].data
+
+    return nil
+}
+
+func (hs *serverHandshakeState) sendServerParameters() error {
+    c := hs.c
+
+    ks, err := generateKeyShare(c.config.rand(), hs.curveID)
+    if err != nil {
+        c.sendAlert(alertInternalError)
+        return err
+    }
+
+    hs.hello.serverShare = keyShareEntry{group: hs.curveID, data: ks.publicKey()}
+
+    sharedKey, err := ks.sharedKey(hs.clientShare)
+    if err != nil {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: invalid client key share")
+    }
+
+    if _, err := c.writeHandshakeRecord(hs.hello, hs.transcript); err != nil {
+        return err
+    }
+
+    handshakeSecret := hs.suite.handshakeSecret(sharedKey)
+
+    hs.clientHandshakeSecret = hs.suite.deriveSecret(handshakeSecret, clientHandshakeTrafficLabel, hs.transcript)
+    serverSecret := hs.suite.deriveSecret(handshakeSecret, serverHandshakeTrafficLabel, hs.transcript)
+
+    c.in.setTrafficSecret(hs.suite, hs.clientHandshakeSecret)
+    c.out.setTrafficSecret(hs.suite, serverSecret)
+
+    hs.masterSecret = hs.suite.masterSecret(handshakeSecret)
+
+    encryptedExtensions := new(encryptedExtensionsMsg)
+    if _, err := c.writeHandshakeRecord(encryptedExtensions, hs.transcript); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (hs *serverHandshakeState) requestClientCert() bool {
+    return hs.c.config.ClientAuth >= RequestClientCert
+}
+
+func (hs *serverHandshakeState) sendServerCertificate() error {
+    c := hs.c
+    config := c.config
+
+    if hs.requestClientCert() {
+        certReq := new(certificateRequestMsg)
+        certReq.supportedSignatureAlgorithms = config.signatureSchemes()
+        if config.ClientCAs != nil {
+            certReq.certificateAuthorities = config.ClientCAs.Subjects()
+        }
+
+        if _, err := c.writeHandshakeRecord(certReq, hs.transcript); err != nil {
+            return err
+        }
+    }
+
+    certMsg := new(certificateMsg)
+    certMsg.certificates = hs.cert.Certificate
+
+    if _, err := c.writeHandshakeRecord(certMsg, hs.transcript); err != nil {
+        return err
+    }
+
+    certVerify := new(certificateVerifyMsg)
+    certVerify.signatureAlgorithm = hs.sigScheme
+
+    signed := signedMessage(serverSignatureContext, hs.transcript)
+    sig, err := signHandshake(config.rand(), hs.cert.PrivateKey, hs.sigScheme, signed)
+    if err != nil {
+        c.sendAlert(alertInternalError)
+        return errors.New("tls13: failed to sign handshake: " + err.Error())
+    }
+
+    certVerify.signature = sig
+
+    if _, err := c.writeHandshakeRecord(certVerify, hs.transcript); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (hs *serverHandshakeState) sendServerFinished() error {
+    c := hs.c
+
+    serverSecret := c.out.trafficSecret
+
+    finished := &finishedMsg{
+        verifyData: hs.suite.finishedHash(serverSecret, hs.transcript),
+    }
+
+    if _, err := c.writeHandshakeRecord(finished, hs.transcript); err != nil {
+        return err
+    }
+
+    // Derive secrets that take context through the server Finished.
+    hs.trafficSecret = hs.suite.deriveSecret(hs.masterSecret, clientApplicationTrafficLabel, hs.transcript)
+    serverAppSecret := hs.suite.deriveSecret(hs.masterSecret, serverApplicationTrafficLabel, hs.transcript)
+
+    c.out.setTrafficSecret(hs.suite, serverAppSecret)
+
+    return nil
+}
+
+func (hs *serverHandshakeState) readClientCertificate() error {
+    c := hs.c
+
+    if !hs.requestClientCert() {
+        return nil
+    }
+
+    msg, err := c.readHandshake(hs.transcript)
+    if err != nil {
+        return err
+    }
+
+    certMsg, ok := msg.(*certificateMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(certMsg, msg)
+    }
+
+    if err := hs.processCertsFromClient(certMsg.certificates); err != nil {
+        return err
+    }
+
+    if len(certMsg.certificates) == 0 {
+        return nil
+    }
+
+    // certificateVerifyMsg is included in the transcript, but not until
+    // after we verify the handshake signature, since the state before
+    // this message was sent is used.
+    msg, err = c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    certVerify, ok := msg.(*certificateVerifyMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(certVerify, msg)
+    }
+
+    // See RFC 8446, Section 4.4.3.
+    if !isSupportedSignatureScheme(certVerify.signatureAlgorithm, c.config.signatureSchemes()) {
+        c.sendAlert(alertIllegalParameter)
+        return errors.New("tls13: client certificate used with invalid signature algorithm")
+    }
+
+    signed := signedMessage(clientSignatureContext, hs.transcript)
+    if err := verifyHandshake(c.peerCertificates[0].PublicKey, certVerify.signatureAlgorithm, signed, certVerify.signature); err != nil {
+        c.sendAlert(alertDecryptError)
+        return errors.New("tls13: invalid signature by the client certificate: " + err.Error())
+    }
+
+    hs.transcript.Write(certVerify.raw)
+
+    return nil
+}
+
+func (hs *serverHandshakeState) readClientFinished() error {
+    c := hs.c
+
+    msg, err := c.readHandshake(nil)
+    if err != nil {
+        return err
+    }
+
+    finished, ok := msg.(*finishedMsg)
+    if !ok {
+        c.sendAlert(alertUnexpectedMessage)
+        return unexpectedMessageError(finished, msg)
+    }
+
+    expectedMAC := hs.suite.finishedHash(hs.clientHandshakeSecret, hs.transcript)
+    if !verifyFinished(expectedMAC, finished.verifyData) {
+        c.sendAlert(alertDecryptError)
+        return errors.New("tls13: invalid client finished hash")
+    }
+
+    c.in.setTrafficSecret(hs.suite, hs.trafficSecret)
+
+    return nil
+}
+
+// processCertsFromClient takes a chain of client certificates from a
+// Certificate message and verifies them.
+func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) error {
+    c := hs.c
+    config := c.config
+
+    if len(certificates) == 0 {
+        if requiresClientCert(config.ClientAuth) {
+            c.sendAlert(alertCertificateRequired)
+            return errors.New("tls13: client didn't provide a certificate")
+        }
+
+        return nil
+    }
+
+    verify := config.ClientAuth >= VerifyClientCertIfGiven && !config.InsecureSkipVerify
+
+    return c.verifyPeerCertificates(certificates, x509.ExtKeyUsageClientAuth, verify)
+}
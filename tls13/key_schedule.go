@@ -0,0 +1,226 @@
+package tls13
+
+import (
+    "io"
+    "hash"
+    "errors"
+    "math/big"
+    "crypto/ecdh"
+    "crypto/hmac"
+    "crypto/elliptic"
+
+    "golang.org/x/crypto/hkdf"
+    "golang.org/x/crypto/cryptobyte"
+
+    "github.com/deatil/go-cryptobin/gm/sm2"
+)
+
+// This file contains the functions necessary to compute the TLS 1.3 key
+// schedule. See RFC 8446, Section 7.
+
+const (
+    clientHandshakeTrafficLabel   = "c hs traffic"
+    serverHandshakeTrafficLabel   = "s hs traffic"
+    clientApplicationTrafficLabel = "c ap traffic"
+    serverApplicationTrafficLabel = "s ap traffic"
+    trafficUpdateLabel            = "traffic upd"
+    derivedLabel                  = "derived"
+)
+
+// expandLabel implements HKDF-Expand-Label from RFC 8446, Section 7.1.
+func (c *cipherSuite) expandLabel(secret []byte, label string, context []byte, length int) []byte {
+    var hkdfLabel cryptobyte.Builder
+    hkdfLabel.AddUint16(uint16(length))
+    hkdfLabel.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+        b.AddBytes([]byte("tls13 "))
+        b.AddBytes([]byte(label))
+    })
+    hkdfLabel.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+        b.AddBytes(context)
+    })
+
+    hkdfLabelBytes, err := hkdfLabel.Bytes()
+    if err != nil {
+        // Rather than calling BytesOrPanic, we explicitly handle this error, in
+        // order to provide a reasonable error message. It should be basically
+        // impossible for this to panic, and routing errors back through the
+        // tree rooted in this function is quite painful. The labels are fixed
+        // size, and the context is either a fixed-length computed hash, or
+        // parsed from a field which has the same length limitation. As such, an
+        // error here is likely to only be caused during development.
+        //
+        // NOTE: another reasonable approach here might be to return a
+        // randomized slice if we encounter an error, which would break the
+        // connection, but avoid panicking. This would perhaps be safer but
+        // significantly more confusing to users.
+        panic(errors.New("failed to construct HKDF label: " + err.Error()))
+    }
+
+    out := make([]byte, length)
+    n, err := hkdf.Expand(c.hash, secret, hkdfLabelBytes).Read(out)
+    if err != nil || n != length {
+        panic("tls13: HKDF-Expand-Label invocation failed unexpectedly")
+    }
+
+    return out
+}
+
+// deriveSecret implements Derive-Secret from RFC 8446, Section 7.1.
+func (c *cipherSuite) deriveSecret(secret []byte, label string, transcript hash.Hash) []byte {
+    if transcript == nil {
+        transcript = c.hash()
+    }
+
+    return c.expandLabel(secret, label, transcript.Sum(nil), c.hashSize())
+}
+
+// extract implements HKDF-Extract with the cipher suite hash.
+func (c *cipherSuite) extract(newSecret, currentSecret []byte) []byte {
+    if newSecret == nil {
+        newSecret = make([]byte, c.hashSize())
+    }
+
+    return hkdf.Extract(c.hash, newSecret, currentSecret)
+}
+
+func (c *cipherSuite) hashSize() int {
+    return c.hash().Size()
+}
+
+// nextTrafficSecret generates the next traffic secret, given the current one,
+// according to RFC 8446, Section 7.2.
+func (c *cipherSuite) nextTrafficSecret(trafficSecret []byte) []byte {
+    return c.expandLabel(trafficSecret, trafficUpdateLabel, nil, c.hashSize())
+}
+
+// trafficKey generates traffic keys according to RFC 8446, Section 7.3.
+func (c *cipherSuite) trafficKey(trafficSecret []byte) (key, iv []byte) {
+    key = c.expandLabel(trafficSecret, "key", nil, c.keyLen)
+    iv = c.expandLabel(trafficSecret, "iv", nil, aeadNonceLength)
+    return
+}
+
+// finishedHash generates the Finished verify_data or PskBinderEntry according
+// to RFC 8446, Section 4.4.4. See sections 4.4 and 4.2.11.2 for the baseKey
+// selection.
+func (c *cipherSuite) finishedHash(baseKey []byte, transcript hash.Hash) []byte {
+    finishedKey := c.expandLabel(baseKey, "finished", nil, c.hashSize())
+    verifyData := hmac.New(c.hash, finishedKey)
+    verifyData.Write(transcript.Sum(nil))
+
+    return verifyData.Sum(nil)
+}
+
+// 握手密钥
+func (c *cipherSuite) handshakeSecret(sharedKey []byte) []byte {
+    earlySecret := c.extract(nil, nil)
+
+    return c.extract(sharedKey, c.deriveSecret(earlySecret, derivedLabel, nil))
+}
+
+// 主密钥
+func (c *cipherSuite) masterSecret(handshakeSecret []byte) []byte {
+    return c.extract(nil, c.deriveSecret(handshakeSecret, derivedLabel, nil))
+}
+
+// ==========
+
+// keyShare 密钥交换
+type keyShare interface {
+    curveID() CurveID
+    publicKey() []byte
+    sharedKey(peerPublicKey []byte) ([]byte, error)
+}
+
+var errInvalidKeyShare = errors.New("tls13: invalid key share")
+
+// 生成曲线的临时密钥
+func generateKeyShare(rand io.Reader, curve CurveID) (keyShare, error) {
+    switch curve {
+        case X25519:
+            return generateECDHKeyShare(rand, curve, ecdh.X25519())
+        case CurveP256:
+            return generateECDHKeyShare(rand, curve, ecdh.P256())
+        case CurveP384:
+            return generateECDHKeyShare(rand, curve, ecdh.P384())
+        case CurveSM2:
+            return generateSM2KeyShare(rand)
+    }
+
+    return nil, errors.New("tls13: unsupported curve")
+}
+
+type ecdhKeyShare struct {
+    id  CurveID
+    key *ecdh.PrivateKey
+}
+
+func generateECDHKeyShare(rand io.Reader, id CurveID, curve ecdh.Curve) (keyShare, error) {
+    key, err := curve.GenerateKey(rand)
+    if err != nil {
+        return nil, err
+    }
+
+    return &ecdhKeyShare{id, key}, nil
+}
+
+func (k *ecdhKeyShare) curveID() CurveID {
+    return k.id
+}
+
+func (k *ecdhKeyShare) publicKey() []byte {
+    return k.key.PublicKey().Bytes()
+}
+
+func (k *ecdhKeyShare) sharedKey(peerPublicKey []byte) ([]byte, error) {
+    peer, err := k.key.Curve().NewPublicKey(peerPublicKey)
+    if err != nil {
+        return nil, errInvalidKeyShare
+    }
+
+    return k.key.ECDH(peer)
+}
+
+// sm2KeyShare curveSM2 的 ECDHE, 共享密钥为 x 坐标, RFC 8998 2.2
+type sm2KeyShare struct {
+    key *sm2.PrivateKey
+}
+
+func generateSM2KeyShare(rand io.Reader) (keyShare, error) {
+    key, err := sm2.GenerateKey(rand)
+    if err != nil {
+        return nil, err
+    }
+
+    return &sm2KeyShare{key}, nil
+}
+
+func (k *sm2KeyShare) curveID() CurveID {
+    return CurveSM2
+}
+
+func (k *sm2KeyShare) publicKey() []byte {
+    return elliptic.Marshal(k.key.Curve, k.key.X, k.key.Y)
+}
+
+func (k *sm2KeyShare) sharedKey(peerPublicKey []byte) ([]byte, error) {
+    curve := k.key.Curve
+    byteLen := (curve.Params().BitSize + 7) / 8
+
+    if len(peerPublicKey) != 1+2*byteLen || peerPublicKey[0] != 4 {
+        return nil, errInvalidKeyShare
+    }
+
+    x := new(big.Int).SetBytes(peerPublicKey[1 : 1+byteLen])
+    y := new(big.Int).SetBytes(peerPublicKey[1+byteLen:])
+    if !curve.IsOnCurve(x, y) {
+        return nil, errInvalidKeyShare
+    }
+
+    sx, sy := curve.ScalarMult(x, y, k.key.D.Bytes())
+    if sx.Sign() == 0 && sy.Sign() == 0 {
+        return nil, errInvalidKeyShare
+    }
+
+    return sx.FillBytes(make([]byte, byteLen)), nil
+}
@@ -0,0 +1,503 @@
+package tls13
+
+import (
+    "io"
+    "net"
+    "time"
+    "sync"
+    "bytes"
+    "crypto"
+    "testing"
+    "math/big"
+    "crypto/rand"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "encoding/hex"
+    "encoding/pem"
+    "crypto/x509/pkix"
+
+    "github.com/deatil/go-cryptobin/x509"
+    "github.com/deatil/go-cryptobin/gm/sm2"
+)
+
+type testSigner interface {
+    crypto.PrivateKey
+    Public() crypto.PublicKey
+}
+
+type testPKI struct {
+    pool        *x509.CertPool
+    serverSM2   Certificate
+    serverECDSA Certificate
+    clientSM2   Certificate
+}
+
+var serial int64 = 1
+
+func newTestKey(t *testing.T, ecc bool) testSigner {
+    if ecc {
+        priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        return priv
+    }
+
+    priv, err := sm2.GenerateKey(rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return priv
+}
+
+func newTestCert(t *testing.T, cn string, priv testSigner, extUsage []x509.ExtKeyUsage, parent *x509.Certificate, parentKey testSigner) (Certificate, *x509.Certificate) {
+    serial++
+
+    template := &x509.Certificate{
+        SerialNumber: big.NewInt(serial),
+        Subject:      pkix.Name{CommonName: cn},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(time.Hour),
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  extUsage,
+    }
+
+    if parent == nil {
+        template.IsCA = true
+        template.BasicConstraintsValid = true
+        template.KeyUsage |= x509.KeyUsageCertSign
+        parent = template
+        parentKey = priv
+    } else {
+        template.DNSNames = []string{cn}
+    }
+
+    if _, ok := parentKey.(*sm2.PrivateKey); ok {
+        template.SignatureAlgorithm = x509.SM2WithSM3
+    } else {
+        template.SignatureAlgorithm = x509.ECDSAWithSHA256
+    }
+
+    der, err := x509.CreateCertificate(rand.Reader, template, parent, priv.Public(), parentKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cert, err := x509.ParseCertificate(der)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return Certificate{Certificate: [][]byte{der}, PrivateKey: priv}, cert
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+    caKey := newTestKey(t, false)
+    _, ca := newTestCert(t, "Test CA", caKey, nil, nil, nil)
+
+    pool := x509.NewCertPool()
+    pool.AddCert(ca)
+
+    server := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+    client := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+    p := &testPKI{pool: pool}
+    p.serverSM2, _ = newTestCert(t, "server.test", newTestKey(t, false), server, ca, caKey)
+    p.serverECDSA, _ = newTestCert(t, "server.test", newTestKey(t, true), server, ca, caKey)
+    p.clientSM2, _ = newTestCert(t, "client.test", newTestKey(t, false), client, ca, caKey)
+
+    return p
+}
+
+func (p *testPKI) configs() (*Config, *Config) {
+    serverConfig := &Config{
+        Certificates: []Certificate{p.serverSM2, p.serverECDSA},
+        ClientCAs:    p.pool,
+    }
+    clientConfig := &Config{
+        Certificates: []Certificate{p.clientSM2},
+        RootCAs:      p.pool,
+        ServerName:   "server.test",
+    }
+
+    return serverConfig, clientConfig
+}
+
+// bufferedConn 异步写入的 net.Pipe 连接, 避免握手失败时双方同时写入阻塞
+type bufferedConn struct {
+    net.Conn
+
+    mu     sync.Mutex
+    closed bool
+    ch     chan []byte
+}
+
+func newBufferedConn(conn net.Conn) *bufferedConn {
+    b := &bufferedConn{
+        Conn: conn,
+        ch:   make(chan []byte, 1024),
+    }
+
+    go func() {
+        for p := range b.ch {
+            conn.Write(p)
+        }
+    }()
+
+    return b
+}
+
+func (b *bufferedConn) Write(p []byte) (int, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if b.closed {
+        return 0, io.ErrClosedPipe
+    }
+
+    b.ch <- append([]byte(nil), p...)
+
+    return len(p), nil
+}
+
+func (b *bufferedConn) Close() error {
+    b.mu.Lock()
+    if !b.closed {
+        b.closed = true
+        close(b.ch)
+    }
+    b.mu.Unlock()
+
+    return b.Conn.Close()
+}
+
+// 使用 net.Pipe 完成握手并交换数据
+func runPipe(t *testing.T, serverConfig, clientConfig *Config) (ConnectionState, ConnectionState, error) {
+    cp, sp := net.Pipe()
+
+    c, s := newBufferedConn(cp), newBufferedConn(sp)
+    defer c.Close()
+    defer s.Close()
+
+    server := Server(s, serverConfig)
+    client := Client(c, clientConfig)
+
+    type result struct {
+        state ConnectionState
+        err   error
+    }
+
+    done := make(chan result, 1)
+    go func() {
+        if err := server.Handshake(); err != nil {
+            s.Close()
+            done <- result{err: err}
+            return
+        }
+
+        buf := make([]byte, 5)
+        if _, err := io.ReadFull(server, buf); err != nil {
+            done <- result{err: err}
+            return
+        }
+
+        if _, err := server.Write(bytes.ToUpper(buf)); err != nil {
+            done <- result{err: err}
+            return
+        }
+
+        done <- result{state: server.ConnectionState()}
+    }()
+
+    if err := client.Handshake(); err != nil {
+        c.Close()
+        <-done
+        return ConnectionState{}, ConnectionState{}, err
+    }
+
+    if _, err := client.Write([]byte("hello")); err != nil {
+        return ConnectionState{}, ConnectionState{}, err
+    }
+
+    buf := make([]byte, 5)
+    if _, err := io.ReadFull(client, buf); err != nil {
+        return ConnectionState{}, ConnectionState{}, err
+    }
+
+    if string(buf) != "HELLO" {
+        t.Errorf("got %q, want %q", buf, "HELLO")
+    }
+
+    res := <-done
+    if res.err != nil {
+        return ConnectionState{}, ConnectionState{}, res.err
+    }
+
+    return res.state, client.ConnectionState(), nil
+}
+
+func fromHex(s string) []byte {
+    b, _ := hex.DecodeString(s)
+    return b
+}
+
+// RFC 8448, Section 3
+func Test_KeySchedule(t *testing.T) {
+    suite := cipherSuiteByID(TLS_AES_128_GCM_SHA256)
+
+    earlySecret := suite.extract(nil, nil)
+    if !bytes.Equal(earlySecret, fromHex("33ad0a1c607ec03b09e6cd9893680ce210adf300aa1f2660e1b22e10f170f92a")) {
+        t.Errorf("early secret got %x", earlySecret)
+    }
+
+    sharedKey := fromHex("8bd4054fb55b9d63fdfbacf9f04b9f0d35e6d63f537563efd46272900f89492d")
+
+    handshakeSecret := suite.handshakeSecret(sharedKey)
+    if !bytes.Equal(handshakeSecret, fromHex("1dc826e93606aa6fdc0aadc12f741b01046aa6b99f691ed221a9f0ca043fbeac")) {
+        t.Errorf("handshake secret got %x", handshakeSecret)
+    }
+
+    serverSecret := fromHex("b67b7d690cc16c4e75e54213cb2d37b4e9c912bcded9105d42befd59d391ad38")
+
+    key, iv := suite.trafficKey(serverSecret)
+    if !bytes.Equal(key, fromHex("3fce516009c21727d0f2e4e86ee403bc")) {
+        t.Errorf("key got %x", key)
+    }
+
+    if !bytes.Equal(iv, fromHex("5d313eb2671276ee13000b30")) {
+        t.Errorf("iv got %x", iv)
+    }
+}
+
+func Test_Handshake(t *testing.T) {
+    p := newTestPKI(t)
+
+    for _, id := range defaultCipherSuites {
+        t.Run(CipherSuiteName(id), func(t *testing.T) {
+            serverConfig, clientConfig := p.configs()
+            clientConfig.CipherSuites = []uint16{id}
+
+            ss, cs, err := runPipe(t, serverConfig, clientConfig)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if cs.CipherSuite != id || ss.CipherSuite != id {
+                t.Errorf("CipherSuite got %x/%x, want %x", cs.CipherSuite, ss.CipherSuite, id)
+            }
+
+            if cs.Version != VersionTLS13 {
+                t.Errorf("Version got %x", cs.Version)
+            }
+
+            if len(cs.PeerCertificates) != 1 || len(cs.VerifiedChains) == 0 {
+                t.Error("client should verify the server certificate")
+            }
+        })
+    }
+}
+
+func Test_ShangMi(t *testing.T) {
+    p := newTestPKI(t)
+
+    serverConfig, clientConfig := p.configs()
+    clientConfig.CipherSuites = []uint16{TLS_SM4_GCM_SM3}
+    clientConfig.CurvePreferences = []CurveID{CurveSM2}
+    clientConfig.SignatureSchemes = []SignatureScheme{SM2SigSM3}
+
+    ss, cs, err := runPipe(t, serverConfig, clientConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if cs.CurveID != CurveSM2 || ss.CurveID != CurveSM2 {
+        t.Errorf("CurveID got %d/%d", cs.CurveID, ss.CurveID)
+    }
+
+    if _, ok := cs.PeerCertificates[0].PublicKey.(*sm2.PublicKey); !ok {
+        t.Error("server should use the SM2 certificate")
+    }
+
+    if ss.ServerName != "server.test" {
+        t.Errorf("ServerName got %q", ss.ServerName)
+    }
+}
+
+func Test_ECDSA(t *testing.T) {
+    p := newTestPKI(t)
+
+    serverConfig, clientConfig := p.configs()
+    clientConfig.CurvePreferences = []CurveID{X25519}
+    clientConfig.SignatureSchemes = []SignatureScheme{ECDSAWithP256AndSHA256}
+
+    _, cs, err := runPipe(t, serverConfig, clientConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if cs.CurveID != X25519 {
+        t.Errorf("CurveID got %d", cs.CurveID)
+    }
+
+    if _, ok := cs.PeerCertificates[0].PublicKey.(*ecdsa.PublicKey); !ok {
+        t.Error("server should use the ECDSA certificate")
+    }
+}
+
+func Test_HelloRetryRequest(t *testing.T) {
+    p := newTestPKI(t)
+
+    for _, id := range []uint16{TLS_SM4_CCM_SM3, TLS_AES_256_GCM_SHA384} {
+        t.Run(CipherSuiteName(id), func(t *testing.T) {
+            serverConfig, clientConfig := p.configs()
+            serverConfig.CurvePreferences = []CurveID{CurveSM2}
+            clientConfig.CurvePreferences = []CurveID{X25519, CurveSM2}
+            clientConfig.CipherSuites = []uint16{id}
+
+            ss, cs, err := runPipe(t, serverConfig, clientConfig)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if cs.CurveID != CurveSM2 || ss.CurveID != CurveSM2 {
+                t.Errorf("CurveID got %d/%d", cs.CurveID, ss.CurveID)
+            }
+        })
+    }
+
+    serverConfig, clientConfig := p.configs()
+    serverConfig.CurvePreferences = []CurveID{CurveP384}
+    clientConfig.CurvePreferences = []CurveID{X25519, CurveSM2}
+
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("handshake without a mutual curve should fail")
+    }
+}
+
+func Test_ClientAuth(t *testing.T) {
+    p := newTestPKI(t)
+
+    serverConfig, clientConfig := p.configs()
+    serverConfig.ClientAuth = RequireAndVerifyClientCert
+
+    ss, _, err := runPipe(t, serverConfig, clientConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(ss.PeerCertificates) != 1 || len(ss.VerifiedChains) == 0 {
+        t.Error("server should verify the client certificate")
+    }
+
+    clientConfig.Certificates = nil
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("handshake without a required client certificate should fail")
+    }
+
+    serverConfig.ClientAuth = VerifyClientCertIfGiven
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err != nil {
+        t.Error(err)
+    }
+}
+
+func Test_BadRoot(t *testing.T) {
+    p := newTestPKI(t)
+    other := newTestPKI(t)
+
+    serverConfig, clientConfig := p.configs()
+    clientConfig.RootCAs = other.pool
+
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("handshake with an untrusted server should fail")
+    }
+
+    clientConfig.ServerName = "other.test"
+    clientConfig.RootCAs = p.pool
+
+    if _, _, err := runPipe(t, serverConfig, clientConfig); err == nil {
+        t.Error("handshake with a wrong server name should fail")
+    }
+
+    clientConfig.InsecureSkipVerify = true
+
+    _, cs, err := runPipe(t, serverConfig, clientConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(cs.VerifiedChains) != 0 {
+        t.Error("InsecureSkipVerify should not verify chains")
+    }
+}
+
+func Test_X509KeyPair(t *testing.T) {
+    p := newTestPKI(t)
+
+    certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.serverSM2.Certificate[0]})
+
+    der, err := sm2.MarshalPrivateKey(p.serverSM2.PrivateKey.(*sm2.PrivateKey))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+    cert, err := X509KeyPair(certPEM, keyPEM)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if cert.Leaf == nil || len(cert.Certificate) != 1 {
+        t.Error("X509KeyPair parse fail")
+    }
+
+    otherDER, _ := sm2.MarshalPrivateKey(p.clientSM2.PrivateKey.(*sm2.PrivateKey))
+    otherPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER})
+
+    if _, err := X509KeyPair(certPEM, otherPEM); err == nil {
+        t.Error("mismatched key should fail")
+    }
+}
+
+func Test_Listen(t *testing.T) {
+    p := newTestPKI(t)
+    serverConfig, clientConfig := p.configs()
+
+    ln, err := Listen("tcp", "127.0.0.1:0", serverConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ln.Close()
+
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+
+        io.Copy(conn, conn)
+    }()
+
+    conn, err := Dial("tcp", ln.Addr().String(), clientConfig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+
+    msg := []byte("tls13 echo")
+    if _, err := conn.Write(msg); err != nil {
+        t.Fatal(err)
+    }
+
+    buf := make([]byte, len(msg))
+    if _, err := io.ReadFull(conn, buf); err != nil {
+        t.Fatal(err)
+    }
+
+    if !bytes.Equal(buf, msg) {
+        t.Errorf("got %q, want %q", buf, msg)
+    }
+}
@@ -0,0 +1,109 @@
+// Package aesround provides the AES encryption round function, the same
+// as the AESENC instruction, for ciphers built on AES rounds such as
+// AEGIS and Deoxys.
+package aesround
+
+// BlockSize is the AES block size in bytes.
+const BlockSize = 16
+
+// Rounds computes one AES encryption round for each 16-byte block:
+// dst[i] = MixColumns(ShiftRows(SubBytes(src[i]))) ^ rk[i].
+// Dst, src and rk have the same length, a multiple of 16.
+// Dst may overlap src or rk entirely.
+func Rounds(dst, src, rk []byte) {
+    if len(src) % BlockSize != 0 || len(rk) != len(src) || len(dst) < len(src) {
+        panic("go-cryptobin/aesround: invalid buffer length")
+    }
+
+    if len(src) == 0 {
+        return
+    }
+
+    rounds(dst, src, rk)
+}
+
+// Round computes one AES encryption round of a single block.
+func Round(dst, src, rk *[BlockSize]byte) {
+    rounds(dst[:], src[:], rk[:])
+}
+
+// 查表实现, 非常量时间
+func roundsGeneric(dst, src, rk []byte) {
+    for len(src) >= BlockSize {
+        roundGeneric(dst, src, rk)
+
+        dst = dst[BlockSize:]
+        src = src[BlockSize:]
+        rk = rk[BlockSize:]
+    }
+}
+
+func roundGeneric(dst, src, rk []byte) {
+    s0 := uint32(src[0])<<24 | uint32(src[1])<<16 | uint32(src[2])<<8 | uint32(src[3])
+    s1 := uint32(src[4])<<24 | uint32(src[5])<<16 | uint32(src[6])<<8 | uint32(src[7])
+    s2 := uint32(src[8])<<24 | uint32(src[9])<<16 | uint32(src[10])<<8 | uint32(src[11])
+    s3 := uint32(src[12])<<24 | uint32(src[13])<<16 | uint32(src[14])<<8 | uint32(src[15])
+
+    t0 := te0[s0>>24] ^ te1[s1>>16&0xff] ^ te2[s2>>8&0xff] ^ te3[s3&0xff]
+    t1 := te0[s1>>24] ^ te1[s2>>16&0xff] ^ te2[s3>>8&0xff] ^ te3[s0&0xff]
+    t2 := te0[s2>>24] ^ te1[s3>>16&0xff] ^ te2[s0>>8&0xff] ^ te3[s1&0xff]
+    t3 := te0[s3>>24] ^ te1[s0>>16&0xff] ^ te2[s1>>8&0xff] ^ te3[s2&0xff]
+
+    putWord(dst[0:], t0, rk[0:])
+    putWord(dst[4:], t1, rk[4:])
+    putWord(dst[8:], t2, rk[8:])
+    putWord(dst[12:], t3, rk[12:])
+}
+
+func putWord(dst []byte, t uint32, rk []byte) {
+    dst[0] = byte(t>>24) ^ rk[0]
+    dst[1] = byte(t>>16) ^ rk[1]
+    dst[2] = byte(t>>8) ^ rk[2]
+    dst[3] = byte(t) ^ rk[3]
+}
+
+var te0, te1, te2, te3 [256]uint32
+
+func init() {
+    var sbox [256]byte
+
+    // S 盒: GF(2^8) 求逆后仿射变换
+    p, q := byte(1), byte(1)
+    for {
+        p = p ^ (p << 1) ^ ((p >> 7) * 0x1b)
+
+        q ^= q << 1
+        q ^= q << 2
+        q ^= q << 4
+        if q & 0x80 != 0 {
+            q ^= 0x09
+        }
+
+        x := q ^ rotl8(q, 1) ^ rotl8(q, 2) ^ rotl8(q, 3) ^ rotl8(q, 4)
+        sbox[p] = x ^ 0x63
+
+        if p == 1 {
+            break
+        }
+    }
+    sbox[0] = 0x63
+
+    for i := 0; i < 256; i++ {
+        s := uint32(sbox[i])
+        s2 := uint32(xtime(sbox[i]))
+        s3 := s2 ^ s
+
+        te0[i] = s2<<24 | s<<16 | s<<8 | s3
+        te1[i] = s3<<24 | s2<<16 | s<<8 | s
+        te2[i] = s<<24 | s3<<16 | s2<<8 | s
+        te3[i] = s<<24 | s<<16 | s3<<8 | s2
+    }
+}
+
+func rotl8(x byte, n uint) byte {
+    return x<<n | x>>(8-n)
+}
+
+func xtime(x byte) byte {
+    return x<<1 ^ (x>>7)*0x1b
+}
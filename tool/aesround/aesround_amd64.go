@@ -0,0 +1,21 @@
+//go:build !purego
+
+package aesround
+
+import (
+    "golang.org/x/sys/cpu"
+)
+
+var useAESNI = cpu.X86.HasAES
+
+//go:noescape
+func roundsAsm(dst, src, rk *byte, n int)
+
+func rounds(dst, src, rk []byte) {
+    if useAESNI {
+        roundsAsm(&dst[0], &src[0], &rk[0], len(src) / BlockSize)
+        return
+    }
+
+    roundsGeneric(dst, src, rk)
+}
@@ -0,0 +1,56 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// func roundsAsm(dst, src, rk *byte, n int)
+TEXT ·roundsAsm(SB), NOSPLIT, $0-32
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ rk+16(FP), DX
+	MOVQ n+24(FP), CX
+
+	// 每次处理 4 个分组
+loop4:
+	CMPQ CX, $4
+	JB   loop1
+
+	MOVOU 0(SI), X0
+	MOVOU 16(SI), X1
+	MOVOU 32(SI), X2
+	MOVOU 48(SI), X3
+	MOVOU 0(DX), X4
+	MOVOU 16(DX), X5
+	MOVOU 32(DX), X6
+	MOVOU 48(DX), X7
+	AESENC X4, X0
+	AESENC X5, X1
+	AESENC X6, X2
+	AESENC X7, X3
+	MOVOU X0, 0(DI)
+	MOVOU X1, 16(DI)
+	MOVOU X2, 32(DI)
+	MOVOU X3, 48(DI)
+
+	ADDQ $64, SI
+	ADDQ $64, DX
+	ADDQ $64, DI
+	SUBQ $4, CX
+	JMP  loop4
+
+loop1:
+	TESTQ CX, CX
+	JZ    done
+
+	MOVOU (SI), X0
+	MOVOU (DX), X1
+	AESENC X1, X0
+	MOVOU X0, (DI)
+
+	ADDQ $16, SI
+	ADDQ $16, DX
+	ADDQ $16, DI
+	DECQ CX
+	JMP  loop1
+
+done:
+	RET
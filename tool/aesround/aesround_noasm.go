@@ -0,0 +1,7 @@
+//go:build purego || !amd64
+
+package aesround
+
+func rounds(dst, src, rk []byte) {
+    roundsGeneric(dst, src, rk)
+}
@@ -0,0 +1,75 @@
+package aesround
+
+import (
+    "bytes"
+    "testing"
+    "crypto/rand"
+    "encoding/hex"
+)
+
+// FIPS 197 附录 B 第一轮
+func Test_Round(t *testing.T) {
+    src, _ := hex.DecodeString("193de3bea0f4e22b9ac68d2ae9f84808")
+    rk, _ := hex.DecodeString("a0fafe1788542cb123a339392a6c7605")
+    want, _ := hex.DecodeString("a49c7ff2689f352b6b5bea43026a5049")
+
+    var s, k, out [BlockSize]byte
+    copy(s[:], src)
+    copy(k[:], rk)
+
+    Round(&out, &s, &k)
+    if !bytes.Equal(out[:], want) {
+        t.Errorf("got %x, want %x", out, want)
+    }
+
+    out = [BlockSize]byte{}
+    roundsGeneric(out[:], src, rk)
+    if !bytes.Equal(out[:], want) {
+        t.Errorf("generic got %x, want %x", out, want)
+    }
+}
+
+// 汇编与查表实现比对
+func Test_Rounds(t *testing.T) {
+    src := make([]byte, BlockSize * 7)
+    rk := make([]byte, BlockSize * 7)
+    rand.Read(src)
+    rand.Read(rk)
+
+    got := make([]byte, len(src))
+    want := make([]byte, len(src))
+
+    Rounds(got, src, rk)
+    roundsGeneric(want, src, rk)
+
+    if !bytes.Equal(got, want) {
+        t.Errorf("got %x, want %x", got, want)
+    }
+
+    // 原地计算
+    Rounds(src, src, rk)
+    if !bytes.Equal(src, want) {
+        t.Error("in-place mismatch")
+    }
+}
+
+func Test_SBox(t *testing.T) {
+    // 全零输入的一轮: 每字节为 S(0) = 0x63, MixColumns 后仍为 0x63
+    var zero, out [BlockSize]byte
+    Round(&out, &zero, &zero)
+
+    for _, b := range out {
+        if b != 0x63 {
+            t.Fatalf("got %x", out)
+        }
+    }
+}
+
+func Benchmark_Rounds(b *testing.B) {
+    buf := make([]byte, BlockSize * 8)
+    b.SetBytes(int64(len(buf)))
+
+    for i := 0; i < b.N; i++ {
+        Rounds(buf, buf, buf)
+    }
+}
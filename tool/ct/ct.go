@@ -0,0 +1,81 @@
+package ct
+
+// 常量时间 S 盒查表.
+// 8 位 S 盒的每个输出位存为 256 位真值表, 查表时用掩码选取
+// 真值表的字并移位取位, 内存访问与输入无关, 可以防止缓存计时攻击.
+
+// Sbox 为 8 位常量时间 S 盒
+type Sbox struct {
+    t [4][8]uint64
+}
+
+// NewSbox 由 256 字节的查找表生成 S 盒
+func NewSbox(s *[256]byte) *Sbox {
+    b := new(Sbox)
+
+    for x := 0; x < 256; x++ {
+        for j := 0; j < 8; j++ {
+            b.t[x>>6][j] |= uint64((s[x] >> j) & 1) << (x & 63)
+        }
+    }
+
+    return b
+}
+
+// Lookup 返回 S(x)
+func (s *Sbox) Lookup(x byte) byte {
+    hi := uint64(x >> 6)
+    sh := uint(x & 63)
+
+    m0 := Eq(hi, 0)
+    m1 := Eq(hi, 1)
+    m2 := Eq(hi, 2)
+    m3 := Eq(hi, 3)
+
+    var y uint64
+    for j := 0; j < 8; j++ {
+        w := (s.t[0][j] & m0) | (s.t[1][j] & m1) |
+             (s.t[2][j] & m2) | (s.t[3][j] & m3)
+
+        y |= ((w >> sh) & 1) << j
+    }
+
+    return byte(y)
+}
+
+// Lookup32 对 32 位字的每个字节查表
+func (s *Sbox) Lookup32(x uint32) uint32 {
+    return uint32(s.Lookup(byte(x))) |
+           uint32(s.Lookup(byte(x >> 8))) << 8 |
+           uint32(s.Lookup(byte(x >> 16))) << 16 |
+           uint32(s.Lookup(byte(x >> 24))) << 24
+}
+
+// Sbox4 为 4 位 S 盒, 16 个表项打包在一个 uint64 中
+type Sbox4 uint64
+
+// NewSbox4 由 16 个表项生成 S 盒
+func NewSbox4(s []byte) Sbox4 {
+    var b uint64
+    for x := 0; x < 16; x++ {
+        b |= uint64(s[x] & 0xf) << (4 * x)
+    }
+
+    return Sbox4(b)
+}
+
+// Lookup 返回 S(x & 0xf)
+func (s Sbox4) Lookup(x byte) byte {
+    return byte(uint64(s) >> ((x & 0xf) * 4)) & 0xf
+}
+
+// Eq 在 a == b 时返回全 1 掩码, 否则返回 0.
+// a ^ b 需小于 1 << 63.
+func Eq(a, b uint64) uint64 {
+    return -(((a ^ b) - 1) >> 63)
+}
+
+// Bit 返回 x 第 i 位扩展成的掩码
+func Bit(x uint64, i uint) uint64 {
+    return -((x >> i) & 1)
+}
@@ -0,0 +1,57 @@
+package ct
+
+import (
+    "testing"
+    "math/rand"
+)
+
+func Test_Sbox(t *testing.T) {
+    var s [256]byte
+    for i, v := range rand.New(rand.NewSource(1)).Perm(256) {
+        s[i] = byte(v)
+    }
+
+    b := NewSbox(&s)
+    for x := 0; x < 256; x++ {
+        if got := b.Lookup(byte(x)); got != s[x] {
+            t.Fatalf("Lookup(%d) = %d, want %d", x, got, s[x])
+        }
+    }
+
+    x := uint32(0x01fe7f80)
+    want := uint32(s[0x01])<<24 | uint32(s[0xfe])<<16 | uint32(s[0x7f])<<8 | uint32(s[0x80])
+    if got := b.Lookup32(x); got != want {
+        t.Errorf("Lookup32 = %08x, want %08x", got, want)
+    }
+}
+
+func Test_Sbox4(t *testing.T) {
+    s := []byte{12, 4, 6, 2, 10, 5, 11, 9, 14, 8, 13, 7, 0, 3, 15, 1}
+
+    b := NewSbox4(s)
+    for x := 0; x < 16; x++ {
+        if got := b.Lookup(byte(x)); got != s[x] {
+            t.Fatalf("Lookup(%d) = %d, want %d", x, got, s[x])
+        }
+    }
+}
+
+func Test_Mask(t *testing.T) {
+    if Eq(3, 3) != ^uint64(0) || Eq(3, 4) != 0 || Eq(0, 0) != ^uint64(0) {
+        t.Error("Eq fail")
+    }
+
+    if Bit(5, 0) != ^uint64(0) || Bit(5, 1) != 0 {
+        t.Error("Bit fail")
+    }
+}
+
+func Benchmark_Lookup(b *testing.B) {
+    var s [256]byte
+    box := NewSbox(&s)
+
+    var x byte
+    for i := 0; i < b.N; i++ {
+        x = box.Lookup(x)
+    }
+}
@@ -16,6 +16,7 @@ import (
 
     "github.com/deatil/go-cryptobin/hash/sm3"
     "github.com/deatil/go-cryptobin/hash/md2"
+    "github.com/deatil/go-cryptobin/hash/blake3"
     "github.com/deatil/go-cryptobin/hash/gost/gost34112012256"
     "github.com/deatil/go-cryptobin/hash/gost/gost34112012512"
 )
@@ -66,6 +67,8 @@ var funcHashes = map[string]HashFunc{
 
     "GOST34112012256": gost34112012256.New,
     "GOST34112012512": gost34112012512.New,
+
+    "BLAKE3": blake3.New,
 }
 
 // 默认
@@ -111,6 +111,11 @@ func Test_Hash(t *testing.T) {
             "Discard medicine more than two years old.",
             "a9b8eef57cc0b453508e09e69b458d9d352574952f9f3649c1f4384e0f3d3f2021e52338e19a0ac12c583354332879f65489bf649422447866cdec5394c54b4b",
         },
+        {
+            "BLAKE3",
+            "Discard medicine more than two years old.",
+            "00773d83d15c5e5c5706fb77856575aeeb3e88b33f15829075b4b13c62600985",
+        },
     }
 
     for _, c := range cases {
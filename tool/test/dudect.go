@@ -0,0 +1,185 @@
+package test
+
+import (
+    "os"
+    "math"
+    "sort"
+    "time"
+    "testing"
+    "math/rand"
+)
+
+// dudect 风格的计时泄露检测.
+// 交替随机地使用两类输入 (固定输入和随机输入) 测量运行时间,
+// 然后用 Welch t 检验比较两类的时间分布.
+// |t| 大于 10 时基本可以确定存在计时泄露.
+// See "Dude, is my code constant time?", Reparaz et al. 2017.
+
+// DudectEnv 为开启计时检测的环境变量
+const DudectEnv = "DUDECT"
+
+// DudectThreshold 为判定存在泄露的 t 值阈值
+const DudectThreshold = 10.0
+
+// DudectConfig 为计时检测的配置
+type DudectConfig struct {
+    // 测量次数
+    Measurements int
+
+    // 每次测量中运行的次数, 用于提高计时精度
+    Repeat int
+
+    // 输入数据长度
+    InputSize int
+}
+
+// DudectResult 为计时检测结果
+type DudectResult struct {
+    // 所有检验中的最大 |t| 值
+    T float64
+
+    // 得到最大 |t| 值的检验使用的测量数
+    N int
+}
+
+// Leaky 返回 |t| 是否超过阈值
+func (r DudectResult) Leaky() bool {
+    return r.T > DudectThreshold
+}
+
+// Dudect 运行计时检测. fixed 为类 0 使用的固定输入,
+// 类 1 使用随机输入, fn 为被测函数.
+func Dudect(cfg DudectConfig, fixed []byte, fn func(in []byte)) DudectResult {
+    if cfg.Measurements <= 0 {
+        cfg.Measurements = 100000
+    }
+    if cfg.Repeat <= 0 {
+        cfg.Repeat = 1
+    }
+    if cfg.InputSize <= 0 {
+        cfg.InputSize = len(fixed)
+    }
+
+    random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+    n := cfg.Measurements
+    classes := make([]int, n)
+    inputs := make([][]byte, n)
+
+    for i := 0; i < n; i++ {
+        classes[i] = random.Intn(2)
+
+        in := make([]byte, cfg.InputSize)
+        if classes[i] == 0 {
+            copy(in, fixed)
+        } else {
+            random.Read(in)
+        }
+
+        inputs[i] = in
+    }
+
+    times := make([]float64, n)
+    for i := 0; i < n; i++ {
+        in := inputs[i]
+
+        start := time.Now()
+        for j := 0; j < cfg.Repeat; j++ {
+            fn(in)
+        }
+        times[i] = float64(time.Since(start))
+    }
+
+    // 丢弃前面的测量, 避免预热的影响
+    skip := n / 10
+    classes, times = classes[skip:], times[skip:]
+
+    return dudectAnalyze(classes, times)
+}
+
+// 对原始数据和按百分位截断的数据分别做 t 检验, 返回最大值
+func dudectAnalyze(classes []int, times []float64) DudectResult {
+    sorted := append([]float64{}, times...)
+    sort.Float64s(sorted)
+
+    thresholds := []float64{math.Inf(1)}
+    for k := 0; k < 10; k++ {
+        p := 1 - math.Pow(0.5, 10*float64(k+1)/10)
+        thresholds = append(thresholds, sorted[int(p*float64(len(sorted)-1))])
+    }
+
+    var res DudectResult
+    for _, th := range thresholds {
+        var w [2]welford
+
+        for i, v := range times {
+            if v <= th {
+                w[classes[i]].push(v)
+            }
+        }
+
+        t := math.Abs(welchT(&w[0], &w[1]))
+        if t > res.T {
+            res = DudectResult{
+                T: t,
+                N: w[0].n + w[1].n,
+            }
+        }
+    }
+
+    return res
+}
+
+// 在线计算均值和方差
+type welford struct {
+    n    int
+    mean float64
+    m2   float64
+}
+
+func (w *welford) push(x float64) {
+    w.n++
+
+    delta := x - w.mean
+    w.mean += delta / float64(w.n)
+    w.m2 += delta * (x - w.mean)
+}
+
+func (w *welford) variance() float64 {
+    if w.n < 2 {
+        return 0
+    }
+
+    return w.m2 / float64(w.n-1)
+}
+
+func welchT(a, b *welford) float64 {
+    if a.n < 2 || b.n < 2 {
+        return 0
+    }
+
+    d := math.Sqrt(a.variance()/float64(a.n) + b.variance()/float64(b.n))
+    if d == 0 {
+        return 0
+    }
+
+    return (a.mean - b.mean) / d
+}
+
+// AssertConstantTime 运行计时检测, |t| 超过阈值时测试失败.
+// 计时检测耗时较长且受运行环境影响, 只在设置了环境变量
+// DUDECT=1 时运行, 否则跳过.
+func AssertConstantTime(t *testing.T, cfg DudectConfig, fixed []byte, fn func(in []byte)) {
+    t.Helper()
+
+    if os.Getenv(DudectEnv) != "1" {
+        t.Skip("set " + DudectEnv + "=1 to run timing leakage checks")
+    }
+
+    res := Dudect(cfg, fixed, fn)
+    t.Logf("dudect: max |t| = %.2f, measurements = %d", res.T, res.N)
+
+    if res.Leaky() {
+        t.Errorf("timing leakage detected: |t| = %.2f > %.0f", res.T, DudectThreshold)
+    }
+}
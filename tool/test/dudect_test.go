@@ -0,0 +1,38 @@
+package test
+
+import (
+    "testing"
+)
+
+func Test_DudectAnalyze(t *testing.T) {
+    n := 2000
+
+    classes := make([]int, n)
+    same := make([]float64, n)
+    diff := make([]float64, n)
+
+    for i := 0; i < n; i++ {
+        classes[i] = i % 2
+
+        same[i] = float64(100 + i%7)
+        diff[i] = float64(100 + i%7 + 50*classes[i])
+    }
+
+    if res := dudectAnalyze(classes, same); res.Leaky() {
+        t.Errorf("same distribution reported leaky, t = %.2f", res.T)
+    }
+
+    if res := dudectAnalyze(classes, diff); !res.Leaky() {
+        t.Errorf("different distribution not reported leaky, t = %.2f", res.T)
+    }
+}
+
+func Test_Dudect(t *testing.T) {
+    res := Dudect(DudectConfig{
+        Measurements: 1000,
+    }, make([]byte, 16), func(in []byte) {})
+
+    if res.N == 0 {
+        t.Error("no measurements")
+    }
+}
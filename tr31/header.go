@@ -0,0 +1,341 @@
+package tr31
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// 密钥块版本
+const (
+    // TDEA key derivation binding method
+    VersionB = "B"
+
+    // AES key derivation binding method
+    VersionD = "D"
+)
+
+// 常用的密钥用途
+const (
+    KeyUsageBDK         = "B0" // Base Derivation Key
+    KeyUsageDUKPTIK     = "B1" // Initial DUKPT Key
+    KeyUsageCVK         = "C0" // Card Verification Key
+    KeyUsageDataEnc     = "D0" // Symmetric Key for Data Encryption
+    KeyUsageKEK         = "K0" // Key Encryption or Wrapping
+    KeyUsageKBPK        = "K1" // TR-31 Key Block Protection Key
+    KeyUsageISO9797Alg3 = "M3" // ISO 9797-1 MAC Algorithm 3
+    KeyUsageCMAC        = "M6" // ISO 9797-1:2011 MAC Algorithm 5, CMAC
+    KeyUsagePINEnc      = "P0" // PIN Encryption
+    KeyUsagePVV         = "V2" // PIN Verification, Visa PVV
+)
+
+// 算法
+const (
+    AlgorithmAES  = "A"
+    AlgorithmDEA  = "D"
+    AlgorithmEC   = "E"
+    AlgorithmHMAC = "H"
+    AlgorithmRSA  = "R"
+    AlgorithmDSA  = "S"
+    AlgorithmTDEA = "T"
+)
+
+// 使用模式
+const (
+    ModeOfUseBoth     = "B" // Encrypt/Wrap and Decrypt/Unwrap
+    ModeOfUseGenerate = "C" // Both Generate and Verify
+    ModeOfUseDecrypt  = "D" // Decrypt/Unwrap Only
+    ModeOfUseEncrypt  = "E" // Encrypt/Wrap Only
+    ModeOfUseGenOnly  = "G" // Generate Only
+    ModeOfUseNone     = "N" // No special restrictions
+    ModeOfUseSign     = "S" // Signature Only
+    ModeOfUseVerify   = "V" // Verify Only
+    ModeOfUseDerive   = "X" // Key used to derive other keys
+    ModeOfUseVariant  = "Y" // Key used to create key variants
+)
+
+// 可导出性
+const (
+    ExportabilityTrusted   = "E" // Exportable under a KEK in a trusted form
+    ExportabilityNone      = "N" // Non-exportable
+    ExportabilitySensitive = "S" // Sensitive, exportable in a non-trusted form
+)
+
+// 填充用的可选块 ID
+const paddingBlockID = "PB"
+
+const headerLen = 16
+
+// Block is an optional block of the key block header.
+type Block struct {
+    ID   string
+    Data string
+}
+
+// Header is the key block header of ANSI X9.143 / TR-31.
+type Header struct {
+    VersionID     string
+    KeyUsage      string
+    Algorithm     string
+    ModeOfUse     string
+    VersionNum    string
+    Exportability string
+    Reserved      string
+
+    // optional blocks, the padding block PB is handled
+    // by Wrap and Unwrap
+    Blocks []Block
+}
+
+// NewHeader returns a header with no key version,
+// the reserved field and no optional blocks.
+func NewHeader(versionID, keyUsage, algorithm, modeOfUse, exportability string) *Header {
+    return &Header{
+        VersionID:     versionID,
+        KeyUsage:      keyUsage,
+        Algorithm:     algorithm,
+        ModeOfUse:     modeOfUse,
+        VersionNum:    "00",
+        Exportability: exportability,
+        Reserved:      "00",
+    }
+}
+
+// AddBlock adds an optional block.
+func (h *Header) AddBlock(id, data string) {
+    h.Blocks = append(h.Blocks, Block{
+        ID:   id,
+        Data: data,
+    })
+}
+
+// GetBlock returns the data of the optional block.
+func (h *Header) GetBlock(id string) (string, bool) {
+    for _, b := range h.Blocks {
+        if b.ID == id {
+            return b.Data, true
+        }
+    }
+
+    return "", false
+}
+
+func (h *Header) check() error {
+    fields := []struct {
+        name  string
+        value string
+        size  int
+    }{
+        {"version ID", h.VersionID, 1},
+        {"key usage", h.KeyUsage, 2},
+        {"algorithm", h.Algorithm, 1},
+        {"mode of use", h.ModeOfUse, 1},
+        {"key version number", h.VersionNum, 2},
+        {"exportability", h.Exportability, 1},
+        {"reserved field", h.Reserved, 2},
+    }
+
+    for _, f := range fields {
+        if len(f.value) != f.size || !isPrintable(f.value) {
+            return fmt.Errorf("go-cryptobin/tr31: invalid %s %q", f.name, f.value)
+        }
+    }
+
+    for _, b := range h.Blocks {
+        if len(b.ID) != 2 || !isAlphanumeric(b.ID) {
+            return fmt.Errorf("go-cryptobin/tr31: invalid optional block ID %q", b.ID)
+        }
+
+        if b.ID == paddingBlockID {
+            return fmt.Errorf("go-cryptobin/tr31: padding block is added by Wrap")
+        }
+
+        if !isPrintable(b.Data) {
+            return fmt.Errorf("go-cryptobin/tr31: optional block %s data is not printable", b.ID)
+        }
+    }
+
+    return nil
+}
+
+// 编码头部, 用 PB 块补齐到加密分组大小 blockSize. bodyLen 为头部之后的长度
+func (h *Header) encode(blockSize int, bodyLen int) (string, error) {
+    if err := h.check(); err != nil {
+        return "", err
+    }
+
+    var blocks strings.Builder
+    for _, b := range h.Blocks {
+        blocks.WriteString(encodeBlock(b.ID, b.Data))
+    }
+
+    numBlocks := len(h.Blocks)
+
+    if n := (headerLen + blocks.Len()) % blockSize; n != 0 {
+        // PB 块的 ID 和长度占 4 个字符
+        padLen := (blockSize - (n + 4) % blockSize) % blockSize
+
+        blocks.WriteString(encodeBlock(paddingBlockID, strings.Repeat("0", padLen)))
+        numBlocks++
+    }
+
+    if numBlocks > 99 {
+        return "", fmt.Errorf("go-cryptobin/tr31: too many optional blocks")
+    }
+
+    length := headerLen + blocks.Len() + bodyLen
+    if length > 9999 {
+        return "", fmt.Errorf("go-cryptobin/tr31: key block is too long")
+    }
+
+    var sb strings.Builder
+    sb.WriteString(h.VersionID)
+    sb.WriteString(fmt.Sprintf("%04d", length))
+    sb.WriteString(h.KeyUsage)
+    sb.WriteString(h.Algorithm)
+    sb.WriteString(h.ModeOfUse)
+    sb.WriteString(h.VersionNum)
+    sb.WriteString(h.Exportability)
+    sb.WriteString(fmt.Sprintf("%02d", numBlocks))
+    sb.WriteString(h.Reserved)
+    sb.WriteString(blocks.String())
+
+    return sb.String(), nil
+}
+
+// ID || 长度 || 数据, 超过 255 时长度为 "00" || "04" || 4 位长度
+func encodeBlock(id, data string) string {
+    if n := 4 + len(data); n <= 0xff {
+        return id + fmt.Sprintf("%02X", n) + data
+    }
+
+    return id + "0004" + fmt.Sprintf("%04X", 10 + len(data)) + data
+}
+
+// ParseHeader parses the header of the key block and returns
+// the header and the header length in the key block.
+func ParseHeader(keyBlock string) (*Header, int, error) {
+    if len(keyBlock) < headerLen {
+        return nil, 0, fmt.Errorf("go-cryptobin/tr31: key block header is too short")
+    }
+
+    if !isPrintable(keyBlock[:headerLen]) {
+        return nil, 0, fmt.Errorf("go-cryptobin/tr31: key block header is not printable")
+    }
+
+    length, err := parseDecimal(keyBlock[1:5])
+    if err != nil {
+        return nil, 0, fmt.Errorf("go-cryptobin/tr31: invalid key block length %q", keyBlock[1:5])
+    }
+
+    if length != len(keyBlock) {
+        return nil, 0, fmt.Errorf("go-cryptobin/tr31: key block length %d does not match %d", length, len(keyBlock))
+    }
+
+    numBlocks, err := parseDecimal(keyBlock[12:14])
+    if err != nil {
+        return nil, 0, fmt.Errorf("go-cryptobin/tr31: invalid number of optional blocks %q", keyBlock[12:14])
+    }
+
+    h := &Header{
+        VersionID:     keyBlock[0:1],
+        KeyUsage:      keyBlock[5:7],
+        Algorithm:     keyBlock[7:8],
+        ModeOfUse:     keyBlock[8:9],
+        VersionNum:    keyBlock[9:11],
+        Exportability: keyBlock[11:12],
+        Reserved:      keyBlock[14:16],
+    }
+
+    pos := headerLen
+    for i := 0; i < numBlocks; i++ {
+        id, data, n, err := parseBlock(keyBlock[pos:])
+        if err != nil {
+            return nil, 0, err
+        }
+
+        pos += n
+
+        if id != paddingBlockID {
+            h.AddBlock(id, data)
+        }
+    }
+
+    return h, pos, nil
+}
+
+func parseBlock(s string) (id, data string, n int, err error) {
+    if len(s) < 4 {
+        return "", "", 0, fmt.Errorf("go-cryptobin/tr31: optional block is too short")
+    }
+
+    id = s[:2]
+
+    n64, err := strconv.ParseUint(s[2:4], 16, 8)
+    if err != nil {
+        return "", "", 0, fmt.Errorf("go-cryptobin/tr31: invalid optional block %s length %q", id, s[2:4])
+    }
+
+    n, start := int(n64), 4
+
+    // 扩展长度
+    if n == 0 {
+        if len(s) < 6 {
+            return "", "", 0, fmt.Errorf("go-cryptobin/tr31: optional block %s is too short", id)
+        }
+
+        ll, err := strconv.ParseUint(s[4:6], 16, 8)
+        if err != nil || ll == 0 || len(s) < 6 + int(ll) {
+            return "", "", 0, fmt.Errorf("go-cryptobin/tr31: invalid optional block %s length", id)
+        }
+
+        n64, err = strconv.ParseUint(s[6:6+int(ll)], 16, 32)
+        if err != nil {
+            return "", "", 0, fmt.Errorf("go-cryptobin/tr31: invalid optional block %s length", id)
+        }
+
+        n, start = int(n64), 6 + int(ll)
+    }
+
+    if n < start || n > len(s) {
+        return "", "", 0, fmt.Errorf("go-cryptobin/tr31: invalid optional block %s length %d", id, n)
+    }
+
+    data = s[start:n]
+    if !isPrintable(data) {
+        return "", "", 0, fmt.Errorf("go-cryptobin/tr31: optional block %s data is not printable", id)
+    }
+
+    return id, data, n, nil
+}
+
+func parseDecimal(s string) (int, error) {
+    for _, c := range s {
+        if c < '0' || c > '9' {
+            return 0, fmt.Errorf("not decimal")
+        }
+    }
+
+    return strconv.Atoi(s)
+}
+
+func isPrintable(s string) bool {
+    for i := 0; i < len(s); i++ {
+        if s[i] < 0x20 || s[i] > 0x7e {
+            return false
+        }
+    }
+
+    return true
+}
+
+func isAlphanumeric(s string) bool {
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+            return false
+        }
+    }
+
+    return true
+}
@@ -0,0 +1,263 @@
+// Package tr31 implements the ANSI X9.143 / ASC X9 TR-31 key block,
+// with the TDEA (version B) and AES (version D) key derivation binding
+// methods.
+package tr31
+
+import (
+    "io"
+    "fmt"
+    "errors"
+    "strings"
+    "crypto/aes"
+    "crypto/des"
+    "crypto/rand"
+    "crypto/cipher"
+    "crypto/subtle"
+    "encoding/hex"
+    "encoding/binary"
+
+    "github.com/deatil/go-cryptobin/mac"
+    cryptobin_des "github.com/deatil/go-cryptobin/cipher/des"
+)
+
+var (
+    ErrUnsupportedVersion = errors.New("go-cryptobin/tr31: unsupported key block version")
+    ErrInvalidKBPK        = errors.New("go-cryptobin/tr31: invalid key block protection key")
+    ErrInvalidMAC         = errors.New("go-cryptobin/tr31: key block MAC does not match")
+)
+
+// 派生密钥的用途
+const (
+    usageEncryption = 0x0000
+    usageMAC        = 0x0001
+)
+
+// binding 为版本对应的分组算法和派生参数
+type binding struct {
+    newCipher func(key []byte) (cipher.Block, error)
+    blockSize int
+    macSize   int
+}
+
+func bindingOf(versionID string) (binding, error) {
+    switch versionID {
+        case VersionB:
+            return binding{newTDEACipher, des.BlockSize, 8}, nil
+        case VersionD:
+            return binding{aes.NewCipher, aes.BlockSize, 16}, nil
+    }
+
+    return binding{}, ErrUnsupportedVersion
+}
+
+// 16 字节为双倍长 TDEA, 24 字节为三倍长 TDEA
+func newTDEACipher(key []byte) (cipher.Block, error) {
+    if len(key) == 16 {
+        return cryptobin_des.NewTwoDESCipher(key)
+    }
+
+    return des.NewTripleDESCipher(key)
+}
+
+// 派生数据: 计数器 || 用途 || 0x00 || 算法 || 长度(位)
+func deriveKey(versionID string, kbpk []byte, usage uint16) ([]byte, error) {
+    b, err := bindingOf(versionID)
+    if err != nil {
+        return nil, err
+    }
+
+    var algorithm uint16
+    switch versionID {
+        case VersionB:
+            switch len(kbpk) {
+                case 16:
+                    algorithm = 0x0000
+                case 24:
+                    algorithm = 0x0001
+                default:
+                    return nil, ErrInvalidKBPK
+            }
+        case VersionD:
+            switch len(kbpk) {
+                case 16:
+                    algorithm = 0x0002
+                case 24:
+                    algorithm = 0x0003
+                case 32:
+                    algorithm = 0x0004
+                default:
+                    return nil, ErrInvalidKBPK
+            }
+    }
+
+    block, err := b.newCipher(kbpk)
+    if err != nil {
+        return nil, err
+    }
+
+    cmac := mac.NewCMAC(block, b.blockSize)
+
+    var data [8]byte
+    binary.BigEndian.PutUint16(data[1:], usage)
+    binary.BigEndian.PutUint16(data[4:], algorithm)
+    binary.BigEndian.PutUint16(data[6:], uint16(len(kbpk) * 8))
+
+    var key []byte
+    for i := 1; len(key) < len(kbpk); i++ {
+        data[0] = byte(i)
+        key = append(key, cmac.MAC(data[:])...)
+    }
+
+    return key[:len(kbpk)], nil
+}
+
+// KBEK 和 KBAK
+func deriveKeys(versionID string, kbpk []byte) (kbek, kbak []byte, err error) {
+    kbek, err = deriveKey(versionID, kbpk, usageEncryption)
+    if err != nil {
+        return
+    }
+
+    kbak, err = deriveKey(versionID, kbpk, usageMAC)
+    return
+}
+
+// MAC = CMAC(KBAK, 头部 || 明文密钥数据)
+func calculateMAC(b binding, kbak []byte, header string, payload []byte) ([]byte, error) {
+    block, err := b.newCipher(kbak)
+    if err != nil {
+        return nil, err
+    }
+
+    data := make([]byte, 0, len(header) + len(payload))
+    data = append(data, header...)
+    data = append(data, payload...)
+
+    return mac.NewCMAC(block, b.macSize).MAC(data), nil
+}
+
+// Wrap wraps the key with the key block protection key kbpk and
+// returns the key block. Version B uses a 16 or 24 bytes TDEA kbpk,
+// version D uses an AES kbpk.
+func Wrap(kbpk []byte, header *Header, key []byte) (string, error) {
+    return wrap(rand.Reader, kbpk, header, key)
+}
+
+func wrap(random io.Reader, kbpk []byte, header *Header, key []byte) (string, error) {
+    b, err := bindingOf(header.VersionID)
+    if err != nil {
+        return "", err
+    }
+
+    if len(key) == 0 || len(key) > 0xffff / 8 {
+        return "", fmt.Errorf("go-cryptobin/tr31: invalid key length %d", len(key))
+    }
+
+    kbek, kbak, err := deriveKeys(header.VersionID, kbpk)
+    if err != nil {
+        return "", err
+    }
+
+    // 密钥长度(位) || 密钥 || 随机填充
+    payloadLen := 2 + len(key)
+    payloadLen += (b.blockSize - payloadLen % b.blockSize) % b.blockSize
+
+    payload := make([]byte, payloadLen)
+    binary.BigEndian.PutUint16(payload, uint16(len(key) * 8))
+    copy(payload[2:], key)
+
+    if _, err := io.ReadFull(random, payload[2+len(key):]); err != nil {
+        return "", err
+    }
+
+    headerStr, err := header.encode(b.blockSize, 2 * (payloadLen + b.macSize))
+    if err != nil {
+        return "", err
+    }
+
+    tag, err := calculateMAC(b, kbak, headerStr, payload)
+    if err != nil {
+        return "", err
+    }
+
+    block, err := b.newCipher(kbek)
+    if err != nil {
+        return "", err
+    }
+
+    encrypted := make([]byte, payloadLen)
+    cipher.NewCBCEncrypter(block, tag[:b.blockSize]).CryptBlocks(encrypted, payload)
+
+    return headerStr +
+        strings.ToUpper(hex.EncodeToString(encrypted)) +
+        strings.ToUpper(hex.EncodeToString(tag)), nil
+}
+
+// Unwrap verifies the key block with the key block protection key kbpk
+// and returns the header and the key.
+func Unwrap(kbpk []byte, keyBlock string) (*Header, []byte, error) {
+    header, n, err := ParseHeader(keyBlock)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    b, err := bindingOf(header.VersionID)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if n % b.blockSize != 0 {
+        return nil, nil, fmt.Errorf("go-cryptobin/tr31: header length %d is not a multiple of %d", n, b.blockSize)
+    }
+
+    body := keyBlock[n:]
+    if len(body) < 2 * (b.blockSize + b.macSize) {
+        return nil, nil, fmt.Errorf("go-cryptobin/tr31: key block is too short")
+    }
+
+    encrypted, err := hex.DecodeString(body[:len(body) - 2*b.macSize])
+    if err != nil {
+        return nil, nil, fmt.Errorf("go-cryptobin/tr31: invalid encrypted key data")
+    }
+
+    tag, err := hex.DecodeString(body[len(body) - 2*b.macSize:])
+    if err != nil {
+        return nil, nil, fmt.Errorf("go-cryptobin/tr31: invalid key block MAC")
+    }
+
+    if len(encrypted) % b.blockSize != 0 {
+        return nil, nil, fmt.Errorf("go-cryptobin/tr31: encrypted key data is not a multiple of %d", b.blockSize)
+    }
+
+    kbek, kbak, err := deriveKeys(header.VersionID, kbpk)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    block, err := b.newCipher(kbek)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    payload := make([]byte, len(encrypted))
+    cipher.NewCBCDecrypter(block, tag[:b.blockSize]).CryptBlocks(payload, encrypted)
+
+    expectedTag, err := calculateMAC(b, kbak, keyBlock[:n], payload)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if subtle.ConstantTimeCompare(expectedTag, tag) != 1 {
+        return nil, nil, ErrInvalidMAC
+    }
+
+    bitLen := int(binary.BigEndian.Uint16(payload))
+    if bitLen % 8 != 0 || bitLen / 8 > len(payload) - 2 {
+        return nil, nil, fmt.Errorf("go-cryptobin/tr31: invalid key length %d bits", bitLen)
+    }
+
+    key := make([]byte, bitLen / 8)
+    copy(key, payload[2:])
+
+    return header, key, nil
+}
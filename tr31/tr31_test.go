@@ -0,0 +1,253 @@
+package tr31
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "encoding/hex"
+
+    "github.com/deatil/go-cryptobin/mac"
+    cryptobin_test "github.com/deatil/go-cryptobin/tool/test"
+)
+
+func fromHex(s string) []byte {
+    h, _ := hex.DecodeString(s)
+    return h
+}
+
+// TODO: 导入 X9.143 的版本 B (TDEA) 示例密钥块
+func Test_UnwrapVector(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    // ANSI X9.143 AES key derivation binding method example
+    kbpk := fromHex("88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6")
+    keyBlock := "D0112P0AE00E0000B82679114F470F540165EDFBF7E250FCEA43F810D215F8D207E2E417C07156A27E8E31DA05F7425509593D03A457DC34"
+
+    header, key, err := Unwrap(kbpk, keyBlock)
+    assertNoError(err, "Test_UnwrapVector")
+
+    assertEqual(key, fromHex("3F419E1CB7079442AA37474C2EFBF8B8"), "Test_UnwrapVector-key")
+    assertEqual(header.VersionID, VersionD, "Test_UnwrapVector-VersionID")
+    assertEqual(header.KeyUsage, KeyUsagePINEnc, "Test_UnwrapVector-KeyUsage")
+    assertEqual(header.Algorithm, AlgorithmAES, "Test_UnwrapVector-Algorithm")
+    assertEqual(header.ModeOfUse, ModeOfUseEncrypt, "Test_UnwrapVector-ModeOfUse")
+    assertEqual(header.Exportability, ExportabilityTrusted, "Test_UnwrapVector-Exportability")
+    assertEqual(len(header.Blocks), 0, "Test_UnwrapVector-Blocks")
+
+    // 修改密钥块
+    for _, i := range []int{20, len(keyBlock) - 1} {
+        tampered := []byte(keyBlock)
+        if tampered[i] == '0' {
+            tampered[i] = '1'
+        } else {
+            tampered[i] = '0'
+        }
+
+        _, _, err = Unwrap(kbpk, string(tampered))
+        assertEqual(err, ErrInvalidMAC, "Test_UnwrapVector-tampered")
+    }
+
+    // 修改头部
+    _, _, err = Unwrap(kbpk, "D0112P0AE00N" + keyBlock[12:])
+    assertEqual(err, ErrInvalidMAC, "Test_UnwrapVector-header")
+}
+
+// 派生数据按 X9.143 的格式直接写出
+func Test_DeriveKeys(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    cases := []struct {
+        versionID string
+        kbpk      []byte
+        kbek      []string
+        kbak      []string
+    }{
+        {
+            VersionB,
+            fromHex("89E88CF7931444F334BD7547FC3F380C"),
+            []string{"0100000000000080", "0200000000000080"},
+            []string{"0100010000000080", "0200010000000080"},
+        },
+        {
+            VersionB,
+            fromHex("89E88CF7931444F334BD7547FC3F380C0123456789ABCDEF"),
+            []string{"01000000000100C0", "02000000000100C0", "03000000000100C0"},
+            []string{"01000100000100C0", "02000100000100C0", "03000100000100C0"},
+        },
+        {
+            VersionD,
+            fromHex("88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6"),
+            []string{"0100000000040100", "0200000000040100"},
+            []string{"0100010000040100", "0200010000040100"},
+        },
+    }
+
+    for _, c := range cases {
+        b, err := bindingOf(c.versionID)
+        assertNoError(err, "Test_DeriveKeys")
+
+        block, err := b.newCipher(c.kbpk)
+        assertNoError(err, "Test_DeriveKeys")
+
+        cmac := mac.NewCMAC(block, b.blockSize)
+
+        derive := func(data []string) []byte {
+            var out []byte
+            for _, d := range data {
+                out = append(out, cmac.MAC(fromHex(d))...)
+            }
+
+            return out[:len(c.kbpk)]
+        }
+
+        kbek, kbak, err := deriveKeys(c.versionID, c.kbpk)
+        assertNoError(err, "Test_DeriveKeys")
+
+        assertEqual(kbek, derive(c.kbek), "Test_DeriveKeys-KBEK-" + c.versionID)
+        assertEqual(kbak, derive(c.kbak), "Test_DeriveKeys-KBAK-" + c.versionID)
+    }
+}
+
+func Test_WrapUnwrap(t *testing.T) {
+    cases := []struct {
+        name      string
+        versionID string
+        algorithm string
+        kbpk      []byte
+        key       []byte
+    }{
+        {"B-2TDEA", VersionB, AlgorithmTDEA, fromHex("89E88CF7931444F334BD7547FC3F380C"), fromHex("F039121BEC83D26B169BDCD5B22AAF8F")},
+        {"B-3TDEA", VersionB, AlgorithmTDEA, fromHex("89E88CF7931444F334BD7547FC3F380C0123456789ABCDEF"), fromHex("F039121BEC83D26B169BDCD5B22AAF8F0123456789ABCDEF")},
+        {"D-AES128", VersionD, AlgorithmAES, fromHex("88E1AB2A2E3DD38C1FA039A536500CC8"), fromHex("3F419E1CB7079442AA37474C2EFBF8B8")},
+        {"D-AES192", VersionD, AlgorithmAES, fromHex("88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01"), fromHex("3F419E1CB7079442AA37474C2EFBF8B8")},
+        {"D-AES256", VersionD, AlgorithmAES, fromHex("88E1AB2A2E3DD38C1FA039A536500CC8A87AB9D62DC92C01058FA79F44657DE6"), fromHex("3F419E1CB7079442AA37474C2EFBF8B8A87AB9D62DC92C01058FA79F44657DE6")},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            assertEqual := cryptobin_test.AssertEqualT(t)
+            assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+            header := NewHeader(c.versionID, KeyUsageDataEnc, c.algorithm, ModeOfUseBoth, ExportabilityNone)
+            header.AddBlock("KS", "00604B120F9292800000")
+            header.AddBlock("TS", "20241019120000Z")
+
+            keyBlock, err := Wrap(c.kbpk, header, c.key)
+            assertNoError(err, "Wrap")
+
+            if strings.ToUpper(keyBlock) != keyBlock {
+                t.Errorf("key block should be uppercase, got %s", keyBlock)
+            }
+
+            header2, n, err := ParseHeader(keyBlock)
+            assertNoError(err, "ParseHeader")
+
+            blockSize := 16
+            if c.versionID == VersionB {
+                blockSize = 8
+            }
+
+            if n % blockSize != 0 {
+                t.Errorf("header length %d is not a multiple of %d", n, blockSize)
+            }
+
+            assertEqual(header2, header, "ParseHeader-header")
+
+            header3, key, err := Unwrap(c.kbpk, keyBlock)
+            assertNoError(err, "Unwrap")
+
+            assertEqual(header3, header, "Unwrap-header")
+            assertEqual(key, c.key, "Unwrap-key")
+
+            // 随机填充
+            keyBlock2, err := Wrap(c.kbpk, header, c.key)
+            assertNoError(err, "Wrap-2")
+
+            if keyBlock2 == keyBlock {
+                t.Error("key blocks should be randomly padded")
+            }
+
+            // 错误的 KBPK
+            kbpk := bytes.Clone(c.kbpk)
+            kbpk[0] ^= 2
+
+            _, _, err = Unwrap(kbpk, keyBlock)
+            assertEqual(err, ErrInvalidMAC, "Unwrap-kbpk")
+        })
+    }
+}
+
+func Test_ExtendedBlock(t *testing.T) {
+    assertEqual := cryptobin_test.AssertEqualT(t)
+    assertNoError := cryptobin_test.AssertNoErrorT(t)
+
+    kbpk := fromHex("88E1AB2A2E3DD38C1FA039A536500CC8")
+    key := fromHex("3F419E1CB7079442AA37474C2EFBF8B8")
+
+    data := strings.Repeat("A", 300)
+
+    header := NewHeader(VersionD, KeyUsageKEK, AlgorithmAES, ModeOfUseBoth, ExportabilityNone)
+    header.AddBlock("CT", data)
+
+    keyBlock, err := Wrap(kbpk, header, key)
+    assertNoError(err, "Test_ExtendedBlock")
+
+    // 扩展长度: "00" || "04" || 4 位长度
+    assertEqual(keyBlock[16:26], "CT00040136", "Test_ExtendedBlock-length")
+
+    header2, key2, err := Unwrap(kbpk, keyBlock)
+    assertNoError(err, "Test_ExtendedBlock-Unwrap")
+
+    got, ok := header2.GetBlock("CT")
+    assertEqual(ok, true, "Test_ExtendedBlock-GetBlock")
+    assertEqual(got, data, "Test_ExtendedBlock-data")
+    assertEqual(key2, key, "Test_ExtendedBlock-key")
+}
+
+func Test_HeaderError(t *testing.T) {
+    kbpk := fromHex("88E1AB2A2E3DD38C1FA039A536500CC8")
+    key := fromHex("3F419E1CB7079442AA37474C2EFBF8B8")
+
+    headers := []*Header{
+        NewHeader("A", KeyUsageKEK, AlgorithmTDEA, ModeOfUseBoth, ExportabilityNone),
+        NewHeader(VersionD, "K", AlgorithmAES, ModeOfUseBoth, ExportabilityNone),
+        NewHeader(VersionD, KeyUsageKEK, "", ModeOfUseBoth, ExportabilityNone),
+    }
+
+    header := NewHeader(VersionD, KeyUsageKEK, AlgorithmAES, ModeOfUseBoth, ExportabilityNone)
+    header.AddBlock(paddingBlockID, "0000")
+    headers = append(headers, header)
+
+    header = NewHeader(VersionD, KeyUsageKEK, AlgorithmAES, ModeOfUseBoth, ExportabilityNone)
+    header.AddBlock("K", "0000")
+    headers = append(headers, header)
+
+    for i, h := range headers {
+        if _, err := Wrap(kbpk, h, key); err == nil {
+            t.Errorf("#%d: Wrap should fail", i)
+        }
+    }
+
+    // KBPK 长度
+    header = NewHeader(VersionB, KeyUsageKEK, AlgorithmTDEA, ModeOfUseBoth, ExportabilityNone)
+    if _, err := Wrap(kbpk[:8], header, key); err != ErrInvalidKBPK {
+        t.Errorf("Wrap should return ErrInvalidKBPK, got %v", err)
+    }
+
+    keyBlocks := []string{
+        "",
+        "D0112P0AE00E00",
+        "D0113P0AE00E0000B82679114F470F540165EDFBF7E250FCEA43F810D215F8D207E2E417C07156A27E8E31DA05F7425509593D03A457DC34",
+        "D0112P0AE00E0100B82679114F470F540165EDFBF7E250FCEA43F810D215F8D207E2E417C07156A27E8E31DA05F7425509593D03A457DC34",
+        "D0112P0AE00E0000Z82679114F470F540165EDFBF7E250FCEA43F810D215F8D207E2E417C07156A27E8E31DA05F7425509593D03A457DC34",
+        "C0112P0AE00E0000B82679114F470F540165EDFBF7E250FCEA43F810D215F8D207E2E417C07156A27E8E31DA05F7425509593D03A457DC34",
+    }
+
+    for i, keyBlock := range keyBlocks {
+        if _, _, err := Unwrap(kbpk, keyBlock); err == nil {
+            t.Errorf("#%d: Unwrap should fail", i)
+        }
+    }
+}
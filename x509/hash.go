@@ -16,6 +16,7 @@ import (
     "golang.org/x/crypto/ripemd160"
 
     "github.com/deatil/go-cryptobin/hash/sm3"
+    "github.com/deatil/go-cryptobin/hash/blake3"
 
     cipher_gost "github.com/deatil/go-cryptobin/cipher/gost"
     "github.com/deatil/go-cryptobin/hash/gost/gost341194"
@@ -78,6 +79,8 @@ func (h Hash) String() string {
         return "GOST34112012256"
     case GOST34112012512:
         return "GOST34112012512"
+    case BLAKE3:
+        return "BLAKE3"
     default:
         return "unknown hash value " + strconv.Itoa(int(h))
     }
@@ -107,6 +110,7 @@ const (
     GOST34112001
     GOST34112012256
     GOST34112012512
+    BLAKE3                      // import github.com/deatil/go-cryptobin/hash/blake3
     maxHash
 )
 
@@ -134,6 +138,7 @@ var digestSizes = []uint8{
     GOST34112001:    32,
     GOST34112012256: 32,
     GOST34112012512: 64,
+    BLAKE3:          32,
 }
 
 // Size returns the length, in bytes, of a digest resulting from the given hash
@@ -230,4 +235,6 @@ func init() {
     RegisterHash(GOST34112001, newHashGOST34112001)
     RegisterHash(GOST34112012256, gost34112012256.New)
     RegisterHash(GOST34112012512, gost34112012512.New)
+
+    RegisterHash(BLAKE3, blake3.New)
 }
@@ -88,7 +88,8 @@ func Test_String(t *testing.T) {
         {GOST34112001, "GOST34112001"},
         {GOST34112012256, "GOST34112012256"},
         {GOST34112012512, "GOST34112012512"},
-        {maxHash, "unknown hash value 24"},
+        {BLAKE3, "BLAKE3"},
+        {maxHash, "unknown hash value 25"},
     }
 
     for _, td := range tests {